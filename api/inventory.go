@@ -25,3 +25,18 @@ func (c *Client) Inventory() (*Inventory, error) {
 
 	return &v, nil
 }
+
+// Collectors retrieves the builtin collector inventory from the agent
+func (c *Client) Collectors() (*Collectors, error) {
+	data, err := c.get("/inventory/collectors/")
+	if err != nil {
+		return nil, err
+	}
+
+	var v Collectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, errors.Wrap(err, "parsing collector inventory")
+	}
+
+	return &v, nil
+}
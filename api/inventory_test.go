@@ -56,3 +56,50 @@ func TestInventory(t *testing.T) {
 		ts.Close()
 	}
 }
+
+func TestCollectors(t *testing.T) {
+	t.Log("Testing Collectors")
+
+	tests := []struct {
+		name        string
+		response    string
+		shouldErr   bool
+		expectedErr string
+	}{
+		{"invalid (json/parse)", "invalid", true, "parsing collector inventory: invalid character 'i' looking for beginning of value"},
+		{"valid", `[{"name":"cpu","enabled":true}]`, false, ""},
+	}
+
+	for _, test := range tests {
+		t.Log("\t", test.name)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/inventory/collectors/" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(test.response))
+		}))
+
+		c, err := New(ts.URL)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		_, err = c.Collectors()
+
+		if test.shouldErr {
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if err.Error() != test.expectedErr {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+		}
+
+		ts.Close()
+	}
+}
@@ -28,6 +28,19 @@ type Metrics map[string]Metric
 // Inventory defines list of active plugins
 type Inventory []Plugin
 
+// Collectors defines list of builtin collectors
+type Collectors []Collector
+
+// Collector defines a builtin collector
+type Collector struct {
+	Name            string `json:"name"`
+	Enabled         bool   `json:"enabled"` // false if enabled in configuration but unknown or failed to initialize
+	LastRunStart    string `json:"last_run_start"`
+	LastRunEnd      string `json:"last_run_end"`
+	LastRunDuration string `json:"last_run_duration"`
+	LastError       string `json:"last_error"`
+}
+
 // Plugin defines an active plugin
 type Plugin struct {
 	ID              string   `json:"id"` // combination of name`instance
@@ -39,4 +52,15 @@ type Plugin struct {
 	LastRunEnd      string   `json:"last_run_end"`
 	LastRunDuration string   `json:"last_run_duration"`
 	LastError       string   `json:"last_error"`
+	LastExitCode    int      `json:"last_exit_code"`
+}
+
+// Version defines the agent build and the hash of its effective configuration
+type Version struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	BuildDate  string `json:"build_date"`
+	Tag        string `json:"tag"`
+	ConfigHash string `json:"config_hash"` // sha256 of the effective configuration, secrets excluded
 }
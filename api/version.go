@@ -0,0 +1,27 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Version retrieves the build information and configuration hash from the agent
+func (c *Client) Version() (*Version, error) {
+	data, err := c.get("/version/")
+	if err != nil {
+		return nil, err
+	}
+
+	var v Version
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, errors.Wrap(err, "parsing version")
+	}
+
+	return &v, nil
+}
@@ -54,10 +54,44 @@ in JSON format.`,
 			return
 		}
 
+		//
+		// validate configuration and exit
+		//
+		if viper.GetBool(config.KeyValidate) {
+			errs := agent.Validate()
+			if len(errs) == 0 {
+				fmt.Println("configuration OK")
+				return
+			}
+			fmt.Fprintf(os.Stderr, "configuration invalid, %d problem(s) found:\n", len(errs))
+			for _, err := range errs {
+				fmt.Fprintf(os.Stderr, "  - %s\n", err)
+			}
+			os.Exit(1)
+		}
+
+		//
+		// collect once, submit and exit
+		//
+		if viper.GetBool(config.KeyOneshot) {
+			if err := agent.Oneshot(); err != nil {
+				log.Fatal().Err(err).Msg("oneshot")
+			}
+			return
+		}
+
+		cfgHash, err := config.Hash()
+		if err != nil {
+			log.Warn().Err(err).Msg("config hash")
+		}
+
 		log.Info().
 			Int("pid", os.Getpid()).
 			Str("name", release.NAME).
-			Str("ver", release.VERSION).Msg("Starting")
+			Str("ver", release.VERSION).
+			Str("commit", release.COMMIT).
+			Str("build_date", release.DATE).
+			Str("config_hash", cfgHash).Msg("Starting")
 
 		a, err := agent.New()
 		if err != nil {
@@ -67,7 +101,7 @@ in JSON format.`,
 		config.StatConfig()
 
 		if err := a.Start(); err != nil {
-			log.Fatal().Err(err).Msg("starting agent")
+			log.Fatal().Err(err).Msg("agent")
 		}
 	},
 }
@@ -99,6 +133,21 @@ func init() {
 		RootCmd.PersistentFlags().StringVarP(&cfgFile, longOpt, shortOpt, "", description)
 	}
 
+	{
+		const (
+			key          = config.KeyConfigDir
+			longOpt      = "config-dir"
+			defaultValue = defaults.ConfigDir
+			envVar       = release.ENVPREFIX + "_CONFIG_DIR"
+			description  = "Drop-in directory, *.(json|toml|yaml) files are merged over the config file in lexical order"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		var (
 			key         = config.KeyListen
@@ -109,8 +158,8 @@ func init() {
 		)
 
 		RootCmd.Flags().StringSliceP(longOpt, shortOpt, []string{}, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -122,11 +171,26 @@ func init() {
 		)
 
 		RootCmd.Flags().StringSlice(longOpt, defaults.Collectors, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.Collectors)
 	}
 
+	{
+		const (
+			key          = config.KeyCollectorsStrict
+			longOpt      = "collectors-strict"
+			defaultValue = defaults.CollectorsStrict
+			envVar       = release.ENVPREFIX + "_COLLECTORS_STRICT"
+			description  = "Fail on unknown builtin collector names, rather than ignoring them"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key         = config.KeyListenSocket
@@ -137,8 +201,36 @@ func init() {
 		)
 
 		RootCmd.Flags().StringSliceP(longOpt, shortOpt, []string{}, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyPIDFile
+			longOpt     = "pid-file"
+			envVar      = release.ENVPREFIX + "_PID_FILE"
+			description = "Write the agent's pid to file, locked to prevent a second agent from starting, empty disables"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.PIDFile, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.PIDFile)
+	}
+
+	{
+		const (
+			key         = config.KeyPluginCollisions
+			longOpt     = "plugin-collisions"
+			envVar      = release.ENVPREFIX + "_PLUGIN_COLLISIONS"
+			description = "Metric name collisions between plugins, drop (keep first plugin's, sorted by id) or namespace (keep both)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.PluginCollisions, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.PluginCollisions)
 	}
 
 	{
@@ -151,8 +243,8 @@ func init() {
 		)
 
 		RootCmd.Flags().StringP(longOpt, shortOpt, defaults.PluginPath, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.PluginPath)
 	}
 
@@ -165,11 +257,69 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.PluginTTLUnits, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.PluginTTLUnits)
 	}
 
+	{
+		const (
+			key         = config.KeyPluginSlowThreshold
+			longOpt     = "plugin-slow-threshold"
+			envVar      = release.ENVPREFIX + "_PLUGIN_SLOW_THRESHOLD"
+			description = "Plugins whose last run took at least this long run in the background, their last result is served until they finish (e.g. 10s, empty disables)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.PluginSlowThreshold, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.PluginSlowThreshold)
+	}
+
+	{
+		const (
+			key         = config.KeyPluginTimeout
+			longOpt     = "plugin-timeout"
+			envVar      = release.ENVPREFIX + "_PLUGIN_TIMEOUT"
+			description = "Default plugin execution timeout (e.g. 10s, 0 = no timeout)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.PluginTimeout, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.PluginTimeout)
+	}
+
+	{
+		const (
+			key          = config.KeyPluginWatch
+			longOpt      = "plugin-watch"
+			defaultValue = defaults.PluginWatch
+			envVar       = release.ENVPREFIX + "_PLUGIN_WATCH"
+			description  = "Watch plugin directory, re-scan automatically when plugins are added/removed/changed"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyPluginWorkers
+			longOpt      = "plugin-workers"
+			defaultValue = defaults.PluginWorkers
+			envVar       = release.ENVPREFIX + "_PLUGIN_WORKERS"
+			description  = "Maximum number of plugins to execute concurrently (0 = number of CPUs)"
+		)
+
+		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
 	//
 	// Reverse mode
 	//
@@ -183,8 +333,8 @@ func init() {
 		)
 
 		RootCmd.Flags().BoolP(longOpt, shortOpt, defaults.Reverse, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.Reverse)
 	}
 
@@ -198,8 +348,36 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyReverseClientCertFile
+			longOpt      = "reverse-client-cert-file"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_REVERSE_CLIENT_CERT_FILE"
+			description  = "Client certificate file presented to the broker, if required (PEM cert and CAs concatenated together)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyReverseClientKeyFile
+			longOpt      = "reverse-client-key-file"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_REVERSE_CLIENT_KEY_FILE"
+			description  = "Client certificate key file"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -212,8 +390,107 @@ func init() {
 		)
 
 		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyReverseMaxFrameSize
+			longOpt      = "reverse-max-frame-size"
+			defaultValue = defaults.ReverseMaxFrameSize
+			envVar       = release.ENVPREFIX + "_REVERSE_MAX_FRAME_SIZE"
+			description  = "Max frame payload size accepted from broker, larger frames reset the connection"
+		)
+
+		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = config.KeyReverseCompression
+			longOpt     = "reverse-compression"
+			envVar      = release.ENVPREFIX + "_REVERSE_COMPRESSION"
+			description = "Compress metrics sent to the broker (gzip), only if the broker supports it, empty disables"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.ReverseCompression, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ReverseCompression)
+	}
+
+	{
+		const (
+			key         = config.KeyReverseCommandTimeout
+			longOpt     = "reverse-command-timeout"
+			envVar      = release.ENVPREFIX + "_REVERSE_COMMAND_TIMEOUT"
+			description = "Maximum time to service a broker command (e.g. a metric request), an error response is sent to the broker on overrun"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.ReverseCommandTimeout, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ReverseCommandTimeout)
+	}
+
+	{
+		const (
+			key         = config.KeyReverseConnectJitter
+			longOpt     = "reverse-connect-jitter"
+			envVar      = release.ENVPREFIX + "_REVERSE_CONNECT_JITTER"
+			description = "Maximum random delay before connecting to the broker, initially and after a lost connection (e.g. 30s), empty disables"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.ReverseConnectJitter, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ReverseConnectJitter)
+	}
+
+	{
+		const (
+			key         = config.KeyReverseLatencyInterval
+			longOpt     = "reverse-latency-interval"
+			envVar      = release.ENVPREFIX + "_REVERSE_LATENCY_INTERVAL"
+			description = "How often to measure broker round-trip latency (e.g. 1m), empty disables"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.ReverseLatencyInterval, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ReverseLatencyInterval)
+	}
+
+	{
+		const (
+			key         = config.KeyDirect
+			longOpt     = "direct"
+			envVar      = release.ENVPREFIX + "_DIRECT"
+			description = "Submit metrics directly to the check (--check-id, httptrap) on an interval, instead of reverse"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaults.Direct, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.Direct)
+	}
+
+	{
+		const (
+			key         = config.KeyDirectInterval
+			longOpt     = "direct-interval"
+			envVar      = release.ENVPREFIX + "_DIRECT_INTERVAL"
+			description = "How often metrics are collected and submitted in direct mode"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.DirectInterval, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.DirectInterval)
 	}
 
 	//
@@ -230,8 +507,8 @@ func init() {
 		)
 
 		RootCmd.Flags().StringP(longOpt, shortOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -244,8 +521,8 @@ func init() {
 		)
 
 		RootCmd.Flags().BoolP(longOpt, shortOpt, defaults.CheckCreate, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckCreate)
 	}
 
@@ -259,8 +536,8 @@ func init() {
 		)
 
 		RootCmd.Flags().StringP(longOpt, shortOpt, defaults.CheckTarget, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckTarget)
 
 	}
@@ -274,8 +551,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, "", desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -287,11 +564,24 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.CheckBroker, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckBroker)
 	}
 
+	{
+		const (
+			key         = config.KeyCheckBundleTemplate
+			longOpt     = "check-bundle-template"
+			envVar      = release.ENVPREFIX + "_CHECK_BUNDLE_TEMPLATE"
+			description = "JSON file describing the check bundle (e.g. tags, period, metric_filters), if creating a check bundle"
+		)
+
+		RootCmd.Flags().String(longOpt, "", desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
 	{
 		const (
 			key         = config.KeyCheckTags
@@ -301,11 +591,69 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.CheckTags, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckTags)
 	}
 
+	{
+		const (
+			key         = config.KeyCheckSearchTags
+			longOpt     = "check-search-tags"
+			envVar      = release.ENVPREFIX + "_CHECK_SEARCH_TAGS"
+			description = "Tags [comma separated list] to find the check bundle by, instead of the check target (added if creating a check bundle)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.CheckSearchTags, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.CheckSearchTags)
+	}
+
+	{
+		const (
+			key         = config.KeyCheckSearchSelect
+			longOpt     = "check-search-select"
+			envVar      = release.ENVPREFIX + "_CHECK_SEARCH_SELECT"
+			description = "Check bundle to use when more than one matches the search tags (none|oldest|newest, none fails)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.CheckSearchSelect, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.CheckSearchSelect)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckAnnotations
+			longOpt      = "check-annotations"
+			defaultValue = defaults.CheckAnnotations
+			envVar       = release.ENVPREFIX + "_CHECK_ANNOTATIONS"
+			description  = "Post annotations when the agent starts, stops and reloads its configuration (requires API token)"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckAnnotationCategory
+			longOpt      = "check-annotation-category"
+			defaultValue = defaults.CheckAnnotationCategory
+			envVar       = release.ENVPREFIX + "_CHECK_ANNOTATION_CATEGORY"
+			description  = "Category of agent lifecycle annotations"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key         = config.KeyCheckEnableNewMetrics
@@ -316,8 +664,8 @@ func init() {
 		)
 
 		RootCmd.Flags().BoolP(longOpt, shortOpt, defaults.CheckEnableNewMetrics, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckEnableNewMetrics)
 	}
 
@@ -330,8 +678,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.CheckMetricStatePath, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckMetricStatePath)
 	}
 
@@ -344,11 +692,24 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.CheckMetricRefreshTTL, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.CheckMetricRefreshTTL)
 	}
 
+	{
+		const (
+			key         = config.KeyCheckForceEnableMetrics
+			longOpt     = "check-force-enable-metrics"
+			envVar      = release.ENVPREFIX + "_CHECK_FORCE_ENABLE_METRICS"
+			description = "List of metric names always enabled on the check, even before they are first reported (requires --check-enable-new-metrics)"
+		)
+
+		RootCmd.Flags().StringSlice(longOpt, []string{}, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
 	//
 	// API
 	//
@@ -361,8 +722,8 @@ func init() {
 			description  = "Circonus API Token key"
 		)
 		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -374,8 +735,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.APIApp, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.APIApp)
 	}
 
@@ -388,8 +749,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.APIURL, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.APIURL)
 	}
 
@@ -403,8 +764,139 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	//
+	// Secondary (HA) cluster
+	//
+	{
+		const (
+			key          = config.KeyCheckSecondaryBundleID
+			longOpt      = "check-secondary-id"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_CHECK_SECONDARY_ID"
+			description  = "Secondary cluster HTTPTRAP Check Bundle ID, metrics are mirrored to it"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckSecondaryAPIKey
+			longOpt      = "check-secondary-api-key"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_CHECK_SECONDARY_API_KEY"
+			description  = "Secondary cluster Circonus API Token key"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyCheckSecondaryAPIApp
+			longOpt     = "check-secondary-api-app"
+			envVar      = release.ENVPREFIX + "_CHECK_SECONDARY_API_APP"
+			description = "Secondary cluster Circonus API Token app"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.APIApp, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.APIApp)
+	}
+
+	{
+		const (
+			key         = config.KeyCheckSecondaryAPIURL
+			longOpt     = "check-secondary-api-url"
+			envVar      = release.ENVPREFIX + "_CHECK_SECONDARY_API_URL"
+			description = "Secondary cluster Circonus API URL"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.APIURL, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.APIURL)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckSecondaryAPICAFile
+			longOpt      = "check-secondary-api-ca-file"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_CHECK_SECONDARY_API_CA_FILE"
+			description  = "Secondary cluster Circonus API CA certificate file"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckSpoolDir
+			longOpt      = "check-spool-dir"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_CHECK_SPOOL_DIR"
+			description  = "Directory to spool failed metric submissions, retried in order by subsequent submissions (empty disables)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckSpoolMaxAge
+			longOpt      = "check-spool-max-age"
+			defaultValue = defaults.CheckSpoolMaxAge
+			envVar       = release.ENVPREFIX + "_CHECK_SPOOL_MAX_AGE"
+			description  = "Spooled submissions older than max age are dropped"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyCheckSpoolMaxSize
+			longOpt      = "check-spool-max-size"
+			defaultValue = defaults.CheckSpoolMaxSize
+			envVar       = release.ENVPREFIX + "_CHECK_SPOOL_MAX_SIZE"
+			description  = "Oldest spooled submissions are dropped when the spool exceeds max size (e.g. 100MiB)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = config.KeyCheckProbeDisabled
+			longOpt     = "no-check-probe"
+			envVar      = release.ENVPREFIX + "_NO_CHECK_PROBE"
+			description = "Disable the startup broker connectivity probe"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaults.NoCheckProbe, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.NoCheckProbe)
 	}
 
 	//
@@ -420,8 +912,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -433,8 +925,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.SSLCertFile, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.SSLCertFile)
 	}
 
@@ -447,11 +939,25 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.SSLKeyFile, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.SSLKeyFile)
 	}
 
+	{
+		const (
+			key          = config.KeySSLClientCAFile
+			longOpt      = "ssl-client-ca-file"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_SSL_CLIENT_CA_FILE"
+			description  = "SSL CA file used to verify client certificates - setting requires client certificates"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
 	{
 		const (
 			key         = config.KeySSLVerify
@@ -461,8 +967,8 @@ func init() {
 		)
 
 		RootCmd.Flags().Bool(longOpt, defaults.SSLVerify, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.SSLVerify)
 	}
 
@@ -478,8 +984,8 @@ func init() {
 		)
 
 		RootCmd.Flags().Bool(longOpt, defaults.NoStatsd, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.NoStatsd)
 	}
 
@@ -492,11 +998,237 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdPort, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdPort)
 	}
 
+	{
+		const (
+			key         = config.KeyStatsdAggregationWindow
+			longOpt     = "statsd-aggregation-window"
+			envVar      = release.ENVPREFIX + "_STATSD_AGGREGATION_WINDOW"
+			description = "StatsD window to aggregate counter and gauge updates before applying them (e.g. 1s, empty disables)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdAggregationWindow, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdAggregationWindow)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdCounterMode
+			longOpt     = "statsd-counter-mode"
+			envVar      = release.ENVPREFIX + "_STATSD_COUNTER_MODE"
+			description = "StatsD host counters reported as counts, per second rates over the flush interval, or both (count|rate|both)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdCounterMode, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdCounterMode)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdGaugeTTL
+			longOpt     = "statsd-gauge-ttl"
+			envVar      = release.ENVPREFIX + "_STATSD_GAUGE_TTL"
+			description = "StatsD gauges not updated within the ttl are no longer reported (e.g. 5m, empty disables)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdGaugeTTL, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdGaugeTTL)
+	}
+
+	{
+		const (
+			key          = config.KeyStatsdQueueSize
+			longOpt      = "statsd-queue-size"
+			defaultValue = defaults.StatsdQueueSize
+			envVar       = release.ENVPREFIX + "_STATSD_QUEUE_SIZE"
+			description  = "StatsD number of received packets queued for processing"
+		)
+
+		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyStatsdQueueMetrics
+			longOpt      = "statsd-queue-metrics"
+			defaultValue = false
+			envVar       = release.ENVPREFIX + "_STATSD_QUEUE_METRICS"
+			description  = "StatsD report packet queue depth host gauges (_queue_depth_max, _queue_depth_avg, _queue_depth_high_water) each collection"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyStatsdRateLimit
+			longOpt      = "statsd-rate-limit"
+			defaultValue = defaults.StatsdRateLimit
+			envVar       = release.ENVPREFIX + "_STATSD_RATE_LIMIT"
+			description  = "StatsD packets per second accepted from a single source ip, excess packets are dropped (0 disables)"
+		)
+
+		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyStatsdRateBurst
+			longOpt      = "statsd-rate-burst"
+			defaultValue = defaults.StatsdRateBurst
+			envVar       = release.ENVPREFIX + "_STATSD_RATE_BURST"
+			description  = "StatsD maximum burst of packets accepted from a single source ip (0 uses the rate limit)"
+		)
+
+		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdTimerPercentiles
+			longOpt     = "statsd-timer-percentiles"
+			envVar      = release.ENVPREFIX + "_STATSD_TIMER_PERCENTILES"
+			description = "StatsD percentiles computed from host timers each flush and reported as gauges <name>.p<N> (e.g. 50,90,95,99)"
+		)
+
+		RootCmd.Flags().StringSlice(longOpt, []string{}, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdTimerPercentilesOnly
+			longOpt     = "statsd-timer-percentiles-only"
+			envVar      = release.ENVPREFIX + "_STATSD_TIMER_PERCENTILES_ONLY"
+			description = "StatsD report only the percentiles of host timers, not the histogram (requires --statsd-timer-percentiles)"
+		)
+
+		RootCmd.Flags().Bool(longOpt, false, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdInvalidChars
+			longOpt     = "statsd-invalid-chars"
+			envVar      = release.ENVPREFIX + "_STATSD_INVALID_CHARS"
+			description = "StatsD handling of invalid characters in metric names and set values (sanitize|reject)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdInvalidChars, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdInvalidChars)
+	}
+
+	{
+		const (
+			key          = config.KeyStatsdSetDelimiter
+			longOpt      = "statsd-set-delimiter"
+			defaultValue = defaults.StatsdSetDelimiter
+			envVar       = release.ENVPREFIX + "_STATSD_SET_DELIMITER"
+			description  = "StatsD string joining set names and members, occurrences in set members are replaced with '_' (or rejected)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyStatsdSetMaxLength
+			longOpt      = "statsd-set-max-length"
+			defaultValue = defaults.StatsdSetMaxLength
+			envVar       = release.ENVPREFIX + "_STATSD_SET_MAX_LENGTH"
+			description  = "StatsD maximum set member length, longer members are truncated with a hash of the member appended (0 disables)"
+		)
+
+		RootCmd.Flags().Int(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdStateFile
+			longOpt     = "statsd-state-file"
+			envVar      = release.ENVPREFIX + "_STATSD_STATE_FILE"
+			description = "StatsD file host counters and gauges are saved to on shutdown and restored from on startup (empty disables)"
+		)
+
+		RootCmd.Flags().String(longOpt, "", desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdStateMaxAge
+			longOpt     = "statsd-state-max-age"
+			envVar      = release.ENVPREFIX + "_STATSD_STATE_MAX_AGE"
+			description = "StatsD saved state older than max age is discarded on startup"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdStateMaxAge, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdStateMaxAge)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdStrictLines
+			longOpt     = "statsd-strict-lines"
+			envVar      = release.ENVPREFIX + "_STATSD_STRICT_LINES"
+			description = "StatsD split packets strictly on newlines, without trimming whitespace (e.g. carriage returns) or skipping blank lines"
+		)
+
+		RootCmd.Flags().Bool(longOpt, false, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdZeroCounter
+			longOpt     = "statsd-zero-counter"
+			envVar      = release.ENVPREFIX + "_STATSD_ZERO_COUNTER"
+			description = "StatsD handling of counters with a value of 0 (zero|drop|one)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdZeroCounter, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdZeroCounter)
+	}
+
 	{
 		const (
 			key         = config.KeyStatsdHostPrefix
@@ -506,8 +1238,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdHostPrefix, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdHostPrefix)
 	}
 
@@ -520,11 +1252,25 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdHostCategory, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdHostCategory)
 	}
 
+	{
+		const (
+			key         = config.KeyStatsdHostTag
+			longOpt     = "statsd-host-tag"
+			envVar      = release.ENVPREFIX + "_STATSD_HOST_TAG"
+			description = "StatsD tag identifying the originating host (e.g. host), tagged metrics are host metrics per originating host"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdHostTag, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdHostTag)
+	}
+
 	{
 		const (
 			key          = config.KeyStatsdGroupCID
@@ -535,8 +1281,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 	}
 
 	{
@@ -548,11 +1294,39 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdGroupPrefix, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdGroupPrefix)
 	}
 
+	{
+		const (
+			key         = config.KeyStatsdGroupTag
+			longOpt     = "statsd-group-tag"
+			envVar      = release.ENVPREFIX + "_STATSD_GROUP_TAG"
+			description = "StatsD tag (category:value) identifying group metrics when routing by tag, removed from the metric"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdGroupTag, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdGroupTag)
+	}
+
+	{
+		const (
+			key         = config.KeyStatsdRouting
+			longOpt     = "statsd-routing"
+			envVar      = release.ENVPREFIX + "_STATSD_ROUTING"
+			description = "StatsD host/group routing (prefix|tag|both)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdRouting, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdRouting)
+	}
+
 	{
 		const (
 			key         = config.KeyStatsdGroupCounters
@@ -562,8 +1336,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdGroupCounters, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdGroupCounters)
 	}
 
@@ -576,8 +1350,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdGroupGauges, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdGroupGauges)
 	}
 
@@ -590,11 +1364,25 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.StatsdGroupSets, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.StatsdGroupSets)
 	}
 
+	{
+		const (
+			key         = config.KeyStatsdGroupInterval
+			longOpt     = "statsd-group-interval"
+			envVar      = release.ENVPREFIX + "_STATSD_GROUP_INTERVAL"
+			description = "StatsD group metric submission interval"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.StatsdGroupInterval, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.StatsdGroupInterval)
+	}
+
 	// Miscellenous
 
 	{
@@ -606,11 +1394,108 @@ func init() {
 		)
 
 		RootCmd.Flags().Bool(longOpt, defaults.DisableGzip, description)
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.DisableGzip)
 	}
 
+	{
+		const (
+			key         = config.KeyServerDisableWrite
+			longOpt     = "no-write"
+			envVar      = release.ENVPREFIX + "_NO_WRITE"
+			description = "Reject metrics pushed to the HTTP listener(s) (/write, /prom), socket listener(s) are not affected"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaults.ServerDisableWrite, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ServerDisableWrite)
+	}
+
+	{
+		const (
+			key         = config.KeyServerWriteStrict
+			longOpt     = "write-strict"
+			envVar      = release.ENVPREFIX + "_WRITE_STRICT"
+			description = "Reject /write requests containing metrics with an unsupported type or invalid value"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaults.ServerWriteStrict, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ServerWriteStrict)
+	}
+
+	{
+		const (
+			key          = config.KeyServerAuthToken
+			longOpt      = "auth-token"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_AUTH_TOKEN"
+			description  = "Require 'Authorization: Bearer <token>' on HTTP requests"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyServerAuthUser
+			longOpt      = "auth-user"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_AUTH_USER"
+			description  = "Require HTTP basic auth with this user on HTTP requests"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyServerAuthPassword
+			longOpt      = "auth-password"
+			defaultValue = ""
+			envVar       = release.ENVPREFIX + "_AUTH_PASSWORD"
+			description  = "Password for HTTP basic auth user"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyServerCORSOrigins
+			longOpt     = "cors-origins"
+			envVar      = release.ENVPREFIX + "_CORS_ORIGINS"
+			description = "Origins allowed to make cross-origin (CORS) HTTP requests (e.g. http://localhost:8080), '*' allows any origin"
+		)
+
+		RootCmd.Flags().StringSlice(longOpt, []string{}, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key         = config.KeyServerShutdownTimeout
+			longOpt     = "shutdown-timeout"
+			envVar      = release.ENVPREFIX + "_SHUTDOWN_TIMEOUT"
+			description = "Time to wait for in-flight HTTP requests to complete on shutdown (e.g. 30s)"
+		)
+
+		RootCmd.Flags().String(longOpt, defaults.ServerShutdownTimeout, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaults.ServerShutdownTimeout)
+	}
+
 	{
 		const (
 			key         = config.KeyDebug
@@ -621,8 +1506,8 @@ func init() {
 		)
 
 		RootCmd.Flags().BoolP(longOpt, shortOpt, defaults.Debug, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.Debug)
 	}
 
@@ -636,8 +1521,8 @@ func init() {
 		)
 
 		RootCmd.Flags().Bool(longOpt, defaultValue, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaultValue)
 	}
 
@@ -650,8 +1535,38 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, "", desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+	}
+
+	{
+		const (
+			key          = config.KeyDebugPprof
+			longOpt      = "debug-pprof"
+			defaultValue = defaults.DebugPprof
+			envVar       = release.ENVPREFIX + "_DEBUG_PPROF"
+			description  = "Enable pprof server (/debug/pprof/)"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = config.KeyDebugPprofListen
+			longOpt      = "debug-pprof-listen"
+			defaultValue = defaults.DebugPprofListen
+			envVar       = release.ENVPREFIX + "_DEBUG_PPROF_LISTEN"
+			description  = "pprof server listen address [IP]:[PORT]"
+		)
+
+		RootCmd.Flags().String(longOpt, defaultValue, desc(description, envVar))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
+		viper.SetDefault(key, defaultValue)
 	}
 
 	{
@@ -663,8 +1578,8 @@ func init() {
 		)
 
 		RootCmd.Flags().String(longOpt, defaults.LogLevel, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.LogLevel)
 	}
 
@@ -677,14 +1592,14 @@ func init() {
 		)
 
 		RootCmd.Flags().Bool(longOpt, defaults.LogPretty, desc(description, envVar))
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
-		viper.BindEnv(key, envVar)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindEnv(key, envVar)
 		viper.SetDefault(key, defaults.LogPretty)
 	}
 
 	// RootCmd.Flags().Bool("watch", defaults.Watch, "Watch plugins, reload on change")
 	// viper.SetDefault("watch", defaults.Watch)
-	// viper.BindPFlag("watch", RootCmd.Flags().Lookup("watch"))
+	// config.BindPFlag("watch", RootCmd.Flags().Lookup("watch"))
 
 	{
 		const (
@@ -695,18 +1610,42 @@ func init() {
 			description  = "Show version and exit"
 		)
 		RootCmd.Flags().BoolP(longOpt, shortOpt, defaultValue, description)
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
 	}
 
 	{
 		const (
 			key         = config.KeyShowConfig
 			longOpt     = "show-config"
-			description = "Show config (json|toml|yaml) and exit"
+			description = "Show config (json|toml|yaml|sources) and exit"
 		)
 
 		RootCmd.Flags().String(longOpt, "", description)
-		viper.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+	}
+
+	{
+		const (
+			key          = config.KeyOneshot
+			longOpt      = "oneshot"
+			defaultValue = false
+			description  = "Collect metrics once, submit them to the check (--check-id, httptrap) and exit (non-zero if submission fails)"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, description)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
+	}
+
+	{
+		const (
+			key          = config.KeyValidate
+			longOpt      = "validate"
+			defaultValue = false
+			description  = "Validate configuration, report all problems found, and exit (non-zero if invalid)"
+		)
+
+		RootCmd.Flags().Bool(longOpt, defaultValue, description)
+		config.BindPFlag(key, RootCmd.Flags().Lookup(longOpt))
 	}
 }
 
@@ -773,10 +1712,9 @@ func initConfig() {
 
 	viper.AutomaticEnv()
 
-	if err := viper.ReadInConfig(); err != nil {
-		f := viper.ConfigFileUsed()
-		if f != "" {
-			log.Fatal().Err(err).Str("config_file", f).Msg("Unable to load config file")
+	if err := config.ReadConfig(); err != nil {
+		if _, notFound := err.(viper.ConfigFileNotFoundError); !notFound {
+			log.Fatal().Err(err).Str("config_file", viper.ConfigFileUsed()).Msg("Unable to load config file")
 		}
 	}
 }
@@ -7,20 +7,32 @@ package agent
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins"
 	"github.com/circonus-labs/circonus-agent/internal/check"
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/inherit"
 	"github.com/circonus-labs/circonus-agent/internal/plugins"
 	"github.com/circonus-labs/circonus-agent/internal/release"
 	"github.com/circonus-labs/circonus-agent/internal/reverse"
 	"github.com/circonus-labs/circonus-agent/internal/server"
 	"github.com/circonus-labs/circonus-agent/internal/statsd"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
+// restartTimeout is how long a graceful restart waits for the new agent to be ready
+const restartTimeout = 1 * time.Minute
+
+// annotationTimeout is how long posting a lifecycle annotation may delay
+// startup, shutdown or a reload
+const annotationTimeout = 10 * time.Second
+
 // New returns a new agent instance
 func New() (*Agent, error) {
 	var err error
@@ -36,16 +48,25 @@ func New() (*Agent, error) {
 		return nil, err
 	}
 
-	a.builtins, err = builtins.New()
+	// before any listeners are created, a second agent fails here rather
+	// than with conflicting addresses
+	a.pidFile, err = acquirePIDFile()
 	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		if err != nil {
+			a.pidFile.release()
+		}
+	}()
 
-	a.plugins, err = plugins.New(a.t.Context(context.Background()))
+	a.builtins, err = builtins.New()
 	if err != nil {
 		return nil, err
 	}
-	if err = a.plugins.Scan(a.builtins); err != nil {
+
+	a.plugins, err = plugins.New(a.t.Context(context.Background()))
+	if err != nil {
 		return nil, err
 	}
 
@@ -54,10 +75,22 @@ func New() (*Agent, error) {
 		return nil, err
 	}
 
+	// the check is created (or found) before the plugins are scanned so
+	// the plugin environment includes the check bundle id
 	a.check, err = check.New(nil)
 	if err != nil {
 		return nil, err
 	}
+	a.check.SetMetricMetaSource(a.plugins)
+	a.plugins.SetCheckID(a.check.CID())
+
+	// statsd format fifo plugins are started by Scan
+	if a.statsdServer.Enabled() {
+		a.plugins.SetStatsdReceiver(a.statsdServer)
+	}
+	if err = a.plugins.Scan(a.builtins); err != nil {
+		return nil, err
+	}
 
 	a.listenServer, err = server.New(a.check, a.builtins, a.plugins, a.statsdServer)
 	if err != nil {
@@ -72,42 +105,152 @@ func New() (*Agent, error) {
 	if err != nil {
 		return nil, err
 	}
+	a.listenServer.SetReverseStatus(a.reverseConn)
 
 	a.signalNotifySetup()
 
+	a.logStartupSummary()
+
 	return &a, nil
 }
 
-// Start the agent
+// Start the agent, as a service if started by the windows service control
+// manager. Blocks until the agent has stopped (see Wait).
 func (a *Agent) Start() error {
+	return runService(a)
+}
+
+// run starts the agent components, signals the service manager once the
+// agent is ready (see ready) and waits for the agent to stop
+func (a *Agent) run() error {
+	// listeners must exist before inherit.Ready, unclaimed inherited
+	// listeners are closed (statsd and socket listeners are created in New)
+	if err := a.listenServer.Listen(); err != nil {
+		return err
+	}
+
 	go a.handleSignals()
 
+	a.plugins.Watch(a.builtins)
+
 	a.t.Go(a.statsdServer.Start)
 	a.t.Go(a.reverseConn.Start)
 	a.t.Go(a.listenServer.Start)
 
+	// signal the previous agent process, if this is a graceful restart
+	inherit.Ready()
+
+	a.annotate(check.AnnotationStart)
+
+	a.ready()
+
 	log.Debug().
 		Int("pid", os.Getpid()).
 		Str("name", release.NAME).
 		Str("ver", release.VERSION).Msg("Starting wait")
 
+	return a.Wait()
+}
+
+// restart starts a new agent process (the current, possibly upgraded,
+// executable) passing it the listeners, and stops this process once the new
+// one is ready. If the new process fails to start, this process continues.
+func (a *Agent) restart() {
+	pid, err := inherit.Restart(restartTimeout)
+	if err != nil {
+		log.Error().Err(err).Msg("graceful restart failed, continuing")
+		return
+	}
+	log.Info().Int("new_pid", pid).Msg("graceful restart, new agent running, stopping")
+	a.Stop()
+}
+
+// Wait blocks until the agent has stopped, returning the first error from a
+// running component or the aggregated shutdown error from Stop
+func (a *Agent) Wait() error {
 	return a.t.Wait()
 }
 
-// Stop cleans up and shuts down the Agent
-func (a *Agent) Stop() {
+// Stop cleans up and shuts down the Agent. Every component is stopped, even
+// if an earlier one fails; failures are logged and returned as a single error
+// (which is also the error returned by Wait, resulting in a non-zero exit).
+func (a *Agent) Stop() error {
 	a.stopSignalHandler()
-	a.plugins.Stop()
-	a.statsdServer.Stop()
-	a.reverseConn.Stop()
-	a.listenServer.Stop()
 
-	a.t.Kill(nil)
+	notifyStopping()
+
+	a.annotate(check.AnnotationStop)
+
+	var errs []string
+	stop := func(name string, stopFn func() error) {
+		if err := stopFn(); err != nil {
+			log.Error().Err(err).Str("component", name).Msg("stopping")
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	stop("plugins", a.plugins.Stop)
+	stop("statsd", a.statsdServer.Stop)
+	stop("reverse", func() error {
+		a.reverseConn.Stop()
+		return nil
+	})
+	stop("server", a.listenServer.Stop)
+
+	a.pidFile.release()
+	a.pidFile = nil
+
+	var err error
+	if len(errs) > 0 {
+		err = errors.Errorf("shutdown: %s", strings.Join(errs, "; "))
+	}
+
+	a.t.Kill(err)
 
 	log.Debug().
 		Int("pid", os.Getpid()).
 		Str("name", release.NAME).
-		Str("ver", release.VERSION).Msg("Stopped")
+		Str("ver", release.VERSION).
+		Bool("clean", err == nil).Msg("Stopped")
+
+	return err
+}
+
+// ready signals the service manager (systemd Type=notify, windows service
+// control manager) that the agent is ready: the listeners are up and a first
+// collection of the builtin collectors has completed. If the collection
+// fails, readiness is not signaled, the service manager's start timeout applies.
+func (a *Agent) ready() {
+	if err := a.builtins.Run(""); err != nil {
+		log.Error().Err(err).Msg("first collection failed, not signaling ready")
+		return
+	}
+
+	notifyReady()
+
+	log.Debug().Msg("ready")
+}
+
+// annotate posts a lifecycle annotation (if enabled), failures are logged,
+// an unresponsive API is abandoned after annotationTimeout
+func (a *Agent) annotate(event string) {
+	if a.check == nil {
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.check.Annotate(event)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Warn().Err(err).Str("event", event).Msg("posting annotation")
+		}
+	case <-time.After(annotationTimeout):
+		log.Warn().Str("event", event).Dur("timeout", annotationTimeout).Msg("posting annotation, timed out")
+	}
 }
 
 // stopSignalHandler disables the signal handler
@@ -6,6 +6,7 @@
 package agent
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/circonus-labs/circonus-agent/internal/config"
@@ -61,6 +62,56 @@ func TestStop(t *testing.T) {
 			t.Fatal("expected not nil")
 		}
 
+		if err := a.Stop(); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	t.Log("Testing Wait")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("clean shutdown")
+	{
+		viper.Set(config.KeyPluginDir, "testdata")
+		viper.Set(config.KeyStatsdDisabled, true)
+		a, err := New()
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		a.t.Go(func() error {
+			<-a.t.Dying()
+			return nil
+		})
+
+		if err := a.Stop(); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if err := a.Wait(); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	}
+
+	t.Log("component error")
+	{
+		viper.Set(config.KeyPluginDir, "testdata")
+		viper.Set(config.KeyStatsdDisabled, true)
+		a, err := New()
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+
+		expect := errors.New("boom")
+		a.t.Go(func() error {
+			<-a.t.Dying()
+			return expect
+		})
 		a.Stop()
+		if err := a.Wait(); err != expect {
+			t.Fatalf("expected (%s) got (%v)", expect, err)
+		}
 	}
 }
@@ -0,0 +1,69 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package agent
+
+import (
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// sdNotifySocketEnv is set by systemd for services with Type=notify
+const sdNotifySocketEnv = "NOTIFY_SOCKET"
+
+// notifyReady tells systemd the agent is ready (Type=notify)
+func notifyReady() {
+	sdNotify("READY=1")
+}
+
+// notifyReloading tells systemd the configuration is being reloaded,
+// followed by notifyReady once reloaded
+func notifyReloading() {
+	sdNotify("RELOADING=1")
+}
+
+// notifyStopping tells systemd the agent is stopping
+func notifyStopping() {
+	sdNotify("STOPPING=1")
+}
+
+// sdNotify sends a state change to the systemd notification socket, if the
+// agent is running as a systemd service. Failures are logged, they do not
+// affect the agent.
+func sdNotify(state string) {
+	if err := sendNotify(os.Getenv(sdNotifySocketEnv), state); err != nil {
+		log.Warn().Err(err).Str("state", state).Msg("systemd notify")
+	}
+}
+
+// sendNotify sends state to the notification socket, nothing is sent if
+// socket is empty. A leading '@' denotes an abstract socket.
+func sendNotify(socket, state string) error {
+	if socket == "" {
+		return nil
+	}
+
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if socket[0] == '@' {
+		addr.Name = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return errors.Wrap(err, "connecting to notify socket")
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return errors.Wrap(err, "sending notification")
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package agent
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSendNotify(t *testing.T) {
+	t.Log("Testing sendNotify")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tno socket")
+	{
+		if err := sendNotify("", "READY=1"); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("\tmissing socket")
+	{
+		if err := sendNotify(filepath.Join("testdata", "missing.sock"), "READY=1"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tsocket")
+	{
+		dir, err := ioutil.TempDir("", "notify")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer os.RemoveAll(dir)
+
+		socket := filepath.Join(dir, "notify.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer conn.Close()
+
+		if err := sendNotify(socket, "READY=1"); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		buf := make([]byte, 64)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if string(buf[:n]) != "READY=1" {
+			t.Fatalf("expected (READY=1) got (%s)", string(buf[:n]))
+		}
+	}
+
+	t.Log("\tabstract socket")
+	{
+		name := "@circonus-agent-notify-test"
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: "\x00" + name[1:], Net: "unixgram"})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer conn.Close()
+
+		if err := sendNotify(name, "STOPPING=1"); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		buf := make([]byte, 64)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if string(buf[:n]) != "STOPPING=1" {
+			t.Fatalf("expected (STOPPING=1) got (%s)", string(buf[:n]))
+		}
+	}
+}
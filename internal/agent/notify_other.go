@@ -0,0 +1,17 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !linux,!windows
+
+package agent
+
+// launchd (and the service managers of the BSDs and Solaris) have no
+// readiness protocol, a service is running once started. KeepAlive (restart
+// unless the agent exited cleanly) relies on the agent exiting with a
+// non-zero status when it stops because of an error.
+
+func notifyReady()     {}
+func notifyReloading() {}
+func notifyStopping()  {}
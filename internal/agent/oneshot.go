@@ -0,0 +1,102 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"context"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/plugins"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Oneshot runs all builtins and plugins once, submits the metrics directly
+// to the check and returns. The listeners (server, statsd) and the reverse
+// connection are not started. An error is returned if submission fails.
+func Oneshot() error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	b, err := builtins.New()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p, err := plugins.New(ctx)
+	if err != nil {
+		return err
+	}
+
+	c, err := check.New(nil)
+	if err != nil {
+		return err
+	}
+	c.SetMetricMetaSource(p)
+	p.SetCheckID(c.CID())
+
+	// no initial run, the plugins are run (and waited for) below
+	if err = p.Load(b); err != nil {
+		return err
+	}
+
+	if err := oneshot(b, p, c); err != nil {
+		return err
+	}
+
+	return p.Stop()
+}
+
+// oneshotCheck is where one-shot metrics are submitted (check.Check)
+type oneshotCheck interface {
+	EnableNewMetrics(m *cgm.Metrics) error
+	SubmitMetrics(m *cgm.Metrics) error
+	MirrorMetrics(m *cgm.Metrics)
+}
+
+// oneshot runs the builtins and plugins, waiting for them to finish, and
+// submits their metrics to the check
+func oneshot(b *builtins.Builtins, p *plugins.Plugins, c oneshotCheck) error {
+	metrics := cgm.Metrics{}
+
+	// NOTE: errors from Run are already logged, individual builtin or
+	//       plugin failures do not prevent submitting the remaining metrics
+	b.Run("")
+	for metricName, metric := range *b.Flush("") {
+		metrics[metricName] = metric
+	}
+
+	p.Run("")
+	for metricName, metric := range *p.Flush("") {
+		metrics[metricName] = metric
+	}
+
+	if len(metrics) == 0 {
+		log.Warn().Msg("oneshot, no metrics collected")
+	}
+
+	if err := c.EnableNewMetrics(&metrics); err != nil {
+		log.Warn().Err(err).Msg("unable to update check metrics")
+	}
+
+	// the secondary (HA) check, if configured, is independent of the primary
+	err := c.SubmitMetrics(&metrics)
+	c.MirrorMetrics(&metrics)
+	if err != nil {
+		return errors.Wrap(err, "oneshot")
+	}
+
+	log.Info().Int("metrics", len(metrics)).Msg("oneshot, submitted")
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/plugins"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// submitRecorder records the metrics submitted by oneshot
+type submitRecorder struct {
+	submitted *cgm.Metrics
+}
+
+func (s *submitRecorder) EnableNewMetrics(m *cgm.Metrics) error { return nil }
+func (s *submitRecorder) SubmitMetrics(m *cgm.Metrics) error {
+	s.submitted = m
+	return nil
+}
+func (s *submitRecorder) MirrorMetrics(m *cgm.Metrics) {}
+
+func TestOneshot(t *testing.T) {
+	t.Log("Testing oneshot")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "oneshot")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// output arrives after a delay, submission must wait for it
+	script := "#!/bin/sh\nsleep 1\nprintf 'delayed\\tn\\t1\\n'\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "slow.sh"), []byte(script), 0755); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, dir)
+
+	b, err := builtins.New()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	p, err := plugins.New(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if err := p.Load(b); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	c := &submitRecorder{}
+	if err := oneshot(b, p, c); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if c.submitted == nil {
+		t.Fatal("expected metrics to be submitted")
+	}
+	if _, ok := (*c.submitted)["slow`delayed"]; !ok {
+		t.Fatalf("expected plugin metric slow`delayed, got %#v", *c.submitted)
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,104 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/inherit"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// pidFile is the agent's pid file, locked while the agent is running so a
+// second agent using the same pid file refuses to start
+type pidFile struct {
+	f    *os.File
+	path string
+}
+
+var (
+	errPIDFileLocked   = errors.New("locked")
+	errLockUnsupported = errors.New("locking not supported")
+)
+
+// acquirePIDFile locks the pid file (if configured) and writes the agent's
+// pid to it. After a graceful restart the pid file, still locked, is
+// inherited from the previous agent process.
+func acquirePIDFile() (*pidFile, error) {
+	path := viper.GetString(config.KeyPIDFile)
+	if path == "" {
+		return nil, nil
+	}
+	name := "pidfile:" + path
+
+	f := inherit.InheritFile(name)
+	if f == nil {
+		var err error
+		f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening pid file")
+		}
+
+		if err := lockFile(f); err != nil {
+			switch err {
+			case errLockUnsupported:
+				log.Warn().Str("pid_file", path).Msg("pid file locking not supported, not checking for another agent")
+			case errPIDFileLocked:
+				f.Close()
+				pid := "unknown"
+				if data, rerr := ioutil.ReadFile(path); rerr == nil && len(strings.TrimSpace(string(data))) > 0 {
+					pid = strings.TrimSpace(string(data))
+				}
+				return nil, errors.Errorf("another agent is already running (pid file %s locked by pid %s)", path, pid)
+			default:
+				f.Close()
+				return nil, errors.Wrap(err, "locking pid file")
+			}
+		}
+	}
+
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "writing pid file")
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "writing pid file")
+	}
+
+	p := &pidFile{f: f, path: path}
+
+	// the new agent process holds the lock through its copy of the file
+	inherit.PassFile(name, func() (*os.File, error) {
+		return dupFile(p.f)
+	})
+
+	return p, nil
+}
+
+// release removes the pid file and releases the lock. If the pid file now
+// belongs to a new agent process (graceful restart), which shares the lock,
+// it is left in place.
+func (p *pidFile) release() {
+	if p == nil {
+		return
+	}
+	defer p.f.Close() // releases the lock, unless shared with a new agent process
+
+	data, err := ioutil.ReadFile(p.path)
+	if err == nil && strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		return
+	}
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		log.Warn().Err(err).Str("pid_file", p.path).Msg("removing pid file")
+	}
+}
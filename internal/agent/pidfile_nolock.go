@@ -0,0 +1,15 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows solaris
+
+package agent
+
+import "os"
+
+// lockFile is not supported, the pid file is written without a lock
+func lockFile(f *os.File) error {
+	return errLockUnsupported
+}
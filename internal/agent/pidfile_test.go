@@ -0,0 +1,104 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows,!solaris
+
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestPIDFile(t *testing.T) {
+	t.Log("Testing pid file")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "pidfile")
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.pid")
+	pid := strconv.Itoa(os.Getpid())
+
+	t.Log("disabled")
+	{
+		viper.Reset()
+		p, err := acquirePIDFile()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if p != nil {
+			t.Fatal("expected nil")
+		}
+		p.release() // nil safe
+	}
+
+	viper.Reset()
+	viper.Set(config.KeyPIDFile, path)
+
+	t.Log("acquire")
+	p, err := acquirePIDFile()
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if strings.TrimSpace(string(data)) != pid {
+		t.Fatalf("expected pid %s, got (%s)", pid, string(data))
+	}
+
+	t.Log("second agent")
+	{
+		if _, err := acquirePIDFile(); err == nil {
+			t.Fatal("expected error")
+		} else if !strings.Contains(err.Error(), "another agent is already running") || !strings.Contains(err.Error(), "pid "+pid) {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("release")
+	{
+		p.release()
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected pid file removed, got (%v)", err)
+		}
+	}
+
+	t.Log("release, restarted (pid file belongs to new agent)")
+	{
+		p, err := acquirePIDFile()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if err := ioutil.WriteFile(path, []byte("1\n"), 0644); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		p.release()
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected pid file kept, got (%s)", err)
+		}
+		// lock released with the last copy of the file
+		p, err = acquirePIDFile()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		p.release()
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,24 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package agent
+
+import (
+	"os"
+	"syscall"
+)
+
+// dupFile returns a duplicate of the file, sharing its lock, to pass to a
+// new agent process on restart
+func dupFile(f *os.File) (*os.File, error) {
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(fd) // only passed to the new agent process (not plugins)
+	return os.NewFile(uintptr(fd), f.Name()), nil
+}
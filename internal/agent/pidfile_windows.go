@@ -0,0 +1,19 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package agent
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// dupFile is not supported, graceful restart is not supported on windows
+func dupFile(f *os.File) (*os.File, error) {
+	return nil, errors.New("not supported on windows")
+}
@@ -0,0 +1,187 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// restartSettings are settings which are only applied when the
+// agent starts, changes are logged on reload but not applied
+var restartSettings = []string{
+	config.KeyAPICAFile,
+	config.KeyAPITokenApp,
+	config.KeyAPITokenKey,
+	config.KeyAPIURL,
+	config.KeyCheckAnnotationCategory,
+	config.KeyCheckAnnotations,
+	config.KeyCheckBroker,
+	config.KeyCheckBundleID,
+	config.KeyCheckBundleTemplate,
+	config.KeyCheckCreate,
+	config.KeyCheckEnableNewMetrics,
+	config.KeyCheckForceEnableMetrics,
+	config.KeyCheckMetricFilters,
+	config.KeyCheckMetricRefreshTTL,
+	config.KeyCheckMetricStateDir,
+	config.KeyCheckMetricTypes,
+	config.KeyCheckProbeDisabled,
+	config.KeyCheckSearchSelect,
+	config.KeyCheckSearchTags,
+	config.KeyCheckSecondaryAPICAFile,
+	config.KeyCheckSecondaryAPIApp,
+	config.KeyCheckSecondaryAPIKey,
+	config.KeyCheckSecondaryAPIURL,
+	config.KeyCheckSecondaryBundleID,
+	config.KeyCheckSpoolDir,
+	config.KeyCheckSpoolMaxAge,
+	config.KeyCheckSpoolMaxSize,
+	config.KeyCheckTags,
+	config.KeyCheckTarget,
+	config.KeyCheckTitle,
+	config.KeyDebug,
+	config.KeyDebugCGM,
+	config.KeyDebugDumpMetrics,
+	config.KeyDebugPprof,
+	config.KeyDebugPprofListen,
+	config.KeyDirect,
+	config.KeyDirectInterval,
+	config.KeyDisableGzip,
+	config.KeyListen,
+	config.KeyListenSocket,
+	config.KeyLogLevel,
+	config.KeyLogPretty,
+	config.KeyPIDFile,
+	config.KeyPluginDir,
+	config.KeyPluginTTLUnits,
+	config.KeyPluginWatch,
+	config.KeyReverse,
+	config.KeyReverseBrokerCAFile,
+	config.KeyReverseClientCertFile,
+	config.KeyReverseClientKeyFile,
+	config.KeyReverseCommandTimeout,
+	config.KeyReverseCompression,
+	config.KeyReverseConnectJitter,
+	config.KeyReverseLatencyInterval,
+	config.KeyReverseMaxConnRetry,
+	config.KeyReverseMaxFrameSize,
+	config.KeyServerAuthPassword,
+	config.KeyServerAuthToken,
+	config.KeyServerAuthUser,
+	config.KeyServerCORSOrigins,
+	config.KeyServerDisableWrite,
+	config.KeyServerShutdownTimeout,
+	config.KeySSLCertFile,
+	config.KeySSLClientCAFile,
+	config.KeySSLKeyFile,
+	config.KeySSLListen,
+	config.KeySSLVerify,
+	config.KeyStatsdAggregationWindow,
+	config.KeyStatsdCounterMode,
+	config.KeyStatsdDisabled,
+	config.KeyStatsdGaugeTTL,
+	config.KeyStatsdGroupCID,
+	config.KeyStatsdGroupCounters,
+	config.KeyStatsdGroupGauges,
+	config.KeyStatsdGroupInterval,
+	config.KeyStatsdGroupPrefix,
+	config.KeyStatsdGroupSets,
+	config.KeyStatsdGroupTag,
+	config.KeyStatsdHostCategory,
+	config.KeyStatsdHostPrefix,
+	config.KeyStatsdHostTag,
+	config.KeyStatsdInvalidChars,
+	config.KeyStatsdMetricFilters,
+	config.KeyStatsdPort,
+	config.KeyStatsdQueueMetrics,
+	config.KeyStatsdQueueSize,
+	config.KeyStatsdRateBurst,
+	config.KeyStatsdRateLimit,
+	config.KeyStatsdRouting,
+	config.KeyStatsdSetDelimiter,
+	config.KeyStatsdSetMaxLength,
+	config.KeyStatsdStateFile,
+	config.KeyStatsdStateMaxAge,
+	config.KeyStatsdStrictLines,
+	config.KeyStatsdTimerPercentiles,
+	config.KeyStatsdTimerPercentilesOnly,
+	config.KeyStatsdZeroCounter,
+}
+
+// Reload re-reads the configuration file and applies the settings which
+// can be changed while running: builtin collectors, plugins (re-scanning
+// the plugin directory), and the check configuration. The reverse connection
+// and listeners are not touched, changes to settings which require a restart
+// are logged.
+func (a *Agent) Reload() error {
+	log.Info().Msg("Reloading configuration")
+
+	notifyReloading()
+	defer notifyReady()
+
+	before := settingsSnapshot(restartSettings)
+
+	if err := config.ReadConfig(); err != nil {
+		if _, notFound := err.(viper.ConfigFileNotFoundError); !notFound {
+			return errors.Wrapf(err, "reading config file %s", viper.ConfigFileUsed())
+		}
+		log.Debug().Err(err).Msg("no config file, continuing reload")
+	}
+
+	if err := config.Validate(); err != nil {
+		return errors.Wrap(err, "validating config")
+	}
+
+	for _, key := range changedSettings(before, settingsSnapshot(restartSettings)) {
+		log.Warn().Str("setting", key).Msg("changed, requires restart to apply")
+	}
+
+	if err := a.builtins.Reload(); err != nil {
+		return errors.Wrap(err, "reloading builtins")
+	}
+
+	if err := a.plugins.Reload(a.builtins); err != nil {
+		return errors.Wrap(err, "reloading plugins")
+	}
+
+	if err := a.check.RefreshCheckConfig(); err != nil {
+		return errors.Wrap(err, "refreshing check config")
+	}
+
+	log.Info().Msg("Configuration reloaded")
+
+	a.annotate(check.AnnotationReload)
+
+	return nil
+}
+
+// settingsSnapshot returns the current values of settings
+func settingsSnapshot(keys []string) map[string]string {
+	snap := make(map[string]string, len(keys))
+	for _, key := range keys {
+		snap[key] = fmt.Sprintf("%v", viper.Get(key))
+	}
+	return snap
+}
+
+// changedSettings returns the (sorted) keys with different values
+func changedSettings(before, after map[string]string) []string {
+	changed := []string{}
+	for key, val := range after {
+		if before[key] != val {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
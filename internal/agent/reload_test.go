@@ -0,0 +1,110 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestReload(t *testing.T) {
+	t.Log("Testing Reload")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, "testdata")
+	viper.Set(config.KeyStatsdDisabled, true)
+	a, err := New()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	t.Log("no config file")
+	{
+		if err := a.Reload(); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	}
+
+	t.Log("invalid config file")
+	{
+		f, err := ioutil.TempFile("", "reload")
+		if err != nil {
+			t.Fatalf("creating temp file (%s)", err)
+		}
+		defer os.Remove(f.Name())
+		f.WriteString(`{"plugin_timeout": `)
+		f.Close()
+		viper.SetConfigFile(f.Name())
+		viper.SetConfigType("json")
+		if err := a.Reload(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("valid config file")
+	{
+		f, err := ioutil.TempFile("", "reload")
+		if err != nil {
+			t.Fatalf("creating temp file (%s)", err)
+		}
+		defer os.Remove(f.Name())
+		f.WriteString(`{"plugin_timeout": "abc"}`)
+		f.Close()
+		viper.SetConfigFile(f.Name())
+		viper.SetConfigType("json")
+		if err := a.Reload(); err == nil {
+			t.Fatal("expected error (invalid plugin timeout)")
+		}
+
+		if err := ioutil.WriteFile(f.Name(), []byte(`{"plugin_timeout": "10s", "listen": [":2610"]}`), 0644); err != nil {
+			t.Fatalf("writing temp file (%s)", err)
+		}
+		if err := a.Reload(); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestChangedSettings(t *testing.T) {
+	t.Log("Testing changedSettings")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, []string{":2609"})
+	viper.Set(config.KeyReverse, false)
+	keys := []string{config.KeyListen, config.KeyReverse, config.KeySSLListen}
+
+	before := settingsSnapshot(keys)
+
+	t.Log("unchanged")
+	{
+		if changed := changedSettings(before, settingsSnapshot(keys)); len(changed) != 0 {
+			t.Fatalf("expected no changes, got %v", changed)
+		}
+	}
+
+	t.Log("changed")
+	{
+		viper.Set(config.KeyListen, []string{":2610"})
+		viper.Set(config.KeySSLListen, ":443")
+		changed := changedSettings(before, settingsSnapshot(keys))
+		if len(changed) != 2 || changed[0] != config.KeyListen || changed[1] != config.KeySSLListen {
+			t.Fatalf("expected [%s %s], got %v", config.KeyListen, config.KeySSLListen, changed)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,14 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package agent
+
+// runService runs the agent, the service manager (systemd, launchd, etc.)
+// starts the agent as a regular process
+func runService(a *Agent) error {
+	return a.run()
+}
@@ -0,0 +1,96 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package agent
+
+import (
+	"sync"
+
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/windows/svc"
+)
+
+var (
+	readyCh   = make(chan struct{})
+	readyOnce sync.Once
+)
+
+// notifyReady tells the service control manager the agent is running
+func notifyReady() {
+	readyOnce.Do(func() { close(readyCh) })
+}
+
+func notifyReloading() {}
+func notifyStopping()  {}
+
+// runService runs the agent, under the service control manager when
+// started as a windows service (the service name is the agent name)
+func runService(a *Agent) error {
+	interactive, err := svc.IsAnInteractiveSession()
+	if err != nil {
+		return errors.Wrap(err, "determining if running as a service")
+	}
+	if interactive {
+		return a.run()
+	}
+
+	ws := &winService{agent: a}
+	if err := svc.Run(release.NAME, ws); err != nil {
+		return errors.Wrap(err, "running service")
+	}
+	return ws.err
+}
+
+// winService handles service control requests
+type winService struct {
+	agent *Agent
+	err   error
+}
+
+// Execute runs the agent, reporting it as running once ready (see
+// notifyReady) and stopping it on a stop or shutdown request. A non-zero
+// exit code is reported if the agent stops because of an error, so the
+// service recovery actions apply.
+func (ws *winService) Execute(args []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown
+
+	status <- svc.Status{State: svc.StartPending}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ws.agent.run()
+	}()
+
+	ready := readyCh
+	for {
+		select {
+		case <-ready:
+			status <- svc.Status{State: svc.Running, Accepts: accepts}
+			ready = nil
+		case err := <-done:
+			ws.err = err
+			status <- svc.Status{State: svc.Stopped}
+			if err != nil {
+				return false, 1
+			}
+			return false, 0
+		case req := <-r:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				log.Info().Msg("service stop requested")
+				status <- svc.Status{State: svc.StopPending}
+				go ws.agent.Stop()
+			default:
+				log.Warn().Uint32("cmd", uint32(req.Cmd)).Msg("unexpected service control request")
+			}
+		}
+	}
+}
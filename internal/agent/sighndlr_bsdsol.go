@@ -22,7 +22,7 @@ import (
 )
 
 func (a *Agent) signalNotifySetup() {
-	signal.Notify(a.signalCh, os.Interrupt, unix.SIGTERM, unix.SIGHUP, unix.SIGPIPE, unix.SIGINFO)
+	signal.Notify(a.signalCh, os.Interrupt, unix.SIGTERM, unix.SIGHUP, unix.SIGPIPE, unix.SIGUSR1, unix.SIGUSR2, unix.SIGINFO)
 }
 
 // handleSignals runs the signal handler thread
@@ -41,8 +41,16 @@ func (a *Agent) handleSignals() error {
 			switch sig {
 			case os.Interrupt, unix.SIGTERM:
 				a.Stop()
-			case unix.SIGPIPE, unix.SIGHUP:
+			case unix.SIGHUP:
+				if err := a.Reload(); err != nil {
+					log.Error().Err(err).Msg("reloading configuration")
+				}
+			case unix.SIGPIPE:
 				// Noop
+			case unix.SIGUSR1:
+				a.dumpState()
+			case unix.SIGUSR2:
+				a.restart()
 			case unix.SIGINFO:
 				stacklen := runtime.Stack(buf, true)
 				fmt.Printf("=== received SIGINFO ===\n*** goroutine dump...\n%s\n*** end\n", buf[:stacklen])
@@ -41,7 +41,11 @@ func (a *Agent) handleSignals() error {
 			switch sig {
 			case os.Interrupt, syscall.SIGTERM:
 				a.Stop()
-			case syscall.SIGPIPE, syscall.SIGHUP:
+			case syscall.SIGHUP:
+				if err := a.Reload(); err != nil {
+					log.Error().Err(err).Msg("reloading configuration")
+				}
+			case syscall.SIGPIPE:
 				// Noop
 			case syscall.SIGTRAP:
 				stacklen := runtime.Stack(buf, true)
@@ -0,0 +1,51 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// stateReporter is implemented by subsystems included in the diagnostic state dump
+type stateReporter interface {
+	State() map[string]interface{}
+}
+
+// dumpState logs a diagnostic snapshot of each subsystem and the
+// effective configuration (secrets redacted), triggered by SIGUSR1
+func (a *Agent) dumpState() {
+	log.Info().Msg("=== diagnostic state dump ===")
+
+	subsystems := []struct {
+		name     string
+		reporter stateReporter
+	}{
+		{"builtins", a.builtins},
+		{"plugins", a.plugins},
+		{"reverse", a.reverseConn},
+		{"statsd", a.statsdServer},
+	}
+
+	for _, sub := range subsystems {
+		log.Info().
+			Str("subsystem", sub.name).
+			Interface("state", sub.reporter.State()).
+			Msg("diagnostic state")
+	}
+
+	cfg, err := config.RedactedConfig()
+	if err != nil {
+		log.Error().Err(err).Msg("diagnostic state, config")
+	} else {
+		log.Info().
+			Str("subsystem", "config").
+			Interface("state", cfg).
+			Msg("diagnostic state")
+	}
+
+	log.Info().Msg("=== end diagnostic state dump ===")
+}
@@ -0,0 +1,52 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestDumpState(t *testing.T) {
+	t.Log("Testing dumpState")
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, "testdata")
+	viper.Set(config.KeyStatsdDisabled, true)
+	viper.Set(config.KeyServerAuthToken, "secret-token")
+	a, err := New()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var buf bytes.Buffer
+	origLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+
+	a.dumpState()
+
+	log.Logger = origLogger
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	viper.Reset()
+
+	out := buf.String()
+	for _, expect := range []string{`"subsystem":"builtins"`, `"subsystem":"plugins"`, `"subsystem":"reverse"`, `"subsystem":"statsd"`, `"subsystem":"config"`} {
+		if !strings.Contains(out, expect) {
+			t.Fatalf("expected (%s) in (%s)", expect, out)
+		}
+	}
+	if strings.Contains(out, "secret-token") {
+		t.Fatalf("expected auth token to be redacted (%s)", out)
+	}
+}
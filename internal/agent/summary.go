@@ -0,0 +1,28 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/rs/zerolog/log"
+)
+
+// logStartupSummary logs a single structured event summarizing the agent
+// configuration once all subsystems are initialized, for fleet tooling to parse
+func (a *Agent) logStartupSummary() {
+	log.Info().
+		Str("name", release.NAME).
+		Str("version", release.VERSION).
+		Strs("collectors", a.builtins.Enabled()).
+		Int("plugins", a.plugins.Count()).
+		Bool("statsd_enabled", a.statsdServer.Enabled()).
+		Str("statsd_address", a.statsdServer.Address()).
+		Bool("reverse_enabled", a.reverseConn.Enabled()).
+		Str("reverse_broker", a.reverseConn.Broker()).
+		Str("check_cid", a.check.CID()).
+		Strs("listen", a.listenServer.ListenAddresses()).
+		Msg("startup summary")
+}
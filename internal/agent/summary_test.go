@@ -0,0 +1,61 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestLogStartupSummary(t *testing.T) {
+	t.Log("Testing logStartupSummary")
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, "testdata")
+	viper.Set(config.KeyStatsdDisabled, true)
+	a, err := New()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	var buf bytes.Buffer
+	origLogger := log.Logger
+	log.Logger = zerolog.New(&buf)
+
+	a.logStartupSummary()
+
+	log.Logger = origLogger
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	viper.Reset()
+
+	var summary map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil {
+		t.Fatalf("expected single json event, got %s (%s)", err, buf.String())
+	}
+
+	if summary["message"] != "startup summary" {
+		t.Fatalf("expected message 'startup summary', got %v", summary["message"])
+	}
+	for _, key := range []string{"collectors", "plugins", "statsd_enabled", "statsd_address", "reverse_enabled", "reverse_broker", "check_cid", "listen"} {
+		if _, ok := summary[key]; !ok {
+			t.Fatalf("expected (%s) in summary (%s)", key, buf.String())
+		}
+	}
+	if summary["statsd_enabled"] != false {
+		t.Fatalf("expected statsd_enabled false, got %v", summary["statsd_enabled"])
+	}
+	if summary["reverse_enabled"] != false {
+		t.Fatalf("expected reverse_enabled false, got %v", summary["reverse_enabled"])
+	}
+}
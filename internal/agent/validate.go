@@ -0,0 +1,52 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"context"
+	"os"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/plugins"
+	"github.com/circonus-labs/circonus-agent/internal/server"
+	"github.com/circonus-labs/circonus-agent/internal/statsd"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Validate exercises the same configuration paths used by New without
+// starting any listeners, connections or plugins. Every problem found is
+// returned. The API is not contacted, check configuration is not verified.
+func Validate() []error {
+	errs := config.ValidateAll()
+
+	b, err := builtins.New()
+	if err != nil {
+		errs = append(errs, errors.Wrap(err, "builtins"))
+	} else {
+		errs = append(errs, b.Validate()...)
+	}
+
+	if _, err := plugins.New(context.Background()); err != nil {
+		errs = append(errs, errors.Wrap(err, "plugins"))
+	} else if dir := viper.GetString(config.KeyPluginDir); dir != "" {
+		// a missing plugin directory is only a warning at startup
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			errs = append(errs, errors.Errorf("plugins: plugin directory (%s) not found", dir))
+		}
+	}
+
+	if err := statsd.Validate(); err != nil {
+		errs = append(errs, errors.Wrap(err, "statsd"))
+	}
+
+	if err := server.Validate(); err != nil {
+		errs = append(errs, errors.Wrap(err, "server"))
+	}
+
+	return errs
+}
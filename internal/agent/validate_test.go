@@ -0,0 +1,47 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestValidate(t *testing.T) {
+	t.Log("Testing Validate")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("valid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginDir, "testdata")
+		viper.Set(config.KeyStatsdDisabled, true)
+		if errs := Validate(); len(errs) != 0 {
+			t.Fatalf("expected no errors, got (%v)", errs)
+		}
+	}
+
+	t.Log("multiple problems")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginDir, "testdata/missing")
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "")
+		viper.Set(config.KeyCollectors, []string{"not_a_collector"})
+		viper.Set(config.KeyCheckBundleID, "123")
+		viper.Set(config.KeyCheckCreate, true)
+		errs := Validate()
+		if len(errs) != 4 {
+			t.Fatalf("expected 4 errors, got %d (%v)", len(errs), errs)
+		}
+	}
+
+	viper.Reset()
+}
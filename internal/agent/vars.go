@@ -23,6 +23,7 @@ type Agent struct {
 	builtins     *builtins.Builtins
 	check        *check.Check
 	listenServer *server.Server
+	pidFile      *pidFile // nil if not configured
 	plugins      *plugins.Plugins
 	reverseConn  *reverse.Connection
 	signalCh     chan os.Signal
@@ -0,0 +1,176 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+)
+
+// Define stubs to satisfy the collector.Collector interface.
+//
+// The individual sysctl collector implementations must override Collect and Flush.
+//
+// ID and Inventory are generic and do not need to be overriden unless the
+// collector implementation requires it.
+
+// Collect returns collector metrics
+func (c *scommon) Collect() error {
+	c.Lock()
+	defer c.Unlock()
+	return collector.ErrNotImplemented
+}
+
+// Flush returns last metrics collected
+func (c *scommon) Flush() cgm.Metrics {
+	c.Lock()
+	defer c.Unlock()
+	if c.lastMetrics == nil {
+		c.lastMetrics = cgm.Metrics{}
+	}
+	return c.lastMetrics
+}
+
+// ID returns the id of the instance
+func (c *scommon) ID() string {
+	c.Lock()
+	defer c.Unlock()
+	return c.id
+}
+
+// Inventory returns collector stats for /inventory endpoint
+func (c *scommon) Inventory() collector.InventoryStats {
+	c.Lock()
+	defer c.Unlock()
+	return collector.InventoryStats{
+		ID:              c.id,
+		LastRunStart:    c.lastStart.Format(time.RFC3339Nano),
+		LastRunEnd:      c.lastEnd.Format(time.RFC3339Nano),
+		LastRunDuration: c.lastRunDuration.String(),
+		LastError:       c.lastError,
+	}
+}
+
+// applyCommonOptions applies the options shared by all collectors from a config file
+func (c *scommon) applyCommonOptions(opts commonOptions) error {
+	if opts.ID != "" {
+		c.id = opts.ID
+	}
+
+	for _, name := range opts.MetricsEnabled {
+		c.metricStatus[name] = true
+	}
+	for _, name := range opts.MetricsDisabled {
+		c.metricStatus[name] = false
+	}
+
+	if opts.MetricsDefaultStatus != "" {
+		if ok, _ := regexp.MatchString(`^(enabled|disabled)$`, strings.ToLower(opts.MetricsDefaultStatus)); ok {
+			c.metricDefaultActive = strings.ToLower(opts.MetricsDefaultStatus) == metricStatusEnabled
+		} else {
+			return errors.Errorf("%s invalid metric default status (%s)", c.pkgID, opts.MetricsDefaultStatus)
+		}
+	}
+
+	if opts.RunTTL != "" {
+		dur, err := time.ParseDuration(opts.RunTTL)
+		if err != nil {
+			return errors.Wrapf(err, "%s parsing run_ttl", c.pkgID)
+		}
+		c.runTTL = dur
+	}
+
+	return nil
+}
+
+// startCollect verifies a collection may start (run ttl expired, not
+// already running) and marks the collector as running
+func (c *scommon) startCollect() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.runTTL > time.Duration(0) {
+		if time.Since(c.lastEnd) < c.runTTL {
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
+			return collector.ErrTTLNotExpired
+		}
+	}
+	if c.running {
+		c.logger.Warn().Msg(collector.ErrAlreadyRunning.Error())
+		return collector.ErrAlreadyRunning
+	}
+
+	c.running = true
+	c.lastStart = time.Now()
+
+	return nil
+}
+
+// cleanName is used to clean the metric name
+func (c *scommon) cleanName(name string) string {
+	if c.metricNameRegex == nil {
+		return name
+	}
+	return c.metricNameRegex.ReplaceAllString(name, c.metricNameChar)
+}
+
+// addMetric to internal buffer if metric is active
+func (c *scommon) addMetric(metrics *cgm.Metrics, prefix string, mname, mtype string, mval interface{}) error {
+	if metrics == nil {
+		return errors.New("invalid metric submission")
+	}
+
+	if mname == "" {
+		return errors.New("invalid metric, no name")
+	}
+
+	if mtype == "" {
+		return errors.New("invalid metric, no type")
+	}
+
+	// cleanup the raw metric name, if needed
+	mname = c.cleanName(mname)
+	// check status of cleaned metric name
+	active, found := c.metricStatus[mname]
+
+	if (found && active) || (!found && c.metricDefaultActive) {
+		metricName := mname
+		if prefix != "" {
+			metricName = prefix + metricNameSeparator + mname
+		}
+		(*metrics)[metricName] = cgm.Metric{Type: mtype, Value: mval}
+		return nil
+	}
+
+	return errors.Errorf("metric (%s) not active", mname)
+}
+
+// setStatus is used in Collect to set the collector status
+func (c *scommon) setStatus(metrics cgm.Metrics, err error) {
+	c.Lock()
+	if err == nil {
+		c.lastError = ""
+		c.lastMetrics = metrics
+	} else {
+		c.lastError = err.Error()
+		// on error, ensure metrics are reset
+		// do not keep returning a stale set of metrics
+		c.lastMetrics = cgm.Metrics{}
+	}
+	c.lastEnd = time.Now()
+	if !c.lastStart.IsZero() {
+		c.lastRunDuration = time.Since(c.lastStart)
+	}
+	c.running = false
+	c.Unlock()
+}
@@ -0,0 +1,101 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"runtime"
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// CPU metrics from the host cpu load statistics
+type CPU struct {
+	scommon
+	numCPU float64 // number of cpus
+}
+
+// cpuTimes defines the cpu ticks, aggregated for all cpus, in each state
+type cpuTimes struct {
+	user   uint64
+	system uint64
+	idle   uint64
+	nice   uint64
+}
+
+// NewCPUCollector creates new sysctl cpu collector
+//
+// NOTE: cpu load statistics are retrieved with host_statistics(3), the
+// collector is not available when the agent is built without cgo.
+func NewCPUCollector(cfgBaseName string) (collector.Collector, error) {
+	c := CPU{}
+	c.id = "cpu"
+	c.pkgID = "builtins.darwin.sysctl." + c.id
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+	c.numCPU = float64(runtime.NumCPU())
+
+	if _, err := cpuTicks(); err != nil {
+		return nil, errors.Wrap(err, c.pkgID)
+	}
+
+	if cfgBaseName == "" {
+		return &c, nil
+	}
+
+	var opts commonOptions
+	err := config.LoadConfigFile(cfgBaseName, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if err := c.applyCommonOptions(opts); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Collect cpu metrics
+func (c *CPU) Collect() error {
+	metrics := cgm.Metrics{}
+
+	if err := c.startCollect(); err != nil {
+		return err
+	}
+
+	t, err := cpuTicks()
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+
+	// darwin cpu ticks are at 100Hz, the same units as the linux
+	// procfs cpu collector reports (normalized per cpu)
+	norm := c.numCPU
+	metricType := "n" // resmon double
+	c.addMetric(&metrics, c.id, "user", metricType, float64(t.user+t.nice)/norm)
+	c.addMetric(&metrics, c.id, "user"+metricNameSeparator+"normal", metricType, float64(t.user)/norm)
+	c.addMetric(&metrics, c.id, "user"+metricNameSeparator+"nice", metricType, float64(t.nice)/norm)
+	c.addMetric(&metrics, c.id, "kernel", metricType, float64(t.system)/norm)
+	c.addMetric(&metrics, c.id, "idle", metricType, float64(t.idle)/norm)
+
+	c.setStatus(metrics, nil)
+	return nil
+}
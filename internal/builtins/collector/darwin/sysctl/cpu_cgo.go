@@ -0,0 +1,39 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin,cgo
+
+package sysctl
+
+/*
+#include <mach/mach_host.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// cpuTicks returns the cpu ticks, aggregated for all cpus, in each state
+func cpuTicks() (*cpuTimes, error) {
+	var (
+		count C.mach_msg_type_number_t = C.HOST_CPU_LOAD_INFO_COUNT
+		info  C.host_cpu_load_info_data_t
+	)
+
+	status := C.host_statistics(C.host_t(C.mach_host_self()), C.HOST_CPU_LOAD_INFO, C.host_info_t(unsafe.Pointer(&info)), &count)
+	if status != C.KERN_SUCCESS {
+		return nil, errors.Errorf("host_statistics error (%d)", status)
+	}
+
+	return &cpuTimes{
+		user:   uint64(info.cpu_ticks[C.CPU_STATE_USER]),
+		system: uint64(info.cpu_ticks[C.CPU_STATE_SYSTEM]),
+		idle:   uint64(info.cpu_ticks[C.CPU_STATE_IDLE]),
+		nice:   uint64(info.cpu_ticks[C.CPU_STATE_NICE]),
+	}, nil
+}
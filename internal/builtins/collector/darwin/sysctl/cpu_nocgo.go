@@ -0,0 +1,15 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin,!cgo
+
+package sysctl
+
+import "github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+
+// cpuTicks is not available without cgo
+func cpuTicks() (*cpuTimes, error) {
+	return nil, collector.ErrNotImplemented
+}
@@ -0,0 +1,71 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin,cgo
+
+package sysctl
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewCPUCollector(t *testing.T) {
+	t.Log("Testing NewCPUCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewCPUCollector("")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewCPUCollector("testdata/missing")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewCPUCollector("testdata/bad_syntax")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (invalid run ttl)")
+	{
+		_, err := NewCPUCollector("testdata/config_run_ttl_invalid_setting")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestCPUCollect(t *testing.T) {
+	t.Log("Testing CPU Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c, err := NewCPUCollector("")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if err := c.Collect(); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if len(c.Flush()) == 0 {
+		t.Fatal("expected metrics")
+	}
+}
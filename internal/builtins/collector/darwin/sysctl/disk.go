@@ -0,0 +1,192 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/unix"
+)
+
+// Disk space metrics for mounted filesystems (getfsstat)
+type Disk struct {
+	scommon
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// diskOptions defines what elements can be overriden in a config file
+type diskOptions struct {
+	// common
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" yaml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+
+	// collector specific
+	IncludeRegex string `json:"include_regex" toml:"include_regex" yaml:"include_regex"`
+	ExcludeRegex string `json:"exclude_regex" toml:"exclude_regex" yaml:"exclude_regex"`
+}
+
+// diskSkipFSTypes are pseudo filesystems which are never reported
+var diskSkipFSTypes = map[string]bool{
+	"autofs": true,
+	"devfs":  true,
+}
+
+// NewDiskCollector creates new sysctl disk collector
+func NewDiskCollector(cfgBaseName string) (collector.Collector, error) {
+	c := Disk{}
+	c.id = "disk"
+	c.pkgID = "builtins.darwin.sysctl." + c.id
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricNameChar = defaultMetricChar
+	c.metricNameRegex = defaultMetricNameRegex
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+	c.include = defaultIncludeRegex
+	c.exclude = defaultExcludeRegex
+
+	if cfgBaseName == "" {
+		return &c, nil
+	}
+
+	var opts diskOptions
+	err := config.LoadConfigFile(cfgBaseName, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	common := commonOptions{
+		ID:                   opts.ID,
+		MetricsEnabled:       opts.MetricsEnabled,
+		MetricsDisabled:      opts.MetricsDisabled,
+		MetricsDefaultStatus: opts.MetricsDefaultStatus,
+		RunTTL:               opts.RunTTL,
+	}
+	if err := c.applyCommonOptions(common); err != nil {
+		return nil, err
+	}
+
+	if opts.IncludeRegex != "" {
+		rx, err := regexp.Compile(fmt.Sprintf(regexPat, opts.IncludeRegex))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s compiling include regex", c.pkgID)
+		}
+		c.include = rx
+	}
+
+	if opts.ExcludeRegex != "" {
+		rx, err := regexp.Compile(fmt.Sprintf(regexPat, opts.ExcludeRegex))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s compiling exclude regex", c.pkgID)
+		}
+		c.exclude = rx
+	}
+
+	return &c, nil
+}
+
+// Collect disk space metrics for each mounted filesystem
+func (c *Disk) Collect() error {
+	metrics := cgm.Metrics{}
+
+	if err := c.startCollect(); err != nil {
+		return err
+	}
+
+	n, err := unix.Getfsstat(nil, unix.MNT_NOWAIT)
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrapf(err, "%s getfsstat", c.pkgID)
+	}
+	fss := make([]unix.Statfs_t, n)
+	n, err = unix.Getfsstat(fss, unix.MNT_NOWAIT)
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrapf(err, "%s getfsstat", c.pkgID)
+	}
+
+	for i := 0; i < n; i++ {
+		fs := &fss[i]
+		fsType, mountPoint := statfsNames(fs)
+
+		if diskSkipFSTypes[fsType] {
+			continue
+		}
+		if c.exclude.MatchString(mountPoint) || !c.include.MatchString(mountPoint) {
+			c.logger.Debug().Str("mount", mountPoint).Msg("excluded, skipping")
+			continue
+		}
+
+		bsize := uint64(fs.Bsize)
+		total := fs.Blocks * bsize
+		free := fs.Bfree * bsize
+		avail := fs.Bavail * bsize
+		used := total - free
+		usedPct := float64(0)
+		if used+avail > 0 {
+			// percent of space available to non-root users which is in use (as df)
+			usedPct = float64(used) / float64(used+avail) * 100
+		}
+		inodesUsed := fs.Files - fs.Ffree
+		inodesUsedPct := float64(0)
+		if fs.Files > 0 {
+			inodesUsedPct = float64(inodesUsed) / float64(fs.Files) * 100
+		}
+
+		pfx := c.id + metricNameSeparator + c.cleanName(mountPoint)
+		c.addMetric(&metrics, pfx, "total", "L", total)
+		c.addMetric(&metrics, pfx, "free", "L", free)
+		c.addMetric(&metrics, pfx, "avail", "L", avail)
+		c.addMetric(&metrics, pfx, "used", "L", used)
+		c.addMetric(&metrics, pfx, "used_percent", "n", usedPct)
+		c.addMetric(&metrics, pfx, "inodes_total", "L", fs.Files)
+		c.addMetric(&metrics, pfx, "inodes_free", "L", fs.Ffree)
+		c.addMetric(&metrics, pfx, "inodes_used", "L", inodesUsed)
+		c.addMetric(&metrics, pfx, "inodes_used_percent", "n", inodesUsedPct)
+	}
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// statfsNames returns the filesystem type and mount point from a Statfs_t
+func statfsNames(fs *unix.Statfs_t) (string, string) {
+	fsType := make([]byte, 0, len(fs.Fstypename))
+	for _, ch := range fs.Fstypename {
+		if ch == 0 {
+			break
+		}
+		fsType = append(fsType, byte(ch))
+	}
+
+	mountPoint := make([]byte, 0, len(fs.Mntonname))
+	for _, ch := range fs.Mntonname {
+		if ch == 0 {
+			break
+		}
+		mountPoint = append(mountPoint, byte(ch))
+	}
+
+	return string(fsType), string(mountPoint)
+}
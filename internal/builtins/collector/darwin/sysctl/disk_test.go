@@ -0,0 +1,87 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewDiskCollector(t *testing.T) {
+	t.Log("Testing NewDiskCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewDiskCollector("")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewDiskCollector("testdata/missing")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewDiskCollector("testdata/bad_syntax")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (invalid include regex)")
+	{
+		_, err := NewDiskCollector("testdata/config_include_regex_invalid_setting")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (invalid exclude regex)")
+	{
+		_, err := NewDiskCollector("testdata/config_exclude_regex_invalid_setting")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (invalid run ttl)")
+	{
+		_, err := NewDiskCollector("testdata/config_run_ttl_invalid_setting")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestDiskCollect(t *testing.T) {
+	t.Log("Testing Disk Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c, err := NewDiskCollector("")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if err := c.Collect(); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if len(c.Flush()) == 0 {
+		t.Fatal("expected metrics")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/unix"
+)
+
+// Loadavg metrics from the vm.loadavg sysctl
+type Loadavg struct {
+	scommon
+}
+
+// NewLoadavgCollector creates new sysctl loadavg collector
+func NewLoadavgCollector(cfgBaseName string) (collector.Collector, error) {
+	c := Loadavg{}
+	c.id = "loadavg"
+	c.pkgID = "builtins.darwin.sysctl." + c.id
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+
+	if cfgBaseName == "" {
+		return &c, nil
+	}
+
+	var opts commonOptions
+	err := config.LoadConfigFile(cfgBaseName, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if err := c.applyCommonOptions(opts); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Collect metrics from the vm.loadavg sysctl
+func (c *Loadavg) Collect() error {
+	metrics := cgm.Metrics{}
+
+	if err := c.startCollect(); err != nil {
+		return err
+	}
+
+	raw, err := unix.SysctlRaw("vm.loadavg")
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrapf(err, "%s sysctl vm.loadavg", c.pkgID)
+	}
+
+	avg, err := parseLoadavg(raw)
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+
+	metricType := "n"
+	c.addMetric(&metrics, c.id, "1", metricType, avg[0])
+	c.addMetric(&metrics, c.id, "5", metricType, avg[1])
+	c.addMetric(&metrics, c.id, "15", metricType, avg[2])
+
+	c.setStatus(metrics, nil)
+	return nil
+}
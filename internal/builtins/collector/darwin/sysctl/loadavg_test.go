@@ -0,0 +1,71 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewLoadavgCollector(t *testing.T) {
+	t.Log("Testing NewLoadavgCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewLoadavgCollector("")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewLoadavgCollector("testdata/missing")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewLoadavgCollector("testdata/bad_syntax")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (invalid run ttl)")
+	{
+		_, err := NewLoadavgCollector("testdata/config_run_ttl_invalid_setting")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestLoadavgCollect(t *testing.T) {
+	t.Log("Testing Loadavg Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c, err := NewLoadavgCollector("")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if err := c.Collect(); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if len(c.Flush()) == 0 {
+		t.Fatal("expected metrics")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+// Package sysctl provides builtin collectors for macOS (darwin) using sysctl(3)
+// and related system calls
+package sysctl
+
+import (
+	"path"
+	"runtime"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// New creates new sysctl collectors
+func New() ([]collector.Collector, error) {
+	none := []collector.Collector{}
+
+	if runtime.GOOS != "darwin" {
+		return none, nil
+	}
+
+	l := log.With().Str("pkg", "builtins.sysctl").Logger()
+
+	enbledCollectors := config.EnabledCollectors()
+	if len(enbledCollectors) == 0 {
+		l.Info().Msg("no builtin collectors enabled")
+		return none, nil
+	}
+
+	collectors := make([]collector.Collector, 0, len(enbledCollectors))
+	initErrMsg := "initializing builtin collector"
+	for _, name := range enbledCollectors {
+		cfgBase := name + "_collector"
+		switch name {
+		case "cpu":
+			c, err := NewCPUCollector(path.Join(defaults.EtcPath, cfgBase))
+			if err != nil {
+				if errors.Cause(err) == collector.ErrNotImplemented {
+					l.Warn().Str("name", name).Msg("requires cgo, not available in this build")
+					continue
+				}
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "disk":
+			c, err := NewDiskCollector(path.Join(defaults.EtcPath, cfgBase))
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "loadavg":
+			c, err := NewLoadavgCollector(path.Join(defaults.EtcPath, cfgBase))
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "vm":
+			c, err := NewVMCollector(path.Join(defaults.EtcPath, cfgBase))
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		default:
+			if viper.GetBool(config.KeyCollectorsStrict) {
+				return none, errors.Errorf("unknown builtin collector (%s)", name)
+			}
+			l.Warn().Str("name", name).Msg("unknown builtin collector, ignoring")
+		}
+	}
+
+	return collectors, nil
+}
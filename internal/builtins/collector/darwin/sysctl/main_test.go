@@ -0,0 +1,64 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestNew(t *testing.T) {
+	t.Log("Testing New")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("known collectors")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCollectors, []string{"disk", "loadavg", "vm"})
+
+		c, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(c) != 3 {
+			t.Fatalf("expected 3 collectors, got %d", len(c))
+		}
+	}
+
+	t.Log("unknown collector")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCollectors, []string{"vm", "diskstats"})
+
+		c, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(c) != 1 {
+			t.Fatalf("expected 1 collector, got %d", len(c))
+		}
+	}
+
+	t.Log("unknown collector (strict)")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCollectors, []string{"vm", "diskstats"})
+		viper.Set(config.KeyCollectorsStrict, true)
+
+		_, err := New()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,69 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sysctl
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Raw sysctl values are native structs/integers, darwin on amd64 and
+// arm64 is little endian.
+
+// parseUint returns the value of a raw 32 or 64 bit unsigned integer sysctl
+func parseUint(raw []byte) (uint64, error) {
+	switch len(raw) {
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(raw)), nil
+	case 8:
+		return binary.LittleEndian.Uint64(raw), nil
+	default:
+		return 0, errors.Errorf("unexpected integer size (%d)", len(raw))
+	}
+}
+
+// parseLoadavg returns the 1, 5, and 15 minute load averages from a raw
+// vm.loadavg sysctl (struct loadavg { fixpt_t ldavg[3]; long fscale; })
+func parseLoadavg(raw []byte) ([3]float64, error) {
+	var avg [3]float64
+
+	if len(raw) != 24 {
+		return avg, errors.Errorf("unexpected loadavg size (%d)", len(raw))
+	}
+
+	fscale := float64(binary.LittleEndian.Uint64(raw[16:]))
+	if fscale == 0 {
+		return avg, errors.New("invalid loadavg fscale (0)")
+	}
+
+	for i := range avg {
+		avg[i] = float64(binary.LittleEndian.Uint32(raw[i*4:])) / fscale
+	}
+
+	return avg, nil
+}
+
+// swapUsage defines the swap details from the vm.swapusage sysctl
+type swapUsage struct {
+	total uint64
+	avail uint64
+	used  uint64
+}
+
+// parseSwapUsage returns swap usage from a raw vm.swapusage sysctl
+// (struct xsw_usage { u_int64_t xsu_total, xsu_avail, xsu_used; ... })
+func parseSwapUsage(raw []byte) (*swapUsage, error) {
+	if len(raw) < 24 {
+		return nil, errors.Errorf("unexpected swapusage size (%d)", len(raw))
+	}
+
+	return &swapUsage{
+		total: binary.LittleEndian.Uint64(raw[0:]),
+		avail: binary.LittleEndian.Uint64(raw[8:]),
+		used:  binary.LittleEndian.Uint64(raw[16:]),
+	}, nil
+}
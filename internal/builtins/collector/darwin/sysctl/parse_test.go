@@ -0,0 +1,113 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sysctl
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestParseUint(t *testing.T) {
+	t.Log("Testing parseUint")
+
+	t.Log("32 bit")
+	{
+		raw := make([]byte, 4)
+		binary.LittleEndian.PutUint32(raw, 4096)
+		v, err := parseUint(raw)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if v != 4096 {
+			t.Fatalf("expected 4096, got %d", v)
+		}
+	}
+
+	t.Log("64 bit")
+	{
+		raw := make([]byte, 8)
+		binary.LittleEndian.PutUint64(raw, 17179869184)
+		v, err := parseUint(raw)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if v != 17179869184 {
+			t.Fatalf("expected 17179869184, got %d", v)
+		}
+	}
+
+	t.Log("invalid size")
+	{
+		_, err := parseUint([]byte{1, 2})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestParseLoadavg(t *testing.T) {
+	t.Log("Testing parseLoadavg")
+
+	t.Log("valid")
+	{
+		raw := make([]byte, 24)
+		binary.LittleEndian.PutUint32(raw[0:], 2048)
+		binary.LittleEndian.PutUint32(raw[4:], 1024)
+		binary.LittleEndian.PutUint32(raw[8:], 512)
+		binary.LittleEndian.PutUint64(raw[16:], 2048)
+		avg, err := parseLoadavg(raw)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := [3]float64{1, 0.5, 0.25}
+		if avg != expect {
+			t.Fatalf("expected %v, got %v", expect, avg)
+		}
+	}
+
+	t.Log("invalid size")
+	{
+		_, err := parseLoadavg(make([]byte, 16))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid fscale")
+	{
+		_, err := parseLoadavg(make([]byte, 24))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestParseSwapUsage(t *testing.T) {
+	t.Log("Testing parseSwapUsage")
+
+	t.Log("valid")
+	{
+		raw := make([]byte, 32)
+		binary.LittleEndian.PutUint64(raw[0:], 3000)
+		binary.LittleEndian.PutUint64(raw[8:], 2000)
+		binary.LittleEndian.PutUint64(raw[16:], 1000)
+		swap, err := parseSwapUsage(raw)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if swap.total != 3000 || swap.avail != 2000 || swap.used != 1000 {
+			t.Fatalf("unexpected swap usage (%#v)", swap)
+		}
+	}
+
+	t.Log("invalid size")
+	{
+		_, err := parseSwapUsage(make([]byte, 8))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
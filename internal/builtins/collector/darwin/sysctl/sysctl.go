@@ -0,0 +1,26 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// sysctlUint returns the value of a numeric sysctl
+func sysctlUint(name string) (uint64, error) {
+	raw, err := unix.SysctlRaw(name)
+	if err != nil {
+		return 0, errors.Wrapf(err, "sysctl %s", name)
+	}
+	v, err := parseUint(raw)
+	if err != nil {
+		return 0, errors.Wrapf(err, "sysctl %s", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,57 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package sysctl
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/rs/zerolog"
+)
+
+// scommon defines sysctl metrics common elements
+type scommon struct {
+	id                  string          // OPT id of the collector (used as metric name prefix)
+	pkgID               string          // package prefix used for logging and errors
+	lastEnd             time.Time       // last collection end time
+	lastError           string          // last collection error
+	lastMetrics         cgm.Metrics     // last metrics collected
+	lastRunDuration     time.Duration   // last collection duration
+	lastStart           time.Time       // last collection start time
+	logger              zerolog.Logger  // collector logging instance
+	metricDefaultActive bool            // OPT default status for metrics NOT explicitly in metricStatus
+	metricNameChar      string          // OPT character(s) used as replacement for metricNameRegex
+	metricNameRegex     *regexp.Regexp  // OPT regex for cleaning names, may be overriden in config
+	metricStatus        map[string]bool // OPT list of metrics and whether they should be collected or not
+	running             bool            // is collector currently running
+	runTTL              time.Duration   // OPT ttl for collectors (default is for every request)
+	sync.Mutex
+}
+
+// commonOptions defines the options, shared by all collectors, which can be overriden in a config file
+type commonOptions struct {
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" yaml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+}
+
+const (
+	defaultMetricChar   = "_"        // character used to replace invalid characters in metric name
+	metricNameSeparator = "`"        // character used to separate parts of metric names
+	metricStatusEnabled = "enabled"  // setting string indicating metrics should be made 'active'
+	regexPat            = `^(?:%s)$` // fmt pattern used compile include/exclude regular expressions
+)
+
+var (
+	defaultExcludeRegex    = regexp.MustCompile(fmt.Sprintf(regexPat, ""))
+	defaultIncludeRegex    = regexp.MustCompile(fmt.Sprintf(regexPat, ".+"))
+	defaultMetricNameRegex = regexp.MustCompile(`[^a-zA-Z0-9.-_:` + metricNameSeparator + `]`)
+)
@@ -0,0 +1,167 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sys/unix"
+)
+
+// VM metrics from the hw.* and vm.* sysctls
+type VM struct {
+	scommon
+}
+
+// vmPageCounts are the page count sysctls reported, memory which is free,
+// speculative, purgeable or file backed (pageable_external) is available
+var vmPageCounts = map[string]bool{
+	"free":              true,
+	"speculative":       true,
+	"purgeable":         true,
+	"pageable_external": true,
+	"pageable_internal": false,
+}
+
+// NewVMCollector creates new sysctl vm collector
+func NewVMCollector(cfgBaseName string) (collector.Collector, error) {
+	c := VM{}
+	c.id = "vm"
+	c.pkgID = "builtins.darwin.sysctl." + c.id
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+
+	if cfgBaseName == "" {
+		return &c, nil
+	}
+
+	var opts commonOptions
+	err := config.LoadConfigFile(cfgBaseName, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if err := c.applyCommonOptions(opts); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Collect memory and swap metrics
+func (c *VM) Collect() error {
+	metrics := cgm.Metrics{}
+
+	if err := c.startCollect(); err != nil {
+		return err
+	}
+
+	if err := c.getMemory(&metrics); err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+
+	if err := c.getSwap(&metrics); err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// getMemory collects memory metrics
+func (c *VM) getMemory(metrics *cgm.Metrics) error {
+	memTotal, err := sysctlUint("hw.memsize")
+	if err != nil {
+		return err
+	}
+	pageSize, err := sysctlUint("hw.pagesize")
+	if err != nil {
+		return err
+	}
+
+	var memFree uint64
+	pfx := c.id + metricNameSeparator + "pages"
+	for name, available := range vmPageCounts {
+		v, err := sysctlUint("vm.page_" + name + "_count")
+		if err != nil {
+			// not all counts are available on all os versions
+			c.logger.Debug().Err(err).Msg("page count")
+			continue
+		}
+		if available {
+			memFree += v * pageSize
+		}
+		c.addMetric(metrics, pfx, name, "L", v)
+	}
+
+	if memFree > memTotal {
+		memFree = memTotal
+	}
+	memUsed := memTotal - memFree
+	memFreePct := float64(0)
+	memUsedPct := float64(0)
+	if memTotal > 0 {
+		memFreePct = float64(memFree) / float64(memTotal)
+		memUsedPct = float64(memUsed) / float64(memTotal)
+	}
+
+	pfx = c.id + metricNameSeparator + "memory"
+	c.addMetric(metrics, pfx, "free", "L", memFree)
+	c.addMetric(metrics, pfx, "free_percent", "n", memFreePct*100)
+	c.addMetric(metrics, pfx, "percent_free", "n", memFreePct)
+	c.addMetric(metrics, pfx, "percent_used", "n", memUsedPct)
+	c.addMetric(metrics, pfx, "total", "L", memTotal)
+	c.addMetric(metrics, pfx, "used", "L", memUsed)
+	c.addMetric(metrics, pfx, "used_percent", "n", memUsedPct*100)
+
+	return nil
+}
+
+// getSwap collects swap metrics
+func (c *VM) getSwap(metrics *cgm.Metrics) error {
+	raw, err := unix.SysctlRaw("vm.swapusage")
+	if err != nil {
+		return errors.Wrap(err, "sysctl vm.swapusage")
+	}
+	swap, err := parseSwapUsage(raw)
+	if err != nil {
+		return err
+	}
+
+	swapFreePct := 0.0
+	swapUsedPct := 0.0
+	if swap.total > 0 {
+		swapFreePct = float64(swap.avail) / float64(swap.total)
+		swapUsedPct = float64(swap.used) / float64(swap.total)
+	}
+
+	pfx := c.id + metricNameSeparator + "swap"
+	c.addMetric(metrics, pfx, "free", "L", swap.avail)
+	c.addMetric(metrics, pfx, "free_percent", "n", swapFreePct*100)
+	c.addMetric(metrics, pfx, "percent_free", "n", swapFreePct)
+	c.addMetric(metrics, pfx, "percent_used", "n", swapUsedPct)
+	c.addMetric(metrics, pfx, "total", "L", swap.total)
+	c.addMetric(metrics, pfx, "used", "L", swap.used)
+	c.addMetric(metrics, pfx, "used_percent", "n", swapUsedPct*100)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build darwin
+
+package sysctl
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewVMCollector(t *testing.T) {
+	t.Log("Testing NewVMCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewVMCollector("")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewVMCollector("testdata/missing")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewVMCollector("testdata/bad_syntax")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (invalid run ttl)")
+	{
+		_, err := NewVMCollector("testdata/config_run_ttl_invalid_setting")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestVMCollect(t *testing.T) {
+	t.Log("Testing VM Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c, err := NewVMCollector("")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if err := c.Collect(); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	if len(c.Flush()) == 0 {
+		t.Fatal("expected metrics")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/pkg/errors"
 )
@@ -118,3 +119,12 @@ func (c *pfscommon) setStatus(metrics cgm.Metrics, err error) {
 	c.running = false
 	c.Unlock()
 }
+
+// loadOptions loads the collector options from its section of the collectors
+// config file, if it has one, otherwise from its own config file (cfgBaseName)
+func loadOptions(cfgBaseName string, cfgSection []byte, opts interface{}) error {
+	if cfgSection != nil {
+		return config.LoadConfigSection(cfgSection, opts)
+	}
+	return config.LoadConfigFile(cfgBaseName, opts)
+}
@@ -0,0 +1,250 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Conntrack metrics from the Linux ProcFS netfilter connection tracking
+type Conntrack struct {
+	pfscommon
+	countFile     string
+	maxFile       string
+	statFile      string
+	notLoadedSeen bool
+}
+
+// conntrackOptions defines what elements can be overriden in a config file
+type conntrackOptions struct {
+	// common
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	ProcFSPath           string   `json:"procfs_path" toml:"procfs_path" yaml:"procfs_path"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" toml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+}
+
+// NewConntrackCollector creates new procfs conntrack collector
+func NewConntrackCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
+	c := Conntrack{}
+	c.id = "conntrack"
+	c.pkgID = "builtins.linux.procfs." + c.id
+	c.procFSPath = "/proc"
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+	c.setFiles()
+
+	// NOTE: missing conntrack files are not an error, the nf_conntrack
+	//       module may simply not be loaded (yet), see Collect
+
+	if cfgBaseName == "" && cfgSection == nil {
+		return &c, nil
+	}
+
+	var opts conntrackOptions
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if opts.ID != "" {
+		c.id = opts.ID
+	}
+
+	if opts.ProcFSPath != "" {
+		c.procFSPath = opts.ProcFSPath
+		c.setFiles()
+	}
+
+	if len(opts.MetricsEnabled) > 0 {
+		for _, name := range opts.MetricsEnabled {
+			c.metricStatus[name] = true
+		}
+	}
+	if len(opts.MetricsDisabled) > 0 {
+		for _, name := range opts.MetricsDisabled {
+			c.metricStatus[name] = false
+		}
+	}
+
+	if opts.MetricsDefaultStatus != "" {
+		if ok, _ := regexp.MatchString(`^(enabled|disabled)$`, strings.ToLower(opts.MetricsDefaultStatus)); ok {
+			c.metricDefaultActive = strings.ToLower(opts.MetricsDefaultStatus) == metricStatusEnabled
+		} else {
+			return nil, errors.Errorf("%s invalid metric default status (%s)", c.pkgID, opts.MetricsDefaultStatus)
+		}
+	}
+
+	if opts.RunTTL != "" {
+		dur, err := time.ParseDuration(opts.RunTTL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing run_ttl", c.pkgID)
+		}
+		c.runTTL = dur
+	}
+
+	return &c, nil
+}
+
+// setFiles sets the procfs file paths based on the procfs path
+func (c *Conntrack) setFiles() {
+	c.countFile = filepath.Join(c.procFSPath, "sys", "net", "netfilter", "nf_conntrack_count")
+	c.maxFile = filepath.Join(c.procFSPath, "sys", "net", "netfilter", "nf_conntrack_max")
+	c.statFile = filepath.Join(c.procFSPath, "net", "stat", "nf_conntrack")
+}
+
+// Collect metrics from the procfs resources
+func (c *Conntrack) Collect() error {
+	metrics := cgm.Metrics{}
+
+	c.Lock()
+
+	if c.runTTL > time.Duration(0) {
+		if time.Since(c.lastEnd) < c.runTTL {
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
+			c.Unlock()
+			return collector.ErrTTLNotExpired
+		}
+	}
+	if c.running {
+		c.logger.Warn().Msg(collector.ErrAlreadyRunning.Error())
+		c.Unlock()
+		return collector.ErrAlreadyRunning
+	}
+
+	c.running = true
+	c.lastStart = time.Now()
+	c.Unlock()
+
+	if _, err := os.Stat(c.countFile); os.IsNotExist(err) {
+		// only log once (each time it transitions to not loaded)
+		if !c.notLoadedSeen {
+			c.logger.Info().Str("file", c.countFile).Msg("conntrack not loaded, no metrics")
+			c.notLoadedSeen = true
+		}
+		c.setStatus(metrics, nil)
+		return nil
+	}
+	c.notLoadedSeen = false
+
+	count, err := readConntrackValue(c.countFile)
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+	c.addMetric(&metrics, c.id, "count", "L", count)
+
+	max, err := readConntrackValue(c.maxFile)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("file", c.maxFile).Msg("reading max")
+	} else {
+		c.addMetric(&metrics, c.id, "max", "L", max)
+		if max > 0 {
+			c.addMetric(&metrics, c.id, "utilization", "n", float64(count)/float64(max))
+		}
+	}
+
+	if err := c.statMetrics(&metrics); err != nil {
+		c.logger.Warn().Err(err).Str("file", c.statFile).Msg("reading stats")
+	}
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// statMetrics sums the per-cpu counters in /proc/net/stat/nf_conntrack, the
+// first line contains the field names, each subsequent line the (hex)
+// values for one cpu
+func (c *Conntrack) statMetrics(metrics *cgm.Metrics) error {
+	f, err := os.Open(c.statFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	var fields []string
+	var totals []uint64
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		if fields == nil {
+			fields = values
+			totals = make([]uint64, len(fields))
+			continue
+		}
+		if len(values) != len(fields) {
+			c.logger.Warn().Int("fields", len(values)).Int("expected", len(fields)).Msg("invalid number of fields")
+			continue
+		}
+		for i, value := range values {
+			v, err := strconv.ParseUint(value, 16, 64)
+			if err != nil {
+				c.logger.Warn().Err(err).Str("field", fields[i]).Msg("parsing field")
+				continue
+			}
+			// entries is the global table size, repeated on each line
+			if fields[i] == "entries" {
+				totals[i] = v
+				continue
+			}
+			totals[i] += v
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "parsing %s", f.Name())
+	}
+
+	pfx := c.id + metricNameSeparator + "stat"
+	for i, field := range fields {
+		if field == "entries" {
+			continue // already reported as count
+		}
+		c.addMetric(metrics, pfx, field, "L", totals[i])
+	}
+
+	return nil
+}
+
+// readConntrackValue reads a single numeric value from a procfs sysctl file
+func readConntrackValue(file string) (uint64, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing %s", file)
+	}
+	return v, nil
+}
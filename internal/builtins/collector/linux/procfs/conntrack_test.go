@@ -0,0 +1,173 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/rs/zerolog"
+)
+
+func TestNewConntrackCollector(t *testing.T) {
+	t.Log("Testing NewConntrackCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewConntrackCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewConntrackCollector(filepath.Join("testdata", "missing"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewConntrackCollector(filepath.Join("testdata", "bad_syntax"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (id setting)")
+	{
+		c, err := NewConntrackCollector(filepath.Join("testdata", "config_id_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Conntrack).id != "foo" {
+			t.Fatalf("expected foo, got (%s)", c.ID())
+		}
+	}
+
+	t.Log("config (procfs path setting)")
+	{
+		c, err := NewConntrackCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := filepath.Join("testdata", "sys", "net", "netfilter", "nf_conntrack_count")
+		if c.(*Conntrack).countFile != expect {
+			t.Fatalf("expected (%s), got (%s)", expect, c.(*Conntrack).countFile)
+		}
+	}
+
+	t.Log("config (metrics default status invalid)")
+	{
+		_, err := NewConntrackCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (run ttl setting)")
+	{
+		c, err := NewConntrackCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Conntrack).runTTL != 5*time.Minute {
+			t.Fatal("expected 5m")
+		}
+	}
+}
+
+func TestConntrackCollect(t *testing.T) {
+	t.Log("Testing Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("already running")
+	{
+		c, err := NewConntrackCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c.(*Conntrack).running = true
+
+		if err := c.Collect(); err != nil {
+			if err.Error() != collector.ErrAlreadyRunning.Error() {
+				t.Fatalf("expected (%s) got (%s)", collector.ErrAlreadyRunning, err)
+			}
+		} else {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("not loaded")
+	{
+		c, err := NewConntrackCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*Conntrack).procFSPath = filepath.Join("testdata", "missing")
+		c.(*Conntrack).setFiles()
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 0 {
+			t.Fatalf("expected no metrics, got %v", metrics)
+		}
+	}
+
+	t.Log("good")
+	{
+		c, err := NewConntrackCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		if len(metrics) == 0 {
+			t.Fatalf("expected metrics, got %v", metrics)
+		}
+
+		tests := []struct {
+			name  string
+			value interface{}
+		}{
+			{"conntrack`count", uint64(1024)},
+			{"conntrack`max", uint64(4096)},
+			{"conntrack`utilization", float64(0.25)},
+			{"conntrack`stat`invalid", uint64(18)},
+			{"conntrack`stat`ignore", uint64(51)},
+			{"conntrack`stat`insert_failed", uint64(2)},
+			{"conntrack`stat`drop", uint64(2)},
+			{"conntrack`stat`search_restart", uint64(6)},
+		}
+		for _, test := range tests {
+			m, ok := metrics[test.name]
+			if !ok {
+				t.Fatalf("expected %s, got %v", test.name, metrics)
+			}
+			if m.Value != test.value {
+				t.Fatalf("%s expected %v, got %v", test.name, test.value, m.Value)
+			}
+		}
+		if _, ok := metrics["conntrack`stat`entries"]; ok {
+			t.Fatal("expected no stat entries metric")
+		}
+	}
+}
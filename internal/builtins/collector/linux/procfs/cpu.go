@@ -18,7 +18,6 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
-	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -49,7 +48,7 @@ type cpuOptions struct {
 }
 
 // NewCPUCollector creates new procfs cpu collector
-func NewCPUCollector(cfgBaseName string) (collector.Collector, error) {
+func NewCPUCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
 	procFile := "stat"
 
 	c := CPU{}
@@ -66,7 +65,7 @@ func NewCPUCollector(cfgBaseName string) (collector.Collector, error) {
 	c.clockNorm = clockHZ / 100
 	c.reportAllCPUs = false
 
-	if cfgBaseName == "" {
+	if cfgBaseName == "" && cfgSection == nil {
 		if _, err := os.Stat(c.file); os.IsNotExist(err) {
 			return nil, errors.Wrap(err, c.pkgID)
 		}
@@ -74,7 +73,7 @@ func NewCPUCollector(cfgBaseName string) (collector.Collector, error) {
 	}
 
 	var opts cpuOptions
-	err := config.LoadConfigFile(cfgBaseName, &opts)
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
 	if err != nil {
 		if strings.Contains(err.Error(), "no config found matching") {
 			return &c, nil
@@ -153,7 +152,7 @@ func (c *CPU) Collect() error {
 
 	if c.runTTL > time.Duration(0) {
 		if time.Since(c.lastEnd) < c.runTTL {
-			c.logger.Warn().Msg(collector.ErrTTLNotExpired.Error())
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
 			c.Unlock()
 			return collector.ErrTTLNotExpired
 		}
@@ -24,7 +24,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("no config")
 	{
-		_, err := NewCPUCollector("")
+		_, err := NewCPUCollector("", nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -38,7 +38,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (missing)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "missing"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "missing"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -46,7 +46,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (bad syntax)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "bad_syntax"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "bad_syntax"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -54,7 +54,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (config no settings)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_no_settings"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_no_settings"), nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -74,7 +74,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (id setting)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_id_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_id_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -83,9 +83,28 @@ func TestNewCPUCollector(t *testing.T) {
 		}
 	}
 
+	t.Log("config section (id setting)")
+	{
+		c, err := NewCPUCollector(filepath.Join("testdata", "missing"), []byte(`{"id":"bar"}`))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*CPU).id != "bar" {
+			t.Fatalf("expected bar, got (%s)", c.ID())
+		}
+	}
+
+	t.Log("config section (invalid)")
+	{
+		_, err := NewCPUCollector("", []byte(`{"id":`))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
 	t.Log("config (procfs path setting)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -97,7 +116,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting invalid)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -105,7 +124,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (clock_hz setting)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_clock_hz_valid_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_clock_hz_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -117,7 +136,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (clock_hz setting invalid)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "config_clock_hz_invalid_setting"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "config_clock_hz_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -125,7 +144,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (report all cpus setting true)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_report_all_cpus_true_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_report_all_cpus_true_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -136,7 +155,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (report all cpus setting false)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_report_all_cpus_false_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_report_all_cpus_false_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -147,7 +166,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (report all cpus setting invalid)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "config_report_all_cpus_invalid_setting"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "config_report_all_cpus_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -155,7 +174,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (metrics enabled setting)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_enabled_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -173,7 +192,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (metrics disabled setting)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_disabled_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -191,7 +210,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (metrics default status enabled)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -202,7 +221,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (metrics default status disabled)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -213,7 +232,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (metrics default status invalid)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -221,7 +240,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (run ttl 5m)")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -232,7 +251,7 @@ func TestNewCPUCollector(t *testing.T) {
 
 	t.Log("config (run ttl invalid)")
 	{
-		_, err := NewCPUCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"))
+		_, err := NewCPUCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -244,7 +263,7 @@ func TestCPUFlush(t *testing.T) {
 
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 
-	c, err := NewCPUCollector(filepath.Join("testdata", "config_file_valid_setting"))
+	c, err := NewCPUCollector(filepath.Join("testdata", "config_file_valid_setting"), nil)
 	if err != nil {
 		t.Fatalf("expected NO error, got (%s)", err)
 	}
@@ -265,7 +284,7 @@ func TestCPUCollect(t *testing.T) {
 
 	t.Log("already running")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -283,7 +302,7 @@ func TestCPUCollect(t *testing.T) {
 
 	t.Log("ttl not expired")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -302,7 +321,7 @@ func TestCPUCollect(t *testing.T) {
 
 	t.Log("good")
 	{
-		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewCPUCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
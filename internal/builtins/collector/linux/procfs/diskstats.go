@@ -20,7 +20,6 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
-	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -33,6 +32,9 @@ type Diskstats struct {
 	exclude           *regexp.Regexp
 	sectorSizeDefault uint64
 	sectorSizeCache   map[string]uint64
+	derived           bool               // OPT emit utilization, queue size and io times derived from successive samples
+	lastSample        map[string]*dstats // previous sample, per device, for derived metrics
+	lastSampleTime    time.Time
 }
 
 // diskstatsOptions defines what elements can be overriden in a config file
@@ -49,6 +51,7 @@ type diskstatsOptions struct {
 	IncludeRegex      string `json:"include_regex" toml:"include_regex" yaml:"include_regex"`
 	ExcludeRegex      string `json:"exclude_regex" toml:"exclude_regex" yaml:"exclude_regex"`
 	DefaultSectorSize string `json:"default_sector_size" toml:"default_sector_size" yaml:"default_sector_size"`
+	DerivedMetrics    string `json:"derived_metrics" toml:"derived_metrics" yaml:"derived_metrics"`
 }
 
 type dstats struct {
@@ -69,7 +72,7 @@ type dstats struct {
 }
 
 // NewDiskstatsCollector creates new procfs cpu collector
-func NewDiskstatsCollector(cfgBaseName string) (collector.Collector, error) {
+func NewDiskstatsCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
 	procFile := "diskstats"
 
 	c := Diskstats{}
@@ -86,7 +89,7 @@ func NewDiskstatsCollector(cfgBaseName string) (collector.Collector, error) {
 	c.exclude = defaultExcludeRegex
 	c.sectorSizeDefault = 512
 
-	if cfgBaseName == "" {
+	if cfgBaseName == "" && cfgSection == nil {
 		if _, err := os.Stat(c.file); os.IsNotExist(err) {
 			return nil, errors.Wrap(err, c.pkgID)
 		}
@@ -94,7 +97,7 @@ func NewDiskstatsCollector(cfgBaseName string) (collector.Collector, error) {
 	}
 
 	var opts diskstatsOptions
-	err := config.LoadConfigFile(cfgBaseName, &opts)
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
 	if err != nil {
 		if strings.Contains(err.Error(), "no config found matching") {
 			return &c, nil
@@ -129,6 +132,14 @@ func NewDiskstatsCollector(cfgBaseName string) (collector.Collector, error) {
 		c.sectorSizeDefault = v
 	}
 
+	if opts.DerivedMetrics != "" {
+		derived, err := strconv.ParseBool(opts.DerivedMetrics)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing derived_metrics", c.pkgID)
+		}
+		c.derived = derived
+	}
+
 	if opts.ID != "" {
 		c.id = opts.ID
 	}
@@ -180,7 +191,7 @@ func (c *Diskstats) Collect() error {
 
 	if c.runTTL > time.Duration(0) {
 		if time.Since(c.lastEnd) < c.runTTL {
-			c.logger.Warn().Msg(collector.ErrTTLNotExpired.Error())
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
 			c.Unlock()
 			return collector.ErrTTLNotExpired
 		}
@@ -202,6 +213,7 @@ func (c *Diskstats) Collect() error {
 	}
 	defer f.Close()
 
+	sampleTime := time.Now()
 	stats := make(map[string]*dstats)
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
@@ -244,6 +256,7 @@ func (c *Diskstats) Collect() error {
 	mdrx := regexp.MustCompile(`^md[0-9]+`)
 	pfx := c.id + metricNameSeparator
 	metricType := "L" // uint64
+	sample := make(map[string]*dstats)
 	for devID, devStats := range stats {
 
 		if c.exclude.MatchString(devID) || !c.include.MatchString(devID) {
@@ -278,12 +291,61 @@ func (c *Diskstats) Collect() error {
 		c.addMetric(&metrics, pfx+devID, "io_in_progress", metricType, devStats.currIO)
 		c.addMetric(&metrics, pfx+devID, "io_ms", metricType, devStats.ioms)
 		c.addMetric(&metrics, pfx+devID, "io_ms_weighted", metricType, devStats.iomsWeighted)
+
+		if c.derived {
+			if prev, ok := c.lastSample[devID]; ok {
+				c.addDerivedMetrics(&metrics, pfx+devID, prev, devStats, sampleTime.Sub(c.lastSampleTime))
+			}
+			sample[devID] = devStats
+		}
+	}
+
+	if c.derived {
+		c.lastSample = sample
+		c.lastSampleTime = sampleTime
 	}
 
 	c.setStatus(metrics, nil)
 	return nil
 }
 
+// addDerivedMetrics adds the device utilization (percent of elapsed time
+// with io in progress), average queue size, average io wait (await, ms)
+// and average service time (svc_time, ms) between two samples, as iostat
+// reports them. Nothing is added if the counters were reset.
+func (c *Diskstats) addDerivedMetrics(metrics *cgm.Metrics, prefix string, prev, curr *dstats, elapsed time.Duration) {
+	elapsedMS := float64(elapsed) / float64(time.Millisecond)
+	if elapsedMS <= 0 {
+		return
+	}
+	if curr.ioms < prev.ioms || curr.iomsWeighted < prev.iomsWeighted ||
+		curr.readsCompleted < prev.readsCompleted || curr.writesCompleted < prev.writesCompleted ||
+		curr.readms < prev.readms || curr.writems < prev.writems {
+		c.logger.Debug().Str("device", prefix).Msg("counters reset, skipping derived metrics")
+		return
+	}
+
+	ios := float64((curr.readsCompleted - prev.readsCompleted) + (curr.writesCompleted - prev.writesCompleted))
+	ioms := float64(curr.ioms - prev.ioms)
+
+	util := ioms / elapsedMS * 100
+	if util > 100 {
+		util = 100
+	}
+	await := 0.0
+	svcTime := 0.0
+	if ios > 0 {
+		await = float64((curr.readms-prev.readms)+(curr.writems-prev.writems)) / ios
+		svcTime = ioms / ios
+	}
+
+	metricType := "n" // float64
+	c.addMetric(metrics, prefix, "util", metricType, util)
+	c.addMetric(metrics, prefix, "avg_queue_size", metricType, float64(curr.iomsWeighted-prev.iomsWeighted)/elapsedMS)
+	c.addMetric(metrics, prefix, "await", metricType, await)
+	c.addMetric(metrics, prefix, "svc_time", metricType, svcTime)
+}
+
 func (c *Diskstats) getSectorSize(dev string) uint64 {
 	if sz, have := c.sectorSizeCache[dev]; have {
 		return sz
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/rs/zerolog"
 )
 
@@ -25,7 +26,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("no config")
 	{
-		_, err := NewDiskstatsCollector("")
+		_, err := NewDiskstatsCollector("", nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -39,7 +40,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (missing)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "missing"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "missing"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -47,7 +48,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (bad syntax)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "bad_syntax"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "bad_syntax"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -55,7 +56,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (config no settings)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_no_settings"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_no_settings"), nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -75,7 +76,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (id setting)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_id_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_id_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -86,7 +87,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -98,7 +99,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting invalid)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -106,7 +107,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (include regex)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_include_regex_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_include_regex_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -118,7 +119,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (include regex invalid)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_include_regex_invalid_setting"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_include_regex_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -126,7 +127,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (exclude regex)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_exclude_regex_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_exclude_regex_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -138,7 +139,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (exclude regex invalid)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_exclude_regex_invalid_setting"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_exclude_regex_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -146,7 +147,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (metrics enabled setting)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_enabled_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -164,7 +165,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (metrics disabled setting)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_disabled_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -182,7 +183,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (metrics default status enabled)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -193,7 +194,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (metrics default status disabled)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -204,7 +205,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (metrics default status invalid)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -212,7 +213,7 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (run ttl 5m)")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -223,7 +224,26 @@ func TestNewDiskstatsCollector(t *testing.T) {
 
 	t.Log("config (run ttl invalid)")
 	{
-		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"))
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (derived metrics)")
+	{
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_derived_metrics_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if !c.(*Diskstats).derived {
+			t.Fatal("expected derived metrics enabled")
+		}
+	}
+
+	t.Log("config (derived metrics invalid)")
+	{
+		_, err := NewDiskstatsCollector(filepath.Join("testdata", "config_derived_metrics_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -235,7 +255,7 @@ func TestDiskstatsFlush(t *testing.T) {
 
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 
-	c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_file_valid_setting"))
+	c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_file_valid_setting"), nil)
 	if err != nil {
 		t.Fatalf("expected NO error, got (%s)", err)
 	}
@@ -256,7 +276,7 @@ func TestDiskstatsCollect(t *testing.T) {
 
 	t.Log("already running")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -274,7 +294,7 @@ func TestDiskstatsCollect(t *testing.T) {
 
 	t.Log("ttl not expired")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -293,7 +313,7 @@ func TestDiskstatsCollect(t *testing.T) {
 
 	t.Log("good")
 	{
-		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -311,3 +331,70 @@ func TestDiskstatsCollect(t *testing.T) {
 		}
 	}
 }
+
+func TestDiskstatsDerived(t *testing.T) {
+	t.Log("Testing derived metrics")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c, err := NewDiskstatsCollector(filepath.Join("testdata", "config_derived_metrics_valid_setting"), nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	ds := c.(*Diskstats)
+
+	t.Log("	first sample, no derived metrics")
+	{
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		metrics := c.Flush()
+		if _, ok := metrics["diskstats`sda`util"]; ok {
+			t.Fatalf("expected no derived metrics, got %v", metrics)
+		}
+	}
+
+	t.Log("	second sample")
+	{
+		ds.lastEnd = time.Time{}
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		metrics := c.Flush()
+		for _, mn := range []string{"util", "avg_queue_size", "await", "svc_time"} {
+			if _, ok := metrics["diskstats`sda`"+mn]; !ok {
+				t.Fatalf("expected %s, got %v", mn, metrics)
+			}
+		}
+	}
+
+	t.Log("	calculation")
+	{
+		prev := &dstats{readsCompleted: 100, writesCompleted: 100, readms: 1000, writems: 1000, ioms: 1000, iomsWeighted: 2000}
+		curr := &dstats{readsCompleted: 150, writesCompleted: 150, readms: 1500, writems: 2000, ioms: 1500, iomsWeighted: 3000}
+		metrics := cgm.Metrics{}
+		ds.addDerivedMetrics(&metrics, "sda", prev, curr, time.Second)
+		expect := map[string]float64{
+			"sda`util":           50, // 500ms of 1s
+			"sda`avg_queue_size": 1,  // 1000ms weighted / 1000ms
+			"sda`await":          15, // 1500ms / 100 ios
+			"sda`svc_time":       5,  // 500ms / 100 ios
+		}
+		for mn, v := range expect {
+			if m, ok := metrics[mn]; !ok || m.Value.(float64) != v {
+				t.Fatalf("expected %s %v, got %#v", mn, v, metrics[mn])
+			}
+		}
+	}
+
+	t.Log("	counters reset")
+	{
+		prev := &dstats{ioms: 1000}
+		curr := &dstats{ioms: 10}
+		metrics := cgm.Metrics{}
+		ds.addDerivedMetrics(&metrics, "sda", prev, curr, time.Second)
+		if len(metrics) != 0 {
+			t.Fatalf("expected no metrics, got %v", metrics)
+		}
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
-	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -27,8 +26,9 @@ import (
 // IF metrics from the Linux ProcFS
 type IF struct {
 	pfscommon
-	include *regexp.Regexp
-	exclude *regexp.Regexp
+	include  *regexp.Regexp
+	exclude  *regexp.Regexp
+	detailed bool // all net/dev counters and multicast group memberships (net/dev_mcast)
 }
 
 // ifOptions defines what elements can be overriden in a config file
@@ -42,12 +42,13 @@ type ifOptions struct {
 	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
 
 	// collector specific
-	IncludeRegex string `json:"include_regex" toml:"include_regex" yaml:"include_regex"`
-	ExcludeRegex string `json:"exclude_regex" toml:"exclude_regex" yaml:"exclude_regex"`
+	IncludeRegex     string `json:"include_regex" toml:"include_regex" yaml:"include_regex"`
+	ExcludeRegex     string `json:"exclude_regex" toml:"exclude_regex" yaml:"exclude_regex"`
+	DetailedCounters string `json:"detailed_counters" toml:"detailed_counters" yaml:"detailed_counters"`
 }
 
 // NewIFCollector creates new procfs cpu collector
-func NewIFCollector(cfgBaseName string) (collector.Collector, error) {
+func NewIFCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
 	procFile := filepath.Join("net", "dev")
 
 	c := IF{}
@@ -62,7 +63,7 @@ func NewIFCollector(cfgBaseName string) (collector.Collector, error) {
 	c.include = defaultIncludeRegex
 	c.exclude = regexp.MustCompile(fmt.Sprintf(regexPat, `lo`))
 
-	if cfgBaseName == "" {
+	if cfgBaseName == "" && cfgSection == nil {
 		if _, err := os.Stat(c.file); os.IsNotExist(err) {
 			return nil, errors.Wrap(err, c.pkgID)
 		}
@@ -70,7 +71,7 @@ func NewIFCollector(cfgBaseName string) (collector.Collector, error) {
 	}
 
 	var opts ifOptions
-	err := config.LoadConfigFile(cfgBaseName, &opts)
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
 	if err != nil {
 		if strings.Contains(err.Error(), "no config found matching") {
 			return &c, nil
@@ -97,6 +98,14 @@ func NewIFCollector(cfgBaseName string) (collector.Collector, error) {
 		c.exclude = rx
 	}
 
+	if opts.DetailedCounters != "" {
+		detailed, err := strconv.ParseBool(opts.DetailedCounters)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing detailed_counters", c.pkgID)
+		}
+		c.detailed = detailed
+	}
+
 	if opts.ID != "" {
 		c.id = opts.ID
 	}
@@ -148,7 +157,7 @@ func (c *IF) Collect() error {
 
 	if c.runTTL > time.Duration(0) {
 		if time.Since(c.lastEnd) < c.runTTL {
-			c.logger.Warn().Msg(collector.ErrTTLNotExpired.Error())
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
 			c.Unlock()
 			return collector.ErrTTLNotExpired
 		}
@@ -168,6 +177,12 @@ func (c *IF) Collect() error {
 		return errors.Wrap(err, c.pkgID)
 	}
 
+	if c.detailed {
+		if err := c.mcastCollect(&metrics); err != nil {
+			c.logger.Warn().Err(err).Msg("dev_mcast")
+		}
+	}
+
 	if err := c.snmpCollect(&metrics); err != nil {
 		c.logger.Warn().Err(err).Msg("snmp")
 	}
@@ -206,27 +221,32 @@ func (c *IF) ifCollect(metrics *cgm.Metrics) error {
 	// 16 transmit carrier
 	// 17 transmit compressed
 	fieldsExpected := 17
-	stats := []struct {
+	type ifstat struct {
 		idx  int
 		name string
 		desc string
-	}{
+	}
+	stats := []ifstat{
 		{idx: 1, name: "in_bytes", desc: "receive bytes"},
 		{idx: 2, name: "in_packets", desc: "receive packets"},
 		{idx: 3, name: "in_errors", desc: "receive errs"},
 		{idx: 4, name: "in_drop", desc: "receive drop"},
 		{idx: 5, name: "in_fifo_overrun", desc: "receive fifo"},
-		// {idx: 6, name: "in_frames", desc: "recevie frames"},
-		// {idx: 7, name: "in_compressed", desc: "receive compressed"},
-		// {idx: 8, name: "in_multicast", desc: "receive multicast"},
 		{idx: 9, name: "out_bytes", desc: "transmit bytes"},
 		{idx: 10, name: "out_packets", desc: "transmit packets"},
 		{idx: 11, name: "out_errors", desc: "transmit errors"},
 		{idx: 12, name: "out_drop", desc: "transmit drop"},
 		{idx: 13, name: "out_fifo_overrun", desc: "trasnmit fifo"},
-		// {idx: 14, name: "out_colls", desc: "transmit colls"},
-		// {idx: 15, name: "out_carrier", desc: "transmit carrier"},
-		// {idx: 16, name: "out_compressed", desc: "transmit compressed"},
+	}
+	if c.detailed {
+		stats = append(stats,
+			ifstat{idx: 6, name: "in_frame_errors", desc: "receive frame"},
+			ifstat{idx: 7, name: "in_compressed", desc: "receive compressed"},
+			ifstat{idx: 8, name: "in_multicast", desc: "receive multicast"},
+			ifstat{idx: 14, name: "out_collisions", desc: "transmit colls"},
+			ifstat{idx: 15, name: "out_carrier_errors", desc: "transmit carrier"},
+			ifstat{idx: 16, name: "out_compressed", desc: "transmit compressed"},
+		)
 	}
 
 	scanner := bufio.NewScanner(f)
@@ -273,6 +293,52 @@ func (c *IF) ifCollect(metrics *cgm.Metrics) error {
 	return nil
 }
 
+// mcastCollect gets the number of multicast group memberships of each
+// interface from /proc/net/dev_mcast
+func (c *IF) mcastCollect(metrics *cgm.Metrics) error {
+	mcastFile := strings.Replace(c.file, "dev", "dev_mcast", -1)
+	f, err := os.Open(mcastFile)
+	if err != nil {
+		return errors.Wrap(err, "mcastCollect")
+	}
+	defer f.Close()
+
+	// 1 interface index
+	// 2 interface name
+	// 3 number of references to the group
+	// 4 global use
+	// 5 multicast address
+	groups := make(map[string]uint64)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 5 {
+			c.logger.Warn().Int("expected", 5).Int("found", len(fields)).Msg("dev_mcast - invalid number of fields")
+			continue
+		}
+
+		iface := fields[1]
+		if c.exclude.MatchString(iface) || !c.include.MatchString(iface) {
+			continue
+		}
+
+		groups[iface]++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "mcastCollect parsing %s", f.Name())
+	}
+
+	pfx := c.id + metricNameSeparator
+	metricType := "L" // uint64
+	for iface, n := range groups {
+		c.addMetric(metrics, pfx+iface, "multicast_groups", metricType, n)
+	}
+
+	return nil
+}
+
 type rawstat struct {
 	name string
 	val  string
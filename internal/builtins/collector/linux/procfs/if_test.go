@@ -25,7 +25,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("no config")
 	{
-		_, err := NewIFCollector("")
+		_, err := NewIFCollector("", nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -39,7 +39,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (missing)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "missing"))
+		_, err := NewIFCollector(filepath.Join("testdata", "missing"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -47,7 +47,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (bad syntax)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "bad_syntax"))
+		_, err := NewIFCollector(filepath.Join("testdata", "bad_syntax"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -55,7 +55,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (config no settings)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_no_settings"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_no_settings"), nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -75,7 +75,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (id setting)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_id_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_id_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -86,7 +86,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -98,7 +98,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting invalid)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"))
+		_, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -106,7 +106,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (include regex)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_include_regex_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_include_regex_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -118,7 +118,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (include regex invalid)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "config_include_regex_invalid_setting"))
+		_, err := NewIFCollector(filepath.Join("testdata", "config_include_regex_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -126,7 +126,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (exclude regex)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_exclude_regex_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_exclude_regex_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -138,7 +138,26 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (exclude regex invalid)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "config_exclude_regex_invalid_setting"))
+		_, err := NewIFCollector(filepath.Join("testdata", "config_exclude_regex_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (detailed counters)")
+	{
+		c, err := NewIFCollector(filepath.Join("testdata", "config_detailed_counters_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if !c.(*IF).detailed {
+			t.Fatal("expected true")
+		}
+	}
+
+	t.Log("config (detailed counters invalid)")
+	{
+		_, err := NewIFCollector(filepath.Join("testdata", "config_detailed_counters_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -146,7 +165,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (metrics enabled setting)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_enabled_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -164,7 +183,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (metrics disabled setting)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_disabled_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -182,7 +201,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (metrics default status enabled)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -193,7 +212,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (metrics default status disabled)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -204,7 +223,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (metrics default status invalid)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"))
+		_, err := NewIFCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -212,7 +231,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (run ttl 5m)")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -223,7 +242,7 @@ func TestNewIFCollector(t *testing.T) {
 
 	t.Log("config (run ttl invalid)")
 	{
-		_, err := NewIFCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"))
+		_, err := NewIFCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -235,7 +254,7 @@ func TestIFFlush(t *testing.T) {
 
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 
-	c, err := NewIFCollector(filepath.Join("testdata", "config_file_valid_setting"))
+	c, err := NewIFCollector(filepath.Join("testdata", "config_file_valid_setting"), nil)
 	if err != nil {
 		t.Fatalf("expected NO error, got (%s)", err)
 	}
@@ -256,7 +275,7 @@ func TestIFCollect(t *testing.T) {
 
 	t.Log("already running")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -274,7 +293,7 @@ func TestIFCollect(t *testing.T) {
 
 	t.Log("ttl not expired")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -293,7 +312,7 @@ func TestIFCollect(t *testing.T) {
 
 	t.Log("good")
 	{
-		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -311,3 +330,64 @@ func TestIFCollect(t *testing.T) {
 		}
 	}
 }
+
+func TestIFCollectDetailed(t *testing.T) {
+	t.Log("Testing Collect w/detailed counters")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	detailed := []string{"in_frame_errors", "in_compressed", "in_multicast", "out_collisions", "out_carrier_errors", "out_compressed"}
+
+	t.Log("default")
+	{
+		c, err := NewIFCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		metrics := c.Flush()
+		if _, found := metrics["if`enp0s3`in_bytes"]; !found {
+			t.Fatalf("expected in_bytes, got %v", metrics)
+		}
+		for _, name := range append(detailed, "multicast_groups") {
+			if _, found := metrics["if`enp0s3`"+name]; found {
+				t.Fatalf("expected no %s, got %v", name, metrics)
+			}
+		}
+	}
+
+	t.Log("detailed")
+	{
+		c, err := NewIFCollector(filepath.Join("testdata", "config_detailed_counters_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		metrics := c.Flush()
+		for _, name := range detailed {
+			if _, found := metrics["if`enp0s3`"+name]; !found {
+				t.Fatalf("expected %s, got %v", name, metrics)
+			}
+		}
+		expect := map[string]uint64{
+			"if`enp0s3`multicast_groups": 3,
+			"if`enp0s8`multicast_groups": 1,
+		}
+		for name, ev := range expect {
+			m, found := metrics[name]
+			if !found {
+				t.Fatalf("expected %s, got %v", name, metrics)
+			}
+			if m.Value.(uint64) != ev {
+				t.Fatalf("expected %s=%d, got %v", name, ev, m.Value)
+			}
+		}
+		if _, found := metrics["if`lo`multicast_groups"]; found {
+			t.Fatalf("expected lo excluded, got %v", metrics)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"bufio"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// IPVS metrics from the Linux ProcFS IP virtual server (LVS, kube-proxy ipvs mode)
+type IPVS struct {
+	pfscommon
+	statsFile     string
+	notLoadedSeen bool
+}
+
+// ipvsOptions defines what elements can be overriden in a config file
+type ipvsOptions struct {
+	// common
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	ProcFSPath           string   `json:"procfs_path" toml:"procfs_path" yaml:"procfs_path"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" yaml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+}
+
+// ipvsStatsFields are the ip_vs_stats totals, by position
+var ipvsStatsFields = []string{
+	0: "connections",
+	1: "incoming_packets",
+	2: "outgoing_packets",
+	3: "incoming_bytes",
+	4: "outgoing_bytes",
+}
+
+// ipvsService is a virtual service and the totals of its real servers
+type ipvsService struct {
+	tagList  string
+	active   uint64
+	inactive uint64
+	servers  uint64
+}
+
+// NewIPVSCollector creates new procfs ipvs collector
+func NewIPVSCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
+	c := IPVS{}
+	c.id = "ipvs"
+	c.pkgID = "builtins.linux.procfs." + c.id
+	c.procFSPath = "/proc"
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+	c.setFiles()
+
+	// NOTE: missing ipvs files are not an error, the ip_vs
+	//       module may simply not be loaded (yet), see Collect
+
+	if cfgBaseName == "" && cfgSection == nil {
+		return &c, nil
+	}
+
+	var opts ipvsOptions
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if opts.ID != "" {
+		c.id = opts.ID
+	}
+
+	if opts.ProcFSPath != "" {
+		c.procFSPath = opts.ProcFSPath
+		c.setFiles()
+	}
+
+	if len(opts.MetricsEnabled) > 0 {
+		for _, name := range opts.MetricsEnabled {
+			c.metricStatus[name] = true
+		}
+	}
+	if len(opts.MetricsDisabled) > 0 {
+		for _, name := range opts.MetricsDisabled {
+			c.metricStatus[name] = false
+		}
+	}
+
+	if opts.MetricsDefaultStatus != "" {
+		if ok, _ := regexp.MatchString(`^(enabled|disabled)$`, strings.ToLower(opts.MetricsDefaultStatus)); ok {
+			c.metricDefaultActive = strings.ToLower(opts.MetricsDefaultStatus) == metricStatusEnabled
+		} else {
+			return nil, errors.Errorf("%s invalid metric default status (%s)", c.pkgID, opts.MetricsDefaultStatus)
+		}
+	}
+
+	if opts.RunTTL != "" {
+		dur, err := time.ParseDuration(opts.RunTTL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing run_ttl", c.pkgID)
+		}
+		c.runTTL = dur
+	}
+
+	return &c, nil
+}
+
+// setFiles sets the procfs file paths based on the procfs path
+func (c *IPVS) setFiles() {
+	c.file = filepath.Join(c.procFSPath, "net", "ip_vs")
+	c.statsFile = filepath.Join(c.procFSPath, "net", "ip_vs_stats")
+}
+
+// Collect metrics from the procfs resources
+func (c *IPVS) Collect() error {
+	metrics := cgm.Metrics{}
+
+	c.Lock()
+
+	if c.runTTL > time.Duration(0) {
+		if time.Since(c.lastEnd) < c.runTTL {
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
+			c.Unlock()
+			return collector.ErrTTLNotExpired
+		}
+	}
+	if c.running {
+		c.logger.Warn().Msg(collector.ErrAlreadyRunning.Error())
+		c.Unlock()
+		return collector.ErrAlreadyRunning
+	}
+
+	c.running = true
+	c.lastStart = time.Now()
+	c.Unlock()
+
+	if _, err := os.Stat(c.file); os.IsNotExist(err) {
+		// only log once (each time it transitions to not loaded)
+		if !c.notLoadedSeen {
+			c.logger.Info().Str("file", c.file).Msg("ipvs not loaded, no metrics")
+			c.notLoadedSeen = true
+		}
+		c.setStatus(metrics, nil)
+		return nil
+	}
+	c.notLoadedSeen = false
+
+	if err := c.serviceMetrics(&metrics); err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+
+	if err := c.statsMetrics(&metrics); err != nil {
+		c.logger.Warn().Err(err).Str("file", c.statsFile).Msg("reading stats")
+	}
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// serviceMetrics parses the virtual services and their real servers from
+// /proc/net/ip_vs, e.g.
+//
+//	IP Virtual Server version 1.2.1 (size=4096)
+//	Prot LocalAddress:Port Scheduler Flags
+//	  -> RemoteAddress:Port Forward Weight ActiveConn InActConn
+//	TCP  0A000001:0050 rr
+//	  -> 0A000002:0050      Masq    1      4          12
+//	FWM  00000001 wlc
+//	  -> [2001:0db8:0000:0000:0000:0000:0000:0002]:0050      Route   1      0          0
+func (c *IPVS) serviceMetrics(metrics *cgm.Metrics) error {
+	f, err := os.Open(c.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var svc *ipvsService
+	services := []*ipvsService{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "TCP", "UDP", "SCTP":
+			ip, port, err := parseIPVSAddr(fields[1])
+			if err != nil {
+				c.logger.Warn().Err(err).Str("service", fields[1]).Msg("parsing virtual service address")
+				svc = nil
+				continue
+			}
+			svc = &ipvsService{tagList: "protocol:" + strings.ToLower(fields[0]) + ",vip:" + ip + ",vport:" + port}
+			services = append(services, svc)
+
+		case "FWM":
+			mark, err := strconv.ParseUint(fields[1], 16, 32)
+			if err != nil {
+				c.logger.Warn().Err(err).Str("service", fields[1]).Msg("parsing virtual service firewall mark")
+				svc = nil
+				continue
+			}
+			svc = &ipvsService{tagList: "protocol:fwm,fwmark:" + strconv.FormatUint(mark, 10)}
+			services = append(services, svc)
+
+		case "->":
+			if svc == nil || len(fields) < 6 {
+				continue // header, or real server of a service which could not be parsed
+			}
+			ip, port, err := parseIPVSAddr(fields[1])
+			if err != nil {
+				c.logger.Warn().Err(err).Str("real_server", fields[1]).Msg("parsing real server address")
+				continue
+			}
+			values := make([]uint64, 3)
+			for i, field := range fields[3:6] {
+				v, err := strconv.ParseUint(field, 10, 64)
+				if err != nil {
+					c.logger.Warn().Err(err).Str("real_server", fields[1]).Msg("parsing real server counters")
+					values = nil
+					break
+				}
+				values[i] = v
+			}
+			if values == nil {
+				continue
+			}
+			tagList := svc.tagList + ",rip:" + ip + ",rport:" + port
+			c.addTaggedMetric(metrics, "real_server`weight", tagList, values[0])
+			c.addTaggedMetric(metrics, "real_server`active_connections", tagList, values[1])
+			c.addTaggedMetric(metrics, "real_server`inactive_connections", tagList, values[2])
+			svc.active += values[1]
+			svc.inactive += values[2]
+			svc.servers++
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "parsing %s", f.Name())
+	}
+
+	for _, svc := range services {
+		c.addTaggedMetric(metrics, "service`active_connections", svc.tagList, svc.active)
+		c.addTaggedMetric(metrics, "service`inactive_connections", svc.tagList, svc.inactive)
+		c.addTaggedMetric(metrics, "service`real_servers", svc.tagList, svc.servers)
+	}
+
+	return nil
+}
+
+// statsMetrics parses the totals (since the module was loaded) from
+// /proc/net/ip_vs_stats, the first line of (hex) values. the second
+// line of values, the current rates, is not used.
+func (c *IPVS) statsMetrics(metrics *cgm.Metrics) error {
+	f, err := os.Open(c.statsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		if len(values) != len(ipvsStatsFields) {
+			continue
+		}
+		totals := make([]uint64, len(values))
+		for i, value := range values {
+			v, err := strconv.ParseUint(value, 16, 64)
+			if err != nil {
+				totals = nil
+				break
+			}
+			totals[i] = v
+		}
+		if totals == nil {
+			continue // header
+		}
+		for i, field := range ipvsStatsFields {
+			c.addMetric(metrics, c.id, field, "L", totals[i])
+		}
+		return nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "parsing %s", f.Name())
+	}
+
+	return errors.Errorf("no totals found in %s", f.Name())
+}
+
+// addTaggedMetric adds a counter with stream tags, metric status applies to
+// the metric name without tags (e.g. disabling "real_server`weight" disables
+// it for all real servers)
+func (c *IPVS) addTaggedMetric(metrics *cgm.Metrics, mname, tagList string, mval uint64) {
+	active, found := c.metricStatus[mname]
+	if (found && !active) || (!found && !c.metricDefaultActive) {
+		return
+	}
+
+	st, err := tags.PrepStreamTags(tagList)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("metric", mname).Str("tags", tagList).Msg("ignoring tags")
+	}
+
+	(*metrics)[c.id+metricNameSeparator+mname+st] = cgm.Metric{Type: "L", Value: mval}
+}
+
+// parseIPVSAddr parses an ip_vs address and port, a hex ipv4 address
+// (0A000001:0050) or an ipv6 address ([2001:0db8:...]:0050) with a hex
+// port. The ':' in ipv6 addresses is replaced with '_' (stream tag values
+// may not contain ':').
+func parseIPVSAddr(addr string) (string, string, error) {
+	idx := strings.LastIndex(addr, ":")
+	if idx < 0 {
+		return "", "", errors.Errorf("invalid address (%s)", addr)
+	}
+
+	port, err := strconv.ParseUint(addr[idx+1:], 16, 16)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid port")
+	}
+
+	host := addr[:idx]
+	var ip net.IP
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		ip = net.ParseIP(host[1 : len(host)-1])
+	} else if b, err := hex.DecodeString(host); err == nil && len(b) == net.IPv4len {
+		ip = net.IPv4(b[0], b[1], b[2], b[3])
+	}
+	if ip == nil {
+		return "", "", errors.Errorf("invalid address (%s)", host)
+	}
+
+	return strings.Replace(ip.String(), ":", "_", -1), strconv.FormatUint(port, 10), nil
+}
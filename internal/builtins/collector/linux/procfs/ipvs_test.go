@@ -0,0 +1,228 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/rs/zerolog"
+)
+
+func TestNewIPVSCollector(t *testing.T) {
+	t.Log("Testing NewIPVSCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewIPVSCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewIPVSCollector(filepath.Join("testdata", "missing"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewIPVSCollector(filepath.Join("testdata", "bad_syntax"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (id setting)")
+	{
+		c, err := NewIPVSCollector(filepath.Join("testdata", "config_id_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*IPVS).id != "foo" {
+			t.Fatalf("expected foo, got (%s)", c.ID())
+		}
+	}
+
+	t.Log("config (procfs path setting)")
+	{
+		c, err := NewIPVSCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := filepath.Join("testdata", "net", "ip_vs")
+		if c.(*IPVS).file != expect {
+			t.Fatalf("expected (%s), got (%s)", expect, c.(*IPVS).file)
+		}
+	}
+
+	t.Log("config (metrics default status invalid)")
+	{
+		_, err := NewIPVSCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (run ttl setting)")
+	{
+		c, err := NewIPVSCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*IPVS).runTTL != 5*time.Minute {
+			t.Fatal("expected 5m")
+		}
+	}
+}
+
+func TestIPVSCollect(t *testing.T) {
+	t.Log("Testing Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("already running")
+	{
+		c, err := NewIPVSCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c.(*IPVS).running = true
+
+		if err := c.Collect(); err != nil {
+			if err.Error() != collector.ErrAlreadyRunning.Error() {
+				t.Fatalf("expected (%s) got (%s)", collector.ErrAlreadyRunning, err)
+			}
+		} else {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("not loaded")
+	{
+		c, err := NewIPVSCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*IPVS).procFSPath = filepath.Join("testdata", "missing")
+		c.(*IPVS).setFiles()
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 0 {
+			t.Fatalf("expected no metrics, got %v", metrics)
+		}
+	}
+
+	t.Log("good")
+	{
+		c, err := NewIPVSCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		// 5 totals, 3 per service (3), 3 per real server (4)
+		if len(metrics) != 26 {
+			t.Fatalf("expected 26 metrics, got %d %v", len(metrics), metrics)
+		}
+
+		tests := []struct {
+			name  string
+			value uint64
+		}{
+			{"ipvs`connections", 23765872},
+			{"ipvs`incoming_packets", 3811989221},
+			{"ipvs`outgoing_packets", 0},
+			{"ipvs`incoming_bytes", 5624469947382},
+			{"ipvs`service`active_connections|ST[protocol:tcp,vip:10.0.0.1,vport:80]", 10},
+			{"ipvs`service`inactive_connections|ST[protocol:tcp,vip:10.0.0.1,vport:80]", 15},
+			{"ipvs`service`real_servers|ST[protocol:tcp,vip:10.0.0.1,vport:80]", 2},
+			{"ipvs`service`inactive_connections|ST[protocol:udp,vip:10.0.0.1,vport:53]", 5},
+			{"ipvs`service`real_servers|ST[fwmark:1,protocol:fwm]", 1},
+			{"ipvs`real_server`weight|ST[protocol:tcp,rip:10.0.0.3,rport:80,vip:10.0.0.1,vport:80]", 2},
+			{"ipvs`real_server`active_connections|ST[protocol:tcp,rip:10.0.0.2,rport:80,vip:10.0.0.1,vport:80]", 4},
+			{"ipvs`real_server`inactive_connections|ST[protocol:tcp,rip:10.0.0.2,rport:80,vip:10.0.0.1,vport:80]", 12},
+			{"ipvs`real_server`active_connections|ST[fwmark:1,protocol:fwm,rip:2001_db8__2,rport:443]", 1},
+		}
+		for _, test := range tests {
+			m, ok := metrics[test.name]
+			if !ok {
+				t.Fatalf("expected %s, got %v", test.name, metrics)
+			}
+			if m.Value != test.value {
+				t.Fatalf("%s expected %v, got %v", test.name, test.value, m.Value)
+			}
+		}
+	}
+
+	t.Log("metric disabled")
+	{
+		c, err := NewIPVSCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*IPVS).metricStatus["real_server`weight"] = false
+		c.(*IPVS).metricStatus["connections"] = false
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 21 {
+			t.Fatalf("expected 21 metrics, got %d %v", len(metrics), metrics)
+		}
+	}
+}
+
+func TestParseIPVSAddr(t *testing.T) {
+	t.Log("Testing parseIPVSAddr")
+
+	tests := []struct {
+		addr string
+		ip   string
+		port string
+		err  bool
+	}{
+		{"0A000001:0050", "10.0.0.1", "80", false},
+		{"[2001:0db8:0000:0000:0000:0000:0000:0002]:01BB", "2001_db8__2", "443", false},
+		{"0A0000:0050", "", "", true},
+		{"0A000001", "", "", true},
+		{"0A000001:ZZ", "", "", true},
+		{"[foo]:0050", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Logf("\t%s", tt.addr)
+		ip, port, err := parseIPVSAddr(tt.addr)
+		if tt.err {
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if ip != tt.ip || port != tt.port {
+			t.Fatalf("expected (%s %s) got (%s %s)", tt.ip, tt.port, ip, port)
+		}
+	}
+}
@@ -17,7 +17,6 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
-	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -41,7 +40,7 @@ type loadavgOptions struct {
 }
 
 // NewLoadavgCollector creates new procfs cpu collector
-func NewLoadavgCollector(cfgBaseName string) (collector.Collector, error) {
+func NewLoadavgCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
 	procFile := "loadavg"
 
 	c := Loadavg{}
@@ -53,7 +52,7 @@ func NewLoadavgCollector(cfgBaseName string) (collector.Collector, error) {
 	c.metricStatus = map[string]bool{}
 	c.metricDefaultActive = true
 
-	if cfgBaseName == "" {
+	if cfgBaseName == "" && cfgSection == nil {
 		if _, err := os.Stat(c.file); os.IsNotExist(err) {
 			return nil, errors.Wrap(err, c.pkgID)
 		}
@@ -61,7 +60,7 @@ func NewLoadavgCollector(cfgBaseName string) (collector.Collector, error) {
 	}
 
 	var opts loadavgOptions
-	err := config.LoadConfigFile(cfgBaseName, &opts)
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
 	if err != nil {
 		if strings.Contains(err.Error(), "no config found matching") {
 			return &c, nil
@@ -123,7 +122,7 @@ func (c *Loadavg) Collect() error {
 
 	if c.runTTL > time.Duration(0) {
 		if time.Since(c.lastEnd) < c.runTTL {
-			c.logger.Warn().Msg(collector.ErrTTLNotExpired.Error())
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
 			c.Unlock()
 			return collector.ErrTTLNotExpired
 		}
@@ -9,6 +9,7 @@ package procfs
 
 import (
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 	"time"
@@ -24,7 +25,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("no config")
 	{
-		_, err := NewLoadavgCollector("")
+		_, err := NewLoadavgCollector("", nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -38,7 +39,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (missing)")
 	{
-		_, err := NewLoadavgCollector(filepath.Join("testdata", "missing"))
+		_, err := NewLoadavgCollector(filepath.Join("testdata", "missing"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -46,7 +47,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (bad syntax)")
 	{
-		_, err := NewLoadavgCollector(filepath.Join("testdata", "bad_syntax"))
+		_, err := NewLoadavgCollector(filepath.Join("testdata", "bad_syntax"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -54,7 +55,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (config no settings)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_no_settings"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_no_settings"), nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -74,7 +75,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (id setting)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_id_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_id_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -85,7 +86,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -97,7 +98,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting invalid)")
 	{
-		_, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"))
+		_, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -105,7 +106,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (metrics enabled setting)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_enabled_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -123,7 +124,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (metrics disabled setting)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_disabled_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -141,7 +142,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (metrics default status enabled)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -152,7 +153,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (metrics default status disabled)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -163,7 +164,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (metrics default status invalid)")
 	{
-		_, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"))
+		_, err := NewLoadavgCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -171,7 +172,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (run ttl 5m)")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -182,7 +183,7 @@ func TestNewLoadavgCollector(t *testing.T) {
 
 	t.Log("config (run ttl invalid)")
 	{
-		_, err := NewLoadavgCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"))
+		_, err := NewLoadavgCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -194,7 +195,7 @@ func TestLoadavgFlush(t *testing.T) {
 
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 
-	c, err := NewLoadavgCollector(filepath.Join("testdata", "config_file_valid_setting"))
+	c, err := NewLoadavgCollector(filepath.Join("testdata", "config_file_valid_setting"), nil)
 	if err != nil {
 		t.Fatalf("expected NO error, got (%s)", err)
 	}
@@ -215,7 +216,7 @@ func TestLoadavgCollect(t *testing.T) {
 
 	t.Log("already running")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -233,7 +234,7 @@ func TestLoadavgCollect(t *testing.T) {
 
 	t.Log("ttl not expired")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -250,9 +251,34 @@ func TestLoadavgCollect(t *testing.T) {
 		}
 	}
 
+	t.Log("ttl not expired, last metrics")
+	{
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c.(*Loadavg).runTTL = 60 * time.Second
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		first := c.Flush()
+		if len(first) == 0 {
+			t.Fatal("expected metrics")
+		}
+
+		if err := c.Collect(); err != collector.ErrTTLNotExpired {
+			t.Fatalf("expected (%s) got (%v)", collector.ErrTTLNotExpired, err)
+		}
+		if !reflect.DeepEqual(c.Flush(), first) {
+			t.Fatal("expected last metrics collected")
+		}
+	}
+
 	t.Log("good")
 	{
-		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewLoadavgCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -14,6 +14,7 @@ import (
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
 	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -28,19 +29,38 @@ func New() ([]collector.Collector, error) {
 
 	l := log.With().Str("pkg", "builtins.procfs").Logger()
 
-	enbledCollectors := viper.GetStringSlice(config.KeyCollectors)
+	enbledCollectors := config.EnabledCollectors()
 	if len(enbledCollectors) == 0 {
 		l.Info().Msg("no builtin collectors enabled")
 		return none, nil
 	}
 
+	// a section in the collectors config file takes precedence
+	// over the collector's own <name>_collector config file
+	sections, err := config.LoadConfigSections(path.Join(defaults.EtcPath, collectorsConfigBase))
+	if err != nil {
+		return none, errors.Wrap(err, "collectors config")
+	}
+
 	collectors := make([]collector.Collector, 0, len(enbledCollectors))
 	initErrMsg := "initializing builtin collector"
 	for _, name := range enbledCollectors {
-		cfgBase := name + "_collector"
+		cfgBase := path.Join(defaults.EtcPath, name+"_collector")
+		cfgSection := sections[name]
+		if cfgSection != nil {
+			l.Debug().Str("name", name).Msg("using collectors config section")
+		}
 		switch name {
+		case "conntrack":
+			c, err := NewConntrackCollector(cfgBase, cfgSection)
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
 		case "cpu":
-			c, err := NewCPUCollector(path.Join(defaults.EtcPath, cfgBase))
+			c, err := NewCPUCollector(cfgBase, cfgSection)
 			if err != nil {
 				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
 				continue
@@ -48,7 +68,7 @@ func New() ([]collector.Collector, error) {
 			collectors = append(collectors, c)
 
 		case "diskstats":
-			c, err := NewDiskstatsCollector(path.Join(defaults.EtcPath, cfgBase))
+			c, err := NewDiskstatsCollector(cfgBase, cfgSection)
 			if err != nil {
 				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
 				continue
@@ -56,7 +76,15 @@ func New() ([]collector.Collector, error) {
 			collectors = append(collectors, c)
 
 		case "if":
-			c, err := NewIFCollector(path.Join(defaults.EtcPath, cfgBase))
+			c, err := NewIFCollector(cfgBase, cfgSection)
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "ipvs":
+			c, err := NewIPVSCollector(cfgBase, cfgSection)
 			if err != nil {
 				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
 				continue
@@ -64,7 +92,31 @@ func New() ([]collector.Collector, error) {
 			collectors = append(collectors, c)
 
 		case "loadavg":
-			c, err := NewLoadavgCollector(path.Join(defaults.EtcPath, cfgBase))
+			c, err := NewLoadavgCollector(cfgBase, cfgSection)
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "mdstat":
+			c, err := NewMDStatCollector(cfgBase, cfgSection)
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "softnet":
+			c, err := NewSoftnetCollector(cfgBase, cfgSection)
+			if err != nil {
+				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
+				continue
+			}
+			collectors = append(collectors, c)
+
+		case "thermal":
+			c, err := NewThermalCollector(cfgBase, cfgSection)
 			if err != nil {
 				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
 				continue
@@ -72,7 +124,7 @@ func New() ([]collector.Collector, error) {
 			collectors = append(collectors, c)
 
 		case "vm":
-			c, err := NewVMCollector(path.Join(defaults.EtcPath, cfgBase))
+			c, err := NewVMCollector(cfgBase, cfgSection)
 			if err != nil {
 				l.Error().Str("name", name).Err(err).Msg(initErrMsg)
 				continue
@@ -80,6 +132,9 @@ func New() ([]collector.Collector, error) {
 			collectors = append(collectors, c)
 
 		default:
+			if viper.GetBool(config.KeyCollectorsStrict) {
+				return none, errors.Errorf("unknown builtin collector (%s)", name)
+			}
 			l.Warn().Str("name", name).Msg("unknown builtin collector, ignoring")
 		}
 	}
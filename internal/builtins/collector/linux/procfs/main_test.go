@@ -38,3 +38,32 @@ func TestNew(t *testing.T) {
 		}
 	}
 }
+
+func TestNewStrict(t *testing.T) {
+	t.Log("Testing New (strict)")
+
+	viper.Reset()
+	viper.Set(config.KeyCollectorsStrict, true)
+	defer viper.Reset()
+
+	t.Log("unknown collector")
+	{
+		viper.Set(config.KeyCollectors, []string{"cpu", "cpuu"})
+		_, err := New()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != "unknown builtin collector (cpuu)" {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("known collectors")
+	{
+		viper.Set(config.KeyCollectors, []string{"cpu", "vm"})
+		_, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+}
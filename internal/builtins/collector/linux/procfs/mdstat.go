@@ -0,0 +1,305 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// MDStat metrics from the Linux ProcFS md (software RAID) status
+type MDStat struct {
+	pfscommon
+	notLoadedSeen bool
+}
+
+// mdstatOptions defines what elements can be overriden in a config file
+type mdstatOptions struct {
+	// common
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	ProcFSPath           string   `json:"procfs_path" toml:"procfs_path" yaml:"procfs_path"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" yaml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+}
+
+// mdArray is the status of one md array
+type mdArray struct {
+	name         string
+	active       bool
+	disksTotal   int  // raid disks, from [total/active]
+	disksActive  int  // working disks, from [total/active]
+	haveCounts   bool // [total/active] present (not for raid0, linear or inactive arrays)
+	disksFailed  int
+	disksSpare   int
+	syncAction   string // resync, recovery, reshape or check (empty if none)
+	syncProgress float64
+}
+
+var (
+	mdArrayRx    = regexp.MustCompile(`^(md[0-9a-z_]+)\s*:\s*(\S+)(.*)$`)
+	mdCountsRx   = regexp.MustCompile(`\[([0-9]+)/([0-9]+)\]`)
+	mdProgressRx = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*([0-9.]+)%`)
+	mdPendingRx  = regexp.MustCompile(`(resync|recovery|reshape|check)\s*=\s*(DELAYED|PENDING)`)
+)
+
+// NewMDStatCollector creates new procfs mdstat collector
+func NewMDStatCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
+	procFile := "mdstat"
+
+	c := MDStat{}
+	c.id = "mdstat"
+	c.pkgID = "builtins.linux.procfs." + c.id
+	c.procFSPath = "/proc"
+	c.file = filepath.Join(c.procFSPath, procFile)
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+
+	// NOTE: a missing mdstat file is not an error, the md module
+	//       may simply not be loaded, see Collect
+
+	if cfgBaseName == "" && cfgSection == nil {
+		return &c, nil
+	}
+
+	var opts mdstatOptions
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if opts.ID != "" {
+		c.id = opts.ID
+	}
+
+	if opts.ProcFSPath != "" {
+		c.procFSPath = opts.ProcFSPath
+		c.file = filepath.Join(c.procFSPath, procFile)
+	}
+
+	if len(opts.MetricsEnabled) > 0 {
+		for _, name := range opts.MetricsEnabled {
+			c.metricStatus[name] = true
+		}
+	}
+	if len(opts.MetricsDisabled) > 0 {
+		for _, name := range opts.MetricsDisabled {
+			c.metricStatus[name] = false
+		}
+	}
+
+	if opts.MetricsDefaultStatus != "" {
+		if ok, _ := regexp.MatchString(`^(enabled|disabled)$`, strings.ToLower(opts.MetricsDefaultStatus)); ok {
+			c.metricDefaultActive = strings.ToLower(opts.MetricsDefaultStatus) == metricStatusEnabled
+		} else {
+			return nil, errors.Errorf("%s invalid metric default status (%s)", c.pkgID, opts.MetricsDefaultStatus)
+		}
+	}
+
+	if opts.RunTTL != "" {
+		dur, err := time.ParseDuration(opts.RunTTL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing run_ttl", c.pkgID)
+		}
+		c.runTTL = dur
+	}
+
+	return &c, nil
+}
+
+// Collect metrics from the procfs resource
+func (c *MDStat) Collect() error {
+	metrics := cgm.Metrics{}
+
+	c.Lock()
+
+	if c.runTTL > time.Duration(0) {
+		if time.Since(c.lastEnd) < c.runTTL {
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
+			c.Unlock()
+			return collector.ErrTTLNotExpired
+		}
+	}
+	if c.running {
+		c.logger.Warn().Msg(collector.ErrAlreadyRunning.Error())
+		c.Unlock()
+		return collector.ErrAlreadyRunning
+	}
+
+	c.running = true
+	c.lastStart = time.Now()
+	c.Unlock()
+
+	f, err := os.Open(c.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// only log once (each time it transitions to not loaded)
+			if !c.notLoadedSeen {
+				c.logger.Info().Str("file", c.file).Msg("md not loaded, no metrics")
+				c.notLoadedSeen = true
+			}
+			c.setStatus(metrics, nil)
+			return nil
+		}
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+	defer f.Close()
+	c.notLoadedSeen = false
+
+	arrays, err := parseMDStat(f)
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrapf(err, "%s parsing %s", c.pkgID, f.Name())
+	}
+
+	for _, md := range arrays {
+		tagList := "device:" + md.name
+		if !md.active {
+			c.addTaggedMetric(&metrics, "active", tagList, "L", uint64(0))
+			c.addTaggedMetric(&metrics, "disks_failed", tagList, "L", uint64(md.disksFailed))
+			c.addTaggedMetric(&metrics, "disks_spare", tagList, "L", uint64(md.disksSpare))
+			continue
+		}
+
+		degraded := uint64(0)
+		if md.haveCounts && md.disksActive < md.disksTotal {
+			degraded = 1
+		}
+		c.addTaggedMetric(&metrics, "active", tagList, "L", uint64(1))
+		c.addTaggedMetric(&metrics, "degraded", tagList, "L", degraded)
+		c.addTaggedMetric(&metrics, "disks_total", tagList, "L", uint64(md.disksTotal))
+		c.addTaggedMetric(&metrics, "disks_active", tagList, "L", uint64(md.disksActive))
+		c.addTaggedMetric(&metrics, "disks_failed", tagList, "L", uint64(md.disksFailed))
+		c.addTaggedMetric(&metrics, "disks_spare", tagList, "L", uint64(md.disksSpare))
+		if md.syncAction != "" {
+			c.addTaggedMetric(&metrics, md.syncAction+"_progress", tagList, "n", md.syncProgress)
+		}
+	}
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// parseMDStat parses the content of /proc/mdstat. each array starts with a
+// line listing its state and member disks (e.g. "md0 : active raid5 sdc1[2](F)
+// sdb1[1] sda1[0]"), the following lines hold the disk counts ("[3/2]") and
+// any resync/recovery progress ("recovery = 12.6%").
+func parseMDStat(r io.Reader) ([]*mdArray, error) {
+	var arrays []*mdArray
+	var md *mdArray
+	members := 0
+
+	// arrays without [total/active] (raid0, linear) count member disks
+	finish := func() {
+		if md != nil && md.active && !md.haveCounts {
+			md.disksTotal = members - md.disksFailed - md.disksSpare
+			md.disksActive = md.disksTotal
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if m := mdArrayRx.FindStringSubmatch(line); m != nil {
+			finish()
+			md = &mdArray{name: m[1], active: m[2] == "active"}
+			members = 0
+			arrays = append(arrays, md)
+			for _, field := range strings.Fields(m[3]) {
+				if !strings.Contains(field, "[") {
+					continue // personality, (read-only) etc.
+				}
+				members++
+				if strings.Contains(field, "(F)") {
+					md.disksFailed++
+				} else if strings.Contains(field, "(S)") {
+					md.disksSpare++
+				}
+			}
+			continue
+		}
+
+		if md == nil {
+			continue // Personalities, unused devices
+		}
+
+		if m := mdCountsRx.FindStringSubmatch(line); m != nil && !md.haveCounts {
+			total, err := strconv.Atoi(m[1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s disk count", md.name)
+			}
+			active, err := strconv.Atoi(m[2])
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s active disk count", md.name)
+			}
+			md.disksTotal = total
+			md.disksActive = active
+			md.haveCounts = true
+		}
+
+		if m := mdProgressRx.FindStringSubmatch(line); m != nil {
+			v, err := strconv.ParseFloat(m[2], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s %s progress", md.name, m[1])
+			}
+			md.syncAction = m[1]
+			md.syncProgress = v
+		} else if m := mdPendingRx.FindStringSubmatch(line); m != nil {
+			md.syncAction = m[1]
+			md.syncProgress = 0
+		}
+	}
+	finish()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return arrays, nil
+}
+
+// addTaggedMetric adds a gauge with stream tags, metric status applies to the
+// metric name without tags (e.g. disabling "degraded" disables all arrays)
+func (c *MDStat) addTaggedMetric(metrics *cgm.Metrics, mname, tagList, mtype string, mval interface{}) {
+	active, found := c.metricStatus[mname]
+	if (found && !active) || (!found && !c.metricDefaultActive) {
+		return
+	}
+
+	st, err := tags.PrepStreamTags(tagList)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("metric", mname).Str("tags", tagList).Msg("ignoring tags")
+	}
+
+	(*metrics)[c.id+metricNameSeparator+mname+st] = cgm.Metric{Type: mtype, Value: mval}
+}
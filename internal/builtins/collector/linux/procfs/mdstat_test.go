@@ -0,0 +1,219 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	"github.com/rs/zerolog"
+)
+
+func TestNewMDStatCollector(t *testing.T) {
+	t.Log("Testing NewMDStatCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		_, err := NewMDStatCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewMDStatCollector(filepath.Join("testdata", "missing"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewMDStatCollector(filepath.Join("testdata", "bad_syntax"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (id setting)")
+	{
+		c, err := NewMDStatCollector(filepath.Join("testdata", "config_id_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*MDStat).id != "foo" {
+			t.Fatalf("expected foo, got (%s)", c.ID())
+		}
+	}
+
+	t.Log("config (procfs path setting)")
+	{
+		c, err := NewMDStatCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := filepath.Join("testdata", "mdstat")
+		if c.(*MDStat).file != expect {
+			t.Fatalf("expected (%s), got (%s)", expect, c.(*MDStat).file)
+		}
+	}
+
+	t.Log("config (metrics default status invalid)")
+	{
+		_, err := NewMDStatCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (run ttl setting)")
+	{
+		c, err := NewMDStatCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*MDStat).runTTL != 5*time.Minute {
+			t.Fatal("expected 5m")
+		}
+	}
+}
+
+func TestMDStatCollect(t *testing.T) {
+	t.Log("Testing Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("already running")
+	{
+		c, err := NewMDStatCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c.(*MDStat).running = true
+
+		if err := c.Collect(); err != nil {
+			if err.Error() != collector.ErrAlreadyRunning.Error() {
+				t.Fatalf("expected (%s) got (%s)", collector.ErrAlreadyRunning, err)
+			}
+		} else {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("not loaded")
+	{
+		c, err := NewMDStatCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*MDStat).file = filepath.Join("testdata", "missing")
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 0 {
+			t.Fatalf("expected no metrics, got %v", metrics)
+		}
+	}
+
+	t.Log("no arrays")
+	{
+		c, err := NewMDStatCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 0 {
+			t.Fatalf("expected no metrics, got %v", metrics)
+		}
+	}
+
+	t.Log("good")
+	{
+		c, err := NewMDStatCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*MDStat).file = filepath.Join("testdata", "mdstat_arrays")
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		if len(metrics) != 29 {
+			t.Fatalf("expected 29 metrics, got %d %v", len(metrics), metrics)
+		}
+
+		tests := []struct {
+			name    string
+			tagList string
+			value   interface{}
+		}{
+			{"active", "device:md1", uint64(1)},
+			{"degraded", "device:md1", uint64(0)},
+			{"disks_total", "device:md1", uint64(2)},
+			{"disks_active", "device:md1", uint64(2)},
+			{"active", "device:md0", uint64(1)},
+			{"degraded", "device:md0", uint64(1)},
+			{"disks_total", "device:md0", uint64(3)},
+			{"disks_active", "device:md0", uint64(2)},
+			{"disks_failed", "device:md0", uint64(1)},
+			{"disks_spare", "device:md0", uint64(1)},
+			{"recovery_progress", "device:md0", float64(12.6)},
+			{"degraded", "device:md2", uint64(0)},
+			{"disks_total", "device:md2", uint64(2)},
+			{"disks_active", "device:md2", uint64(2)},
+			{"active", "device:md3", uint64(1)},
+			{"resync_progress", "device:md3", float64(0)},
+			{"active", "device:md4", uint64(0)},
+			{"disks_spare", "device:md4", uint64(1)},
+		}
+		for _, test := range tests {
+			st, err := tags.PrepStreamTags(test.tagList)
+			if err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			mn := "mdstat" + metricNameSeparator + test.name + st
+			m, ok := metrics[mn]
+			if !ok {
+				t.Fatalf("expected %s, got %v", mn, metrics)
+			}
+			if m.Value != test.value {
+				t.Fatalf("%s expected %v, got %v", mn, test.value, m.Value)
+			}
+		}
+	}
+
+	t.Log("metric disabled")
+	{
+		c, err := NewMDStatCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*MDStat).file = filepath.Join("testdata", "mdstat_arrays")
+		c.(*MDStat).metricStatus["degraded"] = false
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 25 {
+			t.Fatalf("expected 25 metrics, got %d", len(metrics))
+		}
+	}
+}
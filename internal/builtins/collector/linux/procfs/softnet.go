@@ -0,0 +1,210 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Softnet metrics from the Linux ProcFS network stack per-cpu statistics
+type Softnet struct {
+	pfscommon
+}
+
+// softnetOptions defines what elements can be overriden in a config file
+type softnetOptions struct {
+	// common
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	ProcFSPath           string   `json:"procfs_path" toml:"procfs_path" yaml:"procfs_path"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" yaml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+}
+
+// softnetFields are the softnet_stat columns collected, by position
+var softnetFields = []string{
+	0: "processed",    // packets processed by the cpu
+	1: "dropped",      // packets dropped, the input (backlog) queue was full
+	2: "time_squeeze", // net_rx_action ran out of budget or time with work remaining
+}
+
+// softnetCPUField is the (optional) column holding the cpu index, added in
+// linux 5.10. older kernels only list online cpus, the line number is used.
+const softnetCPUField = 12
+
+// NewSoftnetCollector creates new procfs softnet collector
+func NewSoftnetCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
+	procFile := filepath.Join("net", "softnet_stat")
+
+	c := Softnet{}
+	c.id = "softnet"
+	c.pkgID = "builtins.linux.procfs." + c.id
+	c.procFSPath = "/proc"
+	c.file = filepath.Join(c.procFSPath, procFile)
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+
+	if cfgBaseName == "" && cfgSection == nil {
+		if _, err := os.Stat(c.file); os.IsNotExist(err) {
+			return nil, errors.Wrap(err, c.pkgID)
+		}
+		return &c, nil
+	}
+
+	var opts softnetOptions
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Interface("config", opts).Msg("loaded config")
+
+	if opts.ID != "" {
+		c.id = opts.ID
+	}
+
+	if opts.ProcFSPath != "" {
+		c.procFSPath = opts.ProcFSPath
+		c.file = filepath.Join(c.procFSPath, procFile)
+	}
+
+	if len(opts.MetricsEnabled) > 0 {
+		for _, name := range opts.MetricsEnabled {
+			c.metricStatus[name] = true
+		}
+	}
+	if len(opts.MetricsDisabled) > 0 {
+		for _, name := range opts.MetricsDisabled {
+			c.metricStatus[name] = false
+		}
+	}
+
+	if opts.MetricsDefaultStatus != "" {
+		if ok, _ := regexp.MatchString(`^(enabled|disabled)$`, strings.ToLower(opts.MetricsDefaultStatus)); ok {
+			c.metricDefaultActive = strings.ToLower(opts.MetricsDefaultStatus) == metricStatusEnabled
+		} else {
+			return nil, errors.Errorf("%s invalid metric default status (%s)", c.pkgID, opts.MetricsDefaultStatus)
+		}
+	}
+
+	if opts.RunTTL != "" {
+		dur, err := time.ParseDuration(opts.RunTTL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing run_ttl", c.pkgID)
+		}
+		c.runTTL = dur
+	}
+
+	if _, err := os.Stat(c.file); os.IsNotExist(err) {
+		return nil, errors.Wrap(err, c.pkgID)
+	}
+
+	return &c, nil
+}
+
+// Collect metrics from the procfs resource
+func (c *Softnet) Collect() error {
+	metrics := cgm.Metrics{}
+
+	c.Lock()
+
+	if c.runTTL > time.Duration(0) {
+		if time.Since(c.lastEnd) < c.runTTL {
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
+			c.Unlock()
+			return collector.ErrTTLNotExpired
+		}
+	}
+	if c.running {
+		c.logger.Warn().Msg(collector.ErrAlreadyRunning.Error())
+		c.Unlock()
+		return collector.ErrAlreadyRunning
+	}
+
+	c.running = true
+	c.lastStart = time.Now()
+	c.Unlock()
+
+	f, err := os.Open(c.file)
+	if err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrap(err, c.pkgID)
+	}
+	defer f.Close()
+
+	// one line per cpu, all values are hex
+	line := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		values := strings.Fields(scanner.Text())
+		cpu := line
+		line++
+
+		if len(values) < len(softnetFields) {
+			c.logger.Warn().Int("fields", len(values)).Int("expected", len(softnetFields)).Msg("invalid number of fields")
+			continue
+		}
+		if len(values) > softnetCPUField {
+			if v, err := strconv.ParseUint(values[softnetCPUField], 16, 32); err == nil {
+				cpu = int(v)
+			}
+		}
+
+		tagList := "cpu:" + strconv.Itoa(cpu)
+		for i, field := range softnetFields {
+			v, err := strconv.ParseUint(values[i], 16, 64)
+			if err != nil {
+				c.logger.Warn().Err(err).Str("field", field).Int("cpu", cpu).Msg("parsing field")
+				continue
+			}
+			c.addTaggedMetric(&metrics, field, tagList, v)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		c.setStatus(metrics, err)
+		return errors.Wrapf(err, "%s parsing %s", c.pkgID, f.Name())
+	}
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// addTaggedMetric adds a counter with stream tags, metric status applies to
+// the metric name without tags (e.g. disabling "dropped" disables all cpus)
+func (c *Softnet) addTaggedMetric(metrics *cgm.Metrics, mname, tagList string, mval uint64) {
+	active, found := c.metricStatus[mname]
+	if (found && !active) || (!found && !c.metricDefaultActive) {
+		return
+	}
+
+	st, err := tags.PrepStreamTags(tagList)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("metric", mname).Str("tags", tagList).Msg("ignoring tags")
+	}
+
+	(*metrics)[c.id+metricNameSeparator+mname+st] = cgm.Metric{Type: "L", Value: mval}
+}
@@ -0,0 +1,167 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/rs/zerolog"
+)
+
+func TestNewSoftnetCollector(t *testing.T) {
+	t.Log("Testing NewSoftnetCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("config (missing)")
+	{
+		_, err := NewSoftnetCollector(filepath.Join("testdata", "missing"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewSoftnetCollector(filepath.Join("testdata", "bad_syntax"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (id setting)")
+	{
+		c, err := NewSoftnetCollector(filepath.Join("testdata", "config_id_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Softnet).id != "foo" {
+			t.Fatalf("expected foo, got (%s)", c.ID())
+		}
+	}
+
+	t.Log("config (procfs path setting)")
+	{
+		c, err := NewSoftnetCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := filepath.Join("testdata", "net", "softnet_stat")
+		if c.(*Softnet).file != expect {
+			t.Fatalf("expected (%s), got (%s)", expect, c.(*Softnet).file)
+		}
+	}
+
+	t.Log("config (procfs path setting invalid)")
+	{
+		_, err := NewSoftnetCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (metrics default status invalid)")
+	{
+		_, err := NewSoftnetCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (run ttl setting)")
+	{
+		c, err := NewSoftnetCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Softnet).runTTL != 5*time.Minute {
+			t.Fatal("expected 5m")
+		}
+	}
+}
+
+func TestSoftnetCollect(t *testing.T) {
+	t.Log("Testing Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("already running")
+	{
+		c, err := NewSoftnetCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c.(*Softnet).running = true
+
+		if err := c.Collect(); err != nil {
+			if err.Error() != collector.ErrAlreadyRunning.Error() {
+				t.Fatalf("expected (%s) got (%s)", collector.ErrAlreadyRunning, err)
+			}
+		} else {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("good")
+	{
+		c, err := NewSoftnetCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		if len(metrics) != 6 {
+			t.Fatalf("expected 6 metrics, got %v", metrics)
+		}
+
+		tests := []struct {
+			name  string
+			value uint64
+		}{
+			{"softnet`processed|ST[cpu:0]", 41394},
+			{"softnet`dropped|ST[cpu:0]", 3},
+			{"softnet`time_squeeze|ST[cpu:0]", 31},
+			{"softnet`processed|ST[cpu:2]", 256}, // cpu index column
+			{"softnet`dropped|ST[cpu:2]", 0},
+			{"softnet`time_squeeze|ST[cpu:2]", 2},
+		}
+		for _, test := range tests {
+			m, ok := metrics[test.name]
+			if !ok {
+				t.Fatalf("expected %s, got %v", test.name, metrics)
+			}
+			if m.Value != test.value {
+				t.Fatalf("%s expected %v, got %v", test.name, test.value, m.Value)
+			}
+		}
+	}
+
+	t.Log("metric disabled")
+	{
+		c, err := NewSoftnetCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*Softnet).metricStatus["processed"] = false
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 4 {
+			t.Fatalf("expected 4 metrics, got %v", metrics)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Thermal metrics from the Linux SysFS hwmon and thermal zone interfaces
+type Thermal struct {
+	pfscommon
+	sysFSPath string
+	include   *regexp.Regexp
+	exclude   *regexp.Regexp
+}
+
+// thermalOptions defines what elements can be overriden in a config file
+type thermalOptions struct {
+	// common
+	ID                   string   `json:"id" toml:"id" yaml:"id"`
+	MetricsEnabled       []string `json:"metrics_enabled" toml:"metrics_enabled" yaml:"metrics_enabled"`
+	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
+	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" toml:"metrics_default_status"`
+	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+
+	// collector specific
+	SysFSPath    string `json:"sysfs_path" toml:"sysfs_path" yaml:"sysfs_path"`
+	IncludeRegex string `json:"include_regex" toml:"include_regex" yaml:"include_regex"`
+	ExcludeRegex string `json:"exclude_regex" toml:"exclude_regex" yaml:"exclude_regex"`
+}
+
+// hwmonSensor defines the sysfs hwmon sensor types collected
+type hwmonSensor struct {
+	prefix     string  // sysfs file prefix (e.g. temp for temp1_input)
+	metricName string  // metric name
+	scale      float64 // divisor to convert the raw value to the metric unit
+}
+
+var hwmonSensors = []hwmonSensor{
+	{prefix: "temp", metricName: "temperature", scale: 1000}, // millidegree Celsius -> Celsius
+	{prefix: "fan", metricName: "fan_speed", scale: 1},       // RPM
+	{prefix: "in", metricName: "voltage", scale: 1000},       // millivolts -> volts
+}
+
+// NewThermalCollector creates new sysfs thermal collector
+func NewThermalCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
+	c := Thermal{}
+	c.id = "thermal"
+	c.pkgID = "builtins.linux.procfs." + c.id
+	c.sysFSPath = "/sys"
+	c.logger = log.With().Str("pkg", c.pkgID).Logger()
+	c.metricStatus = map[string]bool{}
+	c.metricDefaultActive = true
+
+	c.include = defaultIncludeRegex
+	c.exclude = defaultExcludeRegex
+
+	if cfgBaseName == "" && cfgSection == nil {
+		return &c, nil
+	}
+
+	var opts thermalOptions
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
+	if err != nil {
+		if strings.Contains(err.Error(), "no config found matching") {
+			return &c, nil
+		}
+		c.logger.Warn().Err(err).Str("file", cfgBaseName).Msg("loading config file")
+		return nil, errors.Wrapf(err, "%s config", c.pkgID)
+	}
+
+	c.logger.Debug().Str("base", cfgBaseName).Interface("config", opts).Msg("loaded config")
+
+	if opts.IncludeRegex != "" {
+		rx, err := regexp.Compile(fmt.Sprintf(regexPat, opts.IncludeRegex))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s compiling include regex", c.pkgID)
+		}
+		c.include = rx
+	}
+
+	if opts.ExcludeRegex != "" {
+		rx, err := regexp.Compile(fmt.Sprintf(regexPat, opts.ExcludeRegex))
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s compiling exclude regex", c.pkgID)
+		}
+		c.exclude = rx
+	}
+
+	if opts.ID != "" {
+		c.id = opts.ID
+	}
+
+	if opts.SysFSPath != "" {
+		if _, err := os.Stat(opts.SysFSPath); err != nil {
+			return nil, errors.Wrapf(err, "%s sysfs_path", c.pkgID)
+		}
+		c.sysFSPath = opts.SysFSPath
+	}
+
+	if len(opts.MetricsEnabled) > 0 {
+		for _, name := range opts.MetricsEnabled {
+			c.metricStatus[name] = true
+		}
+	}
+	if len(opts.MetricsDisabled) > 0 {
+		for _, name := range opts.MetricsDisabled {
+			c.metricStatus[name] = false
+		}
+	}
+
+	if opts.MetricsDefaultStatus != "" {
+		if ok, _ := regexp.MatchString(`^(enabled|disabled)$`, strings.ToLower(opts.MetricsDefaultStatus)); ok {
+			c.metricDefaultActive = strings.ToLower(opts.MetricsDefaultStatus) == metricStatusEnabled
+		} else {
+			return nil, errors.Errorf("%s invalid metric default status (%s)", c.pkgID, opts.MetricsDefaultStatus)
+		}
+	}
+
+	if opts.RunTTL != "" {
+		dur, err := time.ParseDuration(opts.RunTTL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s parsing run_ttl", c.pkgID)
+		}
+		c.runTTL = dur
+	}
+
+	return &c, nil
+}
+
+// Collect metrics from the sysfs resources
+func (c *Thermal) Collect() error {
+	metrics := cgm.Metrics{}
+
+	c.Lock()
+
+	if c.runTTL > time.Duration(0) {
+		if time.Since(c.lastEnd) < c.runTTL {
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
+			c.Unlock()
+			return collector.ErrTTLNotExpired
+		}
+	}
+	if c.running {
+		c.logger.Warn().Msg(collector.ErrAlreadyRunning.Error())
+		c.Unlock()
+		return collector.ErrAlreadyRunning
+	}
+
+	c.running = true
+	c.lastStart = time.Now()
+	c.Unlock()
+
+	// sensors are discovered on each run, virtual machines commonly have
+	// none, which is not an error (there are simply no metrics)
+	c.collectHwmon(&metrics)
+	c.collectThermalZones(&metrics)
+
+	c.setStatus(metrics, nil)
+	return nil
+}
+
+// collectHwmon reads temperature, fan and voltage sensors from /sys/class/hwmon
+func (c *Thermal) collectHwmon(metrics *cgm.Metrics) {
+	chips, err := filepath.Glob(filepath.Join(c.sysFSPath, "class", "hwmon", "hwmon*"))
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("hwmon")
+		return
+	}
+
+	for _, chipDir := range chips {
+		// older kernels place the sensor files in the device subdirectory
+		dir := chipDir
+		if _, err := os.Stat(filepath.Join(dir, "name")); os.IsNotExist(err) {
+			dir = filepath.Join(chipDir, "device")
+		}
+
+		chip := readSysFSString(filepath.Join(dir, "name"))
+		if chip == "" {
+			chip = filepath.Base(chipDir)
+		}
+
+		for _, sensor := range hwmonSensors {
+			inputs, err := filepath.Glob(filepath.Join(dir, sensor.prefix+"*_input"))
+			if err != nil {
+				continue
+			}
+			for _, input := range inputs {
+				base := strings.TrimSuffix(filepath.Base(input), "_input")
+				label := readSysFSString(filepath.Join(dir, base+"_label"))
+				if label == "" {
+					label = base
+				}
+				if c.exclude.MatchString(label) || !c.include.MatchString(label) {
+					continue
+				}
+				raw := readSysFSString(input)
+				v, err := strconv.ParseFloat(raw, 64)
+				if err != nil {
+					c.logger.Debug().Err(err).Str("file", input).Msg("parsing sensor value")
+					continue
+				}
+				c.addTaggedMetric(metrics, sensor.metricName, "chip:"+chip+",sensor:"+label, v/sensor.scale)
+			}
+		}
+	}
+}
+
+// collectThermalZones reads temperatures from /sys/class/thermal/thermal_zone*
+func (c *Thermal) collectThermalZones(metrics *cgm.Metrics) {
+	zones, err := filepath.Glob(filepath.Join(c.sysFSPath, "class", "thermal", "thermal_zone*"))
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("thermal zones")
+		return
+	}
+
+	for _, zoneDir := range zones {
+		zone := filepath.Base(zoneDir)
+		zoneType := readSysFSString(filepath.Join(zoneDir, "type"))
+		if zoneType == "" {
+			zoneType = zone
+		}
+		if c.exclude.MatchString(zoneType) || !c.include.MatchString(zoneType) {
+			continue
+		}
+		raw := readSysFSString(filepath.Join(zoneDir, "temp"))
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.logger.Debug().Err(err).Str("zone", zone).Msg("parsing zone temperature")
+			continue
+		}
+		c.addTaggedMetric(metrics, "temperature", "zone:"+zone+",type:"+zoneType, v/1000)
+	}
+}
+
+// addTaggedMetric adds a gauge with stream tags, metric status applies to the
+// metric name without tags (e.g. disabling "voltage" disables all voltages)
+func (c *Thermal) addTaggedMetric(metrics *cgm.Metrics, mname, tagList string, mval float64) {
+	active, found := c.metricStatus[mname]
+	if (found && !active) || (!found && !c.metricDefaultActive) {
+		return
+	}
+
+	st, err := tags.PrepStreamTags(tagList)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("metric", mname).Str("tags", tagList).Msg("ignoring tags")
+	}
+
+	(*metrics)[c.id+metricNameSeparator+mname+st] = cgm.Metric{Type: "n", Value: mval}
+}
+
+// readSysFSString returns the trimmed content of a sysfs attribute, empty if
+// it does not exist or cannot be read (e.g. sensor not present)
+func readSysFSString(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,201 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build linux
+
+package procfs
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	"github.com/rs/zerolog"
+)
+
+func TestNewThermalCollector(t *testing.T) {
+	t.Log("Testing NewThermalCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config")
+	{
+		c, err := NewThermalCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Thermal).sysFSPath != "/sys" {
+			t.Fatalf("expected /sys, got (%s)", c.(*Thermal).sysFSPath)
+		}
+	}
+
+	t.Log("config (missing)")
+	{
+		_, err := NewThermalCollector(filepath.Join("testdata", "missing"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("config (bad syntax)")
+	{
+		_, err := NewThermalCollector(filepath.Join("testdata", "bad_syntax"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (id setting)")
+	{
+		c, err := NewThermalCollector(filepath.Join("testdata", "config_id_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Thermal).id != "foo" {
+			t.Fatalf("expected foo, got (%s)", c.ID())
+		}
+	}
+
+	t.Log("config (sysfs path setting)")
+	{
+		c, err := NewThermalCollector(filepath.Join("testdata", "config_sysfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Thermal).sysFSPath != filepath.Join("testdata", "sys") {
+			t.Fatalf("expected testdata/sys, got (%s)", c.(*Thermal).sysFSPath)
+		}
+	}
+
+	t.Log("config (sysfs path setting invalid)")
+	{
+		_, err := NewThermalCollector(filepath.Join("testdata", "config_sysfs_path_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (include regex invalid)")
+	{
+		_, err := NewThermalCollector(filepath.Join("testdata", "config_include_regex_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (run ttl setting)")
+	{
+		c, err := NewThermalCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.(*Thermal).runTTL != 5*time.Minute {
+			t.Fatal("expected 5m")
+		}
+	}
+}
+
+func TestThermalCollect(t *testing.T) {
+	t.Log("Testing Collect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("already running")
+	{
+		c, err := NewThermalCollector(filepath.Join("testdata", "config_sysfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c.(*Thermal).running = true
+
+		if err := c.Collect(); err != nil {
+			if err.Error() != collector.ErrAlreadyRunning.Error() {
+				t.Fatalf("expected (%s) got (%s)", collector.ErrAlreadyRunning, err)
+			}
+		} else {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("no sensors")
+	{
+		c, err := NewThermalCollector("", nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*Thermal).sysFSPath = "testdata"
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 0 {
+			t.Fatalf("expected no metrics, got %v", metrics)
+		}
+	}
+
+	t.Log("good")
+	{
+		c, err := NewThermalCollector(filepath.Join("testdata", "config_sysfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		if len(metrics) != 5 {
+			t.Fatalf("expected 5 metrics, got %v", metrics)
+		}
+
+		tests := []struct {
+			name    string
+			tagList string
+			value   float64
+		}{
+			{"temperature", "chip:coretemp,sensor:Package id 0", 45},
+			{"temperature", "chip:coretemp,sensor:Core 0", 42},
+			{"fan_speed", "chip:it8728,sensor:fan1", 1200},
+			{"voltage", "chip:it8728,sensor:in0", 1.224},
+			{"temperature", "zone:thermal_zone0,type:x86_pkg_temp", 47},
+		}
+		for _, test := range tests {
+			st, err := tags.PrepStreamTags(test.tagList)
+			if err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			mn := "thermal" + metricNameSeparator + test.name + st
+			m, ok := metrics[mn]
+			if !ok {
+				t.Fatalf("expected %s, got %v", mn, metrics)
+			}
+			if m.Value.(float64) != test.value {
+				t.Fatalf("expected %v, got %v", test.value, m.Value)
+			}
+		}
+	}
+
+	t.Log("metric disabled, excluded sensor")
+	{
+		c, err := NewThermalCollector(filepath.Join("testdata", "config_sysfs_path_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		c.(*Thermal).metricStatus["voltage"] = false
+		c.(*Thermal).exclude = regexp.MustCompile("^Core")
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if metrics := c.Flush(); len(metrics) != 3 {
+			t.Fatalf("expected 3 metrics, got %v", metrics)
+		}
+	}
+}
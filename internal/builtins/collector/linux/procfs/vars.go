@@ -40,6 +40,8 @@ const (
 	metricNameSeparator = "`"        // character used to separate parts of metric names
 	metricStatusEnabled = "enabled"  // setting string indicating metrics should be made 'active'
 	regexPat            = `^(?:%s)$` // fmt pattern used compile include/exclude regular expressions
+
+	collectorsConfigBase = "collectors" // base name of the config file with a section per collector (in the etc path)
 )
 
 var (
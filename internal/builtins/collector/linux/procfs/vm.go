@@ -17,7 +17,6 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
-	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -26,6 +25,9 @@ import (
 // VM metrics from the Linux ProcFS
 type VM struct {
 	pfscommon
+	rateFields     map[string]bool   // OPT vmstat fields to report per second rates for
+	lastVMStat     map[string]uint64 // previous sample of the rate fields
+	lastVMStatTime time.Time
 }
 
 // vmOptions defines what elements can be overriden in a config file
@@ -37,10 +39,13 @@ type vmOptions struct {
 	MetricsDisabled      []string `json:"metrics_disabled" toml:"metrics_disabled" yaml:"metrics_disabled"`
 	MetricsDefaultStatus string   `json:"metrics_default_status" toml:"metrics_default_status" toml:"metrics_default_status"`
 	RunTTL               string   `json:"run_ttl" toml:"run_ttl" yaml:"run_ttl"`
+
+	// collector specific
+	VMStatRates []string `json:"vmstat_rates" toml:"vmstat_rates" yaml:"vmstat_rates"`
 }
 
 // NewVMCollector creates new procfs cpu collector
-func NewVMCollector(cfgBaseName string) (collector.Collector, error) {
+func NewVMCollector(cfgBaseName string, cfgSection []byte) (collector.Collector, error) {
 	procFile := "meminfo"
 
 	c := VM{}
@@ -52,7 +57,7 @@ func NewVMCollector(cfgBaseName string) (collector.Collector, error) {
 	c.metricStatus = map[string]bool{}
 	c.metricDefaultActive = true
 
-	if cfgBaseName == "" {
+	if cfgBaseName == "" && cfgSection == nil {
 		if _, err := os.Stat(c.file); err != nil {
 			return nil, errors.Wrap(err, c.pkgID)
 		}
@@ -60,7 +65,7 @@ func NewVMCollector(cfgBaseName string) (collector.Collector, error) {
 	}
 
 	var opts vmOptions
-	err := config.LoadConfigFile(cfgBaseName, &opts)
+	err := loadOptions(cfgBaseName, cfgSection, &opts)
 	if err != nil {
 		if strings.Contains(err.Error(), "no config found matching") {
 			return &c, nil
@@ -107,6 +112,16 @@ func NewVMCollector(cfgBaseName string) (collector.Collector, error) {
 		c.runTTL = dur
 	}
 
+	if len(opts.VMStatRates) > 0 {
+		c.rateFields = make(map[string]bool, len(opts.VMStatRates))
+		for _, field := range opts.VMStatRates {
+			if field == "" || strings.ContainsAny(field, " \t") {
+				return nil, errors.Errorf("%s invalid vmstat_rates field (%s)", c.pkgID, field)
+			}
+			c.rateFields[field] = true
+		}
+	}
+
 	if _, err := os.Stat(c.file); os.IsNotExist(err) {
 		return nil, errors.Wrap(err, c.pkgID)
 	}
@@ -122,7 +137,7 @@ func (c *VM) Collect() error {
 
 	if c.runTTL > time.Duration(0) {
 		if time.Since(c.lastEnd) < c.runTTL {
-			c.logger.Warn().Msg(collector.ErrTTLNotExpired.Error())
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
 			c.Unlock()
 			return collector.ErrTTLNotExpired
 		}
@@ -253,6 +268,11 @@ func (c *VM) parseVMstats(metrics *cgm.Metrics) error {
 	defer f.Close()
 
 	scanner := bufio.NewScanner(f)
+	sampleTime := time.Now()
+	var sample map[string]uint64
+	if len(c.rateFields) > 0 {
+		sample = make(map[string]uint64, len(c.rateFields))
+	}
 
 	var pgFaults, pgMajorFaults, pgScan uint64
 	for scanner.Scan() {
@@ -264,6 +284,12 @@ func (c *VM) parseVMstats(metrics *cgm.Metrics) error {
 			continue
 		}
 
+		if c.rateFields[fields[0]] {
+			if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				sample[fields[0]] = v
+			}
+		}
+
 		switch {
 		case fields[0] == "pgfault":
 			v, err := strconv.ParseUint(fields[1], 10, 64)
@@ -312,5 +338,34 @@ func (c *VM) parseVMstats(metrics *cgm.Metrics) error {
 	c.addMetric(metrics, pfx, "page_fault"+metricNameSeparator+"minor", "L", pgFaults-pgMajorFaults)
 	c.addMetric(metrics, pfx, "page_scan", "L", pgScan)
 
+	if sample != nil {
+		c.addVMStatRates(metrics, sample, sampleTime)
+	}
+
 	return nil
 }
+
+// addVMStatRates adds the per second rate of each of the vmstat_rates fields
+// since the previous collection, as vmstat`<field>_per_sec gauges. There
+// are no rates on the first collection, or for a field whose counter was
+// reset (e.g. wrapped), its rate resumes on the next collection.
+func (c *VM) addVMStatRates(metrics *cgm.Metrics, sample map[string]uint64, sampleTime time.Time) {
+	elapsed := sampleTime.Sub(c.lastVMStatTime).Seconds()
+	if c.lastVMStat != nil && elapsed > 0 {
+		pfx := c.id + metricNameSeparator + "vmstat"
+		for field, v := range sample {
+			prev, ok := c.lastVMStat[field]
+			if !ok {
+				continue
+			}
+			if v < prev {
+				c.logger.Debug().Str("field", field).Msg("counter reset, skipping rate")
+				continue
+			}
+			c.addMetric(metrics, pfx, field+"_per_sec", "n", float64(v-prev)/elapsed)
+		}
+	}
+
+	c.lastVMStat = sample
+	c.lastVMStatTime = sampleTime
+}
@@ -24,7 +24,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("no config")
 	{
-		_, err := NewVMCollector("")
+		_, err := NewVMCollector("", nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -38,7 +38,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (missing)")
 	{
-		_, err := NewVMCollector(filepath.Join("testdata", "missing"))
+		_, err := NewVMCollector(filepath.Join("testdata", "missing"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -46,7 +46,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (bad syntax)")
 	{
-		_, err := NewVMCollector(filepath.Join("testdata", "bad_syntax"))
+		_, err := NewVMCollector(filepath.Join("testdata", "bad_syntax"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -54,7 +54,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (config no settings)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_no_settings"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_no_settings"), nil)
 		if runtime.GOOS == "linux" {
 			if err != nil {
 				t.Fatalf("expected NO error, got (%s)", err)
@@ -74,7 +74,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (id setting)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_id_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_id_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -85,7 +85,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -97,7 +97,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (procfs path setting invalid)")
 	{
-		_, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"))
+		_, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -105,7 +105,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (metrics enabled setting)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_enabled_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -123,7 +123,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (metrics disabled setting)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_disabled_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -141,7 +141,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (metrics default status enabled)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_default_status_enabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -152,7 +152,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (metrics default status disabled)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_metrics_default_status_disabled_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -163,7 +163,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (metrics default status invalid)")
 	{
-		_, err := NewVMCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"))
+		_, err := NewVMCollector(filepath.Join("testdata", "config_metrics_default_status_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -171,7 +171,7 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (run ttl 5m)")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_run_ttl_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -182,7 +182,29 @@ func TestNewVMCollector(t *testing.T) {
 
 	t.Log("config (run ttl invalid)")
 	{
-		_, err := NewVMCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"))
+		_, err := NewVMCollector(filepath.Join("testdata", "config_run_ttl_invalid_setting"), nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("config (vmstat rates)")
+	{
+		c, err := NewVMCollector(filepath.Join("testdata", "config_vmstat_rates_valid_setting"), nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(c.(*VM).rateFields) != 3 {
+			t.Fatalf("expected 3 rate fields, got (%#v)", c.(*VM).rateFields)
+		}
+		if !c.(*VM).rateFields["pgfault"] {
+			t.Fatalf("expected pgfault rate field, got (%#v)", c.(*VM).rateFields)
+		}
+	}
+
+	t.Log("config (vmstat rates invalid)")
+	{
+		_, err := NewVMCollector(filepath.Join("testdata", "config_vmstat_rates_invalid_setting"), nil)
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -194,7 +216,7 @@ func TestVMFlush(t *testing.T) {
 
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 
-	c, err := NewVMCollector(filepath.Join("testdata", "config_file_valid_setting"))
+	c, err := NewVMCollector(filepath.Join("testdata", "config_file_valid_setting"), nil)
 	if err != nil {
 		t.Fatalf("expected NO error, got (%s)", err)
 	}
@@ -215,7 +237,7 @@ func TestVMCollect(t *testing.T) {
 
 	t.Log("already running")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -233,7 +255,7 @@ func TestVMCollect(t *testing.T) {
 
 	t.Log("ttl not expired")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -252,7 +274,7 @@ func TestVMCollect(t *testing.T) {
 
 	t.Log("good")
 	{
-		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"))
+		c, err := NewVMCollector(filepath.Join("testdata", "config_procfs_path_valid_setting"), nil)
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
@@ -270,3 +292,66 @@ func TestVMCollect(t *testing.T) {
 		}
 	}
 }
+
+func TestVMCollectRates(t *testing.T) {
+	t.Log("Testing Collect w/vmstat rates")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c, err := NewVMCollector(filepath.Join("testdata", "config_vmstat_rates_valid_setting"), nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	vm := c.(*VM)
+
+	t.Log("first sample, no rates")
+	{
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		if _, found := metrics["vm`vmstat`pgfault_per_sec"]; found {
+			t.Fatalf("expected no rate on first sample, got %v", metrics)
+		}
+		if vm.lastVMStat["pgfault"] != 8038198 {
+			t.Fatalf("expected pgfault sample 8038198, got (%#v)", vm.lastVMStat)
+		}
+	}
+
+	t.Log("rates and counter reset")
+	{
+		vm.lastVMStat = map[string]uint64{
+			"pgfault":    8038098, // 100 less than testdata
+			"pgmajfault": 1000,    // more than testdata (reset)
+			"pswpin":     0,
+		}
+		vm.lastVMStatTime = time.Now().Add(-10 * time.Second)
+
+		if err := c.Collect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		metrics := c.Flush()
+		m, found := metrics["vm`vmstat`pgfault_per_sec"]
+		if !found {
+			t.Fatalf("expected pgfault rate, got %v", metrics)
+		}
+		if v := m.Value.(float64); v < 9.9 || v > 10 {
+			t.Fatalf("expected pgfault rate ~10, got (%f)", v)
+		}
+		m, found = metrics["vm`vmstat`pswpin_per_sec"]
+		if !found {
+			t.Fatalf("expected pswpin rate, got %v", metrics)
+		}
+		if v := m.Value.(float64); v != 0 {
+			t.Fatalf("expected pswpin rate 0, got (%f)", v)
+		}
+		if _, found := metrics["vm`vmstat`pgmajfault_per_sec"]; found {
+			t.Fatalf("expected no pgmajfault rate after reset, got %v", metrics)
+		}
+		if vm.lastVMStat["pgmajfault"] != 740 {
+			t.Fatalf("expected pgmajfault sample 740, got (%#v)", vm.lastVMStat)
+		}
+	}
+}
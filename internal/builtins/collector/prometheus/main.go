@@ -148,7 +148,7 @@ func (c *Prom) Collect() error {
 
 	if c.runTTL > time.Duration(0) {
 		if time.Since(c.lastEnd) < c.runTTL {
-			c.logger.Warn().Msg(collector.ErrTTLNotExpired.Error())
+			c.logger.Debug().Msg(collector.ErrTTLNotExpired.Error())
 			c.Unlock()
 			return collector.ErrTTLNotExpired
 		}
@@ -159,10 +159,10 @@ func (c *Prom) Collect() error {
 	c.Unlock()
 
 	for _, u := range c.urls {
-		c.logger.Debug().Str("id", u.ID).Str("url", u.URL).Msg("prom fetch request")
+		c.logger.Debug().Str("id", u.ID).Str("url", config.RedactURL(u.URL)).Msg("prom fetch request")
 		err := c.fetchPromMetrics(u, &metrics)
 		if err != nil {
-			c.logger.Error().Err(err).Interface("url", u).Msg("fetching prom metrics")
+			c.logger.Error().Err(config.RedactError(err)).Str("id", u.ID).Str("url", config.RedactURL(u.URL)).Msg("fetching prom metrics")
 		}
 	}
 
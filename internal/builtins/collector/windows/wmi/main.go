@@ -15,6 +15,7 @@ import (
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
 	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -45,7 +46,7 @@ func New() ([]collector.Collector, error) {
 		return none, err
 	}
 
-	enbledCollectors := viper.GetStringSlice(config.KeyCollectors)
+	enbledCollectors := config.EnabledCollectors()
 	if len(enbledCollectors) == 0 {
 		l.Info().Msg("no builtin collectors enabled")
 		return none, nil
@@ -151,6 +152,9 @@ func New() ([]collector.Collector, error) {
 			collectors = append(collectors, c)
 
 		default:
+			if viper.GetBool(config.KeyCollectorsStrict) {
+				return none, errors.Errorf("unknown builtin collector for this OS (%s)", name)
+			}
 			l.Warn().
 				Str("name", name).
 				Msg("unknown builtin collector for this OS, ignoring")
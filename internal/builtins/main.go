@@ -7,10 +7,14 @@
 package builtins
 
 import (
+	"runtime/debug"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/circonus-labs/circonus-agent/api"
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	appstats "github.com/maier/go-appstats"
 	"github.com/pkg/errors"
@@ -22,6 +26,7 @@ func New() (*Builtins, error) {
 	b := Builtins{
 		collectors: make(map[string]collector.Collector),
 		logger:     log.With().Str("pkg", "builtins").Logger(),
+		stats:      make(map[string]*collectStats),
 	}
 
 	b.logger.Info().Msg("configuring builtins")
@@ -34,6 +39,27 @@ func New() (*Builtins, error) {
 	return &b, nil
 }
 
+// Reload re-configures the builtin collectors, applying any
+// changes to which collectors are enabled
+func (b *Builtins) Reload() error {
+	nb := Builtins{
+		collectors: make(map[string]collector.Collector),
+		logger:     b.logger,
+	}
+
+	b.logger.Info().Msg("reconfiguring builtins")
+
+	if err := nb.configure(); err != nil {
+		return errors.Wrap(err, "reconfiguring builtins")
+	}
+
+	b.Lock()
+	b.collectors = nb.collectors
+	b.Unlock()
+
+	return nil
+}
+
 // Run triggers internal collectors to gather metrics
 func (b *Builtins) Run(id string) error {
 	b.Lock()
@@ -50,6 +76,7 @@ func (b *Builtins) Run(id string) error {
 	}
 
 	b.running = true
+	collectors := b.collectors // may be replaced by Reload
 	b.Unlock()
 
 	start := time.Now()
@@ -58,28 +85,22 @@ func (b *Builtins) Run(id string) error {
 	var wg sync.WaitGroup
 
 	if id == "" {
-		wg.Add(len(b.collectors))
-		for id, c := range b.collectors {
+		wg.Add(len(collectors))
+		for id, c := range collectors {
 			b.logger.Debug().Str("builtin", id).Msg("collecting")
 			go func(id string, c collector.Collector) {
-				err := c.Collect()
-				if err != nil {
-					b.logger.Error().Err(err).Msg(id)
-				}
-				wg.Done()
+				defer wg.Done()
+				b.collect(id, c)
 			}(id, c)
 		}
 	} else {
-		c, ok := b.collectors[id]
+		c, ok := collectors[id]
 		if ok {
 			wg.Add(1)
 			b.logger.Debug().Str("builtin", id).Msg("collecting")
 			go func(id string, c collector.Collector) {
-				err := c.Collect()
-				if err != nil {
-					b.logger.Error().Err(err).Msg(id)
-				}
-				wg.Done()
+				defer wg.Done()
+				b.collect(id, c)
 			}(id, c)
 		} else {
 			b.logger.Warn().Str("id", id).Msg("unknown builtin")
@@ -100,6 +121,160 @@ func (b *Builtins) Run(id string) error {
 	return nil
 }
 
+// collect runs a single collector, recording the duration and result of
+// the collection. A panic in the collector is recovered, logged and counted
+// so that it does not take down the agent.
+func (b *Builtins) collect(id string, c collector.Collector) {
+	var err error
+	panicked := false
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			err = errors.Errorf("panic: %v", r)
+			b.logger.Error().
+				Str("builtin", id).
+				Interface("panic", r).
+				Str("stack", string(debug.Stack())).
+				Msg("collector panic, recovered")
+		}
+		b.recordCollect(id, time.Since(start), err, panicked)
+	}()
+
+	err = c.Collect()
+	switch {
+	case err == collector.ErrTTLNotExpired:
+		// run ttl interval, the last metrics collected are served
+		b.logger.Debug().Str("builtin", id).Msg("run ttl not expired, using last metrics")
+	case err != nil:
+		b.logger.Error().Err(err).Msg(id)
+	}
+}
+
+// recordCollect updates the collection stats for a collector
+func (b *Builtins) recordCollect(id string, dur time.Duration, err error, panicked bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stats == nil {
+		b.stats = make(map[string]*collectStats)
+	}
+	s, ok := b.stats[id]
+	if !ok {
+		s = &collectStats{}
+		b.stats[id] = s
+	}
+
+	s.lastDuration = dur
+	appstats.MapSet("builtins", id+"_last_duration", dur)
+
+	switch {
+	case panicked:
+		s.panics++
+		appstats.MapIncrementInt("builtins", id+"_panics")
+	case err == collector.ErrTTLNotExpired || err == collector.ErrAlreadyRunning:
+		s.skipped++
+	case err != nil:
+		s.errors++
+		appstats.MapIncrementInt("builtins", id+"_errors")
+	default:
+		s.ok++
+		appstats.MapIncrementInt("builtins", id+"_ok")
+	}
+}
+
+// State returns diagnostic details about the builtin collectors
+func (b *Builtins) State() map[string]interface{} {
+	b.Lock()
+	defer b.Unlock()
+
+	ids := make([]string, 0, len(b.collectors))
+	for id := range b.collectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	stats := make(map[string]interface{}, len(b.stats))
+	for id, s := range b.stats {
+		stats[id] = map[string]interface{}{
+			"last_duration": s.lastDuration.String(),
+			"ok":            s.ok,
+			"errors":        s.errors,
+			"panics":        s.panics,
+			"skipped":       s.skipped,
+		}
+	}
+
+	return map[string]interface{}{
+		"collectors": ids,
+		"running":    b.running,
+		"stats":      stats,
+	}
+}
+
+// Enabled returns the ids of the enabled builtin collectors, sorted
+func (b *Builtins) Enabled() []string {
+	b.Lock()
+	defer b.Unlock()
+
+	ids := make([]string, 0, len(b.collectors))
+	for id := range b.collectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Inventory returns the builtin collectors, sorted by name. Collectors
+// enabled in the configuration which are unknown or failed to initialize
+// are included as not enabled.
+func (b *Builtins) Inventory() []api.Collector {
+	b.Lock()
+	defer b.Unlock()
+
+	inventory := make([]api.Collector, 0, len(b.collectors))
+	for _, c := range b.collectors {
+		stats := c.Inventory()
+		inventory = append(inventory, api.Collector{
+			Name:            stats.ID,
+			Enabled:         true,
+			LastRunStart:    stats.LastRunStart,
+			LastRunEnd:      stats.LastRunEnd,
+			LastRunDuration: stats.LastRunDuration,
+			LastError:       stats.LastError,
+		})
+	}
+	for _, name := range config.EnabledCollectors() {
+		if _, ok := b.collectors[name]; !ok {
+			inventory = append(inventory, api.Collector{
+				Name:      name,
+				LastError: "unknown or failed to initialize",
+			})
+		}
+	}
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].Name < inventory[j].Name })
+
+	return inventory
+}
+
+// Validate reports enabled collectors which are unknown on this platform or
+// failed to initialize (e.g. invalid collector configuration), the details
+// of initialization failures are logged by the collector package
+func (b *Builtins) Validate() []error {
+	b.Lock()
+	defer b.Unlock()
+
+	var errs []error
+	for _, name := range config.EnabledCollectors() {
+		if _, ok := b.collectors[name]; !ok {
+			errs = append(errs, errors.Errorf("builtin collector '%s' unknown or failed to initialize", name))
+		}
+	}
+
+	return errs
+}
+
 // IsBuiltin determines if an id is a builtin or not
 func (b *Builtins) IsBuiltin(id string) bool {
 	if id == "" {
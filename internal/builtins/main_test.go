@@ -11,8 +11,11 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
 )
 
 // fake collector stub
@@ -50,13 +53,32 @@ func (f *foo) ID() string {
 	return f.id
 }
 func (f *foo) Inventory() collector.InventoryStats {
-	return collector.InventoryStats{
+	f.Lock()
+	defer f.Unlock()
+	stats := collector.InventoryStats{
 		ID:              f.id,
 		LastRunStart:    f.lastStart.Format(time.RFC3339Nano),
 		LastRunEnd:      f.lastEnd.Format(time.RFC3339Nano),
 		LastRunDuration: f.lastRunDuration.String(),
-		LastError:       f.lastError.Error(),
 	}
+	if f.lastError != nil {
+		stats.LastError = f.lastError.Error()
+	}
+	return stats
+}
+
+// bad collector stub, Collect fails or panics
+
+type bad struct {
+	foo
+	panic bool
+}
+
+func (b *bad) Collect() error {
+	if b.panic {
+		panic("collect failed")
+	}
+	return errors.New("collect failed")
 }
 
 // end fake collector stub
@@ -184,6 +206,49 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestCollectStats(t *testing.T) {
+	t.Log("Testing collect stats")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	b, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	b.collectors = map[string]collector.Collector{
+		"foo":   newFoo(),
+		"err":   &bad{foo: foo{id: "err"}},
+		"panic": &bad{foo: foo{id: "panic"}, panic: true},
+	}
+
+	if err := b.Run(""); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	if err := b.Run("panic"); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	expect := map[string]collectStats{
+		"foo":   {ok: 1},
+		"err":   {errors: 1},
+		"panic": {panics: 2},
+	}
+	for id, e := range expect {
+		s, ok := b.stats[id]
+		if !ok {
+			t.Fatalf("expected stats for %s", id)
+		}
+		if s.ok != e.ok || s.errors != e.errors || s.panics != e.panics {
+			t.Fatalf("%s: expected %+v, got %+v", id, e, *s)
+		}
+	}
+
+	state := b.State()
+	stats, ok := state["stats"].(map[string]interface{})
+	if !ok || len(stats) != 3 {
+		t.Fatalf("expected stats for 3 collectors, got (%#v)", state["stats"])
+	}
+}
+
 func TestIsBuiltIn(t *testing.T) {
 	t.Log("Testing IsBuiltIn")
 	zerolog.SetGlobalLevel(zerolog.Disabled)
@@ -299,3 +364,83 @@ func TestFlush(t *testing.T) {
 		}
 	}
 }
+
+func TestState(t *testing.T) {
+	t.Log("Testing State")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	b, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	b.collectors = map[string]collector.Collector{"foo": newFoo()}
+
+	state := b.State()
+	ids, ok := state["collectors"].([]string)
+	if !ok {
+		t.Fatalf("expected []string collectors, got (%#v)", state["collectors"])
+	}
+	if len(ids) != 1 || ids[0] != "foo" {
+		t.Fatalf("expected [foo], got (%v)", ids)
+	}
+	if running, ok := state["running"].(bool); !ok || running {
+		t.Fatalf("expected running false, got (%#v)", state["running"])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Log("Testing Validate")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	b, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	b.collectors = map[string]collector.Collector{"foo": newFoo()}
+
+	t.Log("all enabled collectors initialized")
+	{
+		viper.Set(config.KeyCollectors, []string{"foo"})
+		if errs := b.Validate(); len(errs) != 0 {
+			t.Fatalf("expected no errors, got (%v)", errs)
+		}
+	}
+
+	t.Log("unknown collector")
+	{
+		viper.Set(config.KeyCollectors, []string{"foo", "bar"})
+		if errs := b.Validate(); len(errs) != 1 {
+			t.Fatalf("expected 1 error, got (%v)", errs)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestInventory(t *testing.T) {
+	t.Log("Testing Inventory")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	b, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	b.collectors = map[string]collector.Collector{"foo": newFoo()}
+
+	t.Log("enabled and unknown collectors")
+	{
+		viper.Set(config.KeyCollectors, []string{"foo", "bar"})
+		inventory := b.Inventory()
+		if len(inventory) != 2 {
+			t.Fatalf("expected 2 collectors, got %#v", inventory)
+		}
+		if c := inventory[0]; c.Name != "bar" || c.Enabled || c.LastError == "" {
+			t.Fatalf("unexpected bar %#v", c)
+		}
+		if c := inventory[1]; c.Name != "foo" || !c.Enabled || c.LastError != "" {
+			t.Fatalf("unexpected foo %#v", c)
+		}
+	}
+
+	viper.Reset()
+}
@@ -7,6 +7,7 @@ package builtins
 
 import (
 	"sync"
+	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins/collector"
 	"github.com/rs/zerolog"
@@ -17,5 +18,15 @@ type Builtins struct {
 	collectors map[string]collector.Collector
 	logger     zerolog.Logger
 	running    bool
+	stats      map[string]*collectStats
 	sync.Mutex
 }
+
+// collectStats tracks the results of Collect calls for a builtin collector
+type collectStats struct {
+	lastDuration time.Duration
+	ok           int
+	errors       int
+	panics       int
+	skipped      int // ttl not expired or already running
+}
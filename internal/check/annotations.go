@@ -0,0 +1,55 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+)
+
+// Agent lifecycle events recorded as annotations
+const (
+	AnnotationStart  = "started"
+	AnnotationStop   = "stopped"
+	AnnotationReload = "reloaded configuration"
+)
+
+// Annotate posts an annotation marking an agent lifecycle event (see
+// Annotation*), tagged with the agent version and hostname, in the configured
+// category. Does nothing if annotations are not enabled (check.annotations).
+func (c *Check) Annotate(event string) error {
+	if c.annotationClient == nil {
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	now := uint(time.Now().Unix())
+	a := &api.Annotation{
+		Category:       c.annotationCategory,
+		Title:          fmt.Sprintf("%s %s on %s", release.NAME, event, hostname),
+		Description:    fmt.Sprintf("%s %s (version:%s, host:%s, pid:%d)", release.NAME, event, release.VERSION, hostname, os.Getpid()),
+		RelatedMetrics: []string{},
+		Start:          now,
+		Stop:           now,
+	}
+
+	if _, err := c.annotationClient.CreateAnnotation(a); err != nil {
+		return errors.Wrapf(err, "creating %s annotation", event)
+	}
+
+	c.logger.Debug().Str("event", event).Str("category", a.Category).Msg("posted annotation")
+
+	return nil
+}
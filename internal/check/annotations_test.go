@@ -0,0 +1,100 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestAnnotate(t *testing.T) {
+	t.Log("Testing Annotate")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tdisabled")
+	{
+		c := Check{logger: log.Logger}
+		if err := c.Annotate(AnnotationStart); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("\tenabled")
+	{
+		client := genMockClient()
+		client.CreateAnnotationFunc = func(cfg *api.Annotation) (*api.Annotation, error) {
+			return cfg, nil
+		}
+		c := Check{annotationClient: client, annotationCategory: "agents", logger: log.Logger}
+		if err := c.Annotate(AnnotationReload); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		calls := client.CreateAnnotationCalls()
+		if len(calls) != 1 {
+			t.Fatalf("expected 1 annotation, got %d", len(calls))
+		}
+		a := calls[0].Cfg
+		if a.Category != "agents" {
+			t.Fatalf("expected category (agents) got (%s)", a.Category)
+		}
+		if !strings.HasPrefix(a.Title, release.NAME+" "+AnnotationReload+" on ") {
+			t.Fatalf("unexpected title (%s)", a.Title)
+		}
+		if !strings.Contains(a.Description, "version:"+release.VERSION) || !strings.Contains(a.Description, "host:") {
+			t.Fatalf("expected version and host in description (%s)", a.Description)
+		}
+		if a.Start == 0 || a.Start != a.Stop {
+			t.Fatalf("expected start == stop, got %d %d", a.Start, a.Stop)
+		}
+	}
+
+	t.Log("\tapi error")
+	{
+		client := genMockClient()
+		client.CreateAnnotationFunc = func(cfg *api.Annotation) (*api.Annotation, error) {
+			return nil, errors.New("forced mock api call error")
+		}
+		c := Check{annotationClient: client, logger: log.Logger}
+		err := c.Annotate(AnnotationStop)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		expect := "creating stopped annotation: forced mock api call error"
+		if err.Error() != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, err)
+		}
+	}
+
+	t.Log("\tenabled, check not needed")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckAnnotations, true)
+		client := genMockClient()
+		c, err := New(client)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if c.annotationClient == nil {
+			t.Fatal("expected annotation client")
+		}
+		if c.client != nil {
+			t.Fatal("expected check management disabled")
+		}
+		if c.annotationCategory != "circonus-agent" {
+			t.Fatalf("expected default category, got (%s)", c.annotationCategory)
+		}
+		viper.Reset()
+	}
+}
@@ -12,8 +12,11 @@ import "github.com/circonus-labs/circonus-gometrics/api"
 // API interface abstraction of circonus api (for mocking)
 type API interface {
 	Get(url string) ([]byte, error)
+	Post(url string, data []byte) ([]byte, error)
+	Put(url string, data []byte) ([]byte, error)
 	FetchBroker(cid api.CIDType) (*api.Broker, error)
 	FetchBrokers() (*[]api.Broker, error)
+	CreateAnnotation(cfg *api.Annotation) (*api.Annotation, error)
 	CreateCheckBundle(cfg *api.CheckBundle) (*api.CheckBundle, error)
 	FetchCheckBundleMetrics(cid api.CIDType) (*api.CheckBundleMetrics, error)
 	FetchCheckBundle(cid api.CIDType) (*api.CheckBundle, error)
@@ -9,12 +9,15 @@ import (
 )
 
 var (
+	lockAPIMockCreateAnnotation         sync.RWMutex
 	lockAPIMockCreateCheckBundle        sync.RWMutex
 	lockAPIMockFetchBroker              sync.RWMutex
 	lockAPIMockFetchBrokers             sync.RWMutex
 	lockAPIMockFetchCheckBundle         sync.RWMutex
 	lockAPIMockFetchCheckBundleMetrics  sync.RWMutex
 	lockAPIMockGet                      sync.RWMutex
+	lockAPIMockPost                     sync.RWMutex
+	lockAPIMockPut                      sync.RWMutex
 	lockAPIMockSearchCheckBundles       sync.RWMutex
 	lockAPIMockUpdateCheckBundle        sync.RWMutex
 	lockAPIMockUpdateCheckBundleMetrics sync.RWMutex
@@ -26,6 +29,9 @@ var (
 //
 //         // make and configure a mocked API
 //         mockedAPI := &APIMock{
+//             CreateAnnotationFunc: func(cfg *api.Annotation) (*api.Annotation, error) {
+// 	               panic("TODO: mock out the CreateAnnotation method")
+//             },
 //             CreateCheckBundleFunc: func(cfg *api.CheckBundle) (*api.CheckBundle, error) {
 // 	               panic("TODO: mock out the CreateCheckBundle method")
 //             },
@@ -44,6 +50,12 @@ var (
 //             GetFunc: func(url string) ([]byte, error) {
 // 	               panic("TODO: mock out the Get method")
 //             },
+//             PostFunc: func(url string, data []byte) ([]byte, error) {
+// 	               panic("TODO: mock out the Post method")
+//             },
+//             PutFunc: func(url string, data []byte) ([]byte, error) {
+// 	               panic("TODO: mock out the Put method")
+//             },
 //             SearchCheckBundlesFunc: func(searchCriteria *api.SearchQueryType, filterCriteria *map[string][]string) (*[]api.CheckBundle, error) {
 // 	               panic("TODO: mock out the SearchCheckBundles method")
 //             },
@@ -60,6 +72,9 @@ var (
 //
 //     }
 type APIMock struct {
+	// CreateAnnotationFunc mocks the CreateAnnotation method.
+	CreateAnnotationFunc func(cfg *api.Annotation) (*api.Annotation, error)
+
 	// CreateCheckBundleFunc mocks the CreateCheckBundle method.
 	CreateCheckBundleFunc func(cfg *api.CheckBundle) (*api.CheckBundle, error)
 
@@ -78,6 +93,12 @@ type APIMock struct {
 	// GetFunc mocks the Get method.
 	GetFunc func(url string) ([]byte, error)
 
+	// PostFunc mocks the Post method.
+	PostFunc func(url string, data []byte) ([]byte, error)
+
+	// PutFunc mocks the Put method.
+	PutFunc func(url string, data []byte) ([]byte, error)
+
 	// SearchCheckBundlesFunc mocks the SearchCheckBundles method.
 	SearchCheckBundlesFunc func(searchCriteria *api.SearchQueryType, filterCriteria *map[string][]string) (*[]api.CheckBundle, error)
 
@@ -89,6 +110,11 @@ type APIMock struct {
 
 	// calls tracks calls to the methods.
 	calls struct {
+		// CreateAnnotation holds details about calls to the CreateAnnotation method.
+		CreateAnnotation []struct {
+			// Cfg is the cfg argument value.
+			Cfg *api.Annotation
+		}
 		// CreateCheckBundle holds details about calls to the CreateCheckBundle method.
 		CreateCheckBundle []struct {
 			// Cfg is the cfg argument value.
@@ -117,6 +143,20 @@ type APIMock struct {
 			// URL is the url argument value.
 			URL string
 		}
+		// Post holds details about calls to the Post method.
+		Post []struct {
+			// URL is the url argument value.
+			URL string
+			// Data is the data argument value.
+			Data []byte
+		}
+		// Put holds details about calls to the Put method.
+		Put []struct {
+			// URL is the url argument value.
+			URL string
+			// Data is the data argument value.
+			Data []byte
+		}
 		// SearchCheckBundles holds details about calls to the SearchCheckBundles method.
 		SearchCheckBundles []struct {
 			// SearchCriteria is the searchCriteria argument value.
@@ -137,6 +177,37 @@ type APIMock struct {
 	}
 }
 
+// CreateAnnotation calls CreateAnnotationFunc.
+func (mock *APIMock) CreateAnnotation(cfg *api.Annotation) (*api.Annotation, error) {
+	if mock.CreateAnnotationFunc == nil {
+		panic("moq: APIMock.CreateAnnotationFunc is nil but API.CreateAnnotation was just called")
+	}
+	callInfo := struct {
+		Cfg *api.Annotation
+	}{
+		Cfg: cfg,
+	}
+	lockAPIMockCreateAnnotation.Lock()
+	mock.calls.CreateAnnotation = append(mock.calls.CreateAnnotation, callInfo)
+	lockAPIMockCreateAnnotation.Unlock()
+	return mock.CreateAnnotationFunc(cfg)
+}
+
+// CreateAnnotationCalls gets all the calls that were made to CreateAnnotation.
+// Check the length with:
+//     len(mockedAPI.CreateAnnotationCalls())
+func (mock *APIMock) CreateAnnotationCalls() []struct {
+	Cfg *api.Annotation
+} {
+	var calls []struct {
+		Cfg *api.Annotation
+	}
+	lockAPIMockCreateAnnotation.RLock()
+	calls = mock.calls.CreateAnnotation
+	lockAPIMockCreateAnnotation.RUnlock()
+	return calls
+}
+
 // CreateCheckBundle calls CreateCheckBundleFunc.
 func (mock *APIMock) CreateCheckBundle(cfg *api.CheckBundle) (*api.CheckBundle, error) {
 	if mock.CreateCheckBundleFunc == nil {
@@ -318,6 +389,76 @@ func (mock *APIMock) GetCalls() []struct {
 	return calls
 }
 
+// Post calls PostFunc.
+func (mock *APIMock) Post(url string, data []byte) ([]byte, error) {
+	if mock.PostFunc == nil {
+		panic("moq: APIMock.PostFunc is nil but API.Post was just called")
+	}
+	callInfo := struct {
+		URL  string
+		Data []byte
+	}{
+		URL:  url,
+		Data: data,
+	}
+	lockAPIMockPost.Lock()
+	mock.calls.Post = append(mock.calls.Post, callInfo)
+	lockAPIMockPost.Unlock()
+	return mock.PostFunc(url, data)
+}
+
+// PostCalls gets all the calls that were made to Post.
+// Check the length with:
+//     len(mockedAPI.PostCalls())
+func (mock *APIMock) PostCalls() []struct {
+	URL  string
+	Data []byte
+} {
+	var calls []struct {
+		URL  string
+		Data []byte
+	}
+	lockAPIMockPost.RLock()
+	calls = mock.calls.Post
+	lockAPIMockPost.RUnlock()
+	return calls
+}
+
+// Put calls PutFunc.
+func (mock *APIMock) Put(url string, data []byte) ([]byte, error) {
+	if mock.PutFunc == nil {
+		panic("moq: APIMock.PutFunc is nil but API.Put was just called")
+	}
+	callInfo := struct {
+		URL  string
+		Data []byte
+	}{
+		URL:  url,
+		Data: data,
+	}
+	lockAPIMockPut.Lock()
+	mock.calls.Put = append(mock.calls.Put, callInfo)
+	lockAPIMockPut.Unlock()
+	return mock.PutFunc(url, data)
+}
+
+// PutCalls gets all the calls that were made to Put.
+// Check the length with:
+//     len(mockedAPI.PutCalls())
+func (mock *APIMock) PutCalls() []struct {
+	URL  string
+	Data []byte
+} {
+	var calls []struct {
+		URL  string
+		Data []byte
+	}
+	lockAPIMockPut.RLock()
+	calls = mock.calls.Put
+	lockAPIMockPut.RUnlock()
+	return calls
+}
+
 // SearchCheckBundles calls SearchCheckBundlesFunc.
 func (mock *APIMock) SearchCheckBundles(searchCriteria *api.SearchQueryType, filterCriteria *map[string][]string) (*[]api.CheckBundle, error) {
 	if mock.SearchCheckBundlesFunc == nil {
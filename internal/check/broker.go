@@ -25,6 +25,9 @@ import (
 	"github.com/spf13/viper"
 )
 
+// setReverseConfig builds a reverse configuration for each reverse URL in the
+// check bundle, in the order listed. URLs which cannot be configured are
+// skipped (with a warning) as long as at least one is usable.
 func (c *Check) setReverseConfig() error {
 	c.Lock()
 	defer c.Unlock()
@@ -32,9 +35,35 @@ func (c *Check) setReverseConfig() error {
 	if len(c.bundle.ReverseConnectURLs) == 0 {
 		return errors.New("no reverse URLs found in check bundle")
 	}
-	rURL := c.bundle.ReverseConnectURLs[0]
+	if len(c.bundle.Brokers) == 0 {
+		return errors.New("no brokers found in check bundle")
+	}
+	brokerID := c.bundle.Brokers[0]
 	rSecret := c.bundle.Config["reverse:secret_key"]
 
+	var lastErr error
+	cfgs := make([]ReverseConfig, 0, len(c.bundle.ReverseConnectURLs))
+	for _, rURL := range c.bundle.ReverseConnectURLs {
+		cfg, err := c.reverseConfig(brokerID, rURL, rSecret)
+		if err != nil {
+			c.logger.Warn().Err(config.RedactError(err)).Str("url", rURL).Msg("skipping reverse URL")
+			lastErr = err
+			continue
+		}
+		cfgs = append(cfgs, *cfg)
+	}
+
+	if len(cfgs) == 0 {
+		return lastErr
+	}
+
+	c.revConfigs = &cfgs
+
+	return nil
+}
+
+// reverseConfig returns the reverse configuration for a single reverse URL
+func (c *Check) reverseConfig(brokerID, rURL, rSecret string) (*ReverseConfig, error) {
 	if rSecret != "" {
 		rURL += "#" + rSecret
 	}
@@ -44,32 +73,28 @@ func (c *Check) setReverseConfig() error {
 	// Using raw tls connections, the url protocol is not germane.
 	reverseURL, err := url.Parse(strings.Replace(rURL, "mtev_reverse", "http", -1))
 	if err != nil {
-		return errors.Wrapf(err, "parsing check bundle reverse URL (%s)", rURL)
+		return nil, errors.Wrapf(err, "parsing check bundle reverse URL (%s)", rURL)
 	}
 
 	brokerAddr, err := net.ResolveTCPAddr("tcp", reverseURL.Host)
 	if err != nil {
-		return errors.Wrapf(err, "invalid reverse service address", rURL)
+		return nil, errors.Wrapf(err, "invalid reverse service address (%s)", reverseURL.Host)
 	}
 
-	if len(c.bundle.Brokers) == 0 {
-		return errors.New("no brokers found in check bundle")
-	}
-	brokerID := c.bundle.Brokers[0]
-
 	tlsConfig, err := c.brokerTLSConfig(brokerID, reverseURL)
 	if err != nil {
-		return errors.Wrapf(err, "creating TLS config for (%s - %s)", brokerID, rURL)
+		return nil, errors.Wrapf(err, "creating TLS config for (%s - %s)", brokerID, reverseURL.Host)
+	}
+	if err := reverseClientCert(tlsConfig); err != nil {
+		return nil, err
 	}
 
-	c.revConfig = &ReverseConfig{
+	return &ReverseConfig{
 		ReverseURL: reverseURL,
 		BrokerID:   brokerID,
 		BrokerAddr: brokerAddr,
 		TLSConfig:  tlsConfig,
-	}
-
-	return nil
+	}, nil
 }
 
 // brokerTLSConfig returns the correct TLS configuration for the broker
@@ -116,6 +141,23 @@ func (c *Check) brokerTLSConfig(cid string, reverseURL *url.URL) (*tls.Config, e
 	return tlsConfig, nil
 }
 
+// reverseClientCert adds the client certificate presented to the broker
+// on the reverse connection, if one is configured (mTLS)
+func reverseClientCert(tlsConfig *tls.Config) error {
+	certFile := viper.GetString(config.KeyReverseClientCertFile)
+	if certFile == "" {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, viper.GetString(config.KeyReverseClientKeyFile))
+	if err != nil {
+		return errors.Wrap(err, "loading reverse client certificate")
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+
+	return nil
+}
+
 func (c *Check) getBrokerCN(broker *api.Broker, reverseURL *url.URL) (string, error) {
 	host := reverseURL.Hostname()
 
@@ -15,6 +15,73 @@ import (
 	"github.com/spf13/viper"
 )
 
+func TestSetReverseConfig(t *testing.T) {
+	t.Log("Testing setReverseConfig")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no reverse urls")
+	{
+		c := Check{client: genMockClient(), bundle: &api.CheckBundle{Brokers: []string{"/broker/1234"}}}
+		err := c.setReverseConfig()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != "no reverse URLs found in check bundle" {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("no usable reverse urls")
+	{
+		c := Check{client: genMockClient(), bundle: &api.CheckBundle{
+			Brokers:            []string{"/broker/1234"},
+			ReverseConnectURLs: []string{"mtev_reverse://127.0.0.2:1234/check/foo"},
+		}}
+		err := c.setReverseConfig()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if c.revConfigs != nil {
+			t.Fatal("expected no reverse configs")
+		}
+	}
+
+	t.Log("multiple reverse urls, unusable skipped")
+	{
+		c := Check{client: genMockClient(), bundle: &api.CheckBundle{
+			Brokers: []string{"/broker/1234"},
+			Config:  api.CheckBundleConfig{"reverse:secret_key": "abc123"},
+			ReverseConnectURLs: []string{
+				"mtev_reverse://127.0.0.1:1234/check/foo",
+				"mtev_reverse://127.0.0.2:1234/check/foo",
+				"mtev_reverse://127.0.0.1:4321/check/foo",
+			},
+		}}
+		err := c.setReverseConfig()
+		if err != nil {
+			t.Fatalf("expected NO error got (%s)", err)
+		}
+
+		rcs, err := c.GetReverseConfigs()
+		if err != nil {
+			t.Fatalf("expected NO error got (%s)", err)
+		}
+		if len(*rcs) != 2 {
+			t.Fatalf("expected 2 configs, got %d", len(*rcs))
+		}
+		for i, host := range []string{"127.0.0.1:1234", "127.0.0.1:4321"} {
+			rc := (*rcs)[i]
+			if rc.ReverseURL.Host != host {
+				t.Fatalf("expected (%s) got (%s)", host, rc.ReverseURL.Host)
+			}
+			if rc.ReverseURL.Fragment != "abc123" {
+				t.Fatalf("expected secret (abc123) got (%s)", rc.ReverseURL.Fragment)
+			}
+		}
+	}
+}
+
 func TestBrokerTLSConfig(t *testing.T) {
 	t.Log("Testing brokerTLSConfig")
 
@@ -0,0 +1,103 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// bundleTemplate is the subset of a check bundle which can be set from the
+// check bundle template file when creating a check. The type, config, brokers
+// and metrics are always set by the agent. The api client's check bundle has
+// no metric filters, they are added to the create request (see createCheck).
+type bundleTemplate struct {
+	DisplayName   *string    `json:"display_name"`
+	MetricFilters [][]string `json:"metric_filters"`
+	MetricLimit   *int       `json:"metric_limit"`
+	Notes         *string    `json:"notes"`
+	Period        *uint      `json:"period"`
+	Tags          []string   `json:"tags"`
+	Target        *string    `json:"target"`
+	Timeout       *float32   `json:"timeout"`
+}
+
+// loadBundleTemplate reads the check bundle template file (if configured),
+// substituting placeholders (see placeholders)
+func loadBundleTemplate() (*bundleTemplate, error) {
+	file := viper.GetString(config.KeyCheckBundleTemplate)
+	if file == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading check bundle template")
+	}
+
+	// values are substituted within json strings
+	data, err = expandPlaceholders(data, func(v string) string {
+		ev, _ := json.Marshal(v)
+		return string(ev[1 : len(ev)-1])
+	})
+	if err != nil {
+		return nil, errors.Errorf("check bundle template, %s", err)
+	}
+
+	var tmpl bundleTemplate
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tmpl); err != nil {
+		return nil, errors.Wrap(err, "parsing check bundle template")
+	}
+
+	if tmpl.Target != nil && *tmpl.Target == "" {
+		return nil, errors.New("check bundle template, invalid target (empty)")
+	}
+
+	return &tmpl, nil
+}
+
+// apply sets the fields defined in the template on the check bundle, except
+// the metric filters
+func (t *bundleTemplate) apply(cfg *api.CheckBundle) {
+	if t.DisplayName != nil {
+		cfg.DisplayName = *t.DisplayName
+	}
+	if t.MetricLimit != nil {
+		cfg.MetricLimit = *t.MetricLimit
+	}
+	if t.Notes != nil {
+		cfg.Notes = t.Notes
+	}
+	if t.Period != nil {
+		cfg.Period = *t.Period
+	}
+	if t.Tags != nil {
+		cfg.Tags = t.Tags
+	}
+	if t.Target != nil {
+		cfg.Target = *t.Target
+	}
+	if t.Timeout != nil {
+		cfg.Timeout = *t.Timeout
+	}
+}
+
+// checkTarget returns the target used to find and create the check bundle,
+// the check bundle template's target (if set) overrides the configured target
+func (c *Check) checkTarget() string {
+	if c.bundleTemplate != nil && c.bundleTemplate.Target != nil {
+		return *c.bundleTemplate.Target
+	}
+	return viper.GetString(config.KeyCheckTarget)
+}
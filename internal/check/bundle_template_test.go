@@ -0,0 +1,216 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestLoadBundleTemplate(t *testing.T) {
+	t.Log("Testing loadBundleTemplate")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Log("\tnot set")
+	{
+		viper.Reset()
+		tmpl, err := loadBundleTemplate()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if tmpl != nil {
+			t.Fatalf("expected nil, got (%#v)", tmpl)
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckBundleTemplate, filepath.Join("testdata", "bundle_template", "valid.json"))
+		tmpl, err := loadBundleTemplate()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		cfg := api.NewCheckBundle()
+		cfg.Target = "foo"
+		tmpl.apply(cfg)
+
+		if cfg.DisplayName != hostname+" agent" {
+			t.Fatalf("unexpected display name (%s)", cfg.DisplayName)
+		}
+		if expect := "created by " + release.NAME + " " + release.VERSION; cfg.Notes == nil || *cfg.Notes != expect {
+			t.Fatalf("expected notes (%s), got (%v)", expect, cfg.Notes)
+		}
+		if cfg.Period != 30 || cfg.Timeout != 20 {
+			t.Fatalf("unexpected period/timeout (%d/%v)", cfg.Period, cfg.Timeout)
+		}
+		if !reflect.DeepEqual(cfg.Tags, []string{"env:prod", "role:web"}) {
+			t.Fatalf("unexpected tags (%v)", cfg.Tags)
+		}
+		if len(tmpl.MetricFilters) != 2 || tmpl.MetricFilters[0][1] != "^cpu" {
+			t.Fatalf("unexpected metric filters (%v)", tmpl.MetricFilters)
+		}
+		if cfg.Target != "foo" {
+			t.Fatalf("expected target unchanged, got (%s)", cfg.Target)
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		tests := []struct {
+			file string
+			err  string
+		}{
+			{"missing.json", "reading check bundle template"},
+			{"unknown_field.json", `parsing check bundle template: json: unknown field "type"`},
+			{"unknown_placeholder.json", "check bundle template, unknown placeholder(s) (owner)"},
+			{"invalid.json", "parsing check bundle template: json: cannot unmarshal string"},
+		}
+		for _, tt := range tests {
+			t.Logf("\t\t%s", tt.file)
+			viper.Reset()
+			viper.Set(config.KeyCheckBundleTemplate, filepath.Join("testdata", "bundle_template", tt.file))
+			_, err := loadBundleTemplate()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.HasPrefix(err.Error(), tt.err) {
+				t.Fatalf("expected (%s) got (%s)", tt.err, err)
+			}
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestCreateCheckBundleTemplate(t *testing.T) {
+	t.Log("Testing createCheck w/bundle template")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	viper.Reset()
+	viper.Set(config.KeyCheckTarget, "foo")
+	viper.Set(config.KeyCheckTags, "a:b")
+	viper.Set(config.KeyCheckBroker, "123")
+
+	var created *api.CheckBundle
+	var posted map[string]interface{}
+	client := genMockClient()
+	client.CreateCheckBundleFunc = func(cfg *api.CheckBundle) (*api.CheckBundle, error) {
+		created = cfg
+		return cfg, nil
+	}
+	client.PostFunc = func(url string, data []byte) ([]byte, error) {
+		if url != "/check_bundle" {
+			return nil, errors.Errorf("unexpected url (%s)", url)
+		}
+		if err := json.Unmarshal(data, &posted); err != nil {
+			return nil, err
+		}
+		created = &api.CheckBundle{}
+		if err := json.Unmarshal(data, created); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	t.Log("\ttemplate settings")
+	{
+		viper.Set(config.KeyCheckBundleTemplate, filepath.Join("testdata", "bundle_template", "valid.json"))
+		tmpl, err := loadBundleTemplate()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c := Check{client: client, bundleTemplate: tmpl}
+		if _, err := c.createCheck(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if created.Target != "foo" || created.Type != "json:nad" || created.Brokers[0] != "/broker/123" {
+			t.Fatalf("unexpected bundle (%#v)", created)
+		}
+		if !reflect.DeepEqual(created.Tags, []string{"env:prod", "role:web"}) {
+			t.Fatalf("expected template tags, got (%v)", created.Tags)
+		}
+		if created.Period != 30 {
+			t.Fatalf("expected template period, got (%d)", created.Period)
+		}
+		expect := []interface{}{
+			[]interface{}{"allow", "^cpu", "tags:"},
+			[]interface{}{"deny", ".*", ""},
+		}
+		if !reflect.DeepEqual(posted["metric_filters"], expect) {
+			t.Fatalf("expected template metric filters in request, got (%v)", posted["metric_filters"])
+		}
+	}
+
+	t.Log("\tconfigured metric filters override template")
+	{
+		viper.Set(config.KeyCheckBundleTemplate, filepath.Join("testdata", "bundle_template", "valid.json"))
+		tmpl, err := loadBundleTemplate()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c := Check{client: client, bundleTemplate: tmpl, metricFilters: [][]string{{"deny", "^foo"}}}
+		if _, err := c.createCheck(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := []interface{}{[]interface{}{"deny", "^foo"}}
+		if !reflect.DeepEqual(posted["metric_filters"], expect) {
+			t.Fatalf("expected configured metric filters in request, got (%v)", posted["metric_filters"])
+		}
+	}
+
+	t.Log("\ttemplate target")
+	{
+		viper.Set(config.KeyCheckBundleTemplate, filepath.Join("testdata", "bundle_template", "target.json"))
+		tmpl, err := loadBundleTemplate()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		c := Check{client: client, bundleTemplate: tmpl}
+		expect := hostname + ".example.com"
+		if target := c.checkTarget(); target != expect {
+			t.Fatalf("expected (%s), got (%s)", expect, target)
+		}
+		if _, err := c.createCheck(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if created.Target != expect || created.DisplayName != expect+" /agent" {
+			t.Fatalf("unexpected target/display name (%s/%s)", created.Target, created.DisplayName)
+		}
+		if !reflect.DeepEqual(created.Tags, []string{"a:b"}) {
+			t.Fatalf("expected configured tags, got (%v)", created.Tags)
+		}
+	}
+
+	viper.Reset()
+}
@@ -6,6 +6,7 @@
 package check
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -22,7 +23,7 @@ import (
 func (c *Check) setCheck() error {
 	// retrieve the check via the Circonus API or create a new check (if configured to do so)
 	isCreate := viper.GetBool(config.KeyCheckCreate)
-	isManaged := viper.GetBool(config.KeyCheckEnableNewMetrics)
+	isManaged := viper.GetBool(config.KeyCheckEnableNewMetrics) && c.metricFilters == nil
 	isReverse := viper.GetBool(config.KeyReverse)
 	cid := viper.GetString(config.KeyCheckBundleID)
 
@@ -56,12 +57,19 @@ func (c *Check) setCheck() error {
 		return errors.New("invalid Check object state, bundle is nil")
 	}
 
+	bundle, err := c.reconcileMetricFilters(bundle)
+	if err != nil {
+		return errors.Wrap(err, "setting metric filters")
+	}
+
 	c.Lock()
 	c.bundle = bundle
 	c.Unlock()
 	if isManaged {
 		c.logger.Debug().Msg("setting metric states")
+		c.Lock()
 		err := c.setMetricStates(&bundle.Metrics)
+		c.Unlock()
 		if err != nil {
 			return errors.Wrap(err, "setting metric states")
 		}
@@ -107,13 +115,23 @@ func (c *Check) fetchCheck(cid string) (*api.CheckBundle, error) {
 	return bundle, nil
 }
 
+// findCheck searches for the check bundle for this system, by the search
+// tags if configured, otherwise by the check target
 func (c *Check) findCheck() (*api.CheckBundle, int, error) {
-	target := viper.GetString(config.KeyCheckTarget)
-	if target == "" {
-		return nil, -1, errors.New("invalid check target (empty)")
+	var criteria api.SearchQueryType
+	if len(c.searchTags) > 0 {
+		criteria = searchCriteria(c.searchTags)
+	} else {
+		target, err := expandString(c.checkTarget())
+		if err != nil {
+			return nil, -1, errors.Wrap(err, "check target")
+		}
+		if target == "" {
+			return nil, -1, errors.New("invalid check target (empty)")
+		}
+		criteria = api.SearchQueryType(fmt.Sprintf(`(active:1)(type:"json:nad")(target:"%s")`, target))
 	}
 
-	criteria := api.SearchQueryType(fmt.Sprintf(`(active:1)(type:"json:nad")(target:"%s")`, target))
 	bundles, err := c.client.SearchCheckBundles(&criteria, nil)
 	if err != nil {
 		return nil, -1, errors.Wrap(err, "searching for check bundle")
@@ -125,6 +143,15 @@ func (c *Check) findCheck() (*api.CheckBundle, int, error) {
 		return nil, found, errors.Errorf("no check bundles matched criteria (%s)", string(criteria))
 	}
 
+	if found > 1 && len(c.searchTags) > 0 && c.searchSelect != searchSelectNone {
+		bundle, err := selectBundle(*bundles, c.searchSelect)
+		if err != nil {
+			return nil, found, err
+		}
+		c.logger.Info().Int("matched", found).Str("select", c.searchSelect).Str("cid", bundle.CID).Msg("more than one check bundle matched search tags")
+		return bundle, found, nil
+	}
+
 	if found > 1 {
 		return nil, found, errors.Errorf("more than one (%d) check bundle matched criteria (%s)", len(*bundles), string(criteria))
 	}
@@ -153,14 +180,22 @@ func (c *Check) createCheck() (*api.CheckBundle, error) {
 		targetAddr = ta.String()
 	}
 
-	target := viper.GetString(config.KeyCheckTarget)
+	target, err := expandString(c.checkTarget())
+	if err != nil {
+		return nil, errors.Wrap(err, "check target")
+	}
 	if target == "" {
 		return nil, errors.New("invalid check target (empty)")
 	}
 
+	title, err := expandString(viper.GetString(config.KeyCheckTitle))
+	if err != nil {
+		return nil, errors.Wrap(err, "check title")
+	}
+
 	cfg := api.NewCheckBundle()
 	cfg.Target = target
-	cfg.DisplayName = viper.GetString(config.KeyCheckTitle)
+	cfg.DisplayName = title
 	if cfg.DisplayName == "" {
 		cfg.DisplayName = cfg.Target + " /agent"
 	}
@@ -177,6 +212,25 @@ func (c *Check) createCheck() (*api.CheckBundle, error) {
 		cfg.Tags = strings.Split(tags, ",")
 	}
 
+	// settings from the check bundle template override the defaults above
+	if c.bundleTemplate != nil {
+		c.bundleTemplate.apply(cfg)
+	}
+
+	// the search tags are required to find the check bundle again
+	if len(c.searchTags) > 0 {
+		cfg.Tags = mergeTags(cfg.Tags, c.searchTags)
+	}
+
+	// configured metric filters take precedence over the template
+	var filters [][]string
+	if c.bundleTemplate != nil {
+		filters = c.bundleTemplate.MetricFilters
+	}
+	if c.metricFilters != nil {
+		filters = c.metricFilters
+	}
+
 	brokerCID := viper.GetString(config.KeyCheckBroker)
 	if brokerCID == "" || strings.ToLower(brokerCID) == "select" {
 		broker, err := c.selectBroker("json:nad")
@@ -193,10 +247,45 @@ func (c *Check) createCheck() (*api.CheckBundle, error) {
 
 	cfg.Brokers = []string{brokerCID}
 
-	bundle, err := c.client.CreateCheckBundle(cfg)
+	bundle, err := c.createCheckBundle(cfg, filters)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating check bundle")
 	}
 
 	return bundle, nil
 }
+
+// createCheckBundle creates the check bundle. The api client's check bundle
+// has no metric_filters, when there are filters the bundle is posted with
+// the filters added.
+func (c *Check) createCheckBundle(cfg *api.CheckBundle, filters [][]string) (*api.CheckBundle, error) {
+	if filters == nil {
+		return c.client.CreateCheckBundle(cfg)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	req["metric_filters"] = filters
+	data, err = json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.client.Post(apiconf.CheckBundlePrefix, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle api.CheckBundle
+	if err := json.Unmarshal(result, &bundle); err != nil {
+		return nil, errors.Wrap(err, "parsing check bundle")
+	}
+
+	return &bundle, nil
+}
@@ -266,4 +266,22 @@ func TestCreateCheck(t *testing.T) {
 			t.Fatalf("unexpected error return (%s)", err)
 		}
 	}
+
+	t.Log("title (unknown placeholder)")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckTarget, "foo")
+		viper.Set(config.KeyCheckTitle, "{{owner}} /agent")
+
+		c := Check{client: genMockClient()}
+
+		_, err := c.createCheck()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if err.Error() != "check title: unknown placeholder(s) (owner)" {
+			t.Fatalf("unexpected error return (%s)", err)
+		}
+	}
 }
@@ -6,6 +6,8 @@
 package check
 
 import (
+	"crypto/x509"
+	"io/ioutil"
 	stdlog "log"
 	"path/filepath"
 	"time"
@@ -15,6 +17,7 @@ import (
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/circonus-labs/circonus-gometrics/api"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -37,37 +40,103 @@ func New(apiClient API) (*Check, error) {
 
 	c.stateFile = filepath.Join(c.statePath, "metrics.json")
 
+	metricTypes, err := loadMetricTypes()
+	if err != nil {
+		return nil, err
+	}
+	c.metricTypes = metricTypes
+
+	metricFilters, err := loadMetricFilters()
+	if err != nil {
+		return nil, err
+	}
+	c.metricFilters = metricFilters
+
+	tmpl, err := loadBundleTemplate()
+	if err != nil {
+		return nil, err
+	}
+	c.bundleTemplate = tmpl
+
+	searchTags, err := loadSearchTags()
+	if err != nil {
+		return nil, err
+	}
+	c.searchTags = searchTags
+
+	searchSelect, err := loadSearchSelect()
+	if err != nil {
+		return nil, err
+	}
+	c.searchSelect = searchSelect
+
+	// the secondary (HA) check is independent of the primary check
+	if cid := viper.GetString(config.KeyCheckSecondaryBundleID); cid != "" {
+		sc, err := newSecondary(cid)
+		if err != nil {
+			return nil, errors.Wrap(err, "secondary check")
+		}
+		c.secondary = sc
+	}
+
+	sp, err := newSpool(c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "submission spool")
+	}
+	c.spool = sp
+
 	isCreate := viper.GetBool(config.KeyCheckCreate)
 	isManaged := viper.GetBool(config.KeyCheckEnableNewMetrics)
+	isFiltered := c.metricFilters != nil
 	isReverse := viper.GetBool(config.KeyReverse)
+	isOneshot := viper.GetBool(config.KeyOneshot)
+	isDirect := viper.GetBool(config.KeyDirect)
 	cid := viper.GetString(config.KeyCheckBundleID)
 	needCheck := false
 
-	if isReverse || isManaged || (isCreate && cid == "") {
+	if isManaged && isFiltered {
+		// the metrics accepted are determined by the filters, not per metric
+		c.logger.Warn().Msg("check metric filters configured, ignoring check-enable-new-metrics")
+		isManaged = false
+	}
+
+	if isReverse || isManaged || isFiltered || isOneshot || isDirect || (isCreate && cid == "") {
 		needCheck = true
 	}
 
-	if !needCheck {
+	isAnnotated := viper.GetBool(config.KeyCheckAnnotations)
+
+	if !needCheck && !isAnnotated {
 		c.logger.Info().Msg("check management disabled")
 		return &c, nil // if we don't need a check, return a NOP object
 	}
 
 	if apiClient == nil {
-		// create an API client
-		cfg := &api.Config{
-			TokenKey: viper.GetString(config.KeyAPITokenKey),
-			TokenApp: viper.GetString(config.KeyAPITokenApp),
-			URL:      viper.GetString(config.KeyAPIURL),
-			Log:      stdlog.New(c.logger.With().Str("pkg", "check.api").Logger(), "", 0),
-			Debug:    viper.GetBool(config.KeyDebugCGM),
-		}
-		client, err := api.New(cfg)
+		client, err := newAPIClient(
+			viper.GetString(config.KeyAPITokenKey),
+			viper.GetString(config.KeyAPITokenApp),
+			viper.GetString(config.KeyAPIURL),
+			viper.GetString(config.KeyAPICAFile),
+			c.logger.With().Str("pkg", "check.api").Logger())
 		if err != nil {
-			return nil, errors.Wrap(err, "creating circonus api client")
+			return nil, err
 		}
 		apiClient = client
 	}
 
+	if isAnnotated {
+		c.annotationClient = apiClient
+		c.annotationCategory = viper.GetString(config.KeyCheckAnnotationCategory)
+		if c.annotationCategory == "" {
+			c.annotationCategory = defaults.CheckAnnotationCategory
+		}
+	}
+
+	if !needCheck {
+		c.logger.Info().Msg("check management disabled")
+		return &c, nil // annotations only
+	}
+
 	c.client = apiClient
 
 	if isManaged {
@@ -83,12 +152,20 @@ func New(apiClient API) (*Check, error) {
 			return &c, nil
 		}
 
-		ms, err := c.loadState()
+		ms, version, err := c.loadState()
 		if err != nil {
 			c.logger.Error().Err(err).Msg("unable to load existing metric states, all metrics considered existing")
 		} else {
 			c.metricStates = ms
-			c.logger.Debug().Interface("metric_states", len(*c.metricStates)).Msg("loaded metric states")
+			c.logger.Debug().Interface("metric_states", len(*c.metricStates)).Int("version", version).Msg("loaded metric states")
+			if version < stateVersion {
+				// upgrade in place
+				if err := c.saveState(c.metricStates); err != nil {
+					c.logger.Warn().Err(err).Int("version", version).Msg("upgrading metric state file")
+				} else {
+					c.logger.Info().Int("from", version).Int("to", stateVersion).Msg("upgraded metric state file")
+				}
+			}
 		}
 	}
 
@@ -102,6 +179,10 @@ func New(apiClient API) (*Check, error) {
 	// created initially since user 'nobody' cannot create or update the configuration
 	viper.Set(config.KeyCheckBundleID, c.bundle.CID)
 
+	if !viper.GetBool(config.KeyCheckProbeDisabled) {
+		c.probeBroker()
+	}
+
 	if !isManaged {
 		return &c, nil
 	}
@@ -121,6 +202,9 @@ func New(apiClient API) (*Check, error) {
 	c.refreshTTL = ttl
 	c.manage = isManaged
 
+	c.forceMetrics = viper.GetStringSlice(config.KeyCheckForceEnableMetrics)
+	c.forcePending = len(c.forceMetrics) > 0
+
 	return &c, nil
 }
 
@@ -128,19 +212,49 @@ func New(apiClient API) (*Check, error) {
 func (c *Check) RefreshCheckConfig() error {
 	// c.Lock()
 	// defer c.Unlock()
+	if c.client == nil {
+		c.logger.Debug().Msg("check management disabled, nothing to refresh")
+		return nil
+	}
 	c.logger.Debug().Msg("refreshing check configuration using API")
 	return c.setCheck()
 }
 
-// GetReverseConfig returns the reverse configuration to use for the broker
-func (c *Check) GetReverseConfig() (*ReverseConfig, error) {
+// GetReverseConfigs returns the reverse configurations to use for the broker,
+// one for each usable reverse URL in the check bundle, in failover order
+func (c *Check) GetReverseConfigs() (*[]ReverseConfig, error) {
 	c.Lock()
 	defer c.Unlock()
 
-	if c.revConfig == nil {
+	if c.revConfigs == nil || len(*c.revConfigs) == 0 {
 		return nil, errors.New("invalid reverse configuration")
 	}
-	return c.revConfig, nil
+	return c.revConfigs, nil
+}
+
+// CID returns the check bundle id, empty if the agent is not using a check bundle
+func (c *Check) CID() string {
+	if c == nil {
+		return ""
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	if c.bundle == nil {
+		return ""
+	}
+	return c.bundle.CID
+}
+
+// SetMetricMetaSource sets the source of declared metric metadata (units,
+// type) applied when new metrics are enabled
+func (c *Check) SetMetricMetaSource(src MetricMetaSource) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	c.metricMeta = src
+	c.Unlock()
 }
 
 // EnableNewMetrics updates the check bundle enabling any new metrics
@@ -164,6 +278,7 @@ func (c *Check) EnableNewMetrics(m *cgm.Metrics) error {
 		if time.Since(c.lastRefresh) > c.refreshTTL {
 			c.logger.Debug().Dur("since_last", time.Since(c.lastRefresh)).Dur("ttl", c.refreshTTL).Msg("TTL triggering metric state refresh")
 			c.metricStateUpdate = true
+			c.forcePending = len(c.forceMetrics) > 0
 		}
 	}
 
@@ -178,18 +293,93 @@ func (c *Check) EnableNewMetrics(m *cgm.Metrics) error {
 
 	newMetrics := map[string]api.CheckBundleMetric{}
 
+	// forced metrics are verified once on startup and after each refresh,
+	// any which are not active are enabled whether reported yet or not
+	if c.forcePending {
+		for _, mn := range c.forceMetrics {
+			id := metricID(mn, nil)
+			if status, known := (*c.metricStates)[id]; !known || status != c.statusActiveMetric {
+				newMetrics[id] = c.configMetric(mn, cgm.Metric{Type: "n", Value: float64(0)})
+				c.logger.Debug().Interface("metric", newMetrics[id]).Msg("forcing metric enable")
+			}
+		}
+		c.forcePending = false
+	}
+
+	updatedMetrics := map[string]string{} // id -> name of active metrics with changed units/tags
+
 	for mn, mv := range *m {
-		if _, known := (*c.metricStates)[mn]; !known {
-			newMetrics[mn] = c.configMetric(mn, mv)
-			c.logger.Debug().Interface("metric", newMetrics[mn]).Interface("mv", mv).Msg("found new metric")
+		id := metricID(mn, nil)
+		status, known := (*c.metricStates)[id]
+		if !known {
+			// reported values determine the type of forced metrics as well
+			newMetrics[id] = c.configMetric(mn, mv)
+			c.logger.Debug().Interface("metric", newMetrics[id]).Interface("mv", mv).Msg("found new metric")
+			continue
+		}
+		if status == c.statusActiveMetric && c.metadataChanged(id, mn) {
+			newMetrics[id] = c.configMetric(mn, mv)
+			updatedMetrics[id] = mn
+			c.logger.Debug().Interface("metric", newMetrics[id]).Msg("metric units/tags changed")
 		}
 	}
 
 	if len(newMetrics) > 0 {
 		if err := c.updateCheckBundleMetrics(&newMetrics); err != nil {
 			c.logger.Error().Err(err).Msg("adding mew metrics to check bundle")
+			return nil
+		}
+		// record what was sent, so metadata the API does not retain
+		// is not re-sent on every run (until the next refresh)
+		for id, mn := range updatedMetrics {
+			detail := c.metricDetails[id]
+			if units := c.declaredUnits(mn); units != "" {
+				detail.units = units
+			}
+			_, streamTags := splitStreamTags(mn)
+			detail.tags = mergeTags(detail.tags, streamTags)
+			c.metricDetails[id] = detail
 		}
 	}
 
 	return nil
 }
+
+// newAPIClient creates a circonus api client
+func newAPIClient(key, app, apiURL, caFile string, logger zerolog.Logger) (API, error) {
+	cfg := &api.Config{
+		TokenKey: key,
+		TokenApp: app,
+		URL:      apiURL,
+		Log:      stdlog.New(config.RedactWriter(logger), "", 0),
+		Debug:    viper.GetBool(config.KeyDebugCGM),
+	}
+	if caFile != "" {
+		cp, err := loadCACert(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "circonus api ca file")
+		}
+		cfg.CACert = cp
+	}
+	client, err := api.New(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating circonus api client")
+	}
+	return client, nil
+}
+
+// loadCACert loads a pem encoded CA certificate (or bundle) into a cert pool,
+// used to verify self-hosted Circonus API endpoints signed by an internal CA
+func loadCACert(file string) (*x509.CertPool, error) {
+	cert, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := x509.NewCertPool()
+	if !cp.AppendCertsFromPEM(cert) {
+		return nil, errors.Errorf("no valid certificates found in (%s)", file)
+	}
+
+	return cp, nil
+}
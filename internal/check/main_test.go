@@ -8,13 +8,18 @@ package check
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/circonus-labs/circonus-gometrics/api"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
@@ -210,3 +215,205 @@ func TestNew(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadCACert(t *testing.T) {
+	t.Log("Testing loadCACert")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("missing file")
+	{
+		_, err := loadCACert("testdata/missing.crt")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("not a certificate")
+	{
+		_, err := loadCACert("testdata/check1234.json")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("valid")
+	{
+		cp, err := loadCACert("testdata/ca.crt")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if cp == nil {
+			t.Fatal("expected cert pool")
+		}
+	}
+}
+
+func TestEnableNewMetricsForce(t *testing.T) {
+	t.Log("Testing EnableNewMetrics w/forced metrics")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var updated []api.CheckBundleMetric
+	client := genMockClient()
+	client.FetchCheckBundleFunc = func(cid api.CIDType) (*api.CheckBundle, error) {
+		return &api.CheckBundle{
+			CID: *cid,
+			Metrics: []api.CheckBundleMetric{
+				{Name: "bar", Type: "numeric", Status: "available"},
+				{Name: "baz", Type: "numeric", Status: "active"},
+			},
+		}, nil
+	}
+	client.UpdateCheckBundleFunc = func(cfg *api.CheckBundle) (*api.CheckBundle, error) {
+		updated = cfg.Metrics
+		return cfg, nil
+	}
+
+	c := Check{
+		bundle:             &api.CheckBundle{CID: "/check_bundle/1234"},
+		client:             client,
+		forceMetrics:       []string{"foo", "bar", "baz"},
+		forcePending:       true,
+		lastRefresh:        time.Now(),
+		logger:             log.Logger,
+		manage:             true,
+		metricStates:       &metricStates{"bar": "available", "baz": "active"},
+		refreshTTL:         time.Hour,
+		statePath:          dir,
+		stateFile:          filepath.Join(dir, "metrics.json"),
+		statusActiveMetric: "active",
+	}
+
+	t.Log("	forced, not reported")
+	{
+		if err := c.EnableNewMetrics(&cgm.Metrics{}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(updated) != 3 {
+			t.Fatalf("expected 3 bundle metrics (bar updated in place, foo added), got %#v", updated)
+		}
+		for _, m := range updated {
+			if m.Status != "active" {
+				t.Fatalf("expected all metrics active, got %#v", updated)
+			}
+		}
+		if c.forcePending {
+			t.Fatal("expected forced metrics to be applied once")
+		}
+		if (*c.metricStates)["foo"] != "active" {
+			t.Fatalf("expected foo active, got (%#v)", *c.metricStates)
+		}
+	}
+
+	t.Log("	not re-applied until refresh")
+	{
+		updated = nil
+		if err := c.EnableNewMetrics(&cgm.Metrics{}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if updated != nil {
+			t.Fatalf("expected no update, got %#v", updated)
+		}
+	}
+}
+
+func TestEnableNewMetricsMetadata(t *testing.T) {
+	t.Log("Testing EnableNewMetrics w/changed units and tags")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "state")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	units := "ms"
+	bundleMetrics := []api.CheckBundleMetric{
+		{Name: "foo`bar", Type: "numeric", Status: "active"},
+		{Name: "foo`baz|ST[a:1]", Type: "numeric", Status: "active"}, // stream tags not in tags
+		{Name: "foo`qux", Type: "numeric", Status: "active", Units: &units},
+	}
+
+	var updated []api.CheckBundleMetric
+	updates := 0
+	client := genMockClient()
+	client.FetchCheckBundleFunc = func(cid api.CIDType) (*api.CheckBundle, error) {
+		m := make([]api.CheckBundleMetric, len(bundleMetrics))
+		copy(m, bundleMetrics)
+		return &api.CheckBundle{CID: *cid, Metrics: m}, nil
+	}
+	client.UpdateCheckBundleFunc = func(cfg *api.CheckBundle) (*api.CheckBundle, error) {
+		updates++
+		updated = cfg.Metrics
+		return cfg, nil
+	}
+
+	c := Check{
+		bundle:             &api.CheckBundle{CID: "/check_bundle/1234"},
+		client:             client,
+		lastRefresh:        time.Now(),
+		logger:             log.Logger,
+		manage:             true,
+		refreshTTL:         time.Hour,
+		statePath:          dir,
+		stateFile:          filepath.Join(dir, "metrics.json"),
+		statusActiveMetric: "active",
+	}
+	c.SetMetricMetaSource(testMetaSource{
+		"foo`bar": {"bytes", ""},
+		"foo`qux": {"ms", ""},
+	})
+	if err := c.setMetricStates(&bundleMetrics); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	reported := &cgm.Metrics{
+		"foo`bar":         cgm.Metric{Type: "L", Value: uint64(1)},
+		"foo`baz|ST[a:1]": cgm.Metric{Type: "L", Value: uint64(1)},
+		"foo`qux":         cgm.Metric{Type: "L", Value: uint64(1)},
+	}
+
+	t.Log("	units and tags updated")
+	{
+		if err := c.EnableNewMetrics(reported); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if updates != 1 || len(updated) != 3 {
+			t.Fatalf("expected 1 update w/3 bundle metrics, got %d %#v", updates, updated)
+		}
+		for _, m := range updated {
+			switch m.Name {
+			case "foo`bar":
+				if m.Units == nil || *m.Units != "bytes" {
+					t.Fatalf("expected bytes units, got %#v", m)
+				}
+			case "foo`baz|ST[a:1]":
+				if strings.Join(m.Tags, ",") != "a:1" {
+					t.Fatalf("expected stream tags, got %#v", m.Tags)
+				}
+			case "foo`qux":
+				if m.Units == nil || *m.Units != "ms" {
+					t.Fatalf("expected units unchanged, got %#v", m)
+				}
+			}
+		}
+	}
+
+	t.Log("	unchanged, no update")
+	{
+		if err := c.EnableNewMetrics(reported); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if updates != 1 {
+			t.Fatalf("expected no further updates, got %d", updates)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	metricFilterAllow = "allow"
+	metricFilterDeny  = "deny"
+	metricFilterTags  = "tags:"
+)
+
+// validMetricFilterSettings are the settings of a check.metric_filters rule
+var validMetricFilterSettings = map[string]bool{
+	"action":  true,
+	"comment": true,
+	"match":   true,
+	"tags":    true,
+}
+
+// loadMetricFilters parses and validates the check.metric_filters setting, a
+// list of {action: <allow|deny>, match: <regex>, tags: <tag query>, comment: <text>}
+// rules (tags and comment are optional) and returns them in the check bundle
+// metric_filters format, nil if not set.
+func loadMetricFilters() ([][]string, error) {
+	if !viper.IsSet(config.KeyCheckMetricFilters) {
+		return nil, nil
+	}
+
+	var cfgs []map[string]string
+	if err := viper.UnmarshalKey(config.KeyCheckMetricFilters, &cfgs); err != nil {
+		return nil, errors.Wrap(err, "parsing check metric filters")
+	}
+
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	filters := make([][]string, 0, len(cfgs))
+	for idx, cfg := range cfgs {
+		keys := make([]string, 0, len(cfg))
+		for k := range cfg {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !validMetricFilterSettings[k] {
+				return nil, errors.Errorf("check metric filter %d, unknown setting (%s), expected action, match, tags or comment", idx, k)
+			}
+		}
+
+		action := cfg["action"]
+		if action != metricFilterAllow && action != metricFilterDeny {
+			return nil, errors.Errorf("check metric filter %d, invalid action (%s), expected allow|deny", idx, action)
+		}
+
+		match, ok := cfg["match"]
+		if !ok || match == "" {
+			return nil, errors.Errorf("check metric filter %d, match required", idx)
+		}
+		if _, err := regexp.Compile(match); err != nil {
+			return nil, errors.Wrapf(err, "check metric filter %d, invalid match", idx)
+		}
+
+		filter := []string{action, match}
+		if tags := cfg["tags"]; tags != "" {
+			filter = append(filter, metricFilterTags+tags)
+		}
+		if comment := cfg["comment"]; comment != "" {
+			filter = append(filter, comment)
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// reconcileMetricFilters updates the check bundle's metric_filters when they
+// differ from the configured filters, returning the (updated) check bundle.
+// The api client's check bundle has no metric filters, the check bundle is
+// retrieved and updated as json, other settings are sent back unchanged.
+func (c *Check) reconcileMetricFilters(bundle *api.CheckBundle) (*api.CheckBundle, error) {
+	if c.metricFilters == nil {
+		return bundle, nil
+	}
+
+	data, err := c.client.Get(bundle.CID)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching check bundle metric filters")
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "parsing check bundle")
+	}
+	var current [][]string
+	if mf, ok := raw["metric_filters"]; ok {
+		if err := json.Unmarshal(mf, &current); err != nil {
+			return nil, errors.Wrap(err, "parsing check bundle metric filters")
+		}
+	}
+
+	if equalMetricFilters(current, c.metricFilters) {
+		return bundle, nil
+	}
+
+	c.logger.Info().
+		Interface("current", current).
+		Interface("configured", c.metricFilters).
+		Msg("updating check bundle metric filters")
+
+	mf, err := json.Marshal(c.metricFilters)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating check bundle metric filters")
+	}
+	raw["metric_filters"] = mf
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating check bundle metric filters")
+	}
+
+	result, err := c.client.Put(bundle.CID, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating check bundle metric filters")
+	}
+
+	var updated api.CheckBundle
+	if err := json.Unmarshal(result, &updated); err != nil {
+		return nil, errors.Wrap(err, "parsing updated check bundle")
+	}
+
+	return &updated, nil
+}
+
+// equalMetricFilters compares two lists of metric filters, ignoring trailing
+// empty elements of a filter (e.g. an empty comment returned by the API)
+func equalMetricFilters(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		fa, fb := trimMetricFilter(a[i]), trimMetricFilter(b[i])
+		if len(fa) != len(fb) {
+			return false
+		}
+		for j := range fa {
+			if fa[j] != fb[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func trimMetricFilter(f []string) []string {
+	for len(f) > 0 && f[len(f)-1] == "" {
+		f = f[:len(f)-1]
+	}
+	return f
+}
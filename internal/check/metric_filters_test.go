@@ -0,0 +1,189 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestLoadMetricFilters(t *testing.T) {
+	t.Log("Testing loadMetricFilters")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tnot set")
+	{
+		viper.Reset()
+		filters, err := loadMetricFilters()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if filters != nil {
+			t.Fatalf("expected nil filters, got (%#v)", filters)
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckMetricFilters, []map[string]interface{}{
+			{"action": "allow", "match": "^cpu"},
+			{"action": "allow", "match": "^disk", "tags": "and(device:sda)", "comment": "root disk"},
+			{"action": "deny", "match": ".*", "comment": "everything else"},
+		})
+		filters, err := loadMetricFilters()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := [][]string{
+			{"allow", "^cpu"},
+			{"allow", "^disk", "tags:and(device:sda)", "root disk"},
+			{"deny", ".*", "everything else"},
+		}
+		if !reflect.DeepEqual(filters, expect) {
+			t.Fatalf("expected (%#v) got (%#v)", expect, filters)
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		tests := []struct {
+			desc string
+			cfg  map[string]interface{}
+			err  string
+		}{
+			{"unknown setting", map[string]interface{}{"action": "allow", "match": "a", "type": "numeric"}, "check metric filter 0, unknown setting (type), expected action, match, tags or comment"},
+			{"no action", map[string]interface{}{"match": "a"}, "check metric filter 0, invalid action (), expected allow|deny"},
+			{"bad action", map[string]interface{}{"action": "drop", "match": "a"}, "check metric filter 0, invalid action (drop), expected allow|deny"},
+			{"no match", map[string]interface{}{"action": "allow"}, "check metric filter 0, match required"},
+			{"bad match", map[string]interface{}{"action": "allow", "match": "("}, "check metric filter 0, invalid match: error parsing regexp: missing closing ): `(`"},
+		}
+		for _, tt := range tests {
+			t.Logf("\t\t%s", tt.desc)
+			viper.Reset()
+			viper.Set(config.KeyCheckMetricFilters, []map[string]interface{}{tt.cfg})
+			_, err := loadMetricFilters()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if err.Error() != tt.err {
+				t.Fatalf("expected (%s) got (%s)", tt.err, err)
+			}
+		}
+	}
+}
+
+func TestReconcileMetricFilters(t *testing.T) {
+	t.Log("Testing reconcileMetricFilters")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	filters := [][]string{{"allow", "^cpu"}, {"deny", ".*", "everything else"}}
+	bundle := &api.CheckBundle{CID: "/check_bundle/1234"}
+
+	// check bundle as returned by the api, with settings the api client does not know
+	getBundle := func(url string) ([]byte, error) {
+		if url != "/check_bundle/1234" {
+			return nil, errors.Errorf("unexpected url (%s)", url)
+		}
+		return []byte(`{"_cid":"/check_bundle/1234","display_name":"foo","metric_filters":[["allow","^cpu",""],["deny",".*","everything else"]],"unknown_setting":"bar"}`), nil
+	}
+
+	t.Log("\tnot managed")
+	{
+		client := genMockClient()
+		c := Check{client: client, logger: log.Logger}
+		b, err := c.reconcileMetricFilters(bundle)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if b != bundle {
+			t.Fatal("expected same bundle")
+		}
+		if len(client.PutCalls()) != 0 {
+			t.Fatal("expected no update")
+		}
+	}
+
+	t.Log("\tunchanged")
+	{
+		client := genMockClient()
+		client.GetFunc = getBundle
+		c := Check{client: client, logger: log.Logger, metricFilters: filters}
+		if _, err := c.reconcileMetricFilters(bundle); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(client.PutCalls()) != 0 {
+			t.Fatal("expected no update")
+		}
+	}
+
+	t.Log("\tchanged")
+	{
+		client := genMockClient()
+		client.GetFunc = getBundle
+		client.PutFunc = func(url string, data []byte) ([]byte, error) {
+			return data, nil
+		}
+		changed := [][]string{{"allow", "^(cpu|mem)"}, {"deny", ".*"}}
+		c := Check{client: client, logger: log.Logger, metricFilters: changed}
+		b, err := c.reconcileMetricFilters(bundle)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		calls := client.PutCalls()
+		if len(calls) != 1 {
+			t.Fatal("expected update")
+		}
+		if calls[0].URL != "/check_bundle/1234" {
+			t.Fatalf("unexpected url (%s)", calls[0].URL)
+		}
+		var sent struct {
+			DisplayName    string     `json:"display_name"`
+			MetricFilters  [][]string `json:"metric_filters"`
+			UnknownSetting string     `json:"unknown_setting"`
+		}
+		if err := json.Unmarshal(calls[0].Data, &sent); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if !reflect.DeepEqual(sent.MetricFilters, changed) {
+			t.Fatalf("expected (%#v) got (%#v)", changed, sent.MetricFilters)
+		}
+		if sent.DisplayName != "foo" || sent.UnknownSetting != "bar" {
+			t.Fatalf("expected other settings unchanged, got (%s)", string(calls[0].Data))
+		}
+		if b.CID != "/check_bundle/1234" || b.DisplayName != "foo" {
+			t.Fatalf("unexpected updated bundle (%#v)", b)
+		}
+	}
+
+	t.Log("\tapi error")
+	{
+		client := genMockClient()
+		client.GetFunc = getBundle
+		client.PutFunc = func(url string, data []byte) ([]byte, error) {
+			return nil, errors.New("forced mock api call error")
+		}
+		c := Check{client: client, logger: log.Logger, metricFilters: [][]string{{"deny", ".*"}}}
+		_, err := c.reconcileMetricFilters(bundle)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		expect := "updating check bundle metric filters: forced mock api call error"
+		if err.Error() != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// metricTypeRule maps the metric names matching a pattern to a circonus metric type
+type metricTypeRule struct {
+	rx    *regexp.Regexp
+	mtype string
+}
+
+// validMetricTypes are the circonus metric types a pattern may be mapped to
+var validMetricTypes = map[string]bool{
+	"histogram": true,
+	"numeric":   true,
+	"text":      true,
+}
+
+// loadMetricTypes parses and validates the check.metric_types setting, a
+// list of {match: <regex>, type: <numeric|histogram|text>} mappings applied
+// in order (the first matching pattern is used)
+func loadMetricTypes() ([]metricTypeRule, error) {
+	if !viper.IsSet(config.KeyCheckMetricTypes) {
+		return nil, nil
+	}
+
+	var cfgs []map[string]string
+	if err := viper.UnmarshalKey(config.KeyCheckMetricTypes, &cfgs); err != nil {
+		return nil, errors.Wrap(err, "parsing check metric types")
+	}
+
+	rules := make([]metricTypeRule, 0, len(cfgs))
+	for idx, cfg := range cfgs {
+		keys := make([]string, 0, len(cfg))
+		for k := range cfg {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k != "match" && k != "type" {
+				return nil, errors.Errorf("check metric type %d, unknown setting (%s), expected match and type", idx, k)
+			}
+		}
+
+		match, ok := cfg["match"]
+		if !ok || match == "" {
+			return nil, errors.Errorf("check metric type %d, match required", idx)
+		}
+		rx, err := regexp.Compile(match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "check metric type %d, invalid match", idx)
+		}
+
+		mtype := cfg["type"]
+		if !validMetricTypes[mtype] {
+			return nil, errors.Errorf("check metric type %d, invalid type (%s), expected numeric|histogram|text", idx, mtype)
+		}
+
+		rules = append(rules, metricTypeRule{rx: rx, mtype: mtype})
+	}
+
+	return rules, nil
+}
+
+// mappedType returns the metric type configured for a metric name (without
+// stream tags), if it matches one of the check.metric_types patterns
+func (c *Check) mappedType(name string) (string, bool) {
+	for _, rule := range c.metricTypes {
+		if rule.rx.MatchString(name) {
+			return rule.mtype, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,123 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestLoadMetricTypes(t *testing.T) {
+	t.Log("Testing loadMetricTypes")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tnot set")
+	{
+		viper.Reset()
+		rules, err := loadMetricTypes()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(rules) != 0 {
+			t.Fatalf("expected no rules, got (%#v)", rules)
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckMetricTypes, []map[string]interface{}{
+			{"match": "_total$", "type": "numeric"},
+			{"match": "_seconds$", "type": "histogram"},
+		})
+		rules, err := loadMetricTypes()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(rules) != 2 {
+			t.Fatalf("expected 2 rules, got (%#v)", rules)
+		}
+		if rules[1].mtype != "histogram" || !rules[1].rx.MatchString("request_seconds") {
+			t.Fatalf("unexpected rule (%#v)", rules[1])
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		tests := []struct {
+			desc string
+			cfg  map[string]interface{}
+			err  string
+		}{
+			{"unknown setting", map[string]interface{}{"match": "a", "type": "numeric", "units": "s"}, "check metric type 0, unknown setting (units), expected match and type"},
+			{"no match", map[string]interface{}{"type": "numeric"}, "check metric type 0, match required"},
+			{"bad match", map[string]interface{}{"match": "(", "type": "numeric"}, "check metric type 0, invalid match: error parsing regexp: missing closing ): `(`"},
+			{"no type", map[string]interface{}{"match": "a"}, "check metric type 0, invalid type (), expected numeric|histogram|text"},
+			{"bad type", map[string]interface{}{"match": "a", "type": "counter"}, "check metric type 0, invalid type (counter), expected numeric|histogram|text"},
+		}
+		for _, tt := range tests {
+			t.Logf("\t\t%s", tt.desc)
+			viper.Reset()
+			viper.Set(config.KeyCheckMetricTypes, []map[string]interface{}{tt.cfg})
+			_, err := loadMetricTypes()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if err.Error() != tt.err {
+				t.Fatalf("expected (%s) got (%s)", tt.err, err)
+			}
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestConfigMetricMappedType(t *testing.T) {
+	t.Log("Testing configMetric w/metric type mapping")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyCheckMetricTypes, []map[string]interface{}{
+		{"match": "_total$", "type": "numeric"},
+		{"match": "_seconds$", "type": "histogram"},
+		{"match": "^version", "type": "text"},
+	})
+	rules, err := loadMetricTypes()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	viper.Reset()
+
+	c := Check{logger: log.Logger, metricTypes: rules}
+	cases := []struct {
+		desc string
+		mn   string
+		mv   cgm.Metric
+		mt   string
+	}{
+		{"mapped histogram", "request_seconds", cgm.Metric{Type: "n", Value: float64(0.25)}, "histogram"},
+		{"mapped histogram w/stream tags", "request_seconds|ST[a:1]", cgm.Metric{Type: "n", Value: float64(0.25)}, "histogram"},
+		{"mapped numeric", "requests_total", cgm.Metric{Type: "L", Value: uint64(1)}, "numeric"},
+		{"mapped text", "version", cgm.Metric{Type: "L", Value: uint64(2)}, "text"},
+		{"not mapped", "requests", cgm.Metric{Type: "s", Value: "foo"}, "text"},
+		{"tags not matched", "foo|ST[unit:seconds]", cgm.Metric{Type: "L", Value: uint64(1)}, "numeric"},
+	}
+
+	for _, tc := range cases {
+		t.Logf("\t%s", tc.desc)
+		m := c.configMetric(tc.mn, tc.mv)
+		if m.Type != tc.mt {
+			t.Fatalf("expected '%s' type in %#v", tc.mt, m)
+		}
+	}
+}
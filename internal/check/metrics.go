@@ -8,8 +8,10 @@ package check
 import (
 	"encoding/json"
 	"reflect"
+	"sort"
 	"strings"
 
+	"github.com/circonus-labs/circonus-agent/internal/tags"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/circonus-labs/circonus-gometrics/api"
 	"github.com/pkg/errors"
@@ -48,10 +50,30 @@ func (c *Check) updateCheckBundleMetrics(m *map[string]api.CheckBundleMetric) er
 		return errors.Wrap(err, "unable to fetch up-to-date copy of check")
 	}
 
+	// metrics already in the bundle (e.g. a forced metric which is not
+	// active) are updated in place rather than added a second time
+	existing := make(map[string]int, len(bundle.Metrics))
+	for i, mv := range bundle.Metrics {
+		existing[metricID(mv.Name, mv.Tags)] = i
+	}
+
 	metrics := make([]api.CheckBundleMetric, 0, len(*m))
 
 	for mn, mv := range *m {
 		c.logger.Debug().Str("name", mn).Msg("configuring new check bundle metric")
+		if i, ok := existing[mn]; ok {
+			if bundle.Metrics[i].Status == mv.Status {
+				// already enabled, only the metadata is updated, tags
+				// are merged so tags added outside the agent are kept
+				if mv.Units != nil {
+					bundle.Metrics[i].Units = mv.Units
+				}
+				bundle.Metrics[i].Tags = mergeTags(bundle.Metrics[i].Tags, mv.Tags)
+				continue
+			}
+			bundle.Metrics[i] = mv
+			continue
+		}
 		metrics = append(metrics, mv)
 	}
 
@@ -75,9 +97,12 @@ func (c *Check) updateCheckBundleMetrics(m *map[string]api.CheckBundleMetric) er
 
 func (c *Check) configMetric(mn string, mv cgm.Metric) api.CheckBundleMetric {
 
+	_, streamTags := splitStreamTags(mn)
+
 	cm := api.CheckBundleMetric{
 		Name:   mn,
 		Status: c.statusActiveMetric,
+		Tags:   streamTags,
 	}
 
 	mtype := "numeric" // default
@@ -92,7 +117,114 @@ func (c *Check) configMetric(mn string, mv cgm.Metric) api.CheckBundleMetric {
 		mtype = "text"
 	}
 
+	// configured mappings override the inferred type
+	base, _ := splitStreamTags(mn)
+	if mapped, ok := c.mappedType(base); ok {
+		mtype = mapped
+	}
+
 	cm.Type = mtype
 
+	// declared metadata takes precedence over the inferred type
+	if c.metricMeta != nil {
+		if units, declType, ok := c.metricMeta.MetricMeta(base); ok {
+			if units != "" {
+				cm.Units = &units
+			}
+			if declType != "" {
+				cm.Type = declType
+			}
+		}
+	}
+
 	return cm
 }
+
+// metadataChanged determines if the units (from the metric metadata source)
+// or stream tags of an active metric differ from those on the check bundle.
+// Units are only compared if declared and tags are only added, never removed.
+// Metrics with unknown details (e.g. loaded from the state file, before the
+// first refresh from the API) are not compared.
+func (c *Check) metadataChanged(id, mn string) bool {
+	detail, known := c.metricDetails[id]
+	if !known {
+		return false
+	}
+
+	if units := c.declaredUnits(mn); units != "" && units != detail.units {
+		return true
+	}
+
+	_, streamTags := splitStreamTags(mn)
+	return len(mergeTags(detail.tags, streamTags)) != len(detail.tags)
+}
+
+// declaredUnits returns the units declared for a metric by the metric
+// metadata source (e.g. a plugin manifest), if any
+func (c *Check) declaredUnits(mn string) string {
+	if c.metricMeta == nil {
+		return ""
+	}
+	base, _ := splitStreamTags(mn)
+	units, _, _ := c.metricMeta.MetricMeta(base)
+	return units
+}
+
+// mergeTags returns current with any tags from add which it does not contain appended
+func mergeTags(current, add []string) []string {
+	merged := make([]string, len(current), len(current)+len(add))
+	copy(merged, current)
+	for _, t := range add {
+		found := false
+		for _, ct := range merged {
+			if ct == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, t)
+		}
+	}
+	return merged
+}
+
+// metricID returns the identifier used to track the state of a metric, the
+// metric name with stream tags (encoded in the name and/or in metricTags) in
+// canonical (sorted, de-duplicated) form, e.g. foo|ST[a:1,b:2]
+func metricID(name string, metricTags []string) string {
+	base, streamTags := splitStreamTags(name)
+	streamTags = append(streamTags, metricTags...)
+	if len(streamTags) == 0 {
+		return base
+	}
+
+	sort.Strings(streamTags)
+	uniq := streamTags[:0]
+	for _, t := range streamTags {
+		if t == "" || (len(uniq) > 0 && t == uniq[len(uniq)-1]) {
+			continue
+		}
+		uniq = append(uniq, t)
+	}
+	if len(uniq) == 0 {
+		return base
+	}
+
+	return base + streamTagPrefix + strings.Join(uniq, tags.Separator) + streamTagSuffix
+}
+
+// splitStreamTags splits a metric name into the base name and its stream tags
+func splitStreamTags(name string) (string, []string) {
+	idx := strings.Index(name, streamTagPrefix)
+	if idx == -1 || !strings.HasSuffix(name, streamTagSuffix) {
+		return name, nil
+	}
+
+	tagList := name[idx+len(streamTagPrefix) : len(name)-len(streamTagSuffix)]
+	if tagList == "" {
+		return name[:idx], nil
+	}
+
+	return name[:idx], strings.Split(tagList, tags.Separator)
+}
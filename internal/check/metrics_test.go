@@ -156,6 +156,7 @@ func TestConfigMetric(t *testing.T) {
 		{"string", "foo", cgm.Metric{Type: "s", Value: "bar"}, "text"},
 		{"numeric", "foo", cgm.Metric{Type: "n", Value: []float64{1.0, 2.0, 3.0}}, "histogram"},
 		{"numeric", "foo", cgm.Metric{Type: "n", Value: [...]string{"H[1.0]=1", "H[2.0]=1", "H[3.0]=1"}}, "histogram"},
+		{"stream tags", "foo|ST[a:1,b:2]", cgm.Metric{Type: "L", Value: uint64(1)}, "numeric"},
 	}
 
 	for _, tc := range cases {
@@ -169,3 +170,102 @@ func TestConfigMetric(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigMetricTags(t *testing.T) {
+	t.Log("Testing configMetric w/stream tags")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c := Check{logger: log.Logger}
+
+	m := c.configMetric("foo|ST[a:1,b:2]", cgm.Metric{Type: "L", Value: uint64(1)})
+	if len(m.Tags) != 2 || m.Tags[0] != "a:1" || m.Tags[1] != "b:2" {
+		t.Fatalf("expected tags [a:1 b:2], got (%#v)", m.Tags)
+	}
+
+	m = c.configMetric("foo", cgm.Metric{Type: "L", Value: uint64(1)})
+	if len(m.Tags) != 0 {
+		t.Fatalf("expected no tags, got (%#v)", m.Tags)
+	}
+}
+
+type testMetaSource map[string][2]string
+
+func (m testMetaSource) MetricMeta(metricName string) (string, string, bool) {
+	mm, ok := m[metricName]
+	return mm[0], mm[1], ok
+}
+
+func TestConfigMetricMeta(t *testing.T) {
+	t.Log("Testing configMetric w/metric metadata")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	c := Check{logger: log.Logger}
+	c.SetMetricMetaSource(testMetaSource{
+		"foo`bar": {"bytes", ""},
+		"foo`baz": {"", "histogram"},
+	})
+
+	t.Log("	units, stream tags")
+	{
+		m := c.configMetric("foo`bar|ST[a:1]", cgm.Metric{Type: "L", Value: uint64(1)})
+		if m.Units == nil || *m.Units != "bytes" {
+			t.Fatalf("expected bytes units, got (%#v)", m.Units)
+		}
+		if m.Type != "numeric" {
+			t.Fatalf("expected numeric, got (%s)", m.Type)
+		}
+	}
+
+	t.Log("	type")
+	{
+		m := c.configMetric("foo`baz", cgm.Metric{Type: "n", Value: float64(1)})
+		if m.Units != nil {
+			t.Fatalf("expected no units, got (%#v)", *m.Units)
+		}
+		if m.Type != "histogram" {
+			t.Fatalf("expected histogram, got (%s)", m.Type)
+		}
+	}
+
+	t.Log("	not declared")
+	{
+		m := c.configMetric("foo`qux", cgm.Metric{Type: "s", Value: "a"})
+		if m.Units != nil || m.Type != "text" {
+			t.Fatalf("expected inferred text w/o units, got (%#v)", m)
+		}
+	}
+}
+
+func TestMetricID(t *testing.T) {
+	t.Log("Testing metricID")
+
+	cases := []struct {
+		desc   string
+		name   string
+		tags   []string
+		expect string
+	}{
+		{"no tags", "foo", nil, "foo"},
+		{"stream tags in name", "foo|ST[b:2,a:1]", nil, "foo|ST[a:1,b:2]"},
+		{"tags list", "foo", []string{"b:2", "a:1"}, "foo|ST[a:1,b:2]"},
+		{"name and tags list", "foo|ST[a:1]", []string{"b:2", "a:1"}, "foo|ST[a:1,b:2]"},
+		{"empty stream tags", "foo|ST[]", nil, "foo"},
+		{"empty tag", "foo", []string{""}, "foo"},
+	}
+
+	for _, tc := range cases {
+		t.Logf("\t%s", tc.desc)
+		if id := metricID(tc.name, tc.tags); id != tc.expect {
+			t.Fatalf("expected (%s) got (%s)", tc.expect, id)
+		}
+	}
+
+	t.Log("\tdistinct tag sets do not collide")
+	{
+		if metricID("foo|ST[env:prod]", nil) == metricID("foo|ST[env:dev]", nil) {
+			t.Fatal("expected distinct ids")
+		}
+	}
+}
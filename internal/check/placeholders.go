@@ -0,0 +1,151 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/pkg/errors"
+)
+
+// placeholderRx matches a {{name}} placeholder in the check target, title
+// or check bundle template
+var placeholderRx = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// metadataURL is the (EC2 compatible) instance metadata service used to
+// resolve {{instance_id}}
+var metadataURL = "http://169.254.169.254/latest"
+
+const metadataTimeout = 2 * time.Second
+
+// placeholders resolve the value of each placeholder, only the placeholders
+// referenced are resolved (e.g. the metadata service is not queried unless
+// {{instance_id}} is used)
+var placeholders = map[string]func() (string, error){
+	"agent_name":    func() (string, error) { return release.NAME, nil },
+	"agent_version": func() (string, error) { return release.VERSION, nil },
+	"fqdn":          fqdn,
+	"hostname":      os.Hostname,
+	"instance_id":   instanceID,
+}
+
+// expandPlaceholders substitutes the placeholders in data, esc (if not nil)
+// is applied to each value (e.g. to escape values within json strings)
+func expandPlaceholders(data []byte, esc func(string) string) ([]byte, error) {
+	values := map[string]string{}
+	var unknown []string
+	var resolveErr error
+	data = placeholderRx.ReplaceAllFunc(data, func(m []byte) []byte {
+		name := string(placeholderRx.FindSubmatch(m)[1])
+		v, ok := values[name]
+		if !ok {
+			resolve, known := placeholders[name]
+			if !known {
+				unknown = append(unknown, name)
+				return m
+			}
+			var err error
+			if v, err = resolve(); err != nil {
+				if resolveErr == nil {
+					resolveErr = errors.Wrapf(err, "resolving placeholder %s", name)
+				}
+				return m
+			}
+			values[name] = v
+		}
+		if esc != nil {
+			v = esc(v)
+		}
+		return []byte(v)
+	})
+	if len(unknown) > 0 {
+		return nil, errors.Errorf("unknown placeholder(s) (%s)", strings.Join(unknown, ","))
+	}
+	if resolveErr != nil {
+		return nil, resolveErr
+	}
+
+	return data, nil
+}
+
+// expandString substitutes the placeholders in a setting (check target, title)
+func expandString(s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	data, err := expandPlaceholders([]byte(s), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fqdn returns the fully qualified name of the host (the canonical name of
+// the host name), or the host name if it cannot be resolved
+func fqdn() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	cname, err := net.LookupCNAME(hostname)
+	if err != nil || cname == "" || cname == "." {
+		return hostname, nil
+	}
+	return strings.TrimSuffix(cname, "."), nil
+}
+
+// instanceID returns the cloud instance id from the instance metadata
+// service, a session token is requested first (IMDSv2) and used if issued
+func instanceID() (string, error) {
+	client := &http.Client{Timeout: metadataTimeout}
+
+	var token string
+	req, err := http.NewRequest("PUT", metadataURL+"/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	if resp, err := client.Do(req); err == nil {
+		body, rerr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if rerr == nil && resp.StatusCode == http.StatusOK {
+			token = strings.TrimSpace(string(body))
+		}
+	}
+
+	req, err = http.NewRequest("GET", metadataURL+"/meta-data/instance-id", nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "instance metadata")
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "instance metadata")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("instance metadata, unexpected response (%s)", resp.Status)
+	}
+	id := strings.TrimSpace(string(body))
+	if id == "" {
+		return "", errors.New("instance metadata, empty instance id")
+	}
+
+	return id, nil
+}
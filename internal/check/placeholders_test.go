@@ -0,0 +1,118 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/rs/zerolog"
+)
+
+func TestExpandString(t *testing.T) {
+	t.Log("Testing expandString")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	t.Log("no placeholders")
+	{
+		v, err := expandString("foo /agent")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if v != "foo /agent" {
+			t.Fatalf("unexpected value (%s)", v)
+		}
+	}
+
+	t.Log("hostname, agent")
+	{
+		v, err := expandString("{{hostname}} {{ agent_name }}-{{agent_version}}")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		expect := hostname + " " + release.NAME + "-" + release.VERSION
+		if v != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, v)
+		}
+	}
+
+	t.Log("fqdn")
+	{
+		v, err := expandString("{{fqdn}}")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if v == "" || v == "{{fqdn}}" {
+			t.Fatalf("unexpected value (%s)", v)
+		}
+	}
+
+	t.Log("unknown")
+	{
+		_, err := expandString("{{owner}}-{{hostname}}")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != "unknown placeholder(s) (owner)" {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("instance_id")
+	{
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+				fmt.Fprint(w, "abc")
+			case r.URL.Path == "/latest/meta-data/instance-id":
+				if r.Header.Get("X-aws-ec2-metadata-token") != "abc" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprintln(w, "i-0123456789abcdef0")
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer ts.Close()
+
+		save := metadataURL
+		metadataURL = ts.URL + "/latest"
+		defer func() { metadataURL = save }()
+
+		v, err := expandString("{{instance_id}} /agent")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if v != "i-0123456789abcdef0 /agent" {
+			t.Fatalf("unexpected value (%s)", v)
+		}
+	}
+
+	t.Log("instance_id (unavailable)")
+	{
+		ts := httptest.NewServer(http.NotFoundHandler())
+		defer ts.Close()
+
+		save := metadataURL
+		metadataURL = ts.URL + "/latest"
+		defer func() { metadataURL = save }()
+
+		if _, err := expandString("{{instance_id}}"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
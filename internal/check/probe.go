@@ -0,0 +1,140 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	apiconf "github.com/circonus-labs/circonus-gometrics/api/config"
+	"github.com/pkg/errors"
+)
+
+// probeTimeout is the maximum time allowed for the startup connectivity probe,
+// all brokers are probed concurrently within it
+var probeTimeout = 10 * time.Second
+
+// probeBroker verifies, at startup, the broker the agent depends on can be
+// reached: the check's submission url if it has one (e.g. httptrap), otherwise
+// the reverse broker address(es), probed concurrently so startup is delayed
+// at most probeTimeout regardless of the number of brokers. The result is
+// logged, a failure does not prevent the agent from starting - it is only a
+// diagnostic for misconfigured firewalls or proxies which would otherwise only
+// surface on the first submission or reverse connection attempt.
+func (c *Check) probeBroker() {
+	c.Lock()
+	bundle := c.bundle
+	revConfigs := c.revConfigs
+	c.Unlock()
+
+	if bundle == nil {
+		return
+	}
+
+	deadline := time.Now().Add(probeTimeout)
+
+	if submissionURL := bundle.Config[apiconf.SubmissionURL]; submissionURL != "" {
+		logger := c.logger.With().Str("submission_url", config.RedactURL(submissionURL)).Logger()
+		surl, err := url.Parse(submissionURL)
+		if err != nil {
+			logger.Warn().Err(config.RedactError(err)).Msg("broker probe, invalid submission url")
+			return
+		}
+		var tlsConfig *tls.Config
+		if surl.Scheme == "https" && len(bundle.Brokers) > 0 {
+			// same verification as submissions, see submit()
+			if tc, err := c.brokerTLSConfig(bundle.Brokers[0], surl); err == nil {
+				tlsConfig = tc
+			}
+		}
+		latency, err := probeAddr(surl, tlsConfig, deadline)
+		if err != nil {
+			logger.Error().Err(err).Msg("broker probe FAILED, submission url unreachable - check firewall/proxy settings")
+			return
+		}
+		logger.Info().Float64("latency_ms", msec(latency)).Msg("broker probe OK, submission url reachable")
+		return
+	}
+
+	if revConfigs == nil {
+		return
+	}
+
+	type probeResult struct {
+		latency time.Duration
+		err     error
+	}
+
+	rcs := *revConfigs
+	results := make([]probeResult, len(rcs))
+	var wg sync.WaitGroup
+	for i, rc := range rcs {
+		wg.Add(1)
+		go func(i int, rc ReverseConfig) {
+			defer wg.Done()
+			latency, err := probeAddr(rc.ReverseURL, rc.TLSConfig, deadline)
+			results[i] = probeResult{latency: latency, err: err}
+		}(i, rc)
+	}
+	wg.Wait()
+
+	// logged in broker order
+	for i, rc := range rcs {
+		logger := c.logger.With().Str("broker", rc.BrokerID).Str("address", rc.ReverseURL.Host).Logger()
+		if err := results[i].err; err != nil {
+			logger.Error().Err(err).Msg("broker probe FAILED, reverse address unreachable - check firewall/proxy settings")
+			continue
+		}
+		logger.Info().Float64("latency_ms", msec(results[i].latency)).Msg("broker probe OK, reverse address reachable")
+	}
+}
+
+// probeAddr connects to the url's host (and completes the tls handshake if a
+// tls configuration is provided or the scheme is https), no request is sent.
+// The connection must complete by the deadline. Returns the time taken to connect.
+func probeAddr(u *url.URL, tlsConfig *tls.Config, deadline time.Time) (time.Duration, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if tlsConfig == nil && u.Scheme == "https" {
+		tlsConfig = &tls.Config{ServerName: u.Hostname()}
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	start := time.Now()
+
+	if tlsConfig == nil {
+		conn, err := dialer.Dial("tcp", addr)
+		if err != nil {
+			return 0, errors.Wrapf(err, "connecting to %s", addr)
+		}
+		conn.Close()
+		return time.Since(start), nil
+	}
+
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	if err != nil {
+		return 0, errors.Wrapf(err, "tls connection to %s", addr)
+	}
+	conn.Close()
+
+	return time.Since(start), nil
+}
+
+// msec converts a duration to (fractional) milliseconds for logging
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
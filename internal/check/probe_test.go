@@ -0,0 +1,123 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/rs/zerolog"
+)
+
+func TestProbeAddr(t *testing.T) {
+	t.Log("Testing probeAddr")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("probe should not send a request")
+	})
+
+	t.Log("\thttp")
+	{
+		ts := httptest.NewServer(handler)
+		defer ts.Close()
+		u, err := url.Parse(ts.URL + "/module/httptrap/abc/secret")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if _, err := probeAddr(u, nil, time.Now().Add(probeTimeout)); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("\thttps")
+	{
+		ts := httptest.NewTLSServer(handler)
+		defer ts.Close()
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		t.Log("\t\tunverified certificate")
+		if _, err := probeAddr(u, nil, time.Now().Add(probeTimeout)); err == nil {
+			t.Fatal("expected error")
+		}
+
+		t.Log("\t\tverified certificate")
+		tc := ts.Client().Transport.(*http.Transport).TLSClientConfig
+		tlsConfig := &tls.Config{RootCAs: tc.RootCAs, ServerName: "example.com"}
+		if _, err := probeAddr(u, tlsConfig, time.Now().Add(probeTimeout)); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("\tunreachable")
+	{
+		ts := httptest.NewServer(handler)
+		u, err := url.Parse(ts.URL)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		ts.Close()
+		if _, err := probeAddr(u, nil, time.Now().Add(probeTimeout)); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestProbeBroker(t *testing.T) {
+	t.Log("Testing probeBroker")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tbrokers probed concurrently, shared deadline")
+	{
+		// accepts connections, never completes the tls handshake
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer l.Close()
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		u, err := url.Parse("https://" + l.Addr().String() + "/check/abc")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		rcs := []ReverseConfig{}
+		for _, id := range []string{"1", "2", "3"} {
+			rcs = append(rcs, ReverseConfig{BrokerID: id, ReverseURL: u, TLSConfig: &tls.Config{ServerName: "broker"}})
+		}
+		c := Check{bundle: &api.CheckBundle{CID: "/check_bundle/123"}, revConfigs: &rcs}
+
+		origTimeout := probeTimeout
+		probeTimeout = 500 * time.Millisecond
+		start := time.Now()
+		c.probeBroker()
+		elapsed := time.Since(start)
+		probeTimeout = origTimeout
+
+		if elapsed >= 3*500*time.Millisecond {
+			t.Fatalf("expected brokers to be probed concurrently, took %s", elapsed)
+		}
+	}
+}
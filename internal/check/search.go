@@ -0,0 +1,100 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// how a check bundle is selected when more than one matches the search tags
+const (
+	searchSelectNone   = "none"   // fail (default)
+	searchSelectOldest = "oldest" // the earliest created check bundle
+	searchSelectNewest = "newest" // the most recently created check bundle
+)
+
+// loadSearchTags parses the check.search_tags setting, a comma separated list
+// of tags (placeholders are expanded, e.g. host:{{hostname}}), nil if not set
+func loadSearchTags() ([]string, error) {
+	list := viper.GetString(config.KeyCheckSearchTags)
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		t, err := expandString(tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "check search tag (%s)", tag)
+		}
+		if strings.ContainsAny(t, `"()`) {
+			return nil, errors.Errorf("invalid check search tag (%s)", t)
+		}
+		tags = mergeTags(tags, []string{t})
+	}
+
+	return tags, nil
+}
+
+// loadSearchSelect returns the configured check bundle selection policy
+func loadSearchSelect() (string, error) {
+	switch policy := viper.GetString(config.KeyCheckSearchSelect); policy {
+	case "", searchSelectNone:
+		return searchSelectNone, nil
+	case searchSelectOldest, searchSelectNewest:
+		return policy, nil
+	default:
+		return "", errors.Errorf("invalid check search select (%s), expected none|oldest|newest", policy)
+	}
+}
+
+// searchCriteria returns the search query for an active agent check bundle
+// with all of the tags
+func searchCriteria(tags []string) api.SearchQueryType {
+	q := `(active:1)(type:"json:nad")`
+	for _, tag := range tags {
+		q += fmt.Sprintf(`(tags:"%s")`, tag)
+	}
+	return api.SearchQueryType(q)
+}
+
+// selectBundle returns a check bundle from more than one matching the search
+// tags, according to the selection policy
+func selectBundle(bundles []api.CheckBundle, policy string) (*api.CheckBundle, error) {
+	if len(bundles) == 0 {
+		return nil, errors.New("no check bundles to select from")
+	}
+
+	selected := 0
+	switch policy {
+	case searchSelectOldest:
+		for i := range bundles {
+			if bundles[i].Created < bundles[selected].Created {
+				selected = i
+			}
+		}
+	case searchSelectNewest:
+		for i := range bundles {
+			if bundles[i].Created > bundles[selected].Created {
+				selected = i
+			}
+		}
+	default:
+		return nil, errors.Errorf("no selection policy (%s)", policy)
+	}
+
+	return &bundles[selected], nil
+}
@@ -0,0 +1,233 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestLoadSearchTags(t *testing.T) {
+	t.Log("Testing loadSearchTags")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tnot set")
+	{
+		viper.Reset()
+		tags, err := loadSearchTags()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if tags != nil {
+			t.Fatalf("expected nil, got %v", tags)
+		}
+	}
+
+	t.Log("\tvalid, placeholders expanded, duplicates removed")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckSearchTags, "service:web, host:{{hostname}},,service:web")
+		hn, err := os.Hostname()
+		if err != nil {
+			t.Fatalf("hostname (%s)", err)
+		}
+		tags, err := loadSearchTags()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(tags) != 2 || tags[0] != "service:web" || tags[1] != "host:"+hn {
+			t.Fatalf("unexpected tags %v", tags)
+		}
+	}
+
+	t.Log("\tinvalid tag")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckSearchTags, `service:"web"`)
+		if _, err := loadSearchTags(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tunknown placeholder")
+	{
+		viper.Reset()
+		viper.Set(config.KeyCheckSearchTags, "host:{{foo}}")
+		if _, err := loadSearchTags(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestLoadSearchSelect(t *testing.T) {
+	t.Log("Testing loadSearchSelect")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tests := []struct {
+		policy   string
+		expected string
+		err      string
+	}{
+		{"", searchSelectNone, ""},
+		{"none", searchSelectNone, ""},
+		{"oldest", searchSelectOldest, ""},
+		{"newest", searchSelectNewest, ""},
+		{"first", "", "invalid check search select (first), expected none|oldest|newest"},
+	}
+
+	for _, tst := range tests {
+		t.Logf("\t'%s'", tst.policy)
+		viper.Reset()
+		viper.Set(config.KeyCheckSearchSelect, tst.policy)
+		policy, err := loadSearchSelect()
+		if tst.err != "" {
+			if err == nil || err.Error() != tst.err {
+				t.Fatalf("expected error (%s), got (%v)", tst.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if policy != tst.expected {
+			t.Fatalf("expected (%s) got (%s)", tst.expected, policy)
+		}
+	}
+}
+
+func TestSearchCriteria(t *testing.T) {
+	t.Log("Testing searchCriteria")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	expected := `(active:1)(type:"json:nad")(tags:"service:web")(tags:"host:foo")`
+	if q := string(searchCriteria([]string{"service:web", "host:foo"})); q != expected {
+		t.Fatalf("expected (%s) got (%s)", expected, q)
+	}
+}
+
+func TestSelectBundle(t *testing.T) {
+	t.Log("Testing selectBundle")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	bundles := []api.CheckBundle{
+		{CID: "/check_bundle/2", Created: 200},
+		{CID: "/check_bundle/1", Created: 100},
+		{CID: "/check_bundle/3", Created: 300},
+	}
+
+	t.Log("\toldest")
+	{
+		b, err := selectBundle(bundles, searchSelectOldest)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if b.CID != "/check_bundle/1" {
+			t.Fatalf("expected /check_bundle/1, got (%s)", b.CID)
+		}
+	}
+
+	t.Log("\tnewest")
+	{
+		b, err := selectBundle(bundles, searchSelectNewest)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if b.CID != "/check_bundle/3" {
+			t.Fatalf("expected /check_bundle/3, got (%s)", b.CID)
+		}
+	}
+
+	t.Log("\tnone")
+	{
+		if _, err := selectBundle(bundles, searchSelectNone); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
+
+func TestFindCheckSearchTags(t *testing.T) {
+	t.Log("Testing findCheck by search tags")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	client := &APIMock{
+		SearchCheckBundlesFunc: func(searchCriteria *api.SearchQueryType, filterCriteria *map[string][]string) (*[]api.CheckBundle, error) {
+			q := string(*searchCriteria)
+			if strings.Contains(q, "target:") {
+				t.Fatalf("unexpected target in search criteria (%s)", q)
+			}
+			switch {
+			case strings.Contains(q, `(tags:"service:none")`):
+				return &[]api.CheckBundle{}, nil
+			case strings.Contains(q, `(tags:"service:multiple")`):
+				return &[]api.CheckBundle{
+					{CID: "/check_bundle/1", Created: 100},
+					{CID: "/check_bundle/2", Created: 200},
+				}, nil
+			default:
+				return &[]api.CheckBundle{{CID: "/check_bundle/1", Created: 100}}, nil
+			}
+		},
+	}
+
+	t.Log("\tnot found")
+	{
+		c := Check{client: client, searchTags: []string{"service:none"}, searchSelect: searchSelectNone}
+		_, found, err := c.findCheck()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if found != 0 {
+			t.Fatalf("expected found == 0, got %d", found)
+		}
+	}
+
+	t.Log("\tfound")
+	{
+		c := Check{client: client, searchTags: []string{"service:web"}, searchSelect: searchSelectNone}
+		b, found, err := c.findCheck()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if found != 1 || b.CID != "/check_bundle/1" {
+			t.Fatalf("unexpected result %d %#v", found, b)
+		}
+	}
+
+	t.Log("\tmultiple, no selection policy")
+	{
+		c := Check{client: client, searchTags: []string{"service:multiple"}, searchSelect: searchSelectNone}
+		_, found, err := c.findCheck()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if found != 2 {
+			t.Fatalf("expected found == 2, got %d", found)
+		}
+	}
+
+	t.Log("\tmultiple, newest")
+	{
+		c := Check{client: client, searchTags: []string{"service:multiple"}, searchSelect: searchSelectNewest}
+		b, found, err := c.findCheck()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if found != 2 || b.CID != "/check_bundle/2" {
+			t.Fatalf("unexpected result %d %#v", found, b)
+		}
+	}
+}
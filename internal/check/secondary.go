@@ -0,0 +1,86 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"sync/atomic"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	"github.com/maier/go-appstats"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// newSecondary returns a check for the secondary (HA) cluster. The check
+// bundle is fetched on first use so the secondary cluster being unavailable
+// does not prevent the agent from starting.
+func newSecondary(cid string) (*Check, error) {
+	sc := &Check{
+		logger:       log.With().Str("pkg", "check.secondary").Logger(),
+		secondaryCID: cid,
+	}
+
+	client, err := newAPIClient(
+		viper.GetString(config.KeyCheckSecondaryAPIKey),
+		viper.GetString(config.KeyCheckSecondaryAPIApp),
+		viper.GetString(config.KeyCheckSecondaryAPIURL),
+		viper.GetString(config.KeyCheckSecondaryAPICAFile),
+		sc.logger.With().Str("pkg", "check.secondary.api").Logger())
+	if err != nil {
+		return nil, err
+	}
+	sc.client = client
+
+	return sc, nil
+}
+
+// MirrorMetrics submits metrics to the secondary (HA) check, if configured.
+// Failures are logged and counted, they never affect the primary check.
+func (c *Check) MirrorMetrics(m *cgm.Metrics) {
+	if c.secondary == nil {
+		return
+	}
+
+	// do not queue up behind a slow or unreachable secondary cluster
+	if !atomic.CompareAndSwapInt32(&c.mirroring, 0, 1) {
+		appstats.IncrementInt("check_secondary_skipped")
+		c.logger.Warn().Msg("secondary submission in progress, skipping")
+		return
+	}
+	defer atomic.StoreInt32(&c.mirroring, 0)
+
+	if err := c.secondary.mirror(m); err != nil {
+		appstats.IncrementInt("check_secondary_errors")
+		c.logger.Warn().Err(err).Str("cid", c.secondary.secondaryCID).Msg("secondary submission")
+		return
+	}
+
+	appstats.IncrementInt("check_secondary_submits")
+}
+
+// mirror fetches the secondary check bundle (if needed) and submits metrics
+func (c *Check) mirror(m *cgm.Metrics) error {
+	c.Lock()
+	bundle := c.bundle
+	c.Unlock()
+
+	if bundle == nil {
+		b, err := c.fetchCheck(c.secondaryCID)
+		if err != nil {
+			return errors.Wrap(err, "fetching secondary check")
+		}
+		// metrics are not needed, only the submission url and brokers
+		b.Metrics = []api.CheckBundleMetric{}
+		c.Lock()
+		c.bundle = b
+		c.Unlock()
+	}
+
+	return c.SubmitMetrics(m)
+}
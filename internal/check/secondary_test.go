@@ -0,0 +1,86 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	apiconf "github.com/circonus-labs/circonus-gometrics/api/config"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+func TestMirrorMetrics(t *testing.T) {
+	t.Log("Testing MirrorMetrics")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	m := cgm.Metrics{"foo": cgm.Metric{Type: "n", Value: 1}}
+
+	t.Log("\tno secondary")
+	{
+		c := Check{}
+		c.MirrorMetrics(&m) // nop
+	}
+
+	t.Log("\tsecondary api error")
+	{
+		client := genMockClient()
+		client.FetchCheckBundleFunc = func(cid api.CIDType) (*api.CheckBundle, error) {
+			return nil, errors.New("forced mock api call error")
+		}
+		c := Check{secondary: &Check{client: client, secondaryCID: "123"}}
+		c.MirrorMetrics(&m)
+		if c.secondary.bundle != nil {
+			t.Fatal("expected nil secondary bundle")
+		}
+		if c.mirroring != 0 {
+			t.Fatal("expected mirroring to be reset")
+		}
+	}
+
+	t.Log("\tsubmitted")
+	{
+		submits := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			submits++
+			w.Write([]byte(`{"stats":1}`))
+		}))
+		defer ts.Close()
+
+		fetches := 0
+		client := genMockClient()
+		client.FetchCheckBundleFunc = func(cid api.CIDType) (*api.CheckBundle, error) {
+			fetches++
+			if *cid != "/check_bundle/123" {
+				return nil, errors.Errorf("unexpected cid (%s)", *cid)
+			}
+			return &api.CheckBundle{
+				CID:    "/check_bundle/123",
+				Config: api.CheckBundleConfig{apiconf.SubmissionURL: ts.URL},
+			}, nil
+		}
+		c := Check{secondary: &Check{client: client, secondaryCID: "123"}}
+		c.MirrorMetrics(&m)
+		c.MirrorMetrics(&m)
+		if submits != 2 {
+			t.Fatalf("expected 2 submissions, got %d", submits)
+		}
+		if fetches != 1 {
+			t.Fatalf("expected 1 check bundle fetch, got %d", fetches)
+		}
+	}
+
+	t.Log("\tin progress, skipped")
+	{
+		c := Check{secondary: &Check{secondaryCID: "123"}, mirroring: 1}
+		c.MirrorMetrics(&m) // secondary has no client, would panic if not skipped
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/maier/go-appstats"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// spool holds metric submissions which failed, to be retried (in order)
+// on subsequent submissions. Each payload is a file named for the time it
+// was spooled so lexical order is submission order.
+type spool struct {
+	dir     string
+	logger  zerolog.Logger
+	maxAge  time.Duration
+	maxSize int64
+}
+
+const spoolExt = ".json"
+
+// newSpool returns the submission spool, nil if spooling is not enabled
+func newSpool(logger zerolog.Logger) (*spool, error) {
+	dir := viper.GetString(config.KeyCheckSpoolDir)
+	if dir == "" {
+		return nil, nil
+	}
+
+	maxSize, err := units.ParseBase2Bytes(viper.GetString(config.KeyCheckSpoolMaxSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing spool max size")
+	}
+	maxAge, err := time.ParseDuration(viper.GetString(config.KeyCheckSpoolMaxAge))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing spool max age")
+	}
+
+	return &spool{
+		dir:     dir,
+		logger:  logger.With().Str("spool", dir).Logger(),
+		maxAge:  maxAge,
+		maxSize: int64(maxSize),
+	}, nil
+}
+
+// write adds a payload to the spool
+func (s *spool) write(data []byte) error {
+	name := fmt.Sprintf("%020d%s", time.Now().UnixNano(), spoolExt)
+
+	// write to a temporary file so a partial payload is never submitted
+	tf, err := ioutil.TempFile(s.dir, "spool")
+	if err != nil {
+		return errors.Wrap(err, "creating spool file")
+	}
+	if _, err := tf.Write(data); err != nil {
+		tf.Close()
+		os.Remove(tf.Name())
+		return errors.Wrapf(err, "writing spool file (%s)", tf.Name())
+	}
+	if err := tf.Close(); err != nil {
+		os.Remove(tf.Name())
+		return errors.Wrapf(err, "closing spool file (%s)", tf.Name())
+	}
+	if err := os.Rename(tf.Name(), filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tf.Name())
+		return errors.Wrap(err, "renaming spool file")
+	}
+
+	appstats.IncrementInt("check_spool_written")
+	s.logger.Warn().Str("file", name).Int("bytes", len(data)).Msg("submission spooled")
+
+	s.trim()
+
+	return nil
+}
+
+// replay submits spooled payloads, oldest first, removing each once it has
+// been accepted. It stops at the first failure so order is preserved. A
+// payload rejected by the broker (see isPermanent) is dropped, otherwise it
+// would block the spool until it expired.
+func (s *spool) replay(submit func(data []byte) error) error {
+	s.trim()
+
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+
+	for _, fi := range files {
+		file := filepath.Join(s.dir, fi.Name())
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrap(err, "reading spool file")
+		}
+		if err := submit(data); err != nil {
+			if isPermanent(err) {
+				s.logger.Error().Err(err).Str("file", fi.Name()).Msg("spooled submission rejected")
+				s.remove(fi.Name(), "rejected")
+				continue
+			}
+			return err
+		}
+		if err := os.Remove(file); err != nil {
+			return errors.Wrap(err, "removing submitted spool file")
+		}
+		appstats.IncrementInt("check_spool_submitted")
+		s.logger.Info().Str("file", fi.Name()).Msg("spooled submission sent")
+	}
+
+	return nil
+}
+
+// trim removes spooled payloads older than the maximum age, then the
+// oldest payloads until the spool is within the maximum size
+func (s *spool) trim() {
+	files, err := s.files()
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("listing spool")
+		return
+	}
+
+	var size int64
+	keep := files[:0]
+	for _, fi := range files {
+		if s.maxAge > 0 && time.Since(fi.ModTime()) > s.maxAge {
+			s.remove(fi.Name(), "expired")
+			continue
+		}
+		size += fi.Size()
+		keep = append(keep, fi)
+	}
+
+	for _, fi := range keep {
+		if s.maxSize <= 0 || size <= s.maxSize {
+			break
+		}
+		s.remove(fi.Name(), "spool full")
+		size -= fi.Size()
+	}
+}
+
+// remove drops a spooled payload which will not be submitted
+func (s *spool) remove(name, reason string) {
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+		s.logger.Warn().Err(err).Str("file", name).Msg("removing spool file")
+		return
+	}
+	appstats.IncrementInt("check_spool_dropped")
+	s.logger.Warn().Str("file", name).Str("reason", reason).Msg("spooled submission dropped")
+}
+
+// files returns the spooled payloads in submission order
+func (s *spool) files() ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading spool directory")
+	}
+
+	// entries are sorted by name, i.e. the time they were spooled
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		if fi.Mode().IsRegular() && strings.HasSuffix(fi.Name(), spoolExt) {
+			files = append(files, fi)
+		}
+	}
+
+	return files, nil
+}
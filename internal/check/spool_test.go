@@ -0,0 +1,185 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	apiconf "github.com/circonus-labs/circonus-gometrics/api/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestSpool(t *testing.T) {
+	t.Log("Testing spool")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &spool{dir: dir, logger: log.With().Logger(), maxAge: time.Hour, maxSize: 10}
+
+	t.Log("\twrite, replay in order")
+	{
+		for _, p := range []string{"a", "b", "c"} {
+			if err := s.write([]byte(p)); err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+		}
+		var got string
+		err := s.replay(func(data []byte) error {
+			got += string(data)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if got != "abc" {
+			t.Fatalf("expected abc, got (%s)", got)
+		}
+		if files, _ := s.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d files", len(files))
+		}
+	}
+
+	t.Log("\tmax size, oldest dropped")
+	{
+		s.write([]byte("123456"))
+		s.write([]byte("7890ab"))
+		files, _ := s.files()
+		if len(files) != 1 {
+			t.Fatalf("expected 1 file, got %d", len(files))
+		}
+		data, _ := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+		if string(data) != "7890ab" {
+			t.Fatalf("expected newest payload, got (%s)", string(data))
+		}
+	}
+
+	t.Log("\tmax age, expired dropped")
+	{
+		files, _ := s.files()
+		old := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(filepath.Join(dir, files[0].Name()), old, old)
+		s.trim()
+		if files, _ := s.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d files", len(files))
+		}
+	}
+}
+
+func TestSubmitMetricsSpool(t *testing.T) {
+	t.Log("Testing SubmitMetrics w/spool")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fail := true
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var m cgm.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, ok := m["bad"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for mn := range m {
+			got = append(got, mn)
+		}
+		w.Write([]byte(`{"stats":1}`))
+	}))
+	defer ts.Close()
+
+	c := Check{
+		bundle: &api.CheckBundle{
+			CID:    "/check_bundle/123",
+			Config: api.CheckBundleConfig{apiconf.SubmissionURL: ts.URL},
+		},
+		spool: &spool{dir: dir, logger: log.With().Logger(), maxAge: time.Hour, maxSize: 1024 * 1024},
+	}
+
+	t.Log("\toutage, spooled")
+	{
+		if err := c.SubmitMetrics(&cgm.Metrics{"a": cgm.Metric{Type: "n", Value: 1}}); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := c.SubmitMetrics(&cgm.Metrics{"b": cgm.Metric{Type: "n", Value: 1}}); err == nil {
+			t.Fatal("expected error")
+		}
+		if files, _ := c.spool.files(); len(files) != 2 {
+			t.Fatalf("expected 2 spooled, got %d", len(files))
+		}
+	}
+
+	t.Log("\trecovered, spooled sent first")
+	{
+		fail = false
+		if err := c.SubmitMetrics(&cgm.Metrics{"c": cgm.Metric{Type: "n", Value: 1}}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+			t.Fatalf("expected [a b c], got %v", got)
+		}
+		if files, _ := c.spool.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d", len(files))
+		}
+	}
+
+	t.Log("	rejected (4xx), not spooled")
+	{
+		if err := c.SubmitMetrics(&cgm.Metrics{"bad": cgm.Metric{Type: "n", Value: 1}}); err == nil {
+			t.Fatal("expected error")
+		}
+		if files, _ := c.spool.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d", len(files))
+		}
+	}
+
+	t.Log("	rejected (4xx) spooled payload dropped, not blocking the spool")
+	{
+		got = nil
+		if err := c.spool.write([]byte(`{"bad":{"_type":"n","_value":1}}`)); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := c.spool.write([]byte(`{"d":{"_type":"n","_value":1}}`)); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := c.SubmitMetrics(&cgm.Metrics{"e": cgm.Metric{Type: "n", Value: 1}}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(got) != 2 || got[0] != "d" || got[1] != "e" {
+			t.Fatalf("expected [d e], got %v", got)
+		}
+		if files, _ := c.spool.files(); len(files) != 0 {
+			t.Fatalf("expected empty spool, got %d", len(files))
+		}
+	}
+}
@@ -15,10 +15,9 @@ import (
 	"github.com/pkg/errors"
 )
 
+// setMetricStates updates the known metric states, from m or from the API
+// if m is nil, the caller must hold the lock (see EnableNewMetrics)
 func (c *Check) setMetricStates(m *[]api.CheckBundleMetric) error {
-	c.Lock()
-	defer c.Unlock()
-
 	if m == nil {
 		metrics, err := c.getFullCheckMetrics()
 		if err != nil {
@@ -31,8 +30,18 @@ func (c *Check) setMetricStates(m *[]api.CheckBundleMetric) error {
 		c.metricStates = &metricStates{}
 	}
 
+	if c.metricDetails == nil {
+		c.metricDetails = make(map[string]metricDetail)
+	}
+
 	for _, metric := range *m {
-		(*c.metricStates)[metric.Name] = metric.Status
+		id := metricID(metric.Name, metric.Tags)
+		(*c.metricStates)[id] = metric.Status
+		units := ""
+		if metric.Units != nil {
+			units = *metric.Units
+		}
+		c.metricDetails[id] = metricDetail{units: units, tags: metric.Tags}
 	}
 
 	c.lastRefresh = time.Now()
@@ -45,25 +54,51 @@ func (c *Check) setMetricStates(m *[]api.CheckBundleMetric) error {
 	return nil
 }
 
-func (c *Check) loadState() (*metricStates, error) {
+// loadState loads the metric states and returns the version of the state file
+// format read, version 1 (flat, keyed by bare metric name) files are migrated
+func (c *Check) loadState() (*metricStates, int, error) {
 	if c.stateFile == "" {
-		return nil, errors.New("invalid state file (empty)")
+		return nil, 0, errors.New("invalid state file (empty)")
 	}
 
-	var ms metricStates
-
 	sf, err := os.Open(c.stateFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "opening state file")
+		return nil, 0, errors.Wrap(err, "opening state file")
 	}
 	defer sf.Close()
 
+	var raw map[string]json.RawMessage
 	dec := json.NewDecoder(sf)
-	if err := dec.Decode(&ms); err != nil {
-		return nil, errors.Wrap(err, "parsing state file")
+	if err := dec.Decode(&raw); err != nil {
+		return nil, 0, errors.Wrap(err, "parsing state file")
+	}
+
+	// version 2+, {"version": n, "metrics": {...}}, identified by a numeric
+	// version (in a version 1 file, all values are status strings, a metric
+	// named "version" or "metrics" is not mistaken for the versioned format)
+	var version int
+	if err := json.Unmarshal(raw["version"], &version); err == nil {
+		if version < 2 || version > stateVersion {
+			return nil, 0, errors.Errorf("unsupported state file version (%d)", version)
+		}
+		ms := metricStates{}
+		if err := json.Unmarshal(raw["metrics"], &ms); err != nil {
+			return nil, 0, errors.Wrap(err, "parsing state file metrics")
+		}
+		return &ms, version, nil
+	}
+
+	// version 1, flat map of metric name to status
+	ms := metricStates{}
+	for name, rs := range raw {
+		var status string
+		if err := json.Unmarshal(rs, &status); err != nil {
+			return nil, 0, errors.Wrapf(err, "parsing state file metric (%s)", name)
+		}
+		ms[metricID(name, nil)] = status
 	}
 
-	return &ms, nil
+	return &ms, 1, nil
 }
 
 func (c *Check) saveState(ms *metricStates) error {
@@ -78,7 +113,7 @@ func (c *Check) saveState(ms *metricStates) error {
 
 	enc := json.NewEncoder(sf)
 	enc.SetIndent("", "  ")
-	if err := enc.Encode(ms); err != nil {
+	if err := enc.Encode(stateFile{Version: stateVersion, Metrics: *ms}); err != nil {
 		sf.Close()
 		os.Remove(sf.Name())
 		return errors.Wrap(err, "error encoding state (removing temp file)")
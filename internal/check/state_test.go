@@ -6,6 +6,9 @@
 package check
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/circonus-labs/circonus-agent/internal/config"
@@ -22,7 +25,7 @@ func TestLoadState(t *testing.T) {
 	{
 		c := Check{stateFile: ""}
 
-		_, err := c.loadState()
+		_, _, err := c.loadState()
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -35,7 +38,7 @@ func TestLoadState(t *testing.T) {
 	{
 		c := Check{stateFile: "testdata/state/missing"}
 
-		_, err := c.loadState()
+		_, _, err := c.loadState()
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -48,7 +51,7 @@ func TestLoadState(t *testing.T) {
 	{
 		c := Check{stateFile: "testdata/state/bad.json"}
 
-		_, err := c.loadState()
+		_, _, err := c.loadState()
 		if err == nil {
 			t.Fatal("expected error")
 		}
@@ -57,14 +60,17 @@ func TestLoadState(t *testing.T) {
 		}
 	}
 
-	t.Log("stateFile (valid)")
+	t.Log("stateFile (valid, version 1)")
 	{
 		c := Check{stateFile: "testdata/state/valid.json"}
 
-		ms, err := c.loadState()
+		ms, version, err := c.loadState()
 		if err != nil {
 			t.Fatalf("expected no error, got (%s)", err)
 		}
+		if version != 1 {
+			t.Fatalf("expected version 1, got (%d)", version)
+		}
 		status, found := (*ms)["foo"]
 		if !found {
 			t.Fatalf("expected metric 'foo' in (%#v)", *ms)
@@ -73,6 +79,57 @@ func TestLoadState(t *testing.T) {
 			t.Fatalf("expected foo have status 'active' not (%s)", status)
 		}
 	}
+
+	t.Log("stateFile (valid, version 2)")
+	{
+		c := Check{stateFile: "testdata/state/valid_v2.json"}
+
+		ms, version, err := c.loadState()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if version != stateVersion {
+			t.Fatalf("expected version %d, got (%d)", stateVersion, version)
+		}
+		if status := (*ms)["foo"]; status != "active" {
+			t.Fatalf("expected foo have status 'active' not (%s)", status)
+		}
+		if status := (*ms)["foo|ST[env:prod]"]; status != "available" {
+			t.Fatalf("expected foo|ST[env:prod] have status 'available' not (%s)", status)
+		}
+	}
+
+	t.Log("stateFile (valid, version 1, metrics named 'metrics' and 'version')")
+	{
+		c := Check{stateFile: "testdata/state/valid_v1_keys.json"}
+
+		ms, version, err := c.loadState()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if version != 1 {
+			t.Fatalf("expected version 1, got (%d)", version)
+		}
+		if status := (*ms)["metrics"]; status != "active" {
+			t.Fatalf("expected metrics have status 'active' not (%s)", status)
+		}
+		if status := (*ms)["version"]; status != "available" {
+			t.Fatalf("expected version have status 'available' not (%s)", status)
+		}
+	}
+
+	t.Log("stateFile (unsupported version)")
+	{
+		c := Check{stateFile: "testdata/state/unsupported.json"}
+
+		_, _, err := c.loadState()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != "unsupported state file version (99)" {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
 }
 
 func TestSaveState(t *testing.T) {
@@ -104,6 +161,50 @@ func TestSaveState(t *testing.T) {
 			t.Fatalf("expected no error, got (%s)", err)
 		}
 	}
+
+	t.Log("upgrade version 1 to current")
+	{
+		dir, err := ioutil.TempDir("", "state")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer os.RemoveAll(dir)
+
+		data, err := ioutil.ReadFile("testdata/state/valid.json")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		c := Check{statePath: dir, stateFile: filepath.Join(dir, "metrics.json")}
+		if err := ioutil.WriteFile(c.stateFile, data, 0644); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		old, version, err := c.loadState()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if version != 1 {
+			t.Fatalf("expected version 1, got (%d)", version)
+		}
+		(*old)["foo|ST[env:prod]"] = "active"
+		if err := c.saveState(old); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		upgraded, version, err := c.loadState()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if version != stateVersion {
+			t.Fatalf("expected version %d, got (%d)", stateVersion, version)
+		}
+		if len(*upgraded) != 3 {
+			t.Fatalf("expected 3 metrics, got (%#v)", *upgraded)
+		}
+		if (*upgraded)["bar"] != "available" || (*upgraded)["foo|ST[env:prod]"] != "active" {
+			t.Fatalf("unexpected states (%#v)", *upgraded)
+		}
+	}
 }
 
 func TestVerifyStatePath(t *testing.T) {
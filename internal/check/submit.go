@@ -0,0 +1,158 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	apiconf "github.com/circonus-labs/circonus-gometrics/api/config"
+	"github.com/pkg/errors"
+)
+
+// submitTimeout is the maximum time allowed for a metric submission
+const submitTimeout = 30 * time.Second
+
+// submitError is a submission the broker responded to with an error status
+type submitError struct {
+	code   int
+	status string
+	body   string
+}
+
+func (e *submitError) Error() string {
+	return fmt.Sprintf("submitting metrics, %s (%s)", e.status, e.body)
+}
+
+// isPermanent returns whether a submission error is a rejection of the
+// payload itself (4xx, other than a timeout or rate limit), resubmitting
+// the same payload will not succeed
+func isPermanent(err error) bool {
+	se, ok := errors.Cause(err).(*submitError)
+	if !ok {
+		return false
+	}
+	switch se.code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return se.code >= 400 && se.code < 500
+}
+
+// SubmitMetrics sends metrics directly to the check's submission url (an
+// httptrap check is required), used when the agent is not polled by a broker.
+// If a spool is configured, submissions which failed earlier are sent first
+// and a failed submission is spooled to be retried by the next submission.
+// Submissions rejected by the broker (see isPermanent) are not spooled.
+func (c *Check) SubmitMetrics(m *cgm.Metrics) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "encoding metrics")
+	}
+
+	if c.spool == nil {
+		if err := c.submit(data); err != nil {
+			return err
+		}
+		c.logger.Debug().Int("metrics", len(*m)).Msg("submitted")
+		return nil
+	}
+
+	err = c.spool.replay(c.submit)
+	if err != nil {
+		err = errors.Wrap(err, "submitting spooled metrics")
+	} else {
+		err = c.submit(data)
+	}
+	if err != nil {
+		if isPermanent(err) {
+			return err
+		}
+		if serr := c.spool.write(data); serr != nil {
+			c.logger.Error().Err(serr).Msg("spooling failed submission")
+		}
+		return err
+	}
+
+	c.logger.Debug().Int("metrics", len(*m)).Msg("submitted")
+	return nil
+}
+
+// submit sends a json metrics payload to the check's submission url
+func (c *Check) submit(data []byte) error {
+	c.Lock()
+	bundle := c.bundle
+	c.Unlock()
+
+	if bundle == nil {
+		return errors.New("invalid Check object state, bundle is nil")
+	}
+
+	submissionURL := bundle.Config[apiconf.SubmissionURL]
+	if submissionURL == "" {
+		return errors.Errorf("check bundle (%s) has no submission url, an httptrap check is required", bundle.CID)
+	}
+	surl, err := url.Parse(submissionURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing submission url")
+	}
+
+	var tlsConfig *tls.Config
+	if surl.Scheme == "https" && len(bundle.Brokers) > 0 {
+		// enterprise brokers use certificates signed by the broker CA, public
+		// trap brokers use certificates the system roots can verify
+		tc, err := c.brokerTLSConfig(bundle.Brokers[0], surl)
+		if err != nil {
+			c.logger.Debug().Err(err).Str("host", surl.Hostname()).Msg("using system roots for submission")
+		} else {
+			tlsConfig = tc
+		}
+	}
+
+	client := &http.Client{
+		Timeout: submitTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	req, err := http.NewRequest("PUT", surl.String(), bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "creating submission request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// the error includes the submission url, which contains the check secret
+		return errors.Wrap(config.RedactError(err), "submitting metrics")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return errors.Wrap(err, "reading submission response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &submitError{code: resp.StatusCode, status: resp.Status, body: string(body)}
+	}
+
+	c.logger.Debug().Str("response", string(body)).Msg("submission response")
+
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package check
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/circonus-labs/circonus-gometrics/api"
+	apiconf "github.com/circonus-labs/circonus-gometrics/api/config"
+	"github.com/rs/zerolog"
+)
+
+func TestSubmitMetrics(t *testing.T) {
+	t.Log("Testing SubmitMetrics")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	m := cgm.Metrics{"foo": cgm.Metric{Type: "n", Value: 1}}
+
+	t.Log("\tnil bundle")
+	{
+		c := Check{}
+		if err := c.SubmitMetrics(&m); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tno submission url")
+	{
+		c := Check{bundle: &api.CheckBundle{CID: "/check_bundle/123", Config: api.CheckBundleConfig{}}}
+		expectedErr := "check bundle (/check_bundle/123) has no submission url, an httptrap check is required"
+		err := c.SubmitMetrics(&m)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != expectedErr {
+			t.Fatalf("expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("\tsubmitted")
+	{
+		var got cgm.Metrics
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "PUT" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"stats":1}`))
+		}))
+		defer ts.Close()
+
+		c := Check{bundle: &api.CheckBundle{CID: "/check_bundle/123", Config: api.CheckBundleConfig{apiconf.SubmissionURL: ts.URL}}}
+		if err := c.SubmitMetrics(&m); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if _, ok := got["foo"]; !ok {
+			t.Fatalf("expected foo metric, got %#v", got)
+		}
+	}
+
+	t.Log("\tsubmission rejected")
+	{
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusForbidden)
+		}))
+		defer ts.Close()
+
+		c := Check{bundle: &api.CheckBundle{CID: "/check_bundle/123", Config: api.CheckBundleConfig{apiconf.SubmissionURL: ts.URL}}}
+		if err := c.SubmitMetrics(&m); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+}
@@ -16,29 +16,73 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// metricStates holds the status of known metrics persisted to metrics.json in defaults.StatePath
+// metricStates holds the status of known metrics persisted to metrics.json in defaults.StatePath,
+// keyed by metric identifier (metric name including any stream tags, see metricID)
 type metricStates map[string]string
 
+// metricDetail holds the units and tags of a metric on the check bundle
+type metricDetail struct {
+	units string
+	tags  []string
+}
+
+// stateFile defines the persisted metric state file format (version 2+),
+// version 1 files are a flat metricStates map keyed by bare metric name
+type stateFile struct {
+	Version int          `json:"version"`
+	Metrics metricStates `json:"metrics"`
+}
+
+const (
+	// stateVersion is the current state file format version
+	stateVersion = 2
+
+	// stream tags are encoded in metric names as name|ST[cat:val,...]
+	streamTagPrefix = "|ST["
+	streamTagSuffix = "]"
+)
+
 // Check exposes the check bundle management interface
 type Check struct {
+	annotationCategory    string
+	annotationClient      API // posts lifecycle annotations, nil if disabled
 	statusActiveMetric    string
 	statusActiveBroker    string
 	brokerMaxResponseTime time.Duration
 	brokerMaxRetries      int
 	bundle                *api.CheckBundle
+	bundleTemplate        *bundleTemplate // settings used when creating a check bundle, nil if not configured
 	client                API
+	forceMetrics          []string
+	forcePending          bool
 	lastRefresh           time.Time
 	logger                zerolog.Logger
 	manage                bool
+	metricDetails         map[string]metricDetail // units and tags of known metrics, from the API (not persisted)
+	metricFilters         [][]string              // configured check bundle metric_filters, nil if not managed
+	metricMeta            MetricMetaSource
 	metricStates          *metricStates
+	metricTypes           []metricTypeRule // configured name pattern to type mappings
 	metricStateUpdate     bool
 	refreshTTL            time.Duration
-	revConfig             *ReverseConfig
+	revConfigs            *[]ReverseConfig
+	searchSelect          string   // selection policy when more than one check bundle matches the search tags
+	searchTags            []string // tags used to find (and create) the check bundle, nil if searching by target
+	secondary             *Check
+	secondaryCID          string
+	spool                 *spool
+	mirroring             int32
 	stateFile             string
 	statePath             string
 	sync.Mutex
 }
 
+// MetricMetaSource provides declared metadata for metrics (e.g. plugin
+// manifests), used when new metrics are enabled on the check bundle
+type MetricMetaSource interface {
+	MetricMeta(metricName string) (units string, mtype string, ok bool)
+}
+
 // ReverseConfig contains the reverse configuration for the check
 type ReverseConfig struct {
 	BrokerAddr *net.TCPAddr
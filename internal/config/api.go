@@ -21,6 +21,21 @@ func apiRequired() bool {
 		return true
 	}
 
+	// one-shot submission requires API access (check submission url)
+	if viper.GetBool(KeyOneshot) {
+		return true
+	}
+
+	// direct submission requires API access (check submission url)
+	if viper.GetBool(KeyDirect) {
+		return true
+	}
+
+	// lifecycle annotations require API access
+	if viper.GetBool(KeyCheckAnnotations) {
+		return true
+	}
+
 	// statsd w/group check enabled require API access
 	if !viper.GetBool(KeyStatsdDisabled) && viper.GetString(KeyStatsdGroupCID) != "" {
 		return true
@@ -66,9 +81,17 @@ func validateAPIOptions() error {
 		if err != nil {
 			return errors.Wrap(err, "Invalid API URL")
 		}
-		if parsedURL.Scheme == "" || parsedURL.Host == "" || parsedURL.Path == "" {
+		if parsedURL.Scheme == "" || parsedURL.Host == "" {
 			return errors.Errorf("Invalid API URL (%s)", apiURL)
 		}
+		// self-hosted (on-prem) installations may specify only the
+		// scheme and host, use the same api path as the default url
+		if parsedURL.Path == "" || parsedURL.Path == "/" {
+			if defURL, err := url.Parse(defaults.APIURL); err == nil {
+				parsedURL.Path = defURL.Path
+				apiURL = parsedURL.String()
+			}
+		}
 	}
 
 	// NOTE the api ca file doesn't come from the cosi config
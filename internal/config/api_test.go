@@ -35,6 +35,16 @@ func TestValidateAPIOptions(t *testing.T) {
 		}
 	}
 
+	t.Log("API required (annotations)")
+	{
+		viper.Set(KeyCheckAnnotations, true)
+		yes := apiRequired()
+		if !yes {
+			t.Fatal("Expected true")
+		}
+		viper.Set(KeyCheckAnnotations, false)
+	}
+
 	t.Log("API required (reverse disabled, statsd disabled)")
 	{
 		viper.Set(KeyReverse, false)
@@ -128,6 +138,21 @@ func TestValidateAPIOptions(t *testing.T) {
 		}
 	}
 
+	t.Log("Valid url, host only (https://circonus.example.com)")
+	{
+		viper.Set(KeyAPITokenKey, "foo")
+		viper.Set(KeyAPITokenApp, "foo")
+		viper.Set(KeyAPIURL, "https://circonus.example.com")
+		err := validateAPIOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%s)", err)
+		}
+		expect := "https://circonus.example.com/v2/"
+		if u := viper.GetString(KeyAPIURL); u != expect {
+			t.Fatalf("Expected (%s) got (%s)", expect, u)
+		}
+	}
+
 	t.Log("Valid options")
 	{
 		viper.Set(KeyAPITokenKey, "foo")
@@ -0,0 +1,65 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"sort"
+
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// EnabledCollectors returns the effective set of builtin collectors to enable.
+// See Config.Collectors for the list and map forms and their precedence.
+func EnabledCollectors() []string {
+	var toggles map[string]interface{}
+	switch v := viper.Get(KeyCollectors).(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		toggles = cast.ToStringMap(v)
+	default:
+		return viper.GetStringSlice(KeyCollectors)
+	}
+
+	enabled := make(map[string]bool, len(toggles))
+	for name, v := range toggles {
+		on, err := cast.ToBoolE(v)
+		if err != nil {
+			log.Warn().Err(err).Str("collector", name).Msg("invalid collector setting, expected true|false, ignoring")
+			continue
+		}
+		enabled[name] = on
+	}
+
+	collectors := make([]string, 0, len(defaults.Collectors)+len(enabled))
+	for _, name := range defaults.Collectors {
+		if on, ok := enabled[name]; ok && !on {
+			continue
+		}
+		collectors = append(collectors, name)
+	}
+
+	added := []string{}
+	for name, on := range enabled {
+		if !on {
+			continue
+		}
+		isDefault := false
+		for _, d := range defaults.Collectors {
+			if d == name {
+				isDefault = true
+				break
+			}
+		}
+		if !isDefault {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+
+	return append(collectors, added...)
+}
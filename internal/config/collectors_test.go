@@ -0,0 +1,52 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestEnabledCollectors(t *testing.T) {
+	t.Log("Testing EnabledCollectors")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	saved := defaults.Collectors
+	defaults.Collectors = []string{"cpu", "if", "vm"}
+	defer func() { defaults.Collectors = saved }()
+
+	tt := []struct {
+		name   string
+		value  interface{}
+		expect []string
+	}{
+		{"list", []string{"cpu", "foo"}, []string{"cpu", "foo"}},
+		{"empty list", []string{}, []string{}},
+		{"map disable", map[string]interface{}{"vm": false}, []string{"cpu", "if"}},
+		{"map enable", map[string]interface{}{"foo": true, "bar": "true", "cpu": true}, []string{"cpu", "if", "vm", "bar", "foo"}},
+		{"map invalid", map[string]interface{}{"if": "maybe", "cpu": "false"}, []string{"if", "vm"}},
+	}
+
+	for _, tst := range tt {
+		t.Logf("\t%s", tst.name)
+		viper.Reset()
+		viper.Set(KeyCollectors, tst.value)
+		got := EnabledCollectors()
+		if len(got) == 0 && len(tst.expect) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tst.expect) {
+			t.Fatalf("expected (%v) got (%v)", tst.expect, got)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,145 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configDirExts are the drop-in configuration file extensions merged
+var configDirExts = map[string]bool{".json": true, ".toml": true, ".yaml": true}
+
+// mergeConfigDir merges the configuration files in the drop-in directory
+// (KeyConfigDir) over the main configuration file. Files are merged in
+// lexical order, later files override earlier ones:
+//
+//	maps (e.g. api, check, plugin_ttls) are merged key by key, recursively
+//	all other values (strings, numbers, booleans, lists) replace the earlier value
+//
+// The drop-in directory is determined before the drop-in files are read, a
+// config_dir setting in a drop-in file has no effect. A missing directory is
+// not an error. Environment variable references are expanded in each file.
+func mergeConfigDir() error {
+	dir := viper.GetString(KeyConfigDir)
+	if dir == "" {
+		return nil
+	}
+
+	files, err := configDirFiles(dir)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	settings := map[string]interface{}{}
+	mainFile := viper.ConfigFileUsed()
+	if mainFile != "" {
+		s, err := readConfigSettings(mainFile)
+		if err != nil {
+			return err
+		}
+		settings = s
+	}
+
+	for _, file := range files {
+		s, err := readConfigSettings(file)
+		if err != nil {
+			return err
+		}
+		mergeSettings(settings, s)
+		log.Debug().Str("file", file).Msg("merged drop-in config file")
+	}
+
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return errors.Wrap(err, "merging drop-in config files")
+	}
+
+	// the merged settings replace the settings read from the main configuration
+	// file, the main file's type is restored so it is parsed correctly on reload
+	viper.SetConfigType("yaml")
+	err = viper.ReadConfig(bytes.NewReader(data))
+	if mainFile != "" {
+		viper.SetConfigType(strings.TrimPrefix(filepath.Ext(mainFile), "."))
+	}
+
+	return errors.Wrap(err, "merging drop-in config files")
+}
+
+// configDirFiles returns the configuration files in the drop-in directory,
+// in lexical order. Hidden files and other file types are ignored.
+func configDirFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir) // sorted by name
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "reading config dir")
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || !configDirExts[filepath.Ext(name)] {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		fi, err := os.Stat(file) // follow symlinks
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// readConfigSettings parses a configuration file (type based on the file's
+// extension) into a map of settings, environment variable references are expanded
+func readConfigSettings(file string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading config file %s", file)
+	}
+
+	expanded, err := ExpandEnv(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "config file %s", file)
+	}
+
+	v := viper.New()
+	v.SetConfigType(strings.TrimPrefix(filepath.Ext(file), "."))
+	if err := v.ReadConfig(bytes.NewReader(expanded)); err != nil {
+		return nil, errors.Wrapf(err, "parsing config file %s", file)
+	}
+
+	return v.AllSettings(), nil
+}
+
+// mergeSettings merges src into dst, maps are merged recursively, any
+// other value in src replaces the value in dst
+func mergeSettings(dst, src map[string]interface{}) {
+	for key, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			if dm, ok := dst[key].(map[string]interface{}); ok {
+				mergeSettings(dm, sm)
+				continue
+			}
+		}
+		dst[key] = sv
+	}
+}
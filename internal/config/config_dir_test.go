@@ -0,0 +1,165 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestMergeConfigDir(t *testing.T) {
+	t.Log("Testing mergeConfigDir")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	verify := func() {
+		if key := viper.GetString(KeyAPITokenKey); key != "override" {
+			t.Fatalf("expected (override) got (%s)", key)
+		}
+		if app := viper.GetString(KeyAPITokenApp); app != "circonus-agent" {
+			t.Fatalf("expected (circonus-agent) got (%s)", app)
+		}
+		if w := viper.GetInt(KeyPluginWorkers); w != 2 {
+			t.Fatalf("expected 2 got (%d)", w)
+		}
+		if c := viper.GetStringSlice(KeyCollectors); !reflect.DeepEqual(c, []string{"if"}) {
+			t.Fatalf("expected [if] got (%v)", c)
+		}
+		expect := map[string]string{"foo": "5m", "bar": "1m"}
+		if ttls := viper.GetStringMapString(KeyPluginTTLs); !reflect.DeepEqual(ttls, expect) {
+			t.Fatalf("expected (%v) got (%v)", expect, ttls)
+		}
+	}
+
+	t.Log("no config dir")
+	{
+		viper.Reset()
+		viper.SetConfigFile(filepath.Join("testdata", "test_cfg_yaml.yaml"))
+		if err := ReadConfig(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("missing config dir")
+	{
+		viper.Reset()
+		viper.Set(KeyConfigDir, filepath.Join("testdata", "missing"))
+		viper.SetConfigFile(filepath.Join("testdata", "test_cfg_yaml.yaml"))
+		if err := ReadConfig(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("invalid drop-in")
+	{
+		viper.Reset()
+		viper.Set(KeyConfigDir, filepath.Join("testdata", "conf.d_invalid"))
+		viper.SetConfigFile(filepath.Join("testdata", "test_cfg_yaml.yaml"))
+		if err := ReadConfig(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("merged (config dir in main config file)")
+	{
+		viper.Reset()
+		viper.SetConfigFile(filepath.Join("testdata", "config_dir.yaml"))
+		if err := ReadConfig(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		verify()
+
+		t.Log("\tre-read (reload)")
+		if err := ReadConfig(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		verify()
+	}
+
+	t.Log("no main config file")
+	{
+		viper.Reset()
+		viper.Set(KeyConfigDir, filepath.Join("testdata", "conf.d"))
+		viper.AddConfigPath(filepath.Join("testdata", "not_a_dir"))
+		viper.SetConfigName("missing")
+		err := ReadConfig()
+		if _, notFound := err.(viper.ConfigFileNotFoundError); !notFound {
+			t.Fatalf("expected not found error, got (%v)", err)
+		}
+		if key := viper.GetString(KeyAPITokenKey); key != "override" {
+			t.Fatalf("expected (override) got (%s)", key)
+		}
+		if w := viper.GetInt(KeyPluginWorkers); w != 2 {
+			t.Fatalf("expected 2 got (%d)", w)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestConfigDirFiles(t *testing.T) {
+	t.Log("Testing configDirFiles")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("missing")
+	{
+		files, err := configDirFiles(filepath.Join("testdata", "missing"))
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if len(files) != 0 {
+			t.Fatalf("expected no files, got (%v)", files)
+		}
+	}
+
+	t.Log("valid (lexical order, hidden and other files ignored)")
+	{
+		files, err := configDirFiles(filepath.Join("testdata", "conf.d"))
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		expect := []string{
+			filepath.Join("testdata", "conf.d", "10-api.json"),
+			filepath.Join("testdata", "conf.d", "20-plugins.toml"),
+			filepath.Join("testdata", "conf.d", "30-workers.yaml"),
+		}
+		if !reflect.DeepEqual(files, expect) {
+			t.Fatalf("expected (%v) got (%v)", expect, files)
+		}
+	}
+}
+
+func TestMergeSettings(t *testing.T) {
+	t.Log("Testing mergeSettings")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dst := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 2},
+		"d": []interface{}{"x", "y"},
+		"e": map[string]interface{}{"f": 1},
+	}
+	src := map[string]interface{}{
+		"a": map[string]interface{}{"c": 3, "g": 4},
+		"d": []interface{}{"z"},
+		"e": "scalar",
+	}
+	expect := map[string]interface{}{
+		"a": map[string]interface{}{"b": 1, "c": 3, "g": 4},
+		"d": []interface{}{"z"},
+		"e": "scalar",
+	}
+
+	mergeSettings(dst, src)
+	if !reflect.DeepEqual(dst, expect) {
+		t.Fatalf("expected (%v) got (%v)", expect, dst)
+	}
+}
@@ -27,15 +27,33 @@ const (
 	// Reverse is false by default
 	Reverse = false
 
+	// Direct submission is false by default
+	Direct = false
+
+	// DirectInterval defines how often metrics are submitted in direct mode
+	DirectInterval = "60s"
+
 	// SSLVerify enabled by default
 	SSLVerify = true
 
 	// NoStatsd enabled by default
 	NoStatsd = false
 
+	// CollectorsStrict is false by default, unknown builtin collectors are ignored
+	CollectorsStrict = false
+
+	// ConfigDir is empty by default, no drop-in configuration files are merged
+	ConfigDir = ""
+
 	// Debug is false by default
 	Debug = false
 
+	// DebugPprof is false by default, profiling data is sensitive
+	DebugPprof = false
+
+	// DebugPprofListen is the default pprof server address (loopback only)
+	DebugPprofListen = "127.0.0.1:6060"
+
 	// LogLevel set to info by default
 	LogLevel = "info"
 
@@ -51,6 +69,64 @@ const (
 	// ReverseMaxConnRetry - how many times to retry persistently failing broker connection
 	ReverseMaxConnRetry = 10
 
+	// ReverseCommandTimeout - maximum time to service a broker command
+	ReverseCommandTimeout = "50s"
+
+	// ReverseCompression - compression of metrics sent to the broker, empty disables
+	ReverseCompression = ""
+
+	// ReverseConnectJitter - maximum random delay before connecting to the broker, empty disables
+	ReverseConnectJitter = ""
+
+	// ReverseLatencyInterval - how often to measure broker round-trip latency, empty disables
+	ReverseLatencyInterval = ""
+
+	// ReverseMaxFrameSize - maximum frame payload size accepted from the broker
+	// (max unsigned short - 6 for the frame header)
+	ReverseMaxFrameSize = 65529
+
+	// StatsdAggregationWindow defines how long counter and gauge updates are
+	// aggregated before being applied, empty disables aggregation
+	StatsdAggregationWindow = ""
+
+	// StatsdCounterMode defines how host counters are reported, count, rate
+	// (per second over the flush interval) or both
+	StatsdCounterMode = "count"
+
+	// StatsdRateLimit defines the packets per second accepted from a single
+	// source (0 disables rate limiting)
+	StatsdRateLimit = 0
+
+	// StatsdRateBurst defines the maximum burst of packets accepted from a
+	// single source (0 uses the rate limit)
+	StatsdRateBurst = 0
+
+	// StatsdQueueSize defines how many received packets may be queued for
+	// processing, the listener stops reading while the queue is full
+	StatsdQueueSize = 1000
+
+	// StatsdGaugeTTL defines how long a gauge which is not updated continues
+	// to be reported (empty or 0 disables expiry, gauges are reported indefinitely)
+	StatsdGaugeTTL = ""
+
+	// StatsdInvalidChars defines how metric names and set values containing
+	// invalid characters are handled, sanitize (replace with '_') or reject
+	StatsdInvalidChars = "sanitize"
+
+	// StatsdSetDelimiter defines the string joining set names and members
+	StatsdSetDelimiter = MetricNameSeparator
+
+	// StatsdSetMaxLength defines the maximum length of a set member, longer
+	// members are truncated with a hash of the full member appended (0 disables)
+	StatsdSetMaxLength = 256
+
+	// StatsdStateMaxAge defines how old saved statsd state may be and still be restored
+	StatsdStateMaxAge = "5m"
+
+	// StatsdZeroCounter defines how a counter with a value of 0 is handled,
+	// zero (record 0), drop (ignore) or one (record 1)
+	StatsdZeroCounter = "zero"
+
 	// StatsdPort to listen, NOTE address is always localhost
 	StatsdPort = "8125"
 
@@ -61,6 +137,9 @@ const (
 	// StatsdHostCategory defines the "plugin" in which the host metrics will be namepspaced
 	StatsdHostCategory = "statsd"
 
+	// StatsdHostTag defines the tag identifying the originating host of a metric (disabled)
+	StatsdHostTag = ""
+
 	// StatsdGroupPrefix defines that metrics received through StatsD inteface
 	// which are prefixed with this string plus a period go to the group check, if enabled
 	StatsdGroupPrefix = "group."
@@ -74,21 +153,75 @@ const (
 	// StatsdGroupSets defines how group counter metrics will be handled (average or sum)
 	StatsdGroupSets = "sum"
 
+	// StatsdGroupTag defines the tag identifying group metrics when routing by tag
+	StatsdGroupTag = "scope:group"
+
+	// StatsdRouting defines how metrics are routed to the host or group check (by prefix)
+	StatsdRouting = "prefix"
+
+	// StatsdGroupInterval defines how often group metrics are submitted to the group check
+	StatsdGroupInterval = "10s"
+
 	// MetricNameSeparator defines character used to delimit metric name parts
 	MetricNameSeparator = "`"
 
+	// PIDFile defines the agent pid file (and single instance lock), empty disables
+	PIDFile = ""
+
+	// PluginCollisions defines how metric name collisions between plugins are handled
+	PluginCollisions = "drop"
+
 	// PluginTTLUnits defines the default TTL units for plugins with TTLs
 	// e.g. plugin_ttl30s.sh (30s ttl) plugin_ttl45.sh (would get default ttl units, e.g. 45s)
 	PluginTTLUnits = "s" // seconds
 
+	// PluginSlowThreshold defines the last run duration at which a plugin is
+	// run in the background, "" disables
+	PluginSlowThreshold = ""
+
+	// PluginTimeout defines the default maximum plugin execution time
+	// "0" disables, long running plugins (which stream output) should not have a timeout
+	PluginTimeout = "0"
+
+	// PluginWatch defines whether the plugin directory is watched for changes
+	PluginWatch = false
+
+	// PluginWorkers defines the default number of plugins executed concurrently
+	// 0 = number of CPUs
+	PluginWorkers = 0
+
 	// DisableGzip disables gzip compression on responses
 	DisableGzip = false
 
+	// ServerShutdownTimeout how long to wait for in-flight requests when stopping the server(s)
+	ServerShutdownTimeout = "30s"
+
+	// ServerDisableWrite rejects metrics pushed to the http listener(s)
+	ServerDisableWrite = false
+
+	// ServerWriteStrict rejects /write requests containing invalid metrics
+	ServerWriteStrict = false
+
+	// CheckAnnotations toggles posting agent lifecycle annotations
+	CheckAnnotations = false
+
+	// CheckAnnotationCategory defines the category of agent lifecycle annotations
+	CheckAnnotationCategory = "circonus-agent"
+
 	// CheckEnableNewMetrics toggles enabling new metrics
 	CheckEnableNewMetrics = false
 	// CheckMetricRefreshTTL determines how often to refresh check bundle metrics from API
 	CheckMetricRefreshTTL = "5m"
 
+	// NoCheckProbe the startup broker connectivity probe is enabled by default
+	NoCheckProbe = false
+
+	// CheckSpoolMaxAge defines how long a failed submission is retried
+	CheckSpoolMaxAge = "24h"
+
+	// CheckSpoolMaxSize defines the maximum size of the submission spool
+	CheckSpoolMaxSize = "100MiB"
+
 	// CheckCreate toggles creating a check if a check bundle id is not supplied
 	CheckCreate = false
 
@@ -101,6 +234,13 @@ const (
 
 	// CheckTags to use if creating a check (comma separated list)
 	CheckTags = ""
+
+	// CheckSearchTags to find the check by (comma separated list), empty searches by target
+	CheckSearchTags = ""
+
+	// CheckSearchSelect defines how a check is selected when more than one
+	// matches the search tags, none (fail), oldest or newest
+	CheckSearchSelect = "none"
 )
 
 var (
@@ -193,6 +333,13 @@ func init() {
 			"loadavg",
 			"vm",
 		}
+	case "darwin":
+		Collectors = []string{
+			"cpu",
+			"disk",
+			"loadavg",
+			"vm",
+		}
 	case "windows":
 		Collectors = []string{
 			"cache",
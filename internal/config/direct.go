@@ -0,0 +1,42 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// minDirectInterval is the shortest direct submission interval accepted
+const minDirectInterval = time.Second
+
+// validateDirectOptions verifies the direct submission settings
+func validateDirectOptions() error {
+	if viper.GetBool(KeyReverse) {
+		return errors.New("use --direct OR --reverse, they are mutually exclusive")
+	}
+
+	if viper.GetBool(KeyOneshot) {
+		return errors.New("use --direct OR --oneshot, they are mutually exclusive")
+	}
+
+	if viper.GetString(KeyCheckBundleID) == "" {
+		return errors.New("--direct requires --check-id (an httptrap check)")
+	}
+
+	interval := viper.GetString(KeyDirectInterval)
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid direct interval (%s)", interval)
+	}
+	if d < minDirectInterval {
+		return errors.Errorf("Invalid direct interval (%s), must be at least %s", interval, minDirectInterval)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateDirectOptions(t *testing.T) {
+	t.Log("Testing validateDirectOptions")
+
+	tests := []struct {
+		desc        string
+		reverse     bool
+		oneshot     bool
+		cid         string
+		interval    string
+		expectedErr string
+	}{
+		{"reverse", true, false, "123", "60s", "use --direct OR --reverse, they are mutually exclusive"},
+		{"oneshot", false, true, "123", "60s", "use --direct OR --oneshot, they are mutually exclusive"},
+		{"no check id", false, false, "", "60s", "--direct requires --check-id (an httptrap check)"},
+		{"invalid interval", false, false, "123", "foo", `Invalid direct interval (foo): time: invalid duration "foo"`},
+		{"interval too short", false, false, "123", "10ms", "Invalid direct interval (10ms), must be at least 1s"},
+		{"valid", false, false, "123", "60s", ""},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s", test.desc)
+		viper.Reset()
+		viper.Set(KeyDirect, true)
+		viper.Set(KeyReverse, test.reverse)
+		viper.Set(KeyOneshot, test.oneshot)
+		viper.Set(KeyCheckBundleID, test.cid)
+		viper.Set(KeyDirectInterval, test.interval)
+		err := validateDirectOptions()
+		if test.expectedErr == "" {
+			if err != nil {
+				t.Fatalf("Expected NO error, got (%s)", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != test.expectedErr {
+			t.Fatalf("Expected (%s) got (%s)", test.expectedErr, err)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,139 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+var (
+	// envRefRx matches an escaped dollar ($$), a braced reference (${VAR},
+	// ${VAR:-default}) or a bare reference ($VAR)
+	envRefRx  = regexp.MustCompile(`\$\$|\$\{([^}]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+	envNameRx = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ReadConfig reads the main configuration file located by viper, expanding
+// environment variable references in the file's contents before it is parsed,
+// then merges the files in the drop-in directory (if any, see mergeConfigDir).
+// Returns the viper error unchanged if no configuration file could be found
+// (drop-in files are still merged).
+func ReadConfig() error {
+	err := readMainConfig()
+	if err != nil {
+		if _, notFound := err.(viper.ConfigFileNotFoundError); !notFound {
+			return err
+		}
+	}
+
+	if mergeErr := mergeConfigDir(); mergeErr != nil {
+		return mergeErr
+	}
+
+	return err
+}
+
+// readMainConfig reads the main configuration file, expanding environment
+// variable references
+func readMainConfig() error {
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+
+	file := viper.ConfigFileUsed()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return errors.Wrapf(err, "reading config file %s", file)
+	}
+
+	expanded, err := ExpandEnv(data)
+	if err != nil {
+		return errors.Wrapf(err, "config file %s", file)
+	}
+	if bytes.Equal(data, expanded) {
+		return nil
+	}
+
+	return viper.ReadConfig(bytes.NewReader(expanded))
+}
+
+// ExpandEnv replaces environment variable references in configuration data
+// with values from the process environment. Supported forms:
+//
+//	$VAR, ${VAR}     - value of VAR, an error if VAR is not set
+//	${VAR:-default}  - value of VAR, or default if VAR is unset or empty
+//	$$               - a literal $
+//
+// Lines which are comments (first non-blank character is '#') are not expanded.
+// Values are substituted as-is, quoting in the configuration file is the
+// responsibility of the author (e.g. token: "${CIRCONUS_API_TOKEN}").
+func ExpandEnv(data []byte) ([]byte, error) {
+	var (
+		missing = map[string]bool{}
+		invalid []string
+	)
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("#")) {
+			continue
+		}
+		lines[i] = envRefRx.ReplaceAllFunc(line, func(ref []byte) []byte {
+			if string(ref) == "$$" {
+				return []byte("$")
+			}
+
+			name := strings.TrimPrefix(string(ref), "$")
+			def := ""
+			hasDefault := false
+			if strings.HasPrefix(name, "{") {
+				name = strings.TrimSuffix(strings.TrimPrefix(name, "{"), "}")
+				if idx := strings.Index(name, ":-"); idx != -1 {
+					def = name[idx+2:]
+					name = name[:idx]
+					hasDefault = true
+				}
+			}
+
+			if !envNameRx.MatchString(name) {
+				invalid = append(invalid, string(ref))
+				return ref
+			}
+
+			val, ok := os.LookupEnv(name)
+			if hasDefault && val == "" {
+				return []byte(def)
+			}
+			if !ok {
+				missing[name] = true
+				return ref
+			}
+			return []byte(val)
+		})
+	}
+
+	if len(invalid) > 0 {
+		return nil, errors.Errorf("invalid environment variable reference(s): %s", strings.Join(invalid, ", "))
+	}
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, errors.Errorf("environment variable(s) not set: %s", strings.Join(names, ", "))
+	}
+
+	return bytes.Join(lines, nil), nil
+}
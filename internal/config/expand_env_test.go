@@ -0,0 +1,110 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Log("Testing ExpandEnv")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	os.Setenv("CA_TEST_SET", "foo")
+	os.Setenv("CA_TEST_EMPTY", "")
+	defer os.Unsetenv("CA_TEST_SET")
+	defer os.Unsetenv("CA_TEST_EMPTY")
+	os.Unsetenv("CA_TEST_UNSET")
+
+	tt := []struct {
+		name        string
+		data        string
+		expect      string
+		expectError bool
+	}{
+		{"no refs", "key: value\n", "key: value\n", false},
+		{"bare", "key: $CA_TEST_SET\n", "key: foo\n", false},
+		{"braced", "key: ${CA_TEST_SET}bar\n", "key: foobar\n", false},
+		{"empty", "key: \"${CA_TEST_EMPTY}\"\n", "key: \"\"\n", false},
+		{"default (unset)", "key: ${CA_TEST_UNSET:-baz}\n", "key: baz\n", false},
+		{"default (empty)", "key: ${CA_TEST_EMPTY:-baz}\n", "key: baz\n", false},
+		{"default (set)", "key: ${CA_TEST_SET:-baz}\n", "key: foo\n", false},
+		{"escaped", "key: $$CA_TEST_SET\n", "key: $CA_TEST_SET\n", false},
+		{"lone dollar", "key: 5$\n", "key: 5$\n", false},
+		{"comment", "# see $CA_TEST_UNSET\nkey: 1\n", "# see $CA_TEST_UNSET\nkey: 1\n", false},
+		{"unset", "key: ${CA_TEST_UNSET}\n", "", true},
+		{"unset bare", "key: $CA_TEST_UNSET\n", "", true},
+		{"invalid name", "key: ${1BAD}\n", "", true},
+	}
+
+	for _, tst := range tt {
+		t.Logf("\t%s", tst.name)
+		data, err := ExpandEnv([]byte(tst.data))
+		if tst.expectError {
+			if err == nil {
+				t.Fatalf("expected error for (%s)", tst.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if string(data) != tst.expect {
+			t.Fatalf("expected (%q) got (%q)", tst.expect, string(data))
+		}
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	t.Log("Testing ReadConfig")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("no config file")
+	{
+		viper.Reset()
+		viper.AddConfigPath(filepath.Join("testdata", "not_a_dir"))
+		viper.SetConfigName("missing")
+		if err := ReadConfig(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("unset variable")
+	{
+		viper.Reset()
+		os.Unsetenv("CA_TEST_API_TOKEN")
+		viper.SetConfigFile(filepath.Join("testdata", "expand_env.yaml"))
+		if err := ReadConfig(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("valid")
+	{
+		viper.Reset()
+		os.Setenv("CA_TEST_API_TOKEN", "abc123")
+		defer os.Unsetenv("CA_TEST_API_TOKEN")
+		viper.SetConfigFile(filepath.Join("testdata", "expand_env.yaml"))
+		if err := ReadConfig(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if key := viper.GetString(KeyAPITokenKey); key != "abc123" {
+			t.Fatalf("expected (abc123) got (%s)", key)
+		}
+		if app := viper.GetString(KeyAPITokenApp); app != "circonus-agent" {
+			t.Fatalf("expected (circonus-agent) got (%s)", app)
+		}
+	}
+
+	viper.Reset()
+}
@@ -65,3 +65,77 @@ func LoadConfigFile(base string, target interface{}) error {
 
 	return nil
 }
+
+// LoadConfigSections will attempt to load a json|toml|yaml configuration file
+// with a section per name (e.g. a section per builtin collector). `base` is
+// the full path and base name of the configuration file, the first of
+// '<base>.json', '<base>.toml', and '<base>.yaml' found is loaded. The
+// sections are returned as json, to be loaded with LoadConfigSection. No
+// sections (nil) are returned if there is no configuration file.
+func LoadConfigSections(base string) (map[string][]byte, error) {
+	if base == "" {
+		return nil, errors.Errorf("invalid config file (empty)")
+	}
+
+	for _, ext := range []string{".json", ".toml", ".yaml"} {
+		cfg := base + ext
+		if _, err := os.Stat(cfg); os.IsNotExist(err) {
+			continue
+		}
+
+		settings, err := readConfigSettings(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		sections := make(map[string][]byte, len(settings))
+		for name, v := range settings {
+			if _, ok := v.(map[string]interface{}); !ok {
+				return nil, errors.Errorf("parsing configuration file (%s), section (%s) is not a map", cfg, name)
+			}
+			data, err := json.Marshal(jsonSettings(v))
+			if err != nil {
+				return nil, errors.Wrapf(err, "parsing configuration file (%s), section (%s)", cfg, name)
+			}
+			sections[name] = data
+		}
+		return sections, nil
+	}
+
+	return nil, nil
+}
+
+// LoadConfigSection loads a section returned by LoadConfigSections in to target
+func LoadConfigSection(section []byte, target interface{}) error {
+	if err := json.Unmarshal(section, target); err != nil {
+		return errors.Wrap(err, "parsing configuration section")
+	}
+	return nil
+}
+
+// jsonSettings converts the maps in parsed (yaml) settings to maps with
+// string keys, which can be encoded as json
+func jsonSettings(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, sv := range t {
+			m[k] = jsonSettings(sv)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, sv := range t {
+			m[fmt.Sprintf("%v", k)] = jsonSettings(sv)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, sv := range t {
+			l[i] = jsonSettings(sv)
+		}
+		return l
+	default:
+		return v
+	}
+}
@@ -41,3 +41,57 @@ func TestLoadConfigFile(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadConfigSections(t *testing.T) {
+	t.Log("Testing LoadConfigSections")
+
+	tt := []struct {
+		name        string
+		base        string
+		expectError bool
+		sections    int
+	}{
+		{"JSON", "testdata/test_sections_json", false, 2},
+		{"TOML", "testdata/test_sections_toml", false, 2},
+		{"YAML", "testdata/test_sections_yaml", false, 2},
+		{"empty", "", true, 0},
+		{"missing", "testdata/test_sections_missing", false, 0},
+		{"section not a map", "testdata/test_sections_invalid", true, 0},
+		{"syntax error", "testdata/test_cfg_yaml_error", true, 0},
+	}
+
+	for _, tst := range tt {
+		t.Logf("\t%s", tst.name)
+		sections, err := LoadConfigSections(tst.base)
+		if tst.expectError {
+			if err == nil {
+				t.Fatalf("expected error for %s", tst.base)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got (%s), loading (%s)", err, tst.base)
+		}
+		if len(sections) != tst.sections {
+			t.Fatalf("expected %d sections, got %d", tst.sections, len(sections))
+		}
+		if tst.sections == 0 {
+			continue
+		}
+
+		var c struct {
+			ID      string   `json:"id"`
+			AllCPU  string   `json:"report_all_cpus"`
+			Enabled []string `json:"metrics_enabled"`
+		}
+		if err := LoadConfigSection(sections["cpu"], &c); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.ID != "cpu" || c.AllCPU != "true" {
+			t.Fatalf("unexpected cpu section %#v", c)
+		}
+		if tst.name == "YAML" && len(c.Enabled) != 2 {
+			t.Fatalf("expected 2 metrics enabled, got %#v", c)
+		}
+	}
+}
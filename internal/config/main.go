@@ -6,6 +6,7 @@
 package config
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"expvar"
 	"fmt"
@@ -20,38 +21,84 @@ import (
 
 // Validate verifies the required portions of the configuration
 func Validate() error {
+	if errs := ValidateAll(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateAll runs every configuration check, returning all of
+// the problems found rather than stopping at the first
+func ValidateAll() []error {
+	var errs []error
 
 	if apiRequired() {
 		err := validateAPIOptions()
 		if err != nil {
-			return errors.Wrap(err, "API config")
+			errs = append(errs, errors.Wrap(err, "API config"))
 		}
 	}
 
 	if viper.GetBool(KeyReverse) {
 		err := validateReverseOptions()
 		if err != nil {
-			return errors.Wrap(err, "reverse config")
+			errs = append(errs, errors.Wrap(err, "reverse config"))
 		}
 	}
 
+	if err := validateServerAuthOptions(); err != nil {
+		errs = append(errs, errors.Wrap(err, "server auth config"))
+	}
+
+	if err := validateServerCORSOptions(); err != nil {
+		errs = append(errs, errors.Wrap(err, "server cors config"))
+	}
+
 	if viper.GetString(KeyCheckBundleID) != "" && viper.GetBool(KeyCheckCreate) {
-		return errors.New("use --check-create OR --check-id, they are mutually exclusive")
+		errs = append(errs, errors.New("use --check-create OR --check-id, they are mutually exclusive"))
 	}
 
-	return nil
+	if viper.GetString(KeyCheckBundleID) != "" && viper.GetString(KeyCheckSearchTags) != "" {
+		errs = append(errs, errors.New("use --check-search-tags OR --check-id, they are mutually exclusive"))
+	}
+
+	if viper.GetString(KeyCheckSecondaryBundleID) != "" {
+		if err := validateSecondaryOptions(); err != nil {
+			errs = append(errs, errors.Wrap(err, "secondary check config"))
+		}
+	}
+
+	if viper.GetString(KeyCheckSpoolDir) != "" {
+		if err := validateSpoolOptions(); err != nil {
+			errs = append(errs, errors.Wrap(err, "spool config"))
+		}
+	}
+
+	if viper.GetBool(KeyDirect) {
+		if err := validateDirectOptions(); err != nil {
+			errs = append(errs, errors.Wrap(err, "direct config"))
+		}
+	}
+
+	if viper.GetBool(KeyOneshot) {
+		if viper.GetBool(KeyReverse) {
+			errs = append(errs, errors.New("use --oneshot OR --reverse, they are mutually exclusive"))
+		}
+		if viper.GetString(KeyCheckBundleID) == "" {
+			errs = append(errs, errors.New("--oneshot requires --check-id (an httptrap check)"))
+		}
+	}
+
+	return errs
 }
 
 // StatConfig adds the running config to the app stats
 func StatConfig() error {
-	cfg, err := getConfig()
+	cfg, err := RedactedConfig()
 	if err != nil {
 		return err
 	}
 
-	cfg.API.Key = "..."
-	cfg.API.App = "..."
-
 	expvar.Publish("config", expvar.Func(func() interface{} {
 		return &cfg
 	}))
@@ -59,6 +106,56 @@ func StatConfig() error {
 	return nil
 }
 
+// RedactedConfig returns the current configuration with secrets masked
+func RedactedConfig() (*Config, error) {
+	cfg, err := getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.API.Key = redacted
+	cfg.API.App = redacted
+	if cfg.Check.Secondary.APIKey != "" {
+		cfg.Check.Secondary.APIKey = redacted
+	}
+	if cfg.Server.AuthToken != "" {
+		cfg.Server.AuthToken = redacted
+	}
+	if cfg.Server.AuthPassword != "" {
+		cfg.Server.AuthPassword = redacted
+	}
+	for name, src := range cfg.PluginHTTP {
+		if len(src.Headers) == 0 {
+			continue
+		}
+		headers := make(map[string]string, len(src.Headers))
+		for h := range src.Headers {
+			headers[h] = redacted
+		}
+		src.Headers = headers
+		cfg.PluginHTTP[name] = src
+	}
+
+	return cfg, nil
+}
+
+// Hash returns a sha256 hash (hex) of the effective configuration, for
+// detecting configuration drift. Secrets are masked before hashing (see
+// RedactedConfig), changing only a secret does not change the hash.
+func Hash() (string, error) {
+	cfg, err := RedactedConfig()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding config")
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
 // getConfig dumps the current configuration and returns it
 func getConfig() (*Config, error) {
 	var cfg *Config
@@ -67,10 +164,14 @@ func getConfig() (*Config, error) {
 		return nil, errors.Wrap(err, "parsing config")
 	}
 
+	// collectors may be a list or a map of toggles, see Config
+	cfg.Collectors = EnabledCollectors()
+
 	return cfg, nil
 }
 
-// ShowConfig prints the running configuration
+// ShowConfig prints the running configuration, or with the "sources" format
+// each setting with its effective value and source (see SettingSources)
 func ShowConfig(w io.Writer) error {
 	var cfg *Config
 	var err error
@@ -86,6 +187,8 @@ func ShowConfig(w io.Writer) error {
 	log.Debug().Str("format", format).Msg("show-config")
 
 	switch format {
+	case "sources":
+		return showSources(w)
 	case "json":
 		data, err = json.MarshalIndent(cfg, " ", "  ")
 		if err != nil {
@@ -6,9 +6,12 @@
 package config
 
 import (
+	"bytes"
 	"io/ioutil"
+	"strings"
 	"testing"
 
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 )
@@ -29,6 +32,9 @@ func TestValidate(t *testing.T) {
 	t.Log("reverse")
 	{
 		viper.Set(KeyReverse, true)
+		viper.Set(KeyAPITokenKey, "foo")
+		viper.Set(KeyAPITokenApp, "bar")
+		viper.Set(KeyAPIURL, defaults.APIURL)
 		err := Validate()
 		if err != nil {
 			t.Fatalf("Expected NO error, got (%s)", err)
@@ -36,6 +42,44 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateAll(t *testing.T) {
+	t.Log("Testing ValidateAll")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(KeyReverse, true)
+	viper.Set(KeyCheckBundleID, "123")
+	viper.Set(KeyCheckCreate, true)
+	viper.Set(KeyServerAuthPassword, "foo")
+
+	errs := ValidateAll()
+	if len(errs) != 3 { // API, server auth, check-create/check-id
+		t.Fatalf("expected 3 errors, got %d (%v)", len(errs), errs)
+	}
+
+	if err := Validate(); err == nil || err.Error() != errs[0].Error() {
+		t.Fatalf("expected first error (%s), got (%v)", errs[0], err)
+	}
+
+	t.Log("\toneshot")
+	{
+		viper.Reset()
+		viper.Set(KeyOneshot, true)
+		viper.Set(KeyReverse, true)
+		viper.Set(KeyAPITokenKey, "foo")
+		viper.Set(KeyAPITokenApp, "bar")
+		viper.Set(KeyAPIURL, "http://127.0.0.1/v2")
+
+		errs := ValidateAll()
+		if len(errs) != 2 { // oneshot/reverse, oneshot w/o check-id
+			t.Fatalf("expected 2 errors, got %d (%v)", len(errs), errs)
+		}
+	}
+
+	viper.Reset()
+}
+
 func TestShowConfig(t *testing.T) {
 	t.Log("Testing ShowConfig")
 	zerolog.SetGlobalLevel(zerolog.Disabled)
@@ -66,6 +110,20 @@ func TestShowConfig(t *testing.T) {
 			t.Fatalf("expected no error, got %s", err)
 		}
 	}
+
+	t.Log("TOML, collectors map")
+	{
+		viper.Set(KeyCollectors, map[string]interface{}{"foo": true})
+		viper.Set(KeyShowConfig, "toml")
+		var buf bytes.Buffer
+		if err := ShowConfig(&buf); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if !strings.Contains(buf.String(), `"foo"`) {
+			t.Fatalf("expected effective collectors list, got (%s)", buf.String())
+		}
+		viper.Set(KeyCollectors, nil)
+	}
 }
 
 func TestGetConfig(t *testing.T) {
@@ -90,3 +148,36 @@ func TestStatConfig(t *testing.T) {
 		t.Fatalf("expected no error, got %s", err)
 	}
 }
+
+func TestHash(t *testing.T) {
+	t.Log("Testing Hash")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+
+	h1, err := Hash()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	h2, err := Hash()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if h1 != h2 {
+		t.Fatalf("expected same hash, got (%s) (%s)", h1, h2)
+	}
+
+	t.Log("\tsecret change -> same hash")
+	viper.Set(KeyAPITokenKey, "foo")
+	if h, _ := Hash(); h != h1 {
+		t.Fatalf("expected same hash, got (%s)", h)
+	}
+
+	t.Log("\tsetting change -> new hash")
+	viper.Set(KeyPluginDir, "/tmp")
+	if h, _ := Hash(); h == h1 {
+		t.Fatalf("expected different hash, got (%s)", h)
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,147 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// redacted replaces secrets in log output and the redacted configuration
+const redacted = "..."
+
+// minSecretLen values of sensitive settings shorter than this are not
+// masked in free text, they would mask unrelated text
+const minSecretLen = 4
+
+// sensitiveKeys are the settings whose values are secrets, the current
+// value of each is masked wherever it appears in log output
+var sensitiveKeys = []string{
+	KeyAPITokenKey,
+	KeyCheckSecondaryAPIKey,
+	KeyServerAuthPassword,
+	KeyServerAuthToken,
+}
+
+var (
+	// urlRx matches urls in free text (e.g. error messages)
+	urlRx = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+._-]*://[^\s"'<>]+`)
+	// sensitiveParamRx matches url query parameters likely to hold credentials
+	sensitiveParamRx = regexp.MustCompile(`(?i)(key|token|secret|pass|auth)`)
+)
+
+// Redact masks secrets in a string destined for log output: the values of
+// the sensitive settings and credentials embedded in urls (see RedactURL)
+func Redact(s string) string {
+	for _, key := range sensitiveKeys {
+		v := viper.GetString(key)
+		if len(v) < minSecretLen || strings.ToLower(v) == cosiName {
+			continue
+		}
+		s = strings.Replace(s, v, redacted, -1)
+	}
+
+	return urlRx.ReplaceAllStringFunc(s, RedactURL)
+}
+
+// RedactURL masks the credentials embedded in a url: the password in the
+// user info, the fragment (e.g. the reverse secret), query parameters named
+// like credentials and the secret in httptrap submission urls
+// (.../module/httptrap/<check uuid>/<secret>). Urls which cannot be parsed
+// are returned unchanged.
+func RedactURL(rawURL string) string {
+	// url.Parse does not understand 'mtev_reverse' (the underscore)
+	scheme := ""
+	if strings.HasPrefix(rawURL, "mtev_reverse:") {
+		scheme = "mtev_reverse"
+	}
+	u, err := url.Parse(strings.Replace(rawURL, "mtev_reverse:", "http:", 1))
+	if err != nil {
+		return rawURL
+	}
+
+	changed := false
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			changed = true
+		}
+	}
+
+	if u.Fragment != "" {
+		u.Fragment = redacted
+		changed = true
+	}
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for name := range q {
+			if sensitiveParamRx.MatchString(name) {
+				q.Set(name, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = q.Encode()
+		}
+	}
+
+	segs := strings.Split(u.Path, "/")
+	for i, seg := range segs {
+		if seg == "httptrap" && i+2 < len(segs) && segs[i+2] != "" {
+			segs[i+2] = redacted
+			u.Path = strings.Join(segs, "/")
+			u.RawPath = ""
+			changed = true
+			break
+		}
+	}
+
+	if !changed {
+		return rawURL
+	}
+	if scheme != "" {
+		u.Scheme = scheme
+	}
+
+	return u.String()
+}
+
+// RedactError returns err with secrets masked in its message (see Redact),
+// err is returned unchanged if it does not contain any
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if r := Redact(msg); r != msg {
+		return errors.New(r)
+	}
+	return err
+}
+
+// RedactWriter returns a writer masking secrets (see Redact) in everything
+// written to w, for loggers handed to other packages (e.g. the circonus api client)
+func RedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+	if _, err := rw.w.Write([]byte(Redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
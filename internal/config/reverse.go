@@ -6,8 +6,11 @@
 package config
 
 import (
+	"crypto/tls"
 	"strings"
+	"time"
 
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -42,6 +45,60 @@ func validateReverseOptions() error {
 		log.Debug().Str("cid", cid).Msg("reverse, specified cid")
 	}
 
+	// the client certificate is optional, but requires both cert and key
+	certFile := viper.GetString(KeyReverseClientCertFile)
+	keyFile := viper.GetString(KeyReverseClientKeyFile)
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("Invalid reverse client certificate, both cert and key files are required")
+	}
+	if certFile != "" {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			return errors.Wrap(err, "Invalid reverse client certificate")
+		}
+	}
+
+	// unset (0) uses the default, which is also the protocol maximum
+	if size := viper.GetInt(KeyReverseMaxFrameSize); size < 0 || size > defaults.ReverseMaxFrameSize {
+		return errors.Errorf("Invalid reverse max frame size (%d), must be between 0 and %d (0 uses the default)", size, defaults.ReverseMaxFrameSize)
+	}
+
+	if jitter := viper.GetString(KeyReverseConnectJitter); jitter != "" {
+		d, err := time.ParseDuration(jitter)
+		if err != nil {
+			return errors.Wrap(err, "Invalid reverse connect jitter")
+		}
+		if d < 0 {
+			return errors.Errorf("Invalid reverse connect jitter (%s), must not be negative", jitter)
+		}
+	}
+
+	// empty uses the default
+	if timeout := viper.GetString(KeyReverseCommandTimeout); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return errors.Wrap(err, "Invalid reverse command timeout")
+		}
+		if d <= 0 {
+			return errors.Errorf("Invalid reverse command timeout (%s), must be greater than 0", timeout)
+		}
+	}
+
+	switch compression := viper.GetString(KeyReverseCompression); compression {
+	case "", "gzip":
+	default:
+		return errors.Errorf("Invalid reverse compression (%s), must be gzip or empty", compression)
+	}
+
+	if interval := viper.GetString(KeyReverseLatencyInterval); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return errors.Wrap(err, "Invalid reverse latency interval")
+		}
+		if d < 0 {
+			return errors.Errorf("Invalid reverse latency interval (%s), must not be negative", interval)
+		}
+	}
+
 	// valid cid or, if cid empty, reverse will search for a cid
 	return nil
 }
@@ -79,4 +79,139 @@ func TestValidateReverseOptions(t *testing.T) {
 			t.Errorf("unexpected error (%s)", err)
 		}
 	}
+
+	t.Log("Reverse, max frame size (invalid, 70000)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseMaxFrameSize, 70000)
+		err := validateReverseOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != "Invalid reverse max frame size (70000), must be between 0 and 65529 (0 uses the default)" {
+			t.Errorf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("Reverse, max frame size (valid, 1024)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseMaxFrameSize, 1024)
+		err := validateReverseOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+		viper.Set(KeyReverseMaxFrameSize, 0)
+	}
+
+	t.Log("Reverse, max frame size (valid, 0 uses the default)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseMaxFrameSize, 0)
+		err := validateReverseOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("Reverse, connect jitter (invalid, -1s)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseConnectJitter, "-1s")
+		err := validateReverseOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != "Invalid reverse connect jitter (-1s), must not be negative" {
+			t.Errorf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("Reverse, connect jitter (valid, 30s)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseConnectJitter, "30s")
+		err := validateReverseOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+		viper.Set(KeyReverseConnectJitter, "")
+	}
+
+	t.Log("Reverse, command timeout (invalid, 0s)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseCommandTimeout, "0s")
+		err := validateReverseOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != "Invalid reverse command timeout (0s), must be greater than 0" {
+			t.Errorf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("Reverse, command timeout (valid, 20s)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseCommandTimeout, "20s")
+		err := validateReverseOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+		viper.Set(KeyReverseCommandTimeout, "")
+	}
+
+	t.Log("Reverse, compression (invalid, zstd)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseCompression, "zstd")
+		err := validateReverseOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != "Invalid reverse compression (zstd), must be gzip or empty" {
+			t.Errorf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("Reverse, compression (valid, gzip)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseCompression, "gzip")
+		err := validateReverseOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+		viper.Set(KeyReverseCompression, "")
+	}
+
+	t.Log("Reverse, client cert (key missing)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseClientCertFile, "testdata/missing.pem")
+		err := validateReverseOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != "Invalid reverse client certificate, both cert and key files are required" {
+			t.Errorf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("Reverse, client cert (missing files)")
+	{
+		viper.Set(KeyCheckBundleID, "123")
+		viper.Set(KeyReverseClientCertFile, "testdata/missing.pem")
+		viper.Set(KeyReverseClientKeyFile, "testdata/missing.key")
+		err := validateReverseOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if !strings.HasPrefix(err.Error(), "Invalid reverse client certificate:") {
+			t.Errorf("unexpected error (%s)", err)
+		}
+		viper.Set(KeyReverseClientCertFile, "")
+		viper.Set(KeyReverseClientKeyFile, "")
+	}
 }
@@ -0,0 +1,57 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"net/url"
+
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// validateSecondaryOptions verifies the secondary (HA) cluster settings
+func validateSecondaryOptions() error {
+	cid := viper.GetString(KeyCheckSecondaryBundleID)
+	ok, err := IsValidCheckID(cid)
+	if err != nil {
+		return errors.Wrap(err, "Secondary Check ID")
+	}
+	if !ok {
+		return errors.Errorf("Invalid Secondary Check ID (%s)", cid)
+	}
+
+	if viper.GetString(KeyCheckSecondaryAPIKey) == "" {
+		return errors.New("secondary API key is required")
+	}
+
+	if viper.GetString(KeyCheckSecondaryAPIApp) == "" {
+		viper.Set(KeyCheckSecondaryAPIApp, defaults.APIApp)
+	}
+
+	apiURL := viper.GetString(KeyCheckSecondaryAPIURL)
+	if apiURL == "" {
+		apiURL = defaults.APIURL
+		viper.Set(KeyCheckSecondaryAPIURL, apiURL)
+	}
+	parsedURL, err := url.Parse(apiURL)
+	if err != nil {
+		return errors.Wrap(err, "Invalid secondary API URL")
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return errors.Errorf("Invalid secondary API URL (%s)", apiURL)
+	}
+
+	if file := viper.GetString(KeyCheckSecondaryAPICAFile); file != "" {
+		f, err := verifyFile(file)
+		if err != nil {
+			return err
+		}
+		viper.Set(KeyCheckSecondaryAPICAFile, f)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateSecondaryOptions(t *testing.T) {
+	t.Log("Testing validateSecondaryOptions")
+
+	t.Log("invalid cid")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSecondaryBundleID, "foo")
+		expectedErr := "Invalid Secondary Check ID (foo)"
+		err := validateSecondaryOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr {
+			t.Fatalf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("no api key")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSecondaryBundleID, "123")
+		expectedErr := "secondary API key is required"
+		err := validateSecondaryOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr {
+			t.Fatalf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("invalid api url")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSecondaryBundleID, "123")
+		viper.Set(KeyCheckSecondaryAPIKey, "foo")
+		viper.Set(KeyCheckSecondaryAPIURL, "foo")
+		expectedErr := "Invalid secondary API URL (foo)"
+		err := validateSecondaryOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr {
+			t.Fatalf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("valid, defaults applied")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSecondaryBundleID, "/check_bundle/123")
+		viper.Set(KeyCheckSecondaryAPIKey, "foo")
+		err := validateSecondaryOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%s)", err)
+		}
+		if viper.GetString(KeyCheckSecondaryAPIURL) == "" {
+			t.Fatal("Expected default secondary API URL")
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,44 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+func validateServerAuthOptions() error {
+	user := viper.GetString(KeyServerAuthUser)
+	pass := viper.GetString(KeyServerAuthPassword)
+
+	if user != "" && pass == "" {
+		return errors.New("auth user specified without auth password")
+	}
+	if user == "" && pass != "" {
+		return errors.New("auth password specified without auth user")
+	}
+
+	return nil
+}
+
+func validateServerCORSOptions() error {
+	for _, origin := range viper.GetStringSlice(KeyServerCORSOrigins) {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return errors.Wrapf(err, "invalid origin (%s)", origin)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return errors.Errorf("invalid origin (%s), expected scheme://host[:port] or '*'", origin)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateServerAuthOptions(t *testing.T) {
+	t.Log("Testing validateServerAuthOptions")
+
+	t.Log("no auth (OK)")
+	{
+		viper.Reset()
+		err := validateServerAuthOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("token only (OK)")
+	{
+		viper.Reset()
+		viper.Set(KeyServerAuthToken, "foo")
+		err := validateServerAuthOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("user and password (OK)")
+	{
+		viper.Reset()
+		viper.Set(KeyServerAuthUser, "foo")
+		viper.Set(KeyServerAuthPassword, "bar")
+		err := validateServerAuthOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("user w/o password")
+	{
+		viper.Reset()
+		viper.Set(KeyServerAuthUser, "foo")
+		expectedErr := errors.New("auth user specified without auth password")
+		err := validateServerAuthOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Fatalf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("password w/o user")
+	{
+		viper.Reset()
+		viper.Set(KeyServerAuthPassword, "bar")
+		expectedErr := errors.New("auth password specified without auth user")
+		err := validateServerAuthOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Fatalf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestValidateServerCORSOptions(t *testing.T) {
+	t.Log("Testing validateServerCORSOptions")
+
+	tests := []struct {
+		desc    string
+		origins []string
+		err     string
+	}{
+		{"not set", nil, ""},
+		{"any", []string{"*"}, ""},
+		{"valid", []string{"http://localhost:8080", "https://dash.example.com/"}, ""},
+		{"no scheme", []string{"localhost:8080"}, "invalid origin (localhost:8080), expected scheme://host[:port] or '*'"},
+		{"path", []string{"http://localhost/dash"}, "invalid origin (http://localhost/dash), expected scheme://host[:port] or '*'"},
+		{"invalid", []string{"http://[::1"}, `invalid origin (http://[::1): parse "http://[::1": missing ']' in host`},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s", test.desc)
+		viper.Reset()
+		if test.origins != nil {
+			viper.Set(KeyServerCORSOrigins, test.origins)
+		}
+		err := validateServerCORSOptions()
+		if test.err == "" {
+			if err != nil {
+				t.Fatalf("Expected NO error, got (%v)", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != test.err {
+			t.Fatalf("Expected (%s) got (%s)", test.err, err)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,238 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// viper does not report where a value came from, the flag and environment
+// variable bound to each setting are recorded so the source can be determined
+var (
+	bindingsmu   sync.Mutex
+	flagBindings = map[string]*pflag.Flag{}
+	envBindings  = map[string][]string{}
+)
+
+// Setting sources, in order of precedence
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// SettingSource is the effective value of a setting and the source which provided it
+type SettingSource struct {
+	Key    string
+	Value  string
+	Source string // flag, env, file or default
+	Origin string // flag name, environment variable or config file
+}
+
+// BindPFlag binds a command line flag to a setting (see viper.BindPFlag)
+func BindPFlag(key string, flag *pflag.Flag) error {
+	if flag == nil {
+		return errors.Errorf("flag for '%s' is nil", key)
+	}
+
+	bindingsmu.Lock()
+	flagBindings[strings.ToLower(key)] = flag
+	bindingsmu.Unlock()
+
+	return viper.BindPFlag(key, flag)
+}
+
+// BindEnv binds environment variables to a setting (see viper.BindEnv)
+func BindEnv(key string, envVars ...string) error {
+	bindingsmu.Lock()
+	k := strings.ToLower(key)
+	envBindings[k] = append(envBindings[k], envVars...)
+	bindingsmu.Unlock()
+
+	return viper.BindEnv(append([]string{key}, envVars...)...)
+}
+
+// SettingSources returns every setting with its effective value and source,
+// sorted by key. Sensitive values and credentials in urls are redacted.
+func SettingSources() ([]SettingSource, error) {
+	files, err := settingFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	bindingsmu.Lock()
+	defer bindingsmu.Unlock()
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+
+	sources := make([]SettingSource, 0, len(keys))
+	for _, key := range keys {
+		s := SettingSource{
+			Key:    key,
+			Value:  settingValue(key),
+			Source: SourceDefault,
+		}
+
+		if flag, ok := flagBindings[key]; ok && flag.Changed {
+			s.Source = SourceFlag
+			s.Origin = "--" + flag.Name
+		} else if env, ok := settingEnv(key); ok {
+			s.Source = SourceEnv
+			s.Origin = env
+		} else {
+			// later (drop-in) files override earlier ones
+			for i := len(files) - 1; i >= 0; i-- {
+				if hasSetting(files[i].settings, key) {
+					s.Source = SourceFile
+					s.Origin = files[i].path
+					break
+				}
+			}
+		}
+
+		sources = append(sources, s)
+	}
+
+	return sources, nil
+}
+
+// showSources writes the setting sources report, one setting per line:
+// key = value (source)
+func showSources(w io.Writer) error {
+	sources, err := SettingSources()
+	if err != nil {
+		return err
+	}
+
+	width := 0
+	for _, s := range sources {
+		if len(s.Key) > width {
+			width = len(s.Key)
+		}
+	}
+
+	fmt.Fprintln(w)
+	for _, s := range sources {
+		src := s.Source
+		if s.Origin != "" {
+			src = fmt.Sprintf("%s %s", s.Source, s.Origin)
+		}
+		fmt.Fprintf(w, "%-*s = %s (%s)\n", width, s.Key, s.Value, src)
+	}
+	fmt.Fprintln(w)
+
+	return nil
+}
+
+// settingEnv returns the environment variable providing a setting, the
+// bound variables are checked first then the automatic (upper case key) variable
+func settingEnv(key string) (string, bool) {
+	for _, env := range envBindings[key] {
+		if _, ok := os.LookupEnv(env); ok {
+			return env, true
+		}
+	}
+	env := strings.ToUpper(key)
+	if _, ok := os.LookupEnv(env); ok {
+		return env, true
+	}
+	return "", false
+}
+
+// settingValue returns the effective value of a setting formatted for
+// display, sensitive values, http plugin header values (e.g. authorization)
+// and credentials in urls are redacted
+func settingValue(key string) string {
+	v := fmt.Sprintf("%v", viper.Get(key))
+	for _, sk := range sensitiveKeys {
+		if key == sk && v != "" && strings.ToLower(v) != cosiName {
+			return redacted
+		}
+	}
+	if isPluginHTTPHeader(key) && v != "" {
+		return redacted
+	}
+	return urlRx.ReplaceAllStringFunc(v, RedactURL)
+}
+
+// isPluginHTTPHeader reports whether a key is an http plugin's headers
+// (plugin_http.<name>.headers) or one of its header values
+func isPluginHTTPHeader(key string) bool {
+	parts := strings.Split(key, ".")
+	return len(parts) >= 3 && parts[0] == KeyPluginHTTP && parts[2] == "headers"
+}
+
+type settingFile struct {
+	path     string
+	settings map[string]interface{}
+}
+
+// settingFiles returns the settings in the main configuration file and the
+// drop-in configuration files, in the order they are merged
+func settingFiles() ([]settingFile, error) {
+	paths := []string{}
+	if file := viper.ConfigFileUsed(); file != "" {
+		if _, err := os.Stat(file); err == nil {
+			paths = append(paths, file)
+		}
+	}
+	if dir := viper.GetString(KeyConfigDir); dir != "" {
+		dropins, err := configDirFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, dropins...)
+	}
+
+	files := make([]settingFile, 0, len(paths))
+	for _, path := range paths {
+		s, err := readConfigSettings(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, settingFile{path: path, settings: s})
+	}
+
+	return files, nil
+}
+
+// hasSetting reports whether a (dotted, nested) key is present in settings
+func hasSetting(settings map[string]interface{}, key string) bool {
+	parts := strings.Split(key, ".")
+	m := settings
+	for i, part := range parts {
+		v, ok := m[part]
+		if !ok {
+			return false
+		}
+		if i == len(parts)-1 {
+			return true
+		}
+		switch sub := v.(type) {
+		case map[string]interface{}:
+			m = sub
+		case map[interface{}]interface{}:
+			m = make(map[string]interface{}, len(sub))
+			for k, sv := range sub {
+				m[strings.ToLower(fmt.Sprintf("%v", k))] = sv
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// validateSpoolOptions verifies the submission spool settings
+func validateSpoolOptions() error {
+	dir, err := filepath.Abs(viper.GetString(KeyCheckSpoolDir))
+	if err != nil {
+		return errors.Wrap(err, "spool dir")
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return errors.Wrap(err, "spool dir")
+	}
+	if !fi.IsDir() {
+		return errors.Errorf("spool dir (%s) not a directory", dir)
+	}
+	viper.Set(KeyCheckSpoolDir, dir)
+
+	maxSize := viper.GetString(KeyCheckSpoolMaxSize)
+	if size, err := units.ParseBase2Bytes(maxSize); err != nil {
+		return errors.Wrapf(err, "Invalid spool max size (%s)", maxSize)
+	} else if size <= 0 {
+		return errors.Errorf("Invalid spool max size (%s), must be greater than 0", maxSize)
+	}
+
+	maxAge := viper.GetString(KeyCheckSpoolMaxAge)
+	if d, err := time.ParseDuration(maxAge); err != nil {
+		return errors.Wrapf(err, "Invalid spool max age (%s)", maxAge)
+	} else if d <= 0 {
+		return errors.Errorf("Invalid spool max age (%s), must be greater than 0", maxAge)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateSpoolOptions(t *testing.T) {
+	t.Log("Testing validateSpoolOptions")
+
+	t.Log("missing dir")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSpoolDir, filepath.Join("testdata", "missing"))
+		if err := validateSpoolOptions(); err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+
+	t.Log("not a dir")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSpoolDir, filepath.Join("testdata", "cosiv2.json"))
+		if err := validateSpoolOptions(); err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+
+	t.Log("invalid max size")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSpoolDir, "testdata")
+		viper.Set(KeyCheckSpoolMaxSize, "foo")
+		viper.Set(KeyCheckSpoolMaxAge, "1h")
+		if err := validateSpoolOptions(); err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+
+	t.Log("invalid max age")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSpoolDir, "testdata")
+		viper.Set(KeyCheckSpoolMaxSize, "10MiB")
+		viper.Set(KeyCheckSpoolMaxAge, "-1h")
+		expectedErr := "Invalid spool max age (-1h), must be greater than 0"
+		err := validateSpoolOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr {
+			t.Fatalf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("valid")
+	{
+		viper.Reset()
+		viper.Set(KeyCheckSpoolDir, "testdata")
+		viper.Set(KeyCheckSpoolMaxSize, "10MiB")
+		viper.Set(KeyCheckSpoolMaxAge, "1h")
+		if err := validateSpoolOptions(); err != nil {
+			t.Fatalf("Expected NO error, got (%s)", err)
+		}
+		if !filepath.IsAbs(viper.GetString(KeyCheckSpoolDir)) {
+			t.Fatalf("Expected absolute path, got (%s)", viper.GetString(KeyCheckSpoolDir))
+		}
+	}
+
+	viper.Reset()
+}
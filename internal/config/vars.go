@@ -25,6 +25,12 @@ type API struct {
 	URL    string `json:"url" yaml:"url" toml:"url"`
 }
 
+// Direct defines the running config.direct structure
+type Direct struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled" toml:"enabled"`
+	Interval string `json:"interval" yaml:"interval" toml:"interval"`
+}
+
 // ReverseCreateCheckOptions defines the running config.reverse.check structure
 type ReverseCreateCheckOptions struct {
 	Broker string `json:"broker" yaml:"broker" toml:"broker"`
@@ -32,38 +38,126 @@ type ReverseCreateCheckOptions struct {
 	Title  string `json:"title" yaml:"title" toml:"title"`
 }
 
+// CheckSecondary defines the running config.check.secondary structure
+type CheckSecondary struct {
+	APIApp    string `mapstructure:"api_app" json:"api_app" yaml:"api_app" toml:"api_app"`
+	APICAFile string `mapstructure:"api_ca_file" json:"api_ca_file" yaml:"api_ca_file" toml:"api_ca_file"`
+	APIKey    string `mapstructure:"api_key" json:"api_key" yaml:"api_key" toml:"api_key"`
+	APIURL    string `mapstructure:"api_url" json:"api_url" yaml:"api_url" toml:"api_url"`
+	BundleID  string `mapstructure:"bundle_id" json:"bundle_id" yaml:"bundle_id" toml:"bundle_id"`
+}
+
+// CheckSpool defines the running config.check.spool structure
+type CheckSpool struct {
+	Dir     string `json:"dir" yaml:"dir" toml:"dir"`
+	MaxAge  string `mapstructure:"max_age" json:"max_age" yaml:"max_age" toml:"max_age"`
+	MaxSize string `mapstructure:"max_size" json:"max_size" yaml:"max_size" toml:"max_size"`
+}
+
 // Check defines the check parameters
 type Check struct {
-	Broker           string `json:"broker" yaml:"broker" toml:"broker"`
-	BundleID         string `mapstructure:"bundle_id" json:"bundle_id" yaml:"bundle_id" toml:"bundle_id"`
-	Create           bool   `mapstructure:"create" json:"create" yaml:"create" toml:"create"`
-	EnableNewMetrics bool   `mapstructure:"enable_new_metrics" json:"enable_new_metrics" yaml:"enable_new_metrics" toml:"enable_new_metrics"`
-	MetricStateDir   string `mapstructure:"metric_state_dir" json:"metric_state_dir" yaml:"metric_state_dir" toml:"metric_state_dir"`
-	MetricRefreshTTL string `mapstructure:"metric_refresh_ttl" json:"metric_refresh_ttl" yaml:"metric_refresh_ttl" toml:"metric_refresh_ttl"`
-	Tags             string `json:"tags" yaml:"tags" toml:"tags"`
-	Target           string `mapstructure:"target" json:"target" yaml:"target" toml:"target"`
-	Title            string `json:"title" yaml:"title" toml:"title"`
+	AnnotationCategory string              `mapstructure:"annotation_category" json:"annotation_category" yaml:"annotation_category" toml:"annotation_category"`
+	Annotations        bool                `json:"annotations" yaml:"annotations" toml:"annotations"`
+	Broker             string              `json:"broker" yaml:"broker" toml:"broker"`
+	BundleID           string              `mapstructure:"bundle_id" json:"bundle_id" yaml:"bundle_id" toml:"bundle_id"`
+	BundleTemplate     string              `mapstructure:"bundle_template" json:"bundle_template" yaml:"bundle_template" toml:"bundle_template"`
+	Create             bool                `mapstructure:"create" json:"create" yaml:"create" toml:"create"`
+	EnableNewMetrics   bool                `mapstructure:"enable_new_metrics" json:"enable_new_metrics" yaml:"enable_new_metrics" toml:"enable_new_metrics"`
+	ForceEnable        []string            `mapstructure:"force_enable_metrics" json:"force_enable_metrics" yaml:"force_enable_metrics" toml:"force_enable_metrics"`
+	MetricFilters      []CheckMetricFilter `mapstructure:"metric_filters" json:"metric_filters" yaml:"metric_filters" toml:"metric_filters"`
+	MetricStateDir     string              `mapstructure:"metric_state_dir" json:"metric_state_dir" yaml:"metric_state_dir" toml:"metric_state_dir"`
+	MetricRefreshTTL   string              `mapstructure:"metric_refresh_ttl" json:"metric_refresh_ttl" yaml:"metric_refresh_ttl" toml:"metric_refresh_ttl"`
+	MetricTypes        []CheckMetricType   `mapstructure:"metric_types" json:"metric_types" yaml:"metric_types" toml:"metric_types"`
+	ProbeDisabled      bool                `mapstructure:"probe_disabled" json:"probe_disabled" yaml:"probe_disabled" toml:"probe_disabled"`
+	SearchSelect       string              `mapstructure:"search_select" json:"search_select" yaml:"search_select" toml:"search_select"`
+	SearchTags         string              `mapstructure:"search_tags" json:"search_tags" yaml:"search_tags" toml:"search_tags"`
+	Secondary          CheckSecondary      `json:"secondary" yaml:"secondary" toml:"secondary"`
+	Spool              CheckSpool          `json:"spool" yaml:"spool" toml:"spool"`
+	Tags               string              `json:"tags" yaml:"tags" toml:"tags"`
+	Target             string              `mapstructure:"target" json:"target" yaml:"target" toml:"target"`
+	Title              string              `json:"title" yaml:"title" toml:"title"`
+}
+
+// CheckMetricFilter allows or denies the metrics matching a regular expression
+// (and optional tag query) on the check bundle, in the running
+// config.check.metric_filters list
+type CheckMetricFilter struct {
+	Action  string `json:"action" yaml:"action" toml:"action"`
+	Comment string `json:"comment" yaml:"comment" toml:"comment"`
+	Match   string `json:"match" yaml:"match" toml:"match"`
+	Tags    string `json:"tags" yaml:"tags" toml:"tags"`
+}
+
+// CheckMetricType maps metric names matching a regular expression to a
+// circonus metric type (numeric|histogram|text) in the running config.check.metric_types list
+type CheckMetricType struct {
+	Match string `json:"match" yaml:"match" toml:"match"`
+	Type  string `json:"type" yaml:"type" toml:"type"`
+}
+
+// PluginFIFO defines a named pipe plugin source in the running config.plugin_fifo structure
+type PluginFIFO struct {
+	Format string `json:"format" yaml:"format" toml:"format"`
+	Path   string `json:"path" yaml:"path" toml:"path"`
+}
+
+// PluginHTTP defines an http json plugin source in the running config.plugin_http structure
+type PluginHTTP struct {
+	Headers  map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+	Interval string            `json:"interval" yaml:"interval" toml:"interval"`
+	Timeout  string            `json:"timeout" yaml:"timeout" toml:"timeout"`
+	URL      string            `json:"url" yaml:"url" toml:"url"`
+}
+
+// PluginSandbox defines plugin execution restrictions in the running config.plugin_sandbox structure
+type PluginSandbox struct {
+	CPUTime   string `mapstructure:"cpu_time" json:"cpu_time" yaml:"cpu_time" toml:"cpu_time"`
+	Group     string `json:"group" yaml:"group" toml:"group"`
+	Memory    string `json:"memory" yaml:"memory" toml:"memory"`
+	OpenFiles uint64 `mapstructure:"open_files" json:"open_files" yaml:"open_files" toml:"open_files"`
+	User      string `json:"user" yaml:"user" toml:"user"`
 }
 
 // Reverse defines the running config.reverse structure
 type Reverse struct {
-	BrokerCAFile string `mapstructure:"broker_ca_file" json:"broker_ca_file" yaml:"broker_ca_file" toml:"broker_ca_file"`
-	Enabled      bool   `json:"enabled" yaml:"enabled" toml:"enabled"`
-	MaxConnRetry int    `mapstructure:"max_conn_retry" json:"max_conn_retry" yaml:"max_conn_retry" toml:"max_conn_retry"`
+	BrokerCAFile    string `mapstructure:"broker_ca_file" json:"broker_ca_file" yaml:"broker_ca_file" toml:"broker_ca_file"`
+	ClientCertFile  string `mapstructure:"client_cert_file" json:"client_cert_file" yaml:"client_cert_file" toml:"client_cert_file"`
+	ClientKeyFile   string `mapstructure:"client_key_file" json:"client_key_file" yaml:"client_key_file" toml:"client_key_file"`
+	CommandTimeout  string `mapstructure:"command_timeout" json:"command_timeout" yaml:"command_timeout" toml:"command_timeout"`
+	Compression     string `json:"compression" yaml:"compression" toml:"compression"`
+	ConnectJitter   string `mapstructure:"connect_jitter" json:"connect_jitter" yaml:"connect_jitter" toml:"connect_jitter"`
+	Enabled         bool   `json:"enabled" yaml:"enabled" toml:"enabled"`
+	LatencyInterval string `mapstructure:"latency_interval" json:"latency_interval" yaml:"latency_interval" toml:"latency_interval"`
+	MaxConnRetry    int    `mapstructure:"max_conn_retry" json:"max_conn_retry" yaml:"max_conn_retry" toml:"max_conn_retry"`
+	MaxFrameSize    int    `mapstructure:"max_frame_size" json:"max_frame_size" yaml:"max_frame_size" toml:"max_frame_size"`
 }
 
 // SSL defines the running config.ssl structure
 type SSL struct {
-	CertFile string `mapstructure:"cert_file" json:"cert_file" yaml:"cert_file" toml:"cert_file"`
-	KeyFile  string `mapstructure:"key_file" json:"key_file" yaml:"key_file" toml:"key_file"`
-	Listen   string `json:"listen" yaml:"listen" toml:"listen"`
-	Verify   bool   `json:"verify" yaml:"verify" toml:"verify"`
+	CertFile     string `mapstructure:"cert_file" json:"cert_file" yaml:"cert_file" toml:"cert_file"`
+	ClientCAFile string `mapstructure:"client_ca_file" json:"client_ca_file" yaml:"client_ca_file" toml:"client_ca_file"`
+	KeyFile      string `mapstructure:"key_file" json:"key_file" yaml:"key_file" toml:"key_file"`
+	Listen       string `json:"listen" yaml:"listen" toml:"listen"`
+	Verify       bool   `json:"verify" yaml:"verify" toml:"verify"`
+}
+
+// Server defines the running config.server structure
+type Server struct {
+	AuthPassword    string   `mapstructure:"auth_password" json:"auth_password" yaml:"auth_password" toml:"auth_password"`
+	AuthToken       string   `mapstructure:"auth_token" json:"auth_token" yaml:"auth_token" toml:"auth_token"`
+	AuthUser        string   `mapstructure:"auth_user" json:"auth_user" yaml:"auth_user" toml:"auth_user"`
+	CORSOrigins     []string `mapstructure:"cors_origins" json:"cors_origins" yaml:"cors_origins" toml:"cors_origins"`
+	DisableGzip     bool     `mapstructure:"disable_gzip" json:"disable_gzip" yaml:"disable_gzip" toml:"disable_gzip"`
+	DisableWrite    bool     `mapstructure:"disable_write" json:"disable_write" yaml:"disable_write" toml:"disable_write"`
+	ShutdownTimeout string   `mapstructure:"shutdown_timeout" json:"shutdown_timeout" yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	WriteStrict     bool     `mapstructure:"write_strict" json:"write_strict" yaml:"write_strict" toml:"write_strict"`
 }
 
 // StatsDHost defines the running config.statsd.host structure
 type StatsDHost struct {
 	Category     string `json:"category" yaml:"category" toml:"category"`
 	MetricPrefix string `mapstructure:"metric_prefix" json:"metric_prefix" yaml:"metric_prefix" toml:"metric_prefix"`
+	Tag          string `json:"tag" yaml:"tag" toml:"tag"`
 }
 
 // StatsDGroup defines the running config.statsd.group structure
@@ -71,34 +165,88 @@ type StatsDGroup struct {
 	CheckBundleID string `mapstructure:"check_bundle_id" json:"check_bundle_id" yaml:"check_bundle_id" toml:"check_bundle_id"`
 	Counters      string `json:"counters" yaml:"counters" toml:"counters"`
 	Gauges        string `json:"gauges" yaml:"gauges" toml:"gauges"`
+	Interval      string `json:"interval" yaml:"interval" toml:"interval"`
 	MetricPrefix  string `mapstructure:"metric_prefix" json:"metric_prefix" yaml:"metric_prefix" toml:"metric_prefix"`
 	Sets          string `json:"sets" yaml:"sets" toml:"sets"`
+	Tag           string `json:"tag" yaml:"tag" toml:"tag"`
+}
+
+// StatsDMetricFilter allows or denies the statsd metric names matching a
+// regular expression in the running config.statsd.metric_filters list
+type StatsDMetricFilter struct {
+	Action string `json:"action" yaml:"action" toml:"action"`
+	Match  string `json:"match" yaml:"match" toml:"match"`
 }
 
 // StatsD defines the running config.statsd structure
 type StatsD struct {
-	Disabled bool        `json:"disabled" yaml:"disabled" toml:"disabled"`
-	Group    StatsDGroup `json:"group" yaml:"group" toml:"group"`
-	Host     StatsDHost  `json:"host" yaml:"host" toml:"host"`
-	Port     string      `json:"port" yaml:"port" toml:"port"`
+	AggregationWindow    string               `mapstructure:"aggregation_window" json:"aggregation_window" yaml:"aggregation_window" toml:"aggregation_window"`
+	CounterMode          string               `mapstructure:"counter_mode" json:"counter_mode" yaml:"counter_mode" toml:"counter_mode"`
+	Disabled             bool                 `json:"disabled" yaml:"disabled" toml:"disabled"`
+	GaugeTTL             string               `mapstructure:"gauge_ttl" json:"gauge_ttl" yaml:"gauge_ttl" toml:"gauge_ttl"`
+	Group                StatsDGroup          `json:"group" yaml:"group" toml:"group"`
+	Host                 StatsDHost           `json:"host" yaml:"host" toml:"host"`
+	InvalidChars         string               `mapstructure:"invalid_chars" json:"invalid_chars" yaml:"invalid_chars" toml:"invalid_chars"`
+	MetricFilters        []StatsDMetricFilter `mapstructure:"metric_filters" json:"metric_filters" yaml:"metric_filters" toml:"metric_filters"`
+	Port                 string               `json:"port" yaml:"port" toml:"port"`
+	QueueMetrics         bool                 `mapstructure:"queue_metrics" json:"queue_metrics" yaml:"queue_metrics" toml:"queue_metrics"`
+	QueueSize            int                  `mapstructure:"queue_size" json:"queue_size" yaml:"queue_size" toml:"queue_size"`
+	RateBurst            int                  `mapstructure:"rate_burst" json:"rate_burst" yaml:"rate_burst" toml:"rate_burst"`
+	RateLimit            int                  `mapstructure:"rate_limit" json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+	Routing              string               `json:"routing" yaml:"routing" toml:"routing"`
+	SetDelimiter         string               `mapstructure:"set_delimiter" json:"set_delimiter" yaml:"set_delimiter" toml:"set_delimiter"`
+	SetMaxLength         int                  `mapstructure:"set_max_length" json:"set_max_length" yaml:"set_max_length" toml:"set_max_length"`
+	StateFile            string               `mapstructure:"state_file" json:"state_file" yaml:"state_file" toml:"state_file"`
+	StateMaxAge          string               `mapstructure:"state_max_age" json:"state_max_age" yaml:"state_max_age" toml:"state_max_age"`
+	StrictLines          bool                 `mapstructure:"strict_lines" json:"strict_lines" yaml:"strict_lines" toml:"strict_lines"`
+	TimerPercentiles     []string             `mapstructure:"timer_percentiles" json:"timer_percentiles" yaml:"timer_percentiles" toml:"timer_percentiles"`
+	TimerPercentilesOnly bool                 `mapstructure:"timer_percentiles_only" json:"timer_percentiles_only" yaml:"timer_percentiles_only" toml:"timer_percentiles_only"`
+	ZeroCounter          string               `mapstructure:"zero_counter" json:"zero_counter" yaml:"zero_counter" toml:"zero_counter"`
 }
 
 // Config defines the running config structure
+//
+// Collectors may be either a list or a map of collector names:
+//   - list (--collectors, env, or config file), the exact set of collectors to enable
+//   - map (config file only), e.g. {cpu: true, vm: false}, toggles individual
+//     collectors relative to the platform default list: true enables, false disables
+//
+// A list set via the command line or environment takes precedence over the config
+// file, as with all other settings. The running config holds the effective set,
+// see EnabledCollectors.
 type Config struct {
-	API              API      `json:"api" yaml:"api" toml:"api"`
-	Check            Check    `json:"check" yaml:"check" toml:"check"`
-	Collectors       []string `json:"collectors" yaml:"collectors" toml:"collectors"`
-	Debug            bool     `json:"debug" yaml:"debug" toml:"debug"`
-	DebugCGM         bool     `mapstructure:"debug_cgm" json:"debug_cgm" yaml:"debug_cgm" toml:"debug_cgm"`
-	DebugDumpMetrics string   `mapstructure:"debug_dump_metrics" json:"debug_dump_metrics" yaml:"debug_dump_metrics" toml:"debug_dump_metrics"`
-	Listen           []string `json:"listen" yaml:"listen" toml:"listen"`
-	ListenSocket     []string `mapstructure:"listen_socket" json:"listen_socket" yaml:"listen_socket" toml:"listen_socket"`
-	Log              Log      `json:"log" yaml:"log" toml:"log"`
-	PluginDir        string   `mapstructure:"plugin_dir" json:"plugin_dir" yaml:"plugin_dir" toml:"plugin_dir"`
-	PluginTTLUnits   string   `mapstructure:"plugin_ttl_units" json:"plugin_ttl_units" yaml:"plugin_ttl_units" toml:"plugin_ttl_units"`
-	Reverse          Reverse  `json:"reverse" yaml:"reverse" toml:"reverse"`
-	SSL              SSL      `json:"ssl" yaml:"ssl" toml:"ssl"`
-	StatsD           StatsD   `json:"statsd" yaml:"statsd" toml:"statsd"`
+	API                 API                      `json:"api" yaml:"api" toml:"api"`
+	Check               Check                    `json:"check" yaml:"check" toml:"check"`
+	Collectors          []string                 `mapstructure:"-" json:"collectors" yaml:"collectors" toml:"collectors"`
+	CollectorsStrict    bool                     `mapstructure:"collectors_strict" json:"collectors_strict" yaml:"collectors_strict" toml:"collectors_strict"`
+	ConfigDir           string                   `mapstructure:"config_dir" json:"config_dir" yaml:"config_dir" toml:"config_dir"`
+	Debug               bool                     `json:"debug" yaml:"debug" toml:"debug"`
+	DebugCGM            bool                     `mapstructure:"debug_cgm" json:"debug_cgm" yaml:"debug_cgm" toml:"debug_cgm"`
+	DebugDumpMetrics    string                   `mapstructure:"debug_dump_metrics" json:"debug_dump_metrics" yaml:"debug_dump_metrics" toml:"debug_dump_metrics"`
+	DebugPprof          bool                     `mapstructure:"debug_pprof" json:"debug_pprof" yaml:"debug_pprof" toml:"debug_pprof"`
+	DebugPprofListen    string                   `mapstructure:"debug_pprof_listen" json:"debug_pprof_listen" yaml:"debug_pprof_listen" toml:"debug_pprof_listen"`
+	Direct              Direct                   `json:"direct" yaml:"direct" toml:"direct"`
+	Listen              []string                 `json:"listen" yaml:"listen" toml:"listen"`
+	ListenSocket        []string                 `mapstructure:"listen_socket" json:"listen_socket" yaml:"listen_socket" toml:"listen_socket"`
+	Log                 Log                      `json:"log" yaml:"log" toml:"log"`
+	PIDFile             string                   `mapstructure:"pid_file" json:"pid_file" yaml:"pid_file" toml:"pid_file"`
+	PluginCollisions    string                   `mapstructure:"plugin_collisions" json:"plugin_collisions" yaml:"plugin_collisions" toml:"plugin_collisions"`
+	PluginDir           string                   `mapstructure:"plugin_dir" json:"plugin_dir" yaml:"plugin_dir" toml:"plugin_dir"`
+	PluginFIFO          map[string]PluginFIFO    `mapstructure:"plugin_fifo" json:"plugin_fifo" yaml:"plugin_fifo" toml:"plugin_fifo"`
+	PluginHTTP          map[string]PluginHTTP    `mapstructure:"plugin_http" json:"plugin_http" yaml:"plugin_http" toml:"plugin_http"`
+	PluginPersistent    []string                 `mapstructure:"plugin_persistent" json:"plugin_persistent" yaml:"plugin_persistent" toml:"plugin_persistent"`
+	PluginSandbox       map[string]PluginSandbox `mapstructure:"plugin_sandbox" json:"plugin_sandbox" yaml:"plugin_sandbox" toml:"plugin_sandbox"`
+	PluginSlowThreshold string                   `mapstructure:"plugin_slow_threshold" json:"plugin_slow_threshold" yaml:"plugin_slow_threshold" toml:"plugin_slow_threshold"`
+	PluginTimeout       string                   `mapstructure:"plugin_timeout" json:"plugin_timeout" yaml:"plugin_timeout" toml:"plugin_timeout"`
+	PluginTimeouts      map[string]string        `mapstructure:"plugin_timeouts" json:"plugin_timeouts" yaml:"plugin_timeouts" toml:"plugin_timeouts"`
+	PluginTTLs          map[string]string        `mapstructure:"plugin_ttls" json:"plugin_ttls" yaml:"plugin_ttls" toml:"plugin_ttls"`
+	PluginTTLUnits      string                   `mapstructure:"plugin_ttl_units" json:"plugin_ttl_units" yaml:"plugin_ttl_units" toml:"plugin_ttl_units"`
+	PluginWatch         bool                     `mapstructure:"plugin_watch" json:"plugin_watch" yaml:"plugin_watch" toml:"plugin_watch"`
+	PluginWorkers       int                      `mapstructure:"plugin_workers" json:"plugin_workers" yaml:"plugin_workers" toml:"plugin_workers"`
+	Reverse             Reverse                  `json:"reverse" yaml:"reverse" toml:"reverse"`
+	Server              Server                   `json:"server" yaml:"server" toml:"server"`
+	SSL                 SSL                      `json:"ssl" yaml:"ssl" toml:"ssl"`
+	StatsD              StatsD                   `json:"statsd" yaml:"statsd" toml:"statsd"`
 }
 
 type cosiCheckConfig struct {
@@ -132,6 +280,19 @@ const (
 	// permissions. metrics will be dumped for each _successful_ request.
 	KeyDebugDumpMetrics = "debug_dump_metrics"
 
+	// KeyDebugPprof enables the pprof (/debug/pprof/) server
+	KeyDebugPprof = "debug_pprof"
+
+	// KeyDebugPprofListen address for the pprof server
+	KeyDebugPprofListen = "debug_pprof_listen"
+
+	// KeyDirect submit metrics directly to the check (httptrap) on an interval,
+	// rather than being polled by the broker (mutually exclusive with reverse)
+	KeyDirect = "direct.enabled"
+
+	// KeyDirectInterval how often metrics are collected and submitted in direct mode
+	KeyDirectInterval = "direct.interval"
+
 	// KeyListen primary address and port to listen on
 	KeyListen = "listen"
 
@@ -144,30 +305,118 @@ const (
 	// KeyLogPretty output formatted log lines (for running in foreground)
 	KeyLogPretty = "log.pretty"
 
+	// KeyPIDFile file the agent's pid is written to, locked while the agent is
+	// running so a second agent using the same file refuses to start, empty disables
+	KeyPIDFile = "pid_file"
+
+	// KeyPluginCollisions how a metric name emitted by more than one plugin is
+	// handled, drop (keep the metric from the first plugin, sorted by id) or
+	// namespace (also keep the later plugin's metric, namespaced by plugin id)
+	KeyPluginCollisions = "plugin_collisions"
+
 	// KeyPluginDir plugin directory
 	KeyPluginDir = "plugin_dir"
 
+	// KeyPluginFIFO named pipes (fifos) metrics are read from, as virtual plugins,
+	// a map of plugin name to path and format, plugin (default) or statsd
+	// (config file only, e.g. {"app": {"path": "/run/app/metrics", "format": "statsd"}})
+	KeyPluginFIFO = "plugin_fifo"
+
+	// KeyPluginHTTP http(s) endpoints returning json metrics, polled as virtual plugins,
+	// a map of plugin name to url, interval, timeout, and headers (config file only,
+	// e.g. {"app": {"url": "http://127.0.0.1:8080/metrics.json", "interval": "30s"}})
+	KeyPluginHTTP = "plugin_http"
+
+	// KeyPluginPersistent list of plugins (or plugin`instance) to run persistently,
+	// started once and restarted if they exit (config file only, e.g. ["tail_log"])
+	KeyPluginPersistent = "plugin_persistent"
+
+	// KeyPluginSandbox per-plugin execution restrictions, a map of plugin
+	// name ("*" for all plugins) to the user/group to run the plugin as and
+	// resource limits (config file only, unix only)
+	KeyPluginSandbox = "plugin_sandbox"
+
+	// KeyPluginSlowThreshold plugins whose last run took at least this long
+	// run in the background, their last result is served until they finish
+	// (empty or 0 disables, all plugins are waited for)
+	KeyPluginSlowThreshold = "plugin_slow_threshold"
+
+	// KeyPluginTimeout default maximum plugin execution time, plugins exceeding
+	// the timeout are terminated (0 = no timeout)
+	KeyPluginTimeout = "plugin_timeout"
+
+	// KeyPluginTimeouts per-plugin execution timeout overrides, a map
+	// of plugin name to timeout (config file only, e.g. {"foo": "30s"})
+	KeyPluginTimeouts = "plugin_timeouts"
+
+	// KeyPluginTTLs per-plugin run ttls, a map of plugin name to ttl
+	// (config file only, e.g. {"foo": "5m"}), overrides a ttl in the file name
+	KeyPluginTTLs = "plugin_ttls"
+
 	// KeyPluginTTLUnits plugin run ttl units
 	KeyPluginTTLUnits = "plugin_ttl_units"
 
+	// KeyPluginWatch re-scan the plugin directory automatically when it changes
+	KeyPluginWatch = "plugin_watch"
+
+	// KeyPluginWorkers maximum number of plugins to execute concurrently
+	// (0 = number of CPUs)
+	KeyPluginWorkers = "plugin_workers"
+
 	// KeyReverse indicates whether to use reverse connections
 	KeyReverse = "reverse.enabled"
 
 	// KeyReverseBrokerCAFile custom broker ca file
 	KeyReverseBrokerCAFile = "reverse.broker_ca_file"
 
+	// KeyReverseClientCertFile client certificate presented to the broker (mTLS)
+	KeyReverseClientCertFile = "reverse.client_cert_file"
+
+	// KeyReverseClientKeyFile key for the client certificate presented to the broker
+	KeyReverseClientKeyFile = "reverse.client_key_file"
+
+	// KeyReverseCompression compression of the metrics sent to the broker
+	// (gzip), only if supported by the broker, empty disables
+	KeyReverseCompression = "reverse.compression"
+
+	// KeyReverseCommandTimeout maximum time to service a broker command (e.g. a
+	// metric request), an error response is sent to the broker on overrun
+	KeyReverseCommandTimeout = "reverse.command_timeout"
+
+	// KeyReverseConnectJitter maximum random delay before connecting to the broker,
+	// initially and when reconnecting after a connection is lost, empty or 0 disables
+	KeyReverseConnectJitter = "reverse.connect_jitter"
+
+	// KeyReverseLatencyInterval how often to measure the broker round-trip latency
+	// (time from sending metrics to the broker closing the request channel), empty disables
+	KeyReverseLatencyInterval = "reverse.latency_interval"
+
 	// KeyReverseMaxConnRetry how many times to retry a persistently failing broker connection. default 10, -1 = indefinitely
 	KeyReverseMaxConnRetry = "reverse.max_conn_retry"
 
+	// KeyReverseMaxFrameSize maximum frame payload size accepted from the broker,
+	// larger frames reset the connection (default and upper limit 65529)
+	KeyReverseMaxFrameSize = "reverse.max_frame_size"
+
+	// KeyOneshot - collect metrics once, submit them to the check and exit
+	KeyOneshot = "oneshot"
+
 	// KeyShowConfig - show configuration and exit
 	KeyShowConfig = "show-config"
 
 	// KeyShowVersion - show version information and exit
 	KeyShowVersion = "version"
 
+	// KeyValidate - validate configuration and exit
+	KeyValidate = "validate"
+
 	// KeySSLCertFile pem certificate file for SSL
 	KeySSLCertFile = "ssl.cert_file"
 
+	// KeySSLClientCAFile pem file of CA(s) used to verify client certificates,
+	// setting enables client certificate verification (mTLS) on the ssl listener
+	KeySSLClientCAFile = "ssl.client_ca_file"
+
 	// KeySSLKeyFile key for ssl.cert_file
 	KeySSLKeyFile = "ssl.key_file"
 
@@ -177,9 +426,21 @@ const (
 	// KeySSLVerify controls verification for ssl connections
 	KeySSLVerify = "ssl.verify"
 
+	// KeyStatsdAggregationWindow how long counter and gauge updates are aggregated
+	// in memory before being applied (empty or 0 disables aggregation)
+	KeyStatsdAggregationWindow = "statsd.aggregation_window"
+
+	// KeyStatsdCounterMode how host counters are reported, as counts, per second
+	// rates computed over the flush interval, or both (count|rate|both)
+	KeyStatsdCounterMode = "statsd.counter_mode"
+
 	// KeyStatsdDisabled disables the default statsd listener
 	KeyStatsdDisabled = "statsd.disabled"
 
+	// KeyStatsdGaugeTTL how long a gauge is reported without being updated before
+	// it expires and is no longer submitted (empty or 0 disables expiry)
+	KeyStatsdGaugeTTL = "statsd.gauge_ttl"
+
 	// KeyStatsdGroupCID circonus check bundle id for "group" metrics sent to statsd
 	KeyStatsdGroupCID = "statsd.group.check_bundle_id"
 
@@ -189,30 +450,144 @@ const (
 	// KeyStatsdGroupGauges operator for group gauges (sum|average)
 	KeyStatsdGroupGauges = "statsd.group.gauges"
 
+	// KeyStatsdGroupInterval how often group metrics are submitted to the group check
+	KeyStatsdGroupInterval = "statsd.group.interval"
+
 	// KeyStatsdGroupPrefix metrics prefixed with this string are considered "group" metrics
 	KeyStatsdGroupPrefix = "statsd.group.metric_prefix"
 
 	// KeyStatsdGroupSets operator for group sets (sum|average)
 	KeyStatsdGroupSets = "statsd.group.sets"
 
+	// KeyStatsdGroupTag stream tag (category:value) identifying "group" metrics when
+	// routing by tag, the tag is removed from the metric
+	KeyStatsdGroupTag = "statsd.group.tag"
+
 	// KeyStatsdHostCategory "plugin" name to put metrics sent to host
 	KeyStatsdHostCategory = "statsd.host.category"
 
 	// KeyStatsdHostPrefix metrics prefixed with this string are considered "host" metrics
 	KeyStatsdHostPrefix = "statsd.host.metric_prefix"
 
+	// KeyStatsdHostTag stream tag category identifying the originating host (e.g. host),
+	// metrics with the tag are host metrics kept distinct by the tag, empty disables
+	KeyStatsdHostTag = "statsd.host.tag"
+
+	// KeyStatsdInvalidChars how metric names and set values containing invalid
+	// characters are handled (sanitize|reject)
+	KeyStatsdInvalidChars = "statsd.invalid_chars"
+
+	// KeyStatsdMetricFilters ordered list of allow|deny rules applied to the
+	// names of statsd metrics received, the first matching rule applies (config file only)
+	KeyStatsdMetricFilters = "statsd.metric_filters"
+
 	// KeyStatsdPort port for statsd listener (note, address will always be 'localhost')
 	KeyStatsdPort = "statsd.port"
 
-	// KeyCollectors defines the builtin collectors to enable
+	// KeyStatsdQueueMetrics reports the packet queue depth (max, avg and high
+	// water mark) as host gauges each flush
+	KeyStatsdQueueMetrics = "statsd.queue_metrics"
+
+	// KeyStatsdQueueSize number of received packets which may be queued for processing
+	KeyStatsdQueueSize = "statsd.queue_size"
+
+	// KeyStatsdRateBurst maximum burst of packets accepted from a single source
+	// when rate limiting is enabled (0 uses the rate limit)
+	KeyStatsdRateBurst = "statsd.rate_burst"
+
+	// KeyStatsdRateLimit packets per second accepted from a single source ip,
+	// packets over the limit are dropped (0 disables rate limiting)
+	KeyStatsdRateLimit = "statsd.rate_limit"
+
+	// KeyStatsdRouting how metrics are routed to the host or group check
+	// (prefix|tag|both)
+	KeyStatsdRouting = "statsd.routing"
+
+	// KeyStatsdSetDelimiter string joining set names and members in the
+	// counter names tracking set members (default is the metric name separator)
+	KeyStatsdSetDelimiter = "statsd.set_delimiter"
+
+	// KeyStatsdSetMaxLength set members longer than this are truncated, with a
+	// hash of the full member appended (0 disables)
+	KeyStatsdSetMaxLength = "statsd.set_max_length"
+
+	// KeyStatsdStateFile file where host counters and gauges not yet collected are
+	// saved when the agent stops and restored from when it starts (empty disables)
+	KeyStatsdStateFile = "statsd.state_file"
+
+	// KeyStatsdStateMaxAge saved state older than this is discarded at startup
+	KeyStatsdStateMaxAge = "statsd.state_max_age"
+
+	// KeyStatsdStrictLines split packets strictly on newlines, without trimming
+	// whitespace (e.g. \r of \r\n) or skipping blank lines
+	KeyStatsdStrictLines = "statsd.strict_lines"
+
+	// KeyStatsdTimerPercentiles percentiles (e.g. 50,90,95,99) computed from
+	// host timers (ms) each flush and reported as gauges (empty disables)
+	KeyStatsdTimerPercentiles = "statsd.timer_percentiles"
+
+	// KeyStatsdTimerPercentilesOnly report only the percentiles of host timers,
+	// not the histogram (requires timer percentiles)
+	KeyStatsdTimerPercentilesOnly = "statsd.timer_percentiles_only"
+
+	// KeyStatsdZeroCounter how a counter with a value of 0 is handled, recorded
+	// as 0 (zero), ignored (drop) or recorded as 1 (one, the original behavior)
+	KeyStatsdZeroCounter = "statsd.zero_counter"
+
+	// KeyCollectors defines the builtin collectors to enable (list or map, see Config)
 	KeyCollectors = "collectors"
 
+	// KeyCollectorsStrict treat unknown builtin collector names as an error rather than ignoring them
+	KeyCollectorsStrict = "collectors_strict"
+
+	// KeyConfigDir drop-in directory, configuration files in the directory are
+	// merged over the main configuration file in lexical order (see ReadConfig)
+	KeyConfigDir = "config_dir"
+
 	// KeyDisableGzip disables gzip on http responses
 	KeyDisableGzip = "server.disable_gzip"
 
+	// KeyServerAuthToken requires requests to the http listener(s) to supply
+	// the token in an 'Authorization: Bearer <token>' header
+	KeyServerAuthToken = "server.auth_token"
+
+	// KeyServerAuthUser requires requests to the http listener(s) to supply
+	// basic auth with this user name (requires server.auth_password)
+	KeyServerAuthUser = "server.auth_user"
+
+	// KeyServerAuthPassword password for basic auth (requires server.auth_user)
+	KeyServerAuthPassword = "server.auth_password"
+
+	// KeyServerCORSOrigins origins allowed to make cross-origin requests to the
+	// http listener(s) (e.g. browser based dashboards), '*' allows any origin
+	KeyServerCORSOrigins = "server.cors_origins"
+
+	// KeyServerShutdownTimeout how long to wait for in-flight requests to
+	// complete when stopping the server(s) before forcibly closing them
+	KeyServerShutdownTimeout = "server.shutdown_timeout"
+
+	// KeyServerDisableWrite rejects metrics pushed to the http listener(s) (/write
+	// and /prom), the socket listener(s) continue to accept them
+	KeyServerDisableWrite = "server.disable_write"
+
+	// KeyServerWriteStrict rejects a /write request entirely if any metric
+	// has an unsupported type or invalid value (default, such metrics are skipped)
+	KeyServerWriteStrict = "server.write_strict"
+
+	// KeyCheckAnnotations posts circonus annotations when the agent starts,
+	// stops and reloads its configuration
+	KeyCheckAnnotations = "check.annotations"
+
+	// KeyCheckAnnotationCategory category of the agent lifecycle annotations
+	KeyCheckAnnotationCategory = "check.annotation_category"
+
 	// KeyCheckBundleID the check bundle id to use
 	KeyCheckBundleID = "check.bundle_id"
 
+	// KeyCheckBundleTemplate json file describing the check bundle (e.g. tags,
+	// period, metric_filters) to use when creating a new check bundle
+	KeyCheckBundleTemplate = "check.bundle_template"
+
 	// KeyCheckTarget the check bundle target to use to search for or create a check bundle
 	// note: if not using reverse, this must be an IP address reachable by the broker
 	KeyCheckTarget = "check.target"
@@ -221,6 +596,16 @@ const (
 	KeyCheckEnableNewMetrics = "check.enable_new_metrics"
 	// KeyCheckMetricStateDir defines the path where check metric state will be maintained when --check-enable-new-metrics is turned on
 	KeyCheckMetricStateDir = "check.metric_state_dir"
+	// KeyCheckForceEnableMetrics metric names which are always enabled on the check
+	// (when enable new metrics is turned on), even before they are first reported
+	KeyCheckForceEnableMetrics = "check.force_enable_metrics"
+	// KeyCheckMetricFilters ordered list of allow|deny rules managed as the
+	// check bundle's metric_filters, when set new metrics are not enabled
+	// individually (config file only)
+	KeyCheckMetricFilters = "check.metric_filters"
+	// KeyCheckMetricTypes maps metric name patterns to circonus metric types,
+	// overriding the inferred type of new metrics (config file only)
+	KeyCheckMetricTypes = "check.metric_types"
 	// KeyCheckMetricRefreshTTL determines how often to refresh check bundle metrics from API when enable new metrics is turned on
 	KeyCheckMetricRefreshTTL = "check.metric_refresh_ttl"
 
@@ -236,6 +621,43 @@ const (
 	// KeyCheckTags a specific set of tags to use when creating a new check bundle
 	KeyCheckTags = "check.tags"
 
+	// KeyCheckSearchTags tags [comma separated list] used to find the check bundle,
+	// instead of the check target, and added to a new check bundle
+	KeyCheckSearchTags = "check.search_tags"
+
+	// KeyCheckSearchSelect how a check bundle is selected when more than one
+	// matches the search tags (none|oldest|newest, none fails)
+	KeyCheckSearchSelect = "check.search_select"
+
+	// KeyCheckSecondaryBundleID an httptrap check bundle on a second (HA) circonus
+	// cluster, metrics are mirrored to it in addition to the primary check
+	KeyCheckSecondaryBundleID = "check.secondary.bundle_id"
+
+	// KeyCheckSecondaryAPIKey circonus api token key for the secondary cluster
+	KeyCheckSecondaryAPIKey = "check.secondary.api_key"
+
+	// KeyCheckSecondaryAPIApp circonus api token key application name for the secondary cluster
+	KeyCheckSecondaryAPIApp = "check.secondary.api_app"
+
+	// KeyCheckSecondaryAPIURL circonus api url for the secondary cluster
+	KeyCheckSecondaryAPIURL = "check.secondary.api_url"
+
+	// KeyCheckProbeDisabled disables the startup broker connectivity probe
+	KeyCheckProbeDisabled = "check.probe_disabled"
+
+	// KeyCheckSecondaryAPICAFile custom ca for the secondary cluster circonus api
+	KeyCheckSecondaryAPICAFile = "check.secondary.api_ca_file"
+
+	// KeyCheckSpoolDir directory where metric submissions which failed are
+	// spooled and retried by subsequent submissions (empty disables spooling)
+	KeyCheckSpoolDir = "check.spool.dir"
+
+	// KeyCheckSpoolMaxAge spooled submissions older than max age are dropped
+	KeyCheckSpoolMaxAge = "check.spool.max_age"
+
+	// KeyCheckSpoolMaxSize oldest spooled submissions are dropped when the spool exceeds max size
+	KeyCheckSpoolMaxSize = "check.spool.max_size"
+
 	cosiName = "cosi"
 )
 
@@ -0,0 +1,224 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package inherit manages the listeners handed from one agent process to
+// the next during a graceful restart (see Restart). A restarted agent uses
+// the inherited listeners, matched by network and address, instead of
+// creating new ones, so no connections or packets are refused while the
+// new binary starts.
+package inherit
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// envListeners names the inherited listeners, in file descriptor order starting at firstFD
+	envListeners = release.ENVPREFIX + "_INHERIT_LISTENERS"
+	// envNotifyFD is the file descriptor the new agent uses to signal it is ready
+	envNotifyFD = release.ENVPREFIX + "_INHERIT_NOTIFY_FD"
+	// firstFD is the first inherited file descriptor (after stdin, stdout and stderr)
+	firstFD = 3
+)
+
+// listener is an active listener which can be passed to a new agent process
+// (*net.TCPListener, *net.UDPConn, *net.UnixListener)
+type listener interface {
+	File() (*os.File, error)
+}
+
+var (
+	inherited map[string]*os.File     // inherited, not yet claimed, listeners by name
+	active    = map[string]listener{} // listeners passed on restart, by name
+	notify    *os.File                // readiness notification (nil if not restarted)
+	mu        sync.Mutex
+)
+
+func init() {
+	inherited = make(map[string]*os.File)
+
+	names := os.Getenv(envListeners)
+	if names != "" {
+		for i, name := range strings.Split(names, ",") {
+			fd := firstFD + i
+			closeOnExec(fd) // not passed on to plugins
+			inherited[name] = os.NewFile(uintptr(fd), name)
+		}
+	}
+
+	if fd, err := strconv.Atoi(os.Getenv(envNotifyFD)); err == nil && fd >= firstFD {
+		closeOnExec(fd)
+		notify = os.NewFile(uintptr(fd), "notify")
+	}
+
+	os.Unsetenv(envListeners)
+	os.Unsetenv(envNotifyFD)
+}
+
+// ListenTCP returns the inherited tcp listener for the address, if there is
+// one, otherwise a new listener
+func ListenTCP(addr *net.TCPAddr) (*net.TCPListener, error) {
+	name := "tcp:" + addr.String()
+
+	if f := claim(name); f != nil {
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "inherited listener %s", name)
+		}
+		tl, ok := l.(*net.TCPListener)
+		if !ok {
+			l.Close()
+			return nil, errors.Errorf("inherited listener %s, not a tcp listener", name)
+		}
+		register(name, tl)
+		return tl, nil
+	}
+
+	tl, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	register(name, tl)
+	return tl, nil
+}
+
+// ListenUDP returns the inherited udp connection for the address, if there
+// is one, otherwise a new connection
+func ListenUDP(addr *net.UDPAddr) (*net.UDPConn, error) {
+	name := "udp:" + addr.String()
+
+	if f := claim(name); f != nil {
+		c, err := net.FilePacketConn(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "inherited listener %s", name)
+		}
+		uc, ok := c.(*net.UDPConn)
+		if !ok {
+			c.Close()
+			return nil, errors.Errorf("inherited listener %s, not a udp connection", name)
+		}
+		register(name, uc)
+		return uc, nil
+	}
+
+	uc, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	register(name, uc)
+	return uc, nil
+}
+
+// ListenUnix returns the inherited unix socket listener for the address, if
+// there is one, otherwise a new listener
+func ListenUnix(addr *net.UnixAddr) (*net.UnixListener, error) {
+	name := "unix:" + addr.String()
+
+	if f := claim(name); f != nil {
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "inherited listener %s", name)
+		}
+		ul, ok := l.(*net.UnixListener)
+		if !ok {
+			l.Close()
+			return nil, errors.Errorf("inherited listener %s, not a unix listener", name)
+		}
+		register(name, ul)
+		return ul, nil
+	}
+
+	ul, err := net.ListenUnix(addr.Network(), addr)
+	if err != nil {
+		return nil, err
+	}
+	register(name, ul)
+	return ul, nil
+}
+
+// HasUnix returns true if a unix socket listener for the address was inherited
+// (the socket file exists, it belongs to the inherited listener)
+func HasUnix(addr *net.UnixAddr) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := inherited["unix:"+addr.String()]
+	return ok
+}
+
+// Ready signals the previous agent process (if this process was started by
+// a graceful restart) that this process is running, the previous process
+// then stops. Inherited listeners which were not claimed (e.g. the listen
+// address changed) are closed.
+func Ready() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for name, f := range inherited {
+		log.Warn().Str("listener", name).Msg("inherited listener not used, closing")
+		f.Close()
+		delete(inherited, name)
+	}
+
+	if notify == nil {
+		return
+	}
+	if _, err := notify.Write([]byte{1}); err != nil {
+		log.Warn().Err(err).Msg("notifying previous agent process")
+	}
+	notify.Close()
+	notify = nil
+}
+
+// InheritFile returns the file with the name passed by the previous agent
+// process (see PassFile), nil if none
+func InheritFile(name string) *os.File {
+	return claim(name)
+}
+
+// PassFile registers a file (e.g. the locked pid file) to be passed to the
+// new agent process on restart, dup returns a duplicate of the file for
+// each restart (the duplicate is closed once passed)
+func PassFile(name string, dup func() (*os.File, error)) {
+	register(name, fileFunc(dup))
+}
+
+// fileFunc adapts a dup function to the listener interface
+type fileFunc func() (*os.File, error)
+
+// File returns a duplicate of the file
+func (f fileFunc) File() (*os.File, error) {
+	return f()
+}
+
+// claim returns the inherited listener with the name, nil if none
+func claim(name string) *os.File {
+	mu.Lock()
+	defer mu.Unlock()
+	f, ok := inherited[name]
+	if !ok {
+		return nil
+	}
+	delete(inherited, name)
+	log.Info().Str("listener", name).Msg("using inherited listener")
+	return f
+}
+
+// register records a listener to be passed on restart
+func register(name string, l listener) {
+	mu.Lock()
+	active[name] = l
+	mu.Unlock()
+}
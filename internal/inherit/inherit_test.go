@@ -0,0 +1,137 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package inherit
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestListenTCP(t *testing.T) {
+	t.Log("Testing ListenTCP")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tnew listener")
+	{
+		l, err := ListenTCP(&net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer l.Close()
+
+		if _, ok := active["tcp:127.0.0.1:0"]; !ok {
+			t.Fatalf("expected listener to be registered (%v)", active)
+		}
+	}
+
+	t.Log("\tinherited listener")
+	{
+		orig, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer orig.Close()
+		addr := orig.Addr().(*net.TCPAddr)
+		f, err := orig.File()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		name := "tcp:" + addr.String()
+		inherited[name] = f
+
+		l, err := ListenTCP(addr)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer l.Close()
+
+		if l.Addr().String() != addr.String() {
+			t.Fatalf("expected (%s) got (%s)", addr, l.Addr())
+		}
+		if _, ok := inherited[name]; ok {
+			t.Fatal("expected inherited listener to be claimed")
+		}
+		if _, ok := active[name]; !ok {
+			t.Fatal("expected listener to be registered")
+		}
+	}
+}
+
+func TestListenUDP(t *testing.T) {
+	t.Log("Testing ListenUDP")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	orig, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer orig.Close()
+	addr := orig.LocalAddr().(*net.UDPAddr)
+	f, err := orig.File()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	inherited["udp:"+addr.String()] = f
+
+	c, err := ListenUDP(addr)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer c.Close()
+
+	if c.LocalAddr().String() != addr.String() {
+		t.Fatalf("expected (%s) got (%s)", addr, c.LocalAddr())
+	}
+}
+
+func TestReady(t *testing.T) {
+	t.Log("Testing Ready")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer r.Close()
+
+	orig, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer orig.Close()
+	f, err := orig.File()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	inherited["tcp:unused"] = f
+	notify = w
+
+	Ready()
+
+	if len(inherited) != 0 {
+		t.Fatalf("expected unclaimed listeners to be closed (%v)", inherited)
+	}
+	if notify != nil {
+		t.Fatal("expected notify to be nil")
+	}
+
+	buf := make([]byte, 2)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 byte, got %d", n)
+	}
+}
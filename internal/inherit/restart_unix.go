@@ -0,0 +1,135 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package inherit
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Restart starts a new agent process, the current executable (which may have
+// been replaced by a new version) with the same arguments and environment,
+// passing it the active listeners. Restart waits, up to timeout, for the new
+// process to signal it is ready (see Ready) and returns its pid. The caller
+// is responsible for stopping the current process. If the new process exits
+// or does not become ready, it is killed and an error is returned, the
+// current process continues to run.
+func Restart(timeout time.Duration) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, errors.Wrap(err, "locating agent executable")
+	}
+
+	names := make([]string, 0, len(active))
+	for name := range active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]*os.File, 0, len(names)+1)
+	defer func() {
+		for _, f := range files {
+			f.Close() // the new process has its own copies
+		}
+	}()
+	passed := make([]string, 0, len(names))
+	for _, name := range names {
+		f, err := active[name].File()
+		if err != nil {
+			log.Warn().Err(err).Str("listener", name).Msg("not passing listener (closed?)")
+			continue
+		}
+		files = append(files, f)
+		passed = append(passed, name)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, errors.Wrap(err, "creating notification pipe")
+	}
+	defer r.Close()
+	files = append(files, w)
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, envListeners+"=") || strings.HasPrefix(e, envNotifyFD+"=") {
+			continue
+		}
+		env = append(env, e)
+	}
+	env = append(env,
+		envListeners+"="+strings.Join(passed, ","),
+		envNotifyFD+"="+strconv.Itoa(firstFD+len(passed)))
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = env
+	cmd.ExtraFiles = files
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	log.Info().Str("cmd", exe).Strs("listeners", passed).Msg("starting new agent process")
+
+	if err := cmd.Start(); err != nil {
+		return 0, errors.Wrap(err, "starting new agent process")
+	}
+	w.Close() // only the new process holds the write end, EOF if it exits
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if n, err := r.Read(buf); err != nil || n != 1 {
+			ready <- errors.New("new agent process exited before it was ready")
+			return
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(timeout):
+		err = errors.Errorf("new agent process not ready after %s", timeout)
+	}
+
+	if err != nil {
+		cmd.Process.Kill()
+		go cmd.Wait()
+		return 0, err
+	}
+
+	// the current process is stopping, its unix sockets now belong to the
+	// new process and must not be removed when the listeners are closed
+	for _, l := range active {
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+
+	return pid, nil
+}
+
+// closeOnExec prevents an inherited file descriptor from being passed to
+// processes started by the agent (e.g. plugins)
+func closeOnExec(fd int) {
+	syscall.CloseOnExec(fd)
+}
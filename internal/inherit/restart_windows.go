@@ -0,0 +1,22 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package inherit
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Restart is not supported on windows
+func Restart(timeout time.Duration) (int, error) {
+	return 0, errors.New("graceful restart not supported on windows")
+}
+
+// closeOnExec is a no-op, listeners are not inherited on windows
+func closeOnExec(fd int) {}
@@ -0,0 +1,206 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// pluginConfigExts are the plugin config file extensions, in the order checked
+var pluginConfigExts = []string{".json", ".toml", ".yaml"}
+
+// pluginConfigVersion is the structured plugin config format version, the
+// original json instance map is version 1. A json config is structured only
+// if it has a numeric version (instances may be named for any setting).
+const pluginConfigVersion = 2
+
+// pluginConfigFile defines a structured plugin config file
+//
+//	version   - config format version, required in json files (2)
+//	args      - arguments passed to the plugin (instance arguments are appended)
+//	env       - environment variables (instance env overrides)
+//	instances - map of instance id to args and/or env, one instance of the plugin is run for each
+//	timeout   - execution timeout (e.g. 30s)
+//	ttl       - run ttl (e.g. 5m, without units the --plugin-ttl-units are used)
+type pluginConfigFile struct {
+	Version   int                       `json:"version" toml:"version" yaml:"version"`
+	Args      []string                  `json:"args" toml:"args" yaml:"args"`
+	Env       map[string]string         `json:"env" toml:"env" yaml:"env"`
+	Instances map[string]instanceConfig `json:"-" toml:"instances" yaml:"instances"`
+	Timeout   string                    `json:"timeout" toml:"timeout" yaml:"timeout"`
+	TTL       string                    `json:"ttl" toml:"ttl" yaml:"ttl"`
+}
+
+// pluginConfig is the parsed configuration of a plugin from its config file
+type pluginConfig struct {
+	file      string
+	instances map[string]instanceConfig // nil, a single (un-named) instance is run
+	timeout   time.Duration             // 0 = not set, agent default is used
+	ttl       time.Duration             // 0 = not set, ttl from the file name (if any) is used
+}
+
+// loadPluginConfig finds and parses the config file for a plugin,
+// <base_name>.json, .toml or .yaml in the plugin directory. Returns
+// nil if there is no config file (or the file is empty).
+func (p *Plugins) loadPluginConfig(fileBase string) (*pluginConfig, error) {
+	file := ""
+	for _, ext := range pluginConfigExts {
+		f := filepath.Join(p.pluginDir, fileBase+ext)
+		if _, err := os.Stat(f); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrap(err, "plugin config")
+		}
+		if file != "" {
+			return nil, errors.Errorf("multiple plugin config files (%s, %s)", filepath.Base(file), filepath.Base(f))
+		}
+		file = f
+	}
+	if file == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading plugin config")
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+
+	var cfg pluginConfigFile
+	switch filepath.Ext(file) {
+	case ".json":
+		err = parseJSONPluginConfig(data, &cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, &cfg)
+	case ".yaml":
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing plugin config (%s)", file)
+	}
+
+	pcfg, err := cfg.parse()
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin config (%s)", file)
+	}
+	pcfg.file = file
+
+	return pcfg, nil
+}
+
+// parseJSONPluginConfig parses a json plugin config, either a structured
+// config (with a numeric version) or an instance map (see parsePluginConfig)
+func parseJSONPluginConfig(data []byte, cfg *pluginConfigFile) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// an instance map value is a list of arguments or an object, never a
+	// number, so an instance named "version" is not mistaken for the version
+	var version int
+	if err := json.Unmarshal(raw["version"], &version); err != nil {
+		instances, err := parsePluginConfig(data)
+		if err != nil {
+			return err
+		}
+		cfg.Instances = instances
+		return nil
+	}
+
+	if version != pluginConfigVersion {
+		return errors.Errorf("unsupported config version (%d)", version)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return err
+	}
+	if instances, ok := raw["instances"]; ok {
+		icfgs, err := parsePluginConfig(instances)
+		if err != nil {
+			return errors.Wrap(err, "instances")
+		}
+		cfg.Instances = icfgs
+	}
+
+	return nil
+}
+
+// parse validates the settings in a plugin config file, the plugin level
+// args and env are applied to each instance
+func (cfg *pluginConfigFile) parse() (*pluginConfig, error) {
+	pcfg := &pluginConfig{}
+
+	// optional in toml and yaml, there is no other format to distinguish
+	if cfg.Version != 0 && cfg.Version != pluginConfigVersion {
+		return nil, errors.Errorf("unsupported config version (%d)", cfg.Version)
+	}
+
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing timeout")
+		}
+		pcfg.timeout = d
+	}
+
+	if cfg.TTL != "" {
+		d, err := parseTTL(cfg.TTL)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing ttl")
+		}
+		pcfg.ttl = d
+	}
+
+	if len(cfg.Instances) == 0 {
+		if len(cfg.Args) > 0 || len(cfg.Env) > 0 {
+			pcfg.instances = map[string]instanceConfig{"": {Args: cfg.Args, Env: cfg.Env}}
+		}
+		return pcfg, nil
+	}
+
+	pcfg.instances = make(map[string]instanceConfig, len(cfg.Instances))
+	for inst, icfg := range cfg.Instances {
+		if inst == "" {
+			return nil, errors.New("invalid instance id (empty)")
+		}
+		args := make([]string, 0, len(cfg.Args)+len(icfg.Args))
+		args = append(args, cfg.Args...)
+		args = append(args, icfg.Args...)
+		env := make(map[string]string, len(cfg.Env)+len(icfg.Env))
+		for k, v := range cfg.Env {
+			env[k] = v
+		}
+		for k, v := range icfg.Env {
+			env[k] = v
+		}
+		pcfg.instances[inst] = instanceConfig{Args: args, Env: env}
+	}
+
+	return pcfg, nil
+}
+
+// isPluginConfigExt determines if a file extension is a plugin config file
+func isPluginConfigExt(ext string) bool {
+	for _, e := range pluginConfigExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
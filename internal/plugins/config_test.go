@@ -0,0 +1,150 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestLoadPluginConfig(t *testing.T) {
+	t.Log("Testing loadPluginConfig")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	p := &Plugins{pluginDir: "testdata/config"}
+
+	t.Log("\tnone")
+	{
+		cfg, err := p.loadPluginConfig("missing")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if cfg != nil {
+			t.Fatalf("expected nil, got (%#v)", cfg)
+		}
+	}
+
+	t.Log("\tempty")
+	{
+		cfg, err := p.loadPluginConfig("empty")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if cfg != nil {
+			t.Fatalf("expected nil, got (%#v)", cfg)
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		for _, name := range []string{"multi", "badttl", "bad", "badinst", "badversion"} {
+			if _, err := p.loadPluginConfig(name); err == nil {
+				t.Fatalf("%s expected error", name)
+			}
+		}
+	}
+
+	t.Log("\tjson (instance map)")
+	{
+		cfg, err := p.loadPluginConfig("legacy")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := map[string]instanceConfig{
+			"inst1": {Args: []string{"a", "b"}, Env: map[string]string{}},
+			"inst2": {Args: []string{"c"}, Env: map[string]string{"FOO": "bar"}},
+		}
+		if !reflect.DeepEqual(cfg.instances, expect) {
+			t.Fatalf("expected (%#v) got (%#v)", expect, cfg.instances)
+		}
+		if cfg.timeout != 0 || cfg.ttl != 0 {
+			t.Fatalf("expected no timeout/ttl, got %s/%s", cfg.timeout, cfg.ttl)
+		}
+	}
+
+	t.Log("\tjson (instance map, instances named for settings)")
+	{
+		cfg, err := p.loadPluginConfig("legacykeys")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := map[string]instanceConfig{
+			"args":    {Args: []string{"a"}, Env: map[string]string{}},
+			"timeout": {Args: []string{"b"}, Env: map[string]string{}},
+			"version": {Args: []string{"c"}, Env: map[string]string{}},
+		}
+		if !reflect.DeepEqual(cfg.instances, expect) {
+			t.Fatalf("expected (%#v) got (%#v)", expect, cfg.instances)
+		}
+		if cfg.timeout != 0 {
+			t.Fatalf("expected no timeout, got %s", cfg.timeout)
+		}
+	}
+
+	t.Log("\tjson (structured)")
+	{
+		cfg, err := p.loadPluginConfig("structured")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := map[string]instanceConfig{
+			"inst1": {Args: []string{"-v", "a"}, Env: map[string]string{"FOO": "bar", "BAZ": "qux"}},
+			"inst2": {Args: []string{"-v", "b"}, Env: map[string]string{"FOO": "override", "BAZ": "qux"}},
+		}
+		if !reflect.DeepEqual(cfg.instances, expect) {
+			t.Fatalf("expected (%#v) got (%#v)", expect, cfg.instances)
+		}
+		if cfg.timeout != 30*time.Second {
+			t.Fatalf("expected 30s, got %s", cfg.timeout)
+		}
+		if cfg.ttl != 5*time.Minute {
+			t.Fatalf("expected 5m, got %s", cfg.ttl)
+		}
+	}
+
+	t.Log("\ttoml")
+	{
+		cfg, err := p.loadPluginConfig("inst")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := map[string]instanceConfig{
+			"inst1": {Args: []string{"-v", "a"}, Env: map[string]string{"FOO": "bar"}},
+			"inst2": {Args: []string{"-v", "b"}, Env: map[string]string{"FOO": "override"}},
+		}
+		if !reflect.DeepEqual(cfg.instances, expect) {
+			t.Fatalf("expected (%#v) got (%#v)", expect, cfg.instances)
+		}
+		if cfg.timeout != 10*time.Second {
+			t.Fatalf("expected 10s, got %s", cfg.timeout)
+		}
+	}
+
+	t.Log("\tyaml (single instance)")
+	{
+		cfg, err := p.loadPluginConfig("single")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		expect := map[string]instanceConfig{
+			"": {Args: []string{"-v", "--all"}, Env: map[string]string{"FOO": "bar"}},
+		}
+		if !reflect.DeepEqual(cfg.instances, expect) {
+			t.Fatalf("expected (%#v) got (%#v)", expect, cfg.instances)
+		}
+		if cfg.ttl != 30*time.Second {
+			t.Fatalf("expected 30s, got %s", cfg.ttl)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,258 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/maier/go-appstats"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// SetStatsdReceiver sets the receiver for the lines read from statsd
+// format named pipe plugins, must be called before Scan. Without a
+// receiver (statsd disabled) statsd format named pipe plugins are not
+// activated.
+func (p *Plugins) SetStatsdReceiver(r PacketReceiver) {
+	p.Lock()
+	defer p.Unlock()
+	p.statsd = r
+}
+
+// loadFIFOSources parses the named pipe plugin sources
+func (p *Plugins) loadFIFOSources() error {
+	var cfgs map[string]config.PluginFIFO
+	if err := viper.UnmarshalKey(config.KeyPluginFIFO, &cfgs); err != nil {
+		return errors.Wrap(err, "parsing fifo plugins")
+	}
+
+	p.fifo = make(map[string]fifoSource, len(cfgs))
+	for name, cfg := range cfgs {
+		if name == "" || strings.Contains(name, metricDelimiter) {
+			return errors.Errorf("invalid fifo plugin name (%s)", name)
+		}
+
+		if cfg.Path == "" {
+			return errors.Errorf("invalid fifo plugin path for %s (empty)", name)
+		}
+
+		src := fifoSource{
+			format: cfg.Format,
+			path:   cfg.Path,
+		}
+		switch src.format {
+		case "":
+			src.format = fifoFormatPlugin
+		case fifoFormatPlugin, fifoFormatStatsd:
+		default:
+			return errors.Errorf("invalid fifo plugin format for %s (%s), must be %s or %s", name, cfg.Format, fifoFormatPlugin, fifoFormatStatsd)
+		}
+
+		p.fifo[name] = src
+	}
+
+	return nil
+}
+
+// configureFIFOPlugins activates the named pipe plugin sources as virtual
+// persistent plugins, sources no longer configured (or changed) are
+// deactivated. A plugin in the plugin directory (or an http plugin) with the
+// same name takes precedence. NOTE: caller must hold lock.
+func (p *Plugins) configureFIFOPlugins(b *builtins.Builtins) {
+	seen := make(map[string]bool)
+
+	for name, src := range p.fifo {
+		if _, reserved := p.reservedNames[name]; reserved {
+			p.logger.Warn().Str("id", name).Msg("reserved plugin name, ignoring fifo plugin")
+			continue
+		}
+
+		if b != nil && b.IsBuiltin(name) {
+			p.logger.Warn().Str("id", name).Msg("Builtin collector already enabled, skipping fifo plugin")
+			continue
+		}
+
+		if src.format == fifoFormatStatsd && p.statsd == nil {
+			p.logger.Error().Str("id", name).Str("path", src.path).Msg("statsd disabled, not activating statsd format fifo plugin")
+			continue
+		}
+
+		plug, ok := p.active[name]
+		if ok && plug.fifo == nil {
+			p.logger.Warn().Str("id", name).Msg("plugin with the same name already active, ignoring fifo plugin")
+			continue
+		}
+		if ok && *plug.fifo != src {
+			// path or format changed, stop reading the previous pipe
+			plug.cancel()
+			delete(p.active, name)
+			ok = false
+		}
+		if !ok {
+			ctx, cancel := context.WithCancel(p.ctx)
+			p.active[name] = &plugin{
+				cancel: cancel,
+				ctx:    ctx,
+				id:     name,
+				name:   name,
+				logger: p.logger.With().Str("plugin", name).Logger(),
+			}
+			plug = p.active[name]
+		}
+		seen[name] = true
+
+		appstats.MapIncrementInt("plugins", "total")
+		fifo := src
+		plug.Lock()
+		plug.command = src.path // reported in the inventory and state
+		plug.fifo = &fifo
+		plug.persistent = true // started once by Scan, see execFIFO
+		plug.statsd = p.statsd
+		plug.Unlock()
+		p.logger.Info().
+			Str("id", name).
+			Str("path", src.path).
+			Str("format", src.format).
+			Msg("Activating fifo plugin")
+	}
+
+	for id, plug := range p.active {
+		if plug.fifo == nil || seen[id] {
+			continue
+		}
+		p.logger.Info().Str("id", id).Msg("Deactivating fifo plugin, no longer configured")
+		if plug.cancel != nil {
+			plug.cancel() // stop reading
+		}
+		delete(p.active, id)
+	}
+}
+
+// execFIFO reads metrics from a named pipe plugin source until the plugin
+// is stopped, the pipe is re-opened each time the last writer closes it.
+// Called from exec with the plugin marked as running, supervised by
+// runPersistent (an error, e.g. the pipe does not exist, is retried).
+func (p *plugin) execFIFO() error {
+	p.Lock()
+	plog := p.logger
+	ctx := p.ctx
+	src := *p.fifo
+	receiver := p.statsd
+	p.Unlock()
+
+	resetStatus := func(err error) {
+		p.Lock()
+		p.lastEnd = time.Now()
+		p.lastRunDuration = time.Since(p.lastStart)
+		p.lastError = err
+		if err != nil {
+			p.lastExitCode = -1
+			p.runsFailed++
+		} else {
+			p.lastExitCode = 0
+			p.runsOK++
+		}
+		p.running = false
+		p.Unlock()
+	}
+
+	if src.format == fifoFormatStatsd && receiver == nil {
+		err := errors.New("statsd not available")
+		plog.Error().Err(err).Str("path", src.path).Msg("fifo plugin")
+		resetStatus(err)
+		return err
+	}
+
+	for {
+		f, err := openFIFO(ctx, src.path)
+		if ctx.Err() != nil {
+			resetStatus(nil)
+			return nil
+		}
+		if err != nil {
+			plog.Error().Err(err).Str("path", src.path).Msg("opening fifo")
+			resetStatus(err)
+			return err
+		}
+
+		err = p.readFIFO(ctx, f, src.format, receiver)
+		f.Close()
+		if ctx.Err() != nil {
+			resetStatus(nil)
+			return nil
+		}
+		if err != nil {
+			plog.Error().Err(err).Str("path", src.path).Msg("reading fifo")
+			resetStatus(err)
+			return err
+		}
+
+		plog.Debug().Str("path", src.path).Msg("fifo closed by writer, re-opening")
+	}
+}
+
+// readFIFO reads lines from the named pipe until the last writer closes it
+// or the context is done. Lines in the plugin format are handled as the
+// output of a persistent plugin, statsd lines are passed to the receiver.
+func (p *plugin) readFIFO(ctx context.Context, f *os.File, format string, receiver PacketReceiver) error {
+	// closing the pipe unblocks a pending read once the context is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			f.Close()
+		case <-done:
+		}
+	}()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if format == fifoFormatStatsd {
+			if err := receiver.ProcessPacket([]byte(line)); err != nil {
+				return errors.Wrap(err, "statsd")
+			}
+			continue
+		}
+
+		// blank line, parse the json received so far
+		if line == "" {
+			if len(lines) > 0 {
+				p.parsePluginOutput(lines)
+				lines = []string{}
+			}
+			continue
+		}
+
+		// tab-delimited lines are handled as they arrive (json is buffered until a blank line)
+		if len(lines) == 0 && !strings.HasPrefix(strings.TrimSpace(line), "{") {
+			p.parsePluginOutput([]string{line})
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(lines) > 0 {
+		p.parsePluginOutput(lines)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return scanner.Err()
+}
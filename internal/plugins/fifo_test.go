@@ -0,0 +1,296 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package plugins
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+type testReceiver struct {
+	sync.Mutex
+	pkts []string
+}
+
+func (r *testReceiver) ProcessPacket(pkt []byte) error {
+	r.Lock()
+	defer r.Unlock()
+	r.pkts = append(r.pkts, string(pkt))
+	return nil
+}
+
+func (r *testReceiver) count() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.pkts)
+}
+
+func TestLoadFIFOSources(t *testing.T) {
+	t.Log("Testing loadFIFOSources")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("none")
+	{
+		viper.Reset()
+		p := &Plugins{}
+		if err := p.loadFIFOSources(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(p.fifo) != 0 {
+			t.Fatalf("expected no sources, got (%#v)", p.fifo)
+		}
+	}
+
+	t.Log("invalid path")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginFIFO, map[string]interface{}{
+			"app": map[string]interface{}{"format": "statsd"},
+		})
+		p := &Plugins{}
+		if err := p.loadFIFOSources(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid format")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginFIFO, map[string]interface{}{
+			"app": map[string]interface{}{"path": "/tmp/app.fifo", "format": "json"},
+		})
+		p := &Plugins{}
+		if err := p.loadFIFOSources(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid name")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginFIFO, map[string]interface{}{
+			"app`1": map[string]interface{}{"path": "/tmp/app.fifo"},
+		})
+		p := &Plugins{}
+		if err := p.loadFIFOSources(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("valid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginFIFO, map[string]interface{}{
+			"app":   map[string]interface{}{"path": "/tmp/app.fifo"},
+			"other": map[string]interface{}{"path": "/tmp/other.fifo", "format": "statsd"},
+		})
+		p := &Plugins{}
+		if err := p.loadFIFOSources(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if p.fifo["app"].format != fifoFormatPlugin {
+			t.Fatalf("expected default format, got (%#v)", p.fifo["app"])
+		}
+		if p.fifo["other"].format != fifoFormatStatsd {
+			t.Fatalf("expected statsd format, got (%#v)", p.fifo["other"])
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestConfigureFIFOPlugins(t *testing.T) {
+	t.Log("Testing configureFIFOPlugins")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	newPlugins := func(r PacketReceiver) *Plugins {
+		return &Plugins{
+			ctx:    context.Background(),
+			active: make(map[string]*plugin),
+			fifo: map[string]fifoSource{
+				"app":   {format: fifoFormatStatsd, path: "/tmp/app.fifo"},
+				"other": {format: fifoFormatPlugin, path: "/tmp/other.fifo"},
+			},
+			reservedNames: make(map[string]bool),
+			statsd:        r,
+		}
+	}
+
+	t.Log("statsd enabled")
+	{
+		p := newPlugins(&testReceiver{})
+		p.configureFIFOPlugins(nil)
+		if _, ok := p.active["app"]; !ok {
+			t.Fatal("expected app to be active")
+		}
+		if _, ok := p.active["other"]; !ok {
+			t.Fatal("expected other to be active")
+		}
+	}
+
+	t.Log("statsd disabled, statsd format not activated")
+	{
+		p := newPlugins(nil)
+		p.configureFIFOPlugins(nil)
+		if _, ok := p.active["app"]; ok {
+			t.Fatal("expected app NOT to be active")
+		}
+		if _, ok := p.active["other"]; !ok {
+			t.Fatal("expected other to be active")
+		}
+	}
+}
+
+func TestExecFIFO(t *testing.T) {
+	t.Log("Testing exec w/fifo plugin")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "fifo")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	newPlugin := func(ctx context.Context, path, format string, r PacketReceiver) *plugin {
+		return &plugin{
+			ctx:        ctx,
+			id:         "app",
+			name:       "app",
+			fifo:       &fifoSource{format: format, path: path},
+			persistent: true,
+			statsd:     r,
+		}
+	}
+
+	write := func(path, data string) {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		f.Close()
+	}
+
+	t.Log("not a fifo")
+	{
+		path := filepath.Join(dir, "file")
+		if err := ioutil.WriteFile(path, []byte("foo\ti\t1\n"), 0644); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		p := newPlugin(context.Background(), path, fifoFormatPlugin, nil)
+		if err := p.exec(); err == nil {
+			t.Fatal("expected error")
+		}
+		if p.runsFailed != 1 || p.lastExitCode != -1 {
+			t.Fatalf("expected 1 failed run, got failed=%d code=%d", p.runsFailed, p.lastExitCode)
+		}
+	}
+
+	t.Log("statsd format, no receiver")
+	{
+		p := newPlugin(context.Background(), filepath.Join(dir, "missing"), fifoFormatStatsd, nil)
+		if err := p.exec(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("plugin format, re-open after writer closes")
+	{
+		path := filepath.Join(dir, "plugin.fifo")
+		if err := syscall.Mkfifo(path, 0600); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p := newPlugin(ctx, path, fifoFormatPlugin, nil)
+		done := make(chan error, 1)
+		go func() { done <- p.exec() }()
+
+		write(path, "foo\ti\t1\n{\"bar\": {\"_type\": \"L\", \"_value\": 2}}\n\n")
+		write(path, "baz\tn\t1.5\n")
+
+		// metrics are parsed as they are read
+		expect := []string{"foo", "bar", "baz"}
+		var m map[string]bool
+		for i := 0; i < 20; i++ {
+			p.Lock()
+			m = make(map[string]bool)
+			if p.metrics != nil {
+				for name := range *p.metrics {
+					m[name] = true
+				}
+			}
+			p.Unlock()
+			if len(m) == len(expect) {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		for _, name := range expect {
+			if !m[name] {
+				t.Fatalf("expected %s, got (%#v)", name, m)
+			}
+		}
+
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected exec to return once stopped")
+		}
+		if p.running {
+			t.Fatal("expected not running")
+		}
+	}
+
+	t.Log("statsd format")
+	{
+		path := filepath.Join(dir, "statsd.fifo")
+		if err := syscall.Mkfifo(path, 0600); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &testReceiver{}
+		p := newPlugin(ctx, path, fifoFormatStatsd, r)
+		done := make(chan error, 1)
+		go func() { done <- p.exec() }()
+
+		write(path, "foo:1|c\nbar:2|g\n")
+
+		for i := 0; i < 20 && r.count() < 2; i++ {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if r.count() != 2 {
+			t.Fatalf("expected 2 packets, got (%#v)", r.pkts)
+		}
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected exec to return once stopped")
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package plugins
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// openFIFO opens a named pipe for reading, blocking until a writer opens
+// it or the context is done
+func openFIFO(ctx context.Context, path string) (*os.File, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return nil, errors.Errorf("not a named pipe (%s)", path)
+	}
+
+	// the open cannot be interrupted, once the context is done the pipe is
+	// opened for writing (non-blocking) until the pending open returns
+	opened := make(chan struct{})
+	defer close(opened)
+	go func() {
+		select {
+		case <-opened:
+			return
+		case <-ctx.Done():
+		}
+		for {
+			if w, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+				w.Close()
+			}
+			select {
+			case <-opened:
+				return
+			case <-time.After(fifoUnblockInterval):
+			}
+		}
+	}()
+
+	f, err := os.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		f.Close()
+		return nil, ctx.Err()
+	}
+
+	return f, nil
+}
@@ -0,0 +1,20 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package plugins
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// openFIFO named pipe plugins are not supported on windows
+func openFIFO(ctx context.Context, path string) (*os.File, error) {
+	return nil, errors.New("fifo plugins not supported on windows")
+}
@@ -0,0 +1,224 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/maier/go-appstats"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// loadHTTPSources parses the http json plugin sources
+func (p *Plugins) loadHTTPSources() error {
+	var cfgs map[string]config.PluginHTTP
+	if err := viper.UnmarshalKey(config.KeyPluginHTTP, &cfgs); err != nil {
+		return errors.Wrap(err, "parsing http plugins")
+	}
+
+	p.http = make(map[string]httpSource, len(cfgs))
+	for name, cfg := range cfgs {
+		if name == "" || strings.Contains(name, metricDelimiter) {
+			return errors.Errorf("invalid http plugin name (%s)", name)
+		}
+
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return errors.Wrapf(err, "parsing http plugin url for %s", name)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("invalid http plugin url for %s (%s), must be http(s)://host[:port]/path", name, config.RedactURL(cfg.URL))
+		}
+
+		src := httpSource{
+			headers: cfg.Headers,
+			timeout: httpTimeout,
+			url:     u.String(),
+		}
+
+		if cfg.Interval != "" {
+			d, err := parseTTL(cfg.Interval)
+			if err != nil {
+				return errors.Wrapf(err, "parsing http plugin interval for %s", name)
+			}
+			src.interval = d
+		}
+
+		if cfg.Timeout != "" {
+			d, err := time.ParseDuration(cfg.Timeout)
+			if err != nil {
+				return errors.Wrapf(err, "parsing http plugin timeout for %s", name)
+			}
+			if d > 0 {
+				src.timeout = d
+			}
+		}
+
+		p.http[name] = src
+	}
+
+	return nil
+}
+
+// configureHTTPPlugins activates the http json plugin sources as virtual plugins,
+// sources no longer configured are deactivated. A plugin in the plugin directory
+// with the same name takes precedence. NOTE: caller must hold lock.
+func (p *Plugins) configureHTTPPlugins(b *builtins.Builtins) {
+	seen := make(map[string]bool)
+
+	for name, src := range p.http {
+		if _, reserved := p.reservedNames[name]; reserved {
+			p.logger.Warn().Str("id", name).Msg("reserved plugin name, ignoring http plugin")
+			continue
+		}
+
+		if b != nil && b.IsBuiltin(name) {
+			p.logger.Warn().Str("id", name).Msg("Builtin collector already enabled, skipping http plugin")
+			continue
+		}
+
+		plug, ok := p.active[name]
+		if ok && plug.url == "" {
+			p.logger.Warn().Str("id", name).Msg("plugin directory has a plugin with the same name, ignoring http plugin")
+			continue
+		}
+		if !ok {
+			ctx, cancel := context.WithCancel(p.ctx)
+			p.active[name] = &plugin{
+				cancel: cancel,
+				ctx:    ctx,
+				id:     name,
+				name:   name,
+				logger: p.logger.With().Str("plugin", name).Logger(),
+			}
+			plug = p.active[name]
+		}
+		seen[name] = true
+
+		appstats.MapIncrementInt("plugins", "total")
+		plug.Lock()
+		plug.command = config.RedactURL(src.url) // reported in the inventory and state
+		plug.url = src.url
+		plug.headers = src.headers
+		plug.runTTL = p.pluginTTL(src.interval, name)
+		plug.timeout = src.timeout
+		plug.Unlock()
+		p.logger.Info().
+			Str("id", name).
+			Str("url", config.RedactURL(src.url)).
+			Msg("Activating http plugin")
+	}
+
+	for id, plug := range p.active {
+		if plug.url == "" || seen[id] {
+			continue
+		}
+		p.logger.Info().Str("id", id).Msg("Deactivating http plugin, no longer configured")
+		if plug.cancel != nil {
+			plug.cancel() // abort, if running
+		}
+		delete(p.active, id)
+	}
+}
+
+// execHTTP fetches metrics from an http json plugin source, called
+// from exec with the plugin marked as running
+func (p *plugin) execHTTP() error {
+	p.Lock()
+	plog := p.logger
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+	timeout := p.timeout
+	srcURL := p.url
+	headers := p.headers
+	p.Unlock()
+	defer cancel()
+
+	resetStatus := func(err error) {
+		p.Lock()
+		p.lastEnd = time.Now()
+		p.lastRunDuration = time.Since(p.lastStart)
+		p.lastError = err
+		if err != nil {
+			p.lastExitCode = -1
+			p.runsFailed++
+		} else {
+			p.lastExitCode = 0
+			p.runsOK++
+		}
+		p.running = false
+		p.Unlock()
+	}
+
+	body, err := fetchHTTP(ctx, srcURL, headers)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			appstats.MapIncrementInt("plugins", "timeouts")
+			err = errors.Errorf("timed out after %s", timeout)
+		}
+		plog.Error().Err(err).Str("url", config.RedactURL(srcURL)).Msg("fetching metrics")
+		resetStatus(err)
+		return err
+	}
+
+	output := strings.TrimSpace(string(body))
+	if !strings.HasPrefix(output, "{") {
+		err := errors.New("invalid response, expected json metrics")
+		plog.Error().Err(err).Str("url", config.RedactURL(srcURL)).Msg("parsing metrics")
+		p.Lock()
+		p.saveMetrics(cgm.Metrics{})
+		p.Unlock()
+		resetStatus(err)
+		return err
+	}
+
+	err = p.parsePluginOutput(strings.Split(output, "\n"))
+	resetStatus(err)
+	return err
+}
+
+// fetchHTTP requests url and returns the response body, non-200 responses are errors
+func fetchHTTP(ctx context.Context, srcURL string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest("GET", srcURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating request")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", release.NAME+"/"+release.VERSION)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(config.RedactError(err), "request")
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, httpMaxResponseSize+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading response")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("response status %s", resp.Status)
+	}
+	if len(body) > httpMaxResponseSize {
+		return nil, errors.Errorf("response exceeds %d bytes", httpMaxResponseSize)
+	}
+
+	return body, nil
+}
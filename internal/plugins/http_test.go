@@ -0,0 +1,269 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestLoadHTTPSources(t *testing.T) {
+	t.Log("Testing loadHTTPSources")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("none")
+	{
+		viper.Reset()
+		p := &Plugins{}
+		if err := p.loadHTTPSources(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(p.http) != 0 {
+			t.Fatalf("expected no sources, got (%#v)", p.http)
+		}
+	}
+
+	t.Log("invalid url")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginHTTP, map[string]interface{}{
+			"app": map[string]interface{}{"url": "ftp://127.0.0.1/metrics"},
+		})
+		p := &Plugins{}
+		if err := p.loadHTTPSources(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid interval")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginHTTP, map[string]interface{}{
+			"app": map[string]interface{}{"url": "http://127.0.0.1/metrics", "interval": "abc"},
+		})
+		p := &Plugins{}
+		if err := p.loadHTTPSources(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid name")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginHTTP, map[string]interface{}{
+			"app`1": map[string]interface{}{"url": "http://127.0.0.1/metrics"},
+		})
+		p := &Plugins{}
+		if err := p.loadHTTPSources(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("valid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginHTTP, map[string]interface{}{
+			"app": map[string]interface{}{
+				"url":      "http://127.0.0.1/metrics",
+				"interval": "30s",
+				"timeout":  "2s",
+				"headers":  map[string]interface{}{"X-Token": "abc"},
+			},
+			"other": map[string]interface{}{"url": "https://127.0.0.1/metrics"},
+		})
+		p := &Plugins{}
+		if err := p.loadHTTPSources(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		src, ok := p.http["app"]
+		if !ok {
+			t.Fatalf("expected app, got (%#v)", p.http)
+		}
+		if src.interval != 30*time.Second {
+			t.Fatalf("expected 30s interval, got %s", src.interval)
+		}
+		if src.timeout != 2*time.Second {
+			t.Fatalf("expected 2s timeout, got %s", src.timeout)
+		}
+		if len(src.headers) != 1 {
+			t.Fatalf("expected 1 header, got (%#v)", src.headers)
+		}
+		if p.http["other"].timeout != httpTimeout {
+			t.Fatalf("expected default timeout, got %s", p.http["other"].timeout)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestExecHTTP(t *testing.T) {
+	t.Log("Testing exec w/http plugin")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metrics":
+			if r.Header.Get("X-Token") != "abc" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintln(w, `{"requests": {"_type": "counter", "_value": 10}, "temp": {"_type": "gauge", "_value": 1.5, "_tags": ["zone:a"]}}`)
+		case "/text":
+			fmt.Fprintln(w, "not json")
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+			fmt.Fprintln(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	newPlugin := func(path string, headers map[string]string) *plugin {
+		return &plugin{
+			ctx:     context.Background(),
+			id:      "app",
+			name:    "app",
+			url:     ts.URL + path,
+			headers: headers,
+			timeout: httpTimeout,
+		}
+	}
+
+	t.Log("valid")
+	{
+		p := newPlugin("/metrics", map[string]string{"X-Token": "abc"})
+		if err := p.exec(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		m := *p.drain()
+		if v, ok := m["requests"]; !ok || v.Value != uint64(10) {
+			t.Fatalf("expected requests=10, got (%#v)", m)
+		}
+		if _, ok := m["temp|ST[zone:a]"]; !ok {
+			t.Fatalf("expected tagged temp, got (%#v)", m)
+		}
+		if p.runsOK != 1 || p.lastExitCode != 0 {
+			t.Fatalf("expected 1 ok run, got ok=%d code=%d", p.runsOK, p.lastExitCode)
+		}
+	}
+
+	t.Log("non-200")
+	{
+		p := newPlugin("/metrics", nil)
+		if err := p.exec(); err == nil {
+			t.Fatal("expected error")
+		}
+		if p.runsFailed != 1 || p.lastExitCode != -1 {
+			t.Fatalf("expected 1 failed run, got failed=%d code=%d", p.runsFailed, p.lastExitCode)
+		}
+	}
+
+	t.Log("not json")
+	{
+		p := newPlugin("/text", nil)
+		if err := p.exec(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("timeout")
+	{
+		p := newPlugin("/slow", nil)
+		p.timeout = 100 * time.Millisecond
+		err := p.exec()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != "timed out after 100ms" {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+}
+
+func TestConfigureHTTPPlugins(t *testing.T) {
+	t.Log("Testing configureHTTPPlugins")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"requests": {"_type": "L", "_value": 1}}`)
+	}))
+	defer ts.Close()
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, "testdata/missing")
+	viper.Set(config.KeyPluginHTTP, map[string]interface{}{
+		"app":    map[string]interface{}{"url": ts.URL, "interval": "1m"},
+		"statsd": map[string]interface{}{"url": ts.URL},
+	})
+	p, err := New(context.Background())
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Log("activate, no plugin directory")
+	{
+		if err := p.Scan(nil); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if !p.IsValid("app") {
+			t.Fatal("expected app to be active")
+		}
+		if p.IsValid("statsd") {
+			t.Fatal("expected reserved name to be ignored")
+		}
+		plug := p.active["app"]
+		plug.Lock()
+		ttl := plug.runTTL
+		plug.Unlock()
+		if ttl != time.Minute {
+			t.Fatalf("expected 1m ttl, got %s", ttl)
+		}
+	}
+
+	t.Log("initial run and flush")
+	{
+		// Scan fires the initial run
+		plug := p.active["app"]
+		for i := 0; i < 20; i++ {
+			plug.Lock()
+			done := !plug.lastEnd.IsZero()
+			plug.Unlock()
+			if done {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		m := *p.Flush("app")
+		if _, ok := m["app`requests"]; !ok {
+			t.Fatalf("expected app`requests, got (%#v)", m)
+		}
+	}
+
+	t.Log("deactivate")
+	{
+		viper.Set(config.KeyPluginHTTP, map[string]interface{}{})
+		if err := p.Reload(nil); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if p.IsValid("app") {
+			t.Fatal("expected app to be deactivated")
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,227 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+)
+
+// influxLineRx matches the shape of an InfluxDB line protocol line,
+// measurement[,tag=value...] field=value[,field=value...] [timestamp]
+var influxLineRx = regexp.MustCompile(`^[^\s,=#"]([^\s,]|\\[\s,])*(,([^\s]|\\\s)+)?\s+([^\s=]|\\[\s=])+=\S`)
+
+// isInfluxOutput reports whether plugin output is in InfluxDB line protocol,
+// based on the first line which is not blank or a comment. Tab delimited
+// output is never line protocol.
+func isInfluxOutput(output []string) bool {
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return !strings.Contains(line, fieldDelimiter) && influxLineRx.MatchString(line)
+	}
+	return false
+}
+
+// parseInfluxOutput parses InfluxDB line protocol plugin output, each field
+// becomes a metric named measurement`field with the tags as stream tags.
+// Timestamps are ignored, metrics are reported when collected. Lines and
+// fields using unsupported features (e.g. boolean fields) are skipped.
+func (p *plugin) parseInfluxOutput(output []string) cgm.Metrics {
+	metrics := cgm.Metrics{}
+	numDuplicates := 0
+	numErrors := 0
+
+	for _, line := range output {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// quotes are literal in the measurement and tags, they only delimit string field values
+		series, rest := cutInfluxSeries(line)
+		sections := append([]string{series}, splitInflux(rest, ' ', true)...)
+		if len(sections) < 2 || len(sections) > 3 {
+			p.logger.Warn().Str("line", line).Msg("invalid line protocol, expected measurement, fields and optional timestamp, skipping")
+			numErrors++
+			continue
+		}
+		if len(sections) == 3 {
+			if _, err := strconv.ParseInt(sections[2], 10, 64); err != nil {
+				p.logger.Warn().Str("line", line).Str("timestamp", sections[2]).Msg("invalid line protocol timestamp, skipping")
+				numErrors++
+				continue
+			}
+		}
+
+		measurement, streamTags, err := influxSeries(sections[0])
+		if err != nil {
+			p.logger.Warn().Err(err).Str("line", line).Msg("invalid line protocol, skipping")
+			numErrors++
+			continue
+		}
+
+		for _, field := range splitInflux(sections[1], ',', true) {
+			kv := splitInflux(field, '=', true)
+			if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+				p.logger.Warn().Str("line", line).Str("field", field).Msg("invalid line protocol field, skipping")
+				numErrors++
+				continue
+			}
+
+			metricName := measurement + metricDelimiter + strings.Replace(influxUnescape(kv[0]), " ", metricDelimiter, -1) + streamTags
+			if _, ok := metrics[metricName]; ok {
+				p.logger.Warn().Str("name", metricName).Msg("duplicate name, skipping")
+				numDuplicates++
+				continue
+			}
+
+			metric, err := influxFieldValue(kv[1])
+			if err != nil {
+				p.logger.Warn().Err(err).Str("line", line).Str("field", field).Msg("unsupported line protocol field, skipping")
+				numErrors++
+				continue
+			}
+
+			metrics[metricName] = *metric
+		}
+	}
+
+	p.logger.Debug().
+		Int("tot_plugin_lines", len(output)).
+		Int("tot_metric", len(metrics)).
+		Int("tot_duplicate", numDuplicates).
+		Int("tot_error", numErrors).
+		Msg("done processing line protocol plugin output")
+
+	return metrics
+}
+
+// influxSeries returns the metric name prefix (measurement) and the stream
+// tags from the measurement[,tag=value...] section of a line
+func influxSeries(series string) (string, string, error) {
+	parts := splitInflux(series, ',', false)
+	measurement := strings.Replace(influxUnescape(parts[0]), " ", metricDelimiter, -1)
+	if measurement == "" {
+		return "", "", errors.New("measurement required")
+	}
+
+	if len(parts) == 1 {
+		return measurement, "", nil
+	}
+
+	// the tag list delimiters (: and ,) are not valid in a tag category or value
+	tagCleaner := strings.NewReplacer(tags.Delimiter, "_", tags.Separator, "_")
+	tagList := make([]string, 0, len(parts)-1)
+	for _, tag := range parts[1:] {
+		kv := splitInflux(tag, '=', false)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return "", "", errors.Errorf("invalid tag (%s)", tag)
+		}
+		tagList = append(tagList, tagCleaner.Replace(influxUnescape(kv[0]))+tags.Delimiter+tagCleaner.Replace(influxUnescape(kv[1])))
+	}
+
+	st, err := tags.PrepStreamTags(strings.Join(tagList, tags.Separator))
+	if err != nil {
+		return "", "", errors.Wrap(err, "tags")
+	}
+
+	return measurement, st, nil
+}
+
+// influxFieldValue converts a line protocol field value into a cgm metric,
+// integers (123i) become l, unsigned integers (123u) L, floats n and
+// strings s. Booleans are not supported.
+func influxFieldValue(v string) (*cgm.Metric, error) {
+	if strings.HasPrefix(v, `"`) {
+		if len(v) < 2 || !strings.HasSuffix(v, `"`) {
+			return nil, errors.Errorf("unterminated string (%s)", v)
+		}
+		s := strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(v[1 : len(v)-1])
+		return &cgm.Metric{Type: "s", Value: s}, nil
+	}
+
+	switch v {
+	case "t", "T", "true", "True", "TRUE", "f", "F", "false", "False", "FALSE":
+		return nil, errors.Errorf("boolean field (%s) not supported", v)
+	}
+
+	switch v[len(v)-1] {
+	case 'i':
+		i, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing integer")
+		}
+		return &cgm.Metric{Type: "l", Value: i}, nil
+	case 'u':
+		u, err := strconv.ParseUint(v[:len(v)-1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing unsigned integer")
+		}
+		return &cgm.Metric{Type: "L", Value: u}, nil
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing float")
+	}
+	return &cgm.Metric{Type: "n", Value: f}, nil
+}
+
+// cutInfluxSeries returns the measurement and tags section of a line (up to
+// the first unescaped space) and the rest of the line
+func cutInfluxSeries(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case ' ':
+			return line[:i], line[i+1:]
+		}
+	}
+	return line, ""
+}
+
+// splitInflux splits s on sep, ignoring escaped (\) separators and, if quotes
+// is set, separators within double quoted strings. Escapes are retained (see
+// influxUnescape). Consecutive spaces are treated as one separator.
+func splitInflux(s string, sep byte, quotes bool) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++ // skip the escaped character
+		case s[i] == '"' && quotes:
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			if sep != ' ' || i > start {
+				parts = append(parts, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if sep != ' ' || start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// influxUnescape removes the escapes from a measurement, tag or field key
+func influxUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return strings.NewReplacer(`\ `, " ", `\,`, ",", `\=`, "=", `\\`, `\`).Replace(s)
+}
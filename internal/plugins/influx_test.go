@@ -0,0 +1,133 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/rs/zerolog"
+)
+
+func TestIsInfluxOutput(t *testing.T) {
+	t.Log("Testing isInfluxOutput")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tests := []struct {
+		desc     string
+		output   []string
+		expected bool
+	}{
+		{"measurement and field", []string{"cpu usage=1"}, true},
+		{"tags and timestamp", []string{"cpu,host=a,cpu=0 usage=1,idle=2 1465839830100400200"}, true},
+		{"escaped space", []string{`cpu\ load,host=a usage=1`}, true},
+		{"comment first", []string{"# telegraf", "", "cpu usage=1"}, true},
+		{"tab delimited", []string{"cpu\tn\t1"}, false},
+		{"tab delimited with equals", []string{"a=b\tn\t1"}, false},
+		{"json", []string{`{"cpu": {"_type": "n", "_value": 1}}`}, false},
+		{"no field", []string{"cpu 1"}, false},
+		{"empty", []string{}, false},
+	}
+
+	for _, tst := range tests {
+		t.Logf("\t%s", tst.desc)
+		if v := isInfluxOutput(tst.output); v != tst.expected {
+			t.Fatalf("expected %v got %v", tst.expected, v)
+		}
+	}
+}
+
+func TestParseInfluxOutput(t *testing.T) {
+	t.Log("Testing parseInfluxOutput")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	p := &plugin{
+		ctx:  context.Background(),
+		id:   "test",
+		name: "test",
+	}
+
+	t.Log("\tvalid")
+	{
+		metrics := p.parseInfluxOutput([]string{
+			"# comment",
+			"cpu,host=web1,cpu=cpu0 usage_user=12.5,usage_system=3i 1465839830100400200",
+			`disk,path=/var\ log used=10u,mode="read write",note="say \"hi\""`,
+			`net\ io in=1`,
+		})
+		expected := cgm.Metrics{
+			"cpu`usage_user|ST[cpu:cpu0,host:web1]":   cgm.Metric{Type: "n", Value: float64(12.5)},
+			"cpu`usage_system|ST[cpu:cpu0,host:web1]": cgm.Metric{Type: "l", Value: int64(3)},
+			"disk`used|ST[path:/var log]":             cgm.Metric{Type: "L", Value: uint64(10)},
+			"disk`mode|ST[path:/var log]":             cgm.Metric{Type: "s", Value: "read write"},
+			"disk`note|ST[path:/var log]":             cgm.Metric{Type: "s", Value: `say "hi"`},
+			"net`io`in":                               cgm.Metric{Type: "n", Value: float64(1)},
+		}
+		if len(metrics) != len(expected) {
+			t.Fatalf("expected %d metrics, got %#v", len(expected), metrics)
+		}
+		for mn, em := range expected {
+			m, ok := metrics[mn]
+			if !ok {
+				t.Fatalf("expected (%s), got %#v", mn, metrics)
+			}
+			if m.Type != em.Type || m.Value != em.Value {
+				t.Fatalf("%s expected %#v got %#v", mn, em, m)
+			}
+		}
+	}
+
+	t.Log("\ttag delimiters replaced")
+	{
+		metrics := p.parseInfluxOutput([]string{"svc,url=http://a:80 up=1"})
+		if _, ok := metrics["svc`up|ST[url:http_//a_80]"]; !ok {
+			t.Fatalf("expected cleaned tag, got %#v", metrics)
+		}
+	}
+
+	t.Log("\tunsupported and invalid, skipped")
+	{
+		metrics := p.parseInfluxOutput([]string{
+			"app up=true,count=2i",
+			"app,host latency=1",
+			"app latency=1 yesterday",
+			"app latency=abc",
+			"app latency=1 2 3",
+			"app count=3i",
+		})
+		if len(metrics) != 1 {
+			t.Fatalf("expected 1 metric, got %#v", metrics)
+		}
+		if m := metrics["app`count"]; m.Value != int64(2) {
+			t.Fatalf("expected first count kept (duplicate skipped), got %#v", metrics)
+		}
+	}
+}
+
+func TestParsePluginOutputInflux(t *testing.T) {
+	t.Log("Testing parsePluginOutput line protocol")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	p := &plugin{
+		ctx:  context.Background(),
+		id:   "test",
+		name: "test",
+	}
+
+	if err := p.parsePluginOutput([]string{"mem,host=a used=1i,free=2i"}); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	if len(*p.metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %#v", *p.metrics)
+	}
+	if _, ok := (*p.metrics)["mem`used|ST[host:a]"]; !ok {
+		t.Fatalf("expected mem`used|ST[host:a], got %#v", *p.metrics)
+	}
+}
@@ -7,14 +7,16 @@ package plugins
 
 import (
 	"context"
-	"encoding/json"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/api"
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
 	"github.com/circonus-labs/circonus-agent/internal/config"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/maier/go-appstats"
@@ -33,6 +35,10 @@ func New(ctx context.Context) (*Plugins, error) {
 		active:        make(map[string]*plugin),
 	}
 
+	if err := p.loadConfig(); err != nil {
+		return nil, err
+	}
+
 	errMsg := "Invalid plugin directory"
 
 	pluginDir := viper.GetString(config.KeyPluginDir)
@@ -75,6 +81,203 @@ func New(ctx context.Context) (*Plugins, error) {
 	return &p, nil
 }
 
+// loadConfig loads the plugin settings which can be changed
+// without restarting the agent (see Reload)
+func (p *Plugins) loadConfig() error {
+	p.workers = viper.GetInt(config.KeyPluginWorkers)
+	if p.workers <= 0 {
+		p.workers = runtime.NumCPU()
+	}
+
+	if err := p.loadTimeouts(); err != nil {
+		return err
+	}
+
+	p.slowThreshold = time.Duration(0)
+	if t := viper.GetString(config.KeyPluginSlowThreshold); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return errors.Wrap(err, "parsing plugin slow threshold")
+		}
+		p.slowThreshold = d
+	}
+
+	if err := p.loadTTLs(); err != nil {
+		return err
+	}
+
+	if err := p.loadHTTPSources(); err != nil {
+		return err
+	}
+
+	if err := p.loadFIFOSources(); err != nil {
+		return err
+	}
+
+	if err := p.loadSandboxes(); err != nil {
+		return err
+	}
+
+	switch c := viper.GetString(config.KeyPluginCollisions); c {
+	case "", collisionsDrop:
+		p.collisions = collisionsDrop
+	case collisionsNS:
+		p.collisions = collisionsNS
+	default:
+		return errors.Errorf("invalid plugin collisions setting (%s), must be %s or %s", c, collisionsDrop, collisionsNS)
+	}
+
+	p.persistent = make(map[string]bool)
+	for _, name := range viper.GetStringSlice(config.KeyPluginPersistent) {
+		p.persistent[name] = true
+	}
+
+	if hn, err := os.Hostname(); err != nil {
+		p.logger.Warn().Err(err).Msg("unable to determine hostname for plugin environment")
+	} else {
+		p.hostname = hn
+	}
+	p.checkID = viper.GetString(config.KeyCheckBundleID)
+
+	return nil
+}
+
+// Reload re-loads plugin settings and re-scans the plugin directory,
+// new plugins are activated and plugins no longer present are removed
+func (p *Plugins) Reload(b *builtins.Builtins) error {
+	p.Lock()
+	err := p.loadConfig()
+	p.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "reloading plugin config")
+	}
+
+	return p.Scan(b)
+}
+
+// loadTimeouts parses the default and per-plugin execution timeouts
+func (p *Plugins) loadTimeouts() error {
+	if t := viper.GetString(config.KeyPluginTimeout); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return errors.Wrap(err, "parsing plugin timeout")
+		}
+		p.timeout = d
+	}
+
+	p.timeouts = make(map[string]time.Duration)
+	for name, t := range viper.GetStringMapString(config.KeyPluginTimeouts) {
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return errors.Wrapf(err, "parsing plugin timeout for %s", name)
+		}
+		p.timeouts[name] = d
+	}
+
+	return nil
+}
+
+// pluginTimeout returns the execution timeout for a specific plugin, the
+// first name with an override is used (e.g. plugin`instance, then plugin),
+// otherwise the timeout from the plugin's config file (if any) or the default
+func (p *Plugins) pluginTimeout(cfgTimeout time.Duration, names ...string) time.Duration {
+	for _, name := range names {
+		if d, ok := p.timeouts[name]; ok {
+			return d
+		}
+	}
+	if cfgTimeout > 0 {
+		return cfgTimeout
+	}
+	return p.timeout
+}
+
+// loadTTLs parses the per-plugin run ttls, ttls without units
+// get the default plugin ttl units
+func (p *Plugins) loadTTLs() error {
+	p.ttls = make(map[string]time.Duration)
+	for name, ttl := range viper.GetStringMapString(config.KeyPluginTTLs) {
+		d, err := parseTTL(ttl)
+		if err != nil {
+			return errors.Wrapf(err, "parsing plugin ttl for %s", name)
+		}
+		p.ttls[name] = d
+	}
+
+	return nil
+}
+
+// pluginTTL returns the run ttl for a specific plugin, the first name
+// with an override is used (e.g. plugin`instance, then plugin), otherwise
+// the ttl from the plugin's file name (if any)
+func (p *Plugins) pluginTTL(fileTTL time.Duration, names ...string) time.Duration {
+	for _, name := range names {
+		if d, ok := p.ttls[name]; ok {
+			return d
+		}
+	}
+	return fileTTL
+}
+
+// parseTTL parses a plugin ttl, adding the default units if none specified
+func parseTTL(ttl string) (time.Duration, error) {
+	if !ttlUnitRx.MatchString(ttl) {
+		ttl += viper.GetString(config.KeyPluginTTLUnits)
+	}
+	return time.ParseDuration(ttl)
+}
+
+// isPersistent determines if a plugin (instance) is configured to be persistent,
+// the first name found is used (e.g. plugin`instance, then plugin)
+func (p *Plugins) isPersistent(names ...string) bool {
+	for _, name := range names {
+		if p.persistent[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginEnv returns the environment variables set for a specific plugin
+// (instance), standard variables first followed by the instance's
+// configured variables
+func (p *Plugins) pluginEnv(name, instanceID string, instanceEnv map[string]string) []string {
+	env := []string{
+		envHostname + "=" + p.hostname,
+		envPluginName + "=" + name,
+		envPluginInstance + "=" + instanceID,
+	}
+
+	// the check in use, otherwise the configured check (if any)
+	checkID := p.checkCID
+	if checkID == "" {
+		checkID = p.checkID
+	}
+	if checkID != "" {
+		env = append(env, envCheckID+"="+checkID)
+	}
+
+	keys := make([]string, 0, len(instanceEnv))
+	for k := range instanceEnv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+instanceEnv[k])
+	}
+
+	return env
+}
+
+// SetCheckID sets the check bundle id (e.g. /check_bundle/123 or 123) of
+// the check in use, including a check created by the agent, for the plugin
+// environment. Plugins scanned after it is set include the id.
+func (p *Plugins) SetCheckID(cid string) {
+	p.Lock()
+	p.checkCID = strings.TrimPrefix(cid, "/check_bundle/")
+	p.Unlock()
+}
+
 // Flush plugin metrics
 func (p *Plugins) Flush(pluginName string) *cgm.Metrics {
 	p.RLock()
@@ -83,25 +286,90 @@ func (p *Plugins) Flush(pluginName string) *cgm.Metrics {
 	appstats.MapSet("plugins", "last_flush", time.Now())
 
 	metrics := cgm.Metrics{}
+	owners := make(map[string]string) // metric name -> id of the plugin which emitted it
 
-	for pluginID, plug := range p.active {
+	// plugins are processed in id order so the outcome of a collision is
+	// the same on every flush, regardless of map iteration order
+	ids := make([]string, 0, len(p.active))
+	for pluginID := range p.active {
 		if pluginName == "" || // all plugins
 			pluginID == pluginName || // specific plugin
 			strings.HasPrefix(pluginID, pluginName+metricDelimiter) { // specific plugin with instances
+			ids = append(ids, pluginID)
+		}
+	}
+	sort.Strings(ids)
 
-			m := plug.drain()
-			for mn, mv := range *m {
-				metrics[pluginID+metricDelimiter+mn] = mv
-			}
+	for _, pluginID := range ids {
+		plug := p.active[pluginID]
+		pluginMetrics := cgm.Metrics{}
+		for mn, mv := range *plug.drain() {
+			pluginMetrics[mn] = mv
+		}
+		for mn, mv := range plug.runMetrics() {
+			pluginMetrics[mn] = mv
+		}
+		for mn, mv := range pluginMetrics {
+			p.addMetric(metrics, owners, pluginID, mn, mv)
 		}
 	}
 
 	return &metrics
 }
 
-// Stop any long running plugins
+// addMetric adds a plugin metric to the flushed metrics, a metric name already
+// emitted by another plugin is a collision: it is logged and the metric is
+// dropped or, with the namespace collision setting, namespaced by plugin id
+func (p *Plugins) addMetric(metrics cgm.Metrics, owners map[string]string, pluginID, name string, m cgm.Metric) {
+	metricName := pluginID + metricDelimiter + name
+	owner, collision := owners[metricName]
+	if !collision {
+		metrics[metricName] = m
+		owners[metricName] = pluginID
+		return
+	}
+
+	appstats.IncrementInt("plugins.metric_collisions")
+
+	if p.collisions == collisionsNS {
+		nsName := strings.Replace(pluginID, metricDelimiter, namespaceDelim, -1) + metricDelimiter + name
+		if _, exists := owners[nsName]; !exists {
+			p.logger.Warn().
+				Str("metric", metricName).
+				Str("plugin", pluginID).
+				Str("first_plugin", owner).
+				Str("namespaced", nsName).
+				Msg("metric name collision between plugins, namespacing metric")
+			metrics[nsName] = m
+			owners[nsName] = pluginID
+			return
+		}
+	}
+
+	p.logger.Warn().
+		Str("metric", metricName).
+		Str("plugin", pluginID).
+		Str("first_plugin", owner).
+		Msg("metric name collision between plugins, dropping metric")
+}
+
+// Stop any long running plugins, plugins still running (e.g. slow plugins
+// run in the background) are terminated and waited for
 func (p *Plugins) Stop() error {
 	p.logger.Info().Msg("Stopping plugins")
+
+	p.RLock()
+	for _, plug := range p.active {
+		if plug.cancel != nil {
+			plug.cancel()
+		}
+	}
+	p.RUnlock()
+
+	p.runmu.Lock()
+	defer p.runmu.Unlock()
+	p.background.Wait()
+
 	return nil
 }
 
@@ -122,39 +390,32 @@ func (p *Plugins) Run(pluginName string) error {
 	p.running = true
 	p.Unlock()
 
-	var wg sync.WaitGroup
+	// scans (Scan/Reload, the directory watcher) wait for the run to finish
+	p.runmu.Lock()
+	defer p.runmu.Unlock()
 
-	if pluginName != "" {
-		numFound := 0
-		for pluginID, pluginRef := range p.active {
-			if pluginID == pluginName || // specific plugin
-				strings.HasPrefix(pluginID, pluginName+"`") { // specific plugin with instances
-				numFound++
-				wg.Add(1)
-				go func(id string, plug *plugin) {
-					plug.exec()
-					wg.Done()
-				}(pluginID, pluginRef)
-			}
-		}
-		if numFound == 0 {
-			p.logger.Error().
-				Str("plugin", pluginName).
-				Msg("Invalid/Unknown")
-			p.running = false
-			return errors.Errorf("invalid plugin (%s)", pluginName)
-		}
-	} else {
-		for pluginID, pluginRef := range p.active {
-			wg.Add(1)
-			go func(id string, plug *plugin) {
-				plug.exec()
-				wg.Done()
-			}(pluginID, pluginRef)
+	// the plugins to run are copied while holding the lock
+	p.RLock()
+	plugins := []*plugin{}
+	for pluginID, pluginRef := range p.active {
+		if pluginName == "" || // all plugins
+			pluginID == pluginName || // specific plugin
+			strings.HasPrefix(pluginID, pluginName+"`") { // specific plugin with instances
+			plugins = append(plugins, pluginRef)
 		}
 	}
+	p.RUnlock()
+	if len(plugins) == 0 && pluginName != "" {
+		p.logger.Error().
+			Str("plugin", pluginName).
+			Msg("Invalid/Unknown")
+		p.Lock()
+		p.running = false
+		p.Unlock()
+		return errors.Errorf("invalid plugin (%s)", pluginName)
+	}
 
-	wg.Wait()
+	p.execPlugins(plugins)
 
 	appstats.MapSet("plugins", "last_run_end", time.Now())
 	appstats.MapSet("plugins", "last_run_duration", time.Since(start))
@@ -167,6 +428,87 @@ func (p *Plugins) Run(pluginName string) error {
 	return nil
 }
 
+// execPlugins runs plugins through a bounded pool of workers so that only
+// p.workers plugins execute concurrently. Plugins not yet started when the
+// context is cancelled (agent shutdown) are skipped.
+//
+// With a slow threshold, plugins are started fastest (by last run duration)
+// first and plugins whose last run took at least the threshold run in the
+// background, outside of the pool, and are not waited for. Their last result
+// is served until the run finishes, a slow plugin still running from an
+// earlier run is not started again (see overrun). Scans and Stop wait for
+// the background runs.
+func (p *Plugins) execPlugins(plugins []*plugin) {
+	p.RLock()
+	workers := p.workers
+	slowThreshold := p.slowThreshold
+	p.RUnlock()
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	durations := make(map[*plugin]time.Duration, len(plugins))
+	if slowThreshold > 0 {
+		for _, plug := range plugins {
+			plug.Lock()
+			durations[plug] = plug.lastRunDuration
+			plug.Unlock()
+		}
+		plugins = append([]*plugin{}, plugins...)
+		sort.SliceStable(plugins, func(i, j int) bool {
+			return durations[plugins[i]] < durations[plugins[j]]
+		})
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+
+	for _, plug := range plugins {
+		plug.Lock()
+		persistent := plug.persistent
+		running := plug.running
+		plug.Unlock()
+		if persistent {
+			continue // started once by Scan, restarted by runPersistent
+		}
+
+		if p.ctx.Err() != nil {
+			p.logger.Warn().Msg("context done, skipping remaining plugins")
+			break
+		}
+
+		if slowThreshold > 0 && durations[plug] >= slowThreshold {
+			if running {
+				plug.overrun()
+				continue
+			}
+			p.background.Add(1)
+			go func(plug *plugin) {
+				defer p.background.Done()
+				plug.exec()
+			}(plug)
+			continue
+		}
+
+		select {
+		case <-p.ctx.Done():
+			continue // picked up by the check above
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(plug *plugin) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			plug.exec()
+		}(plug)
+	}
+
+	wg.Wait()
+}
+
 // IsValid determines if a specific plugin is valid
 func (p *Plugins) IsValid(pluginName string) bool {
 	if pluginName == "" {
@@ -196,11 +538,54 @@ func (p *Plugins) IsInternal(pluginName string) bool {
 	return reserved
 }
 
-// Inventory returns list of active plugins
-func (p *Plugins) Inventory() []byte {
+// State returns diagnostic details about the plugins, including
+// the last run status of each
+func (p *Plugins) State() map[string]interface{} {
+	p.RLock()
+	defer p.RUnlock()
+
+	plugins := make(map[string]interface{}, len(p.active))
+	for id, plug := range p.active {
+		plug.Lock()
+		pstate := map[string]interface{}{
+			"command":           plug.command,
+			"persistent":        plug.persistent,
+			"running":           plug.running,
+			"last_run_start":    plug.lastStart.Format(time.RFC3339Nano),
+			"last_run_end":      plug.lastEnd.Format(time.RFC3339Nano),
+			"last_run_duration": plug.lastRunDuration.String(),
+			"last_exit_code":    plug.lastExitCode,
+			"runs_ok":           plug.runsOK,
+			"runs_failed":       plug.runsFailed,
+			"stale_runs":        plug.staleRuns,
+		}
+		if plug.lastError != nil {
+			pstate["last_error"] = plug.lastError.Error()
+		}
+		plug.Unlock()
+		plugins[id] = pstate
+	}
+
+	return map[string]interface{}{
+		"plugin_dir": p.pluginDir,
+		"rescans":    p.rescans,
+		"running":    p.running,
+		"plugins":    plugins,
+	}
+}
+
+// Count returns the number of active plugins
+func (p *Plugins) Count() int {
+	p.RLock()
+	defer p.RUnlock()
+	return len(p.active)
+}
+
+// Inventory returns the active plugins, sorted by id
+func (p *Plugins) Inventory() []api.Plugin {
 	p.Lock()
 	defer p.Unlock()
-	inventory := api.Inventory{}
+	inventory := make([]api.Plugin, 0, len(p.active))
 	for id, plug := range p.active {
 		plug.Lock()
 		pinfo := api.Plugin{
@@ -212,6 +597,7 @@ func (p *Plugins) Inventory() []byte {
 			LastRunStart:    plug.lastStart.Format(time.RFC3339Nano),
 			LastRunEnd:      plug.lastEnd.Format(time.RFC3339Nano),
 			LastRunDuration: plug.lastRunDuration.String(),
+			LastExitCode:    plug.lastExitCode,
 		}
 		if plug.lastError != nil {
 			pinfo.LastError = plug.lastError.Error()
@@ -219,9 +605,6 @@ func (p *Plugins) Inventory() []byte {
 		plug.Unlock()
 		inventory = append(inventory, pinfo)
 	}
-	data, err := json.Marshal(inventory)
-	if err != nil {
-		p.logger.Fatal().Err(err).Msg("inventory -> json")
-	}
-	return data
+	sort.Slice(inventory, func(i, j int) bool { return inventory[i].ID < inventory[j].ID })
+	return inventory
 }
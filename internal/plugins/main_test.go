@@ -6,16 +6,18 @@
 package plugins
 
 import (
-	"bytes"
 	"context"
 	"os"
 	"path"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins"
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 )
@@ -130,6 +132,47 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunScanConcurrent(t *testing.T) {
+	t.Log("Testing Run concurrent with Scan")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, "testdata")
+	p, err := New(context.Background())
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	b, err := builtins.New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	if err := p.Scan(b); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	// run with -race, Scan updates the active plugins while Run selects them
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			p.Run("") // an error (run already in progress) is expected at times
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			if err := p.Scan(b); err != nil {
+				t.Errorf("expected NO error, got (%s)", err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	time.Sleep(1 * time.Second) // let initial runs complete
+}
+
 func TestFlush(t *testing.T) {
 	t.Log("Testing Flush")
 
@@ -196,6 +239,78 @@ func TestFlush(t *testing.T) {
 		if mv.Value.(float64) != 22.1 {
 			t.Fatalf("expected value 22.1 got %#v", mv)
 		}
+
+		name = id + metricDelimiter + runMetricPrefix + metricDelimiter + "exit_code"
+		mv, ok = (*data)[name]
+		if !ok {
+			t.Fatalf("expected metric named (%s) got (%#v)", name, *data)
+		}
+		if mv.Value.(int32) != 0 {
+			t.Fatalf("expected exit code 0 got %#v", mv)
+		}
+	}
+}
+
+func TestFlushCollisions(t *testing.T) {
+	t.Log("Testing Flush metric name collisions")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	// foo emitting "bar baz" and foo`bar emitting "baz" both flush as foo`bar`baz
+	newPlugins := func(collisions string) *Plugins {
+		return &Plugins{
+			collisions: collisions,
+			active: map[string]*plugin{
+				"foo": {
+					id:      "foo",
+					metrics: &cgm.Metrics{"bar`baz": cgm.Metric{Type: "n", Value: 1.0}},
+				},
+				"foo`bar": {
+					id:      "foo`bar",
+					metrics: &cgm.Metrics{"baz": cgm.Metric{Type: "n", Value: 2.0}, "qux": cgm.Metric{Type: "n", Value: 3.0}},
+				},
+			},
+		}
+	}
+
+	t.Log("\tdrop")
+	{
+		for i := 0; i < 10; i++ {
+			data := newPlugins(collisionsDrop).Flush("")
+			if len(*data) != 2 {
+				t.Fatalf("expected 2 metrics, got %#v", *data)
+			}
+			if v := (*data)["foo`bar`baz"].Value.(float64); v != 1.0 {
+				t.Fatalf("expected first plugin's metric (1), got %v", v)
+			}
+			if _, ok := (*data)["foo`bar`qux"]; !ok {
+				t.Fatalf("expected foo`bar`qux, got %#v", *data)
+			}
+		}
+	}
+
+	t.Log("\tnamespace")
+	{
+		data := newPlugins(collisionsNS).Flush("")
+		if len(*data) != 3 {
+			t.Fatalf("expected 3 metrics, got %#v", *data)
+		}
+		if v := (*data)["foo`bar`baz"].Value.(float64); v != 1.0 {
+			t.Fatalf("expected first plugin's metric (1), got %v", v)
+		}
+		if v, ok := (*data)["foo:bar`baz"]; !ok || v.Value.(float64) != 2.0 {
+			t.Fatalf("expected namespaced metric foo:bar`baz, got %#v", *data)
+		}
+	}
+
+	t.Log("\tinvalid setting")
+	{
+		viper.Set(config.KeyPluginCollisions, "invalid")
+		p := &Plugins{}
+		if err := p.loadConfig(); err == nil {
+			t.Fatal("expected error")
+		}
+		viper.Reset()
 	}
 }
 
@@ -325,14 +440,547 @@ func TestInventory(t *testing.T) {
 
 	t.Log("Valid")
 	{
-		data := p.Inventory()
-		if data == nil {
-			t.Fatalf("expected not nil")
+		inventory := p.Inventory()
+		if len(inventory) == 0 {
+			t.Fatal("expected plugins")
+		}
+
+		found := false
+		for _, pinfo := range inventory {
+			if pinfo.ID == "test" {
+				found = true
+				if pinfo.Name != "test" || pinfo.Instance != "" || pinfo.Command != "testdata/test.sh" {
+					t.Fatalf("unexpected plugin %#v", pinfo)
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("expected test plugin, got %#v", inventory)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Log("Testing State")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Set(config.KeyPluginDir, "testdata")
+
+	p, nerr := New(context.Background())
+	if nerr != nil {
+		t.Fatalf("new err %s", nerr)
+	}
+
+	b, err := builtins.New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	p.pluginDir = "testdata"
+
+	if err := p.Scan(b); err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	state := p.State()
+	if dir, ok := state["plugin_dir"].(string); !ok || dir != "testdata" {
+		t.Fatalf("expected plugin_dir testdata, got (%#v)", state["plugin_dir"])
+	}
+	plugins, ok := state["plugins"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected plugins map, got (%#v)", state["plugins"])
+	}
+	pstate, ok := plugins["test"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected state for plugin test, got (%#v)", plugins)
+	}
+	if cmd, ok := pstate["command"].(string); !ok || cmd != "testdata/test.sh" {
+		t.Fatalf("expected command testdata/test.sh, got (%#v)", pstate["command"])
+	}
+
+	viper.Reset()
+}
+
+func TestLoadTimeouts(t *testing.T) {
+	t.Log("Testing loadTimeouts")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("defaults (no timeout)")
+	{
+		viper.Reset()
+		p := &Plugins{}
+		if err := p.loadTimeouts(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if d := p.pluginTimeout(0, "foo"); d != time.Duration(0) {
+			t.Fatalf("expected 0, got %s", d)
+		}
+	}
+
+	t.Log("invalid default")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginTimeout, "abc")
+		p := &Plugins{}
+		if err := p.loadTimeouts(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid override")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginTimeouts, map[string]string{"foo": "abc"})
+		p := &Plugins{}
+		if err := p.loadTimeouts(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("default w/overrides")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginTimeout, "10s")
+		viper.Set(config.KeyPluginTimeouts, map[string]string{"foo": "30s", "bar`baz": "1m"})
+		p := &Plugins{}
+		if err := p.loadTimeouts(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		tests := []struct {
+			names  []string
+			expect time.Duration
+		}{
+			{[]string{"qux"}, 10 * time.Second},
+			{[]string{"foo"}, 30 * time.Second},
+			{[]string{"foo`inst", "foo"}, 30 * time.Second},
+			{[]string{"bar`baz", "bar"}, time.Minute},
+			{[]string{"bar`other", "bar"}, 10 * time.Second},
+		}
+		for _, test := range tests {
+			if d := p.pluginTimeout(0, test.names...); d != test.expect {
+				t.Fatalf("%v expected %s, got %s", test.names, test.expect, d)
+			}
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestExecPlugins(t *testing.T) {
+	t.Log("Testing execPlugins")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires bash")
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unable to get cwd (%s)", err)
+	}
+
+	newPlugins := func(ctx context.Context) []*plugin {
+		list := []*plugin{}
+		for _, id := range []string{"a", "b", "c"} {
+			list = append(list, &plugin{
+				ctx:     ctx,
+				id:      id,
+				name:    id,
+				command: path.Join(dir, "testdata", "test.sh"),
+			})
+		}
+		return list
+	}
+
+	t.Log("single worker")
+	{
+		p := &Plugins{ctx: context.Background(), workers: 1}
+		list := newPlugins(p.ctx)
+		p.execPlugins(list)
+		for _, plug := range list {
+			if plug.lastEnd.IsZero() {
+				t.Fatalf("expected %s to have run", plug.id)
+			}
+		}
+		for i := 1; i < len(list); i++ {
+			for j := 0; j < i; j++ {
+				a, b := list[i], list[j]
+				if a.lastStart.Before(b.lastEnd) && b.lastStart.Before(a.lastEnd) {
+					t.Fatalf("expected %s and %s to run sequentially", a.id, b.id)
+				}
+			}
+		}
+	}
+
+	t.Log("default workers (0 = number of CPUs)")
+	{
+		p := &Plugins{ctx: context.Background()}
+		list := newPlugins(p.ctx)
+		p.execPlugins(list)
+		for _, plug := range list {
+			if plug.lastEnd.IsZero() {
+				t.Fatalf("expected %s to have run", plug.id)
+			}
+		}
+	}
+
+	t.Log("cancelled context")
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p := &Plugins{ctx: ctx, workers: 1}
+		list := newPlugins(ctx)
+		p.execPlugins(list)
+		for _, plug := range list {
+			if !plug.lastStart.IsZero() {
+				t.Fatalf("expected %s to be skipped", plug.id)
+			}
+		}
+	}
+
+	t.Log("slow threshold (invalid)")
+	{
+		viper.Set(config.KeyPluginSlowThreshold, "soon")
+		p := &Plugins{}
+		if err := p.loadConfig(); err == nil {
+			t.Fatal("expected error")
+		}
+		viper.Reset()
+	}
+
+	t.Log("slow threshold")
+	{
+		p := &Plugins{ctx: context.Background(), workers: 1, slowThreshold: 500 * time.Millisecond}
+		fast := &plugin{
+			ctx:     p.ctx,
+			id:      "fast",
+			name:    "fast",
+			command: path.Join(dir, "testdata", "test.sh"),
+		}
+		slow := &plugin{
+			ctx:             p.ctx,
+			id:              "slow",
+			name:            "slow",
+			command:         path.Join(dir, "testdata", "slow", "slow.sh"),
+			lastRunDuration: time.Second,
+		}
+
+		start := time.Now()
+		p.execPlugins([]*plugin{slow, fast})
+		if time.Since(start) >= 500*time.Millisecond {
+			t.Fatalf("expected slow plugin not to be waited for, took %s", time.Since(start))
+		}
+		if fast.lastEnd.IsZero() {
+			t.Fatal("expected fast to have run")
+		}
+
+		running := func() bool {
+			slow.Lock()
+			defer slow.Unlock()
+			return slow.running
+		}
+		for i := 0; i < 50 && !running(); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !running() {
+			t.Fatal("expected slow to be running")
+		}
+
+		p.execPlugins([]*plugin{slow, fast})
+		slow.Lock()
+		staleRuns := slow.staleRuns
+		slow.Unlock()
+		if staleRuns != 1 {
+			t.Fatalf("expected 1 stale run, got %d", staleRuns)
+		}
+
+		for i := 0; i < 300 && running(); i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if running() {
+			t.Fatal("expected slow to have finished")
+		}
+		metrics := *slow.drain()
+		if _, ok := metrics["metric"]; !ok {
+			t.Fatalf("expected slow plugin metric, got %#v", metrics)
+		}
+		m := slow.runMetrics()
+		mn := runMetricPrefix + metricDelimiter + "stale_runs"
+		if v, ok := m[mn]; !ok || v.Value.(uint64) != 1 {
+			t.Fatalf("expected %s 1, got %#v", mn, m)
+		}
+	}
+
+	t.Log("slow threshold, scan waits for background run")
+	{
+		slow := &plugin{
+			ctx:             context.Background(),
+			id:              "slow",
+			name:            "slow",
+			command:         path.Join(dir, "testdata", "slow", "slow.sh"),
+			lastRunDuration: time.Second,
+		}
+		p := &Plugins{
+			ctx:           context.Background(),
+			active:        map[string]*plugin{"slow": slow},
+			reservedNames: map[string]bool{},
+			workers:       1,
+			slowThreshold: 500 * time.Millisecond,
+		}
+
+		p.execPlugins([]*plugin{slow})
+		if err := p.Load(nil); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		slow.Lock()
+		running := slow.running
+		slow.Unlock()
+		if running {
+			t.Fatal("expected slow to have finished before the scan")
+		}
+		if _, ok := (*slow.drain())["metric"]; !ok {
+			t.Fatal("expected slow plugin metric")
+		}
+	}
+
+	t.Log("slow threshold, stop terminates background run")
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := &plugin{
+			cancel:          cancel,
+			ctx:             ctx,
+			id:              "slow",
+			name:            "slow",
+			command:         path.Join(dir, "testdata", "slow", "slow.sh"),
+			lastRunDuration: time.Second,
+		}
+		p := &Plugins{
+			ctx:           context.Background(),
+			active:        map[string]*plugin{"slow": slow},
+			workers:       1,
+			slowThreshold: 500 * time.Millisecond,
+		}
+
+		p.execPlugins([]*plugin{slow})
+		if err := p.Stop(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if ctx.Err() == nil {
+			t.Fatal("expected slow to be terminated")
+		}
+		slow.Lock()
+		running := slow.running
+		slow.Unlock()
+		if running {
+			t.Fatal("expected slow to have finished")
+		}
+	}
+}
+
+func TestLoadTTLs(t *testing.T) {
+	t.Log("Testing loadTTLs")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("defaults (no ttls)")
+	{
+		viper.Reset()
+		p := &Plugins{}
+		if err := p.loadTTLs(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if d := p.pluginTTL(time.Duration(0), "foo"); d != time.Duration(0) {
+			t.Fatalf("expected 0, got %s", d)
+		}
+		if d := p.pluginTTL(30*time.Second, "foo"); d != 30*time.Second {
+			t.Fatalf("expected 30s, got %s", d)
+		}
+	}
+
+	t.Log("invalid ttl")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginTTLs, map[string]string{"foo": "abc"})
+		p := &Plugins{}
+		if err := p.loadTTLs(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("overrides")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginTTLUnits, "s")
+		viper.Set(config.KeyPluginTTLs, map[string]string{"foo": "5m", "bar`baz": "45"})
+		p := &Plugins{}
+		if err := p.loadTTLs(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		tests := []struct {
+			fileTTL time.Duration
+			names   []string
+			expect  time.Duration
+		}{
+			{time.Duration(0), []string{"qux"}, time.Duration(0)},
+			{10 * time.Second, []string{"foo"}, 5 * time.Minute},
+			{time.Duration(0), []string{"foo`inst", "foo"}, 5 * time.Minute},
+			{time.Duration(0), []string{"bar`baz", "bar"}, 45 * time.Second},
+			{10 * time.Second, []string{"bar`other", "bar"}, 10 * time.Second},
+		}
+		for _, test := range tests {
+			if d := p.pluginTTL(test.fileTTL, test.names...); d != test.expect {
+				t.Fatalf("%v expected %s, got %s", test.names, test.expect, d)
+			}
+		}
+	}
+}
+
+func TestPluginEnv(t *testing.T) {
+	t.Log("Testing pluginEnv")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	p := &Plugins{hostname: "host1", checkID: "123"}
+
+	t.Log("standard")
+	{
+		env := p.pluginEnv("foo", "", nil)
+		expect := []string{
+			"CA_PLUGIN_HOSTNAME=host1",
+			"CA_PLUGIN_NAME=foo",
+			"CA_PLUGIN_INSTANCE=",
+			"CA_PLUGIN_CHECK_ID=123",
+		}
+		if strings.Join(env, " ") != strings.Join(expect, " ") {
+			t.Fatalf("expected %v, got %v", expect, env)
+		}
+	}
+
+	t.Log("w/instance env")
+	{
+		env := p.pluginEnv("foo", "sda", map[string]string{"DEVICE": "sda", "A": "b"})
+		expect := []string{
+			"CA_PLUGIN_HOSTNAME=host1",
+			"CA_PLUGIN_NAME=foo",
+			"CA_PLUGIN_INSTANCE=sda",
+			"CA_PLUGIN_CHECK_ID=123",
+			"A=b",
+			"DEVICE=sda",
+		}
+		if strings.Join(env, " ") != strings.Join(expect, " ") {
+			t.Fatalf("expected %v, got %v", expect, env)
+		}
+	}
+
+	t.Log("check id not known")
+	{
+		p := &Plugins{hostname: "host1"}
+		env := p.pluginEnv("foo", "", nil)
+		expect := []string{
+			"CA_PLUGIN_HOSTNAME=host1",
+			"CA_PLUGIN_NAME=foo",
+			"CA_PLUGIN_INSTANCE=",
+		}
+		if strings.Join(env, " ") != strings.Join(expect, " ") {
+			t.Fatalf("expected %v, got %v", expect, env)
+		}
+	}
+
+	t.Log("check in use (created)")
+	{
+		p := &Plugins{hostname: "host1"}
+		p.SetCheckID("/check_bundle/456")
+		env := p.pluginEnv("foo", "", nil)
+		expect := "CA_PLUGIN_CHECK_ID=456"
+		if env[len(env)-1] != expect {
+			t.Fatalf("expected %s, got %v", expect, env)
+		}
+	}
+}
+
+func TestIsPersistent(t *testing.T) {
+	t.Log("Testing isPersistent")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyPluginPersistent, []string{"foo", "bar`baz"})
+	viper.Set(config.KeyPluginDir, "testdata")
+	p, err := New(context.Background())
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	tests := []struct {
+		names  []string
+		expect bool
+	}{
+		{[]string{"qux"}, false},
+		{[]string{"foo"}, true},
+		{[]string{"foo`inst", "foo"}, true},
+		{[]string{"bar`baz", "bar"}, true},
+		{[]string{"bar`other", "bar"}, false},
+	}
+	for _, test := range tests {
+		if p.isPersistent(test.names...) != test.expect {
+			t.Fatalf("%v expected %v", test.names, test.expect)
 		}
+	}
+	viper.Reset()
+}
 
-		expect := []byte(`{"id":"test","name":"test","instance":"","command":"testdata/test.sh"`)
-		if !bytes.Contains(data, expect) {
-			t.Fatalf("expected (%s) got (%s)", string(expect), string(data))
+func TestReload(t *testing.T) {
+	t.Log("Testing Reload")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyPluginDir, "testdata")
+	p, err := New(context.Background())
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	b, err := builtins.New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	if err := p.Scan(b); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Log("invalid config")
+	{
+		viper.Set(config.KeyPluginTimeout, "abc")
+		if err := p.Reload(b); err == nil {
+			t.Fatal("expected error")
 		}
 	}
+
+	t.Log("valid config")
+	{
+		viper.Set(config.KeyPluginTimeout, "10s")
+		if err := p.Reload(b); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		id := "test"
+		if runtime.GOOS == "windows" {
+			id = "testwin"
+		}
+		plug, ok := p.active[id]
+		if !ok {
+			t.Fatalf("expected %s to be active", id)
+		}
+		plug.Lock()
+		timeout := plug.timeout
+		plug.Unlock()
+		if timeout != 10*time.Second {
+			t.Fatalf("expected 10s timeout, got %s", timeout)
+		}
+	}
+
+	time.Sleep(1 * time.Second) // let initial runs complete
+	viper.Reset()
 }
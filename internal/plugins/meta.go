@@ -0,0 +1,91 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// metricMeta defines the metadata for a metric in a plugin manifest
+type metricMeta struct {
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Units       string `json:"units"`
+}
+
+// validMetaTypes are the circonus check bundle metric types a manifest may declare
+var validMetaTypes = map[string]bool{
+	"numeric":   true,
+	"histogram": true,
+	"text":      true,
+}
+
+// loadManifest reads the optional plugin manifest (e.g. foo.meta.json for
+// foo.sh), keyed by metric name as emitted by the plugin:
+//
+//	{"requests": {"units": "requests", "description": "requests served", "type": "numeric"}}
+//
+// a missing manifest is not an error (nil is returned)
+func (p *Plugins) loadManifest(fileBase string) (map[string]metricMeta, error) {
+	file := filepath.Join(p.pluginDir, fileBase+manifestExt)
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "reading plugin manifest")
+	}
+
+	var meta map[string]metricMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, errors.Wrapf(err, "parsing plugin manifest (%s)", file)
+	}
+
+	for name, mm := range meta {
+		if mm.Type != "" && !validMetaTypes[mm.Type] {
+			p.logger.Warn().
+				Str("manifest", file).
+				Str("metric", name).
+				Str("type", mm.Type).
+				Msg("invalid metric type, ignoring type")
+			mm.Type = ""
+			meta[name] = mm
+		}
+	}
+
+	return meta, nil
+}
+
+// MetricMeta returns the units and circonus metric type declared in the
+// manifest of the plugin which emits metricName (the full metric name as
+// flushed, without stream tags, e.g. foo`bar). Empty values mean none was
+// declared and ok is false if no manifest entry exists for the metric.
+func (p *Plugins) MetricMeta(metricName string) (units string, mtype string, ok bool) {
+	p.RLock()
+	defer p.RUnlock()
+
+	for pluginID, plug := range p.active {
+		prefix := pluginID + metricDelimiter
+		if !strings.HasPrefix(metricName, prefix) {
+			continue
+		}
+		plug.Lock()
+		mm, found := plug.meta[strings.TrimPrefix(metricName, prefix)]
+		plug.Unlock()
+		if found {
+			return mm.Units, mm.Type, true
+		}
+	}
+
+	return "", "", false
+}
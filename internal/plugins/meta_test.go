@@ -0,0 +1,102 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestLoadManifest(t *testing.T) {
+	t.Log("Testing loadManifest")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	p := Plugins{
+		logger:    log.With().Logger(),
+		pluginDir: filepath.Join("testdata", "meta"),
+	}
+
+	t.Log("\tmissing")
+	{
+		meta, err := p.loadManifest("missing")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if meta != nil {
+			t.Fatalf("expected nil, got %#v", meta)
+		}
+	}
+
+	t.Log("\tbad syntax")
+	{
+		_, err := p.loadManifest("bad")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		meta, err := p.loadManifest("test")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(meta) != 3 {
+			t.Fatalf("expected 3 entries, got %#v", meta)
+		}
+		if mm := meta["requests"]; mm.Units != "requests" || mm.Type != "numeric" || mm.Description != "requests served" {
+			t.Fatalf("unexpected requests entry %#v", mm)
+		}
+		if mm := meta["state"]; mm.Type != "" {
+			t.Fatalf("expected invalid type to be ignored, got %#v", mm)
+		}
+	}
+}
+
+func TestMetricMeta(t *testing.T) {
+	t.Log("Testing MetricMeta")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	p := Plugins{
+		logger:    log.With().Logger(),
+		pluginDir: filepath.Join("testdata", "meta"),
+		active:    map[string]*plugin{},
+	}
+	meta, err := p.loadManifest("test")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	p.active["test"] = &plugin{id: "test", name: "test", meta: meta}
+	p.active["test`inst"] = &plugin{id: "test", instanceID: "inst", name: "test`inst", meta: meta}
+	p.active["other"] = &plugin{id: "other", name: "other"}
+
+	tests := []struct {
+		name  string
+		units string
+		mtype string
+		ok    bool
+	}{
+		{"test`requests", "requests", "numeric", true},
+		{"test`inst`latency", "seconds", "histogram", true},
+		{"test`state", "", "", true},
+		{"test`unknown", "", "", false},
+		{"other`requests", "", "", false},
+		{"requests", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s", test.name)
+		units, mtype, ok := p.MetricMeta(test.name)
+		if ok != test.ok || units != test.units || mtype != test.mtype {
+			t.Fatalf("expected (%s,%s,%v), got (%s,%s,%v)", test.units, test.mtype, test.ok, units, mtype, ok)
+		}
+	}
+}
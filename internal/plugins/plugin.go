@@ -8,16 +8,21 @@ package plugins
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/tags"
 	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/maier/go-appstats"
 	"github.com/pkg/errors"
 )
 
@@ -26,6 +31,17 @@ func (p *plugin) drain() *cgm.Metrics {
 	p.Lock()
 	defer p.Unlock()
 
+	// persistent plugins accumulate metrics between drains, only
+	// new metrics are returned (there is nothing to fall back to)
+	if p.persistent {
+		metrics := p.metrics
+		if metrics == nil {
+			metrics = &cgm.Metrics{}
+		}
+		p.metrics = nil
+		return metrics
+	}
+
 	var metrics *cgm.Metrics
 	if p.metrics == nil {
 		if p.prevMetrics == nil {
@@ -42,7 +58,7 @@ func (p *plugin) drain() *cgm.Metrics {
 	return metrics
 }
 
-// parsePluginOutput handles json and tab delimited output from plugins.
+// parsePluginOutput handles json, InfluxDB line protocol and tab delimited output from plugins.
 func (p *plugin) parsePluginOutput(output []string) error {
 	p.Lock()
 	defer p.Unlock()
@@ -52,8 +68,19 @@ func (p *plugin) parsePluginOutput(output []string) error {
 		Int("num_lines", len(output)).
 		Msg("processing plugin output")
 
+	// plugin signaled its metrics have not changed since the last run, the
+	// last parsed metrics (pending or previously drained) are reused as-is
+	if len(output) > 0 && strings.TrimSpace(output[0]) == noChangeSentinel {
+		if len(output) > 1 {
+			p.logger.Warn().Int("num_lines", len(output)-1).Msg("ignoring output after no change")
+		}
+		appstats.MapIncrementInt("plugins", "unchanged")
+		p.logger.Debug().Msg("no change, reusing last metrics")
+		return nil
+	}
+
 	if len(output) == 0 {
-		p.metrics = &cgm.Metrics{}
+		p.saveMetrics(cgm.Metrics{})
 		return errors.Errorf("Zero lines of output")
 	}
 
@@ -61,7 +88,7 @@ func (p *plugin) parsePluginOutput(output []string) error {
 	numDuplicates := 0
 
 	// if first char of first line is '{' then assume output is json
-	if output[0][:1] == "{" {
+	if strings.HasPrefix(strings.TrimSpace(output[0]), "{") {
 		var jm tags.JSONMetrics
 		err := json.Unmarshal([]byte(strings.Join(output, "\n")), &jm)
 		if err != nil {
@@ -69,7 +96,7 @@ func (p *plugin) parsePluginOutput(output []string) error {
 				Err(err).
 				Str("output", strings.Join(output, "\n")).
 				Msg("parsing json")
-			p.metrics = &cgm.Metrics{}
+			p.saveMetrics(cgm.Metrics{})
 			return errors.Wrap(err, "parsing json")
 		}
 		for mn, md := range jm {
@@ -80,9 +107,20 @@ func (p *plugin) parsePluginOutput(output []string) error {
 				}
 				mn += st
 			}
-			metrics[mn] = cgm.Metric{Type: md.Type, Value: md.Value}
+			metric, err := jsonMetric(md)
+			if err != nil {
+				p.logger.Error().Err(err).Str("metric", mn).Interface("value", md.Value).Msg("invalid metric, skipping")
+				continue
+			}
+			metrics[mn] = *metric
 		}
-		p.metrics = &metrics
+		p.saveMetrics(metrics)
+		return nil
+	}
+
+	// InfluxDB line protocol (e.g. telegraf style scripts)
+	if isInfluxOutput(output) {
+		p.saveMetrics(p.parseInfluxOutput(output))
 		return nil
 	}
 
@@ -243,11 +281,83 @@ func (p *plugin) parsePluginOutput(output []string) error {
 		Int("tot_error", len(output)-(len(metrics)+numDuplicates)).
 		Msg("done processing plugin output")
 
-	p.metrics = &metrics
+	p.saveMetrics(metrics)
 
 	return nil
 }
 
+// saveMetrics sets the plugin's current metrics, for persistent plugins
+// metrics are merged into any not yet drained. NOTE: caller must hold lock.
+func (p *plugin) saveMetrics(metrics cgm.Metrics) {
+	if !p.persistent || p.metrics == nil {
+		p.metrics = &metrics
+		return
+	}
+	for mn, m := range metrics {
+		(*p.metrics)[mn] = m
+	}
+}
+
+// jsonMetric converts a metric from json plugin output into a cgm metric. Circonus
+// metric types (i, I, l, L, n, s, O) are passed through as-is, descriptive types
+// are mapped to the equivalent circonus type. A counter becomes L (or n if the value
+// is negative or fractional), a gauge becomes n, a histogram becomes n with a list of
+// samples (numbers or encoded "H[bucket]=count" strings), and text becomes s.
+func jsonMetric(md tags.JSONMetric) (*cgm.Metric, error) {
+	switch md.Type {
+	case "i", "I", "l", "L", "n", "s", "O":
+		return &cgm.Metric{Type: md.Type, Value: md.Value}, nil
+	}
+
+	switch strings.ToLower(md.Type) {
+	case "counter":
+		v, err := jsonNumber(md.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "counter")
+		}
+		if v >= 0 && v == math.Trunc(v) && v <= math.MaxUint64 {
+			return &cgm.Metric{Type: "L", Value: uint64(v)}, nil
+		}
+		return &cgm.Metric{Type: "n", Value: v}, nil
+	case "gauge":
+		v, err := jsonNumber(md.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "gauge")
+		}
+		return &cgm.Metric{Type: "n", Value: v}, nil
+	case "histogram":
+		switch hv := md.Value.(type) {
+		case []interface{}:
+			if len(hv) == 0 {
+				return nil, errors.New("histogram, no samples")
+			}
+			return &cgm.Metric{Type: "n", Value: hv}, nil
+		default:
+			// single sample
+			v, err := jsonNumber(md.Value)
+			if err != nil {
+				return nil, errors.Wrap(err, "histogram")
+			}
+			return &cgm.Metric{Type: "n", Value: []interface{}{v}}, nil
+		}
+	case "text":
+		return &cgm.Metric{Type: "s", Value: fmt.Sprintf("%v", md.Value)}, nil
+	}
+
+	return nil, errors.Errorf("unknown metric type (%s)", md.Type)
+}
+
+// jsonNumber returns a numeric value from json output (number or numeric string)
+func jsonNumber(val interface{}) (float64, error) {
+	switch v := val.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, errors.Errorf("invalid numeric value (%v)", val)
+}
+
 // exec runs a specific plugin and saves plugin output
 func (p *plugin) exec() error {
 	// NOTE: !! IMPORTANT !!
@@ -277,11 +387,42 @@ func (p *plugin) exec() error {
 
 	p.running = true
 	p.lastStart = time.Now()
-	p.cmd = exec.CommandContext(p.ctx, p.command)
+
+	if p.url != "" {
+		p.Unlock()
+		return p.execHTTP()
+	}
+
+	if p.fifo != nil {
+		p.Unlock()
+		return p.execFIFO()
+	}
+
+	// the plugin is terminated if the timeout expires or the agent is shutting down
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if p.timeout > time.Duration(0) && !p.persistent {
+		ctx, cancel = context.WithTimeout(p.ctx, p.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(p.ctx)
+	}
+	defer cancel()
+
+	if len(p.interpreter) > 0 {
+		args := append(append([]string{}, p.interpreter[1:]...), p.command)
+		p.cmd = exec.Command(p.interpreter[0], args...)
+	} else {
+		p.cmd = exec.Command(p.command)
+	}
 	p.cmd.Dir = p.runDir
+	setProcAttributes(p.cmd)
 	if p.instanceArgs != nil {
 		p.cmd.Args = append(p.cmd.Args, p.instanceArgs...)
 	}
+	sandboxCommand(p.cmd, p.sandbox)
+	if len(p.env) > 0 {
+		p.cmd.Env = append(os.Environ(), p.env...)
+	}
 
 	var errOut bytes.Buffer
 	p.cmd.Stderr = &errOut
@@ -293,6 +434,12 @@ func (p *plugin) exec() error {
 		p.lastEnd = time.Now()
 		p.lastRunDuration = time.Since(p.lastStart)
 		p.lastError = err
+		p.lastExitCode = exitCode(p.cmd)
+		if err != nil {
+			p.runsFailed++
+		} else {
+			p.runsOK++
+		}
 		p.running = false
 		p.Unlock()
 	}
@@ -320,6 +467,13 @@ func (p *plugin) exec() error {
 		return errors.Wrap(err, msg)
 	}
 
+	exited := make(chan struct{})
+	watchDone := make(chan struct{})
+	go func() {
+		p.watchProcess(ctx, p.cmd.Process, exited)
+		close(watchDone)
+	}()
+
 	for scanner.Scan() {
 		line := scanner.Text()
 
@@ -331,6 +485,13 @@ func (p *plugin) exec() error {
 			continue
 		}
 
+		// persistent plugin, tab-delimited lines are handled as they arrive
+		// (json is buffered until a blank line)
+		if p.persistent && len(lines) == 0 && !strings.HasPrefix(strings.TrimSpace(line), "{") {
+			p.parsePluginOutput([]string{line})
+			continue
+		}
+
 		// add line to buffer for processing
 		lines = append(lines, line)
 	}
@@ -345,11 +506,30 @@ func (p *plugin) exec() error {
 		runErr = errors.Wrap(err, "scanner, reading stdio")
 	}
 
+	waitErr := p.cmd.Wait()
+	close(exited)
+	<-watchDone
+
+	// output of a plugin terminated on timeout is incomplete, it is
+	// discarded and the metrics from the previous run are kept
+	if ctx.Err() == context.DeadlineExceeded {
+		appstats.MapIncrementInt("plugins", "timeouts")
+		plog.Error().
+			Str("timeout", p.timeout.String()).
+			Str("cmd", p.command).
+			Int("discarded_lines", len(lines)).
+			Msg("timed out, terminated")
+		resetStatus(errors.Errorf("timed out after %s", p.timeout))
+		return errors.Errorf("timed out after %s", p.timeout)
+	}
+
 	// parse lines if there are any in the buffer
 	// or, in case of long running plugin, any left in buffer on exit
-	p.parsePluginOutput(lines)
+	if !p.persistent || len(lines) > 0 {
+		p.parsePluginOutput(lines)
+	}
 
-	if err := p.cmd.Wait(); err != nil {
+	if err := waitErr; err != nil {
 		var stderr string
 		if errOut.Len() > 0 {
 			stderr = strings.Replace(errOut.String(), "\n", "", -1)
@@ -383,3 +563,147 @@ func (p *plugin) exec() error {
 	resetStatus(runErr)
 	return runErr
 }
+
+// runPersistent starts a persistent plugin and restarts it whenever it
+// exits, backing off (doubling from persistentMinBackoff up to
+// persistentMaxBackoff) while it continues to exit. The backoff is reset
+// once the plugin has run for longer than persistentMaxBackoff. Returns
+// when the context is done (agent shutdown), the plugin is terminated
+// by exec in that case.
+func (p *plugin) runPersistent() {
+	p.Lock()
+	if p.supervised {
+		p.Unlock()
+		return // already started (e.g. scan on reload)
+	}
+	p.supervised = true
+	p.Unlock()
+
+	defer func() {
+		p.Lock()
+		p.supervised = false
+		p.Unlock()
+	}()
+
+	backoff := persistentMinBackoff
+
+	for {
+		p.Lock()
+		persistent := p.persistent
+		p.Unlock()
+		if !persistent {
+			p.logger.Info().Msg("no longer persistent, stopping supervision")
+			return
+		}
+
+		start := time.Now()
+		err := p.exec()
+
+		if p.ctx.Err() != nil {
+			p.logger.Debug().Msg("context done, persistent plugin stopped")
+			return
+		}
+
+		if time.Since(start) > persistentMaxBackoff {
+			backoff = persistentMinBackoff
+		}
+
+		appstats.MapIncrementInt("plugins", "restarts")
+		p.logger.Warn().
+			Err(err).
+			Str("backoff", backoff.String()).
+			Msg("persistent plugin exited, restarting")
+
+		select {
+		case <-p.ctx.Done():
+			p.logger.Debug().Msg("context done, persistent plugin stopped")
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > persistentMaxBackoff {
+			backoff = persistentMaxBackoff
+		}
+	}
+}
+
+// runMetrics returns metrics describing the plugin's runs, the last exit code,
+// last run duration (milliseconds), and counts of successful and failed runs.
+// No metrics are returned if the plugin has not completed a run.
+func (p *plugin) runMetrics() cgm.Metrics {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.lastEnd.IsZero() {
+		return cgm.Metrics{}
+	}
+
+	prefix := runMetricPrefix + metricDelimiter
+	metrics := cgm.Metrics{
+		prefix + "exit_code":   cgm.Metric{Type: "i", Value: int32(p.lastExitCode)},
+		prefix + "duration":    cgm.Metric{Type: "n", Value: float64(p.lastRunDuration) / float64(time.Millisecond)},
+		prefix + "runs_ok":     cgm.Metric{Type: "L", Value: p.runsOK},
+		prefix + "runs_failed": cgm.Metric{Type: "L", Value: p.runsFailed},
+	}
+	// only reported once a slow run has overrun (see Plugins.execPlugins)
+	if p.staleRuns > 0 {
+		metrics[prefix+"stale_runs"] = cgm.Metric{Type: "L", Value: p.staleRuns}
+	}
+	return metrics
+}
+
+// overrun records that the plugin's last result is served again because its
+// previous (slow) run has not finished
+func (p *plugin) overrun() {
+	p.Lock()
+	p.staleRuns++
+	p.logger.Warn().
+		Str("last_run_duration", p.lastRunDuration.String()).
+		Str("running_since", p.lastStart.Format(time.RFC3339Nano)).
+		Msg("slow plugin still running, serving last result")
+	p.Unlock()
+	appstats.MapIncrementInt("plugins", "stale_runs")
+}
+
+// exitCode returns the exit code of a completed command, -1 if the
+// command did not start, was terminated by a signal, or is still running
+func exitCode(cmd *exec.Cmd) int {
+	if cmd == nil || cmd.ProcessState == nil {
+		return -1
+	}
+	if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok {
+		return ws.ExitStatus()
+	}
+	if cmd.ProcessState.Success() {
+		return 0
+	}
+	return -1
+}
+
+// watchProcess terminates the plugin's process (group) if the context is done
+// before the process exits (timeout or agent shutdown). SIGTERM is sent first,
+// followed by SIGKILL if the process has not exited within killGracePeriod.
+func (p *plugin) watchProcess(ctx context.Context, proc *os.Process, exited <-chan struct{}) {
+	select {
+	case <-exited:
+		return
+	case <-ctx.Done():
+	}
+
+	p.logger.Warn().Err(ctx.Err()).Msg("terminating plugin")
+	if err := terminateProcess(proc); err != nil {
+		p.logger.Debug().Err(err).Msg("sending terminate")
+	}
+
+	select {
+	case <-exited:
+		return
+	case <-time.After(killGracePeriod):
+	}
+
+	p.logger.Warn().Str("grace_period", killGracePeriod.String()).Msg("plugin did not exit, killing")
+	if err := killProcess(proc); err != nil {
+		p.logger.Debug().Err(err).Msg("sending kill")
+	}
+}
@@ -107,6 +107,54 @@ func TestParsePluginOutput(t *testing.T) {
 		}
 	}
 
+	var jsonTests = []struct {
+		description  string
+		output       []string
+		expectedType string
+		expectedOK   bool
+	}{
+		{"counter", []string{`{"metric": {"_type": "counter", "_value": 10}}`}, "L", true},
+		{"counter (string)", []string{`{"metric": {"_type": "counter", "_value": "10"}}`}, "L", true},
+		{"counter (fractional)", []string{`{"metric": {"_type": "counter", "_value": 1.5}}`}, "n", true},
+		{"gauge", []string{`{"metric": {"_type": "gauge", "_value": 1.5}}`}, "n", true},
+		{"histogram", []string{`{"metric": {"_type": "histogram", "_value": [1, 2, 3]}}`}, "n", true},
+		{"histogram (encoded)", []string{`{"metric": {"_type": "histogram", "_value": ["H[1.0e+00]=3"]}}`}, "n", true},
+		{"histogram (single)", []string{`{"metric": {"_type": "histogram", "_value": 1}}`}, "n", true},
+		{"text", []string{`{"metric": {"_type": "text", "_value": "foo"}}`}, "s", true},
+		{"multi-line", []string{"{", `"metric": {"_type": "gauge", "_value": 1}`, "}"}, "n", true},
+		{"invalid gauge", []string{`{"metric": {"_type": "gauge", "_value": "foo"}}`}, "", false},
+		{"invalid histogram", []string{`{"metric": {"_type": "histogram", "_value": []}}`}, "", false},
+		{"invalid type", []string{`{"metric": {"_type": "foo", "_value": 1}}`}, "", false},
+	}
+
+	for _, jt := range jsonTests {
+		t.Logf("json - %s (%#v)", jt.description, jt.output)
+		p.metrics = nil
+		err := p.parsePluginOutput(jt.output)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		m, ok := (*p.metrics)["metric"]
+		if ok != jt.expectedOK {
+			t.Fatalf("expected metric %v, have (%#v)", jt.expectedOK, p.metrics)
+		}
+		if ok && m.Type != jt.expectedType {
+			t.Fatalf("expected type %s, got %s", jt.expectedType, m.Type)
+		}
+	}
+
+	t.Log("json metric w/tags")
+	{
+		p.metrics = nil
+		err := p.parsePluginOutput([]string{`{"metric": {"_type": "gauge", "_value": 1, "_tags": ["foo:bar"]}}`})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if _, ok := (*p.metrics)["metric|ST[foo:bar]"]; !ok {
+			t.Fatalf("expected tagged metric, have (%#v)", p.metrics)
+		}
+	}
+
 	var tabDelimTests = []struct {
 		description     string
 		output          []string
@@ -144,6 +192,47 @@ func TestParsePluginOutput(t *testing.T) {
 			t.Fatalf("expected %d metric(s), have (%#v) - test output: %#v", tdt.expectedMetrics, p.metrics, tdt.output)
 		}
 	}
+
+	t.Log("no change (pending metrics)")
+	{
+		p.metrics = nil
+		p.prevMetrics = nil
+		if err := p.parsePluginOutput([]string{"metric\tL\t1"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := p.parsePluginOutput([]string{"# nochange"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if m := p.drain(); len(*m) != 1 {
+			t.Fatalf("expected 1 metric, have (%#v)", m)
+		}
+	}
+
+	t.Log("no change (drained metrics)")
+	{
+		if err := p.parsePluginOutput([]string{" # nochange ", "metric\tL\t2"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		m := p.drain()
+		if len(*m) != 1 {
+			t.Fatalf("expected 1 metric, have (%#v)", m)
+		}
+		if v := (*m)["metric"].Value; v != uint64(1) {
+			t.Fatalf("expected previous value 1, got %v", v)
+		}
+	}
+
+	t.Log("no change (no previous metrics)")
+	{
+		p.metrics = nil
+		p.prevMetrics = nil
+		if err := p.parsePluginOutput([]string{"# nochange"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if m := p.drain(); len(*m) != 0 {
+			t.Fatalf("expected 0 metrics, have (%#v)", m)
+		}
+	}
 }
 
 func TestExec(t *testing.T) {
@@ -242,4 +331,290 @@ func TestExec(t *testing.T) {
 			t.Fatalf("expected '%s' metric", metricName)
 		}
 	}
+	t.Log("interpreter")
+	{
+		if runtime.GOOS != "windows" {
+			p.command = path.Join(testDir, "test.sh")
+			p.instanceArgs = nil
+			p.interpreter = []string{"bash", "--norc"}
+			err := p.exec()
+			p.interpreter = nil
+			if err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			if _, ok := (*p.metrics)["metric"]; !ok {
+				t.Fatalf("expected 'metric' metric, got %#v", *p.metrics)
+			}
+		}
+	}
+
+	t.Log("timeout")
+	{
+		if runtime.GOOS == "windows" {
+			t.Skip("timeout test requires bash")
+		}
+		origGrace := killGracePeriod
+		killGracePeriod = 500 * time.Millisecond
+		p.command = path.Join(testDir, "timeout", "hang.sh")
+		p.instanceArgs = nil
+		p.timeout = 500 * time.Millisecond
+		start := time.Now()
+		err := p.exec()
+		elapsed := time.Since(start)
+		killGracePeriod = origGrace
+		p.timeout = time.Duration(0)
+		expectedErr := errors.Errorf("timed out after 500ms")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Fatalf("expected (%s) got (%s)", expectedErr, err)
+		}
+		if elapsed > 5*time.Second {
+			t.Fatalf("expected plugin to be killed, took %s", elapsed)
+		}
+		if p.running {
+			t.Fatal("expected running to be reset")
+		}
+	}
+
+	t.Log("timeout (partial output discarded)")
+	{
+		if runtime.GOOS == "windows" {
+			t.Skip("timeout test requires bash")
+		}
+		origGrace := killGracePeriod
+		killGracePeriod = 100 * time.Millisecond
+		p.command = path.Join(testDir, "timeout", "hang.sh")
+		p.instanceArgs = nil
+		p.timeout = 500 * time.Millisecond
+		p.metrics = &cgm.Metrics{"previous": cgm.Metric{Type: "n", Value: 1}}
+		err := p.exec()
+		killGracePeriod = origGrace
+		p.timeout = time.Duration(0)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if _, ok := (*p.metrics)["metric"]; ok {
+			t.Fatalf("expected partial output to be discarded, got %#v", *p.metrics)
+		}
+		if _, ok := (*p.metrics)["previous"]; !ok {
+			t.Fatalf("expected previous metrics to be kept, got %#v", *p.metrics)
+		}
+	}
+
+	t.Log("env")
+	{
+		if runtime.GOOS == "windows" {
+			t.Skip("env test requires bash")
+		}
+		p.command = path.Join(testDir, "env", "env.sh")
+		p.instanceArgs = nil
+		p.env = []string{"CA_PLUGIN_NAME=foo", "CA_PLUGIN_INSTANCE=sda", "FOO=bar"}
+		err := p.exec()
+		p.env = nil
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		metricName := "foo`sda`bar"
+		if _, ok := (*p.metrics)[metricName]; !ok {
+			t.Fatalf("expected '%s' metric, got %#v", metricName, *p.metrics)
+		}
+	}
+
+	t.Log("TTL (cached output)")
+	{
+		if runtime.GOOS == "windows" {
+			p.command = path.Join(testDir, "testwin.bat")
+		} else {
+			p.command = path.Join(testDir, "test.sh")
+		}
+		p.instanceArgs = nil
+		p.lastEnd = time.Time{}
+		p.runTTL = 5 * time.Minute
+		if err := p.exec(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		first := p.drain()
+		if len(*first) == 0 {
+			t.Fatal("expected metrics")
+		}
+		lastStart := p.lastStart
+		if err := p.exec(); err == nil {
+			t.Fatal("expected error")
+		}
+		if !p.lastStart.Equal(lastStart) {
+			t.Fatal("expected plugin not to be re-executed")
+		}
+		cached := p.drain()
+		if len(*cached) != len(*first) {
+			t.Fatalf("expected cached metrics (%#v) got (%#v)", *first, *cached)
+		}
+		p.runTTL = time.Duration(0)
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	t.Log("Testing persistent plugins")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("accumulate and drain")
+	{
+		p := &plugin{
+			ctx:        context.Background(),
+			id:         "test",
+			name:       "test",
+			persistent: true,
+		}
+		if err := p.parsePluginOutput([]string{"a\tn\t1"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := p.parsePluginOutput([]string{"b\tn\t2"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := p.parsePluginOutput([]string{}); err == nil {
+			t.Fatal("expected error")
+		}
+		data := p.drain()
+		if len(*data) != 2 {
+			t.Fatalf("expected 2 metrics, got %#v", *data)
+		}
+		data = p.drain()
+		if len(*data) != 0 {
+			t.Fatalf("expected 0 metrics after drain, got %#v", *data)
+		}
+	}
+
+	t.Log("restart w/backoff, stop on context done")
+	{
+		if runtime.GOOS == "windows" {
+			t.Skip("persistent test requires bash")
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			t.Fatalf("unable to get cwd (%s)", err)
+		}
+
+		origMin, origMax := persistentMinBackoff, persistentMaxBackoff
+		persistentMinBackoff = 50 * time.Millisecond
+		persistentMaxBackoff = 200 * time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		p := &plugin{
+			ctx:        ctx,
+			id:         "stream",
+			name:       "stream",
+			command:    path.Join(dir, "testdata", "stream", "stream.sh"),
+			persistent: true,
+			timeout:    time.Nanosecond, // ignored for persistent plugins
+		}
+
+		done := make(chan struct{})
+		go func() {
+			p.runPersistent()
+			close(done)
+		}()
+
+		time.Sleep(500 * time.Millisecond)
+
+		p.Lock()
+		lastStart := p.lastStart
+		p.Unlock()
+
+		data := p.drain()
+		for _, mn := range []string{"a", "b", "c"} {
+			if _, ok := (*data)[mn]; !ok {
+				t.Fatalf("expected '%s' metric, got %#v", mn, *data)
+			}
+		}
+
+		time.Sleep(500 * time.Millisecond)
+
+		p.Lock()
+		restarted := p.lastStart.After(lastStart)
+		p.Unlock()
+		if !restarted {
+			t.Fatal("expected plugin to be restarted")
+		}
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected runPersistent to return after context done")
+		}
+
+		persistentMinBackoff, persistentMaxBackoff = origMin, origMax
+	}
+}
+
+func TestRunMetrics(t *testing.T) {
+	t.Log("Testing runMetrics")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires bash")
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unable to get cwd (%s)", err)
+	}
+
+	p := &plugin{
+		ctx:  context.Background(),
+		id:   "test",
+		name: "test",
+	}
+
+	t.Log("not run")
+	{
+		if m := p.runMetrics(); len(m) != 0 {
+			t.Fatalf("expected no metrics, got %#v", m)
+		}
+	}
+
+	t.Log("success")
+	{
+		p.command = path.Join(dir, "testdata", "test.sh")
+		if err := p.exec(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		m := p.runMetrics()
+		prefix := runMetricPrefix + metricDelimiter
+		if v := m[prefix+"exit_code"].Value.(int32); v != 0 {
+			t.Fatalf("expected exit code 0, got %d", v)
+		}
+		if v := m[prefix+"runs_ok"].Value.(uint64); v != 1 {
+			t.Fatalf("expected 1 ok run, got %d", v)
+		}
+		if v := m[prefix+"runs_failed"].Value.(uint64); v != 0 {
+			t.Fatalf("expected 0 failed runs, got %d", v)
+		}
+		if _, ok := m[prefix+"duration"]; !ok {
+			t.Fatalf("expected duration, got %#v", m)
+		}
+	}
+
+	t.Log("failure")
+	{
+		p.command = path.Join(dir, "testdata", "error.sh")
+		if err := p.exec(); err == nil {
+			t.Fatal("expected error")
+		}
+		m := p.runMetrics()
+		prefix := runMetricPrefix + metricDelimiter
+		if v := m[prefix+"exit_code"].Value.(int32); v == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if v := m[prefix+"runs_ok"].Value.(uint64); v != 1 {
+			t.Fatalf("expected 1 ok run, got %d", v)
+		}
+		if v := m[prefix+"runs_failed"].Value.(uint64); v != 1 {
+			t.Fatalf("expected 1 failed run, got %d", v)
+		}
+	}
 }
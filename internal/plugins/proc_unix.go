@@ -0,0 +1,70 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// interpreter returns nil, plugins are run directly (scripts
+// are handled by the kernel via their #! line)
+func interpreter(cmdName string) []string {
+	return nil
+}
+
+// setProcAttributes runs the plugin in its own process group so that
+// the plugin and any children it spawns can be signaled together
+func setProcAttributes(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// sandboxSupported indicates plugin sandbox settings can be applied
+const sandboxSupported = true
+
+// sandboxShell runs the plugin when the sandbox has resource limits, the
+// limits are set with ulimit then the shell is replaced by the plugin
+const sandboxShell = "/bin/sh"
+
+// sandboxCommand applies the plugin's sandbox (if any) to the command, the
+// plugin is run as the sandbox user/group (supplementary groups are cleared)
+// and resource limits are set (soft and hard, the plugin cannot raise them).
+// NOTE: must be called after setProcAttributes and the command's arguments are set.
+func sandboxCommand(cmd *exec.Cmd, sb *sandbox) {
+	if sb == nil {
+		return
+	}
+
+	if sb.credential {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: sb.uid, Gid: sb.gid}
+	}
+
+	limits := sb.ulimitCommands()
+	if limits == "" {
+		return
+	}
+
+	// $0 is the plugin, "$@" its arguments
+	script := limits + ` && exec "$0" "$@"`
+	cmd.Args = append([]string{sandboxShell, "-c", script, cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = sandboxShell
+}
+
+// terminateProcess sends SIGTERM to the plugin's process group
+func terminateProcess(proc *os.Process) error {
+	return syscall.Kill(-proc.Pid, syscall.SIGTERM)
+}
+
+// killProcess sends SIGKILL to the plugin's process group
+func killProcess(proc *os.Process) error {
+	return syscall.Kill(-proc.Pid, syscall.SIGKILL)
+}
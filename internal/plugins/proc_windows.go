@@ -0,0 +1,48 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// interpreters used to run script plugins, by file extension (anything
+// else, e.g. .exe, is run directly)
+var interpreters = map[string][]string{
+	".bat": {"cmd.exe", "/c"},
+	".cmd": {"cmd.exe", "/c"},
+	".ps1": {"powershell.exe", "-NoLogo", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"},
+}
+
+// interpreter returns the command (and arguments) used to run a plugin
+// script, based on its file extension, nil if the plugin is run directly
+func interpreter(cmdName string) []string {
+	return interpreters[strings.ToLower(filepath.Ext(cmdName))]
+}
+
+// setProcAttributes is a no-op, process groups are not used on windows
+func setProcAttributes(cmd *exec.Cmd) {}
+
+// sandboxSupported indicates plugin sandbox settings can be applied
+const sandboxSupported = false
+
+// sandboxCommand is a no-op, plugin sandbox settings are not supported on windows
+func sandboxCommand(cmd *exec.Cmd, sb *sandbox) {}
+
+// terminateProcess kills the plugin process (there is no SIGTERM on windows)
+func terminateProcess(proc *os.Process) error {
+	return proc.Kill()
+}
+
+// killProcess kills the plugin process
+func killProcess(proc *os.Process) error {
+	return proc.Kill()
+}
@@ -0,0 +1,44 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build windows
+
+package plugins
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestInterpreter(t *testing.T) {
+	t.Log("Testing interpreter")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tests := []struct {
+		cmdName string
+		expect  string
+	}{
+		{`C:\plugins\foo.exe`, ""},
+		{`C:\plugins\foo.bat`, "cmd.exe"},
+		{`C:\plugins\foo.CMD`, "cmd.exe"},
+		{`C:\plugins\foo.ps1`, "powershell.exe"},
+	}
+
+	for _, test := range tests {
+		t.Logf("%s -> %q", test.cmdName, test.expect)
+		interp := interpreter(test.cmdName)
+		if test.expect == "" {
+			if interp != nil {
+				t.Fatalf("expected nil, got %v", interp)
+			}
+			continue
+		}
+		if len(interp) == 0 || interp[0] != test.expect {
+			t.Fatalf("expected %s, got %v", test.expect, interp)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// sandboxAll is the plugin_sandbox name applying to all plugins without their own settings
+const sandboxAll = "*"
+
+// loadSandboxes parses the per-plugin execution restrictions
+func (p *Plugins) loadSandboxes() error {
+	var cfgs map[string]config.PluginSandbox
+	if err := viper.UnmarshalKey(config.KeyPluginSandbox, &cfgs); err != nil {
+		return errors.Wrap(err, "parsing plugin sandbox")
+	}
+
+	p.sandboxes = make(map[string]*sandbox, len(cfgs))
+	if len(cfgs) == 0 {
+		return nil
+	}
+
+	if !sandboxSupported {
+		p.logger.Warn().Msg("plugin sandbox not supported on this platform, ignoring")
+		return nil
+	}
+
+	for name, cfg := range cfgs {
+		sb, err := p.parseSandbox(name, cfg)
+		if err != nil {
+			return errors.Wrapf(err, "parsing plugin sandbox for %s", name)
+		}
+		p.sandboxes[name] = sb
+	}
+
+	return nil
+}
+
+// parseSandbox validates the settings for a plugin sandbox. A user or group
+// other than the agent's own requires the agent to run as root, otherwise
+// the privilege drop is skipped (with a warning) and only the limits apply.
+func (p *Plugins) parseSandbox(name string, cfg config.PluginSandbox) (*sandbox, error) {
+	sb := &sandbox{openFiles: cfg.OpenFiles}
+
+	if cfg.CPUTime != "" {
+		d, err := time.ParseDuration(cfg.CPUTime)
+		if err != nil {
+			return nil, errors.Wrap(err, "cpu_time")
+		}
+		if d < time.Second {
+			return nil, errors.Errorf("invalid cpu_time (%s), minimum 1s", cfg.CPUTime)
+		}
+		sb.cpuTime = uint64(math.Ceil(d.Seconds()))
+	}
+
+	if cfg.Memory != "" {
+		size, err := units.ParseBase2Bytes(cfg.Memory)
+		if err != nil {
+			return nil, errors.Wrap(err, "memory")
+		}
+		if size < units.KiB {
+			return nil, errors.Errorf("invalid memory (%s), minimum 1KiB", cfg.Memory)
+		}
+		sb.memory = uint64(size / units.KiB)
+	}
+
+	if cfg.User == "" && cfg.Group == "" {
+		return sb, nil
+	}
+
+	uid, gid := os.Geteuid(), os.Getegid()
+	if cfg.User != "" {
+		u, err := lookupUser(cfg.User)
+		if err != nil {
+			return nil, err
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return nil, errors.Wrapf(err, "user %s uid", cfg.User)
+		}
+		if gid, err = strconv.Atoi(u.Gid); err != nil {
+			return nil, errors.Wrapf(err, "user %s gid", cfg.User)
+		}
+	}
+	if cfg.Group != "" {
+		g, err := lookupGroup(cfg.Group)
+		if err != nil {
+			return nil, err
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return nil, errors.Wrapf(err, "group %s gid", cfg.Group)
+		}
+	}
+
+	if uid == os.Geteuid() && gid == os.Getegid() {
+		return sb, nil // already running as the user and group
+	}
+
+	if os.Geteuid() != 0 {
+		p.logger.Warn().
+			Str("plugin", name).
+			Str("user", cfg.User).
+			Str("group", cfg.Group).
+			Msg("agent not running as root, unable to change plugin user/group, skipping privilege drop")
+		return sb, nil
+	}
+
+	sb.credential = true
+	sb.uid = uint32(uid)
+	sb.gid = uint32(gid)
+
+	return sb, nil
+}
+
+// pluginSandbox returns the sandbox for a specific plugin, the first name
+// with settings is used (e.g. plugin`instance, then plugin, then "*"),
+// nil if the plugin is not sandboxed
+func (p *Plugins) pluginSandbox(names ...string) *sandbox {
+	for _, name := range append(names, sandboxAll) {
+		if sb, ok := p.sandboxes[name]; ok {
+			return sb
+		}
+	}
+	return nil
+}
+
+// ulimitCommands returns the shell ulimit commands for the sandbox resource
+// limits, empty if there are no limits. Some shells (e.g. dash) accept only
+// one resource per ulimit, each limit is set by a separate command.
+func (sb *sandbox) ulimitCommands() string {
+	if sb == nil {
+		return ""
+	}
+	cmds := []string{}
+	if sb.cpuTime > 0 {
+		cmds = append(cmds, fmt.Sprintf("ulimit -t %d", sb.cpuTime))
+	}
+	if sb.memory > 0 {
+		cmds = append(cmds, fmt.Sprintf("ulimit -v %d", sb.memory))
+	}
+	if sb.openFiles > 0 {
+		cmds = append(cmds, fmt.Sprintf("ulimit -n %d", sb.openFiles))
+	}
+	return strings.Join(cmds, " && ")
+}
+
+// lookupUser finds a user by name or numeric uid
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, nerr := strconv.Atoi(name); nerr == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+	}
+	return nil, errors.Wrapf(err, "user %s", name)
+}
+
+// lookupGroup finds a group by name or numeric gid
+func lookupGroup(name string) (*user.Group, error) {
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		return g, nil
+	}
+	if _, nerr := strconv.Atoi(name); nerr == nil {
+		if g, err := user.LookupGroupId(name); err == nil {
+			return g, nil
+		}
+	}
+	return nil, errors.Wrapf(err, "group %s", name)
+}
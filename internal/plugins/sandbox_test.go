@@ -0,0 +1,197 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestLoadSandboxes(t *testing.T) {
+	t.Log("Testing loadSandboxes")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("none")
+	{
+		viper.Reset()
+		p := &Plugins{}
+		if err := p.loadSandboxes(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if sb := p.pluginSandbox("foo"); sb != nil {
+			t.Fatalf("expected nil, got (%#v)", sb)
+		}
+	}
+
+	t.Log("invalid cpu_time")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginSandbox, map[string]interface{}{
+			"foo": map[string]interface{}{"cpu_time": "500ms"},
+		})
+		p := &Plugins{}
+		if err := p.loadSandboxes(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid memory")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginSandbox, map[string]interface{}{
+			"foo": map[string]interface{}{"memory": "abc"},
+		})
+		p := &Plugins{}
+		if err := p.loadSandboxes(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid user")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginSandbox, map[string]interface{}{
+			"foo": map[string]interface{}{"user": "no_such_user_abc"},
+		})
+		p := &Plugins{}
+		if err := p.loadSandboxes(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("limits w/default")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginSandbox, map[string]interface{}{
+			"*":   map[string]interface{}{"open_files": 64},
+			"foo": map[string]interface{}{"cpu_time": "1500ms", "memory": "256MB", "open_files": 128},
+		})
+		p := &Plugins{}
+		if err := p.loadSandboxes(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		sb := p.pluginSandbox("foo`inst", "foo")
+		if sb == nil {
+			t.Fatal("expected foo sandbox")
+		}
+		if sb.credential {
+			t.Fatal("expected no credential")
+		}
+		expect := "ulimit -t 2 && ulimit -v 262144 && ulimit -n 128"
+		if args := sb.ulimitCommands(); args != expect {
+			t.Fatalf("expected (%s), got (%s)", expect, args)
+		}
+
+		sb = p.pluginSandbox("bar")
+		if sb == nil {
+			t.Fatal("expected default sandbox")
+		}
+		if args := sb.ulimitCommands(); args != "ulimit -n 64" {
+			t.Fatalf("expected (ulimit -n 64), got (%s)", args)
+		}
+	}
+
+	t.Log("user")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginSandbox, map[string]interface{}{
+			"foo": map[string]interface{}{"user": "nobody"},
+		})
+		p := &Plugins{}
+		if err := p.loadSandboxes(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		sb := p.pluginSandbox("foo")
+		if sb == nil {
+			t.Fatal("expected foo sandbox")
+		}
+		// privileges are only dropped when the agent runs as root
+		if sb.credential != (os.Geteuid() == 0) {
+			t.Fatalf("unexpected credential setting (%v)", sb.credential)
+		}
+		if sb.ulimitCommands() != "" {
+			t.Fatalf("expected no limits, got (%s)", sb.ulimitCommands())
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestSandboxCommand(t *testing.T) {
+	t.Log("Testing sandboxCommand")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("nil sandbox")
+	{
+		cmd := exec.Command("sh", "-c", "true")
+		path := cmd.Path
+		sandboxCommand(cmd, nil)
+		if cmd.Path != path {
+			t.Fatalf("expected (%s), got (%s)", path, cmd.Path)
+		}
+	}
+
+	t.Log("limits")
+	{
+		cmd := exec.Command("sh", "-c", "ulimit -n")
+		setProcAttributes(cmd)
+		sandboxCommand(cmd, &sandbox{openFiles: 64})
+		if cmd.Path != sandboxShell {
+			t.Fatalf("expected (%s), got (%s)", sandboxShell, cmd.Path)
+		}
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if strings.TrimSpace(string(out)) != "64" {
+			t.Fatalf("expected 64, got (%s)", out)
+		}
+	}
+
+	t.Log("multiple limits")
+	{
+		cmd := exec.Command("sh", "-c", "ulimit -t; ulimit -v; ulimit -n")
+		setProcAttributes(cmd)
+		sandboxCommand(cmd, &sandbox{cpuTime: 30, memory: 1048576, openFiles: 64})
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if limits := strings.Fields(string(out)); strings.Join(limits, " ") != "30 1048576 64" {
+			t.Fatalf("expected (30 1048576 64), got (%s)", out)
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		t.Log("credential - skipping, not root")
+		return
+	}
+
+	t.Log("credential")
+	{
+		cmd := exec.Command("sh", "-c", "id -u")
+		setProcAttributes(cmd)
+		sandboxCommand(cmd, &sandbox{credential: true, uid: 65534, gid: 65534})
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if strings.TrimSpace(string(out)) != "65534" {
+			t.Fatalf("expected 65534, got (%s)", out)
+		}
+	}
+}
@@ -6,9 +6,9 @@
 package plugins
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -17,21 +17,34 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins"
-	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/maier/go-appstats"
 	"github.com/pkg/errors"
-	"github.com/spf13/viper"
 )
 
-// Scan the plugin directory for new/updated plugins
+// Scan the plugin directory for new/updated plugins, each plugin is run
+// once (asynchronously) and persistent plugins are started
 func (p *Plugins) Scan(b *builtins.Builtins) error {
+	return p.scan(b, true)
+}
+
+// Load scans the plugin directory like Scan, without the initial run, the
+// plugins are run by Run (e.g. one-shot collection)
+func (p *Plugins) Load(b *builtins.Builtins) error {
+	return p.scan(b, false)
+}
+
+// scan finds and configures plugins, optionally running each plugin once
+func (p *Plugins) scan(b *builtins.Builtins, run bool) error {
+	// a scan waits for a plugin run in progress and for slow plugins
+	// still running in the background, plugins are not replaced or
+	// deactivated while they are being run
+	p.runmu.Lock()
+	defer p.runmu.Unlock()
+	p.background.Wait()
+
 	p.Lock()
 	defer p.Unlock()
 
-	if p.pluginDir == "" {
-		return nil
-	}
-
 	// initialRun fires each plugin one time. Unlike 'Run' it does
 	// not wait for plugins to finish this will provides:
 	//
@@ -43,6 +56,13 @@ func (p *Plugins) Scan(b *builtins.Builtins) error {
 			p.logger.Debug().
 				Str("plugin", id).
 				Msg("Initializing")
+			plug.Lock()
+			persistent := plug.persistent
+			plug.Unlock()
+			if persistent {
+				go plug.runPersistent()
+				continue
+			}
 			go plug.exec()
 		}
 		return nil
@@ -53,8 +73,21 @@ func (p *Plugins) Scan(b *builtins.Builtins) error {
 	// 	return errors.Wrap(err, "stopping plugin(s)")
 	// }
 
-	if err := p.scanPluginDirectory(b); err != nil {
-		return errors.Wrap(err, "plugin directory scan")
+	if p.pluginDir != "" {
+		if err := p.scanPluginDirectory(b); err != nil {
+			return errors.Wrap(err, "plugin directory scan")
+		}
+	}
+
+	p.configureHTTPPlugins(b)
+	p.configureFIFOPlugins(b)
+
+	if len(p.active) == 0 {
+		p.logger.Warn().Msg("no active plugins found")
+	}
+
+	if !run {
+		return nil
 	}
 
 	if err := initialRun(); err != nil {
@@ -90,10 +123,10 @@ func (p *Plugins) scanPluginDirectory(b *builtins.Builtins) error {
 	if err != nil {
 		return errors.Wrap(err, "compiling ttl regex")
 	}
-	ttlUnitRx, err := regexp.Compile(`(ms|s|m|h)$`)
-	if err != nil {
-		return errors.Wrap(err, "compiling ttl unit regex")
-	}
+
+	// plugins found in this scan, any others which are active have
+	// been removed from the plugin directory (or their config)
+	seen := make(map[string]bool)
 
 	for _, fi := range files {
 		fileName := fi.Name()
@@ -123,7 +156,7 @@ func (p *Plugins) scanPluginDirectory(b *builtins.Builtins) error {
 			continue
 		}
 
-		if fileExt == ".conf" || fileExt == ".json" {
+		if fileExt == ".conf" || isPluginConfigExt(fileExt) {
 			p.logger.Debug().
 				Str("file", fileName).
 				Msg("config file, ignoring")
@@ -176,33 +209,29 @@ func (p *Plugins) scanPluginDirectory(b *builtins.Builtins) error {
 			continue
 		}
 
-		var cfg map[string][]string
-
-		// check for config file
-		cfgFile := filepath.Join(p.pluginDir, fmt.Sprintf("%s.json", fileBase))
-		if data, err := ioutil.ReadFile(cfgFile); err != nil {
-			if !os.IsNotExist(err) {
-				p.logger.Warn().
-					Err(err).
-					Str("config", cfgFile).
-					Str("plugin", fileBase).Msg("plugin config")
-			}
-		} else {
-			if len(data) > 0 {
-				err := json.Unmarshal(data, &cfg)
-				if err != nil {
-					p.logger.Warn().
-						Err(err).
-						Str("config", cfgFile).
-						Str("plugin", fileBase).
-						Str("data", string(data)).
-						Msg("parsing config")
-				}
+		// check for config file, a plugin with an invalid config is not run
+		cfg, err := p.loadPluginConfig(fileBase)
+		if err != nil {
+			p.logger.Error().
+				Err(err).
+				Str("plugin", fileBase).
+				Msg("plugin config, disabling plugin")
+			continue
+		}
+		if cfg != nil {
+			p.logger.Debug().
+				Str("config", cfg.file).
+				Str("plugin", fileBase).
+				Msg("loaded plugin config")
+		}
 
-				p.logger.Debug().
-					Str("config", fmt.Sprintf("%+v", cfg)).
-					Msg("loaded plugin config")
-			}
+		// check for manifest (metric metadata)
+		meta, err := p.loadManifest(fileBase)
+		if err != nil {
+			p.logger.Warn().
+				Err(err).
+				Str("plugin", fileBase).
+				Msg("plugin manifest, ignoring")
 		}
 
 		// parse fileBase for _ttl(.+)
@@ -211,11 +240,7 @@ func (p *Plugins) scanPluginDirectory(b *builtins.Builtins) error {
 		if len(matches) > 0 && len(matches[0]) > 1 {
 			ttl := matches[0][1]
 			if ttl != "" {
-				if !ttlUnitRx.MatchString(ttl) {
-					ttl += viper.GetString(config.KeyPluginTTLUnits)
-				}
-
-				if d, err := time.ParseDuration(ttl); err != nil {
+				if d, err := parseTTL(ttl); err != nil {
 					p.logger.Warn().Err(err).Str("ttl", ttl).Msg("parsing plugin ttl, ignoring ttl")
 				} else {
 					runTTL = d
@@ -223,47 +248,91 @@ func (p *Plugins) scanPluginDirectory(b *builtins.Builtins) error {
 			}
 		}
 
-		if cfg == nil {
+		if cfg != nil && cfg.ttl > 0 {
+			runTTL = cfg.ttl
+		}
+		var cfgTimeout time.Duration
+		if cfg != nil {
+			cfgTimeout = cfg.timeout
+		}
+
+		if cfg == nil || len(cfg.instances) == 0 {
 			plug, ok := p.active[fileBase]
+			if ok && (plug.url != "" || plug.fifo != nil) {
+				// plugin directory takes precedence over an http (or fifo) plugin with the same name
+				plug.cancel()
+				ok = false
+			}
 			if !ok {
+				ctx, cancel := context.WithCancel(p.ctx)
 				p.active[fileBase] = &plugin{
-					ctx:    p.ctx,
+					cancel: cancel,
+					ctx:    ctx,
 					id:     fileBase,
 					name:   fileBase,
 					logger: p.logger.With().Str("plugin", fileBase).Logger(),
 					runDir: p.pluginDir,
-					runTTL: runTTL,
 				}
 				plug = p.active[fileBase]
 			}
+			seen[fileBase] = true
 
 			appstats.MapIncrementInt("plugins", "total")
+			plug.Lock()
 			plug.command = cmdName
+			plug.instanceArgs = nil
+			plug.interpreter = interpreter(cmdName)
+			plug.meta = meta
+			plug.env = p.pluginEnv(fileBase, "", nil)
+			plug.persistent = p.isPersistent(fileBase)
+			plug.runTTL = p.pluginTTL(runTTL, fileBase)
+			plug.sandbox = p.pluginSandbox(fileBase)
+			plug.timeout = p.pluginTimeout(cfgTimeout, fileBase)
+			plug.Unlock()
 			p.logger.Info().
 				Str("id", fileBase).
 				Str("cmd", cmdName).
 				Msg("Activating plugin")
 
 		} else {
-			for inst, args := range cfg {
-				pluginName := fmt.Sprintf("%s`%s", fileBase, inst)
+			for inst, icfg := range cfg.instances {
+				pluginName := fileBase
+				if inst != "" {
+					pluginName = fmt.Sprintf("%s`%s", fileBase, inst)
+				}
 				plug, ok := p.active[pluginName]
+				if ok && (plug.url != "" || plug.fifo != nil) {
+					// plugin directory takes precedence over an http (or fifo) plugin with the same name
+					plug.cancel()
+					ok = false
+				}
 				if !ok {
+					ctx, cancel := context.WithCancel(p.ctx)
 					p.active[pluginName] = &plugin{
-						ctx:          p.ctx,
-						id:           fileBase,
-						instanceID:   inst,
-						instanceArgs: args,
-						name:         pluginName,
-						logger:       p.logger.With().Str("plugin", pluginName).Logger(),
-						runDir:       p.pluginDir,
-						runTTL:       runTTL,
+						cancel:     cancel,
+						ctx:        ctx,
+						id:         fileBase,
+						instanceID: inst,
+						name:       pluginName,
+						logger:     p.logger.With().Str("plugin", pluginName).Logger(),
+						runDir:     p.pluginDir,
 					}
 					plug = p.active[pluginName]
 				}
+				seen[pluginName] = true
 
 				appstats.MapIncrementInt("plugins", "total")
+				plug.Lock()
 				plug.command = cmdName
+				plug.instanceArgs = icfg.Args
+				plug.interpreter = interpreter(cmdName)
+				plug.meta = meta
+				plug.env = p.pluginEnv(fileBase, inst, icfg.Env)
+				plug.persistent = p.isPersistent(pluginName, fileBase)
+				plug.runTTL = p.pluginTTL(runTTL, pluginName, fileBase)
+				plug.sandbox = p.pluginSandbox(pluginName, fileBase)
+				plug.timeout = p.pluginTimeout(cfgTimeout, pluginName, fileBase)
+				plug.Unlock()
 				p.logger.Info().
 					Str("id", pluginName).
 					Str("cmd", cmdName).
@@ -273,9 +342,41 @@ func (p *Plugins) scanPluginDirectory(b *builtins.Builtins) error {
 		}
 	}
 
-	if len(p.active) == 0 {
-		p.logger.Warn().Msg("no active plugins found")
+	for id, plug := range p.active {
+		if seen[id] || plug.url != "" || plug.fifo != nil {
+			continue // http and fifo plugins are managed by configureHTTPPlugins and configureFIFOPlugins
+		}
+		p.logger.Info().Str("id", id).Msg("Deactivating plugin, no longer present")
+		if plug.cancel != nil {
+			plug.cancel() // terminate, if running
+		}
+		delete(p.active, id)
 	}
 
 	return nil
 }
+
+// parsePluginConfig parses a plugin's json config, each instance is either
+// a list of arguments or an object with "args" and/or "env"
+func parsePluginConfig(data []byte) (map[string]instanceConfig, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	cfg := make(map[string]instanceConfig, len(raw))
+	for inst, val := range raw {
+		var args []string
+		if err := json.Unmarshal(val, &args); err == nil {
+			cfg[inst] = instanceConfig{Args: args}
+			continue
+		}
+		var icfg instanceConfig
+		if err := json.Unmarshal(val, &icfg); err != nil {
+			return nil, errors.Wrapf(err, "instance %s", inst)
+		}
+		cfg[inst] = icfg
+	}
+
+	return cfg, nil
+}
@@ -94,5 +94,71 @@ func TestScanPluginDirectory(t *testing.T) {
 		if err != nil {
 			t.Fatalf("expected NO error, got (%s)", err)
 		}
+		if _, ok := p.active["purge_inactive"]; ok {
+			t.Fatal("expected purge_inactive to be removed")
+		}
+		if _, ok := p.active["badcfg"]; ok {
+			t.Fatal("expected badcfg (invalid config) to be disabled")
+		}
+		if _, ok := p.active["goodcfg`inst1"]; !ok {
+			t.Fatal("expected goodcfg`inst1 to be active")
+		}
+	}
+}
+
+func TestParsePluginConfig(t *testing.T) {
+	t.Log("Testing parsePluginConfig")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("invalid json")
+	{
+		_, err := parsePluginConfig([]byte(`{"inst1":`))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid instance")
+	{
+		_, err := parsePluginConfig([]byte(`{"inst1": 1}`))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("args (list)")
+	{
+		cfg, err := parsePluginConfig([]byte(`{"inst1": ["a", "b"]}`))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		icfg, ok := cfg["inst1"]
+		if !ok {
+			t.Fatal("expected inst1")
+		}
+		if len(icfg.Args) != 2 || icfg.Args[0] != "a" || icfg.Args[1] != "b" {
+			t.Fatalf("expected [a b], got %v", icfg.Args)
+		}
+		if len(icfg.Env) != 0 {
+			t.Fatalf("expected no env, got %v", icfg.Env)
+		}
+	}
+
+	t.Log("args and env (object)")
+	{
+		cfg, err := parsePluginConfig([]byte(`{"sda": {"args": ["/dev/sda"], "env": {"DEVICE": "sda"}}, "sdb": {"env": {"DEVICE": "sdb"}}}`))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(cfg) != 2 {
+			t.Fatalf("expected 2 instances, got %d", len(cfg))
+		}
+		if len(cfg["sda"].Args) != 1 || cfg["sda"].Env["DEVICE"] != "sda" {
+			t.Fatalf("unexpected config %#v", cfg["sda"])
+		}
+		if cfg["sdb"].Args != nil || cfg["sdb"].Env["DEVICE"] != "sdb" {
+			t.Fatalf("unexpected config %#v", cfg["sdb"])
+		}
 	}
 }
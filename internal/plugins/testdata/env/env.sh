@@ -0,0 +1,3 @@
+#!/usr/bin/env bash
+
+printf "${CA_PLUGIN_NAME}\`${CA_PLUGIN_INSTANCE}\`${FOO}\tn\t1\n"
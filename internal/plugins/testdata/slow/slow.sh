@@ -0,0 +1,4 @@
+#!/usr/bin/env bash
+
+sleep 1
+printf "metric\tn\t1\n"
@@ -0,0 +1,7 @@
+#!/usr/bin/env bash
+
+# emits metrics then exits, used to test persistent plugins
+printf "a\tn\t1\n"
+printf "b\tn\t2\n"
+printf '{"c": {"_type": "n", "_value": 3}}\n'
+printf "\n"
@@ -0,0 +1,6 @@
+#!/usr/bin/env bash
+
+# ignores SIGTERM and spawns children, must be killed (as a group) after the grace period
+trap '' TERM
+printf "metric\tn\t1\n"
+while true; do sleep 1; done
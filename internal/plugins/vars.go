@@ -8,9 +8,11 @@ package plugins
 import (
 	"context"
 	"os/exec"
+	"regexp"
 	"sync"
 	"time"
 
+	"github.com/circonus-labs/circonus-agent/internal/release"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/rs/zerolog"
 )
@@ -18,33 +20,75 @@ import (
 // Plugins defines plugin manager
 type Plugins struct {
 	active        map[string]*plugin
+	background    sync.WaitGroup // slow plugin runs started by execPlugins, added to and waited for holding runmu
+	checkCID      string         // check bundle id of the check in use (created or found), see SetCheckID
+	checkID       string
+	collisions    string
 	ctx           context.Context
+	fifo          map[string]fifoSource
+	hostname      string
+	http          map[string]httpSource
 	logger        zerolog.Logger
 	pluginDir     string
 	reservedNames map[string]bool
+	persistent    map[string]bool
+	rescans       uint64 // plugin directory re-scans triggered by the watcher
 	running       bool
+	runmu         sync.Mutex // serializes plugin runs and scans, acquired before the Plugins lock
+	sandboxes     map[string]*sandbox
+	slowThreshold time.Duration // plugins whose last run took longer run in the background (0 disables)
+	statsd        PacketReceiver
+	timeout       time.Duration
+	timeouts      map[string]time.Duration
+	ttls          map[string]time.Duration
+	workers       int
 	sync.RWMutex
 }
 
 // Plugin defines a specific plugin
+//
+// env is added to the agent's environment when the plugin is executed:
+//
+//	CA_PLUGIN_HOSTNAME - agent host name
+//	CA_PLUGIN_NAME     - plugin name (e.g. foo for foo.sh)
+//	CA_PLUGIN_INSTANCE - instance id from the plugin config (empty if none)
+//	CA_PLUGIN_CHECK_ID - check bundle id (omitted if not known)
+//
+// followed by any "env" configured for the instance in the plugin config.
 type plugin struct {
+	cancel          context.CancelFunc
 	cmd             *exec.Cmd
 	command         string
 	ctx             context.Context
+	env             []string
+	fifo            *fifoSource // named pipe plugin source (virtual plugin, no command)
+	headers         map[string]string
 	id              string
 	instanceArgs    []string
 	instanceID      string
+	interpreter     []string
 	lastError       error
+	lastExitCode    int
 	lastRunDuration time.Duration
 	lastStart       time.Time
 	lastEnd         time.Time
 	logger          zerolog.Logger
+	meta            map[string]metricMeta // metric metadata from the plugin manifest (if any)
 	metrics         *cgm.Metrics
 	name            string
+	persistent      bool
 	prevMetrics     *cgm.Metrics
 	runDir          string
 	running         bool
 	runTTL          time.Duration
+	runsFailed      uint64
+	runsOK          uint64
+	sandbox         *sandbox // execution restrictions (if any)
+	staleRuns       uint64   // runs skipped while a slow run was still in progress, the last result was served
+	statsd          PacketReceiver
+	supervised      bool
+	timeout         time.Duration
+	url             string // http json plugin source (virtual plugin, no command)
 	sync.Mutex
 }
 
@@ -60,8 +104,92 @@ type plugin struct {
 // 	LastError       string   `json:"last_error"`
 // }
 
+// httpSource defines an http json plugin source (see config.KeyPluginHTTP)
+type httpSource struct {
+	headers  map[string]string
+	interval time.Duration
+	timeout  time.Duration
+	url      string
+}
+
+// fifoSource defines a named pipe plugin source (see config.KeyPluginFIFO)
+type fifoSource struct {
+	format string
+	path   string
+}
+
+// PacketReceiver processes statsd formatted metrics (the statsd server), the
+// lines read from statsd format named pipe plugins are passed to it
+type PacketReceiver interface {
+	ProcessPacket(pkt []byte) error
+}
+
+// sandbox defines plugin execution restrictions (see config.KeyPluginSandbox)
+type sandbox struct {
+	credential bool   // run as uid/gid (only set if the agent is running as root)
+	uid        uint32 // user id to run the plugin as
+	gid        uint32 // group id to run the plugin as
+	cpuTime    uint64 // cpu time limit, seconds (0 = unlimited)
+	memory     uint64 // virtual memory limit, KiB (0 = unlimited)
+	openFiles  uint64 // open file descriptor limit (0 = unlimited)
+}
+
+// instanceConfig defines a plugin instance in a plugin's config, in json either
+// a list of arguments or an object with arguments and environment variables
+// e.g. {"inst1": ["arg1"], "inst2": {"args": ["arg1"], "env": {"FOO": "bar"}}}
+type instanceConfig struct {
+	Args []string          `json:"args" toml:"args" yaml:"args"`
+	Env  map[string]string `json:"env" toml:"env" yaml:"env"`
+}
+
+var (
+	// killGracePeriod is how long a plugin has to exit after SIGTERM before being sent SIGKILL
+	killGracePeriod = 5 * time.Second
+
+	// persistentMinBackoff is the initial delay before restarting a persistent plugin which exited
+	persistentMinBackoff = 1 * time.Second
+
+	// persistentMaxBackoff is the maximum delay before restarting a persistent plugin which exited
+	persistentMaxBackoff = 1 * time.Minute
+
+	// watchDebounce is how long the plugin directory must be free of changes before it is re-scanned
+	watchDebounce = 2 * time.Second
+
+	// watchMaxDelay is the longest a re-scan is deferred by a continuous stream of changes
+	watchMaxDelay = 30 * time.Second
+
+	// httpTimeout is the request timeout for http json plugin sources without a timeout
+	httpTimeout = 10 * time.Second
+
+	// fifoUnblockInterval is how often a pending open of a named pipe is
+	// unblocked (by opening it for writing) once the plugin is stopped
+	fifoUnblockInterval = 100 * time.Millisecond
+
+	// ttlUnitRx determines if a plugin ttl has units
+	ttlUnitRx = regexp.MustCompile(`(ms|s|m|h)$`)
+)
+
+const (
+	envHostname       = release.ENVPREFIX + "_PLUGIN_HOSTNAME"
+	envPluginName     = release.ENVPREFIX + "_PLUGIN_NAME"
+	envPluginInstance = release.ENVPREFIX + "_PLUGIN_INSTANCE"
+	envCheckID        = release.ENVPREFIX + "_PLUGIN_CHECK_ID"
+)
+
 const (
-	fieldDelimiter  = "\t"
-	metricDelimiter = "`"
-	nullMetricValue = "[[null]]"
+	fieldDelimiter   = "\t"
+	runMetricPrefix  = "_plugin"
+	metricDelimiter  = "`"
+	nullMetricValue  = "[[null]]"
+	noChangeSentinel = "# nochange"
+	collisionsDrop   = "drop"
+	collisionsNS     = "namespace"
+	namespaceDelim   = ":" // replaces the instance delimiter in a plugin id used as a namespace
+	manifestExt      = ".meta.json"
+
+	// httpMaxResponseSize is the maximum response body read from an http json plugin source
+	httpMaxResponseSize = 10 * 1024 * 1024
+
+	fifoFormatPlugin = "plugin" // plugin output format, tab delimited lines or json
+	fifoFormatStatsd = "statsd" // statsd metrics, passed to the statsd server
 )
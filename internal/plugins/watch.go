@@ -0,0 +1,109 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package plugins
+
+import (
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/fsnotify/fsnotify"
+	appstats "github.com/maier/go-appstats"
+	"github.com/spf13/viper"
+)
+
+// Watch monitors the plugin directory, re-scanning it when plugins are
+// added, removed, modified or have their permissions changed. A burst of
+// events results in a single re-scan once the directory has been quiet for
+// watchDebounce (or, for a continuous stream of events, after watchMaxDelay).
+// Re-scans wait for a plugin run in progress to finish. Watch is a no-op if
+// plugin_watch is not enabled, there is no plugin directory, or file system
+// notifications are not available on the platform.
+func (p *Plugins) Watch(b *builtins.Builtins) {
+	if !viper.GetBool(config.KeyPluginWatch) {
+		return
+	}
+
+	p.RLock()
+	dir := p.pluginDir
+	p.RUnlock()
+
+	if dir == "" {
+		p.logger.Info().Msg("no plugin directory, not watching")
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("file system notifications unavailable, plugin directory will not be watched (use SIGHUP to re-scan)")
+		return
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		p.logger.Warn().Err(err).Str("dir", dir).Msg("unable to watch plugin directory (use SIGHUP to re-scan)")
+		return
+	}
+
+	p.logger.Info().Str("dir", dir).Msg("watching plugin directory")
+
+	go p.watch(watcher, b, watchDebounce, watchMaxDelay)
+}
+
+// watch handles file system events until the plugins context is done
+func (p *Plugins) watch(watcher *fsnotify.Watcher, b *builtins.Builtins, quiet, maxDelay time.Duration) {
+	defer watcher.Close()
+
+	debounce := time.NewTimer(quiet)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	defer debounce.Stop()
+
+	pending := false    // events received since the last re-scan
+	var first time.Time // first event of the pending burst
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename|fsnotify.Chmod|fsnotify.Write) == 0 {
+				continue
+			}
+			p.logger.Debug().Str("event", event.String()).Msg("plugin directory changed")
+			now := time.Now()
+			if !pending {
+				pending = true
+				first = now
+			} else if !debounce.Stop() {
+				<-debounce.C
+			}
+			wait := quiet
+			if remaining := first.Add(maxDelay).Sub(now); remaining < wait {
+				wait = remaining
+			}
+			debounce.Reset(wait)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			p.logger.Warn().Err(err).Msg("watching plugin directory")
+		case <-debounce.C:
+			pending = false
+			p.logger.Info().Msg("plugin directory changed, re-scanning")
+			appstats.MapIncrementInt("plugins", "rescans")
+			p.Lock()
+			p.rescans++
+			p.Unlock()
+			if err := p.Scan(b); err != nil {
+				p.logger.Error().Err(err).Msg("re-scanning plugin directory")
+			}
+		}
+	}
+}
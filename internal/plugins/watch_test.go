@@ -0,0 +1,159 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// +build !windows
+
+package plugins
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestWatch(t *testing.T) {
+	t.Log("Testing Watch")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "plugin-watch")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	b, err := builtins.New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	savedDebounce := watchDebounce
+	watchDebounce = 100 * time.Millisecond
+	defer func() { watchDebounce = savedDebounce }()
+
+	isActive := func(p *Plugins, id string) bool {
+		p.RLock()
+		defer p.RUnlock()
+		_, ok := p.active[id]
+		return ok
+	}
+
+	waitFor := func(p *Plugins, id string, active bool) bool {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if isActive(p, id) == active {
+				return true
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Log("disabled")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginDir, dir)
+		ctx, cancel := context.WithCancel(context.Background())
+		p, err := New(ctx)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := p.Scan(b); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		p.Watch(b)
+
+		plugin := filepath.Join(dir, "foo.sh")
+		if err := ioutil.WriteFile(plugin, []byte("#!/bin/sh\necho 'x\tL\t1'\n"), 0755); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		time.Sleep(4 * watchDebounce)
+		if isActive(p, "foo") {
+			t.Fatal("expected foo NOT to be active (watch disabled)")
+		}
+		os.Remove(plugin)
+		cancel()
+	}
+
+	t.Log("enabled")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginDir, dir)
+		viper.Set(config.KeyPluginWatch, true)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		p, err := New(ctx)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := p.Scan(b); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		p.Watch(b)
+
+		plugin := filepath.Join(dir, "bar.sh")
+		if err := ioutil.WriteFile(plugin, []byte("#!/bin/sh\necho 'x\tL\t1'\n"), 0755); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if !waitFor(p, "bar", true) {
+			t.Fatal("expected bar to be activated")
+		}
+
+		if err := os.Remove(plugin); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if !waitFor(p, "bar", false) {
+			t.Fatal("expected bar to be deactivated")
+		}
+	}
+
+	t.Log("burst of changes, single re-scan, concurrent runs")
+	{
+		viper.Reset()
+		viper.Set(config.KeyPluginDir, dir)
+		viper.Set(config.KeyPluginWatch, true)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		p, err := New(ctx)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := p.Scan(b); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		p.Watch(b)
+
+		plugin := filepath.Join(dir, "baz.sh")
+		for i := 0; i < 10; i++ {
+			if err := ioutil.WriteFile(plugin, []byte("#!/bin/sh\necho 'x\tL\t1'\n"), 0755); err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			p.Run("") // an error (run already in progress) is expected at times
+			time.Sleep(watchDebounce / 10)
+		}
+		if !waitFor(p, "baz", true) {
+			t.Fatal("expected baz to be activated")
+		}
+		time.Sleep(2 * watchDebounce)
+
+		p.RLock()
+		rescans := p.rescans
+		p.RUnlock()
+		if rescans != 1 {
+			t.Fatalf("expected 1 re-scan, got %d", rescans)
+		}
+		os.Remove(plugin)
+	}
+
+	viper.Reset()
+}
@@ -10,10 +10,18 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/http"
+	"time"
 
+	appstats "github.com/maier/go-appstats"
 	"github.com/pkg/errors"
 )
 
+const cmdTimeoutMetric = "command_timeouts"
+
+// errCommandTimeout is returned when servicing a command exceeds the command timeout
+var errCommandTimeout = errors.New("command timed out")
+
 func (c *Connection) newCommandReader(done <-chan interface{}, conn *tls.Conn) <-chan command {
 	commandReader := make(chan command)
 	go func() {
@@ -60,6 +68,8 @@ func (c *Connection) readCommand(r io.Reader) command {
 	c.commTimeouts = 0
 	c.Unlock()
 
+	c.latencyAck(cmdPkt.header.channelID)
+
 	if !cmdPkt.header.isCommand {
 		c.logger.Warn().
 			Str("cmd_header", fmt.Sprintf("%#v", cmdPkt.header)).
@@ -157,12 +167,65 @@ func (c *Connection) processCommand(cmd command) command {
 		return cmd
 	}
 
-	metrics, err := c.fetchMetricData(&cmd.request)
+	metrics, err := c.fetchMetricDataTimeout(&cmd.request)
+	if err == errCommandTimeout {
+		// respond so the broker is not left waiting, the connection moves on
+		cmd.metrics = commandTimeoutResponse(c.cmdTimeout)
+		return cmd
+	}
 	if err != nil {
 		cmd.err = errors.Wrap(err, "fetching metrics")
 		return cmd
 	}
 
+	if c.compression != "" {
+		metrics = c.compressResponse(metrics)
+	}
+
 	cmd.metrics = metrics
 	return cmd
 }
+
+// fetchMetricDataTimeout fetches the metrics for a command, giving up after
+// the command timeout so that a slow request does not block the commands
+// which follow. The abandoned request completes (or fails) in the background,
+// it is bounded by the metric timeout.
+func (c *Connection) fetchMetricDataTimeout(request *[]byte) (*[]byte, error) {
+	type fetchResult struct {
+		data *[]byte
+		err  error
+	}
+
+	result := make(chan fetchResult, 1)
+	go func() {
+		data, err := c.fetchMetricData(request)
+		result <- fetchResult{data: data, err: err}
+	}()
+
+	timer := time.NewTimer(c.cmdTimeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-result:
+		return r.data, r.err
+	case <-timer.C:
+		c.Lock()
+		c.cmdTimeouts++
+		c.Unlock()
+		if c.cmdMetrics != nil {
+			c.cmdMetrics.Increment(cmdTimeoutMetric)
+		}
+		appstats.MapIncrementInt("reverse", "cmd_timeouts")
+		c.logger.Warn().Str("timeout", c.cmdTimeout.String()).Msg("metric request timed out, sending error response")
+		return nil, errCommandTimeout
+	}
+}
+
+// commandTimeoutResponse returns the http response sent to the broker when
+// a metric request times out
+func commandTimeoutResponse(timeout time.Duration) *[]byte {
+	body := fmt.Sprintf("metric request timed out (%s)\n", timeout)
+	resp := []byte(fmt.Sprintf("HTTP/1.1 %d %s\r\nContent-Type: text/plain\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
+		http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout), len(body), body))
+	return &resp
+}
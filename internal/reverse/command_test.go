@@ -11,7 +11,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/check"
 	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
@@ -155,3 +157,44 @@ func TestProcessCommand(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessCommandTimeout(t *testing.T) {
+	t.Log("Testing processCommand timeout")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		fmt.Fprintln(w, "{}")
+	}))
+	defer ts.Close()
+
+	chk, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+	s, err := New(chk, defaults.Listen)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	s.agentAddress = ts.Listener.Addr().String()
+	s.cmdTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	cmd := s.processCommand(command{name: "CONNECT", request: []byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")})
+	if elapsed := time.Since(start); elapsed >= 500*time.Millisecond {
+		t.Fatalf("expected command to time out, took %s", elapsed)
+	}
+	if cmd.err != nil {
+		t.Fatalf("expected no error, got (%s)", cmd.err)
+	}
+	if cmd.metrics == nil {
+		t.Fatal("expected error response")
+	}
+	if !strings.HasPrefix(string(*cmd.metrics), "HTTP/1.1 504 Gateway Timeout\r\n") {
+		t.Fatalf("expected 504 response, got (%s)", string(*cmd.metrics))
+	}
+	if s.cmdTimeouts != 1 {
+		t.Fatalf("expected 1 command timeout, got %d", s.cmdTimeouts)
+	}
+}
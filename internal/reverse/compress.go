@@ -0,0 +1,116 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package reverse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	compressionGzip          = "gzip"
+	payloadBytesMetric       = "payload_bytes"
+	payloadCompressedMetric  = "payload_compressed_bytes"
+	compressionSkippedMetric = "payload_compression_skipped"
+)
+
+// initCompression initializes compression of the metrics sent to the
+// broker, if enabled
+func (c *Connection) initCompression() error {
+	compression := viper.GetString(config.KeyReverseCompression)
+	if compression == "" {
+		return nil
+	}
+	if compression != compressionGzip {
+		return errors.Errorf("invalid reverse compression (%s)", compression)
+	}
+
+	m, err := c.newManualMetrics("reverse-compression")
+	if err != nil {
+		return errors.Wrap(err, "reverse compression metrics")
+	}
+
+	c.compression = compression
+	c.compressMetrics = m
+
+	return nil
+}
+
+// compressResponse compresses the body of the http response received from
+// the local agent (the payload sent to the broker), signaling the encoding
+// to the broker with the Content-Encoding header. The framing is unchanged.
+// Responses which are already encoded (the broker requested compression)
+// or cannot be parsed are returned as is. The payload sizes before and
+// after compression are counted.
+func (c *Connection) compressResponse(data *[]byte) *[]byte {
+	if data == nil || len(*data) == 0 {
+		return data
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(*data)), nil)
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("parsing metric response, not compressing")
+		c.compressMetrics.Increment(compressionSkippedMetric)
+		return data
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "" {
+		c.compressMetrics.Increment(compressionSkippedMetric)
+		return data // already encoded
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		c.logger.Warn().Err(err).Msg("reading metric response, not compressing")
+		c.compressMetrics.Increment(compressionSkippedMetric)
+		return data
+	}
+
+	var zbody bytes.Buffer
+	zw := gzip.NewWriter(&zbody)
+	if _, err := zw.Write(body); err != nil {
+		c.logger.Warn().Err(err).Msg("compressing metric response")
+		c.compressMetrics.Increment(compressionSkippedMetric)
+		return data
+	}
+	if err := zw.Close(); err != nil {
+		c.logger.Warn().Err(err).Msg("compressing metric response")
+		c.compressMetrics.Increment(compressionSkippedMetric)
+		return data
+	}
+
+	// chunked responses are re-written with the length of the compressed body
+	resp.TransferEncoding = nil
+	resp.ContentLength = int64(zbody.Len())
+	resp.Header.Del("Content-Length")
+	resp.Header.Set("Content-Encoding", compressionGzip)
+	resp.Body = ioutil.NopCloser(&zbody)
+
+	var buf bytes.Buffer
+	if err := resp.Write(&buf); err != nil {
+		c.logger.Warn().Err(err).Msg("writing compressed metric response")
+		c.compressMetrics.Increment(compressionSkippedMetric)
+		return data
+	}
+
+	compressed := buf.Bytes()
+	c.compressMetrics.IncrementByValue(payloadBytesMetric, uint64(len(*data)))
+	c.compressMetrics.IncrementByValue(payloadCompressedMetric, uint64(len(compressed)))
+	c.logger.Debug().
+		Int("bytes", len(*data)).
+		Int("compressed_bytes", len(compressed)).
+		Msg("compressed metric response")
+
+	return &compressed
+}
@@ -0,0 +1,141 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package reverse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestCompressResponse(t *testing.T) {
+	t.Log("Testing metric response compression")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	body := `{"foo":{"_type":"L","_value":1}}` + strings.Repeat(" ", 1024)
+
+	decode := func(data []byte) (*http.Response, string) {
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer resp.Body.Close()
+		if resp.Header.Get("Content-Encoding") != "gzip" {
+			return resp, ""
+		}
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		return resp, string(b)
+	}
+
+	t.Log("\tdisabled")
+	{
+		viper.Reset()
+		c := Connection{logger: log.With().Logger()}
+		if err := c.initCompression(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.compression != "" {
+			t.Fatalf("expected disabled, got (%s)", c.compression)
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyReverseCompression, "zstd")
+		c := Connection{logger: log.With().Logger()}
+		if err := c.initCompression(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	viper.Reset()
+	viper.Set(config.KeyReverseCompression, "gzip")
+	c := Connection{logger: log.With().Logger()}
+	if err := c.initCompression(); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	t.Log("\tcontent-length")
+	{
+		data := []byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body)
+		out := c.compressResponse(&data)
+		if len(*out) >= len(data) {
+			t.Fatalf("expected compressed payload smaller than %d, got %d", len(data), len(*out))
+		}
+		resp, b := decode(*out)
+		if b != body {
+			t.Fatalf("unexpected body (%s)", b)
+		}
+		if resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatalf("expected content type to be kept, got (%#v)", resp.Header)
+		}
+
+		m := *c.Flush()
+		if v, ok := m[payloadBytesMetric]; !ok || v.Value != uint64(len(data)) {
+			t.Fatalf("expected %s=%d, got (%#v)", payloadBytesMetric, len(data), m)
+		}
+		if v, ok := m[payloadCompressedMetric]; !ok || v.Value != uint64(len(*out)) {
+			t.Fatalf("expected %s=%d, got (%#v)", payloadCompressedMetric, len(*out), m)
+		}
+	}
+
+	t.Log("\tchunked")
+	{
+		data := []byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\n\r\n" +
+			"10\r\n" + body[:16] + "\r\n" + strconv.FormatInt(int64(len(body)-16), 16) + "\r\n" + body[16:] + "\r\n0\r\n\r\n")
+		out := c.compressResponse(&data)
+		resp, b := decode(*out)
+		if b != body {
+			t.Fatalf("unexpected body (%s)", b)
+		}
+		if len(resp.TransferEncoding) != 0 {
+			t.Fatalf("expected no transfer encoding, got (%#v)", resp.TransferEncoding)
+		}
+	}
+
+	t.Log("\talready encoded")
+	{
+		data := []byte("HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\nContent-Length: 3\r\n\r\nabc")
+		out := c.compressResponse(&data)
+		if !bytes.Equal(*out, data) {
+			t.Fatalf("expected unchanged payload, got (%s)", string(*out))
+		}
+	}
+
+	t.Log("\tinvalid response")
+	{
+		data := []byte("not http")
+		out := c.compressResponse(&data)
+		if !bytes.Equal(*out, data) {
+			t.Fatalf("expected unchanged payload, got (%s)", string(*out))
+		}
+		m := *c.Flush()
+		if v, ok := m[compressionSkippedMetric]; !ok || v.Value != uint64(2) {
+			t.Fatalf("expected %s=2, got (%#v)", compressionSkippedMetric, m)
+		}
+	}
+
+	viper.Reset()
+}
@@ -7,12 +7,14 @@ package reverse
 
 import (
 	"crypto/tls"
+	"expvar"
 	"fmt"
 	"math/rand"
-	"net"
 	"time"
 
+	"github.com/circonus-labs/circonus-agent/internal/check"
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	appstats "github.com/maier/go-appstats"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -20,9 +22,20 @@ import (
 
 // startReverse manages the actual reverse connection to the Circonus broker
 func (c *Connection) startReverse() error {
+	c.setRunning(true)
+	defer c.setRunning(false)
+	defer c.setConnected(false)
+
+	// spread the initial connections of a fleet of agents (e.g. all
+	// started at once), if a connect jitter is configured
+	if !c.waitJitter("initial connect") {
+		return nil
+	}
+
 	for {
 		conn, cerr := c.connect()
 		if cerr != nil {
+			c.setLastError(cerr.err)
 			if cerr.fatal {
 				c.logger.Error().Err(cerr.err).Msg("connecting to broker")
 				return cerr.err
@@ -49,6 +62,7 @@ func (c *Connection) startReverse() error {
 				continue
 			}
 			if result.err != nil {
+				c.setLastError(result.err)
 				if result.reset {
 					c.logger.Warn().Err(result.err).Int("timeouts", c.commTimeouts).Msg("resetting connection")
 					close(done)
@@ -65,7 +79,10 @@ func (c *Connection) startReverse() error {
 			}
 
 			// send metrics to broker
+			c.latencySent(result.channelID)
 			if err := c.sendMetricData(conn, result.channelID, result.metrics); err != nil {
+				c.setLastError(err)
+				c.latencyAbort()
 				c.logger.Warn().Err(err).Msg("sending metric data, resetting connection")
 				close(done)
 				break
@@ -75,10 +92,16 @@ func (c *Connection) startReverse() error {
 		}
 
 		conn.Close()
+		c.setConnected(false)
 		if c.shutdown() {
 			return nil
 		}
 
+		// every agent connected to a broker loses its connection when the
+		// broker restarts, spread the reconnections
+		if !c.waitJitter("reconnect") {
+			return nil
+		}
 	}
 }
 
@@ -95,27 +118,42 @@ func (c *Connection) connect() (*tls.Conn, *connError) {
 		time.Sleep(c.delay)
 		c.delay = c.getNextDelay(c.delay)
 
+		// When the check bundle lists more than one reverse URL, fail
+		// over to the next broker after configRetryLimit failed attempts
+		// on the current one.
+		//
 		// Under normal circumstances the configuration for reverse is
 		// non-volatile. There are, however, some situations where the
 		// configuration must be rebuilt. (e.g. ip of broker changed,
 		// check changed to use a different broker, broker certificate
-		// changes, etc.) The majority of configuration based errors are
-		// fatal, no attempt is made to resolve.
-		if c.connAttempts%c.configRetryLimit == 0 {
+		// changes, etc.) Once every broker has been tried, the
+		// configuration is rebuilt. The majority of configuration based
+		// errors are fatal, no attempt is made to resolve.
+		if c.connAttempts%c.configRetryLimit == 0 && c.revIdx+1 < len(c.revConfigs) {
+			prev := c.revConfig.ReverseURL.Host
+			c.revIdx++
+			c.revConfig = c.revConfigs[c.revIdx]
+			appstats.MapIncrementInt("reverse", "failovers")
+			c.setBrokerStat()
+			c.logger.Warn().
+				Str("from", prev).
+				Str("to", c.revConfig.ReverseURL.Host).
+				Int("attempts", c.connAttempts).
+				Msg("failing over to next broker")
+		} else if c.connAttempts%c.configRetryLimit == 0 {
 			c.logger.Info().Int("attempts", c.connAttempts).Msg("reconfig triggered")
-			c.logger.Debug().Str("check_bundle", viper.GetString(config.KeyCheckBundleID)).Msg("refreshing check")
-			if err := c.check.RefreshCheckConfig(); err != nil {
-				return nil, &connError{fatal: true, err: errors.Wrap(err, "refreshing check configuration")}
+			if c.staticConfigs == nil {
+				c.logger.Debug().Str("check_bundle", viper.GetString(config.KeyCheckBundleID)).Msg("refreshing check")
+				if err := c.check.RefreshCheckConfig(); err != nil {
+					return nil, &connError{fatal: true, err: errors.Wrap(err, "refreshing check configuration")}
+				}
 			}
 			c.logger.Debug().Str("check_bundle", viper.GetString(config.KeyCheckBundleID)).Msg("setting reverse config")
-			rc, err := c.check.GetReverseConfig()
+			rcs, err := c.reverseConfigs()
 			if err != nil {
 				return nil, &connError{fatal: true, err: errors.Wrap(err, "reconfiguring reverse connection")}
 			}
-			if rc == nil {
-				return nil, &connError{fatal: true, err: errors.Wrap(err, "invalid reverse configuration (nil)")}
-			}
-			c.revConfig = *rc
+			c.setReverseConfigs(rcs)
 			c.logger = log.With().Str("pkg", "reverse").Str("cid", viper.GetString(config.KeyCheckBundleID)).Logger()
 			c.logger.Info().
 				Str("check_bundle", viper.GetString(config.KeyCheckBundleID)).
@@ -133,14 +171,23 @@ func (c *Connection) connect() (*tls.Conn, *connError) {
 	c.Lock()
 	c.connAttempts++
 	c.Unlock()
-	dialer := &net.Dialer{Timeout: c.dialerTimeout}
-	conn, err := tls.DialWithDialer(dialer, "tcp", c.revConfig.BrokerAddr.String(), c.revConfig.TLSConfig)
+	tlsConfig, certRequested := c.dialTLSConfig()
+	conn, err := c.dialBroker(c.revConfig.BrokerAddr.String(), tlsConfig)
 	if err != nil {
+		if *certRequested {
+			// the handshake error is a generic alert from the broker (e.g. bad certificate)
+			err = errors.Wrap(err, "broker requested a client certificate, none configured (reverse.client_cert_file)")
+		}
 		if c.maxConnRetry != -1 && c.connAttempts >= c.maxConnRetry {
 			return nil, &connError{fatal: true, err: errors.Wrapf(err, "after %d failed attempts, last error", c.connAttempts)}
 		}
 		return nil, &connError{fatal: false, err: errors.Wrapf(err, "connecting to %s", revHost)}
 	}
+	if *certRequested {
+		// with TLS 1.3 a required client certificate is verified after the
+		// client completes the handshake, the broker will reset the connection
+		c.logger.Warn().Str("host", revHost).Msg("broker requested a client certificate, none configured (reverse.client_cert_file)")
+	}
 	c.logger.Info().Str("host", revHost).Msg("connected")
 
 	conn.SetDeadline(time.Now().Add(c.commTimeout))
@@ -148,7 +195,7 @@ func (c *Connection) connect() (*tls.Conn, *connError) {
 	if c.revConfig.ReverseURL.Fragment != "" {
 		introReq += "#" + c.revConfig.ReverseURL.Fragment // reverse secret is placed here when reverse url is parsed
 	}
-	c.logger.Debug().Msg(fmt.Sprintf("sending intro '%s'", introReq))
+	c.logger.Debug().Str("url", config.RedactURL(c.revConfig.ReverseURL.String())).Msg("sending intro")
 	if _, err := fmt.Fprintf(conn, "%s HTTP/1.1\r\n\r\n", introReq); err != nil {
 		if err != nil {
 			c.logger.Error().Err(err).Msg("sending intro")
@@ -157,13 +204,165 @@ func (c *Connection) connect() (*tls.Conn, *connError) {
 	}
 
 	c.Lock()
-	// reset timeouts after successful (re)connection
+	// reset timeouts and any pending latency measurement after successful (re)connection
 	c.commTimeouts = 0
+	c.latencyStart = time.Time{}
+	c.connected = true
+	c.connectedSince = time.Now()
 	c.Unlock()
 
 	return conn, nil
 }
 
+// reverseConfigs returns the broker configurations, from WithReverseConfigs
+// if set, otherwise from the check
+func (c *Connection) reverseConfigs() ([]check.ReverseConfig, error) {
+	if c.staticConfigs != nil {
+		if len(c.staticConfigs) == 0 {
+			return nil, errors.New("invalid reverse configuration (none)")
+		}
+		return c.staticConfigs, nil
+	}
+
+	rcs, err := c.check.GetReverseConfigs()
+	if err != nil {
+		return nil, err
+	}
+	if rcs == nil || len(*rcs) == 0 {
+		return nil, errors.New("invalid reverse configuration (nil)")
+	}
+	return *rcs, nil
+}
+
+// dialTLSConfig returns the tls configuration for a connection attempt. When
+// no client certificate is configured, the returned flag is set if the broker
+// requests one during the handshake so the failure can be reported clearly.
+func (c *Connection) dialTLSConfig() (*tls.Config, *bool) {
+	certRequested := false
+
+	if c.revConfig.TLSConfig == nil || len(c.revConfig.TLSConfig.Certificates) > 0 {
+		return c.revConfig.TLSConfig, &certRequested
+	}
+
+	tlsConfig := c.revConfig.TLSConfig.Clone()
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		certRequested = true
+		return &tls.Certificate{}, nil // no certificate, let the broker decide
+	}
+
+	return tlsConfig, &certRequested
+}
+
+// Connected returns whether the reverse connection to the broker is currently established
+func (c *Connection) Connected() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.connected
+}
+
+// Enabled returns whether the reverse connection is enabled
+func (c *Connection) Enabled() bool {
+	return c.enabled
+}
+
+// Broker returns the address of the active broker, empty if not enabled
+func (c *Connection) Broker() string {
+	c.Lock()
+	defer c.Unlock()
+	if c.revConfig.BrokerAddr == nil {
+		return ""
+	}
+	return c.revConfig.BrokerAddr.String()
+}
+
+// State returns details about the reverse connection, used for diagnostics
+// and the /reverse endpoint. state is one of connected, connecting (the
+// connection loop is running, not connected) or disconnected.
+func (c *Connection) State() map[string]interface{} {
+	c.Lock()
+	defer c.Unlock()
+
+	connState := stateDisconnected
+	if c.connected {
+		connState = stateConnected
+	} else if c.running {
+		connState = stateConnecting
+	}
+
+	state := map[string]interface{}{
+		"enabled":       c.enabled,
+		"state":         connState,
+		"connected":     c.connected,
+		"conn_attempts": c.connAttempts,
+		"comm_timeouts": c.commTimeouts,
+		"cmd_timeouts":  c.cmdTimeouts,
+	}
+	if c.connected && !c.connectedSince.IsZero() {
+		state["connected_since"] = c.connectedSince.Format(time.RFC3339)
+	}
+	if c.lastError != nil {
+		state["last_error"] = c.lastError.Error()
+		state["last_error_time"] = c.lastErrorTime.Format(time.RFC3339)
+	}
+	if c.revConfig.BrokerAddr != nil {
+		state["broker"] = c.revConfig.BrokerAddr.String()
+	}
+	if c.revConfig.ReverseURL != nil {
+		state["broker_url"] = c.revConfig.ReverseURL.Host + c.revConfig.ReverseURL.Path
+	}
+	if len(c.revConfigs) > 1 {
+		state["broker_index"] = c.revIdx
+		state["broker_count"] = len(c.revConfigs)
+	}
+
+	return state
+}
+
+// setReverseConfigs sets the broker configurations to use, the first
+// becomes the active configuration. Caller must hold the lock when the
+// connection is in use.
+func (c *Connection) setReverseConfigs(rcs []check.ReverseConfig) {
+	c.revConfigs = rcs
+	c.revIdx = 0
+	c.revConfig = rcs[0]
+	if c.revConfig.ReverseURL != nil {
+		c.setBrokerStat()
+	}
+}
+
+// setBrokerStat reports the active broker in the reverse stats
+func (c *Connection) setBrokerStat() {
+	broker := new(expvar.String)
+	broker.Set(c.revConfig.ReverseURL.Host)
+	appstats.MapSet("reverse", "broker", broker)
+}
+
+// setConnected records the reverse connection state
+func (c *Connection) setConnected(state bool) {
+	c.Lock()
+	c.connected = state
+	if !state {
+		c.connectedSince = time.Time{}
+	}
+	c.Unlock()
+}
+
+// setRunning records whether the connection loop is running
+func (c *Connection) setRunning(state bool) {
+	c.Lock()
+	c.running = state
+	c.Unlock()
+}
+
+// setLastError records the last connection error, errors are redacted
+// since they may include the reverse url (which contains the check secret)
+func (c *Connection) setLastError(err error) {
+	c.Lock()
+	c.lastError = config.RedactError(err)
+	c.lastErrorTime = time.Now()
+	c.Unlock()
+}
+
 // getNextDelay for failed connection attempts
 func (c *Connection) getNextDelay(currDelay time.Duration) time.Duration {
 	if currDelay == c.maxDelay {
@@ -184,6 +383,35 @@ func (c *Connection) getNextDelay(currDelay time.Duration) time.Duration {
 	return delay
 }
 
+// jitterDelay returns a random delay, up to the connect jitter
+func (c *Connection) jitterDelay() time.Duration {
+	if c.connectJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(c.connectJitter)))
+}
+
+// waitJitter waits a random delay (if a connect jitter is configured) before
+// connecting to the broker. Returns false if the connection is shutting down.
+func (c *Connection) waitJitter(reason string) bool {
+	delay := c.jitterDelay()
+	if delay == 0 {
+		return true
+	}
+
+	c.logger.Info().
+		Str("delay", delay.String()).
+		Str("max", c.connectJitter.String()).
+		Msg(reason + " jitter")
+
+	select {
+	case <-c.t.Dying():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
 // resetConnectionAttempts on successful send/receive
 func (c *Connection) resetConnectionAttempts() {
 	c.Lock()
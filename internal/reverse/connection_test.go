@@ -8,6 +8,7 @@ package reverse
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"expvar"
 	"io"
 	"net"
 	"net/url"
@@ -244,6 +245,115 @@ func TestConnect(t *testing.T) {
 	}
 }
 
+func TestConnectClientCert(t *testing.T) {
+	t.Log("Testing connect w/client certificate")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	cert, err := tls.X509KeyPair(tcert, tkey)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	clicert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	cp := x509.NewCertPool()
+	cp.AddCert(clicert)
+
+	// broker requires a client certificate, tls 1.2 so a missing
+	// certificate fails the handshake rather than the first read
+	tcfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MaxVersion:   tls.VersionTLS12,
+	}
+
+	connect := func(clientCerts []tls.Certificate) (*tls.Conn, chan int, error) {
+		l, err := tls.Listen("tcp", "127.0.0.1:0", tcfg)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		defer l.Close()
+
+		peerCerts := make(chan int, 1)
+		go func() {
+			conn, cerr := l.Accept()
+			if cerr != nil {
+				peerCerts <- -1
+				return
+			}
+			defer conn.Close()
+			tc := conn.(*tls.Conn)
+			if herr := tc.Handshake(); herr != nil {
+				peerCerts <- -1
+				return
+			}
+			peerCerts <- len(tc.ConnectionState().PeerCertificates)
+			io.Copy(tc, tc)
+		}()
+
+		chk, cerr := check.New(nil)
+		if cerr != nil {
+			t.Fatalf("expected no error, got (%s)", cerr)
+		}
+		s, err := New(chk, defaults.Listen)
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+		defer s.Stop()
+
+		tsURL, err := url.Parse("http://" + l.Addr().String() + "/check/foo-bar-baz#abc123")
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+		ra, err := net.ResolveTCPAddr("tcp", tsURL.Host)
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+
+		s.revConfig = check.ReverseConfig{
+			ReverseURL: tsURL,
+			BrokerAddr: ra,
+			TLSConfig: &tls.Config{
+				RootCAs:      cp,
+				Certificates: clientCerts,
+			},
+		}
+		s.dialerTimeout = 2 * time.Second
+		s.commTimeout = 2 * time.Second
+
+		conn, connErr := s.connect()
+		if connErr != nil {
+			return nil, peerCerts, connErr
+		}
+		return conn, peerCerts, nil
+	}
+
+	t.Log("	client certificate presented")
+	{
+		conn, peerCerts, err := connect([]tls.Certificate{cert})
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+		if n := <-peerCerts; n != 1 {
+			t.Fatalf("expected broker to receive 1 client certificate, got %d", n)
+		}
+		conn.Close()
+	}
+
+	t.Log("	no client certificate configured")
+	{
+		_, _, err := connect(nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if !strings.Contains(err.Error(), "broker requested a client certificate, none configured") {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+}
+
 func TestSetNextDelay(t *testing.T) {
 	t.Log("Testing setNextDelay")
 
@@ -349,3 +459,255 @@ func TestResetConnectionAttempts(t *testing.T) {
 		t.Fatalf("attempts not reset (%d)", c.connAttempts)
 	}
 }
+
+func TestWaitJitter(t *testing.T) {
+	t.Log("Testing waitJitter")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyReverse, false)
+	chk, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	t.Log("\tinvalid jitter")
+	{
+		viper.Set(config.KeyReverseConnectJitter, "abc")
+		_, err := New(chk, defaults.Listen)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		viper.Set(config.KeyReverseConnectJitter, "")
+	}
+
+	t.Log("\tno jitter")
+	{
+		c, err := New(chk, defaults.Listen)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if d := c.jitterDelay(); d != 0 {
+			t.Fatalf("expected 0, got %s", d)
+		}
+		if !c.waitJitter("test") {
+			t.Fatal("expected true")
+		}
+	}
+
+	t.Log("\tjitter")
+	{
+		viper.Set(config.KeyReverseConnectJitter, "10ms")
+		c, err := New(chk, defaults.Listen)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.connectJitter != 10*time.Millisecond {
+			t.Fatalf("expected 10ms, got %s", c.connectJitter)
+		}
+		for i := 0; i < 100; i++ {
+			if d := c.jitterDelay(); d < 0 || d >= c.connectJitter {
+				t.Fatalf("expected delay in [0,%s), got %s", c.connectJitter, d)
+			}
+		}
+		if !c.waitJitter("test") {
+			t.Fatal("expected true")
+		}
+	}
+
+	t.Log("\tshutting down")
+	{
+		viper.Set(config.KeyReverseConnectJitter, "1h")
+		c, err := New(chk, defaults.Listen)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		c.t.Kill(nil)
+		if c.waitJitter("test") {
+			t.Fatal("expected false")
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestConnected(t *testing.T) {
+	t.Log("Testing Connected")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Set(config.KeyReverse, false)
+	chk, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+	c, err := New(chk, defaults.Listen)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	if c.Connected() {
+		t.Fatal("expected not connected")
+	}
+
+	c.setConnected(true)
+	if !c.Connected() {
+		t.Fatal("expected connected")
+	}
+
+	c.setConnected(false)
+	if c.Connected() {
+		t.Fatal("expected not connected")
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Log("Testing State")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Set(config.KeyReverse, false)
+	chk, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+	c, err := New(chk, defaults.Listen)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	state := c.State()
+	if s, ok := state["state"].(string); !ok || s != stateDisconnected {
+		t.Fatalf("expected (%s), got (%#v)", stateDisconnected, state["state"])
+	}
+
+	c.setRunning(true)
+	c.setLastError(errors.New("connecting to 127.0.0.1:43191: connection refused"))
+	state = c.State()
+	if s, ok := state["state"].(string); !ok || s != stateConnecting {
+		t.Fatalf("expected (%s), got (%#v)", stateConnecting, state["state"])
+	}
+	if e, ok := state["last_error"].(string); !ok || e != "connecting to 127.0.0.1:43191: connection refused" {
+		t.Fatalf("expected last_error, got (%#v)", state["last_error"])
+	}
+	if _, ok := state["last_error_time"]; !ok {
+		t.Fatal("expected last_error_time")
+	}
+	if _, ok := state["connected_since"]; ok {
+		t.Fatal("expected no connected_since when not connected")
+	}
+
+	c.Lock()
+	c.connected = true
+	c.connectedSince = time.Now()
+	c.Unlock()
+	state = c.State()
+	if enabled, ok := state["enabled"].(bool); !ok || enabled {
+		t.Fatalf("expected enabled false, got (%#v)", state["enabled"])
+	}
+	if connected, ok := state["connected"].(bool); !ok || !connected {
+		t.Fatalf("expected connected true, got (%#v)", state["connected"])
+	}
+	if s, ok := state["state"].(string); !ok || s != stateConnected {
+		t.Fatalf("expected (%s), got (%#v)", stateConnected, state["state"])
+	}
+	if _, ok := state["connected_since"]; !ok {
+		t.Fatal("expected connected_since")
+	}
+	if _, ok := state["broker"]; ok {
+		t.Fatal("expected no broker when reverse disabled")
+	}
+
+	c.setConnected(false)
+	state = c.State()
+	if _, ok := state["connected_since"]; ok {
+		t.Fatal("expected no connected_since after disconnect")
+	}
+}
+
+func TestConnectFailover(t *testing.T) {
+	t.Log("Testing connect failover")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Set(config.KeyReverse, false)
+	chk, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+	c, err := New(chk, defaults.Listen)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	// two brokers, neither listening
+	var rcs []check.ReverseConfig
+	for i := 0; i < 2; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		addr := l.Addr().String()
+		l.Close()
+
+		tsURL, err := url.Parse("http://" + addr + "/check/foo-bar-baz")
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+		ra, err := net.ResolveTCPAddr("tcp", tsURL.Host)
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+		rcs = append(rcs, check.ReverseConfig{ReverseURL: tsURL, BrokerAddr: ra, TLSConfig: &tls.Config{}})
+	}
+	c.setReverseConfigs(rcs)
+	c.dialerTimeout = 1 * time.Second
+	c.maxConnRetry = -1
+
+	// the active broker reported in the reverse stats
+	brokerStat := func() string {
+		m, ok := expvar.Get("reverse").(*expvar.Map)
+		if !ok {
+			return ""
+		}
+		v, ok := m.Get("broker").(*expvar.String)
+		if !ok {
+			return ""
+		}
+		return v.Value()
+	}
+
+	t.Log("first broker")
+	{
+		if _, cerr := c.connect(); cerr == nil {
+			t.Fatal("expected error")
+		} else if !strings.Contains(cerr.Error(), rcs[0].ReverseURL.Host) {
+			t.Fatalf("expected (%s) got (%s)", rcs[0].ReverseURL.Host, cerr)
+		}
+		if b := brokerStat(); b != rcs[0].ReverseURL.Host {
+			t.Fatalf("expected broker stat (%s), got (%s)", rcs[0].ReverseURL.Host, b)
+		}
+	}
+
+	t.Log("fail over to second broker")
+	{
+		c.connAttempts = c.configRetryLimit
+		c.delay = 0
+		if _, cerr := c.connect(); cerr == nil {
+			t.Fatal("expected error")
+		} else if !strings.Contains(cerr.Error(), rcs[1].ReverseURL.Host) {
+			t.Fatalf("expected (%s) got (%s)", rcs[1].ReverseURL.Host, cerr)
+		}
+		state := c.State()
+		if idx, ok := state["broker_index"].(int); !ok || idx != 1 {
+			t.Fatalf("expected broker_index 1, got (%#v)", state["broker_index"])
+		}
+		if u, ok := state["broker_url"].(string); !ok || u != rcs[1].ReverseURL.Host+"/check/foo-bar-baz" {
+			t.Fatalf("expected broker_url (%s), got (%#v)", rcs[1].ReverseURL.Host, state["broker_url"])
+		}
+		if b := brokerStat(); b != rcs[1].ReverseURL.Host {
+			t.Fatalf("expected broker stat (%s), got (%s)", rcs[1].ReverseURL.Host, b)
+		}
+	}
+}
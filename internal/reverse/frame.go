@@ -45,8 +45,9 @@ func (c *Connection) readFrameFromBroker(r io.Reader) (*noitFrame, error) {
 		return nil, err
 	}
 
-	if hdr.payloadLen > c.maxPayloadLen {
-		return nil, errors.Errorf("received oversized frame (%d len)", hdr.payloadLen) // restart the connection
+	// reject before allocating the payload buffer
+	if hdr.payloadLen > c.maxFrameLen {
+		return nil, errors.Errorf("received oversized frame (%d len, max %d)", hdr.payloadLen, c.maxFrameLen) // restart the connection
 	}
 
 	if conn, ok := r.(*tls.Conn); ok {
@@ -11,8 +11,10 @@ import (
 	"testing"
 
 	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
 	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
 )
 
 func TestBuildFrame(t *testing.T) {
@@ -227,3 +229,71 @@ func TestReadFrameFromBroker(t *testing.T) {
 		}
 	}
 }
+
+func TestReadFrameFromBrokerOversized(t *testing.T) {
+	t.Log("Testing readFrameFromBroker w/oversized frame")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	chk, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	t.Log("header over protocol max, no payload sent")
+	{
+		s, err := New(chk, defaults.Listen)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		// header claims ~4GB payload, must be rejected before allocation
+		data := []byte{0x80, 0x01, 0xff, 0xff, 0xff, 0xff}
+		p, err := s.readFrameFromBroker(bytes.NewReader(data))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if p != nil {
+			t.Fatalf("expected nil frame, got (%#v)", p)
+		}
+		expect := "received oversized frame (4294967295 len, max 65529)"
+		if err.Error() != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, err)
+		}
+	}
+
+	t.Log("configured max frame size")
+	{
+		viper.Set(config.KeyReverseMaxFrameSize, 10)
+		s, err := New(chk, defaults.Listen)
+		viper.Reset()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		data := buildFrame(1, false, []byte(`{"test": 1}`))
+		if _, err := s.readFrameFromBroker(bytes.NewReader(data)); err == nil {
+			t.Fatal("expected error")
+		}
+
+		data = buildFrame(1, true, []byte("RESET"))
+		if _, err := s.readFrameFromBroker(bytes.NewReader(data)); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+	}
+
+	t.Log("oversized command resets connection")
+	{
+		s, err := New(chk, defaults.Listen)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		data := []byte{0x80, 0x01, 0x7f, 0xff, 0xff, 0xff}
+		cmd := s.readCommand(bytes.NewReader(data))
+		if cmd.err == nil {
+			t.Fatal("expected error")
+		}
+		if !cmd.reset {
+			t.Fatal("expected connection reset")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package reverse
+
+import (
+	stdlog "log"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const latencyMetricName = "broker_latency"
+
+// initLatency initializes the broker latency measurement, if enabled
+func (c *Connection) initLatency() error {
+	interval := viper.GetString(config.KeyReverseLatencyInterval)
+	if interval == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return errors.Wrap(err, "parsing reverse latency interval")
+	}
+	if d <= 0 {
+		return nil
+	}
+
+	m, err := c.newManualMetrics("reverse-latency")
+	if err != nil {
+		return errors.Wrap(err, "reverse latency metrics")
+	}
+
+	c.latencyInterval = d
+	c.latencyMetrics = m
+
+	return nil
+}
+
+// newManualMetrics creates a cgm instance which is only flushed on request
+// (metrics are included in the agent's metrics, see Flush)
+func (c *Connection) newManualMetrics(pkg string) (*cgm.CirconusMetrics, error) {
+	cmc := &cgm.Config{
+		Debug: viper.GetBool(config.KeyDebugCGM),
+		Log:   stdlog.New(c.logger.With().Str("pkg", pkg).Logger(), "", 0),
+	}
+	// put cgm into manual mode (no interval, no api key, invalid submission url)
+	cmc.Interval = "0"                            // disable automatic flush
+	cmc.CheckManager.Check.SubmissionURL = "none" // disable check management (create/update)
+
+	return cgm.NewCirconusMetrics(cmc)
+}
+
+// latencySent starts a measurement for the channel, if one is due. The
+// broker closes the channel (a command frame on the same channel) once it
+// has received the response, the time in between is the round-trip latency.
+func (c *Connection) latencySent(channelID uint16) {
+	if c.latencyMetrics == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	if !c.latencyStart.IsZero() {
+		// abandon a measurement which was never acknowledged
+		if now.Sub(c.latencyStart) < c.commTimeout*time.Duration(c.maxCommTimeouts) {
+			return
+		}
+		c.latencyStart = time.Time{}
+	}
+	if now.Before(c.latencyNext) {
+		return
+	}
+
+	c.latencyChannel = channelID
+	c.latencyStart = now
+}
+
+// latencyAbort discards a pending measurement (e.g. send failed, reconnect)
+func (c *Connection) latencyAbort() {
+	c.Lock()
+	c.latencyStart = time.Time{}
+	c.Unlock()
+}
+
+// latencyAck completes a pending measurement when a frame for the
+// measured channel is received from the broker
+func (c *Connection) latencyAck(channelID uint16) {
+	if c.latencyMetrics == nil {
+		return
+	}
+
+	c.Lock()
+	if c.latencyStart.IsZero() || channelID != c.latencyChannel {
+		c.Unlock()
+		return
+	}
+	now := time.Now()
+	latency := now.Sub(c.latencyStart)
+	c.latencyStart = time.Time{}
+	c.latencyNext = now.Add(c.latencyInterval)
+	c.Unlock()
+
+	c.latencyMetrics.RecordValue(latencyMetricName, latency.Seconds())
+	c.logger.Debug().Uint16("channel", channelID).Str("latency", latency.String()).Msg("broker round-trip")
+}
+
+// Flush returns the broker latency, compression and command timeout
+// metrics collected since the last flush
+func (c *Connection) Flush() *cgm.Metrics {
+	metrics := cgm.Metrics{}
+	for _, m := range []*cgm.CirconusMetrics{c.latencyMetrics, c.compressMetrics, c.cmdMetrics} {
+		if m == nil {
+			continue
+		}
+		for name, metric := range *m.FlushMetrics() {
+			metrics[name] = metric
+		}
+	}
+	return &metrics
+}
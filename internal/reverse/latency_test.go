@@ -0,0 +1,96 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package reverse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+func TestLatency(t *testing.T) {
+	t.Log("Testing broker latency")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tdisabled")
+	{
+		viper.Reset()
+		c := Connection{logger: log.With().Logger()}
+		if err := c.initLatency(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		c.latencySent(1)
+		c.latencyAck(1)
+		if m := c.Flush(); len(*m) != 0 {
+			t.Fatalf("expected no metrics, got %#v", *m)
+		}
+	}
+
+	t.Log("\tinvalid interval")
+	{
+		viper.Reset()
+		viper.Set(config.KeyReverseLatencyInterval, "foo")
+		c := Connection{logger: log.With().Logger()}
+		if err := c.initLatency(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tmeasured")
+	{
+		viper.Reset()
+		viper.Set(config.KeyReverseLatencyInterval, "1h")
+		c := Connection{logger: log.With().Logger(), commTimeout: 10 * time.Second, maxCommTimeouts: 5}
+		if err := c.initLatency(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		c.latencySent(1)
+		c.latencyAck(2) // different channel, ignored
+		if c.latencyStart.IsZero() {
+			t.Fatal("expected pending measurement")
+		}
+		c.latencyAck(1)
+		if !c.latencyStart.IsZero() {
+			t.Fatal("expected no pending measurement")
+		}
+
+		// next measurement not due for an hour
+		c.latencySent(3)
+		if !c.latencyStart.IsZero() {
+			t.Fatal("expected no pending measurement")
+		}
+
+		m := c.Flush()
+		if _, ok := (*m)[latencyMetricName]; !ok {
+			t.Fatalf("expected %s, got %#v", latencyMetricName, *m)
+		}
+	}
+
+	t.Log("\tabort")
+	{
+		viper.Reset()
+		viper.Set(config.KeyReverseLatencyInterval, "1s")
+		c := Connection{logger: log.With().Logger(), commTimeout: 10 * time.Second, maxCommTimeouts: 5}
+		if err := c.initLatency(); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		c.latencySent(1)
+		c.latencyAbort()
+		c.latencyAck(1)
+		if m := c.Flush(); len(*m) != 0 {
+			t.Fatalf("expected no metrics, got %#v", *m)
+		}
+	}
+
+	viper.Reset()
+}
@@ -28,8 +28,10 @@ func init() {
 	rand.Seed(n.Int64())
 }
 
-// New creates a new connection
-func New(check *check.Check, agentAddress string) (*Connection, error) {
+// New creates a new connection, options (e.g. WithDialer) control how the
+// connection to the broker is established. The check may be nil if the
+// broker configurations are set with WithReverseConfigs.
+func New(check *check.Check, agentAddress string, opts ...Option) (*Connection, error) {
 	const (
 		// NOTE: TBD, make some of these user-configurable
 		commTimeoutSeconds    = 10 // seconds, when communicating with noit
@@ -42,14 +44,9 @@ func New(check *check.Check, agentAddress string) (*Connection, error) {
 		brokerActiveStatus    = "active"
 	)
 
-	if check == nil {
-		return nil, errors.New("invalid check value (empty)")
-	}
-	if agentAddress == "" {
-		return nil, errors.New("invalid agent address (empty)")
-	}
 	c := Connection{
 		agentAddress:     agentAddress,
+		agentAuthHeader:  agentAuthHeader(),
 		check:            check,
 		commTimeout:      commTimeoutSeconds * time.Second,
 		connAttempts:     0,
@@ -70,16 +67,63 @@ func New(check *check.Check, agentAddress string) (*Connection, error) {
 		maxRequests:      maxRequests,                                 // max requests from broker before reset
 	}
 
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// the check is only used for the broker configurations
+	if check == nil && c.staticConfigs == nil {
+		return nil, errors.New("invalid check value (empty)")
+	}
+	if agentAddress == "" {
+		return nil, errors.New("invalid agent address (empty)")
+	}
+
+	c.maxFrameLen = c.maxPayloadLen
+	if size := viper.GetInt(config.KeyReverseMaxFrameSize); size > 0 && uint32(size) < c.maxPayloadLen {
+		c.maxFrameLen = uint32(size)
+	}
+
+	// validated, empty uses the default
+	c.cmdTimeout = c.metricTimeout
+	if timeout := viper.GetString(config.KeyReverseCommandTimeout); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing reverse command timeout")
+		}
+		if d > 0 {
+			c.cmdTimeout = d
+		}
+	}
+
+	if jitter := viper.GetString(config.KeyReverseConnectJitter); jitter != "" {
+		d, err := time.ParseDuration(jitter)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing reverse connect jitter")
+		}
+		if d > 0 {
+			c.connectJitter = d
+		}
+	}
+
 	if c.enabled {
 		c.logger.Info().Str("agent_address", c.agentAddress).Msg("reverse")
-		rc, err := c.check.GetReverseConfig()
+		if err := c.initLatency(); err != nil {
+			return nil, err
+		}
+		if err := c.initCompression(); err != nil {
+			return nil, err
+		}
+		m, err := c.newManualMetrics("reverse-command")
 		if err != nil {
-			return nil, errors.Wrap(err, "setting reverse config")
+			return nil, errors.Wrap(err, "reverse command metrics")
 		}
-		if rc == nil {
-			return nil, errors.New("invalid reverse configuration (nil)")
+		c.cmdMetrics = m
+		rcs, err := c.reverseConfigs()
+		if err != nil {
+			return nil, errors.Wrap(err, "setting reverse config")
 		}
-		c.revConfig = *rc
+		c.setReverseConfigs(rcs)
 	}
 
 	c.logger = log.With().Str("pkg", "reverse").Str("cid", viper.GetString(config.KeyCheckBundleID)).Logger()
@@ -6,7 +6,9 @@
 package reverse
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,7 +16,9 @@ import (
 	"net"
 	"time"
 
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 // sendMetricData frames and sends data (in chunks <= maxPayloadLen) to broker
@@ -74,6 +78,8 @@ func (c *Connection) fetchMetricData(request *[]byte) (*[]byte, error) {
 	// plugin execution speed
 	conn.SetDeadline(time.Now().Add(c.metricTimeout))
 
+	request = c.addAgentAuth(request)
+
 	numBytes, err := conn.Write(*request)
 	if err != nil {
 		return nil, errors.Wrap(err, "writing metric request")
@@ -92,3 +98,34 @@ func (c *Connection) fetchMetricData(request *[]byte) (*[]byte, error) {
 
 	return &data, nil
 }
+
+// agentAuthHeader builds the Authorization header required by the local
+// agent listener, if server auth is configured (token takes precedence)
+func agentAuthHeader() string {
+	if token := viper.GetString(config.KeyServerAuthToken); token != "" {
+		return "Authorization: Bearer " + token
+	}
+	if user := viper.GetString(config.KeyServerAuthUser); user != "" {
+		creds := user + ":" + viper.GetString(config.KeyServerAuthPassword)
+		return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	}
+	return ""
+}
+
+// addAgentAuth inserts the agent Authorization header into the request
+// forwarded from the broker (after the request line)
+func (c *Connection) addAgentAuth(request *[]byte) *[]byte {
+	if c.agentAuthHeader == "" || request == nil {
+		return request
+	}
+	idx := bytes.Index(*request, []byte("\r\n"))
+	if idx == -1 {
+		return request
+	}
+	req := make([]byte, 0, len(*request)+len(c.agentAuthHeader)+2)
+	req = append(req, (*request)[:idx+2]...)
+	req = append(req, c.agentAuthHeader...)
+	req = append(req, "\r\n"...)
+	req = append(req, (*request)[idx+2:]...)
+	return &req
+}
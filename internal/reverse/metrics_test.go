@@ -16,8 +16,10 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
 	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
 )
 
 func TestSendMetricData(t *testing.T) {
@@ -117,3 +119,48 @@ func TestFetchMetricData(t *testing.T) {
 		t.Fatalf("%s", string(*data))
 	}
 }
+
+func TestAddAgentAuth(t *testing.T) {
+	t.Log("Testing addAgentAuth")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	req := []byte("GET / HTTP/1.1\r\nHost: 127.0.0.1\r\n\r\n")
+
+	t.Log("no auth")
+	{
+		viper.Reset()
+		c := Connection{agentAuthHeader: agentAuthHeader()}
+		r := c.addAgentAuth(&req)
+		if !bytes.Equal(*r, req) {
+			t.Fatalf("expected (%s) got (%s)", string(req), string(*r))
+		}
+	}
+
+	t.Log("token")
+	{
+		viper.Reset()
+		viper.Set(config.KeyServerAuthToken, "foo")
+		c := Connection{agentAuthHeader: agentAuthHeader()}
+		r := c.addAgentAuth(&req)
+		expect := "GET / HTTP/1.1\r\nAuthorization: Bearer foo\r\nHost: 127.0.0.1\r\n\r\n"
+		if string(*r) != expect {
+			t.Fatalf("expected (%q) got (%q)", expect, string(*r))
+		}
+	}
+
+	t.Log("basic")
+	{
+		viper.Reset()
+		viper.Set(config.KeyServerAuthUser, "foo")
+		viper.Set(config.KeyServerAuthPassword, "bar")
+		c := Connection{agentAuthHeader: agentAuthHeader()}
+		r := c.addAgentAuth(&req)
+		expect := "GET / HTTP/1.1\r\nAuthorization: Basic Zm9vOmJhcg==\r\nHost: 127.0.0.1\r\n\r\n"
+		if string(*r) != expect {
+			t.Fatalf("expected (%q) got (%q)", expect, string(*r))
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,83 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package reverse
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+)
+
+// DialFunc establishes the network connection to a broker, the tls handshake
+// is performed over the returned connection. The context is done when the
+// dialer timeout expires. The signature matches net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Option configures a Connection created by New
+type Option func(*Connection)
+
+// WithDialer sets the function used to connect to the broker, instead of a
+// net.Dialer (e.g. to connect through a proxy, or to an in-process broker
+// when testing or embedding the agent)
+func WithDialer(dial DialFunc) Option {
+	return func(c *Connection) {
+		c.dial = dial
+	}
+}
+
+// WithReverseConfigs sets the broker configurations, in failover order, to
+// use instead of the configurations from the check. The check is not
+// refreshed when every broker has failed, the configurations are re-used.
+func WithReverseConfigs(rcs []check.ReverseConfig) Option {
+	return func(c *Connection) {
+		c.staticConfigs = append([]check.ReverseConfig{}, rcs...)
+	}
+}
+
+// dialBroker connects to the broker at addr and completes the tls handshake,
+// using the dial function if one is set
+func (c *Connection) dialBroker(addr string, tlsConfig *tls.Config) (*tls.Conn, error) {
+	if c.dial == nil {
+		dialer := &net.Dialer{Timeout: c.dialerTimeout}
+		return tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.dialerTimeout)
+	defer cancel()
+
+	rawConn, err := c.dial(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// as tls.DialWithDialer, the server name defaults to the host dialed
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	if tlsConfig.ServerName == "" && !tlsConfig.InsecureSkipVerify {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+
+	conn := tls.Client(rawConn, tlsConfig)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := conn.Handshake(); err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
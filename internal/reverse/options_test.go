@@ -0,0 +1,169 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package reverse
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestNewOptions(t *testing.T) {
+	t.Log("Testing New w/options")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tsURL, err := url.Parse("http://fake.circonus-broker.com:43191/check/foo-bar-baz#abc123")
+	if err != nil {
+		t.Fatalf("expected no error got (%s)", err)
+	}
+	rc := check.ReverseConfig{
+		ReverseURL: tsURL,
+		BrokerAddr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 43191},
+	}
+
+	t.Log("\treverse configs, no check")
+	{
+		viper.Set(config.KeyReverse, true)
+		c, err := New(nil, defaults.Listen, WithReverseConfigs([]check.ReverseConfig{rc}))
+		viper.Reset()
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if c.revConfig.ReverseURL != tsURL {
+			t.Fatalf("expected reverse config (%#v), got (%#v)", rc, c.revConfig)
+		}
+	}
+
+	t.Log("\treverse configs (none)")
+	{
+		viper.Set(config.KeyReverse, true)
+		_, err := New(nil, defaults.Listen, WithReverseConfigs(nil))
+		viper.Reset()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		expect := "setting reverse config: invalid reverse configuration (none)"
+		if err.Error() != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, err)
+		}
+	}
+}
+
+func TestConnectWithDialer(t *testing.T) {
+	t.Log("Testing connect w/dialer")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	cert, err := tls.X509KeyPair(tcert, tkey)
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	tcfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	cp := x509.NewCertPool()
+	clicert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+	cp.AddCert(clicert)
+
+	tsURL, err := url.Parse("http://fake.circonus-broker.com:43191/check/foo-bar-baz#abc123")
+	if err != nil {
+		t.Fatalf("expected no error got (%s)", err)
+	}
+	// not reachable, all connections go through the dialer
+	brokerAddr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 43191}
+	rc := check.ReverseConfig{
+		ReverseURL: tsURL,
+		BrokerAddr: brokerAddr,
+		TLSConfig:  &tls.Config{RootCAs: cp, ServerName: "fake.circonus-broker.com"},
+	}
+
+	t.Log("\tvalid")
+	{
+		intro := make(chan string, 1)
+		var dialed string
+		dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = address
+			cli, srv := net.Pipe()
+			go func() {
+				defer srv.Close()
+				conn := tls.Server(srv, tcfg)
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				intro <- line
+			}()
+			return cli, nil
+		}
+
+		viper.Set(config.KeyReverse, true)
+		s, err := New(nil, defaults.Listen, WithReverseConfigs([]check.ReverseConfig{rc}), WithDialer(dial))
+		viper.Reset()
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+		s.dialerTimeout = 2 * time.Second
+
+		conn, cerr := s.connect()
+		if cerr != nil {
+			t.Fatalf("expected no error got (%s)", cerr)
+		}
+		defer conn.Close()
+
+		if dialed != brokerAddr.String() {
+			t.Fatalf("expected dial of %s, got (%s)", brokerAddr, dialed)
+		}
+		expect := "REVERSE /check/foo-bar-baz#abc123 HTTP/1.1\r\n"
+		select {
+		case line := <-intro:
+			if line != expect {
+				t.Fatalf("expected intro (%q) got (%q)", expect, line)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected intro")
+		}
+	}
+
+	t.Log("\tdial error")
+	{
+		dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("forced dial error")
+		}
+
+		viper.Set(config.KeyReverse, true)
+		s, err := New(nil, defaults.Listen, WithReverseConfigs([]check.ReverseConfig{rc}), WithDialer(dial))
+		viper.Reset()
+		if err != nil {
+			t.Fatalf("expected no error got (%s)", err)
+		}
+
+		s.maxConnRetry = -1
+
+		_, cerr := s.connect()
+		if cerr == nil {
+			t.Fatal("expected error")
+		}
+		if cerr.fatal {
+			t.Fatal("expected non-fatal error")
+		}
+		expect := "connecting to fake.circonus-broker.com:43191: forced dial error"
+		if cerr.Error() != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, cerr)
+		}
+	}
+}
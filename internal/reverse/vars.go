@@ -12,36 +12,65 @@ import (
 	tomb "gopkg.in/tomb.v2"
 
 	"github.com/circonus-labs/circonus-agent/internal/check"
+	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/rs/zerolog"
 )
 
 // Connection defines a reverse connection
 type Connection struct {
 	agentAddress     string
+	agentAuthHeader  string
 	check            *check.Check
 	cmdConnect       string
 	cmdReset         string
+	cmdTimeout       time.Duration        // maximum time to service a command, an error response is sent on overrun
+	cmdTimeouts      int                  // commands which exceeded cmdTimeout
+	cmdMetrics       *cgm.CirconusMetrics // command timeouts, nil if reverse is disabled
 	commTimeout      time.Duration
+	compressMetrics  *cgm.CirconusMetrics // payload sizes, nil if compression disabled
+	compression      string               // compression of metrics sent to the broker, empty if disabled
 	commTimeouts     int
 	configRetryLimit int
 	connAttempts     int
+	connectJitter    time.Duration // maximum random delay before connecting, 0 disables
+	connected        bool
+	connectedSince   time.Time // when the current connection was established, zero if not connected
 	delay            time.Duration
+	dial             DialFunc // connects to the broker, nil uses a net.Dialer
 	dialerTimeout    time.Duration
 	enabled          bool
+	lastError        error                // last connection error (if any)
+	lastErrorTime    time.Time            // when lastError occurred
+	latencyChannel   uint16               // channel of the pending latency measurement
+	latencyInterval  time.Duration        // minimum time between latency measurements
+	latencyMetrics   *cgm.CirconusMetrics // broker latency histogram, nil if disabled
+	latencyNext      time.Time            // next latency measurement allowed
+	latencyStart     time.Time            // start of pending latency measurement, zero if none
 	logger           zerolog.Logger
 	maxCommTimeouts  int
 	maxConnRetry     int
 	maxDelay         time.Duration
 	maxDelayStep     int
+	maxFrameLen      uint32 // max payload len accepted from broker
 	maxPayloadLen    uint32
 	maxRequests      int
 	metricTimeout    time.Duration
 	minDelayStep     int
-	revConfig        check.ReverseConfig
+	revConfig        check.ReverseConfig   // active broker configuration
+	revConfigs       []check.ReverseConfig // all broker configurations, in failover order
+	revIdx           int                   // index of active configuration in revConfigs
+	running          bool                  // connection loop is running (connected or connecting)
+	staticConfigs    []check.ReverseConfig // broker configurations set with WithReverseConfigs, used instead of the check's
 	sync.Mutex
 	t tomb.Tomb
 }
 
+const (
+	stateConnected    = "connected"
+	stateConnecting   = "connecting"
+	stateDisconnected = "disconnected"
+)
+
 // noitHeader defines the header received from the noit/broker
 type noitHeader struct {
 	channelID  uint16
@@ -0,0 +1,57 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// authRequired returns true if either token or basic auth is configured
+func (s *Server) authRequired() bool {
+	return s.authToken != "" || s.authUser != ""
+}
+
+// authorized verifies the request supplied valid credentials. Either a
+// bearer token or basic auth (whichever are configured) is accepted.
+func (s *Server) authorized(r *http.Request) bool {
+	if !s.authRequired() {
+		return true
+	}
+
+	if s.authToken != "" {
+		hdr := r.Header.Get("Authorization")
+		const prefix = "Bearer "
+		if len(hdr) > len(prefix) && strings.EqualFold(hdr[:len(prefix)], prefix) {
+			if subtle.ConstantTimeCompare([]byte(hdr[len(prefix):]), []byte(s.authToken)) == 1 {
+				return true
+			}
+		}
+	}
+
+	if s.authUser != "" {
+		if user, pass, ok := r.BasicAuth(); ok {
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(s.authUser)) == 1
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.authPass)) == 1
+			if userOK && passOK {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unauthorized responds with 401 and the appropriate challenge
+func (s *Server) unauthorized(w http.ResponseWriter) {
+	if s.authUser != "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="circonus-agent"`)
+	} else {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="circonus-agent"`)
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
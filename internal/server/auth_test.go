@@ -0,0 +1,88 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestAuthorized(t *testing.T) {
+	t.Log("Testing authorized")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	t.Log("no auth configured")
+	{
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		req := httptest.NewRequest("GET", "/inventory", nil)
+		if !s.authorized(req) {
+			t.Fatal("expected authorized")
+		}
+	}
+
+	viper.Set(config.KeyServerAuthToken, "foo")
+	viper.Set(config.KeyServerAuthUser, "bar")
+	viper.Set(config.KeyServerAuthPassword, "baz")
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	tests := []struct {
+		desc   string
+		setup  func(r *http.Request)
+		expect bool
+	}{
+		{"no credentials", func(r *http.Request) {}, false},
+		{"valid token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer foo") }, true},
+		{"invalid token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer bad") }, false},
+		{"valid basic", func(r *http.Request) { r.SetBasicAuth("bar", "baz") }, true},
+		{"invalid basic", func(r *http.Request) { r.SetBasicAuth("bar", "bad") }, false},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s", test.desc)
+		req := httptest.NewRequest("GET", "/inventory", nil)
+		test.setup(req)
+		if ok := s.authorized(req); ok != test.expect {
+			t.Fatalf("expected %v, got %v", test.expect, ok)
+		}
+	}
+
+	t.Logf("router w/o credentials -> %d", http.StatusUnauthorized)
+	{
+		req := httptest.NewRequest("GET", "/inventory", nil)
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+		}
+		if resp.Header.Get("WWW-Authenticate") == "" {
+			t.Fatal("expected WWW-Authenticate header")
+		}
+	}
+
+	viper.Reset()
+}
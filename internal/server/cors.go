@@ -0,0 +1,69 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	corsAnyOrigin    = "*"
+	corsAllowMethods = "GET, POST, PUT, OPTIONS"
+	corsAllowHeaders = "Authorization, Content-Type"
+	corsMaxAge       = "600"
+)
+
+// corsOrigin returns the Access-Control-Allow-Origin value for the request
+// origin, empty if cors is not enabled or the origin is not allowed
+func (s *Server) corsOrigin(origin string) string {
+	if len(s.corsOrigins) == 0 || origin == "" {
+		return ""
+	}
+	for _, allowed := range s.corsOrigins {
+		if allowed == corsAnyOrigin {
+			return corsAnyOrigin
+		}
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// cors adds the cors headers for an allowed origin. Returns true if the
+// request was a preflight request, which has been answered.
+func (s *Server) cors(w http.ResponseWriter, r *http.Request) bool {
+	if len(s.corsOrigins) == 0 {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == "OPTIONS" && origin != "" && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if allowed := s.corsOrigin(origin); allowed != "" {
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		// the response varies by origin, unless any origin is allowed
+		if allowed != corsAnyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+		}
+	}
+
+	if !preflight {
+		return false
+	}
+
+	// preflight requests do not carry credentials, they are answered before
+	// authorization; a disallowed origin gets no cors headers, the browser
+	// then blocks the actual request
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
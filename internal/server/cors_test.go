@@ -0,0 +1,163 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestCORS(t *testing.T) {
+	t.Log("Testing cors")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	t.Log("not enabled")
+	{
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set("Origin", "http://localhost:8080")
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		if hdr := w.Result().Header.Get("Access-Control-Allow-Origin"); hdr != "" {
+			t.Fatalf("expected no cors header, got (%s)", hdr)
+		}
+
+		req = httptest.NewRequest("OPTIONS", "/inventory", nil)
+		req.Header.Set("Origin", "http://localhost:8080")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		w = httptest.NewRecorder()
+
+		s.router(w, req)
+
+		if w.Result().StatusCode != http.StatusMethodNotAllowed {
+			t.Fatalf("expected %d, got %d", http.StatusMethodNotAllowed, w.Result().StatusCode)
+		}
+	}
+
+	viper.Set(config.KeyServerCORSOrigins, []string{"http://localhost:8080/"})
+	viper.Set(config.KeyServerAuthToken, "foo")
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Log("allowed origin")
+	{
+		req := httptest.NewRequest("GET", "/inventory", nil)
+		req.Header.Set("Origin", "http://localhost:8080")
+		req.Header.Set("Authorization", "Bearer foo")
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if hdr := resp.Header.Get("Access-Control-Allow-Origin"); hdr != "http://localhost:8080" {
+			t.Fatalf("expected origin, got (%s)", hdr)
+		}
+		if hdr := resp.Header.Get("Vary"); hdr != "Origin" {
+			t.Fatalf("expected Vary Origin, got (%s)", hdr)
+		}
+	}
+
+	t.Log("disallowed origin")
+	{
+		req := httptest.NewRequest("GET", "/inventory", nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("Authorization", "Bearer foo")
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		if hdr := w.Result().Header.Get("Access-Control-Allow-Origin"); hdr != "" {
+			t.Fatalf("expected no cors header, got (%s)", hdr)
+		}
+	}
+
+	t.Log("preflight, w/o credentials")
+	{
+		req := httptest.NewRequest("OPTIONS", "/inventory", nil)
+		req.Header.Set("Origin", "http://localhost:8080")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Headers", "authorization")
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.StatusCode)
+		}
+		if hdr := resp.Header.Get("Access-Control-Allow-Origin"); hdr != "http://localhost:8080" {
+			t.Fatalf("expected origin, got (%s)", hdr)
+		}
+		if hdr := resp.Header.Get("Access-Control-Allow-Methods"); hdr != corsAllowMethods {
+			t.Fatalf("expected (%s), got (%s)", corsAllowMethods, hdr)
+		}
+		if hdr := resp.Header.Get("Access-Control-Allow-Headers"); hdr != corsAllowHeaders {
+			t.Fatalf("expected (%s), got (%s)", corsAllowHeaders, hdr)
+		}
+	}
+
+	t.Log("preflight, disallowed origin")
+	{
+		req := httptest.NewRequest("OPTIONS", "/inventory", nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.StatusCode)
+		}
+		if hdr := resp.Header.Get("Access-Control-Allow-Origin"); hdr != "" {
+			t.Fatalf("expected no cors header, got (%s)", hdr)
+		}
+	}
+
+	t.Log("any origin")
+	{
+		s.corsOrigins = []string{"*"}
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set("Origin", "http://example.com")
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		if hdr := resp.Header.Get("Access-Control-Allow-Origin"); hdr != "*" {
+			t.Fatalf("expected *, got (%s)", hdr)
+		}
+		if hdr := resp.Header.Get("Vary"); hdr != "" {
+			t.Fatalf("expected no Vary, got (%s)", hdr)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,55 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"time"
+
+	"github.com/maier/go-appstats"
+)
+
+// startDirect collects and submits metrics directly to the check on the
+// direct interval, if enabled, until the servers are stopped. This replaces
+// the broker requesting metrics (reverse or polling) for environments where
+// the broker cannot reach the agent.
+func (s *Server) startDirect() error {
+	if s.directInterval == 0 {
+		return nil
+	}
+
+	s.logger.Info().Str("interval", s.directInterval.String()).Msg("direct submission enabled")
+
+	ticker := time.NewTicker(s.directInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.t.Dying():
+			return nil
+		case <-ticker.C:
+			s.submitDirect()
+		}
+	}
+}
+
+// submitDirect collects all metrics and submits them to the check, a failed
+// submission is logged (and spooled, if configured), it does not stop the agent
+func (s *Server) submitDirect() {
+	start := time.Now()
+	metrics := s.collect("")
+
+	if err := s.check.SubmitMetrics(&metrics); err != nil {
+		appstats.IncrementInt("direct_submit_errors")
+		s.logger.Error().Err(err).Int("metrics", len(metrics)).Msg("direct submission")
+		return
+	}
+
+	appstats.IncrementInt("direct_submits")
+	s.logger.Debug().
+		Int("metrics", len(metrics)).
+		Str("duration", time.Since(start).String()).
+		Msg("direct submission")
+}
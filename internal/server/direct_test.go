@@ -0,0 +1,104 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/builtins"
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/plugins"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestStartDirect(t *testing.T) {
+	t.Log("Testing startDirect")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, derr := os.Getwd()
+	if derr != nil {
+		t.Fatalf("unable to get cwd (%s)", derr)
+	}
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	viper.Set(config.KeyPluginDir, path.Join(dir, "testdata"))
+	b, err := builtins.New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	p, err := plugins.New(context.Background())
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	c, err := check.New(nil) // NOP check, submissions fail (no bundle)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Log("disabled (default)")
+	{
+		s, err := New(c, b, p, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.directInterval != 0 {
+			t.Fatalf("expected direct disabled, got %s", s.directInterval)
+		}
+		if err := s.startDirect(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	viper.Set(config.KeyDirect, true)
+
+	t.Log("invalid interval")
+	{
+		viper.Set(config.KeyDirectInterval, "foo")
+		if _, err := New(c, b, p, nil); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("enabled, failed submissions do not stop direct")
+	{
+		viper.Set(config.KeyDirectInterval, "10ms")
+		s, err := New(c, b, p, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.directInterval != 10*time.Millisecond {
+			t.Fatalf("expected 10ms, got %s", s.directInterval)
+		}
+
+		lastMeticsmu.Lock()
+		lastMetrics.ts = time.Time{}
+		lastMeticsmu.Unlock()
+
+		s.t.Go(s.startDirect)
+		time.Sleep(100 * time.Millisecond)
+
+		lastMeticsmu.Lock()
+		collected := !lastMetrics.ts.IsZero()
+		lastMeticsmu.Unlock()
+		if !collected {
+			t.Fatal("expected metrics to be collected")
+		}
+
+		s.t.Kill(nil)
+		if err := s.t.Wait(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	viper.Reset()
+}
@@ -14,11 +14,14 @@ import (
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/circonus-labs/circonus-agent/api"
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/release"
 	"github.com/circonus-labs/circonus-agent/internal/server/promrecv"
 	"github.com/circonus-labs/circonus-agent/internal/server/receiver"
 	cgm "github.com/circonus-labs/circonus-gometrics"
@@ -28,9 +31,22 @@ import (
 
 // run handles requests to execute plugins and return metrics emitted
 // handles /, /run, or /run/plugin_name
+// an optional ?filter=<regex> restricts the metrics returned to names matching the regex
 func (s *Server) run(w http.ResponseWriter, r *http.Request) {
 	id := ""
 
+	var filterRx *regexp.Regexp
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		rx, err := regexp.Compile(filter)
+		if err != nil {
+			appstats.IncrementInt("requests_bad")
+			s.logger.Warn().Err(err).Str("filter", filter).Msg("invalid filter")
+			http.Error(w, fmt.Sprintf("invalid filter regex (%s)", err), http.StatusBadRequest)
+			return
+		}
+		filterRx = rx
+	}
+
 	if strings.HasPrefix(r.URL.Path, "/run/") { // run specific item
 		id = strings.Replace(r.URL.Path, "/run/", "", -1)
 		if id != "" {
@@ -62,6 +78,21 @@ func (s *Server) run(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	metrics := s.collect(id)
+
+	if filterRx != nil {
+		filtered := filterMetrics(metrics, filterRx)
+		s.encodeResponse(&filtered, w, r)
+		return
+	}
+
+	s.encodeResponse(&metrics, w, r)
+}
+
+// collect runs and flushes the builtins, plugins and internal servers
+// identified by id (all if id is blank), enables any new metrics on the
+// check and mirrors the metrics to the secondary check (if configured)
+func (s *Server) collect(id string) cgm.Metrics {
 	lastMeticsmu.Lock()
 	defer lastMeticsmu.Unlock()
 
@@ -136,6 +167,12 @@ func (s *Server) run(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if id == "" && s.reverseMetrics != nil {
+		for metricName, metric := range *s.reverseMetrics.Flush() {
+			metrics["reverse"+config.MetricNameSeparator+metricName] = metric
+		}
+	}
+
 	if flushProm {
 		s.logger.Debug().Msg("prom start")
 		promMetrics := promrecv.Flush()
@@ -152,7 +189,21 @@ func (s *Server) run(w http.ResponseWriter, r *http.Request) {
 		s.logger.Warn().Err(err).Msg("unable to update check metrics")
 	}
 
-	s.encodeResponse(&metrics, w, r)
+	// mirror to the secondary (HA) check, if configured, without delaying the response
+	go s.check.MirrorMetrics(&metrics)
+
+	return metrics
+}
+
+// filterMetrics returns only the metrics with names matching the regex
+func filterMetrics(metrics cgm.Metrics, rx *regexp.Regexp) cgm.Metrics {
+	filtered := cgm.Metrics{}
+	for metricName, metric := range metrics {
+		if rx.MatchString(metricName) {
+			filtered[metricName] = metric
+		}
+	}
+	return filtered
 }
 
 // encodeResponse takes care of encoding the response to an HTTP request for metrics.
@@ -235,14 +286,61 @@ func (s *Server) encodeResponse(m *cgm.Metrics, w http.ResponseWriter, r *http.R
 
 // inventory returns the current, active plugin inventory
 func (s *Server) inventory(w http.ResponseWriter, r *http.Request) {
-	inventory := s.plugins.Inventory()
-	if inventory == nil {
-		inventory = []byte(`{"error": "empty inventory"}`)
-		s.logger.Error().Msg("inventory is nil/empty...")
+	inventory := api.Inventory{}
+	if s.plugins != nil {
+		inventory = s.plugins.Inventory()
+	}
+	s.writeInventory(w, inventory)
+}
+
+// collectorInventory returns the builtin collector inventory
+func (s *Server) collectorInventory(w http.ResponseWriter, r *http.Request) {
+	inventory := api.Collectors{}
+	if s.builtins != nil {
+		inventory = s.builtins.Inventory()
+	}
+	s.writeInventory(w, inventory)
+}
+
+// writeInventory encodes an inventory as the json response
+func (s *Server) writeInventory(w http.ResponseWriter, inventory interface{}) {
+	data, err := json.Marshal(inventory)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("inventory -> json")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(inventory)
+	w.Write(data)
+}
+
+// version returns the agent build information and the hash of the effective
+// configuration (computed per request, it reflects configuration reloads)
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	hash, err := config.Hash()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("config hash")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(api.Version{
+		Name:       release.NAME,
+		Version:    release.VERSION,
+		Commit:     release.COMMIT,
+		BuildDate:  release.DATE,
+		Tag:        release.TAG,
+		ConfigHash: hash,
+	})
+	if err != nil {
+		s.logger.Error().Err(err).Msg("version -> json")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
 }
 
 // socketHandler gates /write for the socket server only
@@ -288,7 +386,7 @@ func (s *Server) write(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := receiver.Parse(id, r.Body); err != nil {
+	if err := receiver.Parse(id, http.MaxBytesReader(w, r.Body, maxWriteSize)); err != nil {
 		s.logger.Warn().Err(err).Msg("write recevier")
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -302,7 +400,7 @@ func (s *Server) write(w http.ResponseWriter, r *http.Request) {
 func (s *Server) promReceiver(w http.ResponseWriter, r *http.Request) {
 	s.logger.Debug().Str("path", r.URL.Path).Msg("prom metrics recevied")
 
-	if err := promrecv.Parse(r.Body); err != nil {
+	if err := promrecv.Parse(http.MaxBytesReader(w, r.Body, maxWriteSize)); err != nil {
 		s.logger.Warn().Err(err).Msg("prom recevier")
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -311,6 +409,39 @@ func (s *Server) promReceiver(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// statsdTest handles PUT/POST requests with statsd formatted metrics, parsing
+// them as the statsd listener would without recording them, the results
+// (name, type, value, destination or error for each metric) are returned
+func (s *Server) statsdTest(w http.ResponseWriter, r *http.Request) {
+	if s.statsdSvr == nil || !s.statsdSvr.Enabled() {
+		http.Error(w, "statsd disabled", http.StatusNotFound)
+		return
+	}
+
+	pkt, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxStatsdTestSize))
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("statsd test")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.statsdSvr.DryRun(pkt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("statsd test -> json")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
 // promOutput returns the last metrics in prom format
 func (s *Server) promOutput(w http.ResponseWriter, r *http.Request) {
 	if lastMetrics.metrics == nil || len(lastMetrics.metrics) == 0 {
@@ -9,20 +9,26 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/circonus-labs/circonus-agent/api"
 	"github.com/circonus-labs/circonus-agent/internal/builtins"
 	"github.com/circonus-labs/circonus-agent/internal/check"
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
 	"github.com/circonus-labs/circonus-agent/internal/plugins"
+	"github.com/circonus-labs/circonus-agent/internal/release"
+	"github.com/circonus-labs/circonus-agent/internal/statsd"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
@@ -39,6 +45,8 @@ func TestRun(t *testing.T) {
 		{"/run/foo", http.StatusNotFound},
 		{"/", http.StatusOK},
 		{"/run", http.StatusOK},
+		{"/run?filter=test", http.StatusOK},
+		{"/run?filter=%5B", http.StatusBadRequest},
 		{"/run/test", http.StatusOK},
 		{"/run/write", http.StatusOK},
 		{"/run/statsd", http.StatusOK},
@@ -90,6 +98,36 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestFilterMetrics(t *testing.T) {
+	t.Log("Testing filterMetrics")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	metrics := cgm.Metrics{
+		"foo`bar": cgm.Metric{Type: "i", Value: 1},
+		"foo`baz": cgm.Metric{Type: "i", Value: 2},
+		"qux":     cgm.Metric{Type: "i", Value: 3},
+	}
+
+	t.Log("match some")
+	{
+		filtered := filterMetrics(metrics, regexp.MustCompile("^foo"))
+		if len(filtered) != 2 {
+			t.Fatalf("expected 2 metrics, got %d (%#v)", len(filtered), filtered)
+		}
+		if _, ok := filtered["qux"]; ok {
+			t.Fatal("expected qux to be filtered")
+		}
+	}
+
+	t.Log("match none")
+	{
+		filtered := filterMetrics(metrics, regexp.MustCompile("^nope$"))
+		if len(filtered) != 0 {
+			t.Fatalf("expected 0 metrics, got %d (%#v)", len(filtered), filtered)
+		}
+	}
+}
+
 func TestInventory(t *testing.T) {
 	t.Log("Testing inventory")
 	zerolog.SetGlobalLevel(zerolog.Disabled)
@@ -130,6 +168,78 @@ func TestInventory(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
 	}
+
+	var inventory api.Inventory
+	if err := json.NewDecoder(resp.Body).Decode(&inventory); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	if inventory == nil {
+		t.Fatal("expected plugin list")
+	}
+
+	t.Logf("GET /inventory/collectors -> %d", http.StatusOK)
+	req = httptest.NewRequest("GET", "/inventory/collectors", nil)
+	w = httptest.NewRecorder()
+
+	s.collectorInventory(w, req)
+
+	resp = w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var collectors api.Collectors
+	if err := json.NewDecoder(resp.Body).Decode(&collectors); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	if collectors == nil {
+		t.Fatal("expected collector list")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	t.Log("Testing version")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	s, err := New(nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	get := func() api.Version {
+		req := httptest.NewRequest("GET", "/version", nil)
+		w := httptest.NewRecorder()
+		s.version(w, req)
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var v api.Version
+		if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		return v
+	}
+
+	t.Logf("GET /version -> %d", http.StatusOK)
+	v := get()
+	if v.Name != release.NAME || v.Version != release.VERSION {
+		t.Fatalf("unexpected release info %#v", v)
+	}
+	if len(v.ConfigHash) != 64 {
+		t.Fatalf("expected sha256 hex config hash, got (%s)", v.ConfigHash)
+	}
+
+	t.Log("config change -> new hash")
+	viper.Set(config.KeyPluginDir, "/tmp")
+	if v2 := get(); v2.ConfigHash == v.ConfigHash {
+		t.Fatalf("expected config hash to change, got (%s)", v2.ConfigHash)
+	}
+
+	viper.Reset()
 }
 
 func TestWrite(t *testing.T) {
@@ -288,6 +398,81 @@ func TestPromReceiver(t *testing.T) {
 
 }
 
+func TestStatsdTest(t *testing.T) {
+	t.Log("Testing statsdTest")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	t.Logf("POST /statsd/test (disabled) -> %d", http.StatusNotFound)
+	{
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		req := httptest.NewRequest("POST", "/statsd/test", strings.NewReader("foo:1|c"))
+		w := httptest.NewRecorder()
+
+		s.statsdTest(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected %d, got %d", http.StatusNotFound, resp.StatusCode)
+		}
+	}
+
+	t.Logf("POST /statsd/test -> %d", http.StatusOK)
+	{
+		viper.Set(config.KeyStatsdPort, "65128")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		ss, err := statsd.New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		s, err := New(c, nil, nil, ss)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		req := httptest.NewRequest("POST", "/statsd/test", strings.NewReader("foo:1|c\nbar"))
+		w := httptest.NewRecorder()
+
+		s.statsdTest(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		var results []statsd.ParseResult
+		if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got %v", results)
+		}
+		if results[0].Name != "foo" || results[0].Destination != "host" || results[0].Error != "" {
+			t.Fatalf("unexpected result %+v", results[0])
+		}
+		if results[1].Error == "" {
+			t.Fatalf("expected error, got %+v", results[1])
+		}
+		if m := ss.Flush(); m != nil && len(*m) != 0 {
+			t.Fatalf("expected no metrics recorded, got %v", *m)
+		}
+	}
+
+	viper.Reset()
+}
+
 func TestSocketHandler(t *testing.T) {
 	t.Log("Testing socketHandler")
 	zerolog.SetGlobalLevel(zerolog.Disabled)
@@ -0,0 +1,91 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/spf13/viper"
+)
+
+type reverseHealth struct {
+	Enabled   bool `json:"enabled"`
+	Connected bool `json:"connected"`
+}
+
+type readyStatus struct {
+	Ready   bool          `json:"ready"`
+	LastRun string        `json:"last_run,omitempty"`
+	Reverse reverseHealth `json:"reverse"`
+}
+
+// healthz responds 200 as long as the process is able to service requests
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	s.sendHealth(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz responds 200 once the agent has completed at least one collection
+// run and, if enabled, the reverse connection to the broker is established.
+// Otherwise, it responds 503.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	status := readyStatus{}
+
+	lastMeticsmu.Lock()
+	lastRun := lastMetrics.ts
+	lastMeticsmu.Unlock()
+
+	if !lastRun.IsZero() {
+		status.LastRun = lastRun.Format(time.RFC3339)
+	}
+
+	status.Reverse.Enabled = viper.GetBool(config.KeyReverse)
+	if status.Reverse.Enabled && s.reverse != nil {
+		status.Reverse.Connected = s.reverse.Connected()
+	}
+
+	status.Ready = !lastRun.IsZero() && (!status.Reverse.Enabled || status.Reverse.Connected)
+
+	code := http.StatusOK
+	if !status.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	s.sendHealth(w, code, status)
+}
+
+// reverseStatus responds with the state of the reverse connection to the
+// broker (connected, connecting or disconnected), the broker, the time the
+// connection was established and the last connection error
+func (s *Server) reverseStatus(w http.ResponseWriter, r *http.Request) {
+	state := map[string]interface{}{
+		"enabled": viper.GetBool(config.KeyReverse),
+		"state":   "disconnected",
+	}
+	if s.reverseState != nil {
+		state = s.reverseState.State()
+	}
+
+	s.sendHealth(w, http.StatusOK, state)
+}
+
+// sendHealth writes a json health response
+func (s *Server) sendHealth(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("encoding health status")
+		http.Error(w, "encoding health status", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		s.logger.Error().Err(err).Msg("writing health status")
+	}
+}
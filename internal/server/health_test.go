@@ -0,0 +1,217 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+type fakeReverse struct {
+	connected bool
+}
+
+func (f *fakeReverse) Connected() bool {
+	return f.connected
+}
+
+type fakeReverseState struct {
+	fakeReverse
+}
+
+func (f *fakeReverseState) State() map[string]interface{} {
+	return map[string]interface{}{"enabled": true, "state": "connected", "broker_url": "127.0.0.1:43191/check/foo"}
+}
+
+func TestHealthz(t *testing.T) {
+	t.Log("Testing healthz")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	viper.Set(config.KeyServerAuthToken, "foo")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Logf("GET /healthz -> %d (no auth required)", http.StatusOK)
+	{
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestReadyz(t *testing.T) {
+	t.Log("Testing readyz")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	rs := &fakeReverse{}
+	s.SetReverseStatus(rs)
+
+	get := func() (int, readyStatus) {
+		req := httptest.NewRequest("GET", "/readyz", nil)
+		w := httptest.NewRecorder()
+		s.readyz(w, req)
+		resp := w.Result()
+		var status readyStatus
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		return resp.StatusCode, status
+	}
+
+	t.Logf("GET /readyz -> %d (no run yet)", http.StatusServiceUnavailable)
+	{
+		lastMeticsmu.Lock()
+		lastMetrics.ts = time.Time{}
+		lastMeticsmu.Unlock()
+
+		code, status := get()
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("expected %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if status.Ready {
+			t.Fatal("expected not ready")
+		}
+	}
+
+	t.Logf("GET /readyz -> %d (run complete, reverse disabled)", http.StatusOK)
+	{
+		lastMeticsmu.Lock()
+		lastMetrics.ts = time.Now()
+		lastMeticsmu.Unlock()
+
+		code, status := get()
+		if code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, code)
+		}
+		if !status.Ready || status.LastRun == "" {
+			t.Fatalf("expected ready w/last run, got (%#v)", status)
+		}
+	}
+
+	t.Logf("GET /readyz -> %d (reverse enabled, not connected)", http.StatusServiceUnavailable)
+	{
+		viper.Set(config.KeyReverse, true)
+		code, status := get()
+		if code != http.StatusServiceUnavailable {
+			t.Fatalf("expected %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if !status.Reverse.Enabled || status.Reverse.Connected {
+			t.Fatalf("expected reverse enabled, not connected, got (%#v)", status)
+		}
+	}
+
+	t.Logf("GET /readyz -> %d (reverse connected)", http.StatusOK)
+	{
+		rs.connected = true
+		code, _ := get()
+		if code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, code)
+		}
+	}
+
+	lastMeticsmu.Lock()
+	lastMetrics.ts = time.Time{}
+	lastMetrics.metrics = nil
+	lastMeticsmu.Unlock()
+	viper.Reset()
+}
+
+func TestReverseStatus(t *testing.T) {
+	t.Log("Testing reverseStatus")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	get := func() map[string]interface{} {
+		req := httptest.NewRequest("GET", "/reverse", nil)
+		w := httptest.NewRecorder()
+		s.router(w, req)
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var state map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		return state
+	}
+
+	t.Log("GET /reverse (no reverse connection)")
+	{
+		state := get()
+		if state["enabled"] != false || state["state"] != "disconnected" {
+			t.Fatalf("expected disabled/disconnected, got (%#v)", state)
+		}
+	}
+
+	t.Log("GET /reverse (no state, readiness only)")
+	{
+		s.SetReverseStatus(&fakeReverse{})
+		state := get()
+		if state["state"] != "disconnected" {
+			t.Fatalf("expected disconnected, got (%#v)", state)
+		}
+	}
+
+	t.Log("GET /reverse (state)")
+	{
+		s.SetReverseStatus(&fakeReverseState{})
+		state := get()
+		if state["state"] != "connected" || state["broker_url"] != "127.0.0.1:43191/check/foo" {
+			t.Fatalf("expected connected w/broker_url, got (%#v)", state)
+		}
+	}
+
+	viper.Reset()
+}
@@ -10,13 +10,17 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/builtins"
 	"github.com/circonus-labs/circonus-agent/internal/check"
 	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-agent/internal/inherit"
 	"github.com/circonus-labs/circonus-agent/internal/plugins"
 	"github.com/circonus-labs/circonus-agent/internal/statsd"
 	"github.com/pkg/errors"
@@ -26,12 +30,58 @@ import (
 
 // New creates a new instance of the listening servers
 func New(c *check.Check, b *builtins.Builtins, p *plugins.Plugins, ss *statsd.Server) (*Server, error) {
+	return newServer(c, b, p, ss, true)
+}
+
+// Validate checks the server options (addresses, timeouts, tls and socket
+// settings) without creating any listeners or socket files
+func Validate() error {
+	_, err := newServer(nil, nil, nil, nil, false)
+	return err
+}
+
+// newServer configures the servers, socket listeners are only created if
+// listen is set, otherwise the socket directories are verified
+func newServer(c *check.Check, b *builtins.Builtins, p *plugins.Plugins, ss *statsd.Server, listen bool) (*Server, error) {
 	s := Server{
-		logger:    log.With().Str("pkg", "server").Logger(),
-		builtins:  b,
-		plugins:   p,
-		statsdSvr: ss,
-		check:     c,
+		logger:       log.With().Str("pkg", "server").Logger(),
+		builtins:     b,
+		plugins:      p,
+		statsdSvr:    ss,
+		check:        c,
+		authToken:    viper.GetString(config.KeyServerAuthToken),
+		authUser:     viper.GetString(config.KeyServerAuthUser),
+		authPass:     viper.GetString(config.KeyServerAuthPassword),
+		corsOrigins:  viper.GetStringSlice(config.KeyServerCORSOrigins),
+		disableWrite: viper.GetBool(config.KeyServerDisableWrite),
+	}
+
+	// graceful shutdown timeout
+	{
+		timeout := viper.GetString(config.KeyServerShutdownTimeout)
+		if timeout == "" {
+			timeout = defaults.ServerShutdownTimeout
+		}
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			s.logger.Error().Err(err).Str("timeout", timeout).Msg("parsing shutdown timeout")
+			return nil, errors.Wrap(err, "parsing server shutdown timeout")
+		}
+		s.shutdownTimeout = d
+	}
+
+	// direct submission to the check (instead of reverse)
+	if viper.GetBool(config.KeyDirect) {
+		interval := viper.GetString(config.KeyDirectInterval)
+		if interval == "" {
+			interval = defaults.DirectInterval
+		}
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			s.logger.Error().Err(err).Str("interval", interval).Msg("parsing direct interval")
+			return nil, errors.Wrap(err, "parsing direct interval")
+		}
+		s.directInterval = d
 	}
 
 	// HTTP listener (1-n)
@@ -69,24 +119,36 @@ func New(c *check.Check, b *builtins.Builtins, p *plugins.Plugins, ss *statsd.Se
 		}
 
 		certFile := viper.GetString(config.KeySSLCertFile)
+		keyFile := viper.GetString(config.KeySSLKeyFile)
+		if certFile == "" || keyFile == "" {
+			s.logger.Error().Str("cert_file", certFile).Str("key_file", keyFile).Msg("SSL server")
+			return nil, errors.New("SSL server requires both cert file and key file")
+		}
+
 		if _, err := os.Stat(certFile); os.IsNotExist(err) {
 			s.logger.Error().Err(err).Str("cert_file", certFile).Msg("SSL server")
 			return nil, errors.Wrapf(err, "SSL server cert file")
 		}
 
-		keyFile := viper.GetString(config.KeySSLKeyFile)
 		if _, err := os.Stat(keyFile); os.IsNotExist(err) {
 			s.logger.Error().Err(err).Str("key_file", keyFile).Msg("SSL server")
 			return nil, errors.Wrapf(err, "SSL server key file")
 		}
 
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			s.logger.Error().Err(err).Msg("SSL server")
+			return nil, errors.Wrap(err, "SSL server tls config")
+		}
+
 		svr := sslServer{
 			address:  ta,
 			certFile: certFile,
 			keyFile:  keyFile,
 			server: &http.Server{
-				Addr:    ta.String(),
-				Handler: http.HandlerFunc(s.router),
+				Addr:      ta.String(),
+				Handler:   http.HandlerFunc(s.router),
+				TLSConfig: tlsConfig,
 				// Handler: httpgzip.NewHandler(http.HandlerFunc(s.router), []string{"application/json"}),
 			},
 		}
@@ -105,12 +167,26 @@ func New(c *check.Check, b *builtins.Builtins, p *plugins.Plugins, ss *statsd.Se
 				return nil, errors.Wrap(err, "Socket server")
 			}
 
-			if _, serr := os.Stat(ua.String()); serr == nil || !os.IsNotExist(serr) {
-				s.logger.Error().Int("id", idx).Str("socket_file", ua.String()).Msg("already exists")
-				return nil, errors.Errorf("Socket server file (%s) exists", ua.String())
+			if !listen {
+				// the socket file may exist (agent running), only the directory is verified
+				dir := filepath.Dir(ua.String())
+				if fi, serr := os.Stat(dir); serr != nil {
+					return nil, errors.Wrapf(serr, "Socket server directory (%s)", dir)
+				} else if !fi.IsDir() {
+					return nil, errors.Errorf("Socket server directory (%s) not a directory", dir)
+				}
+				continue
+			}
+
+			// the socket file of an inherited listener (graceful restart) exists
+			if !inherit.HasUnix(ua) {
+				if _, serr := os.Stat(ua.String()); serr == nil || !os.IsNotExist(serr) {
+					s.logger.Error().Int("id", idx).Str("socket_file", ua.String()).Msg("already exists")
+					return nil, errors.Errorf("Socket server file (%s) exists", ua.String())
+				}
 			}
 
-			ul, err := net.ListenUnix(ua.Network(), ua)
+			ul, err := inherit.ListenUnix(ua)
 			if err != nil {
 				s.logger.Error().Err(err).Int("id", idx).Str("addr", ua.String()).Msg("creating socket")
 				return nil, errors.Wrap(err, "creating socket")
@@ -124,6 +200,15 @@ func New(c *check.Check, b *builtins.Builtins, p *plugins.Plugins, ss *statsd.Se
 		}
 	}
 
+	// pprof listener (optional, debugging)
+	{
+		svr, err := s.newPprofServer()
+		if err != nil {
+			return nil, err
+		}
+		s.svrPprof = svr
+	}
+
 	// validation moved to New so, there will always be at least ONE http server
 	// if len(s.svrHTTP) == 0 && s.svrHTTPS == nil && len(s.svrSockets) == 0 {
 	// 	return nil, errors.New("No servers defined")
@@ -132,6 +217,19 @@ func New(c *check.Check, b *builtins.Builtins, p *plugins.Plugins, ss *statsd.Se
 	return &s, nil
 }
 
+// SetReverseStatus sets the reverse connection used to determine readiness,
+// metrics it provides (if any) are included when all metrics are collected
+// and its state (if provided) is reported by /reverse
+func (s *Server) SetReverseStatus(rs ReverseStatus) {
+	s.reverse = rs
+	if rm, ok := rs.(ReverseMetrics); ok {
+		s.reverseMetrics = rm
+	}
+	if st, ok := rs.(ReverseState); ok {
+		s.reverseState = st
+	}
+}
+
 // GetReverseAgentAddress returns the address reverse should use to talk to the agent.
 // Initially, this is the first server address.
 func (s *Server) GetReverseAgentAddress() (string, error) {
@@ -141,6 +239,48 @@ func (s *Server) GetReverseAgentAddress() (string, error) {
 	return s.svrHTTP[0].address.String(), nil
 }
 
+// ListenAddresses returns the addresses of the HTTP, SSL and socket listeners
+func (s *Server) ListenAddresses() []string {
+	addrs := make([]string, 0, len(s.svrHTTP)+len(s.svrSockets)+1)
+	for _, svr := range s.svrHTTP {
+		addrs = append(addrs, svr.address.String())
+	}
+	if s.svrHTTPS != nil {
+		addrs = append(addrs, "ssl:"+s.svrHTTPS.address.String())
+	}
+	for _, svr := range s.svrSockets {
+		addrs = append(addrs, "unix:"+svr.address.String())
+	}
+	return addrs
+}
+
+// Listen creates the listeners for the HTTP and SSL servers (using the
+// listeners inherited from a graceful restart, if any). Start creates any
+// listeners not already created, Listen is used when the listeners must
+// exist before the servers are started.
+func (s *Server) Listen() error {
+	for _, svr := range s.svrHTTP {
+		if svr.address == nil || svr.listener != nil {
+			continue
+		}
+		l, err := inherit.ListenTCP(svr.address)
+		if err != nil {
+			return errors.Wrap(err, "HTTP server")
+		}
+		svr.listener = l
+	}
+
+	if s.svrHTTPS != nil && s.svrHTTPS.listener == nil {
+		l, err := inherit.ListenTCP(s.svrHTTPS.address)
+		if err != nil {
+			return errors.Wrap(err, "SSL server")
+		}
+		s.svrHTTPS.listener = l
+	}
+
+	return nil
+}
+
 // Start main listening server(s)
 func (s *Server) Start() error {
 	if len(s.svrHTTP) == 0 && s.svrHTTPS == nil && len(s.svrSockets) > 0 {
@@ -148,6 +288,8 @@ func (s *Server) Start() error {
 	}
 
 	s.t.Go(s.startHTTPS)
+	s.t.Go(s.startPprof)
+	s.t.Go(s.startDirect)
 
 	for _, svrHTTP := range s.svrHTTP {
 		s.t.Go(func() error {
@@ -175,6 +317,9 @@ func (s *Server) Start() error {
 		if s.svrHTTPS != nil && s.svrHTTPS.server != nil {
 			s.svrHTTPS.server.Close()
 		}
+		if s.svrPprof != nil && s.svrPprof.server != nil {
+			s.svrPprof.server.Close()
+		}
 		for _, svr := range s.svrHTTP {
 			svr.server.Close()
 		}
@@ -191,38 +336,82 @@ func (s *Server) Start() error {
 	return s.t.Wait()
 }
 
-// Stop the servers in an orderly, graceful fashion
-func (s *Server) Stop() {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Stop the servers in an orderly, graceful fashion. In-flight requests are
+// given up to the shutdown timeout to complete, then servers are forcibly closed.
+// An error is returned if any server could not be closed.
+func (s *Server) Stop() error {
+	timeout := s.shutdownTimeout
+	if timeout <= 0 {
+		timeout, _ = time.ParseDuration(defaults.ServerShutdownTimeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	for _, svrHTTP := range s.svrHTTP {
-		s.logger.Info().Msg("Stopping HTTP server")
-		err := svrHTTP.server.Shutdown(ctx)
-		if err != nil {
-			s.logger.Warn().Err(err).Msg("Closing HTTP server")
+	var (
+		wg     sync.WaitGroup
+		errsmu sync.Mutex
+		errs   []string
+	)
+	shutdown := func(name string, svr *http.Server) {
+		defer wg.Done()
+		if err := s.shutdownServer(ctx, name, svr); err != nil {
+			errsmu.Lock()
+			errs = append(errs, err.Error())
+			errsmu.Unlock()
 		}
 	}
 
+	for _, svrHTTP := range s.svrHTTP {
+		wg.Add(1)
+		go shutdown("HTTP", svrHTTP.server)
+	}
+
 	if s.svrHTTPS != nil {
-		s.logger.Info().Msg("Stopping HTTPS server")
-		err := s.svrHTTPS.server.Shutdown(ctx)
-		if err != nil {
-			s.logger.Warn().Err(err).Msg("Closing HTTPS server")
-		}
+		wg.Add(1)
+		go shutdown("HTTPS", s.svrHTTPS.server)
 	}
 
 	for _, svrSocket := range s.svrSockets {
-		s.logger.Info().Str("server", svrSocket.address.Name).Msg("Stopping Socket server")
-		err := svrSocket.server.Shutdown(ctx)
-		if err != nil {
-			s.logger.Warn().Err(err).Str("server", svrSocket.address.Name).Msg("Closing Socket server")
-		}
+		wg.Add(1)
+		go shutdown("Socket", svrSocket.server)
 	}
 
+	if s.svrPprof != nil {
+		wg.Add(1)
+		go shutdown("pprof", s.svrPprof.server)
+	}
+
+	wg.Wait()
+
 	if s.t.Alive() {
 		s.t.Kill(nil)
 	}
+
+	if len(errs) > 0 {
+		return errors.Errorf("stopping servers: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// shutdownServer gracefully shuts down a server, forcing it closed if the
+// context expires before in-flight requests complete
+func (s *Server) shutdownServer(ctx context.Context, name string, svr *http.Server) error {
+	if svr == nil {
+		return nil
+	}
+
+	s.logger.Info().Str("server", name).Str("addr", svr.Addr).Msg("Stopping server")
+	err := svr.Shutdown(ctx)
+	if err == nil {
+		return nil
+	}
+
+	s.logger.Warn().Err(err).Str("server", name).Str("addr", svr.Addr).Msg("Graceful shutdown incomplete, forcing close")
+	if cerr := svr.Close(); cerr != nil {
+		s.logger.Warn().Err(cerr).Str("server", name).Str("addr", svr.Addr).Msg("Closing server")
+		return errors.Wrapf(cerr, "closing %s server (%s)", name, svr.Addr)
+	}
+	return nil
 }
 
 func (s *Server) startHTTP(svr *httpServer) error {
@@ -236,7 +425,15 @@ func (s *Server) startHTTP(svr *httpServer) error {
 	}
 
 	s.logger.Info().Str("listen", svr.address.String()).Msg("Starting")
-	if err := svr.server.ListenAndServe(); err != nil {
+	if svr.listener == nil {
+		l, err := inherit.ListenTCP(svr.address)
+		if err != nil {
+			s.logger.Fatal().Err(err).Msg("HTTP Server, stopping agent")
+			return errors.Wrap(err, "HTTP server")
+		}
+		svr.listener = l
+	}
+	if err := svr.server.Serve(svr.listener); err != nil {
 		if err != http.ErrServerClosed {
 			s.logger.Fatal().Err(err).Msg("HTTP Server, stopping agent")
 			return errors.Wrap(err, "HTTP server")
@@ -252,7 +449,15 @@ func (s *Server) startHTTPS() error {
 		return nil
 	}
 	s.logger.Info().Str("listen", s.svrHTTPS.server.Addr).Msg("SSL starting")
-	if err := s.svrHTTPS.server.ListenAndServeTLS(s.svrHTTPS.certFile, s.svrHTTPS.keyFile); err != nil {
+	if s.svrHTTPS.listener == nil {
+		l, err := inherit.ListenTCP(s.svrHTTPS.address)
+		if err != nil {
+			s.logger.Fatal().Err(err).Msg("SSL Server, stopping agent")
+			return errors.Wrap(err, "SSL server")
+		}
+		s.svrHTTPS.listener = l
+	}
+	if err := s.svrHTTPS.server.ServeTLS(s.svrHTTPS.listener, s.svrHTTPS.certFile, s.svrHTTPS.keyFile); err != nil {
 		if err != http.ErrServerClosed {
 			s.logger.Fatal().Err(err).Msg("SSL Server, stopping agent")
 			return errors.Wrap(err, "SSL server")
@@ -6,7 +6,11 @@
 package server
 
 import (
+	"context"
 	"errors"
+	"net"
+	"net/http"
+	"os"
 	"path"
 	"regexp"
 	"runtime"
@@ -203,6 +207,32 @@ func TestNew(t *testing.T) {
 		}
 	}
 
+	t.Log("Testing New w/shutdown timeout")
+	{
+		t.Log("\tinvalid")
+		{
+			viper.Reset()
+			viper.Set(config.KeyServerShutdownTimeout, "abc")
+			_, err := New(nil, nil, nil, nil)
+			if err == nil {
+				t.Fatal("expecting error")
+			}
+		}
+
+		t.Log("\tvalid")
+		{
+			viper.Reset()
+			viper.Set(config.KeyServerShutdownTimeout, "5s")
+			s, err := New(nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+			if s.shutdownTimeout != 5*time.Second {
+				t.Fatalf("expected 5s, got (%s)", s.shutdownTimeout)
+			}
+		}
+	}
+
 	t.Log("Tetsting New w/HTTPS")
 	{
 		t.Log("\taddress, no cert/key")
@@ -280,6 +310,74 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("Testing Validate")
+
+	t.Log("\tinvalid listen address")
+	{
+		viper.Reset()
+		viper.Set(config.KeyListen, []string{"127.0.0.a"})
+		if err := Validate(); err == nil {
+			t.Fatal("expected error")
+		}
+		viper.Reset()
+	}
+
+	t.Log("\tlisten address in use")
+	{
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer l.Close()
+		viper.Reset()
+		viper.Set(config.KeyListen, []string{l.Addr().String()})
+		if err := Validate(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		viper.Reset()
+	}
+
+	if runtime.GOOS != "windows" {
+		t.Log("\tsocket directory missing")
+		{
+			viper.Reset()
+			viper.Set(config.KeyListenSocket, []string{path.Join("nodir", "test.sock")})
+			if err := Validate(); err == nil {
+				t.Fatal("expected error")
+			}
+			viper.Reset()
+		}
+
+		t.Log("\tsocket file exists (agent running)")
+		{
+			viper.Reset()
+			viper.Set(config.KeyListenSocket, []string{path.Join("testdata", "exists.sock")})
+			if err := Validate(); err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			viper.Reset()
+		}
+
+		t.Log("\tsocket not created")
+		{
+			viper.Reset()
+			sock := path.Join("testdata", "validate.sock")
+			viper.Set(config.KeyListenSocket, []string{sock})
+			if err := Validate(); err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			if _, err := os.Stat(sock); !os.IsNotExist(err) {
+				os.Remove(sock)
+				t.Fatalf("expected socket file not to exist, got (%v)", err)
+			}
+			viper.Reset()
+		}
+	}
+}
+
 func TestStartHTTP(t *testing.T) {
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 
@@ -501,7 +599,9 @@ func TestStop(t *testing.T) {
 		}
 
 		time.AfterFunc(2*time.Second, func() {
-			s.Stop()
+			if err := s.Stop(); err != nil {
+				t.Errorf("expected no error stopping, got (%s)", err)
+			}
 			done <- 1
 		})
 
@@ -538,4 +638,40 @@ func TestStop(t *testing.T) {
 			<-done
 		})
 	}
+
+	t.Run("in-flight request exceeds shutdown timeout", func(t *testing.T) {
+		viper.Reset()
+		s, err := New(nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		svr := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		})}
+		go svr.Serve(l)
+
+		go http.Get("http://" + l.Addr().String() + "/")
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if err := s.shutdownServer(ctx, "test", svr); err != nil {
+			t.Fatalf("expected no error (forced close succeeds), got (%s)", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("expected forced close shortly after timeout, took %s", elapsed)
+		}
+	})
 }
@@ -0,0 +1,78 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// newPprofServer returns the pprof server, if enabled. The server
+// is separate from the main listeners so that profiling data is
+// never exposed on the (potentially public) agent address.
+func (s *Server) newPprofServer() (*httpServer, error) {
+	if !viper.GetBool(config.KeyDebugPprof) {
+		return nil, nil
+	}
+
+	addr := viper.GetString(config.KeyDebugPprofListen)
+	if addr == "" {
+		addr = defaults.DebugPprofListen
+	}
+
+	ta, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		s.logger.Error().Err(err).Str("addr", addr).Msg("resolving pprof address")
+		return nil, errors.Wrap(err, "pprof server")
+	}
+
+	if ta.IP == nil || !ta.IP.IsLoopback() {
+		s.logger.Warn().
+			Str("addr", ta.String()).
+			Msg("!!! pprof server is NOT bound to a loopback address, profiling data (incl. command line) will be exposed on the network !!!")
+	}
+
+	return &httpServer{
+		address: ta,
+		server: &http.Server{
+			Addr:    ta.String(),
+			Handler: pprofHandler(),
+		},
+	}, nil
+}
+
+// pprofHandler returns a handler for the standard /debug/pprof/ endpoints
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+func (s *Server) startPprof() error {
+	if s.svrPprof == nil {
+		s.logger.Debug().Msg("pprof not enabled, skipping server")
+		return nil
+	}
+
+	s.logger.Info().Str("listen", s.svrPprof.address.String()).Msg("pprof starting")
+	if err := s.svrPprof.server.ListenAndServe(); err != nil {
+		if err != http.ErrServerClosed {
+			// debugging aid, not fatal to the agent
+			s.logger.Error().Err(err).Msg("pprof server")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestNewPprofServer(t *testing.T) {
+	t.Log("Testing newPprofServer")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	t.Log("disabled (default)")
+	{
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.svrPprof != nil {
+			t.Fatal("expected no pprof server")
+		}
+	}
+
+	t.Log("enabled, default address")
+	{
+		viper.Set(config.KeyDebugPprof, true)
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.svrPprof == nil {
+			t.Fatal("expected pprof server")
+		}
+		if !s.svrPprof.address.IP.IsLoopback() {
+			t.Fatalf("expected loopback address, got %s", s.svrPprof.address)
+		}
+	}
+
+	t.Log("enabled, invalid address")
+	{
+		viper.Set(config.KeyDebugPprof, true)
+		viper.Set(config.KeyDebugPprofListen, "127.0.0.1:abc")
+		_, err := New(c, nil, nil, nil)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("enabled, non-loopback address")
+	{
+		viper.Set(config.KeyDebugPprof, true)
+		viper.Set(config.KeyDebugPprofListen, ":6060")
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.svrPprof == nil {
+			t.Fatal("expected pprof server")
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestPprofHandler(t *testing.T) {
+	t.Log("Testing pprofHandler")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	h := pprofHandler()
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/goroutine"} {
+		t.Logf("GET %s -> %d", path, http.StatusOK)
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+
+	t.Logf("GET /run -> %d", http.StatusNotFound)
+	{
+		req := httptest.NewRequest("GET", "/run", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected %d, got %d", http.StatusNotFound, w.Code)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/tags"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+)
+
+// promSample is a single exposition line (name{labels} value)
+type promSample struct {
+	suffix string
+	labels string
+	value  string
+}
+
+// promFamily groups all samples sharing a (sanitized) metric name
+type promFamily struct {
+	help    string
+	ptype   string
+	samples []promSample
+}
+
+var (
+	promNameCleanerRx  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	promLabelCleanerRx = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	promStreamTagRx    = regexp.MustCompile(`^(.+)\|ST\[(.+)\]$`)
+	promHistBucketRx   = regexp.MustCompile(`^H\[([^\]]+)\]=([0-9]+)$`)
+	promLabelEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// promCounterSuffix identifies counters, by prometheus naming convention
+// integer metrics named *_total are cumulative counts, others are gauges
+const promCounterSuffix = "_total"
+
+// promExporter renders current builtin and plugin metrics in the Prometheus
+// text exposition format (https://prometheus.io/docs/instrumenting/exposition_formats/).
+// The builtins and plugins are run and flushed, as for a /run request, so a
+// scrape consumes their current metrics. Receivers (write, prom, statsd) are
+// not included, they are left to be drained by the regular /run requests.
+func (s *Server) promExporter(w http.ResponseWriter, r *http.Request) {
+	metrics := cgm.Metrics{}
+
+	if s.builtins != nil {
+		s.builtins.Run("")
+		for metricName, metric := range *s.builtins.Flush("") {
+			metrics[metricName] = metric
+		}
+	}
+
+	if s.plugins != nil {
+		// NOTE: errors are ignored from plugins.Run, they are already logged
+		s.plugins.Run("")
+		for metricName, metric := range *s.plugins.Flush("") {
+			metrics[metricName] = metric
+		}
+	}
+
+	data := s.metricsToPromExposition(metrics)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		s.logger.Error().Err(err).Msg("writing prometheus exposition")
+	}
+}
+
+// metricsToPromExposition converts metrics into prometheus exposition format,
+// metric families are emitted in sorted order so output is deterministic.
+func (s *Server) metricsToPromExposition(metrics cgm.Metrics) []byte {
+	l := s.logger.With().Str("op", "prom exposition").Logger()
+
+	families := map[string]*promFamily{}
+
+	for origName, metric := range metrics {
+		baseName, labels := promNameAndLabels(origName)
+		if baseName == "" {
+			l.Warn().Str("metric", origName).Msg("invalid metric name, skipping")
+			continue
+		}
+
+		var samples []promSample
+		ptype := "gauge"
+
+		switch metric.Type {
+		case "i", "I", "l", "L", "n":
+			if metric.Type == "n" && isHistogram(metric.Value) {
+				hs, err := promHistogramSamples(metric.Value, labels)
+				if err != nil {
+					l.Warn().Err(err).Str("metric", origName).Msg("converting histogram, skipping")
+					continue
+				}
+				ptype = "histogram"
+				samples = hs
+				break
+			}
+			v, err := promValue(metric.Value)
+			if err != nil {
+				l.Warn().Err(err).Str("metric", origName).Msg("converting value, skipping")
+				continue
+			}
+			if metric.Type != "n" && strings.HasSuffix(baseName, promCounterSuffix) {
+				ptype = "counter"
+			}
+			samples = []promSample{{labels: promLabelString(labels), value: v}}
+		default:
+			// text ('s') and auto-detect ('O') have no prometheus equivalent
+			l.Debug().Str("type", metric.Type).Str("metric", origName).Msg("unsupported metric type, skipping")
+			continue
+		}
+
+		fam, ok := families[baseName]
+		if !ok {
+			fam = &promFamily{
+				help:  fmt.Sprintf("circonus-agent metric %s", strings.Split(origName, "|ST[")[0]),
+				ptype: ptype,
+			}
+			families[baseName] = fam
+		}
+		if fam.ptype != ptype {
+			l.Warn().Str("metric", origName).Str("type", ptype).Str("family_type", fam.ptype).Msg("type mismatch within metric family, skipping")
+			continue
+		}
+		fam.samples = append(fam.samples, samples...)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fam := families[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n", name, promEscapeHelp(fam.help))
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, fam.ptype)
+		for _, sample := range fam.samples {
+			fmt.Fprintf(&buf, "%s%s%s %s\n", name, sample.suffix, sample.labels, sample.value)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// promNameAndLabels splits a circonus metric name (possibly containing
+// stream tags) into a sanitized prometheus metric name and labels
+func promNameAndLabels(name string) (string, map[string]string) {
+	labels := map[string]string{}
+
+	if m := promStreamTagRx.FindStringSubmatch(name); m != nil {
+		name = m[1]
+		for _, tag := range strings.Split(m[2], tags.Separator) {
+			kv := strings.SplitN(tag, tags.Delimiter, 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key := promLabelCleanerRx.ReplaceAllString(kv[0], "_")
+			if key == "" {
+				continue
+			}
+			if key[0] >= '0' && key[0] <= '9' {
+				key = "_" + key
+			}
+			labels[key] = kv[1]
+		}
+	}
+
+	return promSanitizeName(name), labels
+}
+
+// promSanitizeName replaces characters not allowed in prometheus metric names
+func promSanitizeName(name string) string {
+	if name == "" {
+		return ""
+	}
+	name = strings.Replace(name, config.MetricNameSeparator, "_", -1)
+	name = promNameCleanerRx.ReplaceAllString(name, "_")
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// promLabelString formats labels as {k="v",...} in sorted key order
+func promLabelString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+`="`+promEscapeLabel(labels[k])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// promEscapeLabel escapes backslash, double quote and newline in a label value
+func promEscapeLabel(value string) string {
+	return promLabelEscaper.Replace(value)
+}
+
+// promEscapeHelp escapes backslash and newline in HELP text
+func promEscapeHelp(help string) string {
+	help = strings.Replace(help, `\`, `\\`, -1)
+	return strings.Replace(help, "\n", `\n`, -1)
+}
+
+// promValue converts a metric value to its textual prometheus representation
+func promValue(val interface{}) (string, error) {
+	sv := fmt.Sprintf("%v", val)
+	if _, err := strconv.ParseFloat(sv, 64); err != nil {
+		return "", err
+	}
+	return sv, nil
+}
+
+// isHistogram determines if a metric value is a circonus histogram (list of H[bucket]=count)
+func isHistogram(val interface{}) bool {
+	switch v := val.(type) {
+	case []string:
+		return true
+	case []interface{}:
+		return true
+	case string:
+		return strings.HasPrefix(v, "H[")
+	}
+	return false
+}
+
+// promHistogramSamples converts circonus histogram buckets into cumulative
+// prometheus buckets (_bucket, _sum, _count). Circonus bucket values are the
+// lower bound of each bin, prometheus buckets (le) are the upper bound of the
+// bins, followed by +Inf. The sum is estimated from the bin lower bounds.
+func promHistogramSamples(val interface{}, labels map[string]string) ([]promSample, error) {
+	var buckets []string
+	switch v := val.(type) {
+	case []string:
+		buckets = v
+	case []interface{}:
+		for _, b := range v {
+			buckets = append(buckets, fmt.Sprintf("%v", b))
+		}
+	case string:
+		buckets = []string{v}
+	default:
+		return nil, fmt.Errorf("unsupported histogram value type (%T)", val)
+	}
+
+	type bin struct {
+		value float64
+		count uint64
+	}
+	bins := make([]bin, 0, len(buckets))
+	for _, b := range buckets {
+		m := promHistBucketRx.FindStringSubmatch(b)
+		if m == nil {
+			return nil, fmt.Errorf("invalid histogram bucket (%s)", b)
+		}
+		v, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		c, err := strconv.ParseUint(m[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		bins = append(bins, bin{value: v, count: c})
+	}
+	sort.Slice(bins, func(i, j int) bool { return bins[i].value < bins[j].value })
+
+	samples := make([]promSample, 0, len(bins)+3)
+	var cumulative uint64
+	var sum float64
+	for _, b := range bins {
+		cumulative += b.count
+		sum += b.value * float64(b.count)
+		bl := map[string]string{"le": strconv.FormatFloat(promBinUpper(b.value), 'g', -1, 64)}
+		for k, v := range labels {
+			bl[k] = v
+		}
+		samples = append(samples, promSample{suffix: "_bucket", labels: promLabelString(bl), value: strconv.FormatUint(cumulative, 10)})
+	}
+
+	inf := map[string]string{"le": "+Inf"}
+	for k, v := range labels {
+		inf[k] = v
+	}
+	ls := promLabelString(labels)
+	samples = append(samples,
+		promSample{suffix: "_bucket", labels: promLabelString(inf), value: strconv.FormatUint(cumulative, 10)},
+		promSample{suffix: "_sum", labels: ls, value: strconv.FormatFloat(sum, 'g', -1, 64)},
+		promSample{suffix: "_count", labels: ls, value: strconv.FormatUint(cumulative, 10)},
+	)
+
+	return samples, nil
+}
+
+// promBinUpper returns the upper bound of a circonus log linear histogram bin
+// from its value (e.g. 1.2e+01, the bin [12,13) has the upper bound 13). Bins
+// have two significant digits, the magnitude of a negative bin's value is its
+// lower bound, so the value is the upper bound.
+func promBinUpper(v float64) float64 {
+	if v <= 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	// the bin is the value with two significant digits, d.de+exp
+	bin := strconv.FormatFloat(v, 'e', 1, 64)
+	exp, err := strconv.Atoi(bin[strings.Index(bin, "e")+1:])
+	if err != nil {
+		return v
+	}
+	lower, err := strconv.ParseFloat(bin, 64)
+	if err != nil {
+		return v
+	}
+	// round to the bin precision, avoids float artifacts (e.g. 1.3000000000000003)
+	upper, err := strconv.ParseFloat(strconv.FormatFloat(lower+math.Pow10(exp-1), 'e', 1, 64), 64)
+	if err != nil {
+		return v
+	}
+	return upper
+}
@@ -0,0 +1,215 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/check"
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestPromExporter(t *testing.T) {
+	t.Log("Testing promExporter")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Logf("GET /prometheus -> %d (no sources)", http.StatusOK)
+	{
+		req := httptest.NewRequest("GET", "/prometheus", nil)
+		w := httptest.NewRecorder()
+
+		s.promExporter(w, req)
+
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		expect := "text/plain; version=0.0.4"
+		if ct := resp.Header.Get("Content-Type"); ct != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, ct)
+		}
+	}
+}
+
+func TestMetricsToPromExposition(t *testing.T) {
+	t.Log("Testing metricsToPromExposition")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	t.Log("numeric -> gauge")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"cpu`idle": cgm.Metric{Type: "L", Value: uint64(10)},
+		}))
+		for _, expect := range []string{
+			"# HELP cpu_idle circonus-agent metric cpu`idle\n",
+			"# TYPE cpu_idle gauge\n",
+			"cpu_idle 10\n",
+		} {
+			if !strings.Contains(data, expect) {
+				t.Fatalf("expected (%s) got (%s)", expect, data)
+			}
+		}
+	}
+
+	t.Log("stream tags -> labels")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"disk`io|ST[dev:sda,1x:y]": cgm.Metric{Type: "n", Value: 1.5},
+		}))
+		expect := `disk_io{_1x="y",dev="sda"} 1.5`
+		if !strings.Contains(data, expect) {
+			t.Fatalf("expected (%s) got (%s)", expect, data)
+		}
+	}
+
+	t.Log("illegal characters, leading digit")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"9foo.bar-baz": cgm.Metric{Type: "i", Value: 1},
+		}))
+		expect := "_9foo_bar_baz 1\n"
+		if !strings.Contains(data, expect) {
+			t.Fatalf("expected (%s) got (%s)", expect, data)
+		}
+	}
+
+	t.Log("histogram")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"lat": cgm.Metric{Type: "n", Value: []string{"H[2.0e+00]=3", "H[1.0e+00]=1"}},
+		}))
+		for _, expect := range []string{
+			"# TYPE lat histogram\n",
+			`lat_bucket{le="1.1"} 1` + "\n",
+			`lat_bucket{le="2.1"} 4` + "\n",
+			`lat_bucket{le="+Inf"} 4` + "\n",
+			"lat_sum 7\n",
+			"lat_count 4\n",
+		} {
+			if !strings.Contains(data, expect) {
+				t.Fatalf("expected (%s) got (%s)", expect, data)
+			}
+		}
+	}
+
+	t.Log("histogram bin upper bounds")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"lat": cgm.Metric{Type: "n", Value: []string{"H[1.2e+01]=1", "H[9.9e-01]=2", "H[0.0e+00]=1", "H[-1.2e+00]=1"}},
+		}))
+		for _, expect := range []string{
+			`lat_bucket{le="-1.2"} 1` + "\n",
+			`lat_bucket{le="0"} 2` + "\n",
+			`lat_bucket{le="1"} 4` + "\n",
+			`lat_bucket{le="13"} 5` + "\n",
+			`lat_bucket{le="+Inf"} 5` + "\n",
+		} {
+			if !strings.Contains(data, expect) {
+				t.Fatalf("expected (%s) got (%s)", expect, data)
+			}
+		}
+	}
+
+	t.Log("counter")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"requests_total": cgm.Metric{Type: "L", Value: uint64(10)},
+			"load_total":     cgm.Metric{Type: "n", Value: 1.5},
+		}))
+		for _, expect := range []string{
+			"# TYPE requests_total counter\n",
+			"requests_total 10\n",
+			"# TYPE load_total gauge\n",
+		} {
+			if !strings.Contains(data, expect) {
+				t.Fatalf("expected (%s) got (%s)", expect, data)
+			}
+		}
+	}
+
+	t.Log("label values escaped")
+	{
+		data := string(s.metricsToPromExposition(cgm.Metrics{
+			"app|ST[path:C\\dir \"x\"é]": cgm.Metric{Type: "i", Value: 1},
+		}))
+		expect := `app{path="C\\dir \"x\"é"} 1`
+		if !strings.Contains(data, expect) {
+			t.Fatalf("expected (%s) got (%s)", expect, data)
+		}
+	}
+
+	t.Log("text skipped")
+	{
+		data := s.metricsToPromExposition(cgm.Metrics{
+			"version": cgm.Metric{Type: "s", Value: "1.0"},
+		})
+		if len(data) != 0 {
+			t.Fatalf("expected empty, got (%s)", string(data))
+		}
+	}
+}
+
+func TestPromExporterRoute(t *testing.T) {
+	t.Log("Testing router w/prometheus exporter")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyListen, ":2609")
+	c, cerr := check.New(nil)
+	if cerr != nil {
+		t.Fatalf("expected no error, got (%s)", cerr)
+	}
+
+	s, err := New(c, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	for _, p := range []string{"/prometheus", "/metrics/"} {
+		t.Logf("GET %s -> %d", p, http.StatusOK)
+		req := httptest.NewRequest("GET", p, nil)
+		w := httptest.NewRecorder()
+
+		s.router(w, req)
+
+		resp := w.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d (%s)", http.StatusOK, resp.StatusCode, string(body))
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"io"
 	stdlog "log"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -75,6 +76,13 @@ func Parse(id string, data io.ReadCloser) error {
 		return errors.Wrapf(err, "parsing json for %s", id)
 	}
 
+	// strict, reject the request without applying any metrics if any are invalid
+	if viper.GetBool(config.KeyServerWriteStrict) {
+		if err := validate(id, tmp); err != nil {
+			return err
+		}
+	}
+
 	for name, metric := range tmp {
 		metricName := strings.Join([]string{id, name}, config.MetricNameSeparator)
 		if len(metric.Tags) > 0 {
@@ -127,6 +135,45 @@ func Parse(id string, data io.ReadCloser) error {
 	return nil
 }
 
+// validate verifies each metric has a supported type and a value which can be parsed for the type
+func validate(id string, jm tags.JSONMetrics) error {
+	invalid := []string{}
+	for name, metric := range jm {
+		metricName := strings.Join([]string{id, name}, config.MetricNameSeparator)
+		ok := false
+		switch metric.Type {
+		case "i":
+			ok = parseInt32(metricName, metric) != nil
+		case "I":
+			ok = parseUint32(metricName, metric) != nil
+		case "l":
+			ok = parseInt64(metricName, metric) != nil
+		case "L":
+			ok = parseUint64(metricName, metric) != nil
+		case "n":
+			v, isHist := parseFloat(metricName, metric)
+			if v != nil {
+				ok = true
+			} else if isHist {
+				samples := parseHistogram(metricName, metric)
+				ok = samples != nil && len(*samples) > 0
+			}
+		case "s":
+			ok = true
+		}
+		if !ok {
+			invalid = append(invalid, name)
+		}
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return errors.Errorf("id:%s - invalid metric type or value (%s)", id, strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
+
 func parseInt32(metricName string, metric tags.JSONMetric) *int32 {
 	switch t := metric.Value.(type) {
 	case float64:
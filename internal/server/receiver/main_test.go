@@ -14,8 +14,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/tags"
 	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
 )
 
 func TestFlush(t *testing.T) {
@@ -46,6 +48,52 @@ func TestFlush(t *testing.T) {
 	}
 }
 
+func TestParseStrict(t *testing.T) {
+	t.Log("Testing Parse (strict)")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	err := initCGM()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	viper.Set(config.KeyServerWriteStrict, true)
+	defer viper.Reset()
+
+	t.Log("\tinvalid type and value, nothing applied")
+	{
+		metrics.FlushMetrics()
+		data := []byte(`{"ok": {"_type": "i", "_value": 1}, "badtype": {"_type": "z", "_value": 1}, "badval": {"_type": "L", "_value": "abc"}}`)
+		r := ioutil.NopCloser(bytes.NewReader(data))
+		expectedErr := errors.New("id:tests - invalid metric type or value (badtype, badval)")
+		err := Parse("tests", r)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Fatalf("expected (%s) got (%s)", expectedErr, err)
+		}
+		m := metrics.FlushMetrics()
+		if _, ok := (*m)["tests`ok"]; ok {
+			t.Fatalf("expected no metrics, got %#v", *m)
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		data := []byte(`{"ok": {"_type": "i", "_value": 1}, "txt": {"_type": "s", "_value": "foo"}}`)
+		r := ioutil.NopCloser(bytes.NewReader(data))
+		if err := Parse("tests", r); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		m := metrics.FlushMetrics()
+		if _, ok := (*m)["tests`ok"]; !ok {
+			t.Fatalf("expected tests`ok, got %#v", *m)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	t.Log("Testing Parse")
 
@@ -20,6 +20,33 @@ func (s *Server) router(w http.ResponseWriter, r *http.Request) {
 		Str("url", r.URL.String()).
 		Msg("Request")
 
+	// cors headers (if enabled), preflight requests are answered here
+	if s.cors(w, r) {
+		return
+	}
+
+	// health endpoints are exempt from auth so orchestrator probes do not need credentials
+	if r.Method == "GET" {
+		if healthPathRx.MatchString(r.URL.Path) {
+			s.healthz(w, r)
+			return
+		} else if readyPathRx.MatchString(r.URL.Path) {
+			s.readyz(w, r)
+			return
+		}
+	}
+
+	if !s.authorized(r) {
+		appstats.IncrementInt("requests_unauthorized")
+		s.logger.Warn().
+			Str("method", r.Method).
+			Str("url", r.URL.String()).
+			Str("remote", r.RemoteAddr).
+			Msg("Unauthorized")
+		s.unauthorized(w)
+		return
+	}
+
 	switch r.Method {
 	case "GET":
 		if pluginPathRx.MatchString(r.URL.Path) { // run plugin(s)
@@ -28,10 +55,18 @@ func (s *Server) router(w http.ResponseWriter, r *http.Request) {
 			s.logger.Debug().Msg("run complete")
 		} else if inventoryPathRx.MatchString(r.URL.Path) { // plugin inventory
 			s.inventory(w, r)
+		} else if collectorPathRx.MatchString(r.URL.Path) { // builtin collector inventory
+			s.collectorInventory(w, r)
+		} else if versionPathRx.MatchString(r.URL.Path) { // build and config hash
+			s.version(w, r)
+		} else if reversePathRx.MatchString(r.URL.Path) { // reverse connection state
+			s.reverseStatus(w, r)
 		} else if statsPathRx.MatchString(r.URL.Path) { // app stats
 			expvar.Handler().ServeHTTP(w, r)
 		} else if promPathRx.MatchString(r.URL.Path) { // output prom format...
 			s.promOutput(w, r)
+		} else if promExportRx.MatchString(r.URL.Path) { // prometheus exposition format
+			s.promExporter(w, r)
 		} else {
 			appstats.IncrementInt("requests_bad")
 			s.logger.Warn().
@@ -43,8 +78,17 @@ func (s *Server) router(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		fallthrough
 	case "PUT":
-		if writePathRx.MatchString(r.URL.Path) {
+		if s.disableWrite && (writePathRx.MatchString(r.URL.Path) || promPathRx.MatchString(r.URL.Path)) {
+			appstats.IncrementInt("requests_bad")
+			s.logger.Warn().
+				Str("method", r.Method).
+				Str("url", r.URL.String()).
+				Msg("Write disabled")
+			http.Error(w, "Forbidden, write disabled", http.StatusForbidden)
+		} else if writePathRx.MatchString(r.URL.Path) {
 			s.write(w, r)
+		} else if statsdTestRx.MatchString(r.URL.Path) { // statsd parse dry-run, nothing is recorded
+			s.statsdTest(w, r)
 		} else if promPathRx.MatchString(r.URL.Path) {
 			s.promReceiver(w, r)
 		} else {
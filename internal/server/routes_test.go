@@ -235,4 +235,56 @@ func TestRouter(t *testing.T) {
 			t.Fatalf("expected %d, got %d", http.StatusNoContent, resp.StatusCode)
 		}
 	}
+
+	t.Log("Forbidden (PUT /write/foo, /prom) w/write disabled")
+	{
+		viper.Reset()
+		viper.Set(config.KeyListen, ":2609")
+		viper.Set(config.KeyServerDisableWrite, true)
+		c, cerr := check.New(nil)
+		if cerr != nil {
+			t.Fatalf("expected no error, got (%s)", cerr)
+		}
+
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		for _, path := range []string{"/write/foo", "/prom"} {
+			reqBody := bytes.NewReader([]byte(`{"test":{"_type":"i", "_value":1}}`))
+			req := httptest.NewRequest("PUT", path, reqBody)
+			w := httptest.NewRecorder()
+			s.router(w, req)
+			resp := w.Result()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Fatalf("%s expected %d, got %d", path, http.StatusForbidden, resp.StatusCode)
+			}
+		}
+		viper.Reset()
+	}
+
+	t.Log("invalid (PUT /write/foo) w/strict and invalid metric")
+	{
+		viper.Reset()
+		viper.Set(config.KeyListen, ":2609")
+		viper.Set(config.KeyServerWriteStrict, true)
+		c, cerr := check.New(nil)
+		if cerr != nil {
+			t.Fatalf("expected no error, got (%s)", cerr)
+		}
+
+		s, err := New(c, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		reqBody := bytes.NewReader([]byte(`{"test":{"_type":"i", "_value":1}, "bad":{"_type":"z", "_value":1}}`))
+		req := httptest.NewRequest("PUT", "/write/foo", reqBody)
+		w := httptest.NewRecorder()
+		s.router(w, req)
+		resp := w.Result()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected %d, got %d", http.StatusBadRequest, resp.StatusCode)
+		}
+		viper.Reset()
+	}
 }
@@ -0,0 +1,48 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// tlsConfig returns the tls configuration for the SSL server. HTTP/2 is
+// negotiated via ALPN. If a client CA file is configured, clients must
+// present a certificate signed by one of the CAs (mTLS).
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	caFile := viper.GetString(config.KeySSLClientCAFile)
+	if caFile == "" {
+		return cfg, nil
+	}
+
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading client CA file")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no valid certificates found in client CA file (%s)", caFile)
+	}
+
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	s.logger.Info().Str("client_ca_file", caFile).Msg("SSL server requiring client certificates")
+
+	return cfg, nil
+}
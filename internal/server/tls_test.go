@@ -0,0 +1,75 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package server
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestTLSConfig(t *testing.T) {
+	t.Log("Testing tlsConfig")
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	s := &Server{}
+
+	t.Log("\tno client ca")
+	{
+		viper.Reset()
+		cfg, err := s.tlsConfig()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if cfg.ClientAuth != tls.NoClientCert {
+			t.Fatalf("expected no client cert, got (%v)", cfg.ClientAuth)
+		}
+		if len(cfg.NextProtos) == 0 || cfg.NextProtos[0] != "h2" {
+			t.Fatalf("expected h2 first in next protos, got (%v)", cfg.NextProtos)
+		}
+	}
+
+	t.Log("\tmissing client ca")
+	{
+		viper.Reset()
+		viper.Set(config.KeySSLClientCAFile, "testdata/missing.crt")
+		_, err := s.tlsConfig()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tinvalid client ca")
+	{
+		viper.Reset()
+		viper.Set(config.KeySSLClientCAFile, "testdata/cert.crt")
+		_, err := s.tlsConfig()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("\tvalid client ca")
+	{
+		viper.Reset()
+		viper.Set(config.KeySSLClientCAFile, "testdata/ca.crt")
+		cfg, err := s.tlsConfig()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+			t.Fatalf("expected require and verify, got (%v)", cfg.ClientAuth)
+		}
+		if cfg.ClientCAs == nil {
+			t.Fatal("expected client CAs")
+		}
+	}
+
+	viper.Reset()
+}
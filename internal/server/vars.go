@@ -23,8 +23,9 @@ import (
 )
 
 type httpServer struct {
-	address *net.TCPAddr
-	server  *http.Server
+	address  *net.TCPAddr
+	listener *net.TCPListener
+	server   *http.Server
 }
 
 type socketServer struct {
@@ -37,21 +38,48 @@ type sslServer struct {
 	address  *net.TCPAddr
 	certFile string
 	keyFile  string
+	listener *net.TCPListener
 	server   *http.Server
 }
 
+// ReverseStatus reports the state of the reverse connection (used by /readyz)
+type ReverseStatus interface {
+	Connected() bool
+}
+
+// ReverseState provides the details of the reverse connection (used by /reverse)
+type ReverseState interface {
+	State() map[string]interface{}
+}
+
+// ReverseMetrics provides metrics measured by the reverse connection (e.g. broker latency)
+type ReverseMetrics interface {
+	Flush() *cgm.Metrics
+}
+
 // Server defines the listening servers
 type Server struct {
-	builtins   *builtins.Builtins
-	check      *check.Check
-	ctx        context.Context
-	logger     zerolog.Logger
-	plugins    *plugins.Plugins
-	svrHTTP    []*httpServer
-	svrHTTPS   *sslServer
-	svrSockets []*socketServer
-	statsdSvr  *statsd.Server
-	t          tomb.Tomb
+	authPass        string
+	authToken       string
+	authUser        string
+	builtins        *builtins.Builtins
+	check           *check.Check
+	corsOrigins     []string
+	ctx             context.Context
+	directInterval  time.Duration // direct submission interval, 0 if not enabled
+	disableWrite    bool
+	logger          zerolog.Logger
+	plugins         *plugins.Plugins
+	reverse         ReverseStatus
+	reverseMetrics  ReverseMetrics
+	reverseState    ReverseState
+	shutdownTimeout time.Duration
+	svrHTTP         []*httpServer
+	svrHTTPS        *sslServer
+	svrPprof        *httpServer
+	svrSockets      []*socketServer
+	statsdSvr       *statsd.Server
+	t               tomb.Tomb
 }
 
 type previousMetrics struct {
@@ -62,9 +90,22 @@ type previousMetrics struct {
 var (
 	pluginPathRx    = regexp.MustCompile("^/(run(/[a-zA-Z0-9_-]*)?)?$")
 	inventoryPathRx = regexp.MustCompile("^/inventory/?$")
+	collectorPathRx = regexp.MustCompile("^/inventory/collectors/?$")
+	versionPathRx   = regexp.MustCompile("^/version/?$")
 	writePathRx     = regexp.MustCompile("^/write/[a-zA-Z0-9_-]+$")
 	statsPathRx     = regexp.MustCompile("^/stats/?$")
 	promPathRx      = regexp.MustCompile("^/prom/?$")
+	promExportRx    = regexp.MustCompile("^/(prometheus|metrics)/?$")
+	healthPathRx    = regexp.MustCompile("^/healthz/?$")
+	readyPathRx     = regexp.MustCompile("^/readyz/?$")
+	reversePathRx   = regexp.MustCompile("^/reverse/?$")
+	statsdTestRx    = regexp.MustCompile("^/statsd/test/?$")
 	lastMetrics     = &previousMetrics{}
 	lastMeticsmu    sync.Mutex
 )
+
+// maxWriteSize is the maximum request body accepted by the metric receivers (/write, /prom)
+const maxWriteSize = 10 * 1024 * 1024
+
+// maxStatsdTestSize is the maximum request body accepted by /statsd/test
+const maxStatsdTestSize = 64 * 1024
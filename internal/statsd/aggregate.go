@@ -0,0 +1,133 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+)
+
+// aggregator batches counter increments and gauge updates in memory for
+// an aggregation window, reducing the number of updates applied to the
+// host and group metrics. Counters are summed, gauges keep the last value.
+type aggregator struct {
+	window     time.Duration
+	maxEntries int
+	counters   map[aggKey]uint64
+	gauges     map[aggKey]interface{}
+	sync.Mutex
+}
+
+// aggKey identifies an aggregated metric by destination (host|group) and name
+type aggKey struct {
+	dest string
+	name string
+}
+
+// newAggregator returns an aggregator for the window, nil if window is not positive
+func newAggregator(window time.Duration) *aggregator {
+	if window <= 0 {
+		return nil
+	}
+	return &aggregator{
+		window:     window,
+		maxEntries: aggregateMaxEntries,
+		counters:   make(map[aggKey]uint64),
+		gauges:     make(map[aggKey]interface{}),
+	}
+}
+
+// addCounter adds to a counter, returns true if the aggregator is full
+func (a *aggregator) addCounter(dest, name string, v uint64) bool {
+	a.Lock()
+	defer a.Unlock()
+	a.counters[aggKey{dest, name}] += v
+	return len(a.counters)+len(a.gauges) >= a.maxEntries
+}
+
+// setGauge sets a gauge, returns true if the aggregator is full
+func (a *aggregator) setGauge(dest, name string, v interface{}) bool {
+	a.Lock()
+	defer a.Unlock()
+	a.gauges[aggKey{dest, name}] = v
+	return len(a.counters)+len(a.gauges) >= a.maxEntries
+}
+
+// take returns the aggregated counters and gauges, resetting the aggregator
+func (a *aggregator) take() (map[aggKey]uint64, map[aggKey]interface{}) {
+	a.Lock()
+	defer a.Unlock()
+	counters, gauges := a.counters, a.gauges
+	a.counters = make(map[aggKey]uint64)
+	a.gauges = make(map[aggKey]interface{})
+	return counters, gauges
+}
+
+// counter increments a counter, through the group operators (group check)
+// or the aggregation window if enabled
+func (s *Server) counter(dest *cgm.CirconusMetrics, metricDest, name string, v uint64) {
+	if s.groupCounter(metricDest, name, v) {
+		return
+	}
+	if s.agg == nil {
+		dest.IncrementByValue(name, v)
+		return
+	}
+	if s.agg.addCounter(metricDest, name, v) {
+		s.flushAggregate()
+	}
+}
+
+// gauge sets a gauge, through the group operators (group check) or the
+// aggregation window if enabled
+func (s *Server) gauge(dest *cgm.CirconusMetrics, metricDest, name string, v interface{}) {
+	s.touchGauge(metricDest, name)
+	s.trackGauge(metricDest, name)
+	if s.groupGauge(metricDest, name, v) {
+		return
+	}
+	if s.agg == nil {
+		dest.Gauge(name, v)
+		return
+	}
+	if s.agg.setGauge(metricDest, name, v) {
+		s.flushAggregate()
+	}
+}
+
+// flushAggregate applies the aggregated counters and gauges to the
+// host and group metrics
+func (s *Server) flushAggregate() {
+	if s.agg == nil {
+		return
+	}
+
+	counters, gauges := s.agg.take()
+	for k, v := range counters {
+		if dest := s.metricsFor(k.dest); dest != nil {
+			dest.IncrementByValue(k.name, v)
+		}
+	}
+	for k, v := range gauges {
+		if dest := s.metricsFor(k.dest); dest != nil {
+			dest.Gauge(k.name, v)
+		}
+	}
+}
+
+// metricsFor returns the metrics for a destination (host|group)
+func (s *Server) metricsFor(metricDest string) *cgm.CirconusMetrics {
+	switch metricDest {
+	case destHost:
+		return s.hostMetrics
+	case destGroup:
+		return s.groupMetrics
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestNewAggregator(t *testing.T) {
+	t.Log("Testing newAggregator")
+
+	t.Log("zero window")
+	{
+		if a := newAggregator(0); a != nil {
+			t.Fatalf("expected nil, got (%#v)", a)
+		}
+	}
+
+	t.Log("valid window")
+	{
+		a := newAggregator(5 * time.Second)
+		if a == nil {
+			t.Fatal("expected not nil")
+		}
+		if a.window != 5*time.Second {
+			t.Fatalf("expected 5s, got (%s)", a.window)
+		}
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	t.Log("Testing aggregator")
+
+	a := newAggregator(time.Second)
+	a.maxEntries = 3
+
+	t.Log("counters sum")
+	{
+		if full := a.addCounter(destHost, "c", 1); full {
+			t.Fatal("expected not full")
+		}
+		if full := a.addCounter(destHost, "c", 2); full {
+			t.Fatal("expected not full")
+		}
+		if v := a.counters[aggKey{destHost, "c"}]; v != 3 {
+			t.Fatalf("expected 3, got (%d)", v)
+		}
+	}
+
+	t.Log("gauges keep last value")
+	{
+		a.setGauge(destHost, "g", uint64(1))
+		a.setGauge(destHost, "g", uint64(5))
+		if v := a.gauges[aggKey{destHost, "g"}]; v != uint64(5) {
+			t.Fatalf("expected 5, got (%v)", v)
+		}
+	}
+
+	t.Log("full")
+	{
+		if full := a.addCounter(destGroup, "c", 1); !full {
+			t.Fatal("expected full")
+		}
+	}
+
+	t.Log("take resets")
+	{
+		counters, gauges := a.take()
+		if len(counters) != 2 {
+			t.Fatalf("expected 2 counters, got (%d)", len(counters))
+		}
+		if len(gauges) != 1 {
+			t.Fatalf("expected 1 gauge, got (%d)", len(gauges))
+		}
+		if len(a.counters) != 0 || len(a.gauges) != 0 {
+			t.Fatal("expected empty aggregator")
+		}
+	}
+}
+
+func TestFlushAggregate(t *testing.T) {
+	t.Log("Testing flushAggregate")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65125")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	viper.Set(config.KeyStatsdAggregationWindow, "10s")
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+	viper.Reset()
+
+	if s.agg == nil {
+		t.Fatal("expected aggregator")
+	}
+
+	for _, m := range []string{"test:1|c", "test:2|c", "gtest:1|g", "gtest:7|g", "stest:foo|s", "stest:foo|s"} {
+		if err := s.parseMetric(m); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	t.Log("aggregated, not yet applied")
+	{
+		s.agg.Lock()
+		n := len(s.agg.counters) + len(s.agg.gauges)
+		s.agg.Unlock()
+		if n != 3 {
+			t.Fatalf("expected 3 aggregated metrics, got (%d)", n)
+		}
+	}
+
+	t.Log("applied on flush")
+	{
+		metrics := s.Flush()
+		if metrics == nil {
+			t.Fatal("expected not nil")
+		}
+		m := *metrics
+		if v, ok := m["test"]; !ok || v.Value != uint64(3) {
+			t.Fatalf("expected test=3, got (%#v)", v)
+		}
+		if v, ok := m["gtest"]; !ok || v.Value != uint64(7) {
+			t.Fatalf("expected gtest=7, got (%#v)", v)
+		}
+		if v, ok := m["stest"+config.MetricNameSeparator+"foo"]; !ok || v.Value != uint64(2) {
+			t.Fatalf("expected stest`foo=2, got (%#v)", v)
+		}
+	}
+
+	t.Log("state")
+	{
+		state := s.State()
+		if w, ok := state["aggregation_window"].(string); !ok || w != "10s" {
+			t.Fatalf("expected 10s, got (%#v)", state["aggregation_window"])
+		}
+	}
+}
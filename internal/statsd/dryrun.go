@@ -0,0 +1,80 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// ParseResult is the result of parsing one metric value of a test packet
+type ParseResult struct {
+	Metric      string      `json:"metric"`
+	Name        string      `json:"name,omitempty"`
+	Type        string      `json:"type,omitempty"`
+	Value       interface{} `json:"value,omitempty"`
+	Destination string      `json:"destination,omitempty"`
+	Dropped     bool        `json:"dropped,omitempty"`
+	Filtered    bool        `json:"filtered,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// DryRun parses a packet the same way as the listener, without recording
+// the metrics, returning the results for each metric value (or the error
+// for each metric line which could not be parsed). Used to troubleshoot
+// the metric format, host|group prefixes and tags.
+func (s *Server) DryRun(pkt []byte) ([]ParseResult, error) {
+	if s.disabled {
+		return nil, errors.New("statsd disabled")
+	}
+
+	results := []ParseResult{}
+	for _, line := range bytes.Split(pkt, []byte("\n")) {
+		if !s.strictLines {
+			line = bytes.TrimSpace(line)
+		}
+		if len(line) == 0 {
+			continue
+		}
+		metric := string(line)
+
+		pm, err := s.parseLine(metric)
+		if err != nil {
+			results = append(results, ParseResult{Metric: metric, Error: err.Error()})
+			continue
+		}
+
+		if pm.filtered {
+			results = append(results, ParseResult{Metric: metric, Name: pm.name, Filtered: true})
+			continue
+		}
+
+		for _, mv := range pm.values {
+			r := ParseResult{
+				Metric:      metric,
+				Name:        pm.name,
+				Type:        mv.mtype,
+				Destination: pm.metricDest,
+			}
+			v, err := s.parseValue(mv)
+			switch {
+			case err != nil:
+				r.Error = err.Error()
+			case v == nil:
+				r.Dropped = true
+			default:
+				r.Value = v
+			}
+			results = append(results, r)
+			if err != nil {
+				break // as the listener, an invalid value stops the remaining values
+			}
+		}
+	}
+
+	return results, nil
+}
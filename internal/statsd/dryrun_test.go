@@ -0,0 +1,108 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Log("Testing DryRun")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("disabled")
+	{
+		viper.Reset()
+		viper.Set(config.KeyStatsdDisabled, true)
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if _, err := s.DryRun([]byte("foo:1|c")); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	viper.Reset()
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65125")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	viper.Set(config.KeyStatsdHostPrefix, "host.")
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+
+	t.Log("valid")
+	{
+		results, err := s.DryRun([]byte("host.foo:1|c|@.5|#env:prod\nhost.bar:1.5|ms:2|ms\n\nhost.baz:-3|g"))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(results) != 4 {
+			t.Fatalf("expected 4 results, got %v", results)
+		}
+		r := results[0]
+		if !strings.HasPrefix(r.Name, "foo|ST[") {
+			t.Fatalf("expected foo with stream tags, got (%s)", r.Name)
+		}
+		if r.Type != "c" || r.Value != uint64(2) || r.Destination != destHost || r.Error != "" {
+			t.Fatalf("unexpected result %+v", r)
+		}
+		if hv, ok := results[2].Value.(histogramValue); !ok || hv.Value != 2 || hv.Count != 1 {
+			t.Fatalf("unexpected result %+v", results[2])
+		}
+		if results[3].Value != int64(-3) {
+			t.Fatalf("unexpected result %+v", results[3])
+		}
+		if m := *s.hostMetrics.FlushMetrics(); len(m) != 0 {
+			t.Fatalf("expected no metrics recorded, got %v", m)
+		}
+	}
+
+	t.Log("errors")
+	{
+		results, err := s.DryRun([]byte("test\nhost.foo:abc|c\nother:1|c"))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, got %v", results)
+		}
+		if results[0].Error == "" || results[0].Name != "" {
+			t.Fatalf("expected invalid format error, got %+v", results[0])
+		}
+		if results[1].Name != "foo" || !strings.Contains(results[1].Error, "invalid counter value") {
+			t.Fatalf("expected invalid counter error, got %+v", results[1])
+		}
+		// without a group check, metrics not matching the host prefix have no destination
+		if !strings.Contains(results[2].Error, "invalid metric destination") {
+			t.Fatalf("expected invalid destination error, got %+v", results[2])
+		}
+	}
+
+	t.Log("zero counter dropped")
+	{
+		s.zeroCounter = zeroCounterDrop
+		results, err := s.DryRun([]byte("host.foo:0|c"))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(results) != 1 || !results[0].Dropped {
+			t.Fatalf("expected dropped, got %v", results)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,50 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"time"
+
+	"github.com/maier/go-appstats"
+)
+
+// touchGauge records the last update time of a gauge, if gauge expiry is enabled
+func (s *Server) touchGauge(metricDest, name string) {
+	if s.gaugeTTL <= 0 {
+		return
+	}
+	s.gaugeSeenmu.Lock()
+	s.gaugeSeen[aggKey{metricDest, name}] = time.Now()
+	s.gaugeSeenmu.Unlock()
+}
+
+// expireGauges removes gauges which have not been updated within the gauge ttl,
+// so they are no longer submitted
+func (s *Server) expireGauges() {
+	if s.gaugeTTL <= 0 {
+		return
+	}
+
+	var expired []aggKey
+	cutoff := time.Now().Add(-s.gaugeTTL)
+
+	s.gaugeSeenmu.Lock()
+	for k, seen := range s.gaugeSeen {
+		if seen.Before(cutoff) {
+			expired = append(expired, k)
+			delete(s.gaugeSeen, k)
+		}
+	}
+	s.gaugeSeenmu.Unlock()
+
+	for _, k := range expired {
+		if dest := s.metricsFor(k.dest); dest != nil {
+			dest.RemoveGauge(k.name)
+			appstats.IncrementInt("statsd_gauges_expired")
+			s.logger.Debug().Str("dest", k.dest).Str("name", k.name).Msg("gauge expired")
+		}
+	}
+}
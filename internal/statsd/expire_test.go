@@ -0,0 +1,97 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestExpireGauges(t *testing.T) {
+	t.Log("Testing expireGauges")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("disabled")
+	{
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "65125")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		viper.Reset()
+
+		if err := s.parseMetric("gtest:1|g"); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.gaugeSeen != nil {
+			t.Fatal("expected no gauge tracking")
+		}
+		metrics := s.Flush()
+		s.listener.Close()
+		if _, ok := (*metrics)["gtest"]; !ok {
+			t.Fatalf("expected gtest, got (%#v)", metrics)
+		}
+	}
+
+	t.Log("enabled")
+	{
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "65125")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		viper.Set(config.KeyStatsdGaugeTTL, "1m")
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer s.listener.Close()
+		viper.Reset()
+
+		if s.gaugeTTL != time.Minute {
+			t.Fatalf("expected 1m, got (%s)", s.gaugeTTL)
+		}
+
+		for _, m := range []string{"gold:1|g", "gnew:2|g"} {
+			if err := s.parseMetric(m); err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+		}
+
+		// age gold past the ttl
+		s.gaugeSeenmu.Lock()
+		s.gaugeSeen[aggKey{destHost, "gold"}] = time.Now().Add(-2 * time.Minute)
+		s.gaugeSeenmu.Unlock()
+
+		metrics := s.Flush()
+		if _, ok := (*metrics)["gold"]; ok {
+			t.Fatalf("expected gold to be expired, got (%#v)", metrics)
+		}
+		if _, ok := (*metrics)["gnew"]; !ok {
+			t.Fatalf("expected gnew, got (%#v)", metrics)
+		}
+
+		state := s.State()
+		if n, ok := state["gauges_tracked"].(int); !ok || n != 1 {
+			t.Fatalf("expected 1 gauge tracked, got (%#v)", state["gauges_tracked"])
+		}
+
+		t.Log("updated gauge reported again")
+		if err := s.parseMetric("gold:3|g"); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		metrics = s.Flush()
+		if v, ok := (*metrics)["gold"]; !ok || v.Value != uint64(3) {
+			t.Fatalf("expected gold=3, got (%#v)", metrics)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"regexp"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// metricFilter allows or denies the metric names matching a pattern
+type metricFilter struct {
+	allow bool
+	rx    *regexp.Regexp
+}
+
+const (
+	filterAllow = "allow"
+	filterDeny  = "deny"
+)
+
+// loadMetricFilters parses and compiles the statsd.metric_filters setting, a
+// list of {action: <allow|deny>, match: <regex>} rules applied in order
+func loadMetricFilters() ([]metricFilter, error) {
+	if !viper.IsSet(config.KeyStatsdMetricFilters) {
+		return nil, nil
+	}
+
+	var cfgs []config.StatsDMetricFilter
+	if err := viper.UnmarshalKey(config.KeyStatsdMetricFilters, &cfgs); err != nil {
+		return nil, errors.Wrap(err, "parsing StatsD metric filters")
+	}
+
+	filters := make([]metricFilter, 0, len(cfgs))
+	for idx, cfg := range cfgs {
+		if cfg.Action != filterAllow && cfg.Action != filterDeny {
+			return nil, errors.Errorf("StatsD metric filter %d, invalid action (%s), expected allow|deny", idx, cfg.Action)
+		}
+		if cfg.Match == "" {
+			return nil, errors.Errorf("StatsD metric filter %d, match required", idx)
+		}
+		rx, err := regexp.Compile(cfg.Match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "StatsD metric filter %d, invalid match", idx)
+		}
+		filters = append(filters, metricFilter{allow: cfg.Action == filterAllow, rx: rx})
+	}
+
+	return filters, nil
+}
+
+// allowed determines if a metric name (as received, without tags) passes
+// the metric filters, the first matching filter applies. Metrics which do
+// not match any filter are allowed (end with a deny '.' for an allow list).
+func (s *Server) allowed(metricName string) bool {
+	for _, f := range s.filters {
+		if f.rx.MatchString(metricName) {
+			return f.allow
+		}
+	}
+	return true
+}
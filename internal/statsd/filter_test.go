@@ -0,0 +1,136 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestLoadMetricFilters(t *testing.T) {
+	t.Log("Testing loadMetricFilters")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tnot set")
+	{
+		viper.Reset()
+		filters, err := loadMetricFilters()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if filters != nil {
+			t.Fatalf("expected nil, got (%#v)", filters)
+		}
+	}
+
+	tests := []struct {
+		desc   string
+		filter map[string]interface{}
+		err    string
+	}{
+		{"invalid action", map[string]interface{}{"action": "drop", "match": "^foo"}, "StatsD metric filter 0, invalid action (drop), expected allow|deny"},
+		{"no match", map[string]interface{}{"action": "deny"}, "StatsD metric filter 0, match required"},
+		{"invalid match", map[string]interface{}{"action": "deny", "match": "("}, "StatsD metric filter 0, invalid match: error parsing regexp: missing closing ): `(`"},
+	}
+	for _, tt := range tests {
+		t.Logf("\t%s", tt.desc)
+		viper.Reset()
+		viper.Set(config.KeyStatsdMetricFilters, []interface{}{tt.filter})
+		_, err := loadMetricFilters()
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != tt.err {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+
+	t.Log("\tvalid")
+	{
+		viper.Reset()
+		viper.Set(config.KeyStatsdMetricFilters, []interface{}{
+			map[string]interface{}{"action": "allow", "match": "^app\\."},
+			map[string]interface{}{"action": "deny", "match": "."},
+		})
+		filters, err := loadMetricFilters()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(filters) != 2 {
+			t.Fatalf("expected 2 filters, got (%#v)", filters)
+		}
+		if !filters[0].allow || filters[1].allow {
+			t.Fatalf("unexpected actions (%#v)", filters)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestParseMetricFiltered(t *testing.T) {
+	t.Log("Testing parseMetric (metric filters)")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65125")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	viper.Set(config.KeyStatsdMetricFilters, []interface{}{
+		map[string]interface{}{"action": "deny", "match": "^app\\.debug\\."},
+		map[string]interface{}{"action": "allow", "match": "^app\\."},
+		map[string]interface{}{"action": "deny", "match": "."},
+	})
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+
+	tests := []struct {
+		metric string
+		name   string
+		found  bool
+	}{
+		{"app.requests:1|c", "app.requests", true},
+		{"app.debug.queue:1|g", "app.debug.queue", false},
+		{"junk:1|c", "junk", false},
+		{"app.latency:1|ms:2|ms|#env:prod", "app.latency|ST[env:prod]", true},
+	}
+
+	for _, tt := range tests {
+		t.Logf("\t%q", tt.metric)
+		if err := s.parseMetric(tt.metric); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		m := s.hostMetrics.FlushMetrics()
+		if _, ok := (*m)[tt.name]; ok != tt.found {
+			t.Fatalf("expected found %v, got %#v", tt.found, *m)
+		}
+	}
+
+	if n := s.State()["metrics_filtered"]; n != uint64(2) {
+		t.Fatalf("expected 2 filtered, got (%v)", n)
+	}
+
+	t.Log("\tdry run")
+	{
+		results, err := s.DryRun([]byte("junk:1|c"))
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(results) != 1 || !results[0].Filtered {
+			t.Fatalf("expected filtered result, got (%#v)", results)
+		}
+	}
+
+	viper.Reset()
+}
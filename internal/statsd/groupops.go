@@ -0,0 +1,132 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"sync"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+)
+
+// groupOps holds the group counters, gauges and set members received during
+// a group interval, they are reduced with the configured group operators
+// (sum|average) when applied to the group metrics
+type groupOps struct {
+	counters map[string]*opValue
+	gauges   map[string]*opValue
+	sets     map[string]*opValue
+	sync.Mutex
+}
+
+// opValue is the sum and number of values received for a group metric
+type opValue struct {
+	sum float64
+	n   int64
+}
+
+// newGroupOps returns an empty set of group metric values
+func newGroupOps() *groupOps {
+	return &groupOps{
+		counters: make(map[string]*opValue),
+		gauges:   make(map[string]*opValue),
+		sets:     make(map[string]*opValue),
+	}
+}
+
+// add records a value for a metric
+func (g *groupOps) add(values map[string]*opValue, name string, v float64) {
+	g.Lock()
+	defer g.Unlock()
+	ov, ok := values[name]
+	if !ok {
+		ov = &opValue{}
+		values[name] = ov
+	}
+	ov.sum += v
+	ov.n++
+}
+
+// take returns the counters, gauges and set members, resetting the values
+func (g *groupOps) take() (map[string]*opValue, map[string]*opValue, map[string]*opValue) {
+	g.Lock()
+	defer g.Unlock()
+	counters, gauges, sets := g.counters, g.gauges, g.sets
+	g.counters = make(map[string]*opValue)
+	g.gauges = make(map[string]*opValue)
+	g.sets = make(map[string]*opValue)
+	return counters, gauges, sets
+}
+
+// reduce returns the sum or, for the average operator, the mean of the values
+func (ov *opValue) reduce(op string) float64 {
+	if op == groupOpAverage && ov.n > 0 {
+		return ov.sum / float64(ov.n)
+	}
+	return ov.sum
+}
+
+// groupCounter records a group counter increment, returns false if group
+// operators are not in use (no group check)
+func (s *Server) groupCounter(metricDest, name string, v uint64) bool {
+	if metricDest != destGroup || s.groupOps == nil {
+		return false
+	}
+	s.groupOps.add(s.groupOps.counters, name, float64(v))
+	return true
+}
+
+// groupGauge records a group gauge value, returns false if group operators
+// are not in use (no group check) or the value is not numeric
+func (s *Server) groupGauge(metricDest, name string, v interface{}) bool {
+	if metricDest != destGroup || s.groupOps == nil {
+		return false
+	}
+	f, ok := counterValue(v)
+	if !ok {
+		return false
+	}
+	s.groupOps.add(s.groupOps.gauges, name, f)
+	return true
+}
+
+// groupSet records an occurrence of a group set member, returns false if
+// group operators are not in use (no group check)
+func (s *Server) groupSet(metricDest, name string) bool {
+	if metricDest != destGroup || s.groupOps == nil {
+		return false
+	}
+	s.groupOps.add(s.groupOps.sets, name, 1)
+	return true
+}
+
+// flushGroupOps applies the group values received since the last flush to
+// the group metrics. Counters and set members are submitted as counters,
+// the sum of the increments (sum) or the average increment (average).
+// Gauges are submitted as the sum (sum) or the average (average) of the
+// values received. A set member is counted once per occurrence (sum) or
+// once per interval (average).
+func (s *Server) flushGroupOps() {
+	if s.groupOps == nil || s.groupMetrics == nil {
+		return
+	}
+
+	counters, gauges, sets := s.groupOps.take()
+	for name, ov := range counters {
+		setGroupCounter(s.groupMetrics, name, ov.reduce(s.groupCounterOp))
+	}
+	for name, ov := range sets {
+		setGroupCounter(s.groupMetrics, name, ov.reduce(s.groupSetOp))
+	}
+	for name, ov := range gauges {
+		s.groupMetrics.Gauge(name, ov.reduce(s.groupGaugeOp))
+	}
+}
+
+// setGroupCounter adds a reduced counter value, rounded to the nearest
+// integer, to a group counter
+func setGroupCounter(dest *cgm.CirconusMetrics, name string, v float64) {
+	dest.IncrementByValue(name, uint64(v+0.5))
+}
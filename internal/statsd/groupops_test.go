@@ -0,0 +1,99 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestGroupOps(t *testing.T) {
+	t.Log("Testing group operators")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65126")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+
+	if err := s.initHostMetrics(); err != nil {
+		t.Fatalf("initHostMetrics %s", err)
+	}
+	s.groupMetrics = s.hostMetrics // no group check in tests, destination verified by name
+	s.groupPrefix = "group."
+	s.groupOps = newGroupOps()
+
+	tests := []struct {
+		desc      string
+		counterOp string
+		gaugeOp   string
+		setOp     string
+		metrics   []string
+		name      string
+		expected  float64
+	}{
+		{"counter sum", "sum", "average", "sum", []string{"group.requests:2|c", "group.requests:4|c", "group.requests:9|c"}, "requests", 15},
+		{"counter average", "average", "average", "sum", []string{"group.requests:2|c", "group.requests:4|c", "group.requests:9|c"}, "requests", 5},
+		{"gauge sum", "sum", "sum", "sum", []string{"group.load:1.5|g", "group.load:2|g", "group.load:-1|g"}, "load", 2.5},
+		{"gauge average", "sum", "average", "sum", []string{"group.load:1.5|g", "group.load:2|g", "group.load:4|g"}, "load", 2.5},
+		{"set sum", "sum", "average", "sum", []string{"group.users:bob|s", "group.users:bob|s", "group.users:alice|s"}, "users`bob", 2},
+		{"set average", "sum", "average", "average", []string{"group.users:bob|s", "group.users:bob|s", "group.users:alice|s"}, "users`bob", 1},
+	}
+
+	for _, tst := range tests {
+		t.Logf("\t%s", tst.desc)
+		s.groupCounterOp = tst.counterOp
+		s.groupGaugeOp = tst.gaugeOp
+		s.groupSetOp = tst.setOp
+
+		for _, metric := range tst.metrics {
+			if err := s.parseMetric(metric); err != nil {
+				t.Fatalf("expected nil, got (%s)", err)
+			}
+		}
+
+		if m := s.groupMetrics.FlushMetrics(); len(*m) != 0 {
+			t.Fatalf("expected no metrics before group flush, got %#v", *m)
+		}
+
+		s.flushGroupOps()
+		m := s.groupMetrics.FlushMetrics()
+		metric, ok := (*m)[tst.name]
+		if !ok {
+			t.Fatalf("expected %s, got %#v", tst.name, *m)
+		}
+		v, ok := counterValue(metric.Value)
+		if !ok || v != tst.expected {
+			t.Fatalf("expected %v, got %#v", tst.expected, metric)
+		}
+	}
+
+	t.Log("\thost metrics not reduced")
+	{
+		s.groupCounterOp = groupOpAverage
+		for _, metric := range []string{"requests:2|c", "requests:4|c"} {
+			if err := s.parseMetric(metric); err != nil {
+				t.Fatalf("expected nil, got (%s)", err)
+			}
+		}
+		m := s.hostMetrics.FlushMetrics()
+		if v, ok := counterValue((*m)["requests"].Value); !ok || v != 6 {
+			t.Fatalf("expected 6, got %#v", *m)
+		}
+	}
+
+	viper.Reset()
+}
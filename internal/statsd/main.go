@@ -12,8 +12,13 @@ import (
 	"net"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-agent/internal/inherit"
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/maier/go-appstats"
 	"github.com/pkg/errors"
@@ -43,18 +48,40 @@ func New() (*Server, error) {
 		logger:         log.With().Str("pkg", "statsd").Logger(),
 		hostPrefix:     viper.GetString(config.KeyStatsdHostPrefix),
 		hostCategory:   viper.GetString(config.KeyStatsdHostCategory),
+		hostTag:        viper.GetString(config.KeyStatsdHostTag),
 		groupCID:       viper.GetString(config.KeyStatsdGroupCID),
 		groupPrefix:    viper.GetString(config.KeyStatsdGroupPrefix),
 		groupCounterOp: viper.GetString(config.KeyStatsdGroupCounters),
 		groupGaugeOp:   viper.GetString(config.KeyStatsdGroupGauges),
+		groupInterval:  viper.GetString(config.KeyStatsdGroupInterval),
 		groupSetOp:     viper.GetString(config.KeyStatsdGroupSets),
+		groupTag:       viper.GetString(config.KeyStatsdGroupTag),
 		debugCGM:       viper.GetBool(config.KeyDebugCGM),
 		apiKey:         viper.GetString(config.KeyAPITokenKey),
 		apiApp:         viper.GetString(config.KeyAPITokenApp),
 		apiURL:         viper.GetString(config.KeyAPIURL),
 		apiCAFile:      viper.GetString(config.KeyAPICAFile),
-		packetCh:       make(chan []byte, packetQueueSize),
+		counterMode:    viper.GetString(config.KeyStatsdCounterMode),
+		queueMetrics:   viper.GetBool(config.KeyStatsdQueueMetrics),
+		rejectInvalid:  viper.GetString(config.KeyStatsdInvalidChars) == invalidCharsReject,
+		routing:        viper.GetString(config.KeyStatsdRouting),
+		setDelimiter:   viper.GetString(config.KeyStatsdSetDelimiter),
+		setMaxLength:   viper.GetInt(config.KeyStatsdSetMaxLength),
+		stateFile:      viper.GetString(config.KeyStatsdStateFile),
+		strictLines:    viper.GetBool(config.KeyStatsdStrictLines),
+		zeroCounter:    viper.GetString(config.KeyStatsdZeroCounter),
 	}
+	if s.routing == "" {
+		s.routing = routePrefix
+	}
+
+	// validated above, zero uses the default
+	queueSize := viper.GetInt(config.KeyStatsdQueueSize)
+	if queueSize == 0 {
+		queueSize = defaults.StatsdQueueSize
+	}
+	s.packetCh = make(chan []byte, queueSize)
+	s.queue = newQueueStats(queueSize)
 
 	port := viper.GetString(config.KeyStatsdPort)
 	address := net.JoinHostPort("localhost", port)
@@ -64,8 +91,64 @@ func New() (*Server, error) {
 	}
 
 	s.address = addr
-	s.metricRegex = regexp.MustCompile(`^(?P<name>[^:\s]+):(?P<value>[^|\s]+)\|(?P<type>[a-z]+)(?:\|@(?P<sample>[0-9.]+))?(?:\|#(?P<tags>[^:,]+:[^:,]+(,[^:,]+:[^:,]+)*))?$`)
+
+	// validated above, empty or zero disables aggregation
+	if window := viper.GetString(config.KeyStatsdAggregationWindow); window != "" {
+		if d, err := time.ParseDuration(window); err == nil {
+			s.agg = newAggregator(d)
+		}
+	}
+
+	// validated above, zero disables rate limiting
+	if rate := viper.GetInt(config.KeyStatsdRateLimit); rate > 0 {
+		s.limiter = newRateLimiter(rate, viper.GetInt(config.KeyStatsdRateBurst), rateLimitMaxSources)
+	}
+
+	// validated above, empty disables timer percentiles
+	if pcts, err := parsePercentiles(viper.GetStringSlice(config.KeyStatsdTimerPercentiles)); err == nil {
+		s.timers = newTimerSet(pcts, timerMaxMetrics, timerMaxValues)
+		s.timerPercentilesOnly = s.timers != nil && viper.GetBool(config.KeyStatsdTimerPercentilesOnly)
+	}
+
+	// validated above, empty state file disables saving state
+	if s.stateFile != "" {
+		s.stateGauges = make(map[string]uint64)
+		s.stateMaxAge, _ = time.ParseDuration(viper.GetString(config.KeyStatsdStateMaxAge))
+		if s.stateMaxAge <= 0 {
+			s.stateMaxAge, _ = time.ParseDuration(defaults.StatsdStateMaxAge)
+		}
+	}
+
+	// validated above, empty uses the default (count)
+	switch s.counterMode {
+	case counterModeRate, counterModeBoth:
+		s.counterNames = make(map[string]bool)
+	default:
+		s.counterMode = counterModeCount
+	}
+
+	// group operators are applied to the values submitted to the group check
+	if s.groupCID != "" {
+		s.groupOps = newGroupOps()
+	}
+
+	// validated above, empty or zero disables gauge expiry
+	if ttl := viper.GetString(config.KeyStatsdGaugeTTL); ttl != "" {
+		if d, err := time.ParseDuration(ttl); err == nil && d > 0 {
+			s.gaugeTTL = d
+			s.gaugeSeen = make(map[aggKey]time.Time)
+		}
+	}
+	filters, err := loadMetricFilters()
+	if err != nil {
+		return nil, err
+	}
+	s.filters = filters
+
+	s.metricRegex = regexp.MustCompile(`^(?P<name>[^:|]+):(?P<value>[^|\s]+)\|(?P<type>[a-z]+)(?:\|@(?P<sample>[0-9.]+))?(?:\|#(?P<tags>[^:,]+:[^:,]+(,[^:,]+:[^:,]+)*))?$`)
 	s.metricRegexGroupNames = s.metricRegex.SubexpNames()
+	s.packedRegex = regexp.MustCompile(`^(?P<name>[^:|]+):(?P<values>[^:|\s]+\|[a-z]+(?:\|@[0-9.]+)?(?::[^:|\s]+\|[a-z]+(?:\|@[0-9.]+)?)+)(?:\|#(?P<tags>[^:,]+:[^:,]+(,[^:,]+:[^:,]+)*))?$`)
+	s.packedRegexGroupNames = s.packedRegex.SubexpNames()
 
 	if !s.disabled {
 		if ierr := s.initHostMetrics(); ierr != nil {
@@ -77,7 +160,7 @@ func New() (*Server, error) {
 		}
 	}
 
-	l, err := net.ListenUDP("udp", s.address)
+	l, err := inherit.ListenUDP(s.address)
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +176,7 @@ func (s *Server) Start() error {
 		return nil
 	}
 
+	atomic.StoreInt32(&s.started, 1)
 	s.t.Go(s.reader)
 	s.t.Go(s.processor)
 
@@ -112,6 +196,20 @@ func (s *Server) Stop() error {
 		s.t.Kill(nil)
 	}
 
+	// the processor may be applying a packet, wait for the reader and
+	// processor to exit so the final flush includes it and nothing is
+	// recorded after the state is saved
+	if atomic.LoadInt32(&s.started) == 1 {
+		<-s.t.Dead()
+	}
+
+	s.flushAggregate()
+	s.flushGroupOps()
+
+	if err := s.saveState(); err != nil {
+		s.logger.Error().Err(err).Msg("saving state")
+	}
+
 	if s.groupMetrics != nil {
 		s.logger.Info().Msg("Flushing group metrics")
 		s.groupMetricsmu.Lock()
@@ -133,9 +231,18 @@ func (s *Server) Flush() *cgm.Metrics {
 		return &cgm.Metrics{}
 	}
 
+	s.flushTimers()
+	s.flushAggregate()
+	s.expireGauges()
+	s.flushQueueMetrics()
+
 	s.hostMetricsmu.Lock()
-	defer s.hostMetricsmu.Unlock()
-	return s.hostMetrics.FlushMetrics()
+	metrics := s.hostMetrics.FlushMetrics()
+	s.hostMetricsmu.Unlock()
+
+	s.pruneGauges()
+	s.counterRates(metrics, time.Now())
+	return metrics
 }
 
 // initHostMetrics initializes the host metrics circonus-gometrics instance
@@ -156,6 +263,10 @@ func (s *Server) initHostMetrics() error {
 		return errors.Wrap(err, "statsd host check")
 	}
 
+	if err := s.loadState(hm); err != nil {
+		s.logger.Error().Err(err).Msg("restoring state")
+	}
+
 	s.hostMetrics = hm
 
 	s.logger.Info().Msg("host check initialized")
@@ -176,8 +287,9 @@ func (s *Server) initGroupMetrics() error {
 	defer s.groupMetricsmu.Unlock()
 
 	cmc := &cgm.Config{
-		Debug: s.debugCGM,
-		Log:   stdlog.New(s.logger.With().Str("pkg", "statsd-group-check").Logger(), "", 0),
+		Debug:    s.debugCGM,
+		Interval: s.groupInterval,
+		Log:      stdlog.New(config.RedactWriter(s.logger.With().Str("pkg", "statsd-group-check").Logger()), "", 0),
 	}
 	cmc.CheckManager.API.TokenKey = s.apiKey
 	cmc.CheckManager.API.TokenApp = s.apiApp
@@ -209,11 +321,77 @@ func (s *Server) initGroupMetrics() error {
 	return nil
 }
 
+// ProcessPacket parses a packet of metrics received other than by the
+// listener (e.g. lines read from a fifo plugin)
+func (s *Server) ProcessPacket(pkt []byte) error {
+	if s.disabled {
+		return errors.New("statsd disabled")
+	}
+	return s.processPacket(pkt)
+}
+
+// Enabled returns whether the statsd server is enabled
+func (s *Server) Enabled() bool {
+	return !s.disabled
+}
+
+// Address returns the address the statsd server listens on, empty if disabled
+func (s *Server) Address() string {
+	if s.disabled || s.address == nil {
+		return ""
+	}
+	return s.address.String()
+}
+
+// State returns diagnostic details about the statsd server
+func (s *Server) State() map[string]interface{} {
+	state := map[string]interface{}{
+		"disabled":      s.disabled,
+		"packets_total": atomic.LoadUint64(&s.packetsTotal),
+		"packets_bad":   atomic.LoadUint64(&s.packetsBad),
+		"metrics_bad":   atomic.LoadUint64(&s.metricsBad),
+		"queued":        len(s.packetCh),
+		"queue_size":    cap(s.packetCh),
+	}
+	if s.queue != nil {
+		state["queue_high_water"] = s.queue.peak()
+	}
+	if s.address != nil {
+		state["address"] = s.address.String()
+	}
+	if s.agg != nil {
+		state["aggregation_window"] = s.agg.window.String()
+	}
+	if len(s.filters) > 0 {
+		state["metrics_filtered"] = atomic.LoadUint64(&s.metricsFiltered)
+	}
+	if s.limiter != nil {
+		state["packets_throttled"] = atomic.LoadUint64(&s.packetsThrottled)
+		state["rate_limit_sources"] = s.limiter.len()
+	}
+	if s.timers != nil {
+		timers, dropped := s.timers.len()
+		state["timers_buffered"] = timers
+		state["timer_values_dropped"] = dropped
+		state["timer_percentiles_only"] = s.timerPercentilesOnly
+	}
+	if s.stateFile != "" {
+		state["state_file"] = s.stateFile
+	}
+	if s.gaugeTTL > 0 {
+		s.gaugeSeenmu.Lock()
+		state["gauges_tracked"] = len(s.gaugeSeen)
+		s.gaugeSeenmu.Unlock()
+		state["gauge_ttl"] = s.gaugeTTL.String()
+	}
+	return state
+}
+
 // reader reads packets from the statsd listener, adds packets recevied to the queue
 func (s *Server) reader() error {
 	for {
 		buff := make([]byte, maxPacketSize)
-		n, err := s.listener.Read(buff)
+		n, src, err := s.listener.ReadFromUDP(buff)
 		if s.shutdown() {
 			return nil
 		}
@@ -223,24 +401,89 @@ func (s *Server) reader() error {
 		}
 		if n > 0 {
 			appstats.IncrementInt("statsd_packets_total")
+			atomic.AddUint64(&s.packetsTotal, 1)
+			if s.throttled(src) {
+				continue
+			}
 			pkt := make([]byte, n)
 			copy(pkt, buff[:n])
-			s.packetCh <- pkt
+			select {
+			case s.packetCh <- pkt:
+			case <-s.t.Dying():
+				return nil // processor stopped, queue is not drained
+			}
+			s.sampleQueue()
 		}
 	}
 }
 
+// throttled returns whether a packet from src exceeds the rate limit, and
+// should be dropped, counting it for the source if it does
+func (s *Server) throttled(src *net.UDPAddr) bool {
+	if s.limiter == nil || src == nil {
+		return false
+	}
+	source := src.IP.String()
+	if s.limiter.allow(source, time.Now()) {
+		return false
+	}
+	appstats.IncrementInt("statsd_packets_throttled")
+	atomic.AddUint64(&s.packetsThrottled, 1)
+	if s.hostMetrics != nil {
+		s.hostMetrics.Increment(throttledMetricName(source))
+	}
+	return true
+}
+
 // processor reads the packet queue and processes each packet
 func (s *Server) processor() error {
 	defer s.listener.Close()
+
+	// nil channel (never ready) when aggregation is disabled
+	var aggCh <-chan time.Time
+	if s.agg != nil {
+		ticker := time.NewTicker(s.agg.window)
+		defer ticker.Stop()
+		aggCh = ticker.C
+	}
+
+	// group values are reduced by the group operators and applied to the
+	// group metrics each group interval (validated, empty uses the default)
+	var groupCh <-chan time.Time
+	if s.groupOps != nil {
+		interval, err := time.ParseDuration(s.groupInterval)
+		if err != nil || interval <= 0 {
+			interval, _ = time.ParseDuration(defaults.StatsdGroupInterval)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		groupCh = ticker.C
+	}
+
+	// group metrics are submitted independently of host metric collection,
+	// expire gauges periodically so group gauges expire as well
+	var expireCh <-chan time.Time
+	if s.gaugeTTL > 0 {
+		ticker := time.NewTicker(s.gaugeTTL)
+		defer ticker.Stop()
+		expireCh = ticker.C
+	}
+
 	for {
 		select {
 		case <-s.t.Dying():
 			return nil
+		case <-aggCh:
+			s.flushAggregate()
+		case <-groupCh:
+			s.flushGroupOps()
+		case <-expireCh:
+			s.expireGauges()
 		case pkt := <-s.packetCh:
 			err := s.processPacket(pkt)
 			if err != nil {
 				appstats.IncrementInt("statsd_packets_bad")
+				atomic.AddUint64(&s.packetsBad, 1)
 				s.logger.Error().Err(err).Msg("processor")
 				return errors.Wrap(err, "processor")
 			}
@@ -258,6 +501,11 @@ func (s *Server) shutdown() bool {
 	}
 }
 
+// Validate checks the statsd configuration without creating a listener
+func Validate() error {
+	return validateStatsdOptions()
+}
+
 func validateStatsdOptions() error {
 	if viper.GetBool(config.KeyStatsdDisabled) {
 		return nil
@@ -282,11 +530,107 @@ func validateStatsdOptions() error {
 	// validate further if group check is enabled (see groupPrefix validation below)
 	hostPrefix := viper.GetString(config.KeyStatsdHostPrefix)
 
+	// empty uses the default (sanitize)
+	switch invalidChars := viper.GetString(config.KeyStatsdInvalidChars); invalidChars {
+	case "", invalidCharsReject, invalidCharsSanitize:
+	default:
+		return errors.Errorf("Invalid StatsD invalid chars handling (%s), expected sanitize|reject", invalidChars)
+	}
+
+	// empty uses the default (metric name separator)
+	if delim := viper.GetString(config.KeyStatsdSetDelimiter); delim != "" {
+		if len(delim) > setDelimiterMaxLength {
+			return errors.Errorf("Invalid StatsD set delimiter (%s), must be at most %d characters", delim, setDelimiterMaxLength)
+		}
+		for _, r := range delim {
+			if !validMetricRune(r) && string(r) != config.MetricNameSeparator {
+				return errors.Errorf("Invalid StatsD set delimiter (%q), must be printable, without whitespace", delim)
+			}
+		}
+	}
+	if maxLen := viper.GetInt(config.KeyStatsdSetMaxLength); maxLen < 0 || (maxLen > 0 && maxLen < setMaxLengthMin) {
+		return errors.Errorf("Invalid StatsD set max length (%d), must be 0 (disabled) or at least %d", maxLen, setMaxLengthMin)
+	}
+
+	// empty uses the default (count)
+	switch counterMode := viper.GetString(config.KeyStatsdCounterMode); counterMode {
+	case "", counterModeCount, counterModeRate, counterModeBoth:
+	default:
+		return errors.Errorf("Invalid StatsD counter mode (%s), expected count|rate|both", counterMode)
+	}
+
+	// empty uses the default (zero)
+	switch zeroCounter := viper.GetString(config.KeyStatsdZeroCounter); zeroCounter {
+	case "", zeroCounterZero, zeroCounterDrop, zeroCounterOne:
+	default:
+		return errors.Errorf("Invalid StatsD zero counter handling (%s), expected zero|drop|one", zeroCounter)
+	}
+
+	if rate := viper.GetInt(config.KeyStatsdRateLimit); rate < 0 {
+		return errors.Errorf("Invalid StatsD rate limit (%d), must be 0 (disabled) or greater", rate)
+	}
+	if burst := viper.GetInt(config.KeyStatsdRateBurst); burst < 0 {
+		return errors.Errorf("Invalid StatsD rate burst (%d), must be 0 (rate limit) or greater", burst)
+	}
+	if size := viper.GetInt(config.KeyStatsdQueueSize); size < 0 {
+		return errors.Errorf("Invalid StatsD queue size (%d), must be 0 (default) or greater", size)
+	}
+
+	pcts, err := parsePercentiles(viper.GetStringSlice(config.KeyStatsdTimerPercentiles))
+	if err != nil {
+		return errors.Wrap(err, "Invalid StatsD timer percentiles")
+	}
+	if len(pcts) == 0 && viper.GetBool(config.KeyStatsdTimerPercentilesOnly) {
+		return errors.New("Invalid StatsD timer percentiles only, no timer percentiles configured")
+	}
+
 	hostCat := viper.GetString(config.KeyStatsdHostCategory)
 	if hostCat == "" {
 		return errors.New("Invalid StatsD host category (empty)")
 	}
 
+	if hostTag := viper.GetString(config.KeyStatsdHostTag); strings.ContainsAny(hostTag, ":,| \t") {
+		return errors.Errorf("Invalid StatsD host tag (%s), must be a tag category (e.g. host)", hostTag)
+	}
+
+	switch routing := viper.GetString(config.KeyStatsdRouting); routing {
+	case "", routePrefix:
+	case routeTag, routeBoth:
+		groupTag := viper.GetString(config.KeyStatsdGroupTag)
+		if !groupTagRx.MatchString(groupTag) {
+			return errors.Errorf("Invalid StatsD group tag (%s), must be category:value (e.g. scope:group)", groupTag)
+		}
+	default:
+		return errors.Errorf("Invalid StatsD routing (%s), must be %s, %s or %s", routing, routePrefix, routeTag, routeBoth)
+	}
+
+	// only used with a state file, empty uses the default
+	if maxAge := viper.GetString(config.KeyStatsdStateMaxAge); maxAge != "" {
+		if d, err := time.ParseDuration(maxAge); err != nil {
+			return errors.Wrapf(err, "Invalid StatsD state max age (%s)", maxAge)
+		} else if d <= 0 {
+			return errors.Errorf("Invalid StatsD state max age (%s), must be greater than 0", maxAge)
+		}
+	}
+
+	// empty or zero disables gauge expiry
+	if ttl := viper.GetString(config.KeyStatsdGaugeTTL); ttl != "" && ttl != "0" {
+		if d, err := time.ParseDuration(ttl); err != nil {
+			return errors.Wrapf(err, "Invalid StatsD gauge ttl (%s)", ttl)
+		} else if d <= 0 {
+			return errors.Errorf("Invalid StatsD gauge ttl (%s), must be greater than 0", ttl)
+		}
+	}
+
+	// empty or zero disables the aggregation window
+	if window := viper.GetString(config.KeyStatsdAggregationWindow); window != "" && window != "0" {
+		if d, err := time.ParseDuration(window); err != nil {
+			return errors.Wrapf(err, "Invalid StatsD aggregation window (%s)", window)
+		} else if d <= 0 {
+			return errors.Errorf("Invalid StatsD aggregation window (%s), must be greater than 0", window)
+		}
+	}
+
 	groupCID := viper.GetString(config.KeyStatsdGroupCID)
 	if groupCID == "" {
 		return nil // statsd group check support disabled, all metrics go to host
@@ -348,5 +692,14 @@ func validateStatsdOptions() error {
 		return errors.Errorf("Invalid StatsD set operator (%s)", setOp)
 	}
 
+	// empty interval uses the circonus-gometrics default
+	if interval := viper.GetString(config.KeyStatsdGroupInterval); interval != "" {
+		if d, err := time.ParseDuration(interval); err != nil {
+			return errors.Wrapf(err, "Invalid StatsD group interval (%s)", interval)
+		} else if d < time.Second {
+			return errors.Errorf("Invalid StatsD group interval (%s), must be at least 1s", interval)
+		}
+	}
+
 	return nil
 }
@@ -8,6 +8,7 @@ package statsd
 import (
 	"errors"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -167,6 +168,36 @@ func TestStop(t *testing.T) {
 		s.Start()
 		viper.Reset()
 	}
+
+	t.Log("Enabled, waits for processor")
+	{
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "65125")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		done := make(chan struct{})
+		go func() {
+			s.Start()
+			close(done)
+		}()
+		for atomic.LoadInt32(&s.started) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		for i := 0; i < 100; i++ {
+			s.packetCh <- []byte("foo:1|c")
+		}
+		s.Stop()
+		select {
+		case <-s.t.Dead():
+		default:
+			t.Fatal("expected reader and processor to have exited")
+		}
+		<-done
+		viper.Reset()
+	}
 }
 
 func TestFlush(t *testing.T) {
@@ -321,6 +352,54 @@ func TestValidateStatsdOptions(t *testing.T) {
 
 	viper.Set(config.KeyStatsdHostCategory, "statsd")
 
+	t.Log("Host tag (invalid, contains ':')")
+	{
+		viper.Set(config.KeyStatsdHostTag, "host:web1")
+
+		expectedErr := errors.New("Invalid StatsD host tag (host:web1), must be a tag category (e.g. host)")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	viper.Set(config.KeyStatsdHostTag, "host")
+
+	t.Log("Routing (invalid)")
+	{
+		viper.Set(config.KeyStatsdRouting, "name")
+
+		expectedErr := errors.New("Invalid StatsD routing (name), must be prefix, tag or both")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Group tag (invalid, no value)")
+	{
+		viper.Set(config.KeyStatsdRouting, "tag")
+		viper.Set(config.KeyStatsdGroupTag, "scope")
+
+		expectedErr := errors.New("Invalid StatsD group tag (scope), must be category:value (e.g. scope:group)")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	viper.Set(config.KeyStatsdRouting, "both")
+	viper.Set(config.KeyStatsdGroupTag, "scope:group")
+
 	t.Log("Group CID, OK - none")
 	{
 		viper.Set(config.KeyStatsdGroupCID, "")
@@ -562,5 +641,382 @@ func TestValidateStatsdOptions(t *testing.T) {
 		}
 	}
 
+	t.Log("Invalid chars, invalid ('strip')")
+	{
+		viper.Set(config.KeyStatsdInvalidChars, "strip")
+
+		expectedErr := errors.New("Invalid StatsD invalid chars handling (strip), expected sanitize|reject")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdInvalidChars, "reject")
+	}
+
+	t.Log("Counter mode, invalid ('gauge')")
+	{
+		viper.Set(config.KeyStatsdCounterMode, "gauge")
+
+		expectedErr := errors.New("Invalid StatsD counter mode (gauge), expected count|rate|both")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdCounterMode, "both")
+	}
+
+	t.Log("Zero counter, invalid ('ignore')")
+	{
+		viper.Set(config.KeyStatsdZeroCounter, "ignore")
+
+		expectedErr := errors.New("Invalid StatsD zero counter handling (ignore), expected zero|drop|one")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdZeroCounter, "drop")
+	}
+
+	t.Log("Set delimiter, invalid (whitespace)")
+	{
+		viper.Set(config.KeyStatsdSetDelimiter, "a b")
+
+		expectedErr := errors.New("Invalid StatsD set delimiter (\"a b\"), must be printable, without whitespace")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdSetDelimiter, "::")
+	}
+
+	t.Log("Set max length, invalid (8)")
+	{
+		viper.Set(config.KeyStatsdSetMaxLength, 8)
+
+		expectedErr := errors.New("Invalid StatsD set max length (8), must be 0 (disabled) or at least 16")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdSetMaxLength, 64)
+	}
+
+	t.Log("State max age, invalid ('abc')")
+	{
+		viper.Set(config.KeyStatsdStateMaxAge, "abc")
+
+		expectedErr := errors.New("Invalid StatsD state max age (abc): time: invalid duration \"abc\"")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("State max age, invalid ('0s')")
+	{
+		viper.Set(config.KeyStatsdStateMaxAge, "0s")
+
+		expectedErr := errors.New("Invalid StatsD state max age (0s), must be greater than 0")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdStateMaxAge, "5m")
+	}
+
+	t.Log("State max age, invalid ('0s'), no group check")
+	{
+		viper.Set(config.KeyStatsdGroupCID, "")
+		viper.Set(config.KeyStatsdStateMaxAge, "0s")
+
+		expectedErr := errors.New("Invalid StatsD state max age (0s), must be greater than 0")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdStateMaxAge, "5m")
+		viper.Set(config.KeyStatsdGroupCID, "/check_bundle/123")
+	}
+
+	t.Log("Group interval, invalid ('abc')")
+	{
+		viper.Set(config.KeyStatsdGroupInterval, "abc")
+
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+
+	t.Log("Group interval, invalid ('500ms')")
+	{
+		viper.Set(config.KeyStatsdGroupInterval, "500ms")
+
+		expectedErr := errors.New("Invalid StatsD group interval (500ms), must be at least 1s")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Group interval, valid ('30s')")
+	{
+		viper.Set(config.KeyStatsdGroupInterval, "30s")
+
+		err := validateStatsdOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("Gauge ttl, invalid ('abc')")
+	{
+		viper.Set(config.KeyStatsdGaugeTTL, "abc")
+
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+
+	t.Log("Gauge ttl, invalid ('-1m')")
+	{
+		viper.Set(config.KeyStatsdGaugeTTL, "-1m")
+
+		expectedErr := errors.New("Invalid StatsD gauge ttl (-1m), must be greater than 0")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Gauge ttl, valid ('5m')")
+	{
+		viper.Set(config.KeyStatsdGaugeTTL, "5m")
+
+		err := validateStatsdOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("Gauge ttl, invalid ('-1m'), no group check")
+	{
+		viper.Set(config.KeyStatsdGroupCID, "")
+		viper.Set(config.KeyStatsdGaugeTTL, "-1m")
+
+		expectedErr := errors.New("Invalid StatsD gauge ttl (-1m), must be greater than 0")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdGaugeTTL, "5m")
+		viper.Set(config.KeyStatsdGroupCID, "/check_bundle/123")
+	}
+
+	t.Log("Aggregation window, invalid ('abc')")
+	{
+		viper.Set(config.KeyStatsdAggregationWindow, "abc")
+
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+	}
+
+	t.Log("Aggregation window, invalid ('-1s')")
+	{
+		viper.Set(config.KeyStatsdAggregationWindow, "-1s")
+
+		expectedErr := errors.New("Invalid StatsD aggregation window (-1s), must be greater than 0")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Aggregation window, disabled ('0')")
+	{
+		viper.Set(config.KeyStatsdAggregationWindow, "0")
+
+		err := validateStatsdOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("Aggregation window, valid ('5s')")
+	{
+		viper.Set(config.KeyStatsdAggregationWindow, "5s")
+
+		err := validateStatsdOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("Aggregation window, invalid ('-1s'), no group check")
+	{
+		viper.Set(config.KeyStatsdGroupCID, "")
+		viper.Set(config.KeyStatsdAggregationWindow, "-1s")
+
+		expectedErr := errors.New("Invalid StatsD aggregation window (-1s), must be greater than 0")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+		viper.Set(config.KeyStatsdAggregationWindow, "5s")
+		viper.Set(config.KeyStatsdGroupCID, "/check_bundle/123")
+	}
+
+	t.Log("Rate limit, invalid (-1)")
+	{
+		viper.Set(config.KeyStatsdRateLimit, -1)
+
+		expectedErr := errors.New("Invalid StatsD rate limit (-1), must be 0 (disabled) or greater")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Rate burst, invalid (-1)")
+	{
+		viper.Set(config.KeyStatsdRateLimit, 100)
+		viper.Set(config.KeyStatsdRateBurst, -1)
+
+		expectedErr := errors.New("Invalid StatsD rate burst (-1), must be 0 (rate limit) or greater")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Rate limit, valid (100/200)")
+	{
+		viper.Set(config.KeyStatsdRateLimit, 100)
+		viper.Set(config.KeyStatsdRateBurst, 200)
+
+		err := validateStatsdOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	t.Log("Timer percentiles, invalid (101)")
+	{
+		viper.Set(config.KeyStatsdTimerPercentiles, []string{"95", "101"})
+
+		expectedErr := errors.New("Invalid StatsD timer percentiles: invalid percentile (101), must be greater than 0 and at most 100")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Timer percentiles only, no percentiles")
+	{
+		viper.Set(config.KeyStatsdTimerPercentiles, []string{})
+		viper.Set(config.KeyStatsdTimerPercentilesOnly, true)
+
+		expectedErr := errors.New("Invalid StatsD timer percentiles only, no timer percentiles configured")
+		err := validateStatsdOptions()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+		if err.Error() != expectedErr.Error() {
+			t.Errorf("Expected (%s) got (%s)", expectedErr, err)
+		}
+	}
+
+	t.Log("Timer percentiles, valid (50,95,99.9)")
+	{
+		viper.Set(config.KeyStatsdTimerPercentiles, []string{"50", "95", "99.9"})
+		viper.Set(config.KeyStatsdTimerPercentilesOnly, true)
+
+		err := validateStatsdOptions()
+		if err != nil {
+			t.Fatalf("Expected NO error, got (%v)", err)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestState(t *testing.T) {
+	t.Log("Testing State")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Set(config.KeyStatsdDisabled, true)
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
 	viper.Reset()
+
+	atomic.AddUint64(&s.packetsTotal, 2)
+	atomic.AddUint64(&s.packetsBad, 1)
+
+	state := s.State()
+	if disabled, ok := state["disabled"].(bool); !ok || !disabled {
+		t.Fatalf("expected disabled true, got (%#v)", state["disabled"])
+	}
+	if n, ok := state["packets_total"].(uint64); !ok || n != 2 {
+		t.Fatalf("expected packets_total 2, got (%#v)", state["packets_total"])
+	}
+	if n, ok := state["packets_bad"].(uint64); !ok || n != 1 {
+		t.Fatalf("expected packets_bad 1, got (%#v)", state["packets_bad"])
+	}
+	if _, ok := state["address"]; ok {
+		t.Fatal("expected no address when disabled")
+	}
 }
@@ -7,8 +7,12 @@ package statsd
 
 import (
 	"bytes"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/circonus-labs/circonus-agent/internal/config"
 	"github.com/circonus-labs/circonus-agent/internal/tags"
@@ -26,8 +30,16 @@ func (s *Server) processPacket(pkt []byte) error {
 	s.logger.Debug().Str("packet", string(pkt)).Msg("received")
 	metrics := bytes.Split(pkt, []byte("\n"))
 	for _, metric := range metrics {
+		if !s.strictLines {
+			// tolerate \r\n delimiters, surrounding whitespace and blank lines
+			metric = bytes.TrimSpace(metric)
+			if len(metric) == 0 {
+				continue
+			}
+		}
 		if err := s.parseMetric(string(metric)); err != nil {
 			appstats.IncrementInt("statsd_metrics_bad")
+			atomic.AddUint64(&s.metricsBad, 1)
 			s.logger.Warn().Err(err).Str("metric", string(metric)).Msg("parsing")
 		}
 	}
@@ -71,69 +83,222 @@ func (s *Server) getMetricDestination(metricName string) (string, string) {
 	return destIgnore, metricName
 }
 
+// groupTagRx validates the group tag, a single category:value tag
+var groupTagRx = regexp.MustCompile(`^[^:,|\s]+:[^,|\s]+$`)
+
+// histogramBinRx matches a pre-aggregated circonus histogram bin, H[value]=count
+var histogramBinRx = regexp.MustCompile(`^H\[([^\]]+)\]=([0-9]+)$`)
+
+// hostTagged determines if a metric's tag list includes the host tag (if
+// configured) with a value, identifying the host which sent the metric
+func (s *Server) hostTagged(metricTags string) bool {
+	if s.hostTag == "" || metricTags == "" {
+		return false
+	}
+	for _, t := range strings.Split(metricTags, tags.Separator) {
+		if strings.HasPrefix(t, s.hostTag+":") && len(t) > len(s.hostTag)+1 {
+			return true
+		}
+	}
+	return false
+}
+
+// stripGroupTag removes the group tag (if present) from a metric's tag list,
+// returning the remaining tags and whether the group tag was present
+func (s *Server) stripGroupTag(metricTags string) (string, bool) {
+	if s.groupTag == "" || metricTags == "" {
+		return metricTags, false
+	}
+	found := false
+	kept := []string{}
+	for _, t := range strings.Split(metricTags, tags.Separator) {
+		if t == s.groupTag {
+			found = true
+			continue
+		}
+		kept = append(kept, t)
+	}
+	if !found {
+		return metricTags, false
+	}
+	return strings.Join(kept, tags.Separator), true
+}
+
+// trimDestPrefix removes the host or group prefix (if any) from a metric name
+func (s *Server) trimDestPrefix(metricName string) string {
+	if s.hostPrefix != "" && strings.HasPrefix(metricName, s.hostPrefix) {
+		return strings.TrimPrefix(metricName, s.hostPrefix)
+	}
+	if s.groupPrefix != "" && strings.HasPrefix(metricName, s.groupPrefix) {
+		return strings.TrimPrefix(metricName, s.groupPrefix)
+	}
+	return metricName
+}
+
+// valueSegment is a single value|type[|@rate] segment of a metric line
+type valueSegment struct {
+	value string
+	mtype string
+	rate  string
+}
+
+// parsedMetric is a metric line parsed, its values not yet recorded
+type parsedMetric struct {
+	name       string // normalized, including stream tags
+	dest       *cgm.CirconusMetrics
+	metricDest string
+	values     []valueSegment
+	filtered   bool // denied by the metric filters (only name is set)
+}
+
 func (s *Server) parseMetric(metric string) error {
 	// ignore 'blank' lines/empty strings
 	if len(metric) == 0 {
 		return nil
 	}
 
+	pm, err := s.parseLine(metric)
+	if err != nil {
+		return err
+	}
+
+	if pm.filtered {
+		appstats.IncrementInt("statsd_metrics_filtered")
+		atomic.AddUint64(&s.metricsFiltered, 1)
+		s.logger.Debug().Str("metric", metric).Msg("filtered")
+		return nil
+	}
+
+	// all values are parsed before any are applied, an invalid value
+	// rejects the whole line (a packed line is not partially applied)
+	parsed := make([]interface{}, len(pm.values))
+	for i, mv := range pm.values {
+		v, err := s.parseValue(mv)
+		if err != nil {
+			return err
+		}
+		parsed[i] = v
+	}
+
+	// values are applied in order
+	for i, mv := range pm.values {
+		s.recordValue(pm.dest, pm.metricDest, pm.name, mv.mtype, parsed[i])
+
+		s.logger.Debug().
+			Str("metric", metric).
+			Str("Name", pm.name).
+			Str("Type", mv.mtype).
+			Str("Value", mv.value).
+			Str("Destination", pm.metricDest).
+			Msg("parsing")
+	}
+
+	return nil
+}
+
+// parseLine parses a metric line, determining the metric name and destination
+func (s *Server) parseLine(metric string) (*parsedMetric, error) {
 	metricName := ""
-	metricType := ""
-	metricValue := ""
-	metricRate := ""
-	sampleRate := 0.0
 	metricTags := ""
+	values := []valueSegment{}
 
-	if !s.metricRegex.MatchString(metric) {
-		return errors.Errorf("invalid metric format '%s', ignoring", metric)
-	}
-
-	for _, match := range s.metricRegex.FindAllStringSubmatch(metric, -1) {
-		for gIdx, matchVal := range match {
-			switch s.metricRegexGroupNames[gIdx] {
-			case "name":
-				metricName = matchVal
-			case "type":
-				metricType = matchVal
-			case "value":
-				metricValue = matchVal
-			case "sample":
-				metricRate = matchVal
-			case "tags":
-				metricTags = matchVal
-			default:
-				// ignore any other groups
+	switch {
+	case s.metricRegex.MatchString(metric):
+		v := valueSegment{}
+		for _, match := range s.metricRegex.FindAllStringSubmatch(metric, -1) {
+			for gIdx, matchVal := range match {
+				switch s.metricRegexGroupNames[gIdx] {
+				case "name":
+					metricName = matchVal
+				case "type":
+					v.mtype = matchVal
+				case "value":
+					v.value = matchVal
+				case "sample":
+					v.rate = matchVal
+				case "tags":
+					metricTags = matchVal
+				default:
+					// ignore any other groups
+				}
+			}
+		}
+		values = append(values, v)
+	case s.packedRegex.MatchString(metric):
+		// packed, multiple values for the same metric name:v1|t1[|@r1]:v2|t2[|@r2]...
+		packed := ""
+		for _, match := range s.packedRegex.FindAllStringSubmatch(metric, -1) {
+			for gIdx, matchVal := range match {
+				switch s.packedRegexGroupNames[gIdx] {
+				case "name":
+					metricName = matchVal
+				case "values":
+					packed = matchVal
+				case "tags":
+					metricTags = matchVal
+				default:
+					// ignore any other groups
+				}
 			}
 		}
+		for _, segment := range strings.Split(packed, ":") {
+			parts := strings.Split(segment, "|")
+			v := valueSegment{value: parts[0], mtype: parts[1]}
+			if len(parts) > 2 {
+				v.rate = strings.TrimPrefix(parts[2], "@")
+			}
+			values = append(values, v)
+		}
+	default:
+		return nil, errors.Errorf("invalid metric format '%s', ignoring", metric)
 	}
 
-	if metricName == "" || metricValue == "" {
-		return errors.Errorf("empty metric name (%s) or metric value (%s) - metricRegex failed, check", metricName, metricValue)
+	for _, v := range values {
+		if metricName == "" || v.value == "" {
+			return nil, errors.Errorf("empty metric name (%s) or metric value (%s) - metricRegex failed, check", metricName, v.value)
+		}
 	}
 
-	if metricRate != "" {
-		r, err := strconv.ParseFloat(metricRate, 32)
-		if err != nil {
-			return errors.Errorf("invalid metric sampling rate (%s), ignoring", err)
-		}
-		sampleRate = r
+	// filtered on the name as received, before routing and normalization
+	if !s.allowed(metricName) {
+		return &parsedMetric{name: metricName, filtered: true}, nil
 	}
 
 	var (
 		dest       *cgm.CirconusMetrics
 		metricDest string
 	)
-	metricDest, metricName = s.getMetricDestination(metricName)
-
-	if metricDest == destGroup {
-		dest = s.groupMetrics
-	} else if metricDest == destHost {
-		dest = s.hostMetrics
+	groupTagged := false
+	if s.routing == routeTag || s.routing == routeBoth {
+		metricTags, groupTagged = s.stripGroupTag(metricTags)
+	}
+	switch {
+	case s.hostTagged(metricTags):
+		// metrics from other hosts (aggregator deployments) are never merged
+		// into the group, the host tag (a stream tag) keeps them distinct
+		metricDest, metricName = destHost, s.trimDestPrefix(metricName)
+	case groupTagged:
+		metricDest = destGroup
+		if s.routing == routeBoth {
+			metricName = s.trimDestPrefix(metricName)
+		}
+	case s.routing == routeTag:
+		metricDest = destHost // prefixes are not used when routing only by tag
+	default:
+		metricDest, metricName = s.getMetricDestination(metricName)
 	}
 
+	dest = s.metricsFor(metricDest)
+
 	if dest == nil {
-		return errors.Errorf("invalid metric destination (%s)->(%s)", metric, metricDest)
+		return nil, errors.Errorf("invalid metric destination (%s)->(%s)", metric, metricDest)
+	}
+
+	name, err := s.normalize("metric name", metricName)
+	if err != nil {
+		return nil, err
 	}
+	metricName = name
 
 	if metricTags != "" {
 		t, err := tags.PrepStreamTags(metricTags)
@@ -145,67 +310,211 @@ func (s *Server) parseMetric(metric string) error {
 		}
 	}
 
-	switch metricType {
+	return &parsedMetric{
+		name:       metricName,
+		dest:       dest,
+		metricDest: metricDest,
+		values:     values,
+	}, nil
+}
+
+// histogramValue is a histogram (or timer) sample, recorded count times
+type histogramValue struct {
+	Value float64 `json:"value"`
+	Count int64   `json:"count"`
+}
+
+// applyValue records a single metric value in the destination
+func (s *Server) applyValue(dest *cgm.CirconusMetrics, metricDest, metricName string, mv valueSegment) error {
+	v, err := s.parseValue(mv)
+	if err != nil {
+		return err
+	}
+	s.recordValue(dest, metricDest, metricName, mv.mtype, v)
+	return nil
+}
+
+// recordValue records a parsed metric value in the destination
+func (s *Server) recordValue(dest *cgm.CirconusMetrics, metricDest, metricName, mtype string, v interface{}) {
+	if v == nil {
+		return // dropped
+	}
+
+	switch mtype {
+	case "c": // counter
+		s.trackCounter(metricDest, metricName)
+		s.counter(dest, metricDest, metricName, v.(uint64))
+	case "g": // gauge
+		s.gauge(dest, metricDest, metricName, v)
+	case "h", "ms": // histogram (circonus), measurement
+		hv := v.(histogramValue)
+		// host timers are also buffered for percentiles, if enabled
+		if mtype == "ms" && metricDest == destHost && s.timers != nil {
+			s.timer(metricName, hv.Value, hv.Count)
+			if s.timerPercentilesOnly {
+				break
+			}
+		}
+		if hv.Count == 1 {
+			dest.RecordValue(metricName, hv.Value)
+		} else {
+			dest.RecordCountForValue(metricName, hv.Value, hv.Count)
+		}
+	case "s": // set
+		// in the case of sets, the value is the unique "thing" to be tracked
+		// counters are used to track individual "things"
+		setName := s.setMetricName(metricName, v.(string))
+		if !s.groupSet(metricDest, setName) {
+			s.counter(dest, metricDest, setName, 1)
+		}
+	case "t": // text (circonus)
+		dest.SetText(metricName, v.(string))
+	}
+}
+
+// parseValue parses a single metric value according to its type, applying
+// the sample rate: uint64 (counter), int64, uint64 or float64 (gauge),
+// histogramValue (histogram, timer) or string (set, text). A nil value
+// without an error means the value is dropped (e.g. zero counters).
+func (s *Server) parseValue(mv valueSegment) (interface{}, error) {
+	sampleRate := 0.0
+	if mv.rate != "" {
+		r, err := strconv.ParseFloat(mv.rate, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid metric sampling rate (%s), ignoring", err)
+		}
+		sampleRate = r
+	}
+
+	metricValue := mv.value
+
+	switch mv.mtype {
 	case "c": // counter
 		v, err := strconv.ParseUint(metricValue, 10, 64)
 		if err != nil {
-			return errors.Wrap(err, "invalid counter value")
+			return nil, errors.Wrap(err, "invalid counter value")
 		}
 		if v == 0 {
-			v = 1
+			switch s.zeroCounter {
+			case zeroCounterDrop:
+				return nil, nil
+			case zeroCounterOne:
+				v = 1
+			}
 		}
 		if sampleRate > 0 {
 			v = uint64(float64(v) * (1 / sampleRate))
 		}
-		dest.IncrementByValue(metricName, v)
+		return v, nil
 	case "g": // gauge
 		if strings.Contains(metricValue, ".") {
 			v, err := strconv.ParseFloat(metricValue, 64)
 			if err != nil {
-				return errors.Wrap(err, "invalid gauge value")
+				return nil, errors.Wrap(err, "invalid gauge value")
 			}
-			dest.Gauge(metricName, v)
+			return v, nil
 		} else if strings.Contains(metricValue, "-") {
 			v, err := strconv.ParseInt(metricValue, 10, 64)
 			if err != nil {
-				return errors.Wrap(err, "invalid gauge value")
+				return nil, errors.Wrap(err, "invalid gauge value")
+			}
+			return v, nil
+		}
+		v, err := strconv.ParseUint(metricValue, 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gauge value")
+		}
+		return v, nil
+	case "h", "ms": // histogram (circonus), measurement
+		hv := histogramValue{Count: 1}
+		if strings.HasPrefix(metricValue, "H[") {
+			// pre-aggregated bin, the value is recorded count times in one call
+			bv, bn, err := parseHistogramBin(metricValue)
+			if err != nil {
+				return nil, err
+			}
+			hv.Value, hv.Count = bv, bn
+			if sampleRate > 0 {
+				hv.Count = int64(float64(hv.Count) / sampleRate)
 			}
-			dest.Gauge(metricName, v)
 		} else {
-			v, err := strconv.ParseUint(metricValue, 10, 64)
+			pv, err := strconv.ParseFloat(metricValue, 64)
 			if err != nil {
-				return errors.Wrap(err, "invalid gauge value")
+				return nil, errors.Wrap(err, "invalid histogram value")
+			}
+			hv.Value = pv
+			if sampleRate > 0 {
+				hv.Value /= sampleRate
 			}
-			dest.Gauge(metricName, v)
 		}
-	case "h": // histogram (circonus)
-		fallthrough
-	case "ms": // measurement
-		v, err := strconv.ParseFloat(metricValue, 64)
+		return hv, nil
+	case "s": // set
+		v, err := s.normalize("set value", metricValue)
 		if err != nil {
-			return errors.Wrap(err, "invalid histogram value")
+			return nil, err
 		}
-		if sampleRate > 0 {
-			v /= sampleRate
-		}
-		dest.RecordValue(metricName, v)
-	case "s": // set
-		// in the case of sets, the value is the unique "thing" to be tracked
-		// counters are used to track individual "things"
-		dest.Increment(strings.Join([]string{metricName, metricValue}, config.MetricNameSeparator))
+		return s.setMember(v)
 	case "t": // text (circonus)
-		dest.SetText(metricName, metricValue)
+		return metricValue, nil
 	default:
-		return errors.Errorf("invalid metric type (%s)", metricType)
+		return nil, errors.Errorf("invalid metric type (%s)", mv.mtype)
 	}
+}
 
-	s.logger.Debug().
-		Str("metric", metric).
-		Str("Name", metricName).
-		Str("Type", metricType).
-		Str("Value", metricValue).
-		Str("Destination", metricDest).
-		Msg("parsing")
+// parseHistogramBin parses a pre-aggregated histogram bin (e.g. H[1.2e+01]=5),
+// returning the bin value and the number of samples in the bin
+func parseHistogramBin(bin string) (float64, int64, error) {
+	m := histogramBinRx.FindStringSubmatch(bin)
+	if m == nil {
+		return 0, 0, errors.Errorf("invalid histogram bin (%s), expected H[value]=count", bin)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid histogram bin value")
+	}
+	n, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid histogram bin count")
+	}
+	if n == 0 {
+		return 0, 0, errors.Errorf("invalid histogram bin (%s), count must be greater than 0", bin)
+	}
+	return v, n, nil
+}
 
-	return nil
+// normalize checks a metric name (or set value) for characters which are
+// not valid in a circonus metric name: whitespace, control and other
+// non-printable characters, invalid utf-8, and the metric name separator
+// (backtick) which is used to join set names and values. Invalid
+// characters are replaced with '_' or, if configured, rejected.
+func (s *Server) normalize(what, str string) (string, error) {
+	valid := true
+	for _, r := range str {
+		if !validMetricRune(r) {
+			valid = false
+			break
+		}
+	}
+	if valid {
+		return str, nil
+	}
+
+	if s.rejectInvalid {
+		return "", errors.Errorf("invalid character(s) in %s (%q)", what, str)
+	}
+
+	return strings.Map(func(r rune) rune {
+		if validMetricRune(r) {
+			return r
+		}
+		return invalidCharReplace
+	}, str), nil
+}
+
+// validMetricRune determines if a rune is valid in a metric name
+func validMetricRune(r rune) bool {
+	if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+		return false
+	}
+	return !strings.ContainsRune(config.MetricNameSeparator, r)
 }
@@ -7,6 +7,9 @@ package statsd
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/circonus-labs/circonus-agent/internal/config"
@@ -49,7 +52,43 @@ func TestProcessPacket(t *testing.T) {
 		}
 	}
 
+	t.Log("crlf, whitespace and blank lines")
+	{
+		bad := atomic.LoadUint64(&s.metricsBad)
+		if err := s.processPacket([]byte("foo:1|c\r\n \r\n\nbar:2|g \n\t\r\n")); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if n := atomic.LoadUint64(&s.metricsBad) - bad; n != 0 {
+			t.Fatalf("expected 0 bad metrics, got %d", n)
+		}
+		m := *s.hostMetrics.FlushMetrics()
+		if len(m) != 2 {
+			t.Fatalf("expected 2 metrics, got %v", m)
+		}
+	}
+
 	s.listener.Close()
+
+	t.Log("crlf, strict")
+	{
+		viper.Set(config.KeyStatsdStrictLines, true)
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := s.processPacket([]byte("foo:1|c\r\n \r\n\nbar:2|g\n")); err != nil {
+			t.Fatalf("expected no error, got (%s)", err)
+		}
+		if n := atomic.LoadUint64(&s.metricsBad); n != 2 {
+			t.Fatalf("expected 2 bad metrics, got %d", n)
+		}
+		m := *s.hostMetrics.FlushMetrics()
+		if len(m) != 1 {
+			t.Fatalf("expected 1 metric, got %v", m)
+		}
+		s.listener.Close()
+	}
+
 	viper.Reset()
 }
 
@@ -277,6 +316,26 @@ func TestParseMetric(t *testing.T) {
 		{"test:1.0a|h", errors.New(`invalid histogram value: strconv.ParseFloat: parsing "1.0a": invalid syntax`)},
 		{"test:1.0a|ms", errors.New(`invalid histogram value: strconv.ParseFloat: parsing "1.0a": invalid syntax`)},
 		{"test:1|q", errors.New("invalid metric type (q)")},
+		{"test:H[1.2e+01]=5|h", nil},
+		{"test:H[12]=5|h|@.5", nil},
+		{"test:H[1]=5|h:H[2]=3|h", nil},
+		{"test:H[1]=0|h", errors.New("invalid histogram bin (H[1]=0), count must be greater than 0")},
+		{"test:H[1]=x|h", errors.New("invalid histogram bin (H[1]=x), expected H[value]=count")},
+		{"test:H[a]=1|h", errors.New(`invalid histogram bin value: strconv.ParseFloat: parsing "a": invalid syntax`)},
+		{"test:H[1]=1|ms", nil},
+		{"test:H[12]=1000|ms|@.5", nil},
+		{"test:H[1]=0|ms", errors.New("invalid histogram bin (H[1]=0), count must be greater than 0")},
+		{"test metric:1|c", nil},
+		{"test`metric:1|c", nil},
+		{"tést_métrique:1|c", nil},
+		{"test:a`b|s", nil},
+		{"test:1|c:2|c", nil},
+		{"test:1|c|@.1:2|c", nil},
+		{"test:1|c:2|g:1.5|ms|#c:v", nil},
+		{"test:1|c:2.5|c", errors.New(`invalid counter value: strconv.ParseUint: parsing "2.5": invalid syntax`)},
+		{"test:1|c:2|q", errors.New("invalid metric type (q)")},
+		{"invalid-packed:1|c:2", errors.New("invalid metric format 'invalid-packed:1|c:2', ignoring")},
+		{"invalid-packed:1|c::2|c", errors.New("invalid metric format 'invalid-packed:1|c::2|c', ignoring")},
 	}
 
 	for _, mt := range mtests {
@@ -298,3 +357,388 @@ func TestParseMetric(t *testing.T) {
 
 	s.listener.Close()
 }
+
+func TestParseMetricPacked(t *testing.T) {
+	t.Log("Testing parseMetric (packed)")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65125")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+
+	if err := s.initHostMetrics(); err != nil {
+		t.Fatalf("initHostMetrics %s", err)
+	}
+
+	if err := s.parseMetric("test:1|c:2|c:3|c|@.5"); err != nil {
+		t.Fatalf("expected nil, got (%s)", err)
+	}
+	if err := s.parseMetric("gtest:1|g:2|g"); err != nil {
+		t.Fatalf("expected nil, got (%s)", err)
+	}
+
+	m := s.hostMetrics.FlushMetrics()
+
+	counter, ok := (*m)["test"]
+	if !ok {
+		t.Fatalf("expected test metric, got %#v", *m)
+	}
+	if counter.Value.(uint64) != 9 {
+		t.Fatalf("expected 9 (1+2+3/.5), got %v", counter.Value)
+	}
+
+	gauge, ok := (*m)["gtest"]
+	if !ok {
+		t.Fatalf("expected gtest metric, got %#v", *m)
+	}
+	if fmt.Sprintf("%v", gauge.Value) != "2" {
+		t.Fatalf("expected last gauge value 2, got %v", gauge.Value)
+	}
+
+	t.Log("\tinvalid value rejects the whole line")
+	{
+		if err := s.parseMetric("ptest:1|c:2|c:2.5|c"); err == nil {
+			t.Fatal("expected error")
+		}
+		m := s.hostMetrics.FlushMetrics()
+		if _, ok := (*m)["ptest"]; ok {
+			t.Fatalf("expected no ptest metric, got %#v", *m)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestParseMetricZeroCounter(t *testing.T) {
+	t.Log("Testing parseMetric (zero counter)")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65125")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+
+	if err := s.initHostMetrics(); err != nil {
+		t.Fatalf("initHostMetrics %s", err)
+	}
+
+	tests := []struct {
+		mode   string
+		metric string
+		found  bool
+		expect uint64
+	}{
+		{"", "test:0|c", true, 0},
+		{zeroCounterZero, "test:0|c", true, 0},
+		{zeroCounterZero, "test:0|c|@.1", true, 0},
+		{zeroCounterDrop, "test:0|c", false, 0},
+		{zeroCounterDrop, "test:0|c|@.1", false, 0},
+		{zeroCounterOne, "test:0|c", true, 1},
+		{zeroCounterOne, "test:0|c|@.5", true, 2},
+		{zeroCounterDrop, "test:2|c|@.5", true, 4},
+	}
+
+	for _, tt := range tests {
+		t.Logf("	%s %q", tt.mode, tt.metric)
+		s.zeroCounter = tt.mode
+		if err := s.parseMetric(tt.metric); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		m := s.hostMetrics.FlushMetrics()
+		counter, ok := (*m)["test"]
+		if ok != tt.found {
+			t.Fatalf("expected found %v, got %#v", tt.found, *m)
+		}
+		if !ok {
+			continue
+		}
+		if counter.Value.(uint64) != tt.expect {
+			t.Fatalf("expected %d, got %v", tt.expect, counter.Value)
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestParseMetricHostTag(t *testing.T) {
+	t.Log("Testing parseMetric (host tag)")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	viper.Reset()
+	viper.Set(config.KeyStatsdDisabled, false)
+	viper.Set(config.KeyStatsdPort, "65125")
+	viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+	viper.Set(config.KeyStatsdHostTag, "host")
+	s, err := New()
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer s.listener.Close()
+
+	if err := s.initHostMetrics(); err != nil {
+		t.Fatalf("initHostMetrics %s", err)
+	}
+	s.groupMetrics = s.hostMetrics // no group check in tests, destination verified by name
+	s.groupPrefix = "group."
+
+	t.Log("\tgroup prefixed, host tagged")
+	{
+		if err := s.parseMetric("group.requests:1|c|#host:web1"); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		if err := s.parseMetric("group.requests:2|c|#host:web2"); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		m := s.hostMetrics.FlushMetrics()
+		if len(*m) != 2 {
+			t.Fatalf("expected 2 distinct metrics, got %#v", *m)
+		}
+		for n := range *m {
+			if strings.HasPrefix(n, "group.") || !strings.HasPrefix(n, "requests|ST[") {
+				t.Fatalf("expected prefix removed and stream tags, got (%s)", n)
+			}
+		}
+	}
+
+	t.Log("\tsimilar tag category, not host tagged")
+	{
+		s.groupPrefix = "grp."
+		if err := s.parseMetric("grp.requests:1|c|#hostname:web1"); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		m := s.hostMetrics.FlushMetrics()
+		if len(*m) != 1 {
+			t.Fatalf("expected 1 metric, got %#v", *m)
+		}
+		for n := range *m {
+			if !strings.HasPrefix(n, "requests|ST[") {
+				t.Fatalf("expected group destination (prefix removed), got (%s)", n)
+			}
+		}
+	}
+
+	t.Log("\tother tags only")
+	{
+		if s.hostTagged("env:prod,hostname:web1") {
+			t.Fatal("expected false")
+		}
+		if !s.hostTagged("env:prod,host:web1") {
+			t.Fatal("expected true")
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestParseMetricGroupTag(t *testing.T) {
+	t.Log("Testing parseMetric (group tag routing)")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	newServer := func(routing string) *Server {
+		viper.Reset()
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "65125")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		viper.Set(config.KeyStatsdHostPrefix, "host.")
+		viper.Set(config.KeyStatsdGroupPrefix, "group.")
+		viper.Set(config.KeyStatsdGroupTag, defaults.StatsdGroupTag)
+		viper.Set(config.KeyStatsdHostTag, "host")
+		viper.Set(config.KeyStatsdRouting, routing)
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		s.listener.Close()
+		// a second manual mode instance stands in for the group check
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("initHostMetrics %s", err)
+		}
+		s.groupMetrics = s.hostMetrics
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("initHostMetrics %s", err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		desc    string
+		routing string
+		metric  string
+		dest    string
+		name    string
+	}{
+		{"tagged", routeBoth, "foo:1|c|#scope:group,env:prod", destGroup, "foo|ST[env:prod]"},
+		{"tagged w/prefix", routeBoth, "group.bar:1|c|#scope:group", destGroup, "bar"},
+		{"group prefix", routeBoth, "group.baz:1|c", destGroup, "baz"},
+		{"host prefix", routeBoth, "host.qux:1|c", destHost, "qux"},
+		{"no prefix or tag", routeBoth, "quux:1|c", destIgnore, ""},
+		{"tag only, tagged", routeTag, "foo:1|c|#scope:group", destGroup, "foo"},
+		{"tag only, prefix not used", routeTag, "group.foo:1|c", destHost, "group.foo"},
+		{"tag only, host tag", routeTag, "foo:1|c|#scope:group,host:web1", destHost, "foo|ST[host:web1]"},
+		{"prefix only, tag not used", routePrefix, "group.foo:1|c|#scope:group", destGroup, "foo|ST[scope:group]"},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s (%s) %s", test.desc, test.routing, test.metric)
+		s := newServer(test.routing)
+		if test.dest == destIgnore {
+			if err := s.parseMetric(test.metric); err == nil {
+				t.Fatal("expected error (ignored)")
+			}
+			continue
+		}
+		if err := s.parseMetric(test.metric); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		host := s.hostMetrics.FlushMetrics()
+		group := s.groupMetrics.FlushMetrics()
+		switch test.dest {
+		case destHost:
+			if _, ok := (*host)[test.name]; !ok || len(*group) != 0 {
+				t.Fatalf("expected host %s, got host %v group %v", test.name, *host, *group)
+			}
+		case destGroup:
+			if _, ok := (*group)[test.name]; !ok || len(*host) != 0 {
+				t.Fatalf("expected group %s, got host %v group %v", test.name, *host, *group)
+			}
+		}
+	}
+
+	viper.Reset()
+}
+
+func TestNormalize(t *testing.T) {
+	t.Log("Testing normalize")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tests := []struct {
+		name     string
+		in       string
+		sanitize string
+	}{
+		{"valid", "foo.bar_baz-1", "foo.bar_baz-1"},
+		{"space", "foo bar", "foo_bar"},
+		{"tab", "foo\tbar", "foo_bar"},
+		{"backtick", "foo`bar", "foo_bar"},
+		{"unicode", "temp°C_größe", "temp°C_größe"},
+		{"unicode space", "foo\u00a0bar", "foo_bar"},
+		{"control", "foo\x00bar", "foo_bar"},
+		{"invalid utf-8", "foo\xffbar", "foo_bar"},
+	}
+
+	t.Log("sanitize")
+	{
+		s := &Server{}
+		for _, tst := range tests {
+			t.Logf("\t%s", tst.name)
+			got, err := s.normalize("metric name", tst.in)
+			if err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			if got != tst.sanitize {
+				t.Fatalf("expected (%q) got (%q)", tst.sanitize, got)
+			}
+		}
+	}
+
+	t.Log("reject")
+	{
+		s := &Server{rejectInvalid: true}
+		for _, tst := range tests {
+			t.Logf("\t%s", tst.name)
+			got, err := s.normalize("metric name", tst.in)
+			if tst.in == tst.sanitize {
+				if err != nil {
+					t.Fatalf("expected NO error, got (%s)", err)
+				}
+				if got != tst.in {
+					t.Fatalf("expected (%q) got (%q)", tst.in, got)
+				}
+				continue
+			}
+			if err == nil {
+				t.Fatalf("expected error for (%q)", tst.in)
+			}
+		}
+	}
+
+	t.Log("reject set value via parseMetric")
+	{
+		viper.Reset()
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "65125")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		viper.Set(config.KeyStatsdInvalidChars, "reject")
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		defer s.listener.Close()
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("initHostMetrics %s", err)
+		}
+
+		expect := errors.New(`invalid character(s) in set value ("a` + "`" + `b")`)
+		err = s.parseMetric("test:a`b|s")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if err.Error() != expect.Error() {
+			t.Fatalf("expected (%s) got (%s)", expect, err)
+		}
+		viper.Reset()
+	}
+}
+
+func TestParseHistogramBin(t *testing.T) {
+	t.Log("Testing parseHistogramBin")
+
+	tests := []struct {
+		bin    string
+		value  float64
+		count  int64
+		expErr bool
+	}{
+		{"H[1.2e+01]=5", 12, 5, false},
+		{"H[-3]=1", -3, 1, false},
+		{"H[0.5]=100", 0.5, 100, false},
+		{"H[1]=0", 0, 0, true},
+		{"H[1]=-1", 0, 0, true},
+		{"H[]=1", 0, 0, true},
+		{"H[1]", 0, 0, true},
+		{"H[1]=99999999999999999999", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s", test.bin)
+		v, n, err := parseHistogramBin(test.bin)
+		if test.expErr {
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if v != test.value || n != test.count {
+			t.Fatalf("expected %v=%d, got %v=%d", test.value, test.count, v, n)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"sync"
+)
+
+// queueStats tracks the depth of the packet queue, sampled as each packet
+// is queued, to help size the queue (see --statsd-queue-size)
+type queueStats struct {
+	sync.Mutex
+	capacity  int
+	highWater int // deepest queue since start
+	max       int // deepest queue since the last flush
+	samples   uint64
+	sum       uint64
+	warned    bool // high water mark warning logged
+}
+
+func newQueueStats(capacity int) *queueStats {
+	return &queueStats{capacity: capacity}
+}
+
+// sample records the current queue depth, it returns true the first time
+// the high water mark reaches queueWarnRatio of the queue capacity
+func (q *queueStats) sample(depth int) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	q.samples++
+	q.sum += uint64(depth)
+	if depth > q.max {
+		q.max = depth
+	}
+	if depth > q.highWater {
+		q.highWater = depth
+	}
+
+	if !q.warned && float64(q.highWater) >= queueWarnRatio*float64(q.capacity) {
+		q.warned = true
+		return true
+	}
+	return false
+}
+
+// flush returns the maximum and average queue depth since the last flush,
+// and the high water mark, the per flush values are reset
+func (q *queueStats) flush() (int, float64, int) {
+	q.Lock()
+	defer q.Unlock()
+
+	max := q.max
+	avg := float64(0)
+	if q.samples > 0 {
+		avg = float64(q.sum) / float64(q.samples)
+	}
+	q.max = 0
+	q.samples = 0
+	q.sum = 0
+
+	return max, avg, q.highWater
+}
+
+// peak returns the high water mark
+func (q *queueStats) peak() int {
+	q.Lock()
+	defer q.Unlock()
+	return q.highWater
+}
+
+// sampleQueue records the depth of the packet queue, logging a warning once
+// the queue has nearly filled (the reader blocks when it is full)
+func (s *Server) sampleQueue() {
+	if s.queue == nil {
+		return
+	}
+	if s.queue.sample(len(s.packetCh)) {
+		s.logger.Warn().
+			Int("high_water", s.queue.peak()).
+			Int("queue_size", cap(s.packetCh)).
+			Msg("packet queue near capacity, packets may be dropped by the OS, increase --statsd-queue-size")
+	}
+}
+
+// flushQueueMetrics adds the packet queue depth gauges to the host metrics
+func (s *Server) flushQueueMetrics() {
+	if !s.queueMetrics || s.queue == nil || s.hostMetrics == nil {
+		return
+	}
+	max, avg, highWater := s.queue.flush()
+	s.hostMetrics.Gauge(queueDepthMaxMetric, max)
+	s.hostMetrics.Gauge(queueDepthAvgMetric, avg)
+	s.hostMetrics.Gauge(queueDepthHighWaterMetric, highWater)
+}
@@ -0,0 +1,113 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestQueueStats(t *testing.T) {
+	t.Log("Testing queueStats")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	q := newQueueStats(10)
+
+	t.Log("\tno samples")
+	{
+		max, avg, highWater := q.flush()
+		if max != 0 || avg != 0 || highWater != 0 {
+			t.Fatalf("expected 0, 0, 0 got %d, %f, %d", max, avg, highWater)
+		}
+	}
+
+	t.Log("\tsamples")
+	{
+		for _, depth := range []int{1, 4, 2, 1} {
+			if q.sample(depth) {
+				t.Fatalf("expected no warning at depth %d", depth)
+			}
+		}
+		max, avg, highWater := q.flush()
+		if max != 4 || avg != 2 || highWater != 4 {
+			t.Fatalf("expected 4, 2, 4 got %d, %f, %d", max, avg, highWater)
+		}
+	}
+
+	t.Log("\treset after flush, high water kept")
+	{
+		q.sample(3)
+		max, avg, highWater := q.flush()
+		if max != 3 || avg != 3 || highWater != 4 {
+			t.Fatalf("expected 3, 3, 4 got %d, %f, %d", max, avg, highWater)
+		}
+	}
+
+	t.Log("\tnear capacity, warn once")
+	{
+		if !q.sample(9) {
+			t.Fatal("expected warning")
+		}
+		if q.sample(10) {
+			t.Fatal("expected warning only once")
+		}
+		if q.peak() != 10 {
+			t.Fatalf("expected high water 10, got %d", q.peak())
+		}
+	}
+}
+
+func TestFlushQueueMetrics(t *testing.T) {
+	t.Log("Testing flushQueueMetrics")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tdisabled")
+	{
+		s := Server{packetCh: make(chan []byte, 10), queue: newQueueStats(10)}
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		s.packetCh <- []byte("foo:1|c")
+		s.sampleQueue()
+		s.flushQueueMetrics()
+		m := s.hostMetrics.FlushMetrics()
+		if len(*m) != 0 {
+			t.Fatalf("expected no metrics, got %#v", *m)
+		}
+	}
+
+	t.Log("\tenabled")
+	{
+		s := Server{packetCh: make(chan []byte, 10), queue: newQueueStats(10), queueMetrics: true}
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		s.packetCh <- []byte("foo:1|c")
+		s.sampleQueue()
+		s.packetCh <- []byte("foo:1|c")
+		s.sampleQueue()
+		s.flushQueueMetrics()
+		m := s.hostMetrics.FlushMetrics()
+		expect := map[string]string{
+			queueDepthMaxMetric:       "2",
+			queueDepthAvgMetric:       "1.5",
+			queueDepthHighWaterMetric: "2",
+		}
+		for mn, ev := range expect {
+			v, ok := (*m)[mn]
+			if !ok {
+				t.Fatalf("expected %s, got %#v", mn, *m)
+			}
+			if fmt.Sprintf("%v", v.Value) != ev {
+				t.Fatalf("expected %s=%s, got %v", mn, ev, v.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket rate limiter keyed by packet source (ip),
+// the number of sources tracked is bounded, the least recently seen source
+// is evicted when a new source arrives and the limiter is full
+type rateLimiter struct {
+	rate       float64 // tokens (packets) added per second
+	burst      float64 // maximum tokens in a bucket
+	maxSources int
+	sources    map[string]*list.Element
+	lru        *list.List // front is most recently seen
+	sync.Mutex
+}
+
+// sourceBucket is the token bucket for a single source
+type sourceBucket struct {
+	source string
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a rate limiter allowing rate packets per second,
+// with bursts up to burst packets, for at most maxSources distinct sources
+func newRateLimiter(rate, burst, maxSources int) *rateLimiter {
+	if burst < rate {
+		burst = rate
+	}
+	return &rateLimiter{
+		rate:       float64(rate),
+		burst:      float64(burst),
+		maxSources: maxSources,
+		sources:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// allow returns whether a packet from source is within the limit
+func (r *rateLimiter) allow(source string, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if e, ok := r.sources[source]; ok {
+		r.lru.MoveToFront(e)
+		b := e.Value.(*sourceBucket)
+		b.tokens += now.Sub(b.last).Seconds() * r.rate
+		if b.tokens > r.burst {
+			b.tokens = r.burst
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return false
+		}
+		b.tokens--
+		return true
+	}
+
+	if r.lru.Len() >= r.maxSources {
+		if e := r.lru.Back(); e != nil {
+			r.lru.Remove(e)
+			delete(r.sources, e.Value.(*sourceBucket).source)
+		}
+	}
+
+	// a new source starts with a full bucket
+	r.sources[source] = r.lru.PushFront(&sourceBucket{
+		source: source,
+		tokens: r.burst - 1,
+		last:   now,
+	})
+
+	return true
+}
+
+// len returns the number of sources currently tracked
+func (r *rateLimiter) len() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.lru.Len()
+}
+
+// throttledMetricName returns the host metric counting packets dropped for
+// a source, ':' (ipv6) is not valid in a stream tag value and is replaced
+func throttledMetricName(source string) string {
+	return throttledMetric + "|ST[source:" + strings.Replace(source, ":", "_", -1) + "]"
+}
@@ -0,0 +1,111 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Log("Testing rateLimiter")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	now := time.Now()
+
+	t.Log("\tburst, refill")
+	{
+		r := newRateLimiter(10, 20, 10)
+		for i := 0; i < 20; i++ {
+			if !r.allow("a", now) {
+				t.Fatalf("expected packet %d allowed", i)
+			}
+		}
+		if r.allow("a", now) {
+			t.Fatal("expected packet throttled")
+		}
+		if !r.allow("b", now) {
+			t.Fatal("expected other source allowed")
+		}
+		// 10/s, 100ms adds one token
+		if !r.allow("a", now.Add(100*time.Millisecond)) {
+			t.Fatal("expected packet allowed after refill")
+		}
+		if r.allow("a", now.Add(100*time.Millisecond)) {
+			t.Fatal("expected packet throttled")
+		}
+	}
+
+	t.Log("\tburst defaults to rate")
+	{
+		r := newRateLimiter(2, 0, 10)
+		if !r.allow("a", now) || !r.allow("a", now) {
+			t.Fatal("expected packets allowed")
+		}
+		if r.allow("a", now) {
+			t.Fatal("expected packet throttled")
+		}
+	}
+
+	t.Log("\tbounded sources")
+	{
+		r := newRateLimiter(1, 1, 2)
+		r.allow("a", now)
+		r.allow("b", now)
+		r.allow("c", now) // evicts a (least recently seen)
+		if r.len() != 2 {
+			t.Fatalf("expected 2 sources, got %d", r.len())
+		}
+		if !r.allow("a", now) {
+			t.Fatal("expected evicted source to start with a full bucket")
+		}
+		if r.allow("c", now) {
+			t.Fatal("expected tracked source throttled")
+		}
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	t.Log("Testing throttled")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	src := &net.UDPAddr{IP: net.ParseIP("::1")}
+
+	t.Log("\tdisabled")
+	{
+		s := Server{}
+		if s.throttled(src) {
+			t.Fatal("expected not throttled")
+		}
+	}
+
+	t.Log("\tenabled")
+	{
+		s := Server{limiter: newRateLimiter(1, 1, 10)}
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if s.throttled(src) {
+			t.Fatal("expected not throttled")
+		}
+		if !s.throttled(src) {
+			t.Fatal("expected throttled")
+		}
+		if s.packetsThrottled != 1 {
+			t.Fatalf("expected 1 throttled, got %d", s.packetsThrottled)
+		}
+		m := s.hostMetrics.FlushMetrics()
+		mn := throttledMetric + "|ST[source:__1]"
+		if _, ok := (*m)[mn]; !ok {
+			t.Fatalf("expected %s, got %#v", mn, *m)
+		}
+	}
+}
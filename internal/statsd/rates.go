@@ -0,0 +1,85 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"strings"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+)
+
+// trackCounter records the name of a host counter, if counter rates are
+// enabled. The metrics flushed from cgm do not distinguish counters from
+// gauges (e.g. both may be uint64)
+func (s *Server) trackCounter(metricDest, name string) {
+	if s.counterNames == nil || metricDest != destHost {
+		return
+	}
+	s.counterNamesmu.Lock()
+	s.counterNames[name] = true
+	s.counterNamesmu.Unlock()
+}
+
+// counterRates adds the per second rate of the host counters recorded since
+// the last flush to the flushed metrics (or replaces the counters with the
+// rates, in rate mode). cgm resets counters on each flush, so the flushed
+// value is the count for the interval since the previous flush. There is no
+// interval on the first flush, the rates are skipped.
+func (s *Server) counterRates(metrics *cgm.Metrics, now time.Time) {
+	if s.counterNames == nil || metrics == nil {
+		return
+	}
+
+	s.counterNamesmu.Lock()
+	names := s.counterNames
+	s.counterNames = make(map[string]bool, len(names))
+	last := s.lastFlush
+	s.lastFlush = now
+	s.counterNamesmu.Unlock()
+
+	elapsed := now.Sub(last).Seconds()
+	for name := range names {
+		m, ok := (*metrics)[name]
+		if !ok {
+			continue
+		}
+		if s.counterMode == counterModeRate {
+			delete(*metrics, name)
+		}
+		if last.IsZero() || elapsed <= 0 {
+			continue
+		}
+		v, ok := counterValue(m.Value)
+		if !ok {
+			continue
+		}
+		(*metrics)[rateMetricName(name)] = cgm.Metric{Type: "n", Value: v / elapsed}
+	}
+}
+
+// counterValue returns a flushed counter value as a float64
+func counterValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// rateMetricName returns the gauge name for a counter rate, e.g.
+// foo_per_sec, keeping any stream tags at the end of the name
+func rateMetricName(name string) string {
+	if i := strings.Index(name, "|ST["); i > 0 {
+		return name[:i] + rateMetricSuffix + name[i:]
+	}
+	return name + rateMetricSuffix
+}
@@ -0,0 +1,123 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/rs/zerolog"
+)
+
+func TestCounterRates(t *testing.T) {
+	t.Log("Testing counterRates")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tcount mode (disabled)")
+	{
+		s := Server{counterMode: counterModeCount}
+		s.trackCounter(destHost, "foo")
+		m := cgm.Metrics{"foo": cgm.Metric{Type: "L", Value: uint64(10)}}
+		s.counterRates(&m, time.Now())
+		if len(m) != 1 {
+			t.Fatalf("expected 1 metric, got %#v", m)
+		}
+	}
+
+	t.Log("\tboth mode")
+	{
+		s := Server{counterMode: counterModeBoth, counterNames: make(map[string]bool)}
+		now := time.Now()
+
+		s.trackCounter(destHost, "foo")
+		s.trackCounter(destGroup, "bar")
+		m := cgm.Metrics{
+			"foo": cgm.Metric{Type: "L", Value: uint64(10)},
+			"bar": cgm.Metric{Type: "L", Value: uint64(10)},
+			"baz": cgm.Metric{Type: "L", Value: uint64(10)},
+		}
+		s.counterRates(&m, now)
+		if len(m) != 3 {
+			t.Fatalf("expected no rates on first flush, got %#v", m)
+		}
+
+		s.trackCounter(destHost, "foo")
+		m = cgm.Metrics{
+			"foo": cgm.Metric{Type: "L", Value: uint64(10)},
+			"baz": cgm.Metric{Type: "L", Value: uint64(10)},
+		}
+		s.counterRates(&m, now.Add(5*time.Second))
+		if len(m) != 3 {
+			t.Fatalf("expected 3 metrics, got %#v", m)
+		}
+		if _, ok := m["foo"]; !ok {
+			t.Fatalf("expected counter kept, got %#v", m)
+		}
+		r, ok := m["foo_per_sec"]
+		if !ok {
+			t.Fatalf("expected foo_per_sec, got %#v", m)
+		}
+		if r.Type != "n" || r.Value.(float64) != 2 {
+			t.Fatalf("expected 2/s, got %#v", r)
+		}
+		if _, ok := m["baz_per_sec"]; ok {
+			t.Fatalf("expected no rate for untracked metric, got %#v", m)
+		}
+	}
+
+	t.Log("\trate mode")
+	{
+		s := Server{counterMode: counterModeRate, counterNames: make(map[string]bool)}
+		now := time.Now()
+
+		s.trackCounter(destHost, "foo")
+		m := cgm.Metrics{"foo": cgm.Metric{Type: "L", Value: uint64(10)}}
+		s.counterRates(&m, now)
+		if len(m) != 0 {
+			t.Fatalf("expected counter removed, no rate on first flush, got %#v", m)
+		}
+
+		s.trackCounter(destHost, "foo")
+		m = cgm.Metrics{"foo": cgm.Metric{Type: "L", Value: uint64(30)}}
+		s.counterRates(&m, now.Add(10*time.Second))
+		if len(m) != 1 {
+			t.Fatalf("expected 1 metric, got %#v", m)
+		}
+		if r := m["foo_per_sec"]; r.Value == nil || r.Value.(float64) != 3 {
+			t.Fatalf("expected 3/s, got %#v", m)
+		}
+
+		// not updated since the last flush, not reported
+		m = cgm.Metrics{}
+		s.counterRates(&m, now.Add(20*time.Second))
+		if len(m) != 0 {
+			t.Fatalf("expected no metrics, got %#v", m)
+		}
+	}
+}
+
+func TestRateMetricName(t *testing.T) {
+	t.Log("Testing rateMetricName")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"foo", "foo_per_sec"},
+		{"foo|ST[a:b]", "foo_per_sec|ST[a:b]"},
+	}
+
+	for _, tst := range tests {
+		t.Logf("\t%s", tst.name)
+		if n := rateMetricName(tst.name); n != tst.expected {
+			t.Fatalf("expected (%s) got (%s)", tst.expected, n)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/pkg/errors"
+)
+
+// setDelim returns the delimiter joining set names and members
+func (s *Server) setDelim() string {
+	if s.setDelimiter == "" {
+		return config.MetricNameSeparator
+	}
+	return s.setDelimiter
+}
+
+// setMetricName returns the name of the counter tracking a set member
+func (s *Server) setMetricName(name, member string) string {
+	return strings.Join([]string{name, member}, s.setDelim())
+}
+
+// setMember encodes a (normalized) set member so that the counter name is
+// unambiguous: occurrences of the set delimiter are replaced with '_' (or
+// rejected, if configured) and members longer than the maximum length are
+// truncated with a hash of the full member appended, distinct long members
+// sharing a prefix remain distinct.
+func (s *Server) setMember(member string) (string, error) {
+	delim := s.setDelim()
+	if strings.Contains(member, delim) {
+		if s.rejectInvalid {
+			return "", errors.Errorf("set delimiter (%s) in set value (%q)", delim, member)
+		}
+		member = strings.Replace(member, delim, string(invalidCharReplace), -1)
+	}
+
+	if s.setMaxLength > 0 && len(member) > s.setMaxLength {
+		member = truncateSetMember(member, s.setMaxLength)
+	}
+
+	return member, nil
+}
+
+// truncateSetMember shortens member to at most maxLen bytes (on a rune
+// boundary), ending with '_' and a hash (8 hex digits) of the full member
+func truncateSetMember(member string, maxLen int) string {
+	h := fnv.New32a()
+	h.Write([]byte(member))
+	suffix := fmt.Sprintf("%c%08x", invalidCharReplace, h.Sum32())
+
+	keep := maxLen - len(suffix)
+	if keep < 0 {
+		keep = 0
+	}
+	for keep > 0 && !utf8.RuneStart(member[keep]) {
+		keep--
+	}
+
+	return member[:keep] + suffix
+}
@@ -0,0 +1,146 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSetMember(t *testing.T) {
+	t.Log("Testing setMember")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tdefault delimiter")
+	{
+		s := Server{}
+		if name := s.setMetricName("test", "foo"); name != "test`foo" {
+			t.Fatalf("expected (test`foo) got (%s)", name)
+		}
+	}
+
+	t.Log("\tdelimiter in member, sanitize")
+	{
+		s := Server{setDelimiter: "."}
+		tests := []struct {
+			in     string
+			expect string
+		}{
+			{"foo", "foo"},
+			{"b.c", "b_c"},
+			{"..", "__"},
+		}
+		for _, tt := range tests {
+			got, err := s.setMember(tt.in)
+			if err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+			if got != tt.expect {
+				t.Fatalf("expected (%s) got (%s)", tt.expect, got)
+			}
+		}
+	}
+
+	t.Log("\tmulti-character delimiter in member, sanitize")
+	{
+		s := Server{setDelimiter: "::"}
+		got, err := s.setMember("a::b:c")
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if got != "a_b:c" {
+			t.Fatalf("expected (a_b:c) got (%s)", got)
+		}
+	}
+
+	t.Log("\tdelimiter in member, reject")
+	{
+		s := Server{setDelimiter: ".", rejectInvalid: true}
+		_, err := s.setMember("b.c")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		expect := `set delimiter (.) in set value ("b.c")`
+		if err.Error() != expect {
+			t.Fatalf("expected (%s) got (%s)", expect, err)
+		}
+	}
+
+	t.Log("\tmax length")
+	{
+		s := Server{setMaxLength: 32}
+		short := strings.Repeat("a", 32)
+		got, err := s.setMember(short)
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if got != short {
+			t.Fatalf("expected (%s) got (%s)", short, got)
+		}
+
+		long1 := strings.Repeat("a", 40) + "1"
+		long2 := strings.Repeat("a", 40) + "2"
+		got1, _ := s.setMember(long1)
+		got2, _ := s.setMember(long2)
+		if len(got1) != 32 || len(got2) != 32 {
+			t.Fatalf("expected length 32, got (%s) (%s)", got1, got2)
+		}
+		if got1 == got2 {
+			t.Fatalf("expected distinct truncated members, got (%s) for both", got1)
+		}
+		if !strings.HasPrefix(got1, strings.Repeat("a", 23)+"_") {
+			t.Fatalf("expected prefix and hash, got (%s)", got1)
+		}
+	}
+
+	t.Log("\tmax length, multi-byte runes")
+	{
+		s := Server{setMaxLength: 16}
+		got, _ := s.setMember(strings.Repeat("é", 20))
+		if len(got) > 16 || !strings.HasPrefix(got, "ééé_") {
+			t.Fatalf("expected truncation on a rune boundary, got (%s)", got)
+		}
+	}
+}
+
+func TestSetCollisions(t *testing.T) {
+	t.Log("Testing set member collisions")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	s := Server{setDelimiter: "."}
+	if err := s.initHostMetrics(); err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+
+	// without sanitizing, both would be counted as "a.b.c"
+	sets := []struct {
+		name   string
+		member string
+	}{
+		{"a", "b.c"},
+		{"a.b", "c"},
+	}
+	for _, set := range sets {
+		if err := s.applyValue(s.hostMetrics, destHost, set.name, valueSegment{value: set.member, mtype: "s"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+
+	m := s.hostMetrics.FlushMetrics()
+	for _, name := range []string{"a.b_c", "a.b.c"} {
+		v, ok := (*m)[name]
+		if !ok {
+			t.Fatalf("expected %s, got %#v", name, *m)
+		}
+		if v.Value.(uint64) != 1 {
+			t.Fatalf("expected %s=1, got %v", name, v.Value)
+		}
+	}
+}
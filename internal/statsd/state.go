@@ -0,0 +1,183 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics"
+	"github.com/pkg/errors"
+)
+
+// stateSnapshot is the host counters and gauges, not yet collected, saved
+// when the agent stops and restored when it starts
+type stateSnapshot struct {
+	Saved    time.Time             `json:"saved"`
+	Counters map[string]uint64     `json:"counters"`
+	Gauges   map[string]cgm.Metric `json:"gauges"`
+}
+
+// trackGauge records the name of a host gauge, the metrics flushed from
+// cgm do not distinguish gauges from counters (e.g. both may be uint64)
+func (s *Server) trackGauge(metricDest, name string) {
+	if s.stateFile == "" || metricDest != destHost {
+		return
+	}
+	s.stateGaugesmu.Lock()
+	s.stateGauges[name] = s.stateFlushes
+	s.stateGaugesmu.Unlock()
+}
+
+// pruneGauges drops the names of host gauges which were not updated during
+// the flush interval which just ended, the gauges are no longer held by the
+// host metrics. Names updated during the interval are kept for one more
+// interval, a gauge may be applied after the flush (e.g. aggregation window).
+func (s *Server) pruneGauges() {
+	if s.stateFile == "" {
+		return
+	}
+	s.stateGaugesmu.Lock()
+	defer s.stateGaugesmu.Unlock()
+	for name, flushes := range s.stateGauges {
+		if flushes < s.stateFlushes {
+			delete(s.stateGauges, name)
+		}
+	}
+	s.stateFlushes++
+}
+
+// saveState writes the host counters and gauges which have not been
+// collected to the state file. NOTE: the host metrics are flushed, call
+// only when stopping.
+func (s *Server) saveState() error {
+	if s.stateFile == "" || s.hostMetrics == nil {
+		return nil
+	}
+
+	s.hostMetricsmu.Lock()
+	metrics := s.hostMetrics.FlushMetrics()
+	s.hostMetricsmu.Unlock()
+
+	snap := stateSnapshot{
+		Saved:    time.Now(),
+		Counters: make(map[string]uint64),
+		Gauges:   make(map[string]cgm.Metric),
+	}
+
+	s.stateGaugesmu.Lock()
+	for name, m := range *metrics {
+		if _, ok := s.stateGauges[name]; ok {
+			snap.Gauges[name] = m
+			continue
+		}
+		// histograms, text and derived gauges (e.g. timer percentiles) are not saved
+		if v, ok := m.Value.(uint64); ok {
+			snap.Counters[name] = v
+		}
+	}
+	s.stateGaugesmu.Unlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return errors.Wrap(err, "encoding statsd state")
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.stateFile), filepath.Base(s.stateFile)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating statsd state file")
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "writing statsd state file")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "writing statsd state file")
+	}
+	if err := os.Rename(tmp.Name(), s.stateFile); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "saving statsd state file")
+	}
+
+	s.logger.Info().Int("counters", len(snap.Counters)).Int("gauges", len(snap.Gauges)).Str("file", s.stateFile).Msg("saved state")
+	return nil
+}
+
+// loadState restores the host counters and gauges from the state file, a
+// snapshot older than the state max age is discarded. The state file is
+// removed so it is only restored once. NOTE: caller must hold hostMetricsmu.
+func (s *Server) loadState(hm *cgm.CirconusMetrics) error {
+	if s.stateFile == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(s.stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "reading statsd state file")
+	}
+	if err := os.Remove(s.stateFile); err != nil {
+		s.logger.Warn().Err(err).Str("file", s.stateFile).Msg("removing state file")
+	}
+
+	var snap stateSnapshot
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&snap); err != nil {
+		return errors.Wrap(err, "parsing statsd state file")
+	}
+
+	if age := time.Since(snap.Saved); age > s.stateMaxAge {
+		s.logger.Info().Str("age", age.String()).Str("max_age", s.stateMaxAge.String()).Msg("state expired, discarding")
+		return nil
+	}
+
+	for name, v := range snap.Counters {
+		hm.IncrementByValue(name, v)
+	}
+
+	s.stateGaugesmu.Lock()
+	defer s.stateGaugesmu.Unlock()
+	for name, m := range snap.Gauges {
+		v, err := gaugeValue(m)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("gauge", name).Msg("restoring state, ignoring")
+			continue
+		}
+		hm.Gauge(name, v)
+		s.stateGauges[name] = s.stateFlushes
+	}
+
+	s.logger.Info().Int("counters", len(snap.Counters)).Int("gauges", len(snap.Gauges)).Str("file", s.stateFile).Msg("restored state")
+	return nil
+}
+
+// gaugeValue returns the value of a saved gauge, as the type it was saved
+func gaugeValue(m cgm.Metric) (interface{}, error) {
+	n, ok := m.Value.(json.Number)
+	if !ok {
+		return nil, errors.Errorf("invalid value (%v)", m.Value)
+	}
+	switch m.Type {
+	case "i", "l":
+		return strconv.ParseInt(n.String(), 10, 64)
+	case "I", "L":
+		return strconv.ParseUint(n.String(), 10, 64)
+	case "n":
+		return strconv.ParseFloat(n.String(), 64)
+	default:
+		return nil, errors.Errorf("invalid type (%s)", m.Type)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-agent/internal/config"
+	"github.com/circonus-labs/circonus-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+func TestSaveLoadState(t *testing.T) {
+	t.Log("Testing saveState/loadState")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	dir, err := ioutil.TempDir("", "statsd-state")
+	if err != nil {
+		t.Fatalf("expected NO error, got (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state.json")
+
+	newServer := func() *Server {
+		viper.Reset()
+		viper.Set(config.KeyStatsdDisabled, false)
+		viper.Set(config.KeyStatsdPort, "65125")
+		viper.Set(config.KeyStatsdHostCategory, defaults.StatsdHostCategory)
+		viper.Set(config.KeyStatsdStateFile, stateFile)
+		s, err := New()
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		s.listener.Close()
+		return s
+	}
+
+	t.Log("\tno state file")
+	{
+		s := newServer()
+		if s.stateMaxAge != 5*time.Minute {
+			t.Fatalf("expected 5m, got %s", s.stateMaxAge)
+		}
+		if m := s.hostMetrics.FlushMetrics(); len(*m) != 0 {
+			t.Fatalf("expected no metrics, got %v", *m)
+		}
+	}
+
+	t.Log("\tsave and restore")
+	{
+		s := newServer()
+		for _, metric := range []string{"requests:3|c", "requests:2|c", "workers:5|g", "temp:21.5|g", "delta:-2|g", "latency:10|ms", "users:bob|s"} {
+			if err := s.parseMetric(metric); err != nil {
+				t.Fatalf("expected nil, got (%s)", err)
+			}
+		}
+		if err := s.saveState(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if m := s.hostMetrics.FlushMetrics(); len(*m) != 0 {
+			t.Fatalf("expected saved metrics to be flushed, got %v", *m)
+		}
+
+		s = newServer()
+		if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+			t.Fatal("expected state file to be removed")
+		}
+		m := *s.hostMetrics.FlushMetrics()
+
+		expect := map[string]interface{}{
+			"requests":  uint64(5),
+			"workers":   uint64(5),
+			"temp":      float64(21.5),
+			"delta":     int64(-2),
+			"users`bob": uint64(1), // set member counter
+		}
+		if len(m) != len(expect) {
+			t.Fatalf("expected %d metrics, got %v", len(expect), m)
+		}
+		for name, v := range expect {
+			if m[name].Value != v {
+				t.Fatalf("%s expected %#v, got %#v", name, v, m[name].Value)
+			}
+		}
+		if _, ok := s.stateGauges["workers"]; !ok {
+			t.Fatal("expected restored gauge to be tracked")
+		}
+	}
+
+	t.Log("\tgauge names pruned on flush")
+	{
+		s := newServer()
+		if err := s.parseMetric("old:1|g"); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		s.Flush()
+		if err := s.parseMetric("new:1|g"); err != nil {
+			t.Fatalf("expected nil, got (%s)", err)
+		}
+		s.Flush()
+		if _, ok := s.stateGauges["old"]; ok {
+			t.Fatalf("expected old gauge to be pruned, got %v", s.stateGauges)
+		}
+		if _, ok := s.stateGauges["new"]; !ok {
+			t.Fatalf("expected new gauge to be tracked, got %v", s.stateGauges)
+		}
+		s.Flush()
+		if len(s.stateGauges) != 0 {
+			t.Fatalf("expected no gauges tracked, got %v", s.stateGauges)
+		}
+	}
+
+	t.Log("\texpired")
+	{
+		data, err := json.Marshal(stateSnapshot{
+			Saved:    time.Now().Add(-10 * time.Minute),
+			Counters: map[string]uint64{"requests": 1},
+		})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := ioutil.WriteFile(stateFile, data, 0644); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		s := newServer()
+		if m := s.hostMetrics.FlushMetrics(); len(*m) != 0 {
+			t.Fatalf("expected no metrics, got %v", *m)
+		}
+		if _, err := os.Stat(stateFile); !os.IsNotExist(err) {
+			t.Fatal("expected state file to be removed")
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		if err := ioutil.WriteFile(stateFile, []byte("{"), 0644); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		s := newServer()
+		if err := s.loadState(s.hostMetrics); err != nil {
+			t.Fatalf("expected NO error (file removed), got (%s)", err)
+		}
+		if m := s.hostMetrics.FlushMetrics(); len(*m) != 0 {
+			t.Fatalf("expected no metrics, got %v", *m)
+		}
+	}
+
+	viper.Reset()
+}
@@ -0,0 +1,200 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maier/go-appstats"
+	"github.com/pkg/errors"
+)
+
+// timerSet buffers host timer (ms) values for a flush window so percentiles
+// can be reported as gauges when the host metrics are flushed. The number
+// of timers and the values kept per timer are bounded, once a timer holds
+// the maximum number of values the window is sampled (reservoir sampling).
+type timerSet struct {
+	percentiles []float64
+	maxTimers   int
+	maxValues   int
+	timers      map[string]*timerValues
+	dropped     uint64 // values not buffered, the set was full
+	rnd         *rand.Rand
+	sync.Mutex
+}
+
+// timerValues holds the values of a single timer for the current window
+type timerValues struct {
+	seen   int64 // values received in the window, including those not kept
+	values []float64
+}
+
+// newTimerSet returns a timer set reporting percentiles, nil if there are no percentiles
+func newTimerSet(percentiles []float64, maxTimers, maxValues int) *timerSet {
+	if len(percentiles) == 0 {
+		return nil
+	}
+	return &timerSet{
+		percentiles: percentiles,
+		maxTimers:   maxTimers,
+		maxValues:   maxValues,
+		timers:      make(map[string]*timerValues),
+		rnd:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// add buffers a timer value, returns false if the value was dropped because
+// the set already holds the maximum number of timers
+func (ts *timerSet) add(name string, v float64) bool {
+	return ts.addCount(name, v, 1)
+}
+
+// addCount buffers a timer value received n times (e.g. a histogram bin),
+// returns false if the value was dropped because the set already holds the
+// maximum number of timers. Once the timer holds the maximum number of values,
+// a large count replaces the expected number of sampled values in one pass
+// rather than sampling each occurrence.
+func (ts *timerSet) addCount(name string, v float64, n int64) bool {
+	ts.Lock()
+	defer ts.Unlock()
+
+	tv, ok := ts.timers[name]
+	if !ok {
+		if len(ts.timers) >= ts.maxTimers {
+			ts.dropped++
+			return false
+		}
+		tv = &timerValues{}
+		ts.timers[name] = tv
+	}
+
+	for ; n > 0 && len(tv.values) < ts.maxValues; n-- {
+		tv.seen++
+		tv.values = append(tv.values, v)
+	}
+	if n == 0 {
+		return true
+	}
+
+	if n > int64(ts.maxValues) {
+		replace := int(math.Round(float64(ts.maxValues) * float64(n) / float64(tv.seen+n)))
+		for _, i := range ts.rnd.Perm(ts.maxValues)[:replace] {
+			tv.values[i] = v
+		}
+		tv.seen += n
+		return true
+	}
+
+	for ; n > 0; n-- {
+		tv.seen++
+		if i := ts.rnd.Int63n(tv.seen); i < int64(ts.maxValues) {
+			tv.values[i] = v
+		}
+	}
+
+	return true
+}
+
+// take returns the buffered values for each timer, resetting the set for the next window
+func (ts *timerSet) take() map[string][]float64 {
+	ts.Lock()
+	defer ts.Unlock()
+
+	timers := make(map[string][]float64, len(ts.timers))
+	for name, tv := range ts.timers {
+		timers[name] = tv.values
+	}
+	ts.timers = make(map[string]*timerValues)
+
+	return timers
+}
+
+// len returns the number of timers buffered in the current window and the
+// number of values dropped since the set was created
+func (ts *timerSet) len() (int, uint64) {
+	ts.Lock()
+	defer ts.Unlock()
+	return len(ts.timers), ts.dropped
+}
+
+// timer buffers a host timer value, received n times, for percentiles
+func (s *Server) timer(name string, v float64, n int64) {
+	if !s.timers.addCount(name, v, n) {
+		appstats.IncrementInt("statsd_timer_values_dropped")
+		s.logger.Debug().Str("name", name).Msg("timer percentiles full, value dropped")
+	}
+}
+
+// flushTimers reports the percentiles of the timer values received since
+// the last flush as host gauges
+func (s *Server) flushTimers() {
+	if s.timers == nil {
+		return
+	}
+
+	for name, values := range s.timers.take() {
+		if len(values) == 0 {
+			continue
+		}
+		sort.Float64s(values)
+		for _, p := range s.timers.percentiles {
+			s.gauge(s.hostMetrics, destHost, percentileMetricName(name, p), percentile(values, p))
+		}
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0-100] of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// percentileMetricName returns the gauge name for a timer percentile, e.g.
+// foo.p95 or foo.p99_9, keeping any stream tags at the end of the name
+func percentileMetricName(name string, p float64) string {
+	suffix := ".p" + strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", -1)
+	if i := strings.Index(name, "|ST["); i > 0 {
+		return name[:i] + suffix + name[i:]
+	}
+	return name + suffix
+}
+
+// parsePercentiles parses, de-duplicates and sorts the configured timer percentiles
+func parsePercentiles(list []string) ([]float64, error) {
+	seen := make(map[float64]bool, len(list))
+	percentiles := make([]float64, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid percentile (%s)", item)
+		}
+		if p <= 0 || p > 100 {
+			return nil, errors.Errorf("invalid percentile (%s), must be greater than 0 and at most 100", item)
+		}
+		if !seen[p] {
+			seen[p] = true
+			percentiles = append(percentiles, p)
+		}
+	}
+	sort.Float64s(percentiles)
+	return percentiles, nil
+}
@@ -0,0 +1,321 @@
+// Copyright © 2018 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package statsd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestTimerSet(t *testing.T) {
+	t.Log("Testing timerSet")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tdisabled")
+	{
+		if ts := newTimerSet(nil, 10, 10); ts != nil {
+			t.Fatalf("expected nil, got %#v", ts)
+		}
+	}
+
+	t.Log("\tbounded timers")
+	{
+		ts := newTimerSet([]float64{50}, 2, 10)
+		if !ts.add("a", 1) || !ts.add("b", 1) {
+			t.Fatal("expected values added")
+		}
+		if ts.add("c", 1) {
+			t.Fatal("expected value for new timer dropped")
+		}
+		if !ts.add("a", 2) {
+			t.Fatal("expected value for existing timer added")
+		}
+		if n, dropped := ts.len(); n != 2 || dropped != 1 {
+			t.Fatalf("expected 2 timers 1 dropped, got %d %d", n, dropped)
+		}
+	}
+
+	t.Log("\tbounded values, take resets")
+	{
+		ts := newTimerSet([]float64{50}, 2, 10)
+		for i := 0; i < 100; i++ {
+			ts.add("a", float64(i))
+		}
+		timers := ts.take()
+		if len(timers["a"]) != 10 {
+			t.Fatalf("expected 10 values, got %d", len(timers["a"]))
+		}
+		if n, _ := ts.len(); n != 0 {
+			t.Fatalf("expected 0 timers after take, got %d", n)
+		}
+	}
+}
+
+func TestTimerSetAddCount(t *testing.T) {
+	t.Log("Testing timerSet addCount")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\tfits")
+	{
+		ts := newTimerSet([]float64{50}, 2, 10)
+		if !ts.addCount("a", 5, 4) {
+			t.Fatal("expected values added")
+		}
+		if v := ts.take()["a"]; len(v) != 4 {
+			t.Fatalf("expected 4 values, got %v", v)
+		}
+	}
+
+	t.Log("\tlarge count, sampled")
+	{
+		ts := newTimerSet([]float64{50}, 2, 10)
+		for i := 0; i < 10; i++ {
+			ts.add("a", 1)
+		}
+		// 10 values of 1 followed by 90 values of 2, 9 of the 10 kept values
+		// are expected to be replaced
+		if !ts.addCount("a", 2, 90) {
+			t.Fatal("expected values added")
+		}
+		values := ts.take()["a"]
+		if len(values) != 10 {
+			t.Fatalf("expected 10 values, got %v", values)
+		}
+		replaced := 0
+		for _, v := range values {
+			if v == 2 {
+				replaced++
+			}
+		}
+		if replaced != 9 {
+			t.Fatalf("expected 9 replaced values, got %v", values)
+		}
+	}
+
+	t.Log("\tfull")
+	{
+		ts := newTimerSet([]float64{50}, 1, 10)
+		ts.add("a", 1)
+		if ts.addCount("b", 1, 100) {
+			t.Fatal("expected value for new timer dropped")
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	t.Log("Testing percentile")
+
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		p      float64
+		expect float64
+	}{
+		{50, 5},
+		{90, 9},
+		{95, 10},
+		{100, 10},
+		{0.1, 1},
+	}
+
+	for _, test := range tests {
+		t.Logf("\tp%v", test.p)
+		if v := percentile(values, test.p); v != test.expect {
+			t.Fatalf("expected %v, got %v", test.expect, v)
+		}
+	}
+}
+
+func TestPercentileMetricName(t *testing.T) {
+	t.Log("Testing percentileMetricName")
+
+	tests := []struct {
+		name   string
+		p      float64
+		expect string
+	}{
+		{"foo", 95, "foo.p95"},
+		{"foo", 99.9, "foo.p99_9"},
+		{"foo|ST[a:b]", 50, "foo.p50|ST[a:b]"},
+	}
+
+	for _, test := range tests {
+		t.Logf("\t%s %v", test.name, test.p)
+		if n := percentileMetricName(test.name, test.p); n != test.expect {
+			t.Fatalf("expected %s, got %s", test.expect, n)
+		}
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	t.Log("Testing parsePercentiles")
+
+	t.Log("\tvalid")
+	{
+		pcts, err := parsePercentiles([]string{"99", " 50", "95", "50", ""})
+		if err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if len(pcts) != 3 || pcts[0] != 50 || pcts[1] != 95 || pcts[2] != 99 {
+			t.Fatalf("unexpected percentiles %v", pcts)
+		}
+	}
+
+	t.Log("\tinvalid")
+	{
+		for _, item := range []string{"abc", "0", "101", "-5"} {
+			if _, err := parsePercentiles([]string{item}); err == nil {
+				t.Fatalf("expected error for %s", item)
+			}
+		}
+	}
+}
+
+func TestFlushTimers(t *testing.T) {
+	t.Log("Testing flushTimers")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	t.Log("\twith histogram")
+	{
+		s := Server{timers: newTimerSet([]float64{50, 90}, timerMaxMetrics, timerMaxValues)}
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		for i := 1; i <= 10; i++ {
+			if err := s.applyValue(s.hostMetrics, destHost, "foo", valueSegment{value: "10", mtype: "ms"}); err != nil {
+				t.Fatalf("expected NO error, got (%s)", err)
+			}
+		}
+		if err := s.applyValue(s.hostMetrics, destHost, "bar", valueSegment{value: "1", mtype: "h"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		m := s.Flush()
+		for _, mn := range []string{"foo", "foo.p50", "foo.p90", "bar"} {
+			if _, ok := (*m)[mn]; !ok {
+				t.Fatalf("expected %s, got %#v", mn, *m)
+			}
+		}
+		if _, ok := (*m)["bar.p50"]; ok {
+			t.Fatal("expected no percentiles for circonus histogram")
+		}
+		if n, _ := s.timers.len(); n != 0 {
+			t.Fatalf("expected timers cleared, got %d", n)
+		}
+	}
+
+	t.Log("\thistogram bin")
+	{
+		s := Server{timers: newTimerSet([]float64{50}, timerMaxMetrics, timerMaxValues)}
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := s.applyValue(s.hostMetrics, destHost, "foo", valueSegment{value: "H[12]=1000", mtype: "ms"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		m := s.Flush()
+		if _, ok := (*m)["foo"]; !ok {
+			t.Fatalf("expected foo, got %#v", *m)
+		}
+		if p, ok := (*m)["foo.p50"]; !ok || fmt.Sprintf("%v", p.Value) != "12" {
+			t.Fatalf("expected foo.p50 12, got %#v", *m)
+		}
+	}
+
+	t.Log("\tpercentiles only")
+	{
+		s := Server{
+			timers:               newTimerSet([]float64{99}, timerMaxMetrics, timerMaxValues),
+			timerPercentilesOnly: true,
+		}
+		if err := s.initHostMetrics(); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+		if err := s.applyValue(s.hostMetrics, destHost, "foo", valueSegment{value: "10", mtype: "ms"}); err != nil {
+			t.Fatalf("expected NO error, got (%s)", err)
+		}
+
+		m := s.Flush()
+		if _, ok := (*m)["foo"]; ok {
+			t.Fatal("expected no histogram")
+		}
+		if _, ok := (*m)["foo.p99"]; !ok {
+			t.Fatalf("expected foo.p99, got %#v", *m)
+		}
+	}
+}
+
+// BenchmarkTimerValues records a timer value received 1,000 times one value at a time
+func BenchmarkTimerValues(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	s := Server{}
+	if err := s.initHostMetrics(); err != nil {
+		b.Fatalf("expected NO error, got (%s)", err)
+	}
+	mv := valueSegment{value: "12", mtype: "ms"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 1000; j++ {
+			if err := s.applyValue(s.hostMetrics, destHost, "foo", mv); err != nil {
+				b.Fatalf("expected NO error, got (%s)", err)
+			}
+		}
+	}
+}
+
+// BenchmarkTimerBin records a timer value received 1,000 times as a histogram bin
+func BenchmarkTimerBin(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	s := Server{}
+	if err := s.initHostMetrics(); err != nil {
+		b.Fatalf("expected NO error, got (%s)", err)
+	}
+	mv := valueSegment{value: "H[12]=1000", mtype: "ms"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.applyValue(s.hostMetrics, destHost, "foo", mv); err != nil {
+			b.Fatalf("expected NO error, got (%s)", err)
+		}
+	}
+}
+
+// BenchmarkTimerPercentilesValues buffers a timer value received 100,000 times one value at a time
+func BenchmarkTimerPercentilesValues(b *testing.B) {
+	ts := newTimerSet([]float64{50}, timerMaxMetrics, timerMaxValues)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100000; j++ {
+			ts.add("foo", 12)
+		}
+		ts.take()
+	}
+}
+
+// BenchmarkTimerPercentilesBin buffers a timer value received 100,000 times as a histogram bin
+func BenchmarkTimerPercentilesBin(b *testing.B) {
+	ts := newTimerSet([]float64{50}, timerMaxMetrics, timerMaxValues)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ts.addCount("foo", 12, 100000)
+		ts.take()
+	}
+}
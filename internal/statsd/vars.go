@@ -10,6 +10,7 @@ import (
 	"net"
 	"regexp"
 	"sync"
+	"time"
 
 	cgm "github.com/circonus-labs/circonus-gometrics"
 	"github.com/rs/zerolog"
@@ -18,8 +19,21 @@ import (
 
 // Server defines a statsd server
 type Server struct {
+	// counters updated with sync/atomic, kept first in the struct so they
+	// are 64-bit aligned on 32-bit platforms
+	metricsBad       uint64
+	metricsFiltered  uint64
+	packetsBad       uint64
+	packetsThrottled uint64
+	packetsTotal     uint64
+
+	agg                   *aggregator
 	ctx                   context.Context
+	counterMode           string // host counters are reported as counts, rates or both (count|rate|both)
+	counterNames          map[string]bool
+	counterNamesmu        sync.Mutex
 	disabled              bool
+	filters               []metricFilter // metric name allow|deny rules, applied in order
 	address               *net.UDPAddr
 	hostMetrics           *cgm.CirconusMetrics
 	hostMetricsmu         sync.Mutex
@@ -28,11 +42,15 @@ type Server struct {
 	logger                zerolog.Logger
 	hostPrefix            string
 	hostCategory          string
+	hostTag               string // tag category identifying the originating host, empty if disabled
 	groupCID              string
 	groupPrefix           string
 	groupCounterOp        string
 	groupGaugeOp          string
+	groupInterval         string
+	groupOps              *groupOps // group values reduced by the group operators each group interval
 	groupSetOp            string
+	groupTag              string // tag (category:value) identifying group metrics when routing by tag
 	metricRegex           *regexp.Regexp
 	metricRegexGroupNames []string
 	apiKey                string
@@ -40,15 +58,72 @@ type Server struct {
 	apiURL                string
 	apiCAFile             string
 	debugCGM              bool
+	gaugeSeen             map[aggKey]time.Time
+	gaugeSeenmu           sync.Mutex
+	gaugeTTL              time.Duration
+	lastFlush             time.Time // time of the previous flush, the counter rate interval
+	limiter               *rateLimiter
 	listener              *net.UDPConn
 	packetCh              chan []byte
+	packedRegex           *regexp.Regexp
+	packedRegexGroupNames []string
+	queue                 *queueStats // packet queue depth samples
+	queueMetrics          bool        // report the queue depth gauges each flush
+	rejectInvalid         bool
+	setDelimiter          string // joins set names and members (default is the metric name separator)
+	setMaxLength          int    // set members longer than this are truncated (0 disables)
+	started               int32  // set (atomically) once the reader and processor are started
+	zeroCounter           string
+	routing               string            // how metrics are routed to the host or group check (prefix|tag|both)
+	stateFile             string            // host counters and gauges saved on stop, restored on start (empty disables)
+	stateGauges           map[string]uint64 // host gauge names, the flush count when last updated
+	stateFlushes          uint64            // host metric flushes, guarded by stateGaugesmu
+	stateGaugesmu         sync.Mutex
+	stateMaxAge           time.Duration
+	strictLines           bool // split packets strictly on \n, blank or \r terminated lines are invalid
 	t                     tomb.Tomb
+	timers                *timerSet
+	timerPercentilesOnly  bool
 }
 
 const (
-	maxPacketSize   = 1472
-	packetQueueSize = 1000
-	destHost        = "host"
-	destGroup       = "group"
-	destIgnore      = "ignore"
+	maxPacketSize = 1472
+	destHost      = "host"
+	destGroup     = "group"
+	destIgnore    = "ignore"
+
+	routePrefix = "prefix" // by metric name prefix (host|group)
+	routeTag    = "tag"    // by group tag, untagged metrics are host metrics
+	routeBoth   = "both"   // by group tag, then by metric name prefix
+
+	invalidCharsReject   = "reject"
+	invalidCharsSanitize = "sanitize"
+	invalidCharReplace   = '_'
+
+	setDelimiterMaxLength = 8  // longest set delimiter accepted
+	setMaxLengthMin       = 16 // shortest set member maximum length accepted, room for the hash suffix
+
+	counterModeCount = "count" // report counters (original behavior)
+	counterModeRate  = "rate"  // report per second rates instead of counters
+	counterModeBoth  = "both"  // report counters and per second rates
+	rateMetricSuffix = "_per_sec"
+
+	groupOpAverage = "average" // group operator, average of the values received during the group interval (otherwise sum)
+
+	zeroCounterZero = "zero" // record 0
+	zeroCounterDrop = "drop" // ignore the metric
+	zeroCounterOne  = "one"  // record 1 (original behavior)
+
+	aggregateMaxEntries = 10000 // flush the aggregation window early when it holds this many metrics
+
+	rateLimitMaxSources = 10000        // maximum number of packet sources tracked by the rate limiter
+	throttledMetric     = "_throttled" // host counter of packets dropped by the rate limiter, per source
+
+	queueWarnRatio            = 0.9                       // warn when the queue high water mark reaches this fraction of the queue size
+	queueDepthMaxMetric       = "_queue_depth_max"        // host gauge, deepest packet queue since the last flush
+	queueDepthAvgMetric       = "_queue_depth_avg"        // host gauge, average packet queue depth since the last flush
+	queueDepthHighWaterMetric = "_queue_depth_high_water" // host gauge, deepest packet queue since start
+
+	timerMaxMetrics = 5000 // maximum number of host timers buffered for percentiles per flush
+	timerMaxValues  = 1000 // maximum number of values kept per timer per flush, beyond this values are sampled
 )